@@ -22,15 +22,21 @@ import (
 	"github.com/chzyer/readline"
 	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/api"
+	"github.com/sipeed/picoclaw/pkg/app"
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	agentdomain "github.com/sipeed/picoclaw/pkg/domain/agent"
 	"github.com/sipeed/picoclaw/pkg/integration"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/eventbus"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/persistence"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/migrate"
+	"github.com/sipeed/picoclaw/pkg/orchestration"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -655,6 +661,9 @@ func gatewayCmd() {
 
 	// Setup cron tool and service
 	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath())
+	if err := setupBoardDigest(cronService, cfg.Integrations.Digest); err != nil {
+		fmt.Printf("Error scheduling board digest: %v\n", err)
+	}
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -675,6 +684,26 @@ func gatewayCmd() {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	// Bots stopped from the dashboard stay stopped across restarts
+	stateDir := filepath.Join(cfg.WorkspacePath(), "state")
+	channelRepo := persistence.NewChannelRepository(stateDir)
+	channelManager.SetStateRepository(channelRepo)
+
+	// Domain events from the stored aggregates are kept in an append-only
+	// history under state/events.jsonl
+	domainEvents := eventbus.New()
+	container := app.NewContainer(domainEvents, channelRepo,
+		persistence.NewAgentRepository(stateDir), persistence.NewSessionRepository(stateDir),
+		persistence.NewSkillRepository(stateDir), persistence.NewWorkflowRepository(stateDir),
+		nil, nil, cfg.WorkspacePath())
+	eventStore, err := persistence.NewFileEventStore(stateDir)
+	if err != nil {
+		fmt.Printf("Error opening domain event history: %v\n", err)
+	} else {
+		defer eventStore.Close()
+		container.AttachEventStore(eventStore)
+	}
+	channelManager.SetEventBus(domainEvents)
 
 	var transcriber *voice.GroqTranscriber
 	if cfg.Providers.Groq.APIKey != "" {
@@ -716,6 +745,19 @@ func gatewayCmd() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Record bus traffic before anything starts publishing
+	var eventLog *bus.EventLog
+	if cfg.Gateway.EventLog.Enabled {
+		eventLog, err = bus.OpenEventLog(filepath.Join(cfg.WorkspacePath(), "bus_events.db"), cfg.Gateway.EventLog.MaxEvents)
+		if err != nil {
+			fmt.Printf("Error opening bus event log: %v\n", err)
+		} else {
+			defer eventLog.Close()
+			eventLog.Attach(ctx, msgBus)
+			fmt.Println("✓ Bus event log enabled")
+		}
+	}
+
 	if err := cronService.Start(); err != nil {
 		fmt.Printf("Error starting cron service: %v\n", err)
 	}
@@ -743,10 +785,37 @@ func gatewayCmd() {
 		fmt.Printf("Error starting channels: %v\n", err)
 	}
 
+	agentLoop.SetTypingNotifier(channelManager.Typing)
 	go agentLoop.Run(ctx)
 
+	orchestrator := orchestration.NewOrchestrator()
+	go orchestrator.RunLeaseWatcher(ctx)
+	// Agents are registered for routing from their tool and skill bindings
+	// as they start, beginning with this gateway's own agent loop
+	container.BridgeCapabilities(orchestrator, nil)
+	agentService := app.NewAgentService(container.Agents, container.EventBus)
+	gatewayAgent, err := agentService.EnsureRunning("picoclaw",
+		agentdomain.ModelConfig{Model: cfg.Agents.Defaults.Model},
+		agentLoop.GetToolRegistry().List(), skillsInfo["names"].([]string))
+	if err != nil {
+		fmt.Printf("Error registering agent with the orchestrator: %v\n", err)
+	}
+	// Kanban owns task claims; keep the orchestrator's assignments in step.
+	if ki, ok := integrationsRegistry.Get("kanban"); ok {
+		if kb, ok := ki.(*kanban.KanbanIntegration); ok {
+			go orchestrator.RunClaimReconciler(ctx, kanbanClaims(kb), orchestration.DefaultReconcileInterval)
+			go orchestrator.RunAutoAssigner(ctx, msgBus, kanbanBoard{kb}, cfg.Integrations.AutoAssignCategories, orchestration.DefaultAutoAssignLease)
+		}
+	}
+
 	// Start the dashboard API server
 	apiServer := api.NewServer(cfg, agentLoop, channelManager, cronService, msgBus, getWebFS())
+	apiServer.SetOrchestrator(orchestrator)
+	apiServer.SetChannelRepository(channelRepo)
+	apiServer.SetEventBus(domainEvents)
+	if eventLog != nil {
+		apiServer.SetEventLog(eventLog)
+	}
 	if err := apiServer.Start(ctx); err != nil {
 		fmt.Printf("Error starting API server: %v\n", err)
 	} else {
@@ -764,6 +833,10 @@ func gatewayCmd() {
 	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	if gatewayAgent != nil {
+		agentService.StopAgent(gatewayAgent.ID())
+	}
+	domainEvents.Close()
 	fmt.Println("✓ Gateway stopped")
 }
 
@@ -1072,6 +1145,9 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 
 	// Set the onJob handler
 	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
+		if job.Payload.Kind == boardDigestJob {
+			return sendBoardDigest(msgBus, job)
+		}
 		result := cronTool.ExecuteJob(context.Background(), job)
 		return result, nil
 	})
@@ -1079,6 +1155,102 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 	return cronService
 }
 
+// boardDigestJob names the board digest's cron job and is its payload kind.
+const boardDigestJob = "board_digest"
+
+// setupBoardDigest registers the board digest cron job from config, or
+// removes it when the digest is disabled. The job's message holds the
+// digest sections, comma-separated.
+func setupBoardDigest(cronService *cron.CronService, cfg config.BoardDigestConfig) error {
+	if !cfg.Enabled || cfg.Channel == "" || cfg.ChatID == "" {
+		cronService.RemoveJobByName(boardDigestJob)
+		return nil
+	}
+	schedule := cfg.Schedule
+	if schedule == "" {
+		schedule = "0 8 * * *"
+	}
+	sections := cfg.Sections
+	if len(sections) == 0 {
+		sections = kanban.DigestSections()
+	}
+	if err := kanban.ValidateDigestSections(sections); err != nil {
+		return err
+	}
+	_, err := cronService.EnsureJob(boardDigestJob,
+		cron.CronSchedule{Kind: "cron", Expr: schedule},
+		cron.CronPayload{
+			Kind:    boardDigestJob,
+			Message: strings.Join(sections, ","),
+			Deliver: true,
+			Channel: cfg.Channel,
+			To:      cfg.ChatID,
+		})
+	return err
+}
+
+// sendBoardDigest composes the board digest and sends it to the job's chat.
+func sendBoardDigest(msgBus *bus.MessageBus, job *cron.CronJob) (string, error) {
+	ki, _ := integration.GetRegistry().Get("kanban")
+	kb, ok := ki.(*kanban.KanbanIntegration)
+	if !ok {
+		return "", fmt.Errorf("kanban not available")
+	}
+	var sections []string
+	if job.Payload.Message != "" {
+		sections = strings.Split(job.Payload.Message, ",")
+	}
+	text, err := kb.Digest(sections, time.Now())
+	if err != nil {
+		return "", err
+	}
+	msgBus.PublishOutbound(bus.OutboundMessage{
+		Channel: job.Payload.Channel,
+		ChatID:  job.Payload.To,
+		Content: text,
+	})
+	return "ok", nil
+}
+
+// kanbanClaims reads the board's active claims for the orchestrator.
+func kanbanClaims(kb *kanban.KanbanIntegration) orchestration.ClaimSource {
+	return func(ctx context.Context) ([]orchestration.Claim, error) {
+		claims, err := kb.ActiveClaimsCtx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]orchestration.Claim, len(claims))
+		for i, c := range claims {
+			out[i] = orchestration.Claim(c)
+		}
+		return out, nil
+	}
+}
+
+// kanbanBoard lets the orchestrator claim and plan tasks on the board.
+type kanbanBoard struct {
+	kb *kanban.KanbanIntegration
+}
+
+func (b kanbanBoard) Claim(ctx context.Context, taskID, agentID string, lease time.Duration) error {
+	return b.kb.ClaimTaskCtx(ctx, taskID, agentID, lease)
+}
+
+// Plan moves an inbox task to planned; a task already past the inbox is
+// left where it is.
+func (b kanbanBoard) Plan(ctx context.Context, taskID string) error {
+	task, err := b.kb.GetTaskCtx(ctx, taskID)
+	if err != nil || task.State != kanban.StateInbox {
+		return err
+	}
+	return b.kb.TransitionTaskCtx(ctx, taskID, kanban.StatePlanned, "no free agent for auto-assignment", "orchestrator")
+}
+
+// Release drops agentID's claim, moving the task back to planned.
+func (b kanbanBoard) Release(ctx context.Context, taskID, agentID string) error {
+	return b.kb.ReleaseTaskCtx(ctx, taskID, agentID, "")
+}
+
 func loadConfig() (*config.Config, error) {
 	return config.LoadConfig(getConfigPath())
 }
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// auditResultMax caps how much of a tool result is kept per audit entry.
+const auditResultMax = 4000
+
+// AuditEntry is one recorded tool invocation.
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	SessionKey string                 `json:"session"`
+	Channel    string                 `json:"channel,omitempty"`
+	ChatID     string                 `json:"chat_id,omitempty"`
+	Tool       string                 `json:"tool"`
+	Args       map[string]interface{} `json:"args"`
+	Result     string                 `json:"result"`
+	DurationMS int64                  `json:"duration_ms"`
+	Success    bool                   `json:"success"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditLog is an append-only SQLite record of every tool call the agent
+// makes. Arguments are redacted by key name before they are written;
+// results are truncated.
+type AuditLog struct {
+	db         *sql.DB
+	redactKeys []string
+	mu         sync.Mutex
+}
+
+// NewAuditLog opens (or creates) the audit database at path. Argument keys
+// containing any of redactKeys are masked; nil uses utils.DefaultSecretKeys.
+func NewAuditLog(path string, redactKeys []string) (*AuditLog, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open audit db: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS tool_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_key TEXT NOT NULL,
+		channel TEXT DEFAULT '',
+		chat_id TEXT DEFAULT '',
+		tool TEXT NOT NULL,
+		args TEXT NOT NULL,
+		result TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		success INTEGER NOT NULL,
+		error TEXT DEFAULT '',
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_tool_audit_session ON tool_audit(session_key, id);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init audit schema: %w", err)
+	}
+	if redactKeys == nil {
+		redactKeys = utils.DefaultSecretKeys
+	}
+	return &AuditLog{db: db, redactKeys: redactKeys}, nil
+}
+
+// Record appends an entry. Args are redacted and the result truncated here,
+// so callers pass the raw values.
+func (a *AuditLog) Record(entry AuditEntry) error {
+	args, err := json.Marshal(utils.RedactMap(entry.Args, a.redactKeys))
+	if err != nil {
+		return fmt.Errorf("encode audit args: %w", err)
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.db.Exec(`INSERT INTO tool_audit
+		(session_key, channel, chat_id, tool, args, result, duration_ms, success, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.SessionKey, entry.Channel, entry.ChatID, entry.Tool, string(args),
+		utils.Truncate(entry.Result, auditResultMax), entry.DurationMS, entry.Success, entry.Error,
+		entry.CreatedAt.Format(time.RFC3339Nano))
+	return err
+}
+
+// List returns entries newest first, optionally for one session, along with
+// the total number of matching entries for pagination.
+func (a *AuditLog) List(ctx context.Context, sessionKey string, limit, offset int) ([]AuditEntry, int, error) {
+	where := ""
+	args := []interface{}{}
+	if sessionKey != "" {
+		where = " WHERE session_key = ?"
+		args = append(args, sessionKey)
+	}
+
+	var total int
+	if err := a.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tool_audit"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT id, session_key, channel, chat_id, tool, args, result,
+		duration_ms, success, error, created_at FROM tool_audit`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var argsJSON, createdAt string
+		if err := rows.Scan(&e.ID, &e.SessionKey, &e.Channel, &e.ChatID, &e.Tool, &argsJSON, &e.Result,
+			&e.DurationMS, &e.Success, &e.Error, &createdAt); err != nil {
+			return nil, 0, err
+		}
+		json.Unmarshal([]byte(argsJSON), &e.Args)
+		e.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// Close closes the underlying database.
+func (a *AuditLog) Close() error {
+	return a.db.Close()
+}
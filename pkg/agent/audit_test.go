@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLogRecordAndList(t *testing.T) {
+	a, err := NewAuditLog(filepath.Join(t.TempDir(), "audit.db"), nil)
+	if err != nil {
+		t.Fatalf("NewAuditLog() error: %v", err)
+	}
+	defer a.Close()
+
+	for i, session := range []string{"s1", "s1", "s2"} {
+		err := a.Record(AuditEntry{
+			SessionKey: session,
+			Tool:       "exec",
+			Args: map[string]interface{}{
+				"command": "echo hi",
+				"env":     map[string]interface{}{"API_TOKEN": "abc123"},
+			},
+			Result:     "hi",
+			DurationMS: int64(i),
+			Success:    true,
+		})
+		if err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+
+	entries, total, err := a.List(context.Background(), "s1", 1, 0)
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if total != 2 || len(entries) != 1 {
+		t.Fatalf("List(s1, limit 1) = %d entries, total %d; want 1, 2", len(entries), total)
+	}
+	if entries[0].DurationMS != 1 {
+		t.Errorf("newest entry duration = %d, want 1", entries[0].DurationMS)
+	}
+
+	env, _ := entries[0].Args["env"].(map[string]interface{})
+	if env["API_TOKEN"] == "abc123" {
+		t.Error("secret argument was stored unredacted")
+	}
+	if entries[0].Args["command"] != "echo hi" {
+		t.Errorf("command = %v, want unredacted", entries[0].Args["command"])
+	}
+
+	_, total, err = a.List(context.Background(), "", 10, 0)
+	if err != nil {
+		t.Fatalf("List(all) error: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+}
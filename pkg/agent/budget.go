@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// budgetExceeded reports whether the session has used up its token budget
+// (agents.defaults.session_token_budget; 0 means no budget).
+func (al *AgentLoop) budgetExceeded(sessionKey string) bool {
+	return al.sessionBudget > 0 && al.sessions.TokensUsed(sessionKey) >= al.sessionBudget
+}
+
+// budgetReply is sent instead of doing more work on a session that is over
+// its budget.
+func (al *AgentLoop) budgetReply(sessionKey string) string {
+	return fmt.Sprintf("Sorry, this conversation has used its token budget (%d of %d tokens), so I can't take on more work here. "+
+		"Please start a new conversation or ask an admin to clear this one.", al.sessions.TokensUsed(sessionKey), al.sessionBudget)
+}
+
+// recordUsage adds a response's token usage to the session.
+func (al *AgentLoop) recordUsage(sessionKey string, usage *providers.UsageInfo) {
+	if usage == nil {
+		return
+	}
+	n := usage.TotalTokens
+	if n == 0 {
+		n = usage.PromptTokens + usage.CompletionTokens
+	}
+	if n > 0 {
+		al.sessions.AddTokens(sessionKey, n)
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestSessionTokenBudget(t *testing.T) {
+	workspace := t.TempDir()
+	provider := &stubProvider{reply: "done", usage: &providers.UsageInfo{PromptTokens: 400, CompletionTokens: 200}}
+	al := &AgentLoop{
+		provider:       provider,
+		workspace:      workspace,
+		model:          "test-model",
+		contextWindow:  8192,
+		maxIterations:  5,
+		sessions:       session.NewSessionManager(""),
+		contextBuilder: NewContextBuilder(workspace),
+		tools:          tools.NewToolRegistry(),
+		sessionBudget:  1000,
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if reply, err := al.ProcessDirect(ctx, "hello", "cli:budget"); err != nil || reply != "done" {
+			t.Fatalf("message %d: reply %q, error %v", i, reply, err)
+		}
+	}
+	if used := al.sessions.TokensUsed("cli:budget"); used != 1200 {
+		t.Errorf("TokensUsed = %d, want 1200", used)
+	}
+
+	reply, err := al.ProcessDirect(ctx, "one more", "cli:budget")
+	if err != nil || !strings.Contains(reply, "token budget (1200 of 1000 tokens)") {
+		t.Errorf("over budget: reply %q, error %v", reply, err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times, want no call once over budget", provider.calls)
+	}
+
+	if reply, _ := al.ProcessDirect(ctx, "hello", "cli:other"); reply != "done" {
+		t.Errorf("other session: reply %q, budget should be per session", reply)
+	}
+}
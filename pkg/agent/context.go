@@ -19,6 +19,12 @@ type ContextBuilder struct {
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
 	tools        *tools.ToolRegistry // Direct reference to tool registry
+	facts        tools.FactStore     // Long-term facts; nil when disabled
+	maxFacts     int                 // Facts injected per chat
+
+	promptTemplate string            // Replaces the built-in identity when set
+	promptVars     map[string]string // Custom template variables
+	loc            *time.Location    // Zone for dates in the prompt; nil is local
 }
 
 func getGlobalConfigDir() string {
@@ -43,19 +49,55 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	}
 }
 
+// SetFactStore enables injecting up to max remembered facts for the
+// current chat into the system prompt.
+func (cb *ContextBuilder) SetFactStore(store tools.FactStore, max int) {
+	cb.facts = store
+	cb.maxFacts = max
+}
+
+// buildFactsSection lists the facts remembered for a chat, newest first.
+func (cb *ContextBuilder) buildFactsSection(channel, chatID string) string {
+	if cb.facts == nil || cb.maxFacts <= 0 {
+		return ""
+	}
+	facts, err := cb.facts.ListFacts(tools.FactScope(channel, chatID))
+	if err != nil || len(facts) == 0 {
+		return ""
+	}
+	if len(facts) > cb.maxFacts {
+		facts = facts[:cb.maxFacts]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## Remembered Facts\nSaved with memory_set in earlier conversations:\n")
+	for _, f := range facts {
+		fmt.Fprintf(&sb, "- %s: %s\n", f.Key, f.Value)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
 // SetToolsRegistry sets the tools registry for dynamic tool summary generation.
 func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
-func (cb *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 (Monday)")
+func (cb *ContextBuilder) getIdentity(registry *tools.ToolRegistry, vars map[string]string) string {
+	// Build tools section dynamically
+	toolsSection := cb.buildToolsSection(registry)
+
+	if cb.promptTemplate != "" {
+		identity := renderPrompt(cb.promptTemplate, vars)
+		if toolsSection != "" {
+			identity += "\n\n" + toolsSection
+		}
+		return identity
+	}
+
+	now := cb.now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
 
-	// Build tools section dynamically
-	toolsSection := cb.buildToolsSection()
-
 	return fmt.Sprintf(`# picoclaw 🦞
 
 You are picoclaw, a helpful AI assistant.
@@ -84,12 +126,12 @@ Your workspace is at: %s
 		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
 }
 
-func (cb *ContextBuilder) buildToolsSection() string {
-	if cb.tools == nil {
+func (cb *ContextBuilder) buildToolsSection(registry *tools.ToolRegistry) string {
+	if registry == nil {
 		return ""
 	}
 
-	summaries := cb.tools.GetSummaries()
+	summaries := registry.GetSummaries()
 	if len(summaries) == 0 {
 		return ""
 	}
@@ -107,10 +149,16 @@ func (cb *ContextBuilder) buildToolsSection() string {
 }
 
 func (cb *ContextBuilder) BuildSystemPrompt() string {
+	return cb.buildSystemPrompt(cb.tools, cb.templateVars("", "", ""))
+}
+
+// buildSystemPrompt builds the system prompt listing the tools in registry,
+// with vars interpolated into the prompt template.
+func (cb *ContextBuilder) buildSystemPrompt(registry *tools.ToolRegistry, vars map[string]string) string {
 	parts := []string{}
 
 	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	parts = append(parts, cb.getIdentity(registry, vars))
 
 	// Bootstrap files
 	bootstrapContent := cb.LoadBootstrapFiles()
@@ -158,13 +206,21 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 }
 
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
+	return cb.BuildMessagesWithTools(cb.tools, history, summary, currentMessage, media, channel, chatID, "")
+}
+
+// BuildMessagesWithTools is BuildMessages with the system prompt listing
+// only the tools in registry, for channels restricted to a subset. user is
+// the sender's name for the {{user}} template variable.
+func (cb *ContextBuilder) BuildMessagesWithTools(registry *tools.ToolRegistry, history []providers.Message, summary string, currentMessage string, media []string, channel, chatID, user string) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	systemPrompt := cb.buildSystemPrompt(registry, cb.templateVars(channel, chatID, user))
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
 		systemPrompt += fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
+		systemPrompt += cb.buildFactsSection(channel, chatID)
 	}
 
 	// Log system prompt summary for debugging (debug mode only)
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Diagnostic check outcomes.
+const (
+	CheckPass = "pass"
+	CheckFail = "fail"
+)
+
+// providerCheckTimeout bounds the test chat call; toolCheckTimeout bounds
+// all tool probes together.
+const (
+	providerCheckTimeout = 20 * time.Second
+	toolCheckTimeout     = 5 * time.Second
+)
+
+// DiagnosticCheck is the outcome of one self-test.
+type DiagnosticCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Diagnostics is the agent's self-test report: its settings and the result
+// of each check. OK is true when every check passed.
+type Diagnostics struct {
+	OK            bool              `json:"ok"`
+	At            time.Time         `json:"at"`
+	Model         string            `json:"model"`
+	MaxTokens     int               `json:"max_tokens"`
+	Temperature   float64           `json:"temperature"`
+	MaxIterations int               `json:"max_iterations"`
+	Workspace     string            `json:"workspace"`
+	Tools         []string          `json:"tools"`
+	Checks        []DiagnosticCheck `json:"checks"`
+}
+
+// Diagnose runs the self-test: a one-line chat with the provider, a write
+// to the workspace, a sanity check of the model settings and the health
+// probe of every tool that has one. The provider call costs a few tokens.
+func (al *AgentLoop) Diagnose(ctx context.Context) Diagnostics {
+	d := Diagnostics{
+		At:            time.Now(),
+		Model:         al.model,
+		MaxTokens:     al.contextWindow,
+		Temperature:   al.temperature,
+		MaxIterations: al.maxIterations,
+		Workspace:     al.workspace,
+		Tools:         []string{},
+	}
+
+	d.Checks = append(d.Checks,
+		runCheck("settings", al.checkSettings),
+		runCheck("provider", func() (string, error) { return al.checkProvider(ctx) }),
+		runCheck("workspace", al.checkWorkspace),
+	)
+
+	if al.tools != nil {
+		d.Tools = al.tools.List()
+		sort.Strings(d.Tools)
+
+		probeCtx, cancel := context.WithTimeout(ctx, toolCheckTimeout)
+		start := time.Now()
+		health := al.tools.CheckHealth(probeCtx)
+		cancel()
+		elapsed := time.Since(start).Milliseconds()
+
+		names := make([]string, 0, len(health))
+		for name := range health {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := DiagnosticCheck{Name: "tool:" + name, Status: CheckPass, DurationMs: elapsed}
+			if err := health[name]; err != nil {
+				c.Status, c.Detail = CheckFail, err.Error()
+			}
+			d.Checks = append(d.Checks, c)
+		}
+	}
+
+	d.OK = true
+	for _, c := range d.Checks {
+		if c.Status != CheckPass {
+			d.OK = false
+		}
+	}
+	return d
+}
+
+// runCheck times fn and turns its result into a DiagnosticCheck.
+func runCheck(name string, fn func() (string, error)) DiagnosticCheck {
+	start := time.Now()
+	detail, err := fn()
+	c := DiagnosticCheck{Name: name, Status: CheckPass, Detail: detail, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		c.Status, c.Detail = CheckFail, err.Error()
+	}
+	return c
+}
+
+func (al *AgentLoop) checkSettings() (string, error) {
+	var problems []string
+	if al.model == "" {
+		problems = append(problems, "no model configured")
+	}
+	if al.contextWindow <= 0 {
+		problems = append(problems, "max_tokens must be positive")
+	}
+	if al.maxIterations <= 0 {
+		problems = append(problems, "max_tool_iterations must be positive")
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "; "))
+	}
+	return fmt.Sprintf("model %s, max_tokens %d", al.model, al.contextWindow), nil
+}
+
+func (al *AgentLoop) checkProvider(ctx context.Context) (string, error) {
+	if al.provider == nil {
+		return "", errors.New("no provider configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, providerCheckTimeout)
+	defer cancel()
+	resp, err := al.chat(ctx, []providers.Message{{Role: "user", Content: "Reply with OK."}}, nil, al.model, map[string]interface{}{
+		"max_tokens":  16,
+		"temperature": 0.0,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || (resp.Content == "" && len(resp.ToolCalls) == 0) {
+		return "", errors.New("provider returned an empty response")
+	}
+	return "replied " + utils.Truncate(resp.Content, 40), nil
+}
+
+func (al *AgentLoop) checkWorkspace() (string, error) {
+	f, err := os.CreateTemp(al.workspace, ".diagnostics-*")
+	if err != nil {
+		return "", fmt.Errorf("workspace not writable: %w", err)
+	}
+	name := f.Name()
+	_, werr := f.WriteString("ok")
+	f.Close()
+	os.Remove(name)
+	if werr != nil {
+		return "", fmt.Errorf("workspace not writable: %w", werr)
+	}
+	return filepath.Clean(al.workspace), nil
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+type stubProvider struct {
+	reply string
+	usage *providers.UsageInfo
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.LLMResponse{Content: p.reply, Usage: p.usage}, nil
+}
+
+func (p *stubProvider) GetDefaultModel() string { return "stub" }
+
+// probedTool is a tool whose health probe returns err.
+type probedTool struct {
+	name string
+	err  error
+}
+
+func (t *probedTool) Name() string                       { return t.name }
+func (t *probedTool) Description() string                { return "test tool" }
+func (t *probedTool) Parameters() map[string]interface{} { return map[string]interface{}{} }
+func (t *probedTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return "", nil
+}
+func (t *probedTool) HealthCheck(ctx context.Context) error { return t.err }
+
+func TestDiagnose(t *testing.T) {
+	reg := tools.NewToolRegistry()
+	reg.Register(&probedTool{name: "good"})
+	reg.Register(&probedTool{name: "broken", err: errors.New("daemon down")})
+	reg.Register(&tools.ReadFileTool{})
+
+	al := &AgentLoop{
+		provider:      &stubProvider{reply: "OK"},
+		workspace:     t.TempDir(),
+		model:         "test-model",
+		contextWindow: 8192,
+		maxIterations: 20,
+		tools:         reg,
+	}
+
+	d := al.Diagnose(context.Background())
+	status := map[string]string{}
+	for _, c := range d.Checks {
+		status[c.Name] = c.Status
+	}
+	for name, want := range map[string]string{
+		"settings":    CheckPass,
+		"provider":    CheckPass,
+		"workspace":   CheckPass,
+		"tool:good":   CheckPass,
+		"tool:broken": CheckFail,
+	} {
+		if status[name] != want {
+			t.Errorf("check %s = %q, want %q (all: %v)", name, status[name], want, status)
+		}
+	}
+	if _, ok := status["tool:read_file"]; ok {
+		t.Error("tools without a probe should not be checked")
+	}
+	if d.OK || len(d.Tools) != 3 || d.Model != "test-model" {
+		t.Errorf("report = ok %v, tools %v, model %q", d.OK, d.Tools, d.Model)
+	}
+	if left, _ := filepath.Glob(filepath.Join(al.workspace, ".diagnostics-*")); len(left) != 0 {
+		t.Errorf("workspace probe left files behind: %v", left)
+	}
+
+	al.provider = &stubProvider{err: errors.New("401 invalid api key")}
+	al.workspace = filepath.Join(al.workspace, "missing")
+	al.tools = nil
+	d = al.Diagnose(context.Background())
+	for _, c := range d.Checks {
+		if (c.Name == "provider" || c.Name == "workspace") && c.Status != CheckFail {
+			t.Errorf("check %s = %q, want fail", c.Name, c.Status)
+		}
+	}
+	if d.OK {
+		t.Error("report should not be ok with a failing provider")
+	}
+	if _, err := os.Stat(al.workspace); !os.IsNotExist(err) {
+		t.Errorf("workspace probe created the workspace: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// FactStore is the SQLite-backed long-term memory behind the memory_*
+// tools. Facts are key/value pairs scoped per chat (see tools.FactScope).
+type FactStore struct {
+	db *sql.DB
+}
+
+// NewFactStore opens (or creates) the fact database at path.
+func NewFactStore(path string) (*FactStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open facts db: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS facts (
+		scope TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (scope, key)
+	);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init facts schema: %w", err)
+	}
+	return &FactStore{db: db}, nil
+}
+
+// SetFact stores value under key, replacing any previous value.
+func (f *FactStore) SetFact(scope, key, value string) error {
+	_, err := f.db.Exec(`INSERT INTO facts (scope, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(scope, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		scope, key, value, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// GetFact returns the value stored under key, if any.
+func (f *FactStore) GetFact(scope, key string) (string, bool, error) {
+	var value string
+	err := f.db.QueryRow("SELECT value FROM facts WHERE scope = ? AND key = ?", scope, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// DeleteFact forgets key. Forgetting an unknown key is not an error.
+func (f *FactStore) DeleteFact(scope, key string) error {
+	_, err := f.db.Exec("DELETE FROM facts WHERE scope = ? AND key = ?", scope, key)
+	return err
+}
+
+// ListFacts returns a scope's facts, most recently updated first.
+func (f *FactStore) ListFacts(scope string) ([]tools.Fact, error) {
+	rows, err := f.db.Query("SELECT key, value, updated_at FROM facts WHERE scope = ? ORDER BY updated_at DESC, key", scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facts []tools.Fact
+	for rows.Next() {
+		var fact tools.Fact
+		var updatedAt string
+		if err := rows.Scan(&fact.Key, &fact.Value, &updatedAt); err != nil {
+			return nil, err
+		}
+		fact.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+		facts = append(facts, fact)
+	}
+	return facts, rows.Err()
+}
+
+// Close closes the underlying database.
+func (f *FactStore) Close() error {
+	return f.db.Close()
+}
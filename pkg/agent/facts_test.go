@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestFactStoreScopes(t *testing.T) {
+	store, err := NewFactStore(filepath.Join(t.TempDir(), "facts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ctx := tools.WithChat(context.Background(), "telegram", "42")
+	memTools := tools.NewMemoryTools(store)
+	set, get := memTools[0], memTools[1]
+
+	if _, err := get.Execute(context.Background(), map[string]interface{}{"key": "timezone"}); err == nil {
+		t.Error("memory_get without a chat succeeded")
+	}
+	if _, err := set.Execute(ctx, map[string]interface{}{"key": "timezone", "value": "PST"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Execute(ctx, map[string]interface{}{"key": "timezone", "value": "UTC"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := get.Execute(ctx, map[string]interface{}{"key": "timezone"}); got != "UTC" {
+		t.Errorf("memory_get = %q, want UTC", got)
+	}
+
+	// Another chat sees nothing.
+	other := tools.WithChat(context.Background(), "telegram", "7")
+	if got, _ := get.Execute(other, map[string]interface{}{"key": "timezone"}); !strings.HasPrefix(got, "Nothing remembered") {
+		t.Errorf("other chat memory_get = %q", got)
+	}
+
+	cb := &ContextBuilder{}
+	cb.SetFactStore(store, 10)
+	if section := cb.buildFactsSection("telegram", "42"); !strings.Contains(section, "- timezone: UTC") {
+		t.Errorf("facts section = %q", section)
+	}
+
+	if _, err := set.Execute(ctx, map[string]interface{}{"key": "timezone", "value": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if facts, _ := store.ListFacts(tools.FactScope("telegram", "42")); len(facts) != 0 {
+		t.Errorf("facts after forget = %v", facts)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/codex"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
@@ -32,6 +33,7 @@ type AgentLoop struct {
 	workspace      string
 	model          string
 	contextWindow  int           // Maximum context window size in tokens
+	modelWindow    int           // Model's context window that requests are trimmed to; 0 means no trimming
 	temperature    float64       // LLM temperature parameter (0.0-2.0)
 	maxIterations  int
 	sessions       *session.SessionManager
@@ -39,17 +41,40 @@ type AgentLoop struct {
 	tools          *tools.ToolRegistry
 	running        atomic.Bool
 	summarizing    sync.Map      // Tracks which sessions are currently being summarized
+	sessionLocks   sessionLocks  // One message at a time per session
+	typing         TypingNotifier
+	onRunning      atomic.Pointer[func()] // Told when IsRunning changes; see SetStatusHandler
+	audit          *AuditLog     // Tool-call audit trail; nil when disabled
+	personas       map[string]config.ChannelPersona // Per-channel prompt/model overrides
+	sessionBudget  int64         // Token budget per session; 0 means none
+	retryPolicy    providers.RetryPolicy // Retries for transient provider errors
+	llmStats       *llmStats             // Provider call metrics
+	recall         *RecallIndex          // Embedding index of session messages; nil when disabled
+	recallTopK     int
+	recallMinScore float64
 }
 
+// TypingNotifier shows a "typing…" indicator on a channel chat. It is called
+// repeatedly while a message is being processed.
+type TypingNotifier func(ctx context.Context, channel, chatID string) error
+
+// typingInterval re-sends the typing indicator before platforms expire it
+// (Telegram clears it after ~5s).
+const typingInterval = 4 * time.Second
+
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string // Session identifier for history/context
-	Channel         string // Target channel for tool execution
-	ChatID          string // Target chat ID for tool execution
-	UserMessage     string // User message content (may include prefix)
-	DefaultResponse string // Response when LLM returns empty
-	EnableSummary   bool   // Whether to trigger summarization
-	SendResponse    bool   // Whether to send response via bus
+	SessionKey      string  // Session identifier for history/context
+	Channel         string  // Target channel for tool execution
+	ChatID          string  // Target chat ID for tool execution
+	UserMessage     string  // User message content (may include prefix)
+	DefaultResponse string  // Response when LLM returns empty
+	EnableSummary   bool    // Whether to trigger summarization
+	SendResponse    bool    // Whether to send response via bus
+	Model           string              // Model for this message (channel persona or default)
+	Temperature     float64             // Temperature for this message
+	Tools           *tools.ToolRegistry // Tools offered for this message (channel allow-list applied)
+	User            string              // Sender's name, for the {{user}} prompt variable
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
@@ -83,6 +108,7 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 
 	// Register spawn tool
 	subagentManager := tools.NewSubagentManager(provider, workspace, msgBus)
+	subagentManager.SetRetryPolicy(retryPolicyFrom(cfg.Agents.Defaults.LLMRetry))
 	spawnTool := tools.NewSpawnTool(subagentManager)
 	toolsRegistry.Register(spawnTool)
 
@@ -97,6 +123,15 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	)
 	toolsRegistry.Register(opsMonitorTool)
 
+	// Register git tool: reads freely, commits and checkouts go through the
+	// diff approval policy
+	toolsRegistry.Register(tools.NewGitTool(workspace, gitApprovalPolicy(cfg)))
+
+	// Register kanban tool so the agent can manage the board it reports on.
+	// The board is resolved per call; if the integration isn't running the
+	// tool reports that instead of failing registration.
+	toolsRegistry.Register(tools.NewKanbanTool(cfg.Location()))
+
 	// Register QMD memory search tool (hybrid local knowledge base search).
 	// Enable via config: tools.qmd.enabled = true, or env PICOCLAW_TOOLS_QMD_ENABLED=true.
 	// In "auto" mode the tool uses the QMD HTTP daemon when it's running and falls
@@ -104,14 +139,55 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	//   qmd mcp --http --daemon --port 8181
 	if cfg.Tools.QMD.Enabled {
 		qmdTool := tools.NewQMDTool(cfg.Tools.QMD.MCPEndpoint, cfg.Tools.QMD.Mode)
+		qmdTool.SetAutoStartDaemon(cfg.Tools.QMD.AutoStartDaemon)
 		toolsRegistry.Register(qmdTool)
 	}
 
+	// Per-tool timeout and output caps from tools.limits; unset tools keep
+	// their built-in defaults.
+	for name, l := range cfg.Tools.Limits {
+		toolsRegistry.SetLimits(name, tools.ToolLimits{
+			Timeout:        time.Duration(l.TimeoutSec) * time.Second,
+			MaxOutputBytes: l.MaxOutputBytes,
+		})
+	}
+
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
 
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
+	contextBuilder.SetPromptTemplate(cfg.Agents.Defaults.SystemPrompt, cfg.Agents.Defaults.PromptVars, cfg.Location())
+
+	if cfg.Tools.Facts.Enabled {
+		factStore, err := NewFactStore(filepath.Join(workspace, "facts.db"))
+		if err != nil {
+			logger.WarnCF("agent", "Facts memory disabled", map[string]interface{}{"error": err.Error()})
+		} else {
+			for _, tool := range tools.NewMemoryTools(factStore) {
+				toolsRegistry.Register(tool)
+			}
+			contextBuilder.SetFactStore(factStore, cfg.Tools.Facts.MaxInjected)
+		}
+	}
+
+	var auditLog *AuditLog
+	if cfg.Tools.Audit.Enabled {
+		var err error
+		auditLog, err = NewAuditLog(filepath.Join(workspace, "audit.db"), cfg.Tools.Audit.RedactKeys)
+		if err != nil {
+			logger.WarnCF("agent", "Tool audit log disabled", map[string]interface{}{"error": err.Error()})
+		}
+	}
+
+	var recallIndex *RecallIndex
+	if rc := cfg.Agents.Defaults.Recall; rc.Enabled {
+		var err error
+		recallIndex, err = NewRecallIndex(filepath.Join(workspace, "recall.db"), NewHTTPEmbedder(rc.APIBase, rc.APIKey, rc.Model))
+		if err != nil {
+			logger.WarnCF("agent", "Memory recall disabled", map[string]interface{}{"error": err.Error()})
+		}
+	}
 
 	return &AgentLoop{
 		bus:            msgBus,
@@ -119,17 +195,51 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
 		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
+		modelWindow:    cfg.Agents.Defaults.ContextWindow,
 		temperature:    cfg.Agents.Defaults.Temperature,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
 		sessions:       sessionsManager,
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
 		summarizing:    sync.Map{},
+		audit:          auditLog,
+		personas:       copyPersonas(cfg.Agents.Channels),
+		sessionBudget:  cfg.Agents.Defaults.SessionTokenBudget,
+		retryPolicy:    retryPolicyFrom(cfg.Agents.Defaults.LLMRetry),
+		llmStats:       newLLMStats(cfg.Agents.Defaults.Model),
+		recall:         recallIndex,
+		recallTopK:     cfg.Agents.Defaults.Recall.TopK,
+		recallMinScore: cfg.Agents.Defaults.Recall.MinScore,
 	}
 }
 
+// gitApprovalPolicy is the diff approval policy the git tool judges
+// commits and checkouts by: codex.DefaultPolicy with the configured codex
+// overrides, as the API applies them to diffs.
+func gitApprovalPolicy(cfg *config.Config) *codex.ApprovalPolicy {
+	policy := codex.DefaultPolicy()
+	c := cfg.Codex
+	if len(c.CriticalPaths) > 0 {
+		policy.CriticalPaths = c.CriticalPaths
+	}
+	if len(c.CriticalOps) > 0 {
+		policy.CriticalOps = make([]codex.DiffOperation, 0, len(c.CriticalOps))
+		for _, op := range c.CriticalOps {
+			policy.CriticalOps = append(policy.CriticalOps, codex.DiffOperation(op))
+		}
+	}
+	if c.MaxAutoFiles > 0 {
+		policy.MaxAutoFiles = c.MaxAutoFiles
+	}
+	if c.MaxAutoLines > 0 {
+		policy.MaxAutoLines = c.MaxAutoLines
+	}
+	return policy
+}
+
 func (al *AgentLoop) Run(ctx context.Context) error {
-	al.running.Store(true)
+	al.setRunning(true)
+	defer al.setRunning(false)
 
 	for al.running.Load() {
 		select {
@@ -141,16 +251,19 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
+			stopTyping := al.startTyping(ctx, msg)
 			response, err := al.processMessage(ctx, msg)
+			stopTyping()
 			if err != nil {
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
 
 			if response != "" {
 				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: msg.Channel,
-					ChatID:  msg.ChatID,
-					Content: response,
+					Channel:  msg.Channel,
+					ChatID:   msg.ChatID,
+					ThreadID: msg.ThreadID,
+					Content:  response,
 				})
 			}
 		}
@@ -159,8 +272,59 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 	return nil
 }
 
+// SetTypingNotifier installs the callback used to show typing indicators
+// while messages are processed. Nil disables them.
+func (al *AgentLoop) SetTypingNotifier(fn TypingNotifier) {
+	al.typing = fn
+}
+
+// startTyping keeps the typing indicator alive for msg's chat until the
+// returned stop function is called. System messages get no indicator.
+func (al *AgentLoop) startTyping(ctx context.Context, msg bus.InboundMessage) func() {
+	if al.typing == nil || msg.Channel == "system" || msg.ChatID == "" {
+		return func() {}
+	}
+
+	typingCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+		for {
+			if err := al.typing(typingCtx, msg.Channel, msg.ChatID); err != nil && typingCtx.Err() == nil {
+				logger.DebugCF("agent", "Typing indicator failed", map[string]interface{}{
+					"channel": msg.Channel,
+					"error":   err.Error(),
+				})
+			}
+			select {
+			case <-typingCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
 func (al *AgentLoop) Stop() {
-	al.running.Store(false)
+	al.setRunning(false)
+}
+
+// SetStatusHandler registers fn to be called when IsRunning changes. fn
+// must not block.
+func (al *AgentLoop) SetStatusHandler(fn func()) {
+	al.onRunning.Store(&fn)
+}
+
+// setRunning updates the running flag, telling the status handler when it
+// actually changed.
+func (al *AgentLoop) setRunning(running bool) {
+	if al.running.Swap(running) == running {
+		return
+	}
+	if fn := al.onRunning.Load(); fn != nil {
+		(*fn)()
+	}
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
@@ -186,7 +350,7 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Add message preview to log
 	preview := utils.Truncate(msg.Content, 80)
-	logger.InfoCF("agent", fmt.Sprintf("Processing message from %s:%s: %s", msg.Channel, msg.SenderID, preview),
+	logger.InfoCtx(ctx, "agent", fmt.Sprintf("Processing message from %s:%s: %s", msg.Channel, msg.SenderID, preview),
 		map[string]interface{}{
 			"channel":     msg.Channel,
 			"chat_id":     msg.ChatID,
@@ -205,6 +369,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
 		UserMessage:     msg.Content,
+		User:            senderName(msg),
 		DefaultResponse: "I've completed processing but have no response to give.",
 		EnableSummary:   true,
 		SendResponse:    false,
@@ -217,7 +382,7 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		return "", fmt.Errorf("processSystemMessage called with non-system message channel: %s", msg.Channel)
 	}
 
-	logger.InfoCF("agent", "Processing system message",
+	logger.InfoCtx(ctx, "agent", "Processing system message",
 		map[string]interface{}{
 			"sender_id": msg.SenderID,
 			"chat_id":   msg.ChatID,
@@ -252,20 +417,68 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 // runAgentLoop is the core message processing logic.
 // It handles context building, LLM calls, tool execution, and response handling.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (string, error) {
+	// 0. Wait for any other message on this session to finish
+	unlock, err := al.sessionLocks.lock(ctx, opts.SessionKey)
+	if err != nil {
+		logger.WarnCtx(ctx, "agent", "Session busy, message dropped",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"channel":     opts.Channel,
+			})
+		return "", err
+	}
+	defer unlock()
+
+	// A session over its token budget gets a refusal, not another LLM call
+	if al.budgetExceeded(opts.SessionKey) {
+		reply := al.budgetReply(opts.SessionKey)
+		logger.WarnCtx(ctx, "agent", "Session token budget exhausted, message declined",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"tokens_used": al.sessions.TokensUsed(opts.SessionKey),
+				"budget":      al.sessionBudget,
+			})
+		if opts.SendResponse {
+			al.bus.PublishOutbound(bus.OutboundMessage{
+				Channel: opts.Channel,
+				ChatID:  opts.ChatID,
+				Content: reply,
+			})
+		}
+		return reply, nil
+	}
+
 	// 1. Update tool contexts
 	al.updateToolContexts(opts.Channel, opts.ChatID)
 
+	// Channel persona: extra prompt, model/temperature overrides and the
+	// tools this channel may use
+	p := al.personaFor(opts.Channel)
+	opts.Model, opts.Temperature = p.model, p.temperature
+	opts.Tools = al.tools
+	if p.tools != nil {
+		opts.Tools = al.tools.Filtered(p.allowsTool)
+	}
+
 	// 2. Build messages
 	history := al.sessions.GetHistory(opts.SessionKey)
 	summary := al.sessions.GetSummary(opts.SessionKey)
-	messages := al.contextBuilder.BuildMessages(
+	messages := al.contextBuilder.BuildMessagesWithTools(
+		opts.Tools,
 		history,
 		summary,
 		opts.UserMessage,
 		nil,
 		opts.Channel,
 		opts.ChatID,
+		opts.User,
 	)
+	if p.prompt != "" {
+		persona := renderPrompt(p.prompt, al.contextBuilder.templateVars(opts.Channel, opts.ChatID, opts.User))
+		messages[0].Content += "\n\n---\n\n# Channel Persona\n\n" + persona
+	}
+	messages[0].Content += al.recallSection(ctx, opts.SessionKey, opts.UserMessage, history)
+	messages = al.fitContextWindow(messages, opts.SessionKey)
 
 	// 3. Save user message to session
 	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
@@ -284,6 +497,9 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	// 6. Save final assistant message to session
 	al.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
 	al.sessions.Save(al.sessions.GetOrCreate(opts.SessionKey))
+	al.indexTurn(opts.SessionKey,
+		providers.Message{Role: "user", Content: opts.UserMessage},
+		providers.Message{Role: "assistant", Content: finalContent})
 
 	// 7. Optional: summarization
 	if opts.EnableSummary {
@@ -301,7 +517,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 
 	// 9. Log response
 	responsePreview := utils.Truncate(finalContent, 120)
-	logger.InfoCF("agent", fmt.Sprintf("Response: %s", responsePreview),
+	logger.InfoCtx(ctx, "agent", fmt.Sprintf("Response: %s", responsePreview),
 		map[string]interface{}{
 			"session_key":  opts.SessionKey,
 			"iterations":   iteration,
@@ -320,14 +536,14 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 	for iteration < al.maxIterations {
 		iteration++
 
-		logger.DebugCF("agent", "LLM iteration",
+		logger.DebugCtx(ctx, "agent", "LLM iteration",
 			map[string]interface{}{
 				"iteration": iteration,
 				"max":       al.maxIterations,
 			})
 
 		// Build tool definitions
-		toolDefs := al.tools.GetDefinitions()
+		toolDefs := opts.Tools.GetDefinitions()
 		providerToolDefs := make([]providers.ToolDefinition, 0, len(toolDefs))
 		for _, td := range toolDefs {
 			providerToolDefs = append(providerToolDefs, providers.ToolDefinition{
@@ -341,19 +557,19 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		}
 
 		// Log LLM request details
-		logger.DebugCF("agent", "LLM request",
+		logger.DebugCtx(ctx, "agent", "LLM request",
 			map[string]interface{}{
 				"iteration":         iteration,
-				"model":             al.model,
+				"model":             opts.Model,
 				"messages_count":    len(messages),
 				"tools_count":       len(providerToolDefs),
 				"max_tokens":        al.contextWindow,
-				"temperature":       al.temperature,
+				"temperature":       opts.Temperature,
 				"system_prompt_len": len(messages[0].Content),
 			})
 
 		// Log full messages (detailed)
-		logger.DebugCF("agent", "Full LLM request",
+		logger.DebugCtx(ctx, "agent", "Full LLM request",
 			map[string]interface{}{
 				"iteration":     iteration,
 				"messages_json": formatMessagesForLog(messages),
@@ -361,24 +577,25 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Call LLM
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
+		response, err := al.chat(ctx, messages, providerToolDefs, opts.Model, map[string]interface{}{
 			"max_tokens":  al.contextWindow,
-			"temperature": al.temperature,
+			"temperature": opts.Temperature,
 		})
 
 		if err != nil {
-			logger.ErrorCF("agent", "LLM call failed",
+			logger.ErrorCtx(ctx, "agent", "LLM call failed",
 				map[string]interface{}{
 					"iteration": iteration,
 					"error":     err.Error(),
 				})
 			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
 		}
+		al.recordUsage(opts.SessionKey, response.Usage)
 
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
-			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
+			logger.InfoCtx(ctx, "agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
 					"iteration":     iteration,
 					"content_chars": len(finalContent),
@@ -386,12 +603,23 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			break
 		}
 
+		// Stop a tool loop once it has spent the session's budget
+		if al.budgetExceeded(opts.SessionKey) {
+			logger.WarnCtx(ctx, "agent", "Session token budget exhausted, stopping tool loop",
+				map[string]interface{}{
+					"session_key": opts.SessionKey,
+					"iteration":   iteration,
+				})
+			finalContent = al.budgetReply(opts.SessionKey)
+			break
+		}
+
 		// Log tool calls
 		toolNames := make([]string, 0, len(response.ToolCalls))
 		for _, tc := range response.ToolCalls {
 			toolNames = append(toolNames, tc.Name)
 		}
-		logger.InfoCF("agent", "LLM requested tool calls",
+		logger.InfoCtx(ctx, "agent", "LLM requested tool calls",
 			map[string]interface{}{
 				"tools":     toolNames,
 				"count":     len(toolNames),
@@ -424,16 +652,31 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			// Log tool call with arguments preview
 			argsJSON, _ := json.Marshal(tc.Arguments)
 			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+			logger.InfoCtx(ctx, "agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
 				map[string]interface{}{
 					"tool":      tc.Name,
 					"iteration": iteration,
 				})
 
-			result, err := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID)
+			started := time.Now()
+			var result string
+			var err error
+			if _, offered := opts.Tools.Get(tc.Name); !offered {
+				if _, exists := al.tools.Get(tc.Name); exists {
+					err = fmt.Errorf("tool '%s' is not allowed on channel %s", tc.Name, opts.Channel)
+					logger.WarnCtx(ctx, "agent", "Rejected disallowed tool call", map[string]interface{}{
+						"tool":    tc.Name,
+						"channel": opts.Channel,
+					})
+				}
+			}
+			if err == nil {
+				result, err = opts.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID)
+			}
 			if err != nil {
 				result = fmt.Sprintf("Error: %v", err)
 			}
+			al.recordToolCall(opts, tc.Name, tc.Arguments, result, time.Since(started), err)
 
 			toolResultMsg := providers.Message{
 				Role:       "tool",
@@ -450,6 +693,32 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 	return finalContent, iteration, nil
 }
 
+// recordToolCall writes one tool invocation to the audit log, if enabled.
+func (al *AgentLoop) recordToolCall(opts processOptions, name string, args map[string]interface{}, result string, elapsed time.Duration, err error) {
+	if al.audit == nil {
+		return
+	}
+	entry := AuditEntry{
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		Tool:       name,
+		Args:       args,
+		Result:     result,
+		DurationMS: elapsed.Milliseconds(),
+		Success:    err == nil,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if aerr := al.audit.Record(entry); aerr != nil {
+		logger.WarnCF("agent", "Failed to write tool audit entry", map[string]interface{}{
+			"tool":  name,
+			"error": aerr.Error(),
+		})
+	}
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	if tool, ok := al.tools.Get("message"); ok {
@@ -507,11 +776,21 @@ func (al *AgentLoop) GetToolRegistry() *tools.ToolRegistry {
 	return al.tools
 }
 
+// GetAuditLog returns the tool-call audit log, or nil when disabled.
+func (al *AgentLoop) GetAuditLog() *AuditLog {
+	return al.audit
+}
+
 // GetModel returns the active model name.
 func (al *AgentLoop) GetModel() string {
 	return al.model
 }
 
+// GetSessionTokenBudget returns the per-session token budget, 0 if none.
+func (al *AgentLoop) GetSessionTokenBudget() int64 {
+	return al.sessionBudget
+}
+
 // GetWorkspace returns the workspace path.
 func (al *AgentLoop) GetWorkspace() string {
 	return al.workspace
@@ -624,7 +903,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 		// Merge them
 		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
-		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
+		resp, err := al.chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
 			"max_tokens":  1024,
 			"temperature": 0.3,
 		})
@@ -642,8 +921,17 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	}
 
 	if finalSummary != "" {
+		unlock, err := al.sessionLocks.lock(ctx, sessionKey)
+		if err != nil {
+			return
+		}
+		defer unlock()
+
+		// Turns that finished while we were summarizing aren't covered by
+		// the summary, so keep their messages as well
+		keep := 4 + len(al.sessions.GetHistory(sessionKey)) - len(history)
 		al.sessions.SetSummary(sessionKey, finalSummary)
-		al.sessions.TruncateHistory(sessionKey, 4)
+		al.sessions.TruncateHistory(sessionKey, keep)
 		al.sessions.Save(al.sessions.GetOrCreate(sessionKey))
 	}
 }
@@ -659,7 +947,7 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 		prompt += fmt.Sprintf("%s: %s\n", m.Role, m.Content)
 	}
 
-	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
+	response, err := al.chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
 		"max_tokens":  1024,
 		"temperature": 0.3,
 	})
@@ -673,7 +961,7 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
 	total := 0
 	for _, m := range messages {
-		total += len(m.Content) / 4 // Simple heuristic: 4 chars per token
+		total += messageTokens(m) // Simple heuristic: 4 chars per token
 	}
 	return total
 }
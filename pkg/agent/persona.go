@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"github.com/sipeed/picoclaw/pkg/channels/templates"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// persona is the prompt addition, model settings and tool allow-list for
+// one message.
+type persona struct {
+	prompt      string
+	model       string
+	temperature float64
+	tools       map[string]bool // nil allows every tool
+}
+
+// allowsTool reports whether the persona may use the named tool.
+func (p persona) allowsTool(name string) bool {
+	return p.tools == nil || p.tools[name]
+}
+
+// personaFor resolves the persona for a channel from agents.channels,
+// falling back to the agent defaults. A template is looked up on each call
+// so templates loaded after startup are picked up.
+func (al *AgentLoop) personaFor(channel string) persona {
+	p := persona{model: al.model, temperature: al.temperature}
+	cp, ok := al.personas[channel]
+	if !ok {
+		return p
+	}
+
+	var tmpl *templates.BotTemplate
+	if cp.Template != "" {
+		found := false
+		if tmpl, found = templates.Global().Get(cp.Template); !found {
+			logger.WarnCF("agent", "Persona template not found", map[string]interface{}{
+				"channel":  channel,
+				"template": cp.Template,
+			})
+		}
+	}
+
+	p.prompt = cp.SystemPrompt
+	if p.prompt == "" && tmpl != nil {
+		p.prompt = tmpl.Soul
+	}
+	allowed := cp.Tools
+	if len(allowed) == 0 && tmpl != nil {
+		allowed = tmpl.Tools
+	}
+	if len(allowed) > 0 {
+		p.tools = make(map[string]bool, len(allowed))
+		for _, name := range allowed {
+			p.tools[name] = true
+		}
+	}
+	if cp.Model != "" {
+		p.model = cp.Model
+	}
+	if cp.Temperature != nil {
+		p.temperature = *cp.Temperature
+	}
+	return p
+}
+
+// copyPersonas detaches the loop's persona table from the live config.
+func copyPersonas(in map[string]config.ChannelPersona) map[string]config.ChannelPersona {
+	out := make(map[string]config.ChannelPersona, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
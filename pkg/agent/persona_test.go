@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/channels/templates"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPersonaFor(t *testing.T) {
+	templates.Global().Register(&templates.BotTemplate{Name: "persona-test-terse", Soul: "Be terse."})
+	cold := 0.1
+	al := &AgentLoop{
+		model:       "default-model",
+		temperature: 0.7,
+		personas: map[string]config.ChannelPersona{
+			"slack":    {SystemPrompt: "Be formal.", Model: "big-model"},
+			"telegram": {Template: "persona-test-terse", Temperature: &cold},
+		},
+	}
+
+	if p := al.personaFor("discord"); p.prompt != "" || p.model != "default-model" || p.temperature != 0.7 {
+		t.Errorf("discord persona = %+v, want defaults", p)
+	}
+	if p := al.personaFor("slack"); p.prompt != "Be formal." || p.model != "big-model" || p.temperature != 0.7 {
+		t.Errorf("slack persona = %+v", p)
+	}
+	if p := al.personaFor("telegram"); p.prompt != "Be terse." || p.model != "default-model" || p.temperature != 0.1 {
+		t.Errorf("telegram persona = %+v", p)
+	}
+}
+
+func TestPersonaToolAllowList(t *testing.T) {
+	templates.Global().Register(&templates.BotTemplate{Name: "persona-test-public", Tools: []string{"web_search"}})
+	al := &AgentLoop{personas: map[string]config.ChannelPersona{
+		"discord":  {Template: "persona-test-public"},
+		"telegram": {Template: "persona-test-public", Tools: []string{"exec", "read_file"}},
+	}}
+
+	if p := al.personaFor("cli"); !p.allowsTool("exec") {
+		t.Error("cli: exec should be allowed without an allow-list")
+	}
+	if p := al.personaFor("discord"); p.allowsTool("exec") || !p.allowsTool("web_search") {
+		t.Errorf("discord: template allow-list not applied: %v", p.tools)
+	}
+	if p := al.personaFor("telegram"); !p.allowsTool("exec") || p.allowsTool("web_search") {
+		t.Errorf("telegram: explicit tools should override the template: %v", p.tools)
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// promptVarPattern matches a {{name}} placeholder, spaces allowed inside
+// the braces.
+var promptVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// senderNameKeys are the metadata keys channels put a sender's name under,
+// most readable first.
+var senderNameKeys = []string{"display_name", "sender_name", "first_name", "user_name", "username"}
+
+// SetPromptTemplate replaces the built-in identity at the top of the system
+// prompt with tmpl, interpolated on every request (see promptVars). vars
+// are custom variables; loc is the zone for {{date}} and {{time}}.
+func (cb *ContextBuilder) SetPromptTemplate(tmpl string, vars map[string]string, loc *time.Location) {
+	cb.promptTemplate = tmpl
+	cb.promptVars = vars
+	cb.loc = loc
+}
+
+// now is the current time in the configured zone.
+func (cb *ContextBuilder) now() time.Time {
+	if cb.loc == nil {
+		return time.Now()
+	}
+	return time.Now().In(cb.loc)
+}
+
+// templateVars returns the variables for one request: the custom ones from
+// config, overridden by the built-ins that have a value (date, time,
+// weekday, timezone, workspace, channel, chat_id and user).
+func (cb *ContextBuilder) templateVars(channel, chatID, user string) map[string]string {
+	vars := make(map[string]string, len(cb.promptVars)+8)
+	for k, v := range cb.promptVars {
+		vars[k] = v
+	}
+	now := cb.now()
+	workspacePath, _ := filepath.Abs(cb.workspace)
+	for k, v := range map[string]string{
+		"date":      now.Format("2006-01-02"),
+		"time":      now.Format("15:04"),
+		"weekday":   now.Weekday().String(),
+		"timezone":  now.Location().String(),
+		"workspace": workspacePath,
+		"channel":   channel,
+		"chat_id":   chatID,
+		"user":      user,
+	} {
+		if v != "" {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// renderPrompt replaces each {{name}} in tmpl with vars[name]. Unknown
+// names render as empty.
+func renderPrompt(tmpl string, vars map[string]string) string {
+	return promptVarPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return vars[promptVarPattern.FindStringSubmatch(m)[1]]
+	})
+}
+
+// senderName is the most readable name the channel gave for a message's
+// sender, else the sender ID.
+func senderName(msg bus.InboundMessage) string {
+	for _, key := range senderNameKeys {
+		if name := msg.Metadata[key]; name != "" {
+			return name
+		}
+	}
+	return msg.SenderID
+}
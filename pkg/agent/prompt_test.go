@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	vars := map[string]string{"user": "Ada", "team": "infra"}
+	got := renderPrompt("Hi {{user}} of {{ team }}.{{missing}} Literal {x}.", vars)
+	if want := "Hi Ada of infra. Literal {x}."; got != want {
+		t.Errorf("renderPrompt = %q, want %q", got, want)
+	}
+}
+
+func TestPromptTemplateInterpolatedPerRequest(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	cb := NewContextBuilder(t.TempDir())
+	cb.SetPromptTemplate("You help {{user}} on {{channel}}. Today is {{date}}. Team: {{team}}.{{nope}}",
+		map[string]string{"team": "infra", "user": "operator"}, tokyo)
+
+	msgs := cb.BuildMessagesWithTools(nil, nil, "", "hello", nil, "telegram", "42", "Ada")
+	prompt := msgs[0].Content
+	today := time.Now().In(tokyo).Format("2006-01-02")
+	if want := "You help Ada on telegram. Today is " + today + ". Team: infra."; !strings.HasPrefix(prompt, want) {
+		t.Errorf("prompt starts %q, want %q", prompt[:min(len(prompt), 80)], want)
+	}
+	if strings.Contains(prompt, "{{") || strings.Contains(prompt, "You are picoclaw") {
+		t.Errorf("template not applied cleanly: %q", prompt)
+	}
+
+	// No sender: {{user}} falls back to the configured variable.
+	msgs = cb.BuildMessagesWithTools(nil, nil, "", "hello", nil, "cli", "direct", "")
+	if !strings.HasPrefix(msgs[0].Content, "You help operator on cli.") {
+		t.Errorf("fallback prompt = %q", msgs[0].Content)
+	}
+}
+
+func TestSenderName(t *testing.T) {
+	msg := bus.InboundMessage{SenderID: "123", Metadata: map[string]string{"username": "ada_l", "first_name": "Ada"}}
+	if got := senderName(msg); got != "Ada" {
+		t.Errorf("senderName = %q, want Ada", got)
+	}
+	if got := senderName(bus.InboundMessage{SenderID: "123"}); got != "123" {
+		t.Errorf("senderName without metadata = %q, want the sender ID", got)
+	}
+}
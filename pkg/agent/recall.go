@@ -0,0 +1,321 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// recallTimeout bounds embedding the incoming message and searching the
+// index, so a slow embedding backend delays a reply only this long.
+const recallTimeout = 5 * time.Second
+
+// recallSnippetChars caps each recalled message in the system prompt.
+const recallSnippetChars = 500
+
+// Embedder turns texts into vectors, one per text, for the recall index.
+// All vectors from one embedder must have the same dimension.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint: OpenAI
+// itself, or a local server such as Ollama, vLLM or LM Studio.
+type HTTPEmbedder struct {
+	apiBase    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewHTTPEmbedder creates an HTTPEmbedder for apiBase (e.g.
+// "https://api.openai.com/v1"). apiKey may be empty for local servers.
+func NewHTTPEmbedder(apiBase, apiKey, model string) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.apiBase+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embeddings API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d texts", len(out.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings API returned index %d for %d texts", d.Index, len(texts))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// RecalledMessage is an earlier session message returned by a recall
+// search, with its cosine similarity to the query.
+type RecalledMessage struct {
+	Role    string
+	Content string
+	At      time.Time
+	Score   float64
+}
+
+// RecallIndex is the SQLite-backed embedding index of session messages
+// (workspace/recall.db). It outlives history truncation, so messages that
+// were summarized away can still be pulled back in by similarity.
+type RecallIndex struct {
+	db       *sql.DB
+	embedder Embedder
+}
+
+// NewRecallIndex opens (or creates) the recall database at path.
+func NewRecallIndex(path string, embedder Embedder) (*RecallIndex, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open recall db: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS recall (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_key TEXT NOT NULL,
+		role TEXT NOT NULL,
+		content TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_recall_session ON recall(session_key);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init recall schema: %w", err)
+	}
+	return &RecallIndex{db: db, embedder: embedder}, nil
+}
+
+// Add embeds and stores a session's user and assistant messages. Tool
+// traffic and empty messages are skipped.
+func (r *RecallIndex) Add(ctx context.Context, sessionKey string, messages []providers.Message) error {
+	var kept []providers.Message
+	var texts []string
+	for _, m := range messages {
+		if (m.Role == "user" || m.Role == "assistant") && strings.TrimSpace(m.Content) != "" {
+			kept = append(kept, m)
+			texts = append(texts, m.Content)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	vectors, err := r.embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, m := range kept {
+		_, err := tx.Exec(`INSERT INTO recall (session_key, role, content, vector, created_at) VALUES (?, ?, ?, ?, ?)`,
+			sessionKey, m.Role, m.Content, encodeVector(vectors[i]), now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Search returns up to k of a session's indexed messages most similar to
+// query, best first, scoring at least minScore. Messages whose content is
+// in exclude (typically the history already being sent) are skipped.
+func (r *RecallIndex) Search(ctx context.Context, sessionKey, query string, k int, minScore float64, exclude []providers.Message) ([]RecalledMessage, error) {
+	if k <= 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+	}
+	q := vectors[0]
+
+	skip := make(map[string]bool, len(exclude))
+	for _, m := range exclude {
+		skip[m.Role+"\x00"+m.Content] = true
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT role, content, vector, created_at FROM recall WHERE session_key = ?", sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []RecalledMessage
+	for rows.Next() {
+		var m RecalledMessage
+		var vector []byte
+		var at string
+		if err := rows.Scan(&m.Role, &m.Content, &vector, &at); err != nil {
+			return nil, err
+		}
+		if skip[m.Role+"\x00"+m.Content] {
+			continue
+		}
+		m.Score = cosine(q, decodeVector(vector))
+		if m.Score < minScore {
+			continue
+		}
+		m.At, _ = time.Parse(time.RFC3339Nano, at)
+		hits = append(hits, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Close closes the database.
+func (r *RecallIndex) Close() error {
+	return r.db.Close()
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+// cosine is the cosine similarity of a and b; 0 when their dimensions
+// differ (e.g. after switching embedding models) or either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// recallSection returns the system prompt section of earlier messages in
+// the session relevant to userMessage, leaving out those already in
+// history. When recall is off or the embedder fails it is empty, and the
+// request carries recent history only.
+func (al *AgentLoop) recallSection(ctx context.Context, sessionKey, userMessage string, history []providers.Message) string {
+	if al.recall == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, recallTimeout)
+	defer cancel()
+	hits, err := al.recall.Search(ctx, sessionKey, userMessage, al.recallTopK, al.recallMinScore, history)
+	if err != nil {
+		logger.WarnCF("agent", "Memory recall failed, using recent history only",
+			map[string]interface{}{
+				"session_key": sessionKey,
+				"error":       err.Error(),
+			})
+		return ""
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## Relevant Earlier Messages\nFrom earlier in this conversation, most relevant first:\n")
+	for _, h := range hits {
+		fmt.Fprintf(&sb, "- [%s, %s] %s\n", h.Role, h.At.Format("2006-01-02"),
+			utils.Truncate(strings.Join(strings.Fields(h.Content), " "), recallSnippetChars))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// indexTurn adds a finished turn to the recall index in the background.
+func (al *AgentLoop) indexTurn(sessionKey string, messages ...providers.Message) {
+	if al.recall == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := al.recall.Add(ctx, sessionKey, messages); err != nil {
+			logger.WarnCF("agent", "Failed to index messages for recall",
+				map[string]interface{}{
+					"session_key": sessionKey,
+					"error":       err.Error(),
+				})
+		}
+	}()
+}
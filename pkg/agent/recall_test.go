@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// wordEmbedder embeds a text as counts of a fixed vocabulary, so texts
+// sharing words are similar.
+type wordEmbedder struct {
+	vocab []string
+	err   error
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.vocab))
+		for j, word := range e.vocab {
+			vectors[i][j] = float32(strings.Count(strings.ToLower(text), word))
+		}
+	}
+	return vectors, nil
+}
+
+func TestRecallIndex(t *testing.T) {
+	embedder := &wordEmbedder{vocab: []string{"garden", "tomato", "invoice", "tax", "deadline"}}
+	idx, err := NewRecallIndex(filepath.Join(t.TempDir(), "recall.db"), embedder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	ctx := context.Background()
+	err = idx.Add(ctx, "s1", []providers.Message{
+		{Role: "user", Content: "My tomato plants in the garden are wilting"},
+		{Role: "assistant", Content: "Water the tomato garden in the morning."},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "exec"}}},
+		{Role: "tool", ToolCallID: "c1", Content: "tomato tomato tomato"},
+		{Role: "user", Content: "When is the tax invoice deadline?"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add(ctx, "s2", []providers.Message{{Role: "user", Content: "garden tomato"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := idx.Search(ctx, "s1", "how do I save my tomato garden?", 5, 0.5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || !strings.Contains(hits[0].Content, "tomato") || hits[0].Score < hits[1].Score {
+		t.Fatalf("hits = %+v, want the two garden messages of s1, best first", hits)
+	}
+
+	// Messages already in the request's history aren't recalled again.
+	history := []providers.Message{{Role: "user", Content: "My tomato plants in the garden are wilting"}}
+	hits, _ = idx.Search(ctx, "s1", "tomato garden", 5, 0.5, history)
+	if len(hits) != 1 || hits[0].Role != "assistant" {
+		t.Errorf("hits excluding history = %+v", hits)
+	}
+
+	al := &AgentLoop{recall: idx, recallTopK: 1, recallMinScore: 0.5}
+	section := al.recallSection(ctx, "s1", "tax deadline", nil)
+	if !strings.Contains(section, "## Relevant Earlier Messages") || !strings.Contains(section, "invoice deadline") {
+		t.Errorf("recall section = %q", section)
+	}
+
+	// A failing embedder falls back to recent history only.
+	embedder.err = errors.New("connection refused")
+	if section := al.recallSection(ctx, "s1", "tax deadline", nil); section != "" {
+		t.Errorf("recall section with a failing embedder = %q, want empty", section)
+	}
+	if section := (&AgentLoop{}).recallSection(ctx, "s1", "tax deadline", nil); section != "" {
+		t.Errorf("recall section with recall off = %q", section)
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" || req.Model != "embed-small" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Out of order, as the API allows
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	vectors, err := NewHTTPEmbedder(srv.URL+"/v1/", "key", "embed-small").Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+
+	if _, err := NewHTTPEmbedder(srv.URL, "wrong", "embed-small").Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("want an error for a failed request")
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	providerdomain "github.com/sipeed/picoclaw/pkg/domain/provider"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// llmStats counts the agent's calls to its provider. Only the aggregate's
+// Metrics are used.
+type llmStats struct {
+	provider *providerdomain.Provider
+	mu       sync.Mutex
+}
+
+func newLLMStats(model string) *llmStats {
+	return &llmStats{provider: providerdomain.NewProvider(model, "", providerdomain.ProviderConfig{Model: model})}
+}
+
+// retryPolicyFrom builds the chat retry policy from agents.defaults.llm_retry;
+// unset delays fall back to the defaults.
+func retryPolicyFrom(cfg config.LLMRetryConfig) providers.RetryPolicy {
+	policy := providers.DefaultRetryPolicy
+	policy.MaxRetries = cfg.MaxRetries
+	if cfg.BaseDelayMs > 0 {
+		policy.BaseDelay = time.Duration(cfg.BaseDelayMs) * time.Millisecond
+	}
+	if cfg.MaxDelaySec > 0 {
+		policy.MaxDelay = time.Duration(cfg.MaxDelaySec) * time.Second
+	}
+	return policy
+}
+
+// chat calls the provider, retrying transient failures (429, 503, dropped
+// connections) with backoff, and records the outcome in the provider
+// metrics.
+func (al *AgentLoop) chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	start := time.Now()
+	resp, err := providers.ChatWithRetry(ctx, al.provider, al.retryPolicy, func(attempt int, err error, wait time.Duration) {
+		al.llmStats.retry(err)
+		logger.WarnCtx(ctx, "agent", "LLM call failed, retrying", map[string]interface{}{
+			"attempt": attempt,
+			"wait_ms": wait.Milliseconds(),
+			"error":   err.Error(),
+		})
+	}, messages, defs, model, options)
+	al.llmStats.record(resp, err, time.Since(start))
+	return resp, err
+}
+
+// GetProviderMetrics returns the agent's provider call statistics,
+// including how many calls were retried.
+func (al *AgentLoop) GetProviderMetrics() providerdomain.ProviderMetrics {
+	return al.llmStats.metrics()
+}
+
+func (s *llmStats) retry(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provider.RecordRetry(err.Error())
+}
+
+func (s *llmStats) record(resp *providers.LLMResponse, err error, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.provider.RecordError(err.Error())
+		return
+	}
+	var prompt, completion int
+	if resp != nil && resp.Usage != nil {
+		prompt, completion = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	}
+	s.provider.RecordRequest(prompt, completion, elapsed.Milliseconds())
+}
+
+func (s *llmStats) metrics() providerdomain.ProviderMetrics {
+	if s == nil {
+		return providerdomain.NewProviderMetrics()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.provider.Metrics
+}
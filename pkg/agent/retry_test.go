@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// overloadedOnce answers 503 on its first call.
+type overloadedOnce struct{ stubProvider }
+
+func (p *overloadedOnce) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if p.calls == 0 {
+		p.calls++
+		return nil, &providers.StatusError{StatusCode: 503, Body: "overloaded"}
+	}
+	return p.stubProvider.Chat(ctx, messages, defs, model, options)
+}
+
+func TestChatRetriesAndRecordsMetrics(t *testing.T) {
+	p := &overloadedOnce{stubProvider{reply: "hi", usage: &providers.UsageInfo{PromptTokens: 10, CompletionTokens: 2}}}
+	al := &AgentLoop{
+		provider:    p,
+		retryPolicy: retryPolicyFrom(config.LLMRetryConfig{MaxRetries: 2, BaseDelayMs: 1, MaxDelaySec: 1}),
+		llmStats:    newLLMStats("test-model"),
+	}
+
+	resp, err := al.chat(context.Background(), nil, nil, "test-model", nil)
+	if err != nil || resp.Content != "hi" {
+		t.Fatalf("chat = %v, %v; want the reply after one retry", resp, err)
+	}
+	m := al.GetProviderMetrics()
+	if m.RetryCount != 1 || m.RequestCount != 1 || m.ErrorCount != 0 || m.PromptTokens != 10 {
+		t.Errorf("metrics = %+v", m)
+	}
+
+	al.provider = &stubProvider{err: &providers.StatusError{StatusCode: 401, Body: "bad key"}}
+	start := time.Now()
+	if _, err := al.chat(context.Background(), nil, nil, "test-model", nil); err == nil {
+		t.Fatal("auth failure should not be retried into a success")
+	}
+	if m := al.GetProviderMetrics(); m.RetryCount != 1 || m.ErrorCount != 1 {
+		t.Errorf("after auth failure: metrics = %+v", m)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("auth failure should fail without waiting")
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSessionBusy is returned when a message could not start because another
+// message on the same session was still being processed when ctx ended.
+var ErrSessionBusy = errors.New("session is busy with another message")
+
+// sessionLocks serializes work on each session key. A turn reads the
+// history, runs for as long as the LLM takes, then appends to it; two turns
+// interleaving on one session would each build on a stale history and save
+// over the other's messages. Different sessions don't contend.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// sessionLock is one session's mutex, dropped once nobody holds or waits
+// on it.
+type sessionLock struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits for exclusive use of key, giving up with ErrSessionBusy when
+// ctx ends first. The returned unlock must be called exactly once.
+func (s *sessionLocks) lock(ctx context.Context, key string) (func(), error) {
+	l := s.ref(key)
+
+	select {
+	case l.held <- struct{}{}:
+	default:
+		select {
+		case l.held <- struct{}{}:
+		case <-ctx.Done():
+			s.unref(key)
+			return nil, fmt.Errorf("%w: %v", ErrSessionBusy, ctx.Err())
+		}
+	}
+
+	return func() {
+		<-l.held
+		s.unref(key)
+	}, nil
+}
+
+func (s *sessionLocks) ref(key string) *sessionLock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks == nil {
+		s.locks = make(map[string]*sessionLock)
+	}
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sessionLock{held: make(chan struct{}, 1)}
+		s.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (s *sessionLocks) unref(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.locks[key]; ok {
+		l.refs--
+		if l.refs <= 0 {
+			delete(s.locks, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionLocksSerializeSameKey(t *testing.T) {
+	var s sessionLocks
+	unlock, err := s.lock(context.Background(), "web:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another session isn't held up
+	other, err := s.lock(context.Background(), "web:b")
+	if err != nil {
+		t.Fatalf("different session blocked: %v", err)
+	}
+	other()
+
+	acquired := make(chan func())
+	go func() {
+		next, err := s.lock(context.Background(), "web:a")
+		if err != nil {
+			t.Error(err)
+			close(acquired)
+			return
+		}
+		acquired <- next
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second message on the same session ran concurrently")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("waiter never got the session")
+	}
+
+	if len(s.locks) != 0 {
+		t.Errorf("locks left behind: %v", s.locks)
+	}
+}
+
+func TestSessionLocksGiveUpWithContext(t *testing.T) {
+	var s sessionLocks
+	unlock, err := s.lock(context.Background(), "web:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.lock(ctx, "web:a"); !errors.Is(err, ErrSessionBusy) {
+		t.Fatalf("err = %v, want ErrSessionBusy", err)
+	}
+	if got := s.locks["web:a"].refs; got != 1 {
+		t.Errorf("refs = %d after giving up, want 1", got)
+	}
+}
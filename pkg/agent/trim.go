@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// messageTokens estimates one message's tokens: its text plus any tool
+// calls it carries, at ~4 characters per token.
+func messageTokens(m providers.Message) int {
+	chars := len(m.Content)
+	for _, tc := range m.ToolCalls {
+		chars += len(tc.Name)
+		if tc.Function != nil {
+			chars += len(tc.Function.Name) + len(tc.Function.Arguments)
+		}
+		for k, v := range tc.Arguments {
+			if s, ok := v.(string); ok {
+				chars += len(k) + len(s)
+			} else {
+				chars += len(k) + 8
+			}
+		}
+	}
+	return chars / 4
+}
+
+// trimHistory drops the oldest history messages from a request built by
+// BuildMessagesWithTools (system prompt, history, current message) until
+// it fits in window tokens with reserve left for the reply. The system
+// prompt, which carries the session summary, and the current message are
+// always kept. The kept history never starts on a tool result whose call
+// was dropped, so a call and its results stay together. window <= 0
+// disables trimming. Returns the messages and how many were dropped.
+func trimHistory(messages []providers.Message, window, reserve int) ([]providers.Message, int) {
+	if window <= 0 || len(messages) < 3 {
+		return messages, 0
+	}
+	history := messages[1 : len(messages)-1]
+	budget := window - reserve - messageTokens(messages[0]) - messageTokens(messages[len(messages)-1])
+
+	start := len(history)
+	for start > 0 && budget-messageTokens(history[start-1]) >= 0 {
+		budget -= messageTokens(history[start-1])
+		start--
+	}
+	if start == 0 {
+		return messages, 0
+	}
+	for start < len(history) && history[start].Role == "tool" {
+		start++
+	}
+
+	trimmed := make([]providers.Message, 0, len(messages)-start)
+	trimmed = append(trimmed, messages[0])
+	trimmed = append(trimmed, history[start:]...)
+	trimmed = append(trimmed, messages[len(messages)-1])
+	return trimmed, start
+}
+
+// fitContextWindow trims a request's history to the model's context window,
+// keeping room for a max_tokens reply.
+func (al *AgentLoop) fitContextWindow(messages []providers.Message, sessionKey string) []providers.Message {
+	reserve := al.contextWindow
+	if reserve >= al.modelWindow {
+		reserve = 0
+	}
+	trimmed, dropped := trimHistory(messages, al.modelWindow, reserve)
+	if dropped > 0 {
+		logger.InfoCF("agent", "History trimmed to fit context window",
+			map[string]interface{}{
+				"session_key":    sessionKey,
+				"dropped":        dropped,
+				"kept":           len(trimmed) - 2,
+				"context_window": al.modelWindow,
+			})
+	}
+	return trimmed
+}
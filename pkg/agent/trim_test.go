@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestTrimHistory(t *testing.T) {
+	text := func(n int) string { return strings.Repeat("x", n*4) } // n tokens
+	messages := []providers.Message{
+		{Role: "system", Content: text(100)},
+		{Role: "user", Content: text(50)},
+		{Role: "assistant", Content: text(50)},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "read_file"}}},
+		{Role: "tool", ToolCallID: "c1", Content: text(30)},
+		{Role: "tool", ToolCallID: "c1", Content: text(30)},
+		{Role: "assistant", Content: text(20)},
+		{Role: "user", Content: text(10)},
+	}
+
+	if got, dropped := trimHistory(messages, 0, 0); dropped != 0 || len(got) != len(messages) {
+		t.Errorf("window 0 dropped %d", dropped)
+	}
+	if got, dropped := trimHistory(messages, 1000, 0); dropped != 0 || len(got) != len(messages) {
+		t.Errorf("fitting request dropped %d", dropped)
+	}
+
+	// 100 + 10 fixed leaves 70: the last tool result and the reply fit, but
+	// the result's call doesn't, so the result goes too.
+	got, dropped := trimHistory(messages, 200, 20)
+	if dropped != 5 || len(got) != 3 {
+		t.Fatalf("trimHistory = %d messages, dropped %d; want 3, 5", len(got), dropped)
+	}
+	if got[0].Role != "system" || got[1].Content != text(20) || got[2].Content != text(10) {
+		t.Errorf("kept %+v", got)
+	}
+
+	// Room for the whole tool sequence keeps the call with its results.
+	got, dropped = trimHistory(messages, 200, 0)
+	if dropped != 2 || got[1].Role != "assistant" || len(got[1].ToolCalls) != 1 {
+		t.Errorf("dropped %d, first kept %+v; want the tool call", dropped, got[1])
+	}
+
+	// Nothing fits: only the system prompt and current message remain.
+	got, _ = trimHistory(messages, 100, 0)
+	if len(got) != 2 || got[0].Role != "system" || got[1].Content != text(10) {
+		t.Errorf("over-budget request = %+v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// typingCall is one invocation of a fake TypingNotifier.
+type typingCall struct {
+	ctx             context.Context
+	channel, chatID string
+}
+
+func TestStartTyping(t *testing.T) {
+	calls := make(chan typingCall, 8)
+	al := &AgentLoop{}
+	al.SetTypingNotifier(func(ctx context.Context, channel, chatID string) error {
+		calls <- typingCall{ctx, channel, chatID}
+		return nil
+	})
+
+	stop := al.startTyping(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "42"})
+	var first typingCall
+	select {
+	case first = <-calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("typing notifier not called")
+	}
+	if first.channel != "telegram" || first.chatID != "42" {
+		t.Errorf("typing called for %s/%s", first.channel, first.chatID)
+	}
+
+	stop()
+	select {
+	case <-first.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("typing context not cancelled by stop")
+	}
+	select {
+	case c := <-calls:
+		t.Errorf("typing called after stop: %+v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStartTypingSkipsSystemMessages(t *testing.T) {
+	called := false
+	al := &AgentLoop{}
+	al.SetTypingNotifier(func(ctx context.Context, channel, chatID string) error {
+		called = true
+		return nil
+	})
+
+	for _, msg := range []bus.InboundMessage{
+		{Channel: "system", ChatID: "telegram:42"},
+		{Channel: "telegram"},
+	} {
+		al.startTyping(context.Background(), msg)()
+	}
+	(&AgentLoop{}).startTyping(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "42"})()
+	if called {
+		t.Error("typing notifier called for a message that should get no indicator")
+	}
+}
@@ -11,6 +11,7 @@
 // Exempt routes (no token required):
 //   - GET /api/health
 //   - GET /   (dashboard static files)
+//   - POST /api/channels/telegram/webhook (verified by Telegram's secret token)
 //
 // WebSocket upgrade requests check the token in the query param as fallback:
 //   wss://host/api/ws?token=<api_key>
@@ -24,6 +25,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -57,9 +59,7 @@ func authMiddleware(apiKey string, next http.Handler) http.Handler {
 
 		if !tokenValid(token, apiKey) {
 			w.Header().Set("WWW-Authenticate", `Bearer realm="picoclaw"`)
-			writeJSON(w, http.StatusUnauthorized, map[string]string{
-				"error": "unauthorized — bearer token required",
-			})
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized — bearer token required", nil)
 			return
 		}
 
@@ -101,7 +101,9 @@ func tokenValid(provided, expected string) bool {
 // isPublicPath returns true for paths that never require authentication.
 func isPublicPath(path string) bool {
 	switch {
-	case path == "/api/health":
+	case path == "/api/health", path == "/api/openapi.json":
+		return true
+	case path == channels.TelegramWebhookPath:
 		return true
 	case path == "/" || strings.HasPrefix(path, "/assets/") || strings.HasSuffix(path, ".js") ||
 		strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".ico") ||
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Body limits used when gateway.request_limits leaves them at 0.
+const (
+	defaultMaxBodyBytes     = 1 << 20
+	defaultMaxDiffBodyBytes = 16 << 20
+)
+
+// bodyLimit is the largest request body accepted on path.
+func (s *Server) bodyLimit(path string) int64 {
+	limits := s.config.Gateway.RequestLimits
+	if strings.HasPrefix(path, "/api/vscode/diff/") || strings.HasPrefix(path, "/api/codex/diff/") {
+		if limits.MaxDiffBodyBytes > 0 {
+			return limits.MaxDiffBodyBytes
+		}
+		return defaultMaxDiffBodyBytes
+	}
+	if limits.MaxBodyBytes > 0 {
+		return limits.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// bodyLimitMiddleware caps request bodies. One that declares a larger
+// Content-Length is refused up front with 413; one that turns out larger
+// while being read fails the handler's decode, which writeBodyError turns
+// into 413 too.
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit := s.bodyLimit(r.URL.Path)
+		if r.ContentLength > limit {
+			writeBodyTooLarge(w, limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -7,10 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -22,6 +25,10 @@ type BotInfo struct {
 	Running   bool                   `json:"running"`
 	Config    map[string]interface{} `json:"config,omitempty"`
 	CreatedAt string                 `json:"created_at,omitempty"`
+
+	// Metrics counts the messages the bot has handled since it was
+	// registered; absent for bots that aren't registered.
+	Metrics *channeldomain.ChannelMetrics `json:"metrics,omitempty"`
 }
 
 // --- Bot CRUD Handlers ---
@@ -34,7 +41,7 @@ func (s *Server) handleBots(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		s.handleCreateBot(w, r)
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 	}
 }
 
@@ -42,7 +49,7 @@ func (s *Server) handleBots(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleBotByID(w http.ResponseWriter, r *http.Request) {
 	botID := strings.TrimPrefix(r.URL.Path, "/api/bots/")
 
-	// Handle action sub-paths: /api/bots/{id}/start, /api/bots/{id}/stop
+	// Handle sub-paths: /api/bots/{id}/start, /api/bots/{id}/stop, /api/bots/{id}/metrics
 	parts := strings.SplitN(botID, "/", 2)
 	botID = parts[0]
 
@@ -52,8 +59,10 @@ func (s *Server) handleBotByID(w http.ResponseWriter, r *http.Request) {
 			s.handleStartBot(w, r, botID)
 		case "stop":
 			s.handleStopBot(w, r, botID)
+		case "metrics":
+			s.handleBotMetrics(w, r, botID)
 		default:
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown action"})
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "unknown action", nil)
 		}
 		return
 	}
@@ -66,7 +75,7 @@ func (s *Server) handleBotByID(w http.ResponseWriter, r *http.Request) {
 	case "DELETE":
 		s.handleDeleteBot(w, r, botID)
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 	}
 }
 
@@ -82,60 +91,64 @@ func (s *Server) handleListBots(w http.ResponseWriter, r *http.Request) {
 // GET /api/bots/{id} — get single bot info.
 func (s *Server) handleGetBot(w http.ResponseWriter, r *http.Request, botID string) {
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	ch, ok := s.channelManager.GetChannel(botID)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	bot := BotInfo{
 		ID:      botID,
 		Type:    botID,
-		Enabled: true,
+		Enabled: s.channelManager.IsEnabled(botID),
 		Running: ch.IsRunning(),
 		Config:  s.getChannelConfig(botID),
+		Metrics: s.botMetrics(botID),
 	}
 
 	writeJSON(w, http.StatusOK, bot)
 }
 
+// createBotRequest is the body of POST /api/bots.
+type createBotRequest struct {
+	Type      string            `json:"type"`
+	Token     string            `json:"token,omitempty"`
+	Config    map[string]string `json:"config,omitempty"`
+	AllowFrom []string          `json:"allow_from,omitempty"`
+	AutoStart bool              `json:"auto_start,omitempty"`
+}
+
 // POST /api/bots — create/register a new bot.
 func (s *Server) handleCreateBot(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Type      string            `json:"type"`
-		Token     string            `json:"token,omitempty"`
-		Config    map[string]string `json:"config,omitempty"`
-		AllowFrom []string          `json:"allow_from,omitempty"`
-		AutoStart bool              `json:"auto_start,omitempty"`
-	}
+	var req createBotRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Type == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type is required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "type is required", nil)
 		return
 	}
 
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "channel manager not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "channel manager not available", nil)
 		return
 	}
 
 	// Check if already exists
 	if _, exists := s.channelManager.GetChannel(req.Type); exists {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": fmt.Sprintf("bot '%s' already exists", req.Type)})
+		writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("bot '%s' already exists", req.Type), nil)
 		return
 	}
 
 	// Update config and create channel
 	if err := s.updateChannelConfig(req.Type, req.Token, req.Config, req.AllowFrom); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), nil)
 		return
 	}
 
@@ -145,7 +158,7 @@ func (s *Server) handleCreateBot(w http.ResponseWriter, r *http.Request) {
 		"type":   req.Type,
 	})
 
-	logger.InfoCF("api", "Bot created via API", map[string]interface{}{
+	logger.InfoCtx(r.Context(), "api", "Bot created via API", map[string]interface{}{
 		"type": req.Type,
 	})
 
@@ -153,34 +166,38 @@ func (s *Server) handleCreateBot(w http.ResponseWriter, r *http.Request) {
 		"id":      req.Type,
 		"type":    req.Type,
 		"status":  "created",
+		"config":  s.getChannelConfig(req.Type),
 		"message": fmt.Sprintf("Bot '%s' configured. Use POST /api/bots/%s/start to start it.", req.Type, req.Type),
 	})
 }
 
+// updateBotRequest is the body of PUT /api/bots/{id}.
+type updateBotRequest struct {
+	Token     string            `json:"token,omitempty"`
+	Config    map[string]string `json:"config,omitempty"`
+	AllowFrom []string          `json:"allow_from,omitempty"`
+}
+
 // PUT /api/bots/{id} — update bot config.
 func (s *Server) handleUpdateBot(w http.ResponseWriter, r *http.Request, botID string) {
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	if _, ok := s.channelManager.GetChannel(botID); !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
-	var req struct {
-		Token     string            `json:"token,omitempty"`
-		Config    map[string]string `json:"config,omitempty"`
-		AllowFrom []string          `json:"allow_from,omitempty"`
-	}
+	var req updateBotRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if err := s.updateChannelConfig(botID, req.Token, req.Config, req.AllowFrom); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), nil)
 		return
 	}
 
@@ -191,6 +208,7 @@ func (s *Server) handleUpdateBot(w http.ResponseWriter, r *http.Request, botID s
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"id":      botID,
 		"status":  "updated",
+		"config":  s.getChannelConfig(botID),
 		"message": "Config updated. Restart bot for changes to take effect.",
 	})
 }
@@ -198,13 +216,13 @@ func (s *Server) handleUpdateBot(w http.ResponseWriter, r *http.Request, botID s
 // DELETE /api/bots/{id} — remove a bot.
 func (s *Server) handleDeleteBot(w http.ResponseWriter, r *http.Request, botID string) {
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	ch, ok := s.channelManager.GetChannel(botID)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
@@ -221,28 +239,76 @@ func (s *Server) handleDeleteBot(w http.ResponseWriter, r *http.Request, botID s
 		"bot_id": botID,
 	})
 
-	logger.InfoCF("api", "Bot deleted via API", map[string]interface{}{
+	logger.InfoCtx(r.Context(), "api", "Bot deleted via API", map[string]interface{}{
 		"bot_id": botID,
 	})
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// botMetricsResponse is the body of GET /api/bots/{id}/metrics.
+type botMetricsResponse struct {
+	ID         string                       `json:"id"`
+	Running    bool                         `json:"running"`
+	Metrics    channeldomain.ChannelMetrics `json:"metrics"`
+	Connection map[string]interface{}       `json:"connection,omitempty"` // transport state, for channels that report it
+}
+
+// GET /api/bots/{id}/metrics — message counts and activity for one bot.
+func (s *Server) handleBotMetrics(w http.ResponseWriter, r *http.Request, botID string) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+
+	if s.channelManager == nil {
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
+		return
+	}
+
+	ch, ok := s.channelManager.GetChannel(botID)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
+		return
+	}
+
+	resp := botMetricsResponse{ID: botID, Running: ch.IsRunning()}
+	resp.Metrics, _ = s.channelManager.Metrics(botID)
+	if entry, ok := s.channelManager.GetStatus()[botID].(map[string]interface{}); ok {
+		resp.Connection, _ = entry["connection"].(map[string]interface{})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// botMetrics returns the bot's message counts, or nil when it doesn't keep
+// any.
+func (s *Server) botMetrics(botID string) *channeldomain.ChannelMetrics {
+	if s.channelManager == nil {
+		return nil
+	}
+	m, ok := s.channelManager.Metrics(botID)
+	if !ok {
+		return nil
+	}
+	return &m
+}
+
 // POST /api/bots/{id}/start — start a bot.
 func (s *Server) handleStartBot(w http.ResponseWriter, r *http.Request, botID string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	ch, ok := s.channelManager.GetChannel(botID)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
@@ -253,38 +319,78 @@ func (s *Server) handleStartBot(w http.ResponseWriter, r *http.Request, botID st
 
 	ctx := context.Background()
 	if err := ch.Start(ctx); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to start: %v", err),
-		})
+		s.saveBotState(r.Context(), botID, func(c *channeldomain.Channel) { c.MarkError(err.Error()) })
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("failed to start: %v", err), nil)
 		return
 	}
+	s.saveBotState(r.Context(), botID, func(c *channeldomain.Channel) {
+		c.Enable()
+		c.MarkConnected()
+	})
 
 	s.wsHub.Broadcast("bot.started", map[string]interface{}{
 		"bot_id": botID,
 	})
 
-	logger.InfoCF("api", "Bot started via API", map[string]interface{}{
+	logger.InfoCtx(r.Context(), "api", "Bot started via API", map[string]interface{}{
 		"bot_id": botID,
 	})
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
 }
 
+// SetChannelRepository attaches the store of channel aggregates that start
+// and stop record a bot's desired state in; the channel manager reads it to
+// decide what to start. Call before Start.
+func (s *Server) SetChannelRepository(repo channeldomain.Repository) {
+	s.channelRepo = repo
+}
+
+// SetEventBus sets the bus that events raised on the stored bot aggregates
+// are published to, e.g. a bot being started or stopped.
+func (s *Server) SetEventBus(events domain.EventBus) {
+	s.domainEvents = events
+}
+
+// saveBotState applies update to the stored aggregate for botID, creating it
+// on first use, and saves it. Failures are logged rather than returned since
+// the bot itself has already been started or stopped.
+func (s *Server) saveBotState(ctx context.Context, botID string, update func(*channeldomain.Channel)) {
+	if s.channelRepo == nil {
+		return
+	}
+
+	ch, err := s.channelRepo.UpdateByName(botID, func() (*channeldomain.Channel, error) {
+		return channeldomain.Factory{}.CreateChannel(botID, domain.ChannelType(botID), channeldomain.NewChannelConfig(nil), nil)
+	}, update)
+	if err == nil && s.domainEvents != nil {
+		for _, event := range ch.PullEvents() {
+			s.domainEvents.Publish(event)
+		}
+	}
+	if err != nil {
+		logger.WarnCtx(ctx, "api", "Failed to save bot state", map[string]interface{}{
+			"bot_id": botID,
+			"error":  err.Error(),
+		})
+	}
+}
+
 // POST /api/bots/{id}/stop — stop a bot.
 func (s *Server) handleStopBot(w http.ResponseWriter, r *http.Request, botID string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
 	ch, ok := s.channelManager.GetChannel(botID)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "bot not found"})
+		writeError(w, http.StatusNotFound, ErrCodeBotNotFound, "bot not found", nil)
 		return
 	}
 
@@ -297,17 +403,19 @@ func (s *Server) handleStopBot(w http.ResponseWriter, r *http.Request, botID str
 	defer cancel()
 
 	if err := ch.Stop(ctx); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("failed to stop: %v", err),
-		})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("failed to stop: %v", err), nil)
 		return
 	}
+	s.saveBotState(r.Context(), botID, func(c *channeldomain.Channel) {
+		c.Disable()
+		c.MarkDisconnected()
+	})
 
 	s.wsHub.Broadcast("bot.stopped", map[string]interface{}{
 		"bot_id": botID,
 	})
 
-	logger.InfoCF("api", "Bot stopped via API", map[string]interface{}{
+	logger.InfoCtx(r.Context(), "api", "Bot stopped via API", map[string]interface{}{
 		"bot_id": botID,
 	})
 
@@ -326,21 +434,27 @@ func (s *Server) getBotsInfo() []BotInfo {
 	status := s.channelManager.GetStatus()
 	for name, info := range status {
 		infoMap, ok := info.(map[string]interface{})
-		running := false
+		running, enabled := false, true
 		if ok {
 			if r, exists := infoMap["running"]; exists {
 				running, _ = r.(bool)
 			}
+			if e, exists := infoMap["enabled"]; exists {
+				enabled, _ = e.(bool)
+			}
 		}
 
 		bots = append(bots, BotInfo{
 			ID:      name,
 			Type:    name,
-			Enabled: true,
+			Enabled: enabled,
 			Running: running,
 			Config:  s.getChannelConfig(name),
+			Metrics: s.botMetrics(name),
 		})
 	}
+	// The status map has no order; keep the listing stable between calls.
+	sort.Slice(bots, func(i, j int) bool { return bots[i].ID < bots[j].ID })
 
 	// Also include configured-but-not-registered channels
 	configuredChannels := s.getConfiguredChannels()
@@ -391,131 +505,71 @@ func (s *Server) getConfiguredChannels() []BotInfo {
 	if s.config == nil {
 		return bots
 	}
-	cfg := s.config
-
-	if cfg.Channels.Telegram.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "telegram",
-			Type:    "telegram",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_token":  cfg.Channels.Telegram.Token != "",
-				"allow_from": cfg.Channels.Telegram.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.Discord.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "discord",
-			Type:    "discord",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_token":  cfg.Channels.Discord.Token != "",
-				"allow_from": cfg.Channels.Discord.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.Slack.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "slack",
-			Type:    "slack",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_bot_token": cfg.Channels.Slack.BotToken != "",
-				"has_app_token": cfg.Channels.Slack.AppToken != "",
-				"allow_from":    cfg.Channels.Slack.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.WhatsApp.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "whatsapp",
-			Type:    "whatsapp",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"bridge_url": cfg.Channels.WhatsApp.BridgeURL,
-				"allow_from": cfg.Channels.WhatsApp.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.DingTalk.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "dingtalk",
-			Type:    "dingtalk",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_client_id": cfg.Channels.DingTalk.ClientID != "",
-				"allow_from":    cfg.Channels.DingTalk.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.Feishu.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "feishu",
-			Type:    "feishu",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_app_id":  cfg.Channels.Feishu.AppID != "",
-				"allow_from":  cfg.Channels.Feishu.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.QQ.Enabled {
-		bots = append(bots, BotInfo{
-			ID:      "qq",
-			Type:    "qq",
-			Enabled: true,
-			Config: map[string]interface{}{
-				"has_app_id": cfg.Channels.QQ.AppID != "",
-				"allow_from": cfg.Channels.QQ.AllowFrom,
-			},
-		})
-	}
-	if cfg.Channels.MaixCam.Enabled {
+	ch := s.config.Channels
+
+	enabled := []struct {
+		name string
+		on   bool
+	}{
+		{"telegram", ch.Telegram.Enabled},
+		{"discord", ch.Discord.Enabled},
+		{"slack", ch.Slack.Enabled},
+		{"whatsapp", ch.WhatsApp.Enabled},
+		{"dingtalk", ch.DingTalk.Enabled},
+		{"feishu", ch.Feishu.Enabled},
+		{"qq", ch.QQ.Enabled},
+		{"maixcam", ch.MaixCam.Enabled},
+	}
+	for _, c := range enabled {
+		if !c.on {
+			continue
+		}
 		bots = append(bots, BotInfo{
-			ID:      "maixcam",
-			Type:    "maixcam",
+			ID:      c.name,
+			Type:    c.name,
 			Enabled: true,
-			Config: map[string]interface{}{
-				"host": cfg.Channels.MaixCam.Host,
-				"port": cfg.Channels.MaixCam.Port,
-			},
+			Config:  s.getChannelConfig(c.name),
 		})
 	}
 
 	return bots
 }
 
-// getChannelConfig returns redacted config for a channel (no secrets).
-func (s *Server) getChannelConfig(name string) map[string]interface{} {
-	if s.config == nil {
-		return nil
-	}
+// channelConfig returns the config struct for a channel name, or nil.
+func (s *Server) channelConfig(name string) interface{} {
+	ch := &s.config.Channels
 	switch name {
 	case "telegram":
-		return map[string]interface{}{
-			"has_token":  s.config.Channels.Telegram.Token != "",
-			"allow_from": s.config.Channels.Telegram.AllowFrom,
-		}
+		return ch.Telegram
 	case "discord":
-		return map[string]interface{}{
-			"has_token":  s.config.Channels.Discord.Token != "",
-			"allow_from": s.config.Channels.Discord.AllowFrom,
-		}
+		return ch.Discord
 	case "slack":
-		return map[string]interface{}{
-			"has_bot_token": s.config.Channels.Slack.BotToken != "",
-			"has_app_token": s.config.Channels.Slack.AppToken != "",
-			"allow_from":    s.config.Channels.Slack.AllowFrom,
-		}
+		return ch.Slack
 	case "whatsapp":
-		return map[string]interface{}{
-			"bridge_url": s.config.Channels.WhatsApp.BridgeURL,
-			"allow_from": s.config.Channels.WhatsApp.AllowFrom,
-		}
+		return ch.WhatsApp
+	case "dingtalk":
+		return ch.DingTalk
+	case "feishu":
+		return ch.Feishu
+	case "qq":
+		return ch.QQ
+	case "maixcam":
+		return ch.MaixCam
 	default:
+		return nil
+	}
+}
+
+// getChannelConfig returns redacted config for a channel (no secrets).
+func (s *Server) getChannelConfig(name string) map[string]interface{} {
+	if s.config == nil {
+		return nil
+	}
+	cfg := s.channelConfig(name)
+	if cfg == nil {
 		return map[string]interface{}{}
 	}
+	return s.redactStruct(cfg)
 }
 
 // updateChannelConfig updates config for a channel type.
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/persistence"
+)
+
+func TestSaveBotState(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{}
+	s.SetChannelRepository(persistence.NewChannelRepository(dir))
+
+	s.saveBotState(context.Background(), "telegram", func(c *channeldomain.Channel) {
+		c.Enable()
+		c.MarkConnected()
+	})
+	s.saveBotState(context.Background(), "telegram", func(c *channeldomain.Channel) {
+		c.Disable()
+		c.MarkDisconnected()
+	})
+
+	// A fresh repository sees what survived the "restart"
+	ch, err := persistence.NewChannelRepository(dir).FindByName("telegram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Enabled || ch.Status != domain.StatusDisconnected || ch.Type != domain.ChannelTelegram {
+		t.Errorf("stored channel = %+v", ch)
+	}
+	if all, _ := persistence.NewChannelRepository(dir).FindAll(); len(all) != 1 {
+		t.Errorf("stored %d channels, want 1", len(all))
+	}
+
+	// Unknown channel types are logged and skipped
+	s.saveBotState(context.Background(), "not-a-type", func(c *channeldomain.Channel) { c.Enable() })
+	if _, err := s.channelRepo.FindByName("not-a-type"); !errors.Is(err, channeldomain.ErrNotFound) {
+		t.Errorf("FindByName(not-a-type) err = %v", err)
+	}
+}
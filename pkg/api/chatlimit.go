@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// chatLimiter bounds concurrent agent requests, across the server and per
+// session. Each LLM turn can run for minutes, so without it a handful of
+// dashboard tabs or bots can pile up calls on the provider.
+type chatLimiter struct {
+	global     chan struct{} // nil when unlimited
+	perSession int
+	queueWait  time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*sessionSlots
+}
+
+// sessionSlots is one session's semaphore, dropped once nobody holds or
+// waits on it.
+type sessionSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// chatBusyError reports that no slot freed up in time.
+type chatBusyError struct {
+	scope string // "session" or "server"
+	wait  time.Duration
+}
+
+func (e *chatBusyError) Error() string {
+	if e.scope == "session" {
+		return "this session is already handling a message; wait for the reply and try again"
+	}
+	return "the agent is busy with other requests; try again shortly"
+}
+
+func newChatLimiter(cfg config.AgentChatConfig) *chatLimiter {
+	l := &chatLimiter{
+		perSession: cfg.PerSession,
+		queueWait:  time.Duration(cfg.QueueSeconds) * time.Second,
+		sessions:   make(map[string]*sessionSlots),
+	}
+	if l.perSession <= 0 {
+		l.perSession = 1
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// acquire takes a slot for session, waiting up to the queue time. The
+// returned release must be called once the request is done.
+func (l *chatLimiter) acquire(ctx context.Context, session string) (func(), error) {
+	slots := l.ref(session)
+
+	// A nil wait channel never fires: with no queue time, only a free
+	// slot will do.
+	var wait <-chan time.Time
+	if l.queueWait > 0 {
+		timer := time.NewTimer(l.queueWait)
+		defer timer.Stop()
+		wait = timer.C
+	}
+
+	if !take(ctx, wait, slots.sem) {
+		l.unref(session)
+		return nil, l.busy(ctx, "session")
+	}
+	if l.global != nil && !take(ctx, wait, l.global) {
+		<-slots.sem
+		l.unref(session)
+		return nil, l.busy(ctx, "server")
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.global != nil {
+				<-l.global
+			}
+			<-slots.sem
+			l.unref(session)
+		})
+	}, nil
+}
+
+// busy returns the caller's own context error if it gave up, otherwise a
+// chatBusyError for scope.
+func (l *chatLimiter) busy(ctx context.Context, scope string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return &chatBusyError{scope: scope, wait: l.queueWait}
+}
+
+// take claims a slot in sem. If none is free it waits until one is, wait
+// fires, or ctx is done; a nil wait means don't queue at all.
+func take(ctx context.Context, wait <-chan time.Time, sem chan struct{}) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+	if wait == nil {
+		return false
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-wait:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *chatLimiter) ref(session string) *sessionSlots {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, ok := l.sessions[session]
+	if !ok {
+		slots = &sessionSlots{sem: make(chan struct{}, l.perSession)}
+		l.sessions[session] = slots
+	}
+	slots.refs++
+	return slots
+}
+
+func (l *chatLimiter) unref(session string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if slots, ok := l.sessions[session]; ok {
+		slots.refs--
+		if slots.refs <= 0 {
+			delete(l.sessions, session)
+		}
+	}
+}
+
+// acquireChat takes an agent slot for session, writing a 429 (or the
+// context error) and returning ok=false when none is free.
+func (s *Server) acquireChat(w http.ResponseWriter, r *http.Request, session string) (release func(), ok bool) {
+	if s.chatLimits == nil {
+		return func() {}, true
+	}
+	release, err := s.chatLimits.acquire(r.Context(), session)
+	if err == nil {
+		return release, true
+	}
+	if busy, isBusy := err.(*chatBusyError); isBusy {
+		retry := int(busy.wait.Seconds())
+		if retry < 1 {
+			retry = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, busy.Error(), map[string]string{"scope": busy.scope})
+		return nil, false
+	}
+	writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, fmt.Sprintf("request cancelled: %v", err), nil)
+	return nil, false
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestChatLimiterSessionAndGlobal(t *testing.T) {
+	l := newChatLimiter(config.AgentChatConfig{MaxConcurrent: 2})
+	ctx := context.Background()
+
+	relA, err := l.acquire(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same session: single-flight by default.
+	var busy *chatBusyError
+	if _, err := l.acquire(ctx, "a"); !errors.As(err, &busy) || busy.scope != "session" {
+		t.Fatalf("second turn on session a: err = %v, want session busy", err)
+	}
+	relB, err := l.acquire(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Server full.
+	if _, err := l.acquire(ctx, "c"); !errors.As(err, &busy) || busy.scope != "server" {
+		t.Fatalf("third session: err = %v, want server busy", err)
+	}
+
+	relA()
+	relA() // release is idempotent
+	relC, err := l.acquire(ctx, "c")
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	relB()
+	relC()
+	if len(l.sessions) != 0 {
+		t.Errorf("sessions left behind: %v", l.sessions)
+	}
+}
+
+func TestChatLimiterQueues(t *testing.T) {
+	l := newChatLimiter(config.AgentChatConfig{QueueSeconds: 5})
+	release, err := l.acquire(context.Background(), "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+	next, err := l.acquire(context.Background(), "s")
+	if err != nil {
+		t.Fatalf("queued turn: %v", err)
+	}
+	next()
+}
+
+func TestAcquireChatRejectsWith429(t *testing.T) {
+	s := &Server{chatLimits: newChatLimiter(config.AgentChatConfig{})}
+	r := httptest.NewRequest("POST", "/api/agent/chat", nil)
+
+	release, ok := s.acquireChat(httptest.NewRecorder(), r, "web:dashboard")
+	if !ok {
+		t.Fatal("first request rejected")
+	}
+	defer release()
+
+	w := httptest.NewRecorder()
+	if _, ok := s.acquireChat(w, r, "web:dashboard"); ok {
+		t.Fatal("concurrent request on the same session admitted")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,63 @@
+// Codex API — helpers for building structured diffs outside the agent.
+//
+// Routes:
+//   POST   /api/codex/diff/generate — build a StructuredDiff from before/after file contents
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/codex"
+)
+
+// diffGenerateRequest is the body of POST /api/codex/diff/generate.
+type diffGenerateRequest struct {
+	TaskID  string           `json:"task_id"`
+	AgentID string           `json:"agent_id"`
+	Summary string           `json:"summary"`
+	Files   []codex.FileEdit `json:"files"`
+}
+
+// handleCodexDiffGenerate turns before/after file contents into a
+// StructuredDiff with unambiguous old_content snippets, ready to POST to
+// /api/vscode/diff/apply.
+// Body: { task_id, agent_id, summary, files: [{path, before, after}] }
+func (s *Server) handleCodexDiffGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
+	}
+
+	var req diffGenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err, "invalid request body")
+		return
+	}
+	if len(req.Files) == 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "files required", nil)
+		return
+	}
+	if req.AgentID == "" {
+		req.AgentID = vscodeDefaultAgentID
+	}
+
+	diff, err := codex.GenerateDiff("diff-"+uuid.New().String()[:8], req.TaskID, req.AgentID, req.Files)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidDiff, err.Error(), map[string]string{"stage": "generate"})
+		return
+	}
+	diff.CreatedAt = time.Now()
+	if req.Summary != "" {
+		diff.Summary = req.Summary
+	}
+
+	if err := diff.ValidateLimits(s.diffLimits()); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidDiff, err.Error(), map[string]string{"stage": "validate"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
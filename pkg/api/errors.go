@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes returned in APIError.Code. They are part of the API: clients
+// switch on them, so existing values must not change meaning.
+const (
+	ErrCodeBadRequest        = "bad_request"
+	ErrCodeInvalidBody       = "invalid_body"
+	ErrCodeBodyTooLarge      = "body_too_large"
+	ErrCodeMissingField      = "missing_field"
+	ErrCodeInvalidParam      = "invalid_param"
+	ErrCodeInvalidDiff       = "invalid_diff"
+	ErrCodeInvalidWorkspace  = "invalid_workspace"
+	ErrCodeUnknownTool       = "unknown_tool"
+	ErrCodeUnauthorized      = "unauthorized"
+	ErrCodeNotFound          = "not_found"
+	ErrCodeTaskNotFound      = "task_not_found"
+	ErrCodeBotNotFound       = "bot_not_found"
+	ErrCodeSessionNotFound   = "session_not_found"
+	ErrCodeMethodNotAllowed  = "method_not_allowed"
+	ErrCodeConflict          = "conflict"
+	ErrCodeInvalidTransition = "invalid_transition"
+	ErrCodeTaskClaimed       = "task_claimed"
+	ErrCodeNotClaimHolder    = "not_claim_holder"
+	ErrCodeNoClaimableTask   = "no_claimable_task"
+	ErrCodeWIPLimit          = "wip_limit"
+	ErrCodeSessionBusy       = "session_busy"
+	ErrCodeRateLimited       = "rate_limited"
+	ErrCodeUnavailable       = "unavailable"
+	ErrCodeUpstream          = "upstream_error"
+	ErrCodeInternal          = "internal"
+)
+
+// APIError is the body of every error response. Message stays under the
+// "error" key that clients read before codes existed; Code is the stable
+// value to switch on, and Details carries anything specific to the failure
+// (the diff stage that failed, the current claim holder, ...).
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// writeError sends an APIError. details may be nil.
+func writeError(w http.ResponseWriter, status int, code, msg string, details interface{}) {
+	writeJSON(w, status, &APIError{Code: code, Message: msg, Details: details})
+}
+
+// writeBodyError reports a request body that couldn't be decoded: 413 when
+// it ran past the size limit, otherwise 400 with msg.
+func writeBodyError(w http.ResponseWriter, err error, msg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeBodyTooLarge(w, tooLarge.Limit)
+		return
+	}
+	writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, msg, nil)
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeError(w, http.StatusRequestEntityTooLarge, ErrCodeBodyTooLarge,
+		fmt.Sprintf("request body too large (limit %d bytes)", limit),
+		map[string]interface{}{"limit_bytes": limit})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+)
+
+func TestWriteTaskErrorCodes(t *testing.T) {
+	expires := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: TASK-9", kanban.ErrTaskNotFound), http.StatusNotFound, ErrCodeTaskNotFound},
+		{fmt.Errorf("%w: done → inbox", kanban.ErrInvalidTransition), http.StatusConflict, ErrCodeInvalidTransition},
+		{&kanban.ClaimConflictError{TaskID: "TASK-1", ClaimedBy: "agent-b", ExpiresAt: expires}, http.StatusConflict, ErrCodeTaskClaimed},
+		{fmt.Errorf("%w: TASK-1 (agent a)", kanban.ErrNotClaimHolder), http.StatusConflict, ErrCodeNotClaimHolder},
+		{kanban.ErrNoClaimableTask, http.StatusNotFound, ErrCodeNoClaimableTask},
+		{&kanban.WIPLimitError{State: kanban.StateRunning, Limit: 3, Count: 3}, http.StatusConflict, ErrCodeWIPLimit},
+		{errors.New("disk I/O error"), http.StatusInternalServerError, ErrCodeInternal},
+	} {
+		w := httptest.NewRecorder()
+		writeTaskError(w, tc.err)
+
+		var body APIError
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%v: decode: %v", tc.err, err)
+		}
+		if w.Code != tc.status || body.Code != tc.code {
+			t.Errorf("%v: got %d %q, want %d %q", tc.err, w.Code, body.Code, tc.status, tc.code)
+		}
+		if body.Message != tc.err.Error() {
+			t.Errorf("%v: message = %q", tc.err, body.Message)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	writeTaskError(w, &kanban.ClaimConflictError{TaskID: "TASK-1", ClaimedBy: "agent-b", ExpiresAt: expires})
+	var body struct {
+		Details map[string]string `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Details["claimed_by"] != "agent-b" || body.Details["lease_expires_at"] != "2026-01-02T03:04:05Z" {
+		t.Errorf("claim details = %v", body.Details)
+	}
+}
@@ -1,10 +1,20 @@
 // Event bridge — wires the message bus into the WebSocket hub for real-time
 // dashboard updates. Every inbound/outbound message and system event fans out
 // to all connected WebSocket clients via bus tap subscriptions.
+//
+// The optional bus event log is served here too:
+//
+//	GET  /api/bus/events        — logged system events and inbound messages (type, kind, since, until, limit)
+//	POST /api/bus/events/replay — re-deliver a time range to one named subscriber
 package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/logger"
@@ -92,12 +102,149 @@ func (eb *EventBridge) forwardSystem(ctx context.Context, tap <-chan interface{}
 				return
 			}
 			if evt, ok := raw.(bus.SystemEvent); ok {
-				eb.hub.Broadcast(evt.Type, evt.Data)
+				eb.hub.publish(WSEvent{Type: evt.Type, Data: evt.Data, RequestID: evt.RequestID})
 			}
 		}
 	}
 }
 
+// SetEventLog attaches the persistent bus event log served under
+// /api/bus/events. Call before Start.
+func (s *Server) SetEventLog(l *bus.EventLog) {
+	s.eventLog = l
+}
+
+// busEventsMaxLimit caps one page of /api/bus/events.
+const busEventsMaxLimit = 1000
+
+// handleBusEvents queries the event log, oldest first. type may end in "*"
+// to match a prefix.
+//
+//	GET /api/bus/events?type=task.*&kind=system|inbound&since=T&until=T&limit=N
+func (s *Server) handleBusEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	if s.eventLog == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "event log not enabled (gateway.event_log.enabled)", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	query, err := parseEventQuery(q.Get("kind"), q.Get("type"), q.Get("since"), q.Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+		return
+	}
+	query.Limit = 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid limit", nil)
+			return
+		}
+		query.Limit = min(n, busEventsMaxLimit)
+	}
+
+	events, err := s.eventLog.Query(r.Context(), query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// busReplayRequest is the body of POST /api/bus/events/replay.
+type busReplayRequest struct {
+	Consumer string `json:"consumer"`
+	Kind     string `json:"kind,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Since    string `json:"since"`
+	Until    string `json:"until,omitempty"`
+}
+
+// handleBusReplay re-delivers logged events from a time range to one named
+// bus subscriber (e.g. "event-bridge"), for reproducing how it reacted.
+//
+//	POST /api/bus/events/replay
+func (s *Server) handleBusReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
+	}
+	if s.eventLog == nil || s.messageBus == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "event log not enabled (gateway.event_log.enabled)", nil)
+		return
+	}
+
+	var req busReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err, "invalid request body")
+		return
+	}
+	if req.Consumer == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "consumer required", nil)
+		return
+	}
+	if req.Since == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "since required", nil)
+		return
+	}
+	query, err := parseEventQuery(req.Kind, req.Type, req.Since, req.Until)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+		return
+	}
+
+	n, err := s.eventLog.Replay(r.Context(), s.messageBus, req.Consumer, query)
+	if errors.Is(err, bus.ErrNoSubscriber) {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), map[string]interface{}{"replayed": n})
+		return
+	}
+	logger.InfoCtx(r.Context(), "events", "Replayed bus events", map[string]interface{}{
+		"consumer": req.Consumer,
+		"count":    n,
+		"since":    req.Since,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"consumer": req.Consumer,
+		"replayed": n,
+	})
+}
+
+// parseEventQuery builds an event log query from request values; since and
+// until are RFC3339.
+func parseEventQuery(kind, typ, since, until string) (bus.EventQuery, error) {
+	q := bus.EventQuery{Kind: kind, Type: typ}
+	switch kind {
+	case "", bus.EventKindSystem, bus.EventKindInbound:
+	default:
+		return q, errors.New("kind must be system or inbound")
+	}
+	for _, p := range []struct {
+		name, value string
+		dst         *time.Time
+	}{{"since", since, &q.Since}, {"until", until, &q.Until}} {
+		if p.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, p.value)
+		if err != nil {
+			return q, errors.New(p.name + " must be RFC3339")
+		}
+		*p.dst = t
+	}
+	return q, nil
+}
+
 // BroadcastSystemEvent is a convenience for direct broadcast (bypass bus).
 func (eb *EventBridge) BroadcastSystemEvent(eventType string, data map[string]interface{}) {
 	eb.hub.Broadcast(eventType, data)
@@ -109,3 +256,41 @@ func truncate(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "…"
 }
+
+// handleDeadLetters returns messages the bus or WebSocket hub had to drop,
+// with secrets in their payloads redacted.
+//
+//	GET /api/bus/deadletter?limit=N&kind=inbound|outbound|system|ws
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	if s.messageBus == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "message bus not available", nil)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	kind := r.URL.Query().Get("kind")
+
+	entries := s.messageBus.DeadLetters(0)
+	filtered := make([]bus.DeadLetter, 0, len(entries))
+	for _, dl := range entries {
+		if kind != "" && dl.Kind != kind {
+			continue
+		}
+		// Payloads are messages as sent, metadata and all
+		dl.Payload = s.redactJSON(dl.Payload)
+		filtered = append(filtered, dl)
+		if limit > 0 && len(filtered) >= limit {
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":       filtered,
+		"count":         len(filtered),
+		"total_dropped": s.messageBus.DeadLetterTotal(),
+	})
+}
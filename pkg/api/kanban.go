@@ -4,23 +4,47 @@
 //
 // Routes:
 //   GET    /api/tasks              — list tasks (filters: state, category, source, project)
-//   POST   /api/tasks              — create task
+//   POST   /api/tasks              — create task; due_date may be a phrase such as "next friday 3pm"
 //   GET    /api/tasks/{id}         — get task
-//   PUT    /api/tasks/{id}         — update task fields
+//   PUT    /api/tasks/{id}         — update task fields (due_date as on create)
 //   DELETE /api/tasks/{id}         — delete task
 //   POST   /api/tasks/{id}/transition — state machine transition
 //   POST   /api/tasks/{id}/claim   — claim task (agent ownership)
-//   POST   /api/tasks/{id}/release — release claim
+//   POST   /api/tasks/{id}/release — release claim; with a reason, records a failed attempt (reason, output, verify)
 //   POST   /api/tasks/{id}/complete — mark done, clear ownership
-//   GET    /api/tasks/stats        — board stats
+//   GET    /api/tasks/{id}/notes   — list notes
+//   POST   /api/tasks/{id}/notes   — add a note (text or markdown, with attachments); @mentions notify,
+//                                   TASK-n references cross-link
+//   GET    /api/tasks/{id}/activity — recent task events, newest first (limit)
+//   GET    /api/tasks/{id}/watchers — list users watching the task
+//   POST   /api/tasks/{id}/watchers — start watching as { user_id }
+//   DELETE /api/tasks/{id}/watchers — stop watching (?user_id=)
+//   GET    /api/tasks/{id}/logs    — execution log lines after ?after= (seq), oldest first (limit)
+//   POST   /api/tasks/{id}/logs    — append progress output as { line } or { lines }
+//   GET    /api/tasks/{id}/logs/stream — WebSocket tail of the execution log from ?after=
+//   GET    /api/tasks/stats        — board stats (?at= for a past time)
+//   GET    /api/tasks/snapshot     — every task's state as of ?at=, replayed from transitions
 //   GET    /api/tasks/categories   — category stats
+//   GET    /api/tasks/summary      — board and category stats, active claims and overdue count in one read
+//   GET    /api/tasks/meta         — states, categories, priorities, sources (label, color, icon) and transitions
+//   POST   /api/tasks/backup       — snapshot the board DB to the backups dir
+//   POST   /api/tasks/claim-next   — claim the waiting task with the highest aged priority
+//   GET    /api/tasks/changes      — tasks updated since a cursor (since, limit), oldest first
+//
+// Task list and single-task GETs carry an ETag and honor If-None-Match.
+// Claim responses include retry_context when the task has failed before.
 package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/sipeed/picoclaw/pkg/codex"
 	"github.com/sipeed/picoclaw/pkg/integration"
 	"github.com/sipeed/picoclaw/pkg/integration/kanban"
 	"github.com/sipeed/picoclaw/pkg/logger"
@@ -45,7 +69,7 @@ func (s *Server) getKanban() *kanban.KanbanIntegration {
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 	kb := s.getKanban()
 	if kb == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "kanban not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "kanban not available", nil)
 		return
 	}
 
@@ -55,7 +79,7 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		s.handleCreateTask(w, r, kb)
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 	}
 }
 
@@ -63,7 +87,7 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleTaskByID(w http.ResponseWriter, r *http.Request) {
 	kb := s.getKanban()
 	if kb == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "kanban not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "kanban not available", nil)
 		return
 	}
 
@@ -84,6 +108,30 @@ func (s *Server) handleTaskByID(w http.ResponseWriter, r *http.Request) {
 		s.handleCategoryStats(w, r, kb)
 		return
 	}
+	if taskID == "summary" {
+		s.handleTaskSummary(w, r, kb)
+		return
+	}
+	if taskID == "meta" {
+		writeJSONCached(w, r, kanban.GetBoardMeta())
+		return
+	}
+	if taskID == "backup" {
+		s.handleBackupTasks(w, r, kb)
+		return
+	}
+	if taskID == "claim-next" {
+		s.handleClaimNextTask(w, r, kb)
+		return
+	}
+	if taskID == "changes" {
+		s.handleTaskChanges(w, r, kb)
+		return
+	}
+	if taskID == "snapshot" {
+		s.handleTaskSnapshot(w, r, kb)
+		return
+	}
 
 	switch action {
 	case "":
@@ -95,7 +143,7 @@ func (s *Server) handleTaskByID(w http.ResponseWriter, r *http.Request) {
 		case "DELETE":
 			s.handleDeleteTask(w, r, kb, taskID)
 		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 		}
 	case "transition":
 		s.handleTransitionTask(w, r, kb, taskID)
@@ -105,8 +153,18 @@ func (s *Server) handleTaskByID(w http.ResponseWriter, r *http.Request) {
 		s.handleReleaseTask(w, r, kb, taskID)
 	case "complete":
 		s.handleCompleteTask(w, r, kb, taskID)
+	case "notes":
+		s.handleTaskNotes(w, r, kb, taskID)
+	case "activity":
+		s.handleTaskActivity(w, r, kb, taskID)
+	case "watchers":
+		s.handleTaskWatchers(w, r, kb, taskID)
+	case "logs":
+		s.handleTaskLogs(w, r, kb, taskID)
+	case "logs/stream":
+		s.handleTaskLogStream(w, r, kb, taskID)
 	default:
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown action"})
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "unknown action", nil)
 	}
 }
 
@@ -120,35 +178,43 @@ func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request, kb *kan
 		ExcludeDone: q.Get("exclude_done") == "true",
 	}
 
-	tasks, err := kb.ListTasks(filters)
+	tasks, err := kb.ListTasksCtx(r.Context(), filters)
 	if err != nil {
-		logger.ErrorCF("api", "List tasks failed", map[string]interface{}{"error": err.Error()})
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		logger.ErrorCtx(r.Context(), "api", "List tasks failed", map[string]interface{}{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 	if tasks == nil {
 		tasks = []*kanban.Task{}
 	}
-	writeJSON(w, http.StatusOK, tasks)
+	writeJSONCached(w, r, tasks)
+}
+
+// createTaskRequest is the body of POST /api/tasks.
+type createTaskRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Source      string `json:"source"`
+	Priority    string `json:"priority"`
+	Project     string `json:"project"`
+	Assignee    string `json:"assignee"`
+	Workspace   string `json:"workspace"`
+	CreatedBy   string `json:"created_by"`
+	// DueDate is RFC3339, YYYY-MM-DD or a phrase such as "next friday
+	// 3pm", read in the configured timezone.
+	DueDate string `json:"due_date"`
 }
 
 func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Category    string `json:"category"`
-		Source      string `json:"source"`
-		Priority    string `json:"priority"`
-		Project     string `json:"project"`
-		Assignee    string `json:"assignee"`
-	}
+	var req createTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Title == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "title required", nil)
 		return
 	}
 
@@ -160,33 +226,61 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request, kb *ka
 		Priority:    req.Priority,
 		Project:     req.Project,
 		Assignee:    req.Assignee,
+		Workspace:   req.Workspace,
+	}
+	if req.DueDate != "" {
+		due, err := s.parseDueDate(req.DueDate)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+			return
+		}
+		task.DueDate = &due
 	}
 
 	if task.Source == "" {
 		task.Source = kanban.SourceAPI
 	}
 
-	if err := kb.CreateTask(task); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := kb.CreateTaskCtx(r.Context(), task); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
+	if req.CreatedBy != "" {
+		if err := kb.AddWatcherCtx(r.Context(), task.ID, req.CreatedBy); err != nil {
+			logger.WarnCtx(r.Context(), "kanban", "Failed to add creator as watcher", map[string]interface{}{
+				"task_id": task.ID,
+				"error":   err.Error(),
+			})
+		}
+	}
 
 	writeJSON(w, http.StatusCreated, task)
 }
 
+// parseDueDate reads a due date in the configured timezone and returns it
+// in UTC, as timestamps are stored.
+func (s *Server) parseDueDate(v string) (time.Time, error) {
+	loc := time.Local
+	if s.config != nil {
+		loc = s.config.Location()
+	}
+	due, err := kanban.ParseDueDate(v, time.Now().In(loc))
+	return due.UTC(), err
+}
+
 func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
-	task, err := kb.GetTask(id)
+	task, err := kb.GetTaskCtx(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+		writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, "task not found", nil)
 		return
 	}
-	writeJSON(w, http.StatusOK, task)
+	writeJSONCached(w, r, task)
 }
 
 func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
@@ -194,22 +288,37 @@ func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request, kb *ka
 	if newStatus, ok := updates["status"]; ok {
 		delete(updates, "status")
 		if statusStr, ok := newStatus.(string); ok {
-			if err := kb.TransitionTask(id, kanban.TaskState(statusStr), "dashboard update", "api"); err != nil {
+			err := kb.TransitionTaskCtx(r.Context(), id, kanban.TaskState(statusStr), "dashboard update", "api")
+			var wip *kanban.WIPLimitError
+			if errors.As(err, &wip) {
+				writeTaskError(w, err)
+				return
+			}
+			if err != nil {
 				// If transition fails, try as a field update fallback
-				logger.WarnCF("api", "Transition failed, trying field update", map[string]interface{}{"error": err.Error()})
+				logger.WarnCtx(r.Context(), "api", "Transition failed, trying field update", map[string]interface{}{"error": err.Error()})
 			}
 		}
 	}
 
+	if d, ok := updates["due_date"].(string); ok && d != "" {
+		due, err := s.parseDueDate(d)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+			return
+		}
+		updates["due_date"] = due.Format(time.RFC3339)
+	}
+
 	if len(updates) > 0 {
-		if err := kb.UpdateTask(id, updates); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		if err := kb.UpdateTaskCtx(r.Context(), id, updates); err != nil {
+			writeTaskError(w, err)
 			return
 		}
 	}
 
 	// Return updated task
-	task, err := kb.GetTask(id)
+	task, err := kb.GetTaskCtx(r.Context(), id)
 	if err != nil {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 		return
@@ -218,63 +327,110 @@ func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request, kb *ka
 }
 
 func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
-	if err := kb.DeleteTask(id); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := kb.DeleteTaskCtx(r.Context(), id); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "id": id})
 }
 
+// transitionRequest is the body of POST /api/tasks/{id}/transition.
+type transitionRequest struct {
+	State    string `json:"state"`
+	Reason   string `json:"reason"`
+	Executor string `json:"executor"`
+}
+
 func (s *Server) handleTransitionTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		State    string `json:"state"`
-		Reason   string `json:"reason"`
-		Executor string `json:"executor"`
-	}
+	var req transitionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.State == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "state required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "state required", nil)
 		return
 	}
 	if req.Executor == "" {
 		req.Executor = "api"
 	}
 
-	if err := kb.TransitionTask(id, kanban.TaskState(req.State), req.Reason, req.Executor); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	if err := kb.TransitionTaskCtx(r.Context(), id, kanban.TaskState(req.State), req.Reason, req.Executor); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
-	task, _ := kb.GetTask(id)
+	task, _ := kb.GetTaskCtx(r.Context(), id)
 	writeJSON(w, http.StatusOK, task)
 }
 
+// claimRequest is the body of POST /api/tasks/{id}/claim and /api/vscode/tasks/{id}/claim.
+type claimRequest struct {
+	AgentID  string `json:"agent_id"`
+	LeaseSec int    `json:"lease_seconds"`
+}
+
 func (s *Server) handleClaimTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
+	}
+
+	var req claimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, err, "invalid request body")
+		return
+	}
+
+	if req.AgentID == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "agent_id required", nil)
+		return
+	}
+
+	lease := 5 * time.Minute
+	if req.LeaseSec > 0 {
+		lease = time.Duration(req.LeaseSec) * time.Second
+	}
+
+	if err := kb.ClaimTaskCtx(r.Context(), id, req.AgentID, lease); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
-	var req struct {
-		AgentID  string `json:"agent_id"`
-		LeaseSec int    `json:"lease_seconds"`
+	task, _ := kb.GetTaskCtx(r.Context(), id)
+	writeClaimedTask(w, r, kb, task)
+}
+
+// claimNextRequest is the body of POST /api/tasks/claim-next.
+type claimNextRequest struct {
+	AgentID    string                `json:"agent_id"`
+	Categories []kanban.TaskCategory `json:"categories"`
+	LeaseSec   int                   `json:"lease_seconds"`
+}
+
+// handleClaimNextTask claims whichever waiting task has the highest
+// effective (aged) priority for the agent.
+// Body: { agent_id, categories?, lease_seconds? }
+func (s *Server) handleClaimNextTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
 	}
+
+	var req claimNextRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.AgentID == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "agent_id required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "agent_id required", nil)
 		return
 	}
 
@@ -283,71 +439,402 @@ func (s *Server) handleClaimTask(w http.ResponseWriter, r *http.Request, kb *kan
 		lease = time.Duration(req.LeaseSec) * time.Second
 	}
 
-	if err := kb.ClaimTask(id, req.AgentID, lease); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	task, err := kb.ClaimNextCtx(r.Context(), req.AgentID, req.Categories, lease)
+	if err != nil {
+		writeTaskError(w, err)
 		return
 	}
+	writeClaimedTask(w, r, kb, task)
+}
 
-	task, _ := kb.GetTask(id)
-	writeJSON(w, http.StatusOK, task)
+// claimedTask is a claim response: the task itself, plus what went wrong on
+// earlier attempts so the agent can feed it into its prompt.
+type claimedTask struct {
+	*kanban.Task
+	RetryContext *kanban.RetryContext `json:"retry_context,omitempty"`
+}
+
+// writeClaimedTask responds with a freshly claimed task. A failure to load
+// the retry context is logged and the task is still returned.
+func writeClaimedTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, task *kanban.Task) {
+	if task == nil {
+		writeJSON(w, http.StatusOK, task)
+		return
+	}
+	rc, err := kb.RetryContextForCtx(r.Context(), task)
+	if err != nil {
+		logger.WarnCtx(r.Context(), "kanban", "Failed to load retry context", map[string]interface{}{
+			"task_id": task.ID,
+			"error":   err.Error(),
+		})
+	}
+	writeJSON(w, http.StatusOK, claimedTask{Task: task, RetryContext: rc})
+}
+
+// writeTaskError reports a failed kanban call with the status and code
+// matching the failure. When another agent holds the lease, the holder and
+// its expiry are included so callers can show who is working on the task.
+func writeTaskError(w http.ResponseWriter, err error) {
+	var conflict *kanban.ClaimConflictError
+	var wip *kanban.WIPLimitError
+	switch {
+	case errors.As(err, &conflict):
+		writeError(w, http.StatusConflict, ErrCodeTaskClaimed, err.Error(), map[string]interface{}{
+			"claimed_by":       conflict.ClaimedBy,
+			"lease_expires_at": conflict.ExpiresAt.Format(time.RFC3339),
+		})
+	case errors.As(err, &wip):
+		writeError(w, http.StatusConflict, ErrCodeWIPLimit, err.Error(), map[string]interface{}{
+			"state": wip.State,
+			"limit": wip.Limit,
+			"count": wip.Count,
+		})
+	case errors.Is(err, kanban.ErrTaskNotFound):
+		writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, err.Error(), nil)
+	case errors.Is(err, kanban.ErrInvalidTransition):
+		writeError(w, http.StatusConflict, ErrCodeInvalidTransition, err.Error(), nil)
+	case errors.Is(err, kanban.ErrNotClaimHolder):
+		writeError(w, http.StatusConflict, ErrCodeNotClaimHolder, err.Error(), nil)
+	case errors.Is(err, kanban.ErrNoClaimableTask):
+		writeError(w, http.StatusNotFound, ErrCodeNoClaimableTask, err.Error(), nil)
+	case errors.Is(err, kanban.ErrInvalidLease):
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+	default:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+	}
+}
+
+// releaseRequest is the body of POST /api/tasks/{id}/release.
+type releaseRequest struct {
+	AgentID string              `json:"agent_id"`
+	Reason  string              `json:"reason"`
+	Output  string              `json:"output"` // error output of the failed attempt
+	Verify  *codex.VerifyResult `json:"verify"`
 }
 
 func (s *Server) handleReleaseTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		AgentID string `json:"agent_id"`
-		Reason  string `json:"reason"`
-	}
+	var req releaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
-	if err := kb.ReleaseTask(id, req.AgentID, req.Reason); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if req.Reason == "" {
+		if err := kb.ReleaseTaskCtx(r.Context(), id, req.AgentID, ""); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "released"})
+		return
+	}
+
+	failure := attemptFailure(req.Reason, req.Verify)
+	if req.Output != "" {
+		failure.Output = req.Output
+	}
+	if err := kb.FailTaskCtx(r.Context(), id, req.AgentID, failure); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "released"})
 }
 
+// completeRequest is the body of POST /api/tasks/{id}/complete.
+type completeRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
 func (s *Server) handleCompleteTask(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		AgentID string `json:"agent_id"`
-	}
+	var req completeRequest
 	json.NewDecoder(r.Body).Decode(&req)
 
-	if err := kb.CompleteTask(id, req.AgentID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := kb.CompleteTaskCtx(r.Context(), id, req.AgentID); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "completed", "id": id})
 }
 
+// noteRequest is the body of POST /api/tasks/{id}/notes.
+type noteRequest struct {
+	Content     string   `json:"content"`
+	Author      string   `json:"author"`
+	ContentType string   `json:"content_type"` // text/plain (default) or text/markdown
+	Attachments []string `json:"attachments"`  // URLs or file paths
+}
+
+// handleTaskNotes lists (GET) or adds (POST) notes on a task.
+// POST body: { content, author, content_type?, attachments? }
+func (s *Server) handleTaskNotes(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
+	if _, err := kb.GetTaskCtx(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, "task not found", nil)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		notes, err := kb.ListNotesCtx(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+			return
+		}
+		if notes == nil {
+			notes = []*kanban.TaskNote{}
+		}
+		writeJSON(w, http.StatusOK, notes)
+	case "POST":
+		var req noteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err, "invalid request body")
+			return
+		}
+		if strings.TrimSpace(req.Content) == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeMissingField, "content is required", nil)
+			return
+		}
+		opts := kanban.NoteOptions{ContentType: req.ContentType, Attachments: req.Attachments}
+		if err := kb.AddRichNoteCtx(r.Context(), id, req.Content, req.Author, opts); err != nil {
+			if errors.Is(err, kanban.ErrInvalidContentType) {
+				writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+				return
+			}
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "added", "id": id})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
+	}
+}
+
+// watcherRequest is the body of POST /api/tasks/{id}/watchers.
+type watcherRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// handleTaskWatchers lists, adds, or removes the users watching a task.
+func (s *Server) handleTaskWatchers(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
+	if _, err := kb.GetTaskCtx(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, "task not found", nil)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req watcherRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.UserID) == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeMissingField, "user_id required", nil)
+			return
+		}
+		if err := kb.AddWatcherCtx(r.Context(), id, req.UserID); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+	case "DELETE":
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeMissingField, "user_id required", nil)
+			return
+		}
+		if err := kb.RemoveWatcherCtx(r.Context(), id, userID); err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	watchers, err := kb.ListWatchersCtx(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	if watchers == nil {
+		watchers = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"task_id": id, "watchers": watchers})
+}
+
+// handleTaskActivity returns a task's event feed: cross-links, mentions,
+// applied diffs, failed attempts and the like.
+func (s *Server) handleTaskActivity(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid limit", nil)
+			return
+		}
+		limit = n
+	}
+
+	events, err := kb.ListEventsCtx(r.Context(), id, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	if events == nil {
+		events = []*kanban.TaskEvent{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// handleTaskSummary serves the dashboard's board numbers in one response.
+func (s *Server) handleTaskSummary(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
+	summary, err := kb.GetDashboardSummaryCtx(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	writeJSONCached(w, r, summary)
+}
+
 func (s *Server) handleTaskStats(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
-	stats, err := kb.GetBoardStats()
+	var stats map[string]int
+	var err error
+	if v := r.URL.Query().Get("at"); v != "" {
+		at, perr := parseAtParam(v)
+		if perr != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, perr.Error(), nil)
+			return
+		}
+		stats, err = kb.GetBoardStatsAtCtx(r.Context(), at)
+	} else {
+		stats, err = kb.GetBoardStatsCtx(r.Context())
+	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 	writeJSON(w, http.StatusOK, stats)
 }
 
 func (s *Server) handleCategoryStats(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
-	stats, err := kb.GetCategoryStats()
+	stats, err := kb.GetCategoryStatsCtx(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 	writeJSON(w, http.StatusOK, stats)
 }
+
+// handleBackupTasks writes a timestamped copy of the board DB into the
+// kanban backups directory. The destination is never taken from the request
+// so the endpoint can't be used to write arbitrary files.
+func (s *Server) handleBackupTasks(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
+	}
+
+	name := "kanban-" + time.Now().UTC().Format("20060102T150405Z") + ".db"
+	dest := filepath.Join(kb.BackupDir(), name)
+
+	if err := kb.Backup(r.Context(), dest); err != nil {
+		logger.ErrorCtx(r.Context(), "api", "Kanban backup failed", map[string]interface{}{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+
+	var size int64
+	if fi, err := os.Stat(dest); err == nil {
+		size = fi.Size()
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"status":     "ok",
+		"path":       dest,
+		"size_bytes": size,
+	})
+}
+
+// handleTaskSnapshot returns the board as it stood at ?at=.
+func (s *Server) handleTaskSnapshot(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+
+	at, err := parseAtParam(r.URL.Query().Get("at"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+		return
+	}
+	snaps, err := kb.GetBoardStateAtCtx(r.Context(), at)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	if snaps == nil {
+		snaps = []*kanban.TaskSnapshot{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"at":    at.Format(time.RFC3339),
+		"tasks": snaps,
+	})
+}
+
+// parseAtParam reads a point in time given as RFC3339, or as a bare date
+// meaning the end of that day (UTC).
+func parseAtParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, errors.New("at is required")
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, errors.New("at must be RFC3339 or YYYY-MM-DD")
+	}
+	return d.Add(24*time.Hour - time.Second), nil
+}
+
+// handleTaskChanges serves the change feed: tasks updated after ?since=
+// (an RFC3339 time or the previous page's next_cursor), oldest first.
+func (s *Server) handleTaskChanges(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid limit", nil)
+			return
+		}
+		limit = n
+	}
+
+	changes, err := kb.ChangesSinceCtx(r.Context(), q.Get("since"), limit)
+	if errors.Is(err, kanban.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
@@ -46,9 +46,7 @@ func (s *Server) handleKanbanProxy(w http.ResponseWriter, r *http.Request) {
 	// Create proxy request
 	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, proxyURL, r.Body)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]string{
-			"error": "failed to create proxy request",
-		})
+		writeError(w, http.StatusBadGateway, ErrCodeUpstream, "failed to create proxy request", nil)
 		return
 	}
 
@@ -61,14 +59,12 @@ func (s *Server) handleKanbanProxy(w http.ResponseWriter, r *http.Request) {
 	client := &http.Client{Timeout: 15 * time.Second}
 	resp, err := client.Do(proxyReq)
 	if err != nil {
-		logger.WarnCF("kanban-proxy", "Kanban server unreachable", map[string]interface{}{
+		logger.WarnCtx(r.Context(), "kanban-proxy", "Kanban server unreachable", map[string]interface{}{
 			"url":   proxyURL,
 			"error": err.Error(),
 		})
-		writeJSON(w, http.StatusBadGateway, map[string]string{
-			"error":   "kanban server unreachable",
-			"details": "Ensure kanban_server.py is running on " + kanbanURL,
-		})
+		writeError(w, http.StatusBadGateway, ErrCodeUpstream, "kanban server unreachable",
+			"Ensure kanban_server.py is running on "+kanbanURL)
 		return
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,511 @@
+// OpenAPI — machine-readable description of the REST API.
+//
+// Routes:
+//
+//	GET    /api/openapi.json — OpenAPI 3 document for every endpoint below /api
+//
+// The operation table is maintained by hand next to the handlers; request
+// and response schemas are generated from the Go types the handlers decode
+// and encode, so they can't drift from the wire format.
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels/templates"
+	"github.com/sipeed/picoclaw/pkg/codex"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+)
+
+// apiOperation is one endpoint in the OpenAPI document. request and
+// response are values of the body types (nil for no body and a free-form
+// object respectively); path parameters are taken from {name} segments.
+type apiOperation struct {
+	method   string
+	path     string
+	tag      string
+	summary  string
+	query    []apiParam
+	request  interface{}
+	response interface{}
+	status   int  // success status; 0 means 200
+	public   bool // served without the API key
+}
+
+// apiParam is a query parameter.
+type apiParam struct {
+	name, typ, desc string
+}
+
+// statusResponse is the {status, id} acknowledgement many write endpoints
+// return.
+type statusResponse struct {
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+}
+
+var (
+	limitParam  = apiParam{"limit", "integer", "maximum number of items to return"}
+	atParam     = apiParam{"at", "string", "point in time, RFC3339 or YYYY-MM-DD (end of that day, UTC)"}
+	agentParam  = apiParam{"agent_id", "string", "agent the tasks are for"}
+	sinceParam  = apiParam{"since", "string", "RFC3339 time or the previous page's next_cursor"}
+	userIDParam = apiParam{"user_id", "string", "user to stop watching the task"}
+	// tzParam is accepted by every GET; see timezoneMiddleware.
+	tzParam = apiParam{"tz", "string", "IANA timezone to present timestamps in, e.g. Europe/Berlin (default: agents.defaults.timezone, else UTC)"}
+)
+
+// apiOperations lists every endpoint in the document, grouped by tag.
+var apiOperations = []apiOperation{
+	// System
+	{method: "GET", path: "/api/health", tag: "system", summary: "Liveness check", public: true},
+	{method: "GET", path: "/api/system/status", tag: "system", summary: "Gateway, agent, channel and task status"},
+	{method: "GET", path: "/api/system/info", tag: "system", summary: "Host and runtime information"},
+	{method: "GET", path: "/api/openapi.json", tag: "system", summary: "This document", public: true},
+	{method: "GET", path: "/api/bus/deadletter", tag: "system", summary: "Messages the bus or WebSocket hub dropped",
+		query: []apiParam{limitParam, {"kind", "string", "inbound, outbound, system or ws"}}},
+	{method: "GET", path: "/api/bus/events", tag: "system", summary: "Logged bus events, oldest first (needs gateway.event_log)",
+		query: []apiParam{{"type", "string", "event type; a trailing * matches a prefix"}, {"kind", "string", "system or inbound"},
+			{"since", "string", "RFC3339 start time"}, {"until", "string", "RFC3339 end time"}, limitParam},
+		response: busEventsResponse{}},
+	{method: "POST", path: "/api/bus/events/replay", tag: "system", summary: "Re-deliver logged events to one named bus subscriber",
+		request: busReplayRequest{}, response: busReplayResponse{}},
+
+	// Tasks
+	{method: "GET", path: "/api/tasks", tag: "tasks", summary: "List tasks",
+		query: []apiParam{
+			{"state", "string", "only tasks in this state"},
+			{"category", "string", "only tasks in this category"},
+			{"source", "string", "only tasks from this source"},
+			{"project", "string", "only tasks in this project"},
+			{"exclude_done", "boolean", "leave out done tasks"},
+		},
+		response: []*kanban.Task{}},
+	{method: "POST", path: "/api/tasks", tag: "tasks", summary: "Create a task",
+		request: createTaskRequest{}, response: kanban.Task{}, status: http.StatusCreated},
+	{method: "GET", path: "/api/tasks/{id}", tag: "tasks", summary: "Get a task", response: kanban.Task{}},
+	{method: "PUT", path: "/api/tasks/{id}", tag: "tasks", summary: "Update task fields; a status field transitions the task",
+		request: map[string]interface{}{}, response: kanban.Task{}},
+	{method: "DELETE", path: "/api/tasks/{id}", tag: "tasks", summary: "Delete a task", response: statusResponse{}},
+	{method: "POST", path: "/api/tasks/{id}/transition", tag: "tasks", summary: "Move a task through the state machine",
+		request: transitionRequest{}, response: kanban.Task{}},
+	{method: "POST", path: "/api/tasks/{id}/claim", tag: "tasks", summary: "Claim a task for an agent",
+		request: claimRequest{}, response: claimedTask{}},
+	{method: "POST", path: "/api/tasks/{id}/release", tag: "tasks", summary: "Release a claim; with a reason, record a failed attempt",
+		request: releaseRequest{}, response: statusResponse{}},
+	{method: "POST", path: "/api/tasks/{id}/complete", tag: "tasks", summary: "Mark a task done and clear its claim",
+		request: completeRequest{}, response: statusResponse{}},
+	{method: "GET", path: "/api/tasks/{id}/notes", tag: "tasks", summary: "List a task's notes", response: []*kanban.TaskNote{}},
+	{method: "POST", path: "/api/tasks/{id}/notes", tag: "tasks", summary: "Add a note; @mentions notify, TASK-n references cross-link",
+		request: noteRequest{}, response: statusResponse{}, status: http.StatusCreated},
+	{method: "GET", path: "/api/tasks/{id}/activity", tag: "tasks", summary: "Recent task events, newest first",
+		query: []apiParam{limitParam}, response: []*kanban.TaskEvent{}},
+	{method: "GET", path: "/api/tasks/{id}/watchers", tag: "tasks", summary: "List the users watching a task", response: watchersResponse{}},
+	{method: "POST", path: "/api/tasks/{id}/watchers", tag: "tasks", summary: "Start watching a task",
+		request: watcherRequest{}, response: watchersResponse{}},
+	{method: "DELETE", path: "/api/tasks/{id}/watchers", tag: "tasks", summary: "Stop watching a task",
+		query: []apiParam{userIDParam}, response: watchersResponse{}},
+	{method: "GET", path: "/api/tasks/{id}/logs", tag: "tasks", summary: "A task's execution log, oldest first",
+		query: []apiParam{{"after", "integer", "only lines after this seq"}, limitParam}, response: taskLogsResponse{}},
+	{method: "POST", path: "/api/tasks/{id}/logs", tag: "tasks", summary: "Append lines to a task's execution log",
+		request: logAppendRequest{}, response: statusResponse{}, status: http.StatusCreated},
+	{method: "GET", path: "/api/tasks/stats", tag: "tasks", summary: "Task counts by state, with <state>_wip_limit for limited states",
+		query: []apiParam{{"at", "string", "report the board as of this time (RFC3339 or YYYY-MM-DD)"}}, response: map[string]int{}},
+	{method: "GET", path: "/api/tasks/snapshot", tag: "tasks", summary: "Every task's state at a past time",
+		query: []apiParam{atParam}, response: snapshotResponse{}},
+	{method: "GET", path: "/api/tasks/categories", tag: "tasks", summary: "Task counts by category", response: map[string]int{}},
+	{method: "GET", path: "/api/tasks/summary", tag: "tasks", summary: "Board and category counts, active claims and overdue tasks",
+		response: kanban.DashboardSummary{}},
+	{method: "GET", path: "/api/tasks/meta", tag: "tasks", summary: "Display metadata for task enums and the transition graph",
+		response: kanban.BoardMeta{}},
+	{method: "POST", path: "/api/tasks/backup", tag: "tasks", summary: "Back up the board database", status: http.StatusCreated},
+	{method: "POST", path: "/api/tasks/claim-next", tag: "tasks", summary: "Claim the waiting task with the highest aged priority",
+		request: claimNextRequest{}, response: claimedTask{}},
+	{method: "GET", path: "/api/tasks/changes", tag: "tasks", summary: "Tasks updated since a cursor, oldest first",
+		query: []apiParam{sinceParam, limitParam}, response: kanban.TaskChanges{}},
+
+	// Bots
+	{method: "GET", path: "/api/bots", tag: "bots", summary: "List configured bots", response: botsResponse{}},
+	{method: "POST", path: "/api/bots", tag: "bots", summary: "Create a bot", request: createBotRequest{}, status: http.StatusCreated},
+	{method: "POST", path: "/api/bots/from-template", tag: "bots", summary: "Create a bot from a template",
+		request: templates.InstantiateRequest{}, status: http.StatusCreated},
+	{method: "GET", path: "/api/bots/{id}", tag: "bots", summary: "Get a bot", response: BotInfo{}},
+	{method: "PUT", path: "/api/bots/{id}", tag: "bots", summary: "Update a bot's config", request: updateBotRequest{}},
+	{method: "DELETE", path: "/api/bots/{id}", tag: "bots", summary: "Remove a bot", response: statusResponse{}},
+	{method: "POST", path: "/api/bots/{id}/start", tag: "bots", summary: "Start a bot", response: statusResponse{}},
+	{method: "POST", path: "/api/bots/{id}/stop", tag: "bots", summary: "Stop a bot", response: statusResponse{}},
+	{method: "GET", path: "/api/bots/{id}/metrics", tag: "bots", summary: "Message counts and last activity for a bot", response: botMetricsResponse{}},
+	{method: "GET", path: "/api/bot-templates", tag: "bots", summary: "List bot templates"},
+	{method: "POST", path: "/api/bot-templates/{name}/preview", tag: "bots", summary: "Preview the bot a template would create, without creating it",
+		request: templates.InstantiateRequest{}, response: templatePreview{}},
+	{method: "GET", path: "/api/bot-types", tag: "bots", summary: "List the bot types that can be created",
+		response: []map[string]interface{}{}},
+	{method: "GET", path: "/api/channels", tag: "bots", summary: "Channel status"},
+
+	// Sessions
+	{method: "GET", path: "/api/sessions", tag: "sessions", summary: "List agent sessions", response: []sessionSummary{}},
+	{method: "GET", path: "/api/sessions/{key}", tag: "sessions", summary: "Get a session with its history, token usage and budget", response: sessionDetail{}},
+	{method: "DELETE", path: "/api/sessions/{key}", tag: "sessions", summary: "Delete a session", response: statusResponse{}},
+
+	// Cron
+	{method: "GET", path: "/api/cron/jobs", tag: "cron", summary: "List scheduled jobs", response: []cron.CronJob{}},
+	{method: "GET", path: "/api/cron/status", tag: "cron", summary: "Scheduler status"},
+
+	// Agent
+	{method: "POST", path: "/api/agent/chat", tag: "agent", summary: "Send a message to the agent and wait for the reply",
+		request: agentChatRequest{}, response: agentChatResponse{}},
+	{method: "GET", path: "/api/agent/status", tag: "agent", summary: "Agent model, tools, skills and provider call metrics"},
+	{method: "GET", path: "/api/agent/diagnostics", tag: "agent", summary: "Self-test: provider call, workspace write, settings and tool health probes",
+		response: agent.Diagnostics{}},
+	{method: "GET", path: "/api/agent/audit", tag: "agent", summary: "Tool-call audit log, newest first",
+		query:    []apiParam{{"session", "string", "only calls from this session"}, limitParam, {"offset", "integer", "entries to skip"}},
+		response: auditResponse{}},
+	{method: "GET", path: "/api/tools", tag: "agent", summary: "Tool definitions offered to the model",
+		response: []map[string]interface{}{}},
+	{method: "GET", path: "/api/orchestrator/status", tag: "agent", summary: "Task routing and per-agent load",
+		response: orchestratorStatus{}},
+
+	// Skills
+	{method: "GET", path: "/api/skills/{name}", tag: "skills", summary: "Skill detail and metrics", response: skilldomain.Skill{}},
+	{method: "GET", path: "/api/skills/{name}/history", tag: "skills", summary: "Recent runs of a skill, newest first",
+		query: []apiParam{limitParam}},
+	{method: "POST", path: "/api/skills/{name}/dry-run", tag: "skills", summary: "Resolve a skill's command without running it",
+		request: skillDryRunRequest{}, response: skilldomain.DryRunResult{}},
+
+	// VS Code
+	{method: "GET", path: "/api/vscode/status", tag: "vscode", summary: "Status bar data", response: vscodeStatus{}},
+	{method: "POST", path: "/api/vscode/todo", tag: "vscode", summary: "Create a task from an editor TODO",
+		request: vscodeTodoRequest{}, response: kanban.Task{}, status: http.StatusCreated},
+	{method: "POST", path: "/api/vscode/ask", tag: "vscode", summary: "Ask the coding agent a question",
+		request: vscodeAskRequest{}, response: agentChatResponse{}},
+	{method: "POST", path: "/api/vscode/diff/preview", tag: "vscode", summary: "Validate a structured diff without applying it",
+		request: diffPreviewRequest{}, response: diffPreviewResult{}},
+	{method: "POST", path: "/api/vscode/diff/apply", tag: "vscode", summary: "Apply and verify a structured diff",
+		request: diffApplyRequest{}, response: codex.ApplyVerifyResult{}},
+	{method: "GET", path: "/api/vscode/tasks", tag: "vscode", summary: "Coding tasks the agent can claim",
+		query: []apiParam{agentParam, {"category", "string", "only this coding category"}}, response: []*kanban.Task{}},
+	{method: "POST", path: "/api/vscode/tasks/{id}/claim", tag: "vscode", summary: "Claim a coding task",
+		request: claimRequest{}, response: claimedTask{}},
+	{method: "POST", path: "/api/codex/diff/generate", tag: "vscode", summary: "Build a structured diff from before/after contents",
+		request: diffGenerateRequest{}, response: codex.StructuredDiff{}},
+
+	// Ingestion
+	{method: "POST", path: "/api/webhook/{source}", tag: "events", summary: "Publish an event from a local program",
+		request: map[string]interface{}{}, status: http.StatusAccepted},
+	{method: "POST", path: "/api/events", tag: "events", summary: "Ingest an ide-monitor workflow event",
+		request: WorkflowEvent{}, status: http.StatusAccepted},
+	{method: "POST", path: "/api/events/batch", tag: "events", summary: "Ingest workflow events in order, with a status per event",
+		request: []WorkflowEvent{}, response: workflowBatchResponse{}, status: http.StatusAccepted},
+}
+
+// Response shapes that the handlers build as maps, described here for the
+// document only.
+type (
+	watchersResponse struct {
+		TaskID   string   `json:"task_id"`
+		Watchers []string `json:"watchers"`
+	}
+	snapshotResponse struct {
+		At    string                 `json:"at"`
+		Tasks []*kanban.TaskSnapshot `json:"tasks"`
+	}
+	botsResponse struct {
+		Bots  []BotInfo `json:"bots"`
+		Count int       `json:"count"`
+	}
+	sessionSummary struct {
+		Key          string    `json:"key"`
+		Summary      string    `json:"summary"`
+		MessageCount int       `json:"message_count"`
+		Created      time.Time `json:"created"`
+		Updated      time.Time `json:"updated"`
+		TokensUsed   int64     `json:"tokens_used"`
+	}
+	busEventsResponse struct {
+		Events []bus.LoggedEvent `json:"events"`
+		Count  int               `json:"count"`
+	}
+	busReplayResponse struct {
+		Consumer string `json:"consumer"`
+		Replayed int    `json:"replayed"`
+	}
+	agentChatResponse struct {
+		Response string `json:"response"`
+		Session  string `json:"session,omitempty"`
+	}
+	auditResponse struct {
+		Entries []agent.AuditEntry `json:"entries"`
+		Total   int                `json:"total"`
+		Limit   int                `json:"limit"`
+		Offset  int                `json:"offset"`
+	}
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]interface{}
+)
+
+// handleOpenAPI serves the OpenAPI document, built once on first request.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	openAPIOnce.Do(func() { openAPIDoc = buildOpenAPI(apiOperations) })
+	writeJSONCached(w, r, openAPIDoc)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// buildOpenAPI renders ops as an OpenAPI 3.0 document.
+func buildOpenAPI(ops []apiOperation) map[string]interface{} {
+	sb := newSchemaBuilder()
+	errorRef := sb.schema(reflect.TypeOf(APIError{}))
+
+	paths := map[string]interface{}{}
+	for _, op := range ops {
+		item, _ := paths[op.path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.path] = item
+		}
+
+		var params []interface{}
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		query := op.query
+		if op.method == "GET" {
+			query = append(query[:len(query):len(query)], tzParam)
+		}
+		for _, q := range query {
+			params = append(params, map[string]interface{}{
+				"name": q.name, "in": "query", "description": q.desc,
+				"schema": map[string]interface{}{"type": q.typ},
+			})
+		}
+
+		success := map[string]interface{}{"type": "object"}
+		if op.response != nil {
+			success = sb.schema(reflect.TypeOf(op.response))
+		}
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		operation := map[string]interface{}{
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"operationId": operationID(op),
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): jsonContent(http.StatusText(status), success),
+				"default":            jsonContent("Error", errorRef),
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": sb.schema(reflect.TypeOf(op.request))},
+				},
+			}
+		}
+		if op.public {
+			operation["security"] = []interface{}{}
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "PicoClaw API",
+			"version":     "1.0.0",
+			"description": "Dashboard, task board, bot and agent API. Errors use the APIError envelope; switch on its code.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": sb.components,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		},
+	}
+}
+
+func jsonContent(desc string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": desc,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+// operationID derives a stable identifier such as "postTasksIdClaim".
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(op.path, "/api/"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '{' || r == '}' || r == '_'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// schemaBuilder turns Go types into OpenAPI schemas, collecting named
+// structs under components/schemas and referring to them by $ref.
+type schemaBuilder struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: map[string]interface{}{}, names: map[reflect.Type]string{}}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	timestampType = reflect.TypeOf(domain.Timestamp{}) // marshals as its embedded time.Time
+)
+
+// enumValues lists the allowed values of the task enums.
+var enumValues = map[reflect.Type]func() []string{
+	reflect.TypeOf(kanban.TaskState("")): func() []string {
+		var v []string
+		for _, s := range kanban.AllStates() {
+			v = append(v, string(s))
+		}
+		return v
+	},
+	reflect.TypeOf(kanban.TaskCategory("")): func() []string {
+		var v []string
+		for _, c := range kanban.AllCategories() {
+			v = append(v, string(c))
+		}
+		return v
+	},
+	reflect.TypeOf(kanban.TaskSource("")): func() []string {
+		var v []string
+		for _, s := range kanban.AllSources() {
+			v = append(v, string(s))
+		}
+		return v
+	},
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == timestampType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if values, ok := enumValues[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": values()}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.components[name] = map[string]interface{}{} // placeholder for recursive types
+			b.components[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// object builds an inline object schema from t's JSON fields. Embedded
+// structs without a JSON name have their fields promoted, as encoding/json
+// does.
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	b.addFields(t, props)
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, props)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.schema(f.Type)
+	}
+}
+
+// componentName is t's type name, exported, prefixed with its package when
+// another package already used the name.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	return name
+}
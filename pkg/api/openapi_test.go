@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	s.handleOpenAPI(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+		Comps   struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	for path, method := range map[string]string{
+		"/api/tasks":                 "post",
+		"/api/tasks/{id}/claim":      "post",
+		"/api/bots/{id}":             "get",
+		"/api/sessions/{key}":        "delete",
+		"/api/cron/jobs":             "get",
+		"/api/agent/chat":            "post",
+		"/api/vscode/diff/apply":     "post",
+		"/api/tasks/{id}/watchers":   "delete",
+		"/api/codex/diff/generate":   "post",
+		"/api/skills/{name}/dry-run": "post",
+	} {
+		if doc.Paths[path][method] == nil {
+			t.Errorf("missing %s %s", method, path)
+		}
+	}
+
+	// Task carries the state enum and ClaimedTask promotes Task's fields
+	task := doc.Comps.Schemas["Task"]
+	state, _ := task["properties"].(map[string]interface{})["state"].(map[string]interface{})
+	if enum, _ := state["enum"].([]interface{}); len(enum) == 0 {
+		t.Errorf("Task.state has no enum: %v", state)
+	}
+	claimed, _ := doc.Comps.Schemas["ClaimedTask"]["properties"].(map[string]interface{})
+	if claimed["id"] == nil || claimed["retry_context"] == nil {
+		t.Errorf("ClaimedTask properties = %v", claimed)
+	}
+
+	// Every $ref resolves
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Comps.Schemas[name]; !ok {
+					t.Errorf("dangling ref %s", ref)
+				}
+			}
+			for _, c := range v {
+				walk(c)
+			}
+		case []interface{}:
+			for _, c := range v {
+				walk(c)
+			}
+		}
+	}
+	var raw interface{}
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	walk(raw)
+}
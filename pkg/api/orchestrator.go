@@ -0,0 +1,54 @@
+// Orchestrator API — visibility into task routing and agent load.
+//
+// Routes:
+//   GET    /api/orchestrator/status — overall counts, per-agent breakdown, throughput
+//                                      and the last reconciliation with kanban claims
+package api
+
+import (
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/orchestration"
+)
+
+// orchestratorStatus is the /api/orchestrator/status payload.
+type orchestratorStatus struct {
+	Summary    map[string]interface{}      `json:"summary"`
+	Agents     []orchestration.AgentStatus `json:"agents"`
+	Throughput float64                     `json:"throughput_per_min"`
+	WindowSecs int                         `json:"throughput_window_secs"`
+
+	// Reconcile is the last pass matching assignments to kanban claims;
+	// nil until one has run.
+	Reconcile *orchestration.ReconcileReport `json:"reconcile,omitempty"`
+}
+
+// SetOrchestrator attaches the task orchestrator whose state is exposed
+// under /api/orchestrator. Call before Start.
+func (s *Server) SetOrchestrator(o *orchestration.Orchestrator) {
+	s.orchestrator = o
+}
+
+// handleOrchestratorStatus reports what each agent is doing so an operator
+// can tell whether to add agents or whether one is stuck.
+func (s *Server) handleOrchestratorStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	if s.orchestrator == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "orchestrator not available", nil)
+		return
+	}
+
+	status := orchestratorStatus{
+		Summary:    s.orchestrator.Status(),
+		Agents:     s.orchestrator.PerAgentStatus(),
+		Throughput: s.orchestrator.Throughput(),
+		WindowSecs: int(orchestration.ThroughputWindow.Seconds()),
+	}
+	if report, ok := s.orchestrator.LastReconcile(); ok {
+		status.Reconcile = &report
+	}
+	writeJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultOriginHosts are always allowed, whatever gateway.allowed_origins says.
+var defaultOriginHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// isAllowedOrigin reports whether a browser Origin may call the API or open
+// a WebSocket. Localhost is always allowed; gateway.allowed_origins adds
+// more. An entry is one of:
+//
+//	https://dash.example.com   exact origin (scheme, host and port)
+//	dash.lan                   that host on any scheme or port
+//	dash.lan:8080              that host and port on any scheme
+//	*.example.com              any subdomain of example.com, any scheme or port
+//	https://*.example.com      any subdomain, https only
+func (s *Server) isAllowedOrigin(origin string) bool {
+	var extra []string
+	if s != nil && s.config != nil {
+		extra = s.config.Gateway.AllowedOrigins
+	}
+	return originAllowed(origin, extra)
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, h := range defaultOriginHosts {
+		if host == h {
+			return true
+		}
+	}
+
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		scheme := ""
+		if i := strings.Index(entry, "://"); i >= 0 {
+			scheme, entry = entry[:i], entry[i+3:]
+			if scheme != u.Scheme {
+				continue
+			}
+		}
+		entry = strings.TrimSuffix(entry, "/")
+
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+			continue
+		}
+		// An entry with a port must match it; without one, a bare host
+		// matches any port and a full origin only the scheme's default.
+		if _, _, err := net.SplitHostPort(entry); err == nil {
+			if strings.ToLower(u.Host) == entry {
+				return true
+			}
+			continue
+		}
+		if host == entry && (scheme == "" || u.Port() == "") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package api
+
+import "testing"
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://dash.example.com", "lan-box", "ci.local:8080", "*.team.dev", "https://*.secure.io"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:5173", true},
+		{"https://127.0.0.1", true},
+		{"http://[::1]:3000", true},
+		{"http://localhost.evil.com", false},
+		{"https://dash.example.com", true},
+		{"http://dash.example.com", false},
+		{"https://dash.example.com:8443", false},
+		{"http://lan-box:9000", true},
+		{"http://ci.local:8080", true},
+		{"http://ci.local:9090", false},
+		{"https://a.team.dev", true},
+		{"https://team.dev", false},
+		{"https://evilteam.dev", false},
+		{"https://x.secure.io", true},
+		{"http://x.secure.io", false},
+		{"file://localhost", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := originAllowed(tt.origin, allowed); got != tt.want {
+			t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// defaultRedactKeys are the key-name fragments treated as secrets in API
+// responses when gateway.redact_keys is not set.
+var defaultRedactKeys = []string{"token", "secret", "key", "password"}
+
+// redactSecrets returns a copy of m that is safe to send to clients. Every
+// key whose name matches a secret fragment is replaced by a "has_<key>"
+// boolean, so callers can still tell whether it is set. Nested maps and
+// slices are walked. Booleans are never secrets and pass through, which keeps
+// an existing "has_token" flag intact.
+func (s *Server) redactSecrets(m map[string]interface{}) map[string]interface{} {
+	return redactMap(m, s.redactKeys())
+}
+
+// redactKeys returns the configured secret key fragments.
+func (s *Server) redactKeys() []string {
+	if s.config != nil && len(s.config.Gateway.RedactKeys) > 0 {
+		return s.config.Gateway.RedactKeys
+	}
+	return defaultRedactKeys
+}
+
+func redactMap(m map[string]interface{}, keys []string) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if _, isBool := v.(bool); !isBool && utils.IsSecretKey(k, keys) {
+			flag := "has_" + strings.TrimPrefix(k, "has_")
+			out[flag] = !isEmptySecret(v)
+			continue
+		}
+		out[k] = redactAny(v, keys)
+	}
+	return out
+}
+
+func redactAny(v interface{}, keys []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return redactMap(val, keys)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = redactAny(item, keys)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func isEmptySecret(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	default:
+		return false
+	}
+}
+
+// redactJSON converts any value to its JSON form and redacts every object
+// in it, e.g. a message payload whose metadata may carry tokens.
+func (s *Server) redactJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+	return redactAny(decoded, s.redactKeys())
+}
+
+// redactStruct converts a config struct to its JSON map form and redacts it.
+func (s *Server) redactStruct(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return map[string]interface{}{}
+	}
+	return s.redactSecrets(m)
+}
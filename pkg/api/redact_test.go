@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestGetChannelConfigRedactsSecrets(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Channels.Slack.BotToken = "xoxb-secret"
+	cfg.Channels.Slack.AllowFrom = []string{"U1"}
+	cfg.Channels.Feishu.AppSecret = "shh"
+	s := &Server{config: cfg}
+
+	slack := s.getChannelConfig("slack")
+	if _, ok := slack["bot_token"]; ok {
+		t.Error("bot_token leaked in slack config")
+	}
+	if slack["has_bot_token"] != true || slack["has_app_token"] != false {
+		t.Errorf("has_bot_token/has_app_token = %v/%v, want true/false", slack["has_bot_token"], slack["has_app_token"])
+	}
+	if list, _ := slack["allow_from"].([]interface{}); len(list) != 1 {
+		t.Errorf("allow_from = %v, want passthrough", slack["allow_from"])
+	}
+
+	feishu := s.getChannelConfig("feishu")
+	if _, ok := feishu["app_secret"]; ok {
+		t.Error("app_secret leaked in feishu config")
+	}
+
+	nested := s.redactSecrets(map[string]interface{}{
+		"has_token": true,
+		"inner":     map[string]interface{}{"api_key": "k"},
+	})
+	if nested["has_token"] != true {
+		t.Errorf("has_token flag altered: %v", nested["has_token"])
+	}
+	inner, _ := nested["inner"].(map[string]interface{})
+	if _, ok := inner["api_key"]; ok || inner["has_api_key"] != true {
+		t.Errorf("nested api_key not redacted: %v", inner)
+	}
+}
+
+func TestDeadLettersRedactPayloads(t *testing.T) {
+	mb := bus.NewMessageBus()
+	s := &Server{config: config.DefaultConfig(), messageBus: mb}
+	mb.DeadLetter(bus.DeadLetterInbound, "agent", "buffer full", bus.InboundMessage{
+		Channel:  "slack",
+		Content:  "hi",
+		Metadata: map[string]string{"bot_token": "xoxb-secret", "user": "U1"},
+	})
+
+	rec := httptest.NewRecorder()
+	s.handleDeadLetters(rec, httptest.NewRequest("GET", "/api/bus/deadletter", nil))
+	if strings.Contains(rec.Body.String(), "xoxb-secret") {
+		t.Fatalf("token leaked in dead letters: %s", rec.Body.String())
+	}
+	var body struct {
+		Entries []struct {
+			Payload struct {
+				Metadata map[string]interface{} `json:"metadata"`
+			} `json:"payload"`
+		} `json:"entries"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if len(body.Entries) != 1 {
+		t.Fatalf("entries = %s", rec.Body.String())
+	}
+	if md := body.Entries[0].Payload.Metadata; md["has_bot_token"] != true || md["user"] != "U1" {
+		t.Errorf("payload metadata = %v", md)
+	}
+}
@@ -3,26 +3,40 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/app"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/channels/templates"
+	"github.com/sipeed/picoclaw/pkg/codex"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/orchestration"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // Server is the HTTP API server for the PicoClaw dashboard.
@@ -38,6 +52,23 @@ type Server struct {
 	server         *http.Server
 	webFS          fs.FS
 	mu             sync.RWMutex
+
+	orchestrator *orchestration.Orchestrator // nil until SetOrchestrator
+	skillService *app.SkillService           // nil until SetSkillService
+	channelRepo  channeldomain.Repository    // nil until SetChannelRepository
+	domainEvents domain.EventBus             // nil until SetEventBus
+	eventLog     *bus.EventLog               // nil until SetEventLog
+
+	appliedOnce sync.Once
+	appliedLog  *codex.AppliedLog // diff IDs already applied; nil if unavailable
+
+	worktreesOnce sync.Once
+	worktrees     *codex.Worktrees // per-task diff worktrees; nil unless isolation is on
+
+	chatLimits *chatLimiter // concurrent agent requests
+
+	workflowSeen recentIDs       // workflow event IDs already routed
+	tokenBursts  tokenCorrelator // token usage awaiting its commit
 }
 
 // NewServer creates a new API server instance.
@@ -74,10 +105,22 @@ func NewServer(
 		messageBus:     msgBus,
 		startTime:      time.Now(),
 		webFS:          webFS,
+		chatLimits:     newChatLimiter(cfg.Gateway.AgentChat),
 	}
 	s.wsHub = NewWSHub(s)
 	s.eventBridge = NewEventBridge(msgBus, s.wsHub)
 
+	// Push status changes as they happen rather than on the keepalive
+	if agentLoop != nil {
+		agentLoop.SetStatusHandler(s.wsHub.RequestStatus)
+	}
+	if channelMgr != nil {
+		channelMgr.SetStatusHandler(s.wsHub.RequestStatus)
+	}
+	if cronSvc != nil {
+		cronSvc.SetStatusHandler(s.wsHub.RequestStatus)
+	}
+
 	// Load bot templates from standard locations at startup
 	n, warns := templates.LoadDefaults()
 	logger.InfoCF("api", "Bot templates loaded", map[string]interface{}{
@@ -98,8 +141,10 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/health", s.handleHealth)
 	mux.HandleFunc("/api/system/status", s.handleSystemStatus)
 	mux.HandleFunc("/api/system/info", s.handleSystemInfo)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
 
 	mux.HandleFunc("/api/channels", s.handleChannels)
+	mux.HandleFunc(channels.TelegramWebhookPath, s.handleTelegramWebhook)
 
 	mux.HandleFunc("/api/sessions", s.handleSessions)
 	mux.HandleFunc("/api/sessions/", s.handleSessionDetail)
@@ -111,12 +156,15 @@ func (s *Server) Start(ctx context.Context) error {
 
 	mux.HandleFunc("/api/agent/chat", s.handleAgentChat)
 	mux.HandleFunc("/api/agent/status", s.handleAgentStatus)
+	mux.HandleFunc("/api/agent/audit", s.handleAgentAudit)
+	mux.HandleFunc("/api/agent/diagnostics", s.handleAgentDiagnostics)
 
 	// Bot management API
 	mux.HandleFunc("/api/bots", s.handleBots)
 	mux.HandleFunc("/api/bots/from-template", s.handleCreateBotFromTemplate)
 	mux.HandleFunc("/api/bots/", s.handleBotByID)
 	mux.HandleFunc("/api/bot-templates", s.handleListBotTemplates)
+	mux.HandleFunc("/api/bot-templates/", s.handleBotTemplateByName)
 	mux.HandleFunc("/api/bot-types", s.handleBotTypes)
 
 	// Kanban proxy (forwards to Python kanban server)
@@ -129,11 +177,26 @@ func (s *Server) Start(ctx context.Context) error {
 	// VSCode extension API
 	mux.HandleFunc("/api/vscode/", s.handleVSCode)
 
+	// Structured diff helpers
+	mux.HandleFunc("/api/codex/diff/generate", s.handleCodexDiffGenerate)
+
+	// Task orchestration
+	mux.HandleFunc("/api/orchestrator/status", s.handleOrchestratorStatus)
+
+	// Skill registry
+	mux.HandleFunc("/api/skills/", s.handleSkillByName)
+
 	// Webhook ingestion (local programs → picoclaw)
 	mux.HandleFunc("/api/webhook/{source}", s.handleWebhook)
 
 	// Workflow event ingestion (ide-monitor → picoclaw)
 	mux.HandleFunc("/api/events", s.handleWorkflowEvent)
+	mux.HandleFunc("/api/events/batch", s.handleWorkflowEventBatch)
+
+	// Dropped/failed bus deliveries
+	mux.HandleFunc("/api/bus/deadletter", s.handleDeadLetters)
+	mux.HandleFunc("/api/bus/events", s.handleBusEvents)
+	mux.HandleFunc("/api/bus/events/replay", s.handleBusReplay)
 
 	// WebSocket for live events
 	mux.HandleFunc("/api/ws", s.wsHub.HandleWebSocket)
@@ -145,10 +208,13 @@ func (s *Server) Start(ctx context.Context) error {
 
 	s.server = &http.Server{
 		Addr:         addr,
-		Handler:      corsMiddleware(authMiddleware(s.config.Gateway.APIKey, mux)),
+		Handler:      requestIDMiddleware(s.corsMiddleware(gzipMiddleware(authMiddleware(s.config.Gateway.APIKey, s.bodyLimitMiddleware(s.timezoneMiddleware(mux)))))),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		// Derive request contexts from the server context so shutdown
+		// cancels in-flight DB work instead of waiting on it.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	logger.InfoCF("api", "Dashboard API server starting", map[string]interface{}{
@@ -157,6 +223,9 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go s.wsHub.Run(ctx)
 	go s.eventBridge.Run(ctx)
+	if wt := s.getWorktrees(); wt != nil {
+		go s.runWorktreeLifecycle(ctx, wt)
+	}
 
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -181,16 +250,17 @@ func (s *Server) Stop() error {
 
 // --- Middleware ---
 
-func corsMiddleware(next http.Handler) http.Handler {
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		if origin == "" || isAllowedOrigin(origin) {
+		if origin == "" || s.isAllowedOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		} else {
 			w.Header().Set("Access-Control-Allow-Origin", "http://localhost")
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -200,16 +270,208 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// isAllowedOrigin checks if the origin is a trusted localhost address.
-func isAllowedOrigin(origin string) bool {
-	for _, prefix := range []string{"http://localhost", "http://127.0.0.1", "https://localhost", "https://127.0.0.1"} {
-		if strings.HasPrefix(origin, prefix) {
-			return true
+// requestIDMiddleware tags every request with a correlation ID: the
+// client's X-Request-ID when it sent a usable one, otherwise a fresh one.
+// The ID is echoed in the response header and carried in the request
+// context, where the logger's *Ctx functions and bus events pick it up.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts client IDs of up to 128 characters drawn from
+// letters, digits and "-_.:", so they can't smuggle anything into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
 		}
 	}
+	return true
+}
+
+// newRequestID returns a random 16-hex-digit ID.
+func newRequestID() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// gzipMinSize is the smallest response body worth compressing.
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses responses for clients that accept gzip once the
+// body reaches gzipMinSize. WebSocket upgrades, HEAD and Range requests
+// pass through untouched, as do bodies that are already encoded or are
+// compressed formats (images, archives, fonts).
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == "HEAD" || r.Header.Get("Range") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
 	return false
 }
 
+// compressedType reports whether a content type is already compressed.
+func compressedType(contentType string) bool {
+	ct, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	ct = strings.TrimSpace(ct)
+	switch {
+	case ct == "image/svg+xml":
+		return false
+	case strings.HasPrefix(ct, "image/"), strings.HasPrefix(ct, "video/"), strings.HasPrefix(ct, "audio/"),
+		strings.HasPrefix(ct, "font/woff"):
+		return true
+	}
+	switch ct {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+		"application/x-bzip2", "application/x-xz", "application/x-7z-compressed":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large and compressible enough, then either streams it through
+// gzip or writes it as-is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	if g.decided {
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide sends the header and buffered bytes, compressed or not.
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+	h := g.Header()
+	if len(g.buf) > 0 && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	status := g.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if len(g.buf) >= gzipMinSize && h.Get("Content-Encoding") == "" && !compressedType(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.ResponseWriter.WriteHeader(status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(g.buf)
+		g.buf = nil
+		return err
+	}
+
+	g.ResponseWriter.WriteHeader(status)
+	var err error
+	if len(g.buf) > 0 {
+		_, err = g.ResponseWriter.Write(g.buf)
+	}
+	g.buf = nil
+	return err
+}
+
+// Flush commits to a decision early so streamed responses aren't held back.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.decide()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// Close finishes the response. A handler that wrote nothing still gets its
+// status sent.
+func (g *gzipResponseWriter) Close() error {
+	if !g.decided {
+		if g.status == 0 {
+			return nil
+		}
+		if err := g.decide(); err != nil {
+			return err
+		}
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
 // --- Handlers ---
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -248,7 +510,7 @@ func (s *Server) handleSystemStatus(w http.ResponseWriter, r *http.Request) {
 		sessionCount = len(s.agentLoop.GetSessionManager().ListSessions())
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	status := map[string]interface{}{
 		"uptime_seconds": int(uptime.Seconds()),
 		"uptime_human":   formatDuration(uptime),
 		"agent": map[string]interface{}{
@@ -257,10 +519,18 @@ func (s *Server) handleSystemStatus(w http.ResponseWriter, r *http.Request) {
 			"tools":      toolCount,
 			"tool_names": toolNames,
 		},
-		"channels": channelStatus,
-		"cron":     cronStatus,
-		"sessions": sessionCount,
-	})
+		"channels":  channelStatus,
+		"cron":      cronStatus,
+		"sessions":  sessionCount,
+		"websocket": s.wsHub.Stats(),
+	}
+	if kb := s.getKanban(); kb != nil {
+		if summary, err := kb.GetDashboardSummaryCtx(r.Context()); err == nil {
+			status["tasks"] = summary
+		}
+	}
+
+	writeJSON(w, http.StatusOK, status)
 }
 
 func (s *Server) handleSystemInfo(w http.ResponseWriter, r *http.Request) {
@@ -290,7 +560,28 @@ func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]interface{}{})
 		return
 	}
-	writeJSON(w, http.StatusOK, s.channelManager.GetStatus())
+	writeJSON(w, http.StatusOK, s.redactSecrets(s.channelManager.GetStatus()))
+}
+
+// handleTelegramWebhook forwards Telegram updates to the Telegram channel when
+// it runs in webhook mode. Authentication is the channel's secret token, not
+// the API key, so the path is exempt in authMiddleware.
+func (s *Server) handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.channelManager == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "telegram webhook not enabled", nil)
+		return
+	}
+	ch, ok := s.channelManager.GetChannel("telegram")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "telegram webhook not enabled", nil)
+		return
+	}
+	tg, ok := ch.(*channels.TelegramChannel)
+	if !ok || !tg.WebhookMode() {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "telegram webhook not enabled", nil)
+		return
+	}
+	tg.ServeWebhook(w, r)
 }
 
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
@@ -311,29 +602,37 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 			"message_count":  msgCount,
 			"created":        sess.Created,
 			"updated":        sess.Updated,
+			"tokens_used":    sess.TokensUsed,
 		})
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSONCached(w, r, result)
+}
+
+// sessionDetail is the body of GET /api/sessions/{key}: the session with
+// its history and token usage, and the budget that usage counts against.
+type sessionDetail struct {
+	*session.Session
+	TokenBudget int64 `json:"token_budget,omitempty"`
 }
 
 func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	// Extract session key from URL: /api/sessions/{key}
 	key := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
 	if key == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "session key required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "session key required", nil)
 		return
 	}
 
 	if s.agentLoop == nil || s.agentLoop.GetSessionManager() == nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "not found", nil)
 		return
 	}
 
 	if r.Method == "DELETE" {
 		ok := s.agentLoop.GetSessionManager().DeleteSession(key)
 		if !ok {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "session not found", nil)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
@@ -342,11 +641,11 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 
 	session, ok := s.agentLoop.GetSessionManager().GetSession(key)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "session not found", nil)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, session)
+	writeJSON(w, http.StatusOK, sessionDetail{Session: session, TokenBudget: s.agentLoop.GetSessionTokenBudget()})
 }
 
 func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
@@ -377,23 +676,26 @@ func (s *Server) handleCronStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, s.cronService.Status())
 }
 
+// agentChatRequest is the body of POST /api/agent/chat.
+type agentChatRequest struct {
+	Message string `json:"message"`
+	Session string `json:"session"`
+}
+
 func (s *Server) handleAgentChat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		Message string `json:"message"`
-		Session string `json:"session"`
-	}
+	var req agentChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Message == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "message required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "message required", nil)
 		return
 	}
 
@@ -403,18 +705,26 @@ func (s *Server) handleAgentChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.agentLoop == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "agent not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "agent not available", nil)
+		return
+	}
+
+	release, ok := s.acquireChat(w, r, sessionKey)
+	if !ok {
 		return
 	}
+	defer release()
 
 	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
 	defer cancel()
 
 	response, err := s.agentLoop.ProcessDirectWithChannel(ctx, req.Message, sessionKey, "web", "dashboard")
+	if errors.Is(err, agent.ErrSessionBusy) {
+		writeError(w, http.StatusConflict, ErrCodeSessionBusy, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -434,44 +744,127 @@ func (s *Server) handleAgentStatus(w http.ResponseWriter, r *http.Request) {
 	info["running"] = s.agentLoop.IsRunning()
 	info["model"] = s.agentLoop.GetModel()
 	info["workspace"] = s.agentLoop.GetWorkspace()
+	info["provider_metrics"] = s.agentLoop.GetProviderMetrics()
 
-	writeJSON(w, http.StatusOK, info)
+	writeJSON(w, http.StatusOK, s.redactSecrets(info))
 }
 
-func (s *Server) handleStaticFiles(w http.ResponseWriter, r *http.Request) {
-	var staticFS fs.FS
+// handleAgentDiagnostics runs the agent's self-test. It answers 200 with
+// the report either way; ok in the body says whether every check passed.
+//
+//	GET /api/agent/diagnostics
+func (s *Server) handleAgentDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	if s.agentLoop == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "agent not available", nil)
+		return
+	}
 
-	if s.webFS != nil {
-		staticFS = s.webFS
-	} else {
-		// Fallback: serve from local web/dist directory
-		staticFS = os.DirFS("web/dist")
+	report := s.agentLoop.Diagnose(r.Context())
+	for _, c := range report.Checks {
+		if c.Status != agent.CheckPass {
+			logger.WarnCtx(r.Context(), "api", "Agent diagnostic check failed", map[string]interface{}{
+				"check":  c.Name,
+				"detail": c.Detail,
+			})
+		}
 	}
+	writeJSON(w, http.StatusOK, report)
+}
 
-	// For SPA routing: if file doesn't exist, serve index.html
-	path := r.URL.Path
-	if path == "/" {
-		path = "/index.html"
+// handleAgentAudit pages through the agent's tool-call audit log, newest first.
+//
+//	GET /api/agent/audit?session=KEY&limit=N&offset=M
+func (s *Server) handleAgentAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+	if s.agentLoop == nil || s.agentLoop.GetAuditLog() == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "audit log not available", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
 	}
 
-	// Try to open the file
-	f, err := staticFS.Open(strings.TrimPrefix(path, "/"))
+	entries, total, err := s.agentLoop.GetAuditLog().List(r.Context(), q.Get("session"), limit, offset)
 	if err != nil {
-		// Serve index.html for SPA client-side routing
-		r.URL.Path = "/index.html"
-	} else {
-		f.Close()
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+		return
 	}
 
-	http.FileServer(http.FS(staticFS)).ServeHTTP(w, r)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
 }
 
 // --- Helpers ---
 
+// writeJSON encodes data as the response body. Structs keep their declared
+// field order; ad-hoc map responses go through utils.CanonicalJSON so their
+// output is byte-for-byte stable as well.
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	w.Write(encodeJSON(data))
+}
+
+// writeJSONCached is writeJSON for polled GET endpoints: it tags the body
+// with a weak ETag and answers 304 Not Modified when the client's
+// If-None-Match already names it.
+func writeJSONCached(w http.ResponseWriter, r *http.Request, data interface{}) {
+	body := encodeJSON(data)
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// encodeJSON renders a response body the way writeJSON sends it.
+func encodeJSON(data interface{}) []byte {
+	if data != nil && reflect.TypeOf(data).Kind() == reflect.Map {
+		if body, err := utils.CanonicalJSON(data); err == nil {
+			return append(body, '\n')
+		}
+	}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(data)
+	return buf.Bytes()
+}
+
+// etagMatches reports whether an If-None-Match header names etag, using
+// weak comparison.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
 }
 
 func formatDuration(d time.Duration) string {
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	big := strings.Repeat(`{"id":"TASK-001","title":"x"},`, 100)
+	h := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, big)
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ok":true}`)
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, big)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/big", "gzip, deflate")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("big: Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("big: Vary = %q", rec.Header().Get("Vary"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != big {
+		t.Errorf("big: decompressed body mismatch")
+	}
+
+	for _, tc := range []struct{ path, accept string }{
+		{"/big", ""},
+		{"/big", "gzip;q=0"},
+		{"/small", "gzip"},
+		{"/png", "gzip"},
+	} {
+		rec := get(tc.path, tc.accept)
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s (%q): unexpectedly compressed", tc.path, tc.accept)
+		}
+		if rec.Body.Len() == 0 {
+			t.Errorf("%s (%q): empty body", tc.path, tc.accept)
+		}
+	}
+
+	if rec := get("/empty", "gzip"); rec.Code != http.StatusNoContent {
+		t.Errorf("empty: status = %d, want 204", rec.Code)
+	}
+}
+
+func TestWriteJSONCached(t *testing.T) {
+	data := map[string]interface{}{"tasks": []string{"TASK-001"}}
+
+	rec := httptest.NewRecorder()
+	writeJSONCached(rec, httptest.NewRequest("GET", "/api/tasks", nil), data)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, etag = %q", rec.Code, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/api/tasks", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rec = httptest.NewRecorder()
+	writeJSONCached(rec, req, data)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("matching If-None-Match: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	writeJSONCached(rec, req, map[string]interface{}{"tasks": []string{"TASK-002"}})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed body: status = %d, etag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	h := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logger.RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/api/tasks", nil)
+	req.Header.Set("X-Request-ID", "dash-42.a")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen != "dash-42.a" || rec.Header().Get("X-Request-ID") != "dash-42.a" {
+		t.Errorf("client ID: context = %q, header = %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	for _, id := range []string{"", "bad id\nINJECTED", strings.Repeat("x", 129)} {
+		req := httptest.NewRequest("GET", "/api/tasks", nil)
+		req.Header.Set("X-Request-ID", id)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got := rec.Header().Get("X-Request-ID")
+		if got == id || len(got) != 16 || seen != got {
+			t.Errorf("ID %q: header = %q, context = %q, want a fresh ID in both", id, got, seen)
+		}
+	}
+}
+
+func TestTimezoneMiddleware(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	s := &Server{}
+	h := s.timezoneMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"title":      "2026-03-04T09:30:00Z",
+			"created_at": "2026-03-04T09:30:00Z",
+			"events":     []interface{}{map[string]interface{}{"at": "2026-07-01T12:00:00.5Z"}},
+			"notes":      []interface{}{map[string]interface{}{"content": "2026-07-01T12:00:00Z", "due_date": "2026-07-02T00:00:00Z"}},
+		})
+	}))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec
+	}
+
+	rec := get("/api/tasks")
+	if !strings.Contains(rec.Body.String(), `"2026-03-04T09:30:00Z"`) {
+		t.Errorf("no tz: body = %s, want UTC as stored", rec.Body.String())
+	}
+
+	rec = get("/api/tasks?tz=Europe/Berlin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("tz=Europe/Berlin: status = %d", rec.Code)
+	}
+	for _, want := range []string{
+		`"created_at":"2026-03-04T10:30:00+01:00"`, `"at":"2026-07-01T14:00:00.5+02:00"`, `"due_date":"2026-07-02T02:00:00+02:00"`,
+		`"title":"2026-03-04T09:30:00Z"`, `"content":"2026-07-01T12:00:00Z"`, // user content isn't rewritten
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("tz=Europe/Berlin: body = %s, missing %s", rec.Body.String(), want)
+		}
+	}
+
+	if rec := get("/api/tasks?tz=Mars/Olympus"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown tz: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestTimezoneMiddlewareFlush(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	h := s.timezoneMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"created_at":"2026-03-04T09:30:00Z"`))
+		w.(http.Flusher).Flush()
+		if !rec.Flushed || rec.Body.Len() == 0 {
+			t.Error("flushed response still held back")
+		}
+		w.Write([]byte(`}`))
+	}))
+
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tasks?tz=Asia/Tokyo", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"created_at":"2026-03-04T09:30:00Z"}` {
+		t.Errorf("streamed body = %d %s, want it sent as written", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gateway.RequestLimits = config.RequestLimitsConfig{MaxBodyBytes: 16, MaxDiffBodyBytes: 64}
+	s := &Server{config: cfg}
+	h := s.bodyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err, "invalid request body")
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	}))
+
+	post := func(target, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1 // length unknown until read
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	big := `{"content":"` + strings.Repeat("x", 40) + `"}`
+	if rec := post("/api/agent/chat", `{"a":1}`, false); rec.Code != http.StatusOK {
+		t.Errorf("small body: status = %d", rec.Code)
+	}
+	for _, chunked := range []bool{false, true} {
+		rec := post("/api/agent/chat", big, chunked)
+		if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), ErrCodeBodyTooLarge) {
+			t.Errorf("oversized body (chunked %v): status = %d, body %s", chunked, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := post("/api/vscode/diff/apply", big, true); rec.Code != http.StatusOK {
+		t.Errorf("diff endpoint under its higher limit: status = %d", rec.Code)
+	}
+	if rec := post("/api/agent/chat", `{"a":`, false); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+// Skills API — per-skill detail and recent run history.
+//
+// Routes:
+//   GET    /api/skills/{name}         — skill detail, including metrics
+//   GET    /api/skills/{name}/history — recent runs, newest first (limit)
+//   POST   /api/skills/{name}/dry-run — validate inputs and show the resolved command; runs nothing
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/app"
+)
+
+// SetSkillService attaches the skill service exposed under /api/skills.
+// Call before Start.
+func (s *Server) SetSkillService(svc *app.SkillService) {
+	s.skillService = svc
+}
+
+// handleSkillByName dispatches on /api/skills/{name}[/history].
+func (s *Server) handleSkillByName(w http.ResponseWriter, r *http.Request) {
+	if s.skillService == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "skills not available", nil)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/skills/")
+	name, action, _ := strings.Cut(path, "/")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "skill name required", nil)
+		return
+	}
+
+	if action == "dry-run" {
+		s.handleSkillDryRun(w, r, name)
+		return
+	}
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "GET required", nil)
+		return
+	}
+
+	switch action {
+	case "":
+		skill, err := s.skillService.GetSkillByName(name)
+		if err != nil {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "skill not found", nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, skill)
+	case "history":
+		limit := 0
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid limit", nil)
+				return
+			}
+			limit = n
+		}
+		runs, err := s.skillService.ExecutionHistory(name, limit)
+		if err != nil {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "skill not found", nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"skill": name,
+			"runs":  runs,
+		})
+	default:
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "unknown action", nil)
+	}
+}
+
+// skillDryRunRequest is the body of POST /api/skills/{name}/dry-run.
+type skillDryRunRequest struct {
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+// handleSkillDryRun resolves a skill's command for the given inputs.
+// Body: { inputs: {...} }
+func (s *Server) handleSkillDryRun(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
+		return
+	}
+
+	var req skillDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBodyError(w, err, "invalid request body")
+		return
+	}
+
+	res, err := s.skillService.DryRun(name, req.Inputs)
+	if err != nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "skill not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
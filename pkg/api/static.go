@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Cache policies for the dashboard bundle. Files under assets/ carry a
+// content hash in their name (the Vite build convention), so they never
+// change and can be cached for good; index.html must always be revalidated
+// so a new build is picked up.
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+	cacheShort      = "public, max-age=3600"
+)
+
+// staticTypes covers extensions the mime package doesn't know everywhere.
+var staticTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".svg":         "image/svg+xml",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".txt":         "text/plain; charset=utf-8",
+}
+
+// handleStaticFiles serves the embedded dashboard. Unknown paths without an
+// extension are client-side routes and get index.html; missing files and
+// unknown /api/ paths get a plain 404 instead.
+func (s *Server) handleStaticFiles(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "not found", nil)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var staticFS fs.FS
+	if s.webFS != nil {
+		staticFS = s.webFS
+	} else {
+		// Fallback: serve from local web/dist directory
+		staticFS = os.DirFS("web/dist")
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	if serveStaticFile(w, r, staticFS, name) {
+		return
+	}
+	if path.Ext(name) != "" {
+		http.NotFound(w, r)
+		return
+	}
+	// SPA client-side route
+	if !serveStaticFile(w, r, staticFS, "index.html") {
+		http.NotFound(w, r)
+	}
+}
+
+// serveStaticFile writes name from fsys with its content type and cache
+// policy. It returns false, writing nothing, if name is missing or a
+// directory.
+func serveStaticFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+		content = bytes.NewReader(data)
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	ctype := staticTypes[ext]
+	if ctype == "" {
+		ctype = mime.TypeByExtension(ext)
+	}
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	w.Header().Set("Cache-Control", staticCachePolicy(name))
+
+	// Embedded files have a zero ModTime, for which ServeContent omits
+	// Last-Modified.
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return true
+}
+
+func staticCachePolicy(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".html"):
+		return cacheRevalidate
+	case strings.HasPrefix(name, "assets/"):
+		return cacheImmutable
+	default:
+		return cacheShort
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHandleStaticFiles(t *testing.T) {
+	s := &Server{webFS: fstest.MapFS{
+		"index.html":                 {Data: []byte("<html>app</html>")},
+		"assets/index-a1B2c3D4.js":   {Data: []byte("console.log(1)")},
+		"assets/font-9f8e7d6c.woff2": {Data: []byte("wOF2")},
+		"favicon.svg":                {Data: []byte("<svg/>")},
+	}}
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		cache       string
+	}{
+		{"/", 200, "text/html; charset=utf-8", cacheRevalidate},
+		{"/tasks/TASK-001", 200, "text/html; charset=utf-8", cacheRevalidate},
+		{"/assets/index-a1B2c3D4.js", 200, "text/javascript; charset=utf-8", cacheImmutable},
+		{"/assets/font-9f8e7d6c.woff2", 200, "font/woff2", cacheImmutable},
+		{"/favicon.svg", 200, "image/svg+xml", cacheShort},
+		{"/assets/missing.js", 404, "", ""},
+		{"/api/nope", 404, "application/json", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.handleStaticFiles(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.path, rec.Code, tt.status)
+			continue
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+		if rec.Header().Get("Cache-Control") != tt.cache {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.path, rec.Header().Get("Cache-Control"), tt.cache)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleStaticFiles(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// logAppendRequest is the body of POST /api/tasks/{id}/logs: one line, or
+// several in order.
+type logAppendRequest struct {
+	Line  string   `json:"line,omitempty"`
+	Lines []string `json:"lines,omitempty"`
+}
+
+// taskLogsResponse is the body of GET /api/tasks/{id}/logs.
+type taskLogsResponse struct {
+	TaskID string               `json:"task_id"`
+	Lines  []kanban.TaskLogLine `json:"lines"`
+	// Next is the seq to pass as ?after= for the following page.
+	Next int64 `json:"next"`
+}
+
+// handleTaskLogs reads (GET ?after=&limit=) or appends to (POST) a task's
+// execution log.
+func (s *Server) handleTaskLogs(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
+	switch r.Method {
+	case "GET":
+		after, err := parseLogSeq(r.URL.Query().Get("after"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid after", nil)
+			return
+		}
+		limit := 0
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid limit", nil)
+				return
+			}
+			limit = n
+		}
+		if _, err := kb.GetTaskCtx(r.Context(), id); err != nil {
+			writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, "task not found", nil)
+			return
+		}
+		lines, err := kb.ListLogsCtx(r.Context(), id, after, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+			return
+		}
+		if lines == nil {
+			lines = []kanban.TaskLogLine{}
+		}
+		next := after
+		if len(lines) > 0 {
+			next = lines[len(lines)-1].Seq
+		}
+		writeJSON(w, http.StatusOK, taskLogsResponse{TaskID: id, Lines: lines, Next: next})
+	case "POST":
+		var req logAppendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err, "invalid request body")
+			return
+		}
+		lines := req.Lines
+		if req.Line != "" {
+			lines = append([]string{req.Line}, lines...)
+		}
+		if len(lines) == 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeMissingField, "line or lines required", nil)
+			return
+		}
+		if err := kb.AppendLogCtx(r.Context(), id, strings.Join(lines, "\n")); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "appended", "id": id})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
+	}
+}
+
+// handleTaskLogStream tails a task's execution log over a WebSocket: the
+// kept lines after ?after= first, then new lines as they are appended, one
+// TaskLogLine JSON message each.
+func (s *Server) handleTaskLogStream(w http.ResponseWriter, r *http.Request, kb *kanban.KanbanIntegration, id string) {
+	after, err := parseLogSeq(r.URL.Query().Get("after"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, "invalid after", nil)
+		return
+	}
+	if _, err := kb.GetTaskCtx(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, ErrCodeTaskNotFound, "task not found", nil)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.wsHub.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.ErrorCtx(r.Context(), "ws", "WebSocket upgrade failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	lines, err := kb.StreamLogs(ctx, id, after)
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+		return
+	}
+
+	// The client only reads; its reads surface the close and keep pongs flowing
+	go func() {
+		defer cancel()
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			return nil
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(l); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLogSeq reads an ?after= log position; empty means the start.
+func parseLogSeq(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err == nil && n < 0 {
+		err = strconv.ErrRange
+	}
+	return n, err
+}
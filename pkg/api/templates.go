@@ -15,7 +15,7 @@ import (
 // GET /api/bot-templates — list all available bot templates.
 func (s *Server) handleListBotTemplates(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
@@ -24,31 +24,34 @@ func (s *Server) handleListBotTemplates(w http.ResponseWriter, r *http.Request)
 
 	// Build a clean representation — never expose secret default values
 	type templateView struct {
-		Name        string                    `json:"name"`
-		DisplayName string                    `json:"display_name"`
-		Description string                    `json:"description"`
-		Version     string                    `json:"version"`
-		Channel     string                    `json:"channel"`
-		Soul        string                    `json:"soul"`
-		Tools       []string                  `json:"tools"`
-		Cron        string                    `json:"cron,omitempty"`
-		Params      []templates.TemplateParam `json:"params"`
-		Builtin     bool                      `json:"builtin"`
+		Name         string                    `json:"name"`
+		DisplayName  string                    `json:"display_name"`
+		Description  string                    `json:"description"`
+		Version      string                    `json:"version"`
+		Channel      string                    `json:"channel"`
+		Soul         string                    `json:"soul"`
+		Tools        []string                  `json:"tools"`
+		Cron         string                    `json:"cron,omitempty"`
+		Params       []templates.TemplateParam `json:"params"`
+		Builtin      bool                      `json:"builtin"`
+		UnknownTools []string                  `json:"unknown_tools,omitempty"` // tools the agent doesn't have; instantiation fails until they exist
 	}
 
+	knownTools := s.knownToolNames()
 	views := make([]templateView, 0, len(list))
 	for _, t := range list {
 		views = append(views, templateView{
-			Name:        t.Name,
-			DisplayName: t.DisplayName,
-			Description: t.Description,
-			Version:     t.Version,
-			Channel:     t.Channel,
-			Soul:        t.Soul,
-			Tools:       t.Tools,
-			Cron:        t.Cron,
-			Params:      t.Params,
-			Builtin:     t.Builtin,
+			Name:         t.Name,
+			DisplayName:  t.DisplayName,
+			Description:  t.Description,
+			Version:      t.Version,
+			Channel:      t.Channel,
+			Soul:         t.Soul,
+			Tools:        t.Tools,
+			Cron:         t.Cron,
+			Params:       t.Params,
+			Builtin:      t.Builtin,
+			UnknownTools: t.UnknownTools(knownTools),
 		})
 	}
 
@@ -74,18 +77,18 @@ func (s *Server) handleListBotTemplates(w http.ResponseWriter, r *http.Request)
 //	}
 func (s *Server) handleCreateBotFromTemplate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
 	var req templates.InstantiateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Template == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "template name is required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "template name is required", nil)
 		return
 	}
 
@@ -93,46 +96,176 @@ func (s *Server) handleCreateBotFromTemplate(w http.ResponseWriter, r *http.Requ
 	reg := templates.Global()
 	tmpl, ok := reg.Get(req.Template)
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{
-			"error": fmt.Sprintf("template '%s' not found", req.Template),
-		})
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("template '%s' not found", req.Template), nil)
 		return
 	}
 
-	// Validate required params
-	if missing := tmpl.Validate(req.Params); len(missing) > 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error":   "missing required parameters",
-			"missing": missing,
-		})
+	// Validate required params and the template's tools
+	if v := tmpl.Validate(req.Params, s.knownToolNames()); !v.OK() {
+		writeTemplateValidationError(w, v)
 		return
 	}
 
-	// Resolve bot ID: explicit override → template name → slug
-	botID := req.BotID
-	if botID == "" {
-		botID = tmpl.Name
-	}
-	botID = strings.ToLower(strings.ReplaceAll(botID, " ", "-"))
+	bot := resolveTemplateBot(tmpl, req)
+	botID := bot.id
 
 	if s.channelManager == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "channel manager not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "channel manager not available", nil)
 		return
 	}
 
 	// Check for existing bot with this ID
 	if _, exists := s.channelManager.GetChannel(botID); exists {
-		writeJSON(w, http.StatusConflict, map[string]string{
-			"error": fmt.Sprintf("a bot with id '%s' already exists", botID),
-		})
+		writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("a bot with id '%s' already exists", botID), nil)
+		return
+	}
+
+	// Delegate to the existing updateChannelConfig mechanism
+	if err := s.updateChannelConfig(tmpl.Channel, bot.token, bot.config, bot.allowFrom); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), nil)
+		return
+	}
+
+	logger.InfoCtx(r.Context(), "api", "Bot instantiated from template", map[string]interface{}{
+		"bot_id":   botID,
+		"template": tmpl.Name,
+		"channel":  tmpl.Channel,
+	})
+
+	// Broadcast creation event
+	s.wsHub.Broadcast("bot.created", map[string]interface{}{
+		"bot_id":   botID,
+		"template": tmpl.Name,
+		"channel":  tmpl.Channel,
+		"source":   "template",
+	})
+
+	resp := map[string]interface{}{
+		"id":       botID,
+		"template": tmpl.Name,
+		"channel":  tmpl.Channel,
+		"status":   "created",
+		"message":  fmt.Sprintf("Bot '%s' created from template '%s'.", botID, tmpl.Name),
+	}
+	if req.AutoStart {
+		resp["message"] = fmt.Sprintf("Bot '%s' created from template '%s'. Use POST /api/bots/%s/start to start it.", botID, tmpl.Name, botID)
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// templatePreview is the response of POST /api/bot-templates/{name}/preview.
+type templatePreview struct {
+	Bot        BotInfo                    `json:"bot"`
+	Valid      bool                       `json:"valid"`
+	Validation templates.ValidationResult `json:"validation"`
+	Exists     bool                       `json:"exists"` // a bot with this ID already exists, so creating it would conflict
+}
+
+// handleBotTemplateByName dispatches /api/bot-templates/{name}/... requests.
+func (s *Server) handleBotTemplateByName(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/bot-templates/"), "/", 2)
+	if len(parts) == 2 && parts[1] == "preview" {
+		s.handlePreviewBotTemplate(w, r, parts[0])
+		return
+	}
+	writeError(w, http.StatusNotFound, ErrCodeNotFound, "not found", nil)
+}
+
+// POST /api/bot-templates/{name}/preview — show the bot a template would
+// produce for the given params without creating it. The body is the same as
+// POST /api/bots/from-template (the template comes from the path). Secret
+// params are masked, and validation problems are returned alongside the
+// preview rather than as an error.
+func (s *Server) handlePreviewBotTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var req templates.InstantiateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err, "invalid request body")
+			return
+		}
+	}
+
+	tmpl, ok := templates.Global().Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("template '%s' not found", name), nil)
 		return
 	}
 
+	v := tmpl.Validate(req.Params, s.knownToolNames())
+	bot := resolveTemplateBot(tmpl, req)
+
+	// Mask secret params first, then apply the usual key-based redaction.
+	masked := make(map[string]string, len(req.Params))
+	for k, val := range tmpl.ResolvedParams(req.Params) {
+		if tmpl.IsSecret(k) {
+			val = "********"
+		}
+		masked[k] = val
+	}
+	cfg := map[string]interface{}{
+		"allow_from": bot.allowFrom,
+	}
+	if tmpl.IsSecret("token") {
+		cfg["has_token"] = bot.token != ""
+	} else if bot.token != "" {
+		cfg["token"] = bot.token
+	}
+	for k, val := range bot.config {
+		if tmpl.IsSecret(k) {
+			cfg["has_"+k] = val != ""
+			continue
+		}
+		cfg[k] = val
+	}
+	cfg["soul"] = tmpl.ExpandSoul(masked)
+
+	exists := false
+	if s.channelManager != nil {
+		_, exists = s.channelManager.GetChannel(bot.id)
+	}
+
+	writeJSON(w, http.StatusOK, templatePreview{
+		Bot: BotInfo{
+			ID:      bot.id,
+			Type:    tmpl.Channel,
+			Enabled: true,
+			Config:  s.redactSecrets(cfg),
+		},
+		Valid:      v.OK(),
+		Validation: v,
+		Exists:     exists,
+	})
+}
+
+// templateBot is what instantiating a template resolves to: the bot ID and
+// the arguments for updateChannelConfig.
+type templateBot struct {
+	id        string
+	token     string
+	allowFrom []string
+	config    map[string]string
+}
+
+// resolveTemplateBot merges req into tmpl's defaults the way
+// from-template instantiation does. It doesn't validate.
+func resolveTemplateBot(tmpl *templates.BotTemplate, req templates.InstantiateRequest) templateBot {
+	// Resolve bot ID: explicit override → template name → slug
+	botID := req.BotID
+	if botID == "" {
+		botID = tmpl.Name
+	}
+	botID = strings.ToLower(strings.ReplaceAll(botID, " ", "-"))
+
 	// Resolve params (merge defaults + provided)
 	resolved := tmpl.ResolvedParams(req.Params)
 
 	// Extract standard fields from resolved params
-	token := resolved["token"]
 	allowFrom := req.AllowFrom
 	if len(allowFrom) == 0 && resolved["allow_from"] != "" {
 		// Parse comma-separated allow_from from params
@@ -149,7 +282,7 @@ func (s *Server) handleCreateBotFromTemplate(w http.ResponseWriter, r *http.Requ
 
 	// Build extended config from remaining resolved params + template metadata
 	extraConfig := map[string]string{
-		"soul":         tmpl.Soul,
+		"soul":         tmpl.ExpandSoul(resolved),
 		"template":     tmpl.Name,
 		"display_name": tmpl.DisplayName,
 	}
@@ -162,36 +295,25 @@ func (s *Server) handleCreateBotFromTemplate(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// Delegate to the existing updateChannelConfig mechanism
-	if err := s.updateChannelConfig(tmpl.Channel, token, extraConfig, allowFrom); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-
-	logger.InfoCF("api", "Bot instantiated from template", map[string]interface{}{
-		"bot_id":   botID,
-		"template": tmpl.Name,
-		"channel":  tmpl.Channel,
-	})
-
-	// Broadcast creation event
-	s.wsHub.Broadcast("bot.created", map[string]interface{}{
-		"bot_id":   botID,
-		"template": tmpl.Name,
-		"channel":  tmpl.Channel,
-		"source":   "template",
-	})
+	return templateBot{id: botID, token: resolved["token"], allowFrom: allowFrom, config: extraConfig}
+}
 
-	resp := map[string]interface{}{
-		"id":       botID,
-		"template": tmpl.Name,
-		"channel":  tmpl.Channel,
-		"status":   "created",
-		"message":  fmt.Sprintf("Bot '%s' created from template '%s'.", botID, tmpl.Name),
-	}
-	if req.AutoStart {
-		resp["message"] = fmt.Sprintf("Bot '%s' created from template '%s'. Use POST /api/bots/%s/start to start it.", botID, tmpl.Name, botID)
+// knownToolNames lists the agent's registered tools, or nil when there is
+// no agent to check against.
+func (s *Server) knownToolNames() []string {
+	if s.agentLoop == nil || s.agentLoop.GetToolRegistry() == nil {
+		return nil
 	}
+	return s.agentLoop.GetToolRegistry().List()
+}
 
-	writeJSON(w, http.StatusCreated, resp)
+// writeTemplateValidationError reports missing params before unknown tools;
+// details carry both lists.
+func writeTemplateValidationError(w http.ResponseWriter, v templates.ValidationResult) {
+	if len(v.Missing) > 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "missing required parameters", v)
+		return
+	}
+	writeError(w, http.StatusBadRequest, ErrCodeUnknownTool,
+		fmt.Sprintf("template uses unknown tools: %s", strings.Join(v.UnknownTools, ", ")), v)
 }
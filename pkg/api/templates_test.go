@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels/templates"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestPreviewBotTemplate(t *testing.T) {
+	templates.Global().Register(&templates.BotTemplate{
+		Name:    "preview-test",
+		Channel: "telegram",
+		Soul:    "You watch {{project}} for {{token}}.",
+		Params: []templates.TemplateParam{
+			{Name: "token", Required: true, Secret: true},
+			{Name: "project", Default: "picoclaw"},
+			{Name: "allow_from"},
+		},
+	})
+
+	s := &Server{}
+	preview := func(body string) (int, templatePreview) {
+		rec := httptest.NewRecorder()
+		s.handleBotTemplateByName(rec, httptest.NewRequest("POST", "/api/bot-templates/preview-test/preview", strings.NewReader(body)))
+		var p templatePreview
+		json.Unmarshal(rec.Body.Bytes(), &p)
+		return rec.Code, p
+	}
+
+	code, p := preview(`{"params":{"token":"12345:SECRET","allow_from":"1, 2"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if !p.Valid || p.Bot.ID != "preview-test" || p.Bot.Type != "telegram" {
+		t.Errorf("preview = %+v", p)
+	}
+	cfg := p.Bot.Config
+	if cfg["soul"] != "You watch picoclaw for ********." {
+		t.Errorf("soul = %q", cfg["soul"])
+	}
+	if cfg["has_token"] != true || cfg["token"] != nil {
+		t.Errorf("token not masked: %v", cfg)
+	}
+	if from, _ := cfg["allow_from"].([]interface{}); len(from) != 2 {
+		t.Errorf("allow_from = %v", cfg["allow_from"])
+	}
+	raw, _ := json.Marshal(p)
+	if strings.Contains(string(raw), "SECRET") {
+		t.Errorf("secret leaked: %s", raw)
+	}
+
+	code, p = preview(`{}`)
+	if code != http.StatusOK || p.Valid || len(p.Validation.Missing) != 1 || p.Validation.Missing[0] != "token" {
+		t.Errorf("missing params: status %d, %+v", code, p)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleBotTemplateByName(rec, httptest.NewRequest("POST", "/api/bot-templates/nope/preview", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown template: status = %d", rec.Code)
+	}
+}
+
+// stubProvider satisfies providers.LLMProvider for tests that only need an
+// agent's tool registry.
+type stubProvider struct{}
+
+func (stubProvider) Chat(context.Context, []providers.Message, []providers.ToolDefinition, string, map[string]interface{}) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{}, nil
+}
+
+func (stubProvider) GetDefaultModel() string { return "stub" }
+
+// newToolsServer returns a Server whose agent has the default tool registry.
+func newToolsServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	return &Server{agentLoop: agent.NewAgentLoop(cfg, bus.NewMessageBus(), stubProvider{})}
+}
+
+func TestCreateBotFromTemplateUnknownTools(t *testing.T) {
+	templates.Global().Register(&templates.BotTemplate{
+		Name:    "unknown-tools-test",
+		Channel: "telegram",
+		Tools:   []string{"message", "filesystem"},
+		Params:  []templates.TemplateParam{{Name: "token", Required: true}},
+	})
+	s := newToolsServer(t)
+
+	create := func(body string) (int, APIError) {
+		rec := httptest.NewRecorder()
+		s.handleCreateBotFromTemplate(rec, httptest.NewRequest("POST", "/api/bots/from-template", strings.NewReader(body)))
+		var e APIError
+		json.Unmarshal(rec.Body.Bytes(), &e)
+		return rec.Code, e
+	}
+
+	code, e := create(`{"template":"unknown-tools-test","params":{"token":"x"}}`)
+	if code != http.StatusBadRequest || e.Code != ErrCodeUnknownTool || e.Message != "template uses unknown tools: filesystem" {
+		t.Errorf("unknown tool: status %d, %+v", code, e)
+	}
+	if details, _ := e.Details.(map[string]interface{}); details == nil || len(details["unknown_tools"].([]interface{})) != 1 {
+		t.Errorf("unknown tool details = %v", e.Details)
+	}
+
+	// Missing params are reported first, with the unknown tools alongside
+	code, e = create(`{"template":"unknown-tools-test"}`)
+	details, _ := e.Details.(map[string]interface{})
+	if code != http.StatusBadRequest || e.Code != ErrCodeMissingField || details["missing"] == nil || details["unknown_tools"] == nil {
+		t.Errorf("missing param: status %d, %+v", code, e)
+	}
+}
+
+func TestShippedTemplatesUseKnownTools(t *testing.T) {
+	s := newToolsServer(t)
+	known := append(s.knownToolNames(), "cron") // the gateway registers cron on top of the agent's tools
+	reg := templates.NewRegistry()
+	if _, errs := reg.Load("../../templates/bots"); len(errs) > 0 {
+		t.Fatalf("load templates: %v", errs)
+	}
+	for _, tmpl := range reg.List() {
+		if unknown := tmpl.UnknownTools(known); len(unknown) > 0 {
+			t.Errorf("template %s lists unknown tools %v", tmpl.Name, unknown)
+		}
+	}
+}
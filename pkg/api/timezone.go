@@ -0,0 +1,185 @@
+// Response timezones — timestamps are stored and produced in UTC; on read
+// endpoints they are converted to the zone named by ?tz= (an IANA name such
+// as "Europe/Berlin"), or to agents.defaults.timezone when that is set.
+// Only the presentation changes: each RFC3339 string in a timestamp field of
+// a JSON response is rewritten as the same instant in the requested zone.
+// Other strings, such as task titles and note bodies, are left alone even
+// when they look like timestamps.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// timezoneMiddleware converts the timestamps in JSON responses to GET
+// requests under /api/. An unknown ?tz= is rejected with 400.
+func (s *Server) timezoneMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != "GET" && r.Method != "HEAD") || !strings.HasPrefix(r.URL.Path, "/api/") ||
+			strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		loc, err := s.responseLocation(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error(), nil)
+			return
+		}
+		if loc == nil || loc == time.UTC {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tw := &tzResponseWriter{ResponseWriter: w, loc: loc}
+		next.ServeHTTP(tw, r)
+		tw.finish()
+	})
+}
+
+// responseLocation is the zone a read should be presented in: ?tz= when
+// given, else the configured timezone, else nil for UTC as stored.
+func (s *Server) responseLocation(r *http.Request) (*time.Location, error) {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q; use an IANA name such as \"Europe/Berlin\"", tz)
+		}
+		return loc, nil
+	}
+	if s.config != nil && s.config.Agents.Defaults.Timezone != "" {
+		return s.config.Location(), nil
+	}
+	return nil, nil
+}
+
+// tzResponseWriter holds back a successful JSON body so its timestamps can
+// be converted; anything else is passed straight through. A handler that
+// flushes is streaming, so its body is sent as written.
+type tzResponseWriter struct {
+	http.ResponseWriter
+	loc     *time.Location
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	hold    bool
+}
+
+func (t *tzResponseWriter) WriteHeader(code int) {
+	if t.decided {
+		return
+	}
+	t.decided = true
+	t.status = code
+	ct := t.Header().Get("Content-Type")
+	t.hold = code == http.StatusOK && strings.HasPrefix(ct, "application/json")
+	if !t.hold {
+		t.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (t *tzResponseWriter) Write(p []byte) (int, error) {
+	if !t.decided {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.hold {
+		return t.buf.Write(p)
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+// Flush sends whatever is held unconverted and stops holding, so streamed
+// responses aren't buffered until the handler returns.
+func (t *tzResponseWriter) Flush() {
+	if !t.decided {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.hold {
+		t.hold = false
+		t.ResponseWriter.WriteHeader(t.status)
+		t.ResponseWriter.Write(t.buf.Bytes())
+		t.buf.Reset()
+	}
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (t *tzResponseWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// finish writes the held body with its timestamps converted. A body that
+// doesn't parse is sent unchanged.
+func (t *tzResponseWriter) finish() {
+	if !t.hold {
+		return
+	}
+	body := t.buf.Bytes()
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err == nil {
+		var out bytes.Buffer
+		if err := json.NewEncoder(&out).Encode(convertTimestamps(v, t.loc)); err == nil {
+			body = out.Bytes()
+		}
+	}
+	t.Header().Del("Content-Length")
+	t.ResponseWriter.WriteHeader(t.status)
+	t.ResponseWriter.Write(body)
+}
+
+// timestampKeys are the response fields holding instants whose names don't
+// end in _at, _date or _since.
+var timestampKeys = map[string]bool{
+	"at":        true,
+	"time":      true,
+	"timestamp": true,
+	"created":   true,
+	"updated":   true,
+}
+
+// isTimestampKey reports whether a JSON field named key holds an instant.
+func isTimestampKey(key string) bool {
+	return timestampKeys[key] || strings.HasSuffix(key, "_at") ||
+		strings.HasSuffix(key, "_date") || strings.HasSuffix(key, "_since")
+}
+
+// convertTimestamps rewrites the RFC3339 strings in the timestamp fields of
+// a decoded JSON value as the same instant in loc.
+func convertTimestamps(v interface{}, loc *time.Location) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if s, ok := e.(string); ok {
+				if isTimestampKey(k) {
+					x[k] = convertTimestamp(s, loc)
+				}
+				continue
+			}
+			x[k] = convertTimestamps(e, loc)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = convertTimestamps(e, loc)
+		}
+	}
+	return v
+}
+
+// convertTimestamp returns s as the same instant in loc, or unchanged if it
+// isn't RFC3339.
+func convertTimestamp(s string, loc *time.Location) string {
+	// Cheap shape check before parsing: "2006-01-02T…".
+	if len(s) < 20 || s[4] != '-' || s[10] != 'T' {
+		return s
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts.In(loc).Format(time.RFC3339Nano)
+	}
+	return s
+}
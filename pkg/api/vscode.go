@@ -5,20 +5,24 @@
 //   GET    /api/vscode/status      — extension status bar data
 //   POST   /api/vscode/todo        — send TODO from editor to kanban
 //   POST   /api/vscode/ask         — ask coding bot a question
-//   POST   /api/vscode/diff/apply  — apply a structured diff from extension
+//   POST   /api/vscode/diff/apply  — apply+verify a structured diff (approval-gated, once per diff id)
 //   POST   /api/vscode/diff/preview — validate diff without applying
-//   GET    /api/vscode/tasks       — get assigned/available tasks for coding
-//   POST   /api/vscode/tasks/{id}/claim — claim a task from the extension
+//   GET    /api/vscode/tasks       — get claimable/own tasks for coding (?agent_id=&category=)
+//   POST   /api/vscode/tasks/{id}/claim — claim a task as {agent_id, lease_seconds}
 package api
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/codex"
 	"github.com/sipeed/picoclaw/pkg/integration/kanban"
@@ -53,67 +57,73 @@ func (s *Server) handleVSCode(w http.ResponseWriter, r *http.Request) {
 		taskID := strings.TrimSuffix(strings.TrimPrefix(path, "/tasks/"), "/claim")
 		s.handleVSCodeClaimTask(w, r, taskID)
 	default:
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown vscode endpoint"})
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "unknown vscode endpoint", nil)
 	}
 }
 
+// vscodeStatus is the status bar payload for the extension.
+type vscodeStatus struct {
+	AgentRunning  bool   `json:"agent_running"`
+	Model         string `json:"model"`
+	TasksTodo     int    `json:"tasks_todo"`
+	TasksProgress int    `json:"tasks_progress"`
+	TasksTotal    int    `json:"tasks_total"`
+	Workspace     string `json:"workspace"`
+}
+
 // handleVSCodeStatus returns status bar data for the extension.
-// Response: { tasks_todo, tasks_in_progress, agent_running, model }
+// Response: { agent_running, model, tasks_todo, tasks_progress, tasks_total, workspace }
 func (s *Server) handleVSCodeStatus(w http.ResponseWriter, r *http.Request) {
-	result := map[string]interface{}{
-		"agent_running": false,
-		"model":         "",
-		"tasks_todo":    0,
-		"tasks_progress": 0,
-		"tasks_total":   0,
-		"workspace":     "",
-	}
+	var result vscodeStatus
 
 	if s.agentLoop != nil {
-		result["agent_running"] = s.agentLoop.IsRunning()
-		result["model"] = s.agentLoop.GetModel()
-		result["workspace"] = s.agentLoop.GetWorkspace()
+		result.AgentRunning = s.agentLoop.IsRunning()
+		result.Model = s.agentLoop.GetModel()
+		result.Workspace = s.agentLoop.GetWorkspace()
 	}
 
 	if kb := s.getKanban(); kb != nil {
-		if stats, err := kb.GetBoardStats(); err == nil {
-			result["tasks_todo"] = stats["inbox"] + stats["planned"]
-			result["tasks_progress"] = stats["running"]
-			result["tasks_total"] = stats["total"]
+		if stats, err := kb.GetBoardStatsCtx(r.Context()); err == nil {
+			result.TasksTodo = stats["inbox"] + stats["planned"]
+			result.TasksProgress = stats["running"]
+			result.TasksTotal = stats["total"]
 		}
 	}
 
 	writeJSON(w, http.StatusOK, result)
 }
 
+// vscodeTodoRequest is the body of POST /api/vscode/todo.
+type vscodeTodoRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	File        string `json:"file"`     // source file path
+	Line        int    `json:"line"`     // line number
+	Category    string `json:"category"`
+	Priority    string `json:"priority"`
+}
+
 // handleVSCodeTodo creates a task from a TODO comment or selection in the editor.
 func (s *Server) handleVSCodeTodo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		File        string `json:"file"`     // source file path
-		Line        int    `json:"line"`     // line number
-		Category    string `json:"category"`
-		Priority    string `json:"priority"`
-	}
+	var req vscodeTodoRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Title == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "title required", nil)
 		return
 	}
 
 	kb := s.getKanban()
 	if kb == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "kanban not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "kanban not available", nil)
 		return
 	}
 
@@ -141,38 +151,41 @@ func (s *Server) handleVSCodeTodo(w http.ResponseWriter, r *http.Request) {
 		task.Priority = "normal"
 	}
 
-	if err := kb.CreateTask(task); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if err := kb.CreateTaskCtx(r.Context(), task); err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, task)
 }
 
+// vscodeAskRequest is the body of POST /api/vscode/ask.
+type vscodeAskRequest struct {
+	Question string `json:"question"`
+	Context  string `json:"context"` // selected code or file content
+	File     string `json:"file"`
+}
+
 // handleVSCodeAsk sends a question to the coding agent and returns the response.
 func (s *Server) handleVSCodeAsk(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		Question string `json:"question"`
-		Context  string `json:"context"` // selected code or file content
-		File     string `json:"file"`
-	}
+	var req vscodeAskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	if req.Question == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "question required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "question required", nil)
 		return
 	}
 
 	if s.agentLoop == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "agent not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "agent not available", nil)
 		return
 	}
 
@@ -185,12 +198,22 @@ func (s *Server) handleVSCodeAsk(w http.ResponseWriter, r *http.Request) {
 		prompt = "File: " + req.File + "\n" + prompt
 	}
 
+	release, ok := s.acquireChat(w, r, "vscode:extension")
+	if !ok {
+		return
+	}
+	defer release()
+
 	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
 	defer cancel()
 
 	response, err := s.agentLoop.ProcessDirectWithChannel(ctx, prompt, "vscode:extension", "vscode", "extension")
+	if errors.Is(err, agent.ErrSessionBusy) {
+		writeError(w, http.StatusConflict, ErrCodeSessionBusy, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -199,207 +222,570 @@ func (s *Server) handleVSCodeAsk(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// diffPreviewFailure reports the stage at which a previewed diff was rejected.
+type diffPreviewFailure struct {
+	Valid  bool   `json:"valid"`
+	Error  string `json:"error"`
+	Stage  string `json:"stage"` // parse, validate, workspace, preconditions
+	DiffID string `json:"diff_id,omitempty"`
+}
+
+// diffPreviewResult describes a diff that passed preview.
+type diffPreviewResult struct {
+	Valid     bool   `json:"valid"`
+	DiffID    string `json:"diff_id"`
+	TaskID    string `json:"task_id"`
+	Changes   int    `json:"changes"`
+	HasVerify bool   `json:"has_verify"`
+	Summary   string `json:"summary"`
+}
+
+// diffPreviewRequest is the body of POST /api/vscode/diff/preview.
+type diffPreviewRequest struct {
+	Diff      string `json:"diff"`      // raw JSON diff from agent
+	Workspace string `json:"workspace"` // workspace root for precondition check
+}
+
 // handleVSCodeDiffPreview validates a structured diff without applying it.
 func (s *Server) handleVSCodeDiffPreview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		Diff      string `json:"diff"`      // raw JSON diff from agent
-		Workspace string `json:"workspace"` // workspace root for precondition check
-	}
+	var req diffPreviewRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	diff, err := codex.ParseDiff(req.Diff)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"valid":  false,
-			"error":  err.Error(),
-			"stage":  "parse",
-		})
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidDiff, err.Error(), map[string]string{"stage": "parse"})
 		return
 	}
 
-	if err := diff.Validate(); err != nil {
-		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"valid":   false,
-			"error":   err.Error(),
-			"stage":   "validate",
-			"diff_id": diff.ID,
-		})
+	if err := diff.ValidateLimits(s.diffLimits()); err != nil {
+		writeJSON(w, http.StatusOK, diffPreviewFailure{Error: err.Error(), Stage: "validate", DiffID: diff.ID})
 		return
 	}
 
-	// Check preconditions if workspace provided
-	workspace := req.Workspace
-	if workspace == "" && s.config != nil {
-		workspace = s.config.WorkspacePath()
+	// Check preconditions if a workspace resolves
+	workspace, err := s.resolveWorkspace(r.Context(), req.Workspace, diff.TaskID)
+	if err != nil && !errors.Is(err, errNoWorkspace) {
+		writeJSON(w, http.StatusOK, diffPreviewFailure{Error: err.Error(), Stage: "workspace", DiffID: diff.ID})
+		return
 	}
 
 	if workspace != "" {
 		if err := diff.CheckPreconditions(workspace); err != nil {
-			writeJSON(w, http.StatusOK, map[string]interface{}{
-				"valid":   false,
-				"error":   err.Error(),
-				"stage":   "preconditions",
-				"diff_id": diff.ID,
-			})
+			writeJSON(w, http.StatusOK, diffPreviewFailure{Error: err.Error(), Stage: "preconditions", DiffID: diff.ID})
 			return
 		}
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"valid":        true,
-		"diff_id":      diff.ID,
-		"task_id":      diff.TaskID,
-		"changes":      len(diff.Changes),
-		"has_verify":   diff.Verify != nil,
-		"summary":      diff.Summary,
+	writeJSON(w, http.StatusOK, diffPreviewResult{
+		Valid:     true,
+		DiffID:    diff.ID,
+		TaskID:    diff.TaskID,
+		Changes:   len(diff.Changes),
+		HasVerify: diff.Verify != nil,
+		Summary:   diff.Summary,
 	})
 }
 
-// handleVSCodeDiffApply applies a validated structured diff to the workspace.
+// diffApplyRequest is the body of POST /api/vscode/diff/apply.
+type diffApplyRequest struct {
+	Diff      string `json:"diff"`
+	Workspace string `json:"workspace"`
+	Force     bool   `json:"force"` // user explicitly approved this diff
+	// AutoPreconditions stamps the current hash of every touched file
+	// that the diff doesn't already pin.
+	AutoPreconditions bool `json:"auto_preconditions"`
+}
+
+// handleVSCodeDiffApply runs a structured diff through the apply → verify
+// pipeline under the configured approval policy. Risky diffs come back with
+// status "pending_approval" (202) for the extension to prompt on; resending
+// with "force": true applies them once the user has approved.
 func (s *Server) handleVSCodeDiffApply(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
-	var req struct {
-		Diff      string `json:"diff"`
-		Workspace string `json:"workspace"`
-	}
+	var req diffApplyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeBodyError(w, err, "invalid request body")
 		return
 	}
 
 	diff, err := codex.ParseDiff(req.Diff)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidDiff, err.Error(), map[string]string{"stage": "parse"})
 		return
 	}
 
-	if err := diff.Validate(); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := diff.ValidateLimits(s.diffLimits()); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidDiff, err.Error(), map[string]string{"stage": "validate"})
 		return
 	}
 
-	workspace := req.Workspace
-	if workspace == "" && s.config != nil {
-		workspace = s.config.WorkspacePath()
+	workspace, err := s.resolveWorkspace(r.Context(), req.Workspace, diff.TaskID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidWorkspace, err.Error(), map[string]string{"stage": "workspace"})
+		return
 	}
 
-	if workspace == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "workspace path required"})
-		return
+	if req.AutoPreconditions {
+		if err := diff.AddPreconditionsFromWorkspace(workspace); err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+			return
+		}
 	}
 
-	// Check preconditions
-	if err := diff.CheckPreconditions(workspace); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{
-			"error": err.Error(),
-			"stage": "preconditions",
-		})
-		return
+	// A retry of a diff that already went through gets the original result
+	// back instead of being applied a second time. The diff stays locked
+	// until its result is recorded, so a retry racing the original waits
+	// for it.
+	applied := s.getAppliedLog()
+	if applied != nil && diff.ID != "" {
+		unlock := applied.Lock(workspace, diff.ID)
+		defer unlock()
+		prior, err := applied.Lookup(r.Context(), workspace, diff.ID)
+		if err != nil {
+			logger.WarnCtx(r.Context(), "vscode", "Applied diff lookup failed", map[string]interface{}{
+				"diff_id": diff.ID,
+				"error":   err.Error(),
+			})
+		} else if prior != nil {
+			writeJSON(w, http.StatusOK, prior)
+			return
+		}
 	}
 
-	// Apply
-	result, err := diff.Apply(workspace)
+	policy := s.approvalPolicy()
+	gate := policy
+	if req.Force {
+		// Approval already granted: apply without the gate.
+		gate = nil
+	}
+	ctx := codex.WithVerifyLimits(r.Context(), s.verifyLimits())
+	var result *codex.ApplyVerifyResult
+	if worktree := s.taskWorktree(r.Context(), workspace, diff.TaskID); worktree != "" {
+		result, err = diff.ApplyInWorktree(ctx, workspace, worktree, gate)
+	} else {
+		result, err = diff.ApplyAndVerify(ctx, workspace, gate)
+	}
+	if req.Force {
+		// Record what the policy would have said
+		result.ApprovalLevel, result.ApprovalReason = policy.EvaluateApproval(diff)
+		result.Forced = true
+	}
 	if err != nil {
-		logger.ErrorCF("vscode", "Diff apply failed", map[string]interface{}{
+		logger.ErrorCtx(r.Context(), "vscode", "Diff apply failed", map[string]interface{}{
 			"diff_id": diff.ID,
+			"status":  result.Status,
 			"error":   err.Error(),
 		})
 	}
 
+	if result.Status == "success" {
+		s.commitDiff(r.Context(), workspace, diff, result)
+	}
+
+	if applied != nil {
+		if err := applied.Record(r.Context(), workspace, result); err != nil {
+			logger.WarnCtx(r.Context(), "vscode", "Failed to record applied diff", map[string]interface{}{
+				"diff_id": diff.ID,
+				"error":   err.Error(),
+			})
+		}
+	}
+
 	// Publish event
 	if s.messageBus != nil {
-		eventType := "diff.applied"
-		if !result.Success {
-			eventType = "diff.rolled_back"
+		data := map[string]interface{}{
+			"diff_id":        result.DiffID,
+			"task_id":        result.TaskID,
+			"status":         result.Status,
+			"approval_level": result.ApprovalLevel,
+			"forced":         result.Forced,
+			"error":          result.Error,
+		}
+		if result.Apply != nil {
+			data["files_changed"] = result.Apply.FilesChanged
 		}
 		s.messageBus.PublishSystem(bus.SystemEvent{
-			Type:   eventType,
-			Source: "vscode",
-			Data: map[string]interface{}{
-				"diff_id":       result.DiffID,
-				"task_id":       result.TaskID,
-				"success":       result.Success,
-				"files_changed": result.FilesChanged,
-				"error":         result.Error,
-			},
+			Type:      diffEventType(result.Status),
+			Source:    "vscode",
+			Data:      data,
+			RequestID: logger.RequestID(r.Context()),
 		})
 	}
 
 	// Update kanban task if we have one
-	if result.Success && diff.TaskID != "" {
+	if diff.TaskID != "" && !result.AlreadyApplied {
 		if kb := s.getKanban(); kb != nil {
-			kb.UpdateTask(diff.TaskID, map[string]interface{}{
-				"last_error": "",
-			})
-			kb.LogEvent(diff.TaskID, "vscode", "diff.applied", diff.Summary)
+			switch result.Status {
+			case "success":
+				kb.UpdateTaskCtx(r.Context(), diff.TaskID, map[string]interface{}{
+					"last_error": "",
+				})
+				kb.LogEventCtx(r.Context(), diff.TaskID, "vscode", "diff.applied", diff.Summary)
+				// Keep what was applied on the task, not just the summary.
+				note := kanban.NoteOptions{ContentType: kanban.NoteMarkdown, Quiet: true}
+				if err := kb.AddRichNoteCtx(r.Context(), diff.TaskID, diff.Markdown(), diff.AgentID, note); err != nil {
+					logger.WarnCtx(r.Context(), "vscode", "Failed to attach diff to task", map[string]interface{}{
+						"task_id": diff.TaskID,
+						"error":   err.Error(),
+					})
+				}
+			case "apply_failed", "verify_failed", "rolled_back", "merge_failed":
+				// Keep the failure so the next attempt at the task sees it.
+				failure := attemptFailure(result.Error, result.Verify)
+				if failure.Reason == "" {
+					failure.Reason = "diff " + result.Status
+				}
+				if failure.Stage == "" {
+					failure.Stage = "apply"
+				}
+				if err := kb.RecordFailureCtx(r.Context(), diff.TaskID, failure); err != nil {
+					logger.WarnCtx(r.Context(), "vscode", "Failed to record task attempt", map[string]interface{}{
+						"task_id": diff.TaskID,
+						"error":   err.Error(),
+					})
+				}
+			}
 		}
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, diffStatusCode(result.Status), result)
+}
+
+// errNoWorkspace is returned by resolveWorkspace when nothing names a workspace.
+var errNoWorkspace = errors.New("workspace path required")
+
+// resolveWorkspace picks the tree a diff applies to: the workspace asked
+// for, else the task's own, else its project's, else the global workspace.
+// When workspace roots are configured the result must lie within one.
+func (s *Server) resolveWorkspace(ctx context.Context, explicit, taskID string) (string, error) {
+	workspace := explicit
+	if workspace == "" && taskID != "" {
+		if kb := s.getKanban(); kb != nil {
+			if task, err := kb.GetTaskCtx(ctx, taskID); err == nil {
+				workspace = task.Workspace
+				if workspace == "" && s.config != nil {
+					workspace = s.config.ProjectWorkspace(task.Project)
+				}
+			}
+		}
+	}
+	if workspace == "" && s.config != nil {
+		workspace = s.config.WorkspacePath()
+	}
+	if workspace == "" {
+		return "", errNoWorkspace
+	}
+
+	if s.config == nil {
+		return workspace, nil
+	}
+	roots := s.config.WorkspaceRoots()
+	if len(roots) == 0 {
+		return workspace, nil
+	}
+	resolved, err := realPath(workspace)
+	if err != nil {
+		return "", fmt.Errorf("workspace %s: %w", workspace, err)
+	}
+	for _, root := range roots {
+		rootPath, err := realPath(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(rootPath, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("workspace %s is not under an allowed root", workspace)
+}
+
+// realPath returns the absolute, symlink-free form of path.
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// getAppliedLog opens the applied-diff record in the configured workspace on
+// first use. It returns nil if there is no workspace or the database can't be
+// opened, in which case applies are simply not deduplicated.
+func (s *Server) getAppliedLog() *codex.AppliedLog {
+	s.appliedOnce.Do(func() {
+		if s.config == nil || s.config.WorkspacePath() == "" {
+			return
+		}
+		log, err := codex.NewAppliedLog(filepath.Join(s.config.WorkspacePath(), "applied_diffs.db"))
+		if err != nil {
+			logger.WarnCF("vscode", "Diff idempotency disabled", map[string]interface{}{"error": err.Error()})
+			return
+		}
+		s.appliedLog = log
+	})
+	return s.appliedLog
+}
+
+// approvalPolicy builds the diff approval policy from config, starting from
+// codex.DefaultPolicy.
+func (s *Server) approvalPolicy() *codex.ApprovalPolicy {
+	policy := codex.DefaultPolicy()
+	if s.config == nil {
+		return policy
+	}
+	cfg := s.config.Codex
+	if len(cfg.CriticalPaths) > 0 {
+		policy.CriticalPaths = cfg.CriticalPaths
+	}
+	if len(cfg.CriticalOps) > 0 {
+		policy.CriticalOps = make([]codex.DiffOperation, 0, len(cfg.CriticalOps))
+		for _, op := range cfg.CriticalOps {
+			policy.CriticalOps = append(policy.CriticalOps, codex.DiffOperation(op))
+		}
+	}
+	if cfg.MaxAutoFiles > 0 {
+		policy.MaxAutoFiles = cfg.MaxAutoFiles
+	}
+	if cfg.MaxAutoLines > 0 {
+		policy.MaxAutoLines = cfg.MaxAutoLines
+	}
+	return policy
+}
+
+// commitDiff commits an applied diff when codex git_commit is on, noting
+// the commit (or why it failed) on result, and routes a git.commit
+// workflow event for it so it is logged against the task like commits the
+// ide-monitor reports.
+func (s *Server) commitDiff(ctx context.Context, workspace string, diff *codex.StructuredDiff, result *codex.ApplyVerifyResult) {
+	if s.config == nil || !s.config.Codex.GitCommit {
+		return
+	}
+	branch := codex.TaskBranch(s.config.Codex.GitBranchPrefix, diff.TaskID)
+	sha, err := diff.Commit(ctx, workspace, codex.CommitOptions{Branch: branch})
+	if err != nil {
+		result.CommitError = err.Error()
+		logger.WarnCtx(ctx, "vscode", "Failed to commit applied diff", map[string]interface{}{
+			"diff_id": diff.ID,
+			"error":   err.Error(),
+		})
+		return
+	}
+	result.CommitSHA = sha
+	result.Branch = branch
+
+	ev := WorkflowEvent{
+		ID:            "picoclaw-commit-" + sha,
+		SpecVersion:   "1",
+		Source:        "picoclaw",
+		EventType:     "git.commit",
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Confidence:    1,
+		GitCommitSHA:  &sha,
+		Summary:       &diff.Summary,
+		WorkspaceRoot: &workspace,
+	}
+	if diff.TaskID != "" {
+		ev.TaskID = &diff.TaskID
+	}
+	if branch != "" {
+		ev.GitBranch = &branch
+	}
+	if result.Apply != nil {
+		for _, c := range result.Apply.Changes {
+			ev.FilesChanged = append(ev.FilesChanged, WorkflowFile{Path: c.Path, ChangeType: string(c.Op)})
+		}
+	}
+	s.workflowSeen.add(ev.ID)
+	s.routeWorkflowEvent(ctx, ev)
+}
+
+// diffLimits reads the diff size limits from the codex config; zero
+// fields keep codex's defaults.
+func (s *Server) diffLimits() codex.DiffLimits {
+	if s.config == nil {
+		return codex.DiffLimits{}
+	}
+	cfg := s.config.Codex
+	return codex.DiffLimits{
+		MaxChanges:    cfg.MaxChanges,
+		MaxTotalBytes: cfg.MaxTotalBytes,
+		MaxFileBytes:  cfg.MaxFileBytes,
+	}
+}
+
+// verifyLimits reads the diff verification command limits from the tool
+// limits config ("codex_syntax_check", "codex_test").
+func (s *Server) verifyLimits() codex.VerifyLimits {
+	if s.config == nil {
+		return codex.VerifyLimits{}
+	}
+	stage := func(name string) codex.CommandLimits {
+		l := s.config.Tools.Limits[name]
+		return codex.CommandLimits{
+			Timeout:        time.Duration(l.TimeoutSec) * time.Second,
+			MaxOutputBytes: l.MaxOutputBytes,
+		}
+	}
+	return codex.VerifyLimits{Syntax: stage("codex_syntax_check"), Test: stage("codex_test")}
+}
+
+// diffEventType maps an ApplyVerifyResult status to the bus event type.
+func diffEventType(status string) string {
+	switch status {
+	case "success":
+		return "diff.applied"
+	case "pending_approval":
+		return "diff.pending_approval"
+	case "rolled_back":
+		return "diff.rolled_back"
+	default:
+		return "diff.failed"
+	}
+}
+
+// diffStatusCode maps an ApplyVerifyResult status to the HTTP status.
+func diffStatusCode(status string) int {
+	switch status {
+	case "pending_approval":
+		return http.StatusAccepted
+	case "precondition_failed", "merge_failed":
+		return http.StatusConflict
+	case "apply_failed":
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusOK
+	}
+}
+
+// attemptFailure builds the kanban record of a failed attempt from a
+// verification result, which may be nil.
+func attemptFailure(reason string, v *codex.VerifyResult) kanban.AttemptFailure {
+	f := kanban.AttemptFailure{Reason: reason}
+	if v == nil {
+		return f
+	}
+	if f.Reason == "" {
+		f.Reason = v.Error
+	}
+	f.Stage = v.FailedStage()
+	switch f.Stage {
+	case "syntax":
+		f.Output = v.SyntaxOutput
+	case "tests":
+		f.Output = v.TestOutput
+		f.FailingTests = v.FailingTests()
+	}
+	return f
 }
 
-// handleVSCodeTasks returns tasks suitable for coding bots.
+// vscodeDefaultAgentID identifies the extension when it doesn't send its own agent_id.
+const vscodeDefaultAgentID = "vscode-agent"
+
+// vscodeTaskCategories are the task categories a coding agent can pick up.
+var vscodeTaskCategories = []kanban.TaskCategory{
+	kanban.CategoryCode,
+	kanban.CategoryBug,
+	kanban.CategoryFeature,
+	kanban.CategoryInfra,
+}
+
+// handleVSCodeTasks returns tasks suitable for coding bots. Tasks held under
+// an active lease by another agent are hidden; the caller's own claims
+// (?agent_id=) still show. ?category= narrows to one coding category.
 func (s *Server) handleVSCodeTasks(w http.ResponseWriter, r *http.Request) {
 	kb := s.getKanban()
 	if kb == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "kanban not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "kanban not available", nil)
 		return
 	}
 
-	// Return code-related tasks that are claimable
-	tasks, err := kb.ListTasks(kanban.TaskFilters{
+	q := r.URL.Query()
+	agentID := q.Get("agent_id")
+	if agentID == "" {
+		agentID = vscodeDefaultAgentID
+	}
+
+	categories := vscodeTaskCategories
+	if c := kanban.TaskCategory(q.Get("category")); c != "" {
+		known := false
+		for _, cat := range vscodeTaskCategories {
+			if cat == c {
+				known = true
+				break
+			}
+		}
+		if !known {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidParam, fmt.Sprintf("category %q is not a coding category", c), nil)
+			return
+		}
+		categories = []kanban.TaskCategory{c}
+	}
+
+	tasks, err := kb.ListTasksCtx(r.Context(), kanban.TaskFilters{
+		Categories:  categories,
 		ExcludeDone: true,
+		ClaimableBy: agentID,
 		Limit:       50,
 	})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
-
-	// Filter to coding-relevant categories
-	codeTasks := []*kanban.Task{}
-	for _, t := range tasks {
-		if t.Category == kanban.CategoryCode ||
-			t.Category == kanban.CategoryBug ||
-			t.Category == kanban.CategoryFeature ||
-			t.Category == kanban.CategoryInfra {
-			codeTasks = append(codeTasks, t)
-		}
+	if tasks == nil {
+		tasks = []*kanban.Task{}
 	}
 
-	writeJSON(w, http.StatusOK, codeTasks)
+	writeJSON(w, http.StatusOK, tasks)
 }
 
+// vscodeDefaultLease is used when the extension doesn't ask for a lease length.
+const vscodeDefaultLease = 10 * time.Minute
+
 // handleVSCodeClaimTask claims a task from the extension for the local coding agent.
+// Body (optional): { agent_id, lease_seconds }. Each editor instance should send
+// its own agent_id so two windows don't share a claim.
 func (s *Server) handleVSCodeClaimTask(w http.ResponseWriter, r *http.Request, taskID string) {
 	if r.Method != "POST" {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "POST required", nil)
 		return
 	}
 
 	kb := s.getKanban()
 	if kb == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "kanban not available"})
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "kanban not available", nil)
 		return
 	}
 
-	if err := kb.ClaimTask(taskID, "vscode-agent", 10*time.Minute); err != nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	var req claimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBodyError(w, err, "invalid request body")
+		return
+	}
+	if req.AgentID == "" {
+		req.AgentID = vscodeDefaultAgentID
+	}
+	lease := vscodeDefaultLease
+	if req.LeaseSec > 0 {
+		lease = time.Duration(req.LeaseSec) * time.Second
+	}
+
+	if err := kb.ClaimTaskCtx(r.Context(), taskID, req.AgentID, lease); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
-	task, _ := kb.GetTask(taskID)
-	writeJSON(w, http.StatusOK, task)
+	task, _ := kb.GetTaskCtx(r.Context(), taskID)
+	writeClaimedTask(w, r, kb, task)
 }
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/codex"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+)
+
+func TestResolveWorkspaceRoots(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "repos")
+	inside := filepath.Join(root, "app")
+	outside := filepath.Join(base, "elsewhere")
+	for _, dir := range []string{inside, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A symlink inside the root that points out of it.
+	escape := filepath.Join(root, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = inside
+	s := &Server{config: cfg}
+
+	// No roots configured: any workspace is taken as given.
+	if got, err := s.resolveWorkspace(context.Background(), outside, ""); err != nil || got != outside {
+		t.Fatalf("without roots: got %q, %v", got, err)
+	}
+
+	cfg.Codex.WorkspaceRoots = []string{root}
+	for _, tc := range []struct {
+		explicit string
+		ok       bool
+	}{
+		{"", true}, // falls back to the global workspace
+		{inside, true},
+		{root, true},
+		{outside, false},
+		{escape, false},
+		{filepath.Join(root, "..", "elsewhere"), false},
+	} {
+		got, err := s.resolveWorkspace(context.Background(), tc.explicit, "")
+		if tc.ok && err != nil {
+			t.Errorf("resolveWorkspace(%q) error: %v", tc.explicit, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("resolveWorkspace(%q) = %q, want error", tc.explicit, got)
+		}
+	}
+}
+
+// newDiffTestServer returns a server applying diffs to a temp workspace,
+// and a task on its running board for the diffs to belong to.
+func newDiffTestServer(t *testing.T) (s *Server, workspace string, task *kanban.Task) {
+	t.Helper()
+	workspace = t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	s = &Server{config: cfg}
+	t.Cleanup(func() {
+		if s.appliedLog != nil {
+			s.appliedLog.Close()
+		}
+	})
+
+	// Applied diffs are logged on their task, so the board must be running
+	t.Setenv("PICOCLAW_DB", filepath.Join(t.TempDir(), "kanban.db"))
+	kb := s.getKanban()
+	if err := kb.Init(cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := kb.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { kb.Stop(context.Background()) })
+	task = &kanban.Task{Title: "diff target"}
+	if err := kb.CreateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	return s, workspace, task
+}
+
+func TestVSCodeDiffApplyApproval(t *testing.T) {
+	s, workspace, task := newDiffTestServer(t)
+
+	apply := func(diffID, path string, force bool) (int, codex.ApplyVerifyResult) {
+		t.Helper()
+		diff, _ := json.Marshal(codex.StructuredDiff{ID: diffID, TaskID: task.ID, Summary: "test",
+			Changes: []codex.FileChange{{Op: codex.OpCreate, Path: path, NewContent: "x=1\n"}}})
+		body, _ := json.Marshal(diffApplyRequest{Diff: string(diff), Workspace: workspace, Force: force})
+		rec := httptest.NewRecorder()
+		s.handleVSCodeDiffApply(rec, httptest.NewRequest("POST", "/api/vscode/diff/apply", strings.NewReader(string(body))))
+		var result codex.ApplyVerifyResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body.String(), err)
+		}
+		return rec.Code, result
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(workspace, path))
+		return err == nil
+	}
+
+	// A low-risk diff applies without asking
+	code, result := apply("d-auto", "notes.txt", false)
+	if code != http.StatusOK || result.Status != "success" || result.ApprovalLevel != codex.ApprovalAuto || result.Forced || !exists("notes.txt") {
+		t.Errorf("auto: status %d, %+v", code, result)
+	}
+
+	// A critical path waits for approval and leaves the workspace alone
+	code, result = apply("d-env", "app.env", false)
+	if code != http.StatusAccepted || result.Status != "pending_approval" || result.ApprovalLevel == codex.ApprovalAuto ||
+		result.ApprovalReason == "" || exists("app.env") {
+		t.Errorf("pending: status %d, %+v", code, result)
+	}
+
+	// Resending with force applies it and records what the policy said
+	code, result = apply("d-env", "app.env", true)
+	if code != http.StatusOK || result.Status != "success" || !result.Forced || result.ApprovalLevel == codex.ApprovalAuto ||
+		result.ApprovalReason == "" || !exists("app.env") {
+		t.Errorf("forced: status %d, %+v", code, result)
+	}
+
+	// A retry of the forced diff replays the recorded result
+	code, result = apply("d-env", "app.env", true)
+	if code != http.StatusOK || !result.AlreadyApplied || !result.Forced {
+		t.Errorf("retry: status %d, %+v", code, result)
+	}
+}
+
+func TestVSCodeDiffApplyConcurrentRetries(t *testing.T) {
+	s, workspace, task := newDiffTestServer(t)
+	if err := os.WriteFile(filepath.Join(workspace, "main.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, _ := json.Marshal(codex.StructuredDiff{ID: "d-retry", TaskID: task.ID, Summary: "test",
+		Changes: []codex.FileChange{{Op: codex.OpModify, Path: "main.txt", OldContent: "one", NewContent: "two"}},
+		// Slow verification keeps the first apply in flight while the
+		// retries arrive.
+		Verify: &codex.VerifySpec{SyntaxCheck: "sleep 0.2"}})
+	body, _ := json.Marshal(diffApplyRequest{Diff: string(diff), Workspace: workspace})
+
+	// A flaky client resending the same diff must get one apply and the
+	// rest replayed, not precondition failures.
+	results := make([]codex.ApplyVerifyResult, 4)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			s.handleVSCodeDiffApply(rec, httptest.NewRequest("POST", "/api/vscode/diff/apply", strings.NewReader(string(body))))
+			json.Unmarshal(rec.Body.Bytes(), &results[i])
+		}(i)
+	}
+	wg.Wait()
+
+	applied, replayed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Status == "success" && r.AlreadyApplied:
+			replayed++
+		case r.Status == "success":
+			applied++
+		}
+	}
+	if applied != 1 || replayed != len(results)-1 {
+		t.Errorf("results = %+v, want one apply and the rest already applied", results)
+	}
+	if got, _ := os.ReadFile(filepath.Join(workspace, "main.txt")); string(got) != "two\n" {
+		t.Errorf("main.txt = %q", got)
+	}
+}
+
+func TestDiffStatusCode(t *testing.T) {
+	for status, want := range map[string]int{
+		"success":             http.StatusOK,
+		"pending_approval":    http.StatusAccepted,
+		"precondition_failed": http.StatusConflict,
+		"merge_failed":        http.StatusConflict,
+		"apply_failed":        http.StatusUnprocessableEntity,
+		"verify_failed":       http.StatusOK,
+		"rolled_back":         http.StatusOK,
+	} {
+		if got := diffStatusCode(status); got != want {
+			t.Errorf("diffStatusCode(%q) = %d, want %d", status, got, want)
+		}
+	}
+}
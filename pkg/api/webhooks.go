@@ -31,7 +31,7 @@ import (
 // The webhook source name (from URL) becomes the aggregate_id and event categorization.
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodOptions {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
@@ -44,19 +44,19 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Extract the source name from the URL path (/api/webhook/{source})
 	source := r.PathValue("source")
 	if source == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "webhook source name required"})
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, "webhook source name required", nil)
 		return
 	}
 
 	// Parse incoming payload
 	var payload map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON payload"})
+		writeBodyError(w, err, "invalid JSON payload")
 		return
 	}
 
 	if len(payload) == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "empty payload"})
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidBody, "empty payload", nil)
 		return
 	}
 
@@ -85,15 +85,16 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	var sysEvent bus.SystemEvent
 	if event != nil {
 		sysEvent = bus.SystemEvent{
-			Type:   string(event.EventType()),
-			Source: source,
-			Data:   event.Payload(),
+			Type:      string(event.EventType()),
+			Source:    source,
+			Data:      event.Payload(),
+			RequestID: logger.RequestID(r.Context()),
 		}
 	}
 
 	if s.messageBus != nil {
 		s.messageBus.PublishSystem(sysEvent)
-		logger.InfoCF("webhook", "Event received and published",
+		logger.InfoCtx(r.Context(), "webhook", "Event received and published",
 			map[string]interface{}{
 				"source": source,
 				"type":   event.EventType(),
@@ -0,0 +1,212 @@
+// Token-to-commit correlation — attributes Copilot token usage to the git
+// commit it led to. Token bursts reported by the ide-monitor are held per
+// workspace; when a commit arrives, the bursts from the preceding window are
+// summed, published as workflow.commit_cost and added to the committed
+// task's TokensUsed.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	kanban "github.com/sipeed/picoclaw/pkg/integration/kanban"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultCorrelationWindow is used when
+// gateway.workflow_events.correlation_window_sec is unset.
+const defaultCorrelationWindow = 15 * time.Minute
+
+// maxPendingBursts bounds the bursts held per workspace, in case commits
+// stop arriving.
+const maxPendingBursts = 1000
+
+// correlationTokenCommit is the CorrelationType of a workflow.commit_cost
+// event.
+const correlationTokenCommit = "token_burst_to_commit"
+
+// tokenBurst is token usage waiting for the commit it led to.
+type tokenBurst struct {
+	EventID string    `json:"event_id"`
+	BurstID string    `json:"burst_id,omitempty"`
+	Tokens  int       `json:"tokens"`
+	At      time.Time `json:"at"`
+}
+
+// tokenCorrelator holds recent token bursts per workspace. The zero value
+// is ready to use.
+type tokenCorrelator struct {
+	mu      sync.Mutex
+	pending map[string][]tokenBurst
+}
+
+// add records a burst, dropping bursts of the workspace that are older
+// than window.
+func (c *tokenCorrelator) add(workspace string, b tokenBurst, window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string][]tokenBurst)
+	}
+	kept := c.pending[workspace][:0]
+	for _, p := range c.pending[workspace] {
+		if b.At.Sub(p.At) <= window {
+			kept = append(kept, p)
+		}
+	}
+	kept = append(kept, b)
+	if len(kept) > maxPendingBursts {
+		kept = kept[len(kept)-maxPendingBursts:]
+	}
+	c.pending[workspace] = kept
+}
+
+// take removes and returns the workspace's bursts from the window before
+// commitAt. Bursts after commitAt belong to a later commit and stay;
+// bursts older than the window are dropped.
+func (c *tokenCorrelator) take(workspace string, commitAt time.Time, window time.Duration) []tokenBurst {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var taken, later []tokenBurst
+	for _, b := range c.pending[workspace] {
+		switch {
+		case b.At.After(commitAt):
+			later = append(later, b)
+		case commitAt.Sub(b.At) <= window:
+			taken = append(taken, b)
+		}
+	}
+	if len(later) == 0 {
+		delete(c.pending, workspace)
+	} else {
+		c.pending[workspace] = later
+	}
+	return taken
+}
+
+// correlationWindow is the configured window, or the default.
+func (s *Server) correlationWindow() time.Duration {
+	if s.config != nil && s.config.Gateway.WorkflowEvents.CorrelationWindowSec > 0 {
+		return time.Duration(s.config.Gateway.WorkflowEvents.CorrelationWindowSec) * time.Second
+	}
+	return defaultCorrelationWindow
+}
+
+// recordTokenBurst holds ev's token usage for the next commit in its
+// workspace. Events without token data are ignored.
+func (s *Server) recordTokenBurst(ev WorkflowEvent) {
+	tokens := ev.tokenCount()
+	if tokens <= 0 || strings.HasPrefix(ev.EventType, "git.") {
+		return
+	}
+	b := tokenBurst{EventID: ev.ID, Tokens: tokens, At: ev.time()}
+	if ev.BurstID != nil {
+		b.BurstID = *ev.BurstID
+	}
+	s.tokenBursts.add(ev.workspaceKey(), b, s.correlationWindow())
+}
+
+// attributeCommitTokens links the bursts before commit ev to it. The cost
+// is published as workflow.commit_cost and, when the commit names a task
+// (task may be nil), added to that task.
+func (s *Server) attributeCommitTokens(ctx context.Context, ev WorkflowEvent, k *kanban.KanbanIntegration, task *kanban.Task) {
+	bursts := s.tokenBursts.take(ev.workspaceKey(), ev.time(), s.correlationWindow())
+	if len(bursts) == 0 {
+		return
+	}
+	total := 0
+	ids := make([]string, len(bursts))
+	for i, b := range bursts {
+		total += b.Tokens
+		ids[i] = b.EventID
+	}
+
+	sha := ""
+	if ev.GitCommitSHA != nil {
+		sha = *ev.GitCommitSHA
+	}
+	data := map[string]interface{}{
+		"commit_event_id":   ev.ID,
+		"git_commit_sha":    sha,
+		"tokens":            total,
+		"bursts":            bursts,
+		"correlated_events": ids,
+		"correlation_type":  correlationTokenCommit,
+	}
+
+	if task != nil {
+		data["task_id"] = task.ID
+		summary := fmt.Sprintf("commit %s cost %d tokens (%d bursts)", shortSHA(sha), total, len(bursts))
+		if err := k.AddTokensCtx(ctx, task.ID, int64(total), "ide-monitor", summary); err != nil {
+			logger.ErrorCtx(ctx, "workflow", "Failed to attribute tokens to task", map[string]interface{}{
+				"task_id": task.ID,
+				"tokens":  total,
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	logger.InfoCtx(ctx, "workflow", "Correlated token bursts with commit", map[string]interface{}{
+		"git_commit_sha": sha,
+		"tokens":         total,
+		"bursts":         len(bursts),
+		"task_id":        data["task_id"],
+	})
+
+	s.wsHub.Broadcast("workflow.commit_cost", data)
+	if s.messageBus != nil {
+		s.messageBus.PublishSystem(bus.SystemEvent{
+			Type:      "workflow.commit_cost",
+			Source:    "workflow",
+			Data:      data,
+			RequestID: logger.RequestID(ctx),
+		})
+	}
+}
+
+// tokenCount is the event's token usage: the burst total when present,
+// otherwise prompt plus completion tokens.
+func (ev *WorkflowEvent) tokenCount() int {
+	if ev.BurstTokenTotal != nil {
+		return *ev.BurstTokenTotal
+	}
+	n := 0
+	if ev.TokensPrompt != nil {
+		n += *ev.TokensPrompt
+	}
+	if ev.TokensCompletion != nil {
+		n += *ev.TokensCompletion
+	}
+	return n
+}
+
+// time is when the event happened, falling back to now for a missing or
+// unparseable timestamp.
+func (ev *WorkflowEvent) time() time.Time {
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// workspaceKey groups events that can correlate: the workspace ID, else
+// its root path, else the host.
+func (ev *WorkflowEvent) workspaceKey() string {
+	for _, v := range []*string{ev.WorkspaceID, ev.WorkspaceRoot, ev.Hostname} {
+		if v != nil && *v != "" {
+			return *v
+		}
+	}
+	return ""
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestTokenBurstsCorrelateWithCommit(t *testing.T) {
+	s := &Server{startTime: time.Now(), messageBus: bus.NewMessageBus()}
+	s.wsHub = NewWSHub(s)
+	events := s.messageBus.SubscribeSystem("test")
+
+	ws, other := "ws-1", "ws-2"
+	sha := "0123456789abcdef"
+	tokens := func(n int) *int { return &n }
+	at := func(min int) string {
+		return time.Date(2026, 1, 1, 12, min, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+	for _, ev := range []WorkflowEvent{
+		{ID: "stale", EventType: "copilot.burst", Timestamp: at(0), WorkspaceID: &ws, BurstTokenTotal: tokens(1000)},
+		{ID: "b1", EventType: "copilot.burst", Timestamp: at(30), WorkspaceID: &ws, BurstTokenTotal: tokens(300)},
+		{ID: "b2", EventType: "copilot.completion", Timestamp: at(35), WorkspaceID: &ws, TokensPrompt: tokens(100), TokensCompletion: tokens(50)},
+		{ID: "elsewhere", EventType: "copilot.burst", Timestamp: at(36), WorkspaceID: &other, BurstTokenTotal: tokens(7)},
+		{ID: "after", EventType: "copilot.burst", Timestamp: at(45), WorkspaceID: &ws, BurstTokenTotal: tokens(20)},
+		{ID: "c1", EventType: "git.commit", Timestamp: at(40), WorkspaceID: &ws, GitCommitSHA: &sha},
+	} {
+		s.routeWorkflowEvent(context.Background(), ev)
+	}
+
+	var cost *bus.SystemEvent
+	for len(events) > 0 {
+		if ev, ok := (<-events).(bus.SystemEvent); ok && ev.Type == "workflow.commit_cost" {
+			cost = &ev
+		}
+	}
+	if cost == nil {
+		t.Fatal("no workflow.commit_cost event")
+	}
+	data := cost.Data.(map[string]interface{})
+	if data["tokens"] != 450 || data["git_commit_sha"] != sha || data["correlation_type"] != correlationTokenCommit {
+		t.Errorf("commit cost = %v", data)
+	}
+	if ids := data["correlated_events"].([]string); len(ids) != 2 || ids[0] != "b1" || ids[1] != "b2" {
+		t.Errorf("correlated events = %v", ids)
+	}
+
+	// The burst after the commit waits for the next one.
+	if left := s.tokenBursts.take(ws, time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC), time.Hour); len(left) != 1 || left[0].EventID != "after" {
+		t.Errorf("remaining bursts = %+v", left)
+	}
+}
@@ -11,8 +11,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/integration"
@@ -85,45 +89,189 @@ type WorkflowFile struct {
 // handleWorkflowEvent handles POST /api/events from the ide-monitor.
 func (s *Server) handleWorkflowEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
 	var ev WorkflowEvent
 	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		writeBodyError(w, err, "invalid JSON")
 		return
 	}
 
 	// Basic validation
-	if ev.ID == "" || ev.EventType == "" || ev.Source == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id, event_type, source required"})
+	if err := ev.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeMissingField, err.Error(), nil)
 		return
 	}
 
-	logger.InfoCF("workflow", "Received event", map[string]interface{}{
+	// The ide-monitor retries on timeouts; an event already routed is
+	// acknowledged again but not routed twice.
+	if !s.workflowSeen.add(ev.ID) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "duplicate": true})
+		return
+	}
+
+	logger.InfoCtx(r.Context(), "workflow", "Received event", map[string]interface{}{
 		"id":         ev.ID,
 		"event_type": ev.EventType,
 		"source":     ev.Source,
 	})
 
-	// Route asynchronously — don't block the HTTP response
-	go s.routeWorkflowEvent(ev)
+	// Route asynchronously — don't block the HTTP response. The request ID
+	// goes along, but not the request's cancellation.
+	go s.routeWorkflowEvent(context.WithoutCancel(r.Context()), ev)
 
 	writeJSON(w, http.StatusAccepted, map[string]interface{}{"ok": true})
 }
 
+// maxWorkflowEventBatch caps POST /api/events/batch so one request can't
+// queue an unbounded amount of routing work.
+const maxWorkflowEventBatch = 500
+
+// Per-event outcomes reported by POST /api/events/batch.
+const (
+	eventStatusAccepted  = "accepted"
+	eventStatusDuplicate = "duplicate" // already received; don't resend
+	eventStatusInvalid   = "invalid"   // rejected; fix before resending
+)
+
+// workflowEventResult is the outcome for one event of a batch, in request
+// order.
+type workflowEventResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// workflowBatchResponse is the body of POST /api/events/batch.
+type workflowBatchResponse struct {
+	Results    []workflowEventResult `json:"results"`
+	Accepted   int                   `json:"accepted"`
+	Duplicates int                   `json:"duplicates"`
+	Invalid    int                   `json:"invalid"`
+}
+
+// handleWorkflowEventBatch handles POST /api/events/batch: a JSON array of
+// WorkflowEvents, typically the ide-monitor catching up after being offline.
+// Each event is validated and deduplicated like a single POST /api/events;
+// one bad event doesn't reject the rest. Accepted events are routed in
+// array order on a single goroutine, so correlated events (a token burst
+// and the commit that followed) arrive downstream in the order sent.
+func (s *Server) handleWorkflowEventBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeBodyError(w, err, "body must be a JSON array of events")
+		return
+	}
+	if len(raw) > maxWorkflowEventBatch {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest,
+			fmt.Sprintf("batch has %d events; send at most %d per request", len(raw), maxWorkflowEventBatch), nil)
+		return
+	}
+
+	resp := workflowBatchResponse{Results: make([]workflowEventResult, len(raw))}
+	accepted := make([]WorkflowEvent, 0, len(raw))
+	for i, data := range raw {
+		res := &resp.Results[i]
+		res.Index = i
+
+		var ev WorkflowEvent
+		err := json.Unmarshal(data, &ev)
+		if err == nil {
+			err = ev.validate()
+		}
+		res.ID = ev.ID
+		switch {
+		case err != nil:
+			res.Status, res.Error = eventStatusInvalid, err.Error()
+			resp.Invalid++
+		case !s.workflowSeen.add(ev.ID):
+			res.Status = eventStatusDuplicate
+			resp.Duplicates++
+		default:
+			res.Status = eventStatusAccepted
+			resp.Accepted++
+			accepted = append(accepted, ev)
+		}
+	}
+
+	logger.InfoCtx(r.Context(), "workflow", "Received event batch", map[string]interface{}{
+		"events":     len(raw),
+		"accepted":   resp.Accepted,
+		"duplicates": resp.Duplicates,
+		"invalid":    resp.Invalid,
+	})
+
+	if len(accepted) > 0 {
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			for _, ev := range accepted {
+				s.routeWorkflowEvent(ctx, ev)
+			}
+		}()
+	}
+
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// validate checks the fields every event needs to be routed.
+func (ev *WorkflowEvent) validate() error {
+	if ev.ID == "" || ev.EventType == "" || ev.Source == "" {
+		return errors.New("id, event_type, source required")
+	}
+	return nil
+}
+
+// workflowSeenLimit is how many recent event IDs are remembered for
+// deduplication.
+const workflowSeenLimit = 10000
+
+// recentIDs remembers the most recent IDs it was given, forgetting the
+// oldest beyond workflowSeenLimit. The zero value is ready to use.
+type recentIDs struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+}
+
+// add records id and reports whether it was new.
+func (r *recentIDs) add(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ids == nil {
+		r.ids = make(map[string]struct{})
+	}
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+	r.ids[id] = struct{}{}
+	r.order = append(r.order, id)
+	if len(r.order) > workflowSeenLimit {
+		delete(r.ids, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
+
 // routeWorkflowEvent fans out a workflow event to all downstream systems.
-func (s *Server) routeWorkflowEvent(ev WorkflowEvent) {
+func (s *Server) routeWorkflowEvent(ctx context.Context, ev WorkflowEvent) {
 	// 1. Broadcast to dashboard via existing WSHub
 	s.wsHub.Broadcast("workflow."+ev.EventType, ev)
 
 	// 2. Publish to existing message bus for EventBridge fan-out
 	if s.messageBus != nil {
 		s.messageBus.PublishSystem(bus.SystemEvent{
-			Type:   ev.EventType,
-			Source: ev.Source,
-			Data:   ev,
+			Type:      ev.EventType,
+			Source:    ev.Source,
+			Data:      ev,
+			RequestID: logger.RequestID(ctx),
 		})
 	}
 
@@ -132,24 +280,31 @@ func (s *Server) routeWorkflowEvent(ev WorkflowEvent) {
 	// Only Antigravity (intent) and Git (execution) touch Kanban.
 	switch ev.EventType {
 	case "antigravity.task.created":
-		s.upsertWorkflowKanbanCard(ev, kanban.StateInbox)
+		s.upsertWorkflowKanbanCard(ctx, ev, kanban.StateInbox)
 	case "antigravity.task.plan_ready":
-		s.upsertWorkflowKanbanCard(ev, kanban.StatePlanned)
+		s.upsertWorkflowKanbanCard(ctx, ev, kanban.StatePlanned)
 	case "antigravity.task.iterated":
-		s.upsertWorkflowKanbanCard(ev, kanban.StateRunning)
+		s.upsertWorkflowKanbanCard(ctx, ev, kanban.StateRunning)
 	case "antigravity.task.completed":
-		s.upsertWorkflowKanbanCard(ev, kanban.StateDone)
+		s.upsertWorkflowKanbanCard(ctx, ev, kanban.StateDone)
 	case "antigravity.task.failed":
-		s.upsertWorkflowKanbanCard(ev, kanban.StateBlocked)
+		s.upsertWorkflowKanbanCard(ctx, ev, kanban.StateBlocked)
 	case "git.commit", "git.commit_linked_to_task":
-		s.logWorkflowGitCommit(ev)
+		k, task := s.workflowCommitTask(ctx, ev)
+		if task != nil {
+			s.logWorkflowGitCommit(ctx, ev, k, task)
+		}
+		// 4. Attribute the token bursts that led to this commit
+		s.attributeCommitTokens(ctx, ev, k, task)
+	default:
+		s.recordTokenBurst(ev)
 	}
 }
 
 // upsertWorkflowKanbanCard creates or updates a kanban card from a workflow event.
 // Uses ExternalRef (workspace_id:task_id) as the stable identity key.
 // State transitions use TransitionTask for proper audit trail.
-func (s *Server) upsertWorkflowKanbanCard(ev WorkflowEvent, state kanban.TaskState) {
+func (s *Server) upsertWorkflowKanbanCard(ctx context.Context, ev WorkflowEvent, state kanban.TaskState) {
 	reg := integration.GetRegistry()
 	if reg == nil {
 		return
@@ -184,9 +339,9 @@ func (s *Server) upsertWorkflowKanbanCard(ev WorkflowEvent, state kanban.TaskSta
 
 	// Try to find existing task by external ref (indexed lookup)
 	if externalRef != "" {
-		existing, err := k.GetTaskByExternalRef(externalRef)
+		existing, err := k.GetTaskByExternalRefCtx(ctx, externalRef)
 		if err != nil {
-			logger.ErrorCF("workflow", "Failed to lookup kanban card by external_ref", map[string]interface{}{
+			logger.ErrorCtx(ctx, "workflow", "Failed to lookup kanban card by external_ref", map[string]interface{}{
 				"external_ref": externalRef,
 				"error":        err.Error(),
 			})
@@ -197,19 +352,19 @@ func (s *Server) upsertWorkflowKanbanCard(ev WorkflowEvent, state kanban.TaskSta
 				updates := map[string]interface{}{
 					"description": description,
 				}
-				_ = k.UpdateTask(existing.ID, updates)
+				_ = k.UpdateTaskCtx(ctx, existing.ID, updates)
 			}
 			// Transition state using proper state machine
 			if existing.State != state {
-				if err := k.TransitionTask(existing.ID, state, "workflow:"+ev.EventType, "ide-monitor"); err != nil {
-					logger.ErrorCF("workflow", "Failed to transition kanban card", map[string]interface{}{
+				if err := k.TransitionTaskCtx(ctx, existing.ID, state, "workflow:"+ev.EventType, "ide-monitor"); err != nil {
+					logger.ErrorCtx(ctx, "workflow", "Failed to transition kanban card", map[string]interface{}{
 						"task_id":    existing.ID,
 						"from_state": string(existing.State),
 						"to_state":   string(state),
 						"error":      err.Error(),
 					})
 				} else {
-					logger.InfoCF("workflow", "Transitioned kanban card", map[string]interface{}{
+					logger.InfoCtx(ctx, "workflow", "Transitioned kanban card", map[string]interface{}{
 						"task_id":      existing.ID,
 						"external_ref": externalRef,
 						"new_state":    string(state),
@@ -231,13 +386,13 @@ func (s *Server) upsertWorkflowKanbanCard(ev WorkflowEvent, state kanban.TaskSta
 		ExternalRef: externalRef,
 	}
 
-	if err := k.CreateTask(task); err != nil {
-		logger.ErrorCF("workflow", "Failed to create kanban card", map[string]interface{}{
+	if err := k.CreateTaskCtx(ctx, task); err != nil {
+		logger.ErrorCtx(ctx, "workflow", "Failed to create kanban card", map[string]interface{}{
 			"title": title,
 			"error": err.Error(),
 		})
 	} else {
-		logger.InfoCF("workflow", "Created kanban card", map[string]interface{}{
+		logger.InfoCtx(ctx, "workflow", "Created kanban card", map[string]interface{}{
 			"task_id":      task.ID,
 			"title":        title,
 			"state":        string(state),
@@ -246,28 +401,20 @@ func (s *Server) upsertWorkflowKanbanCard(ev WorkflowEvent, state kanban.TaskSta
 	}
 }
 
-// logWorkflowGitCommit logs a git commit event against its correlated task.
-func (s *Server) logWorkflowGitCommit(ev WorkflowEvent) {
+// workflowCommitTask finds the kanban task a git commit event is correlated
+// with. Either result is nil when kanban isn't available or no task matches.
+func (s *Server) workflowCommitTask(ctx context.Context, ev WorkflowEvent) (*kanban.KanbanIntegration, *kanban.Task) {
 	reg := integration.GetRegistry()
 	if reg == nil {
-		return
+		return nil, nil
 	}
 	ki, found := reg.Get("kanban")
 	if !found {
-		return
+		return nil, nil
 	}
 	k, ok := ki.(*kanban.KanbanIntegration)
 	if !ok {
-		return
-	}
-
-	sha := ""
-	if ev.GitCommitSHA != nil {
-		sha = *ev.GitCommitSHA
-	}
-	summary := ""
-	if ev.Summary != nil {
-		summary = *ev.Summary
+		return nil, nil
 	}
 
 	// Find the task by external_ref (preferred) or task_id (fallback)
@@ -279,13 +426,32 @@ func (s *Server) logWorkflowGitCommit(ev WorkflowEvent) {
 	}
 
 	if ref == "" {
-		return
+		return k, nil
 	}
 
-	existing, err := k.GetTaskByExternalRef(ref)
-	if err != nil || existing == nil {
-		return
+	existing, err := k.GetTaskByExternalRefCtx(ctx, ref)
+	if err == nil && existing != nil {
+		return k, existing
+	}
+	// Commits picoclaw makes itself carry the kanban task ID
+	if ev.ExternalRef == nil && ev.TaskID != nil {
+		if task, err := k.GetTaskCtx(ctx, *ev.TaskID); err == nil {
+			return k, task
+		}
+	}
+	return k, nil
+}
+
+// logWorkflowGitCommit logs a git commit event against its correlated task.
+func (s *Server) logWorkflowGitCommit(ctx context.Context, ev WorkflowEvent, k *kanban.KanbanIntegration, task *kanban.Task) {
+	sha := ""
+	if ev.GitCommitSHA != nil {
+		sha = *ev.GitCommitSHA
+	}
+	summary := ""
+	if ev.Summary != nil {
+		summary = *ev.Summary
 	}
 
-	_ = k.LogEvent(existing.ID, "git", "commit", sha+": "+summary)
+	_ = k.LogEventCtx(ctx, task.ID, "git", "commit", sha+": "+summary)
 }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestWorkflowEventBatch(t *testing.T) {
+	s := &Server{startTime: time.Now(), messageBus: bus.NewMessageBus()}
+	s.wsHub = NewWSHub(s)
+	events := s.messageBus.SubscribeSystem("test")
+
+	post := func(body string) (int, workflowBatchResponse) {
+		rec := httptest.NewRecorder()
+		s.handleWorkflowEventBatch(rec, httptest.NewRequest("POST", "/api/events/batch", strings.NewReader(body)))
+		var resp workflowBatchResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	code, resp := post(`[
+		{"id":"e1","source":"copilot","event_type":"copilot.burst"},
+		{"id":"e2","source":"git"},
+		"not an event",
+		{"id":"e3","source":"git","event_type":"git.commit"},
+		{"id":"e1","source":"copilot","event_type":"copilot.burst"}
+	]`)
+	if code != http.StatusAccepted {
+		t.Fatalf("status = %d", code)
+	}
+	want := []string{eventStatusAccepted, eventStatusInvalid, eventStatusInvalid, eventStatusAccepted, eventStatusDuplicate}
+	for i, res := range resp.Results {
+		if res.Index != i || res.Status != want[i] {
+			t.Errorf("result %d = %+v, want %s", i, res, want[i])
+		}
+	}
+	if resp.Accepted != 2 || resp.Duplicates != 1 || resp.Invalid != 2 {
+		t.Errorf("counts = %+v", resp)
+	}
+
+	// Accepted events are routed in request order
+	for _, wantType := range []string{"copilot.burst", "git.commit"} {
+		select {
+		case raw := <-events:
+			if evt := raw.(bus.SystemEvent); evt.Type != wantType {
+				t.Errorf("routed %s, want %s", evt.Type, wantType)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s not routed", wantType)
+		}
+	}
+
+	// The single-event endpoint shares the dedupe
+	rec := httptest.NewRecorder()
+	s.handleWorkflowEvent(rec, httptest.NewRequest("POST", "/api/events",
+		strings.NewReader(`{"id":"e3","source":"git","event_type":"git.commit"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"duplicate":true`) {
+		t.Errorf("resent event: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if code, _ := post(`{"id":"e4"}`); code != http.StatusBadRequest {
+		t.Errorf("non-array body: status = %d", code)
+	}
+}
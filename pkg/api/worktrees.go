@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/codex"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// getWorktrees returns the per-task worktree manager when codex
+// isolate_worktrees is on, else nil.
+func (s *Server) getWorktrees() *codex.Worktrees {
+	s.worktreesOnce.Do(func() {
+		if s.config == nil || !s.config.Codex.IsolateWorktrees {
+			return
+		}
+		s.worktrees = codex.NewWorktrees(s.config.WorktreeDir())
+	})
+	return s.worktrees
+}
+
+// taskWorktree returns the worktree a task's diffs are applied in, or ""
+// to apply them in the workspace itself: isolation is off, there is no
+// task, or the workspace isn't a git repository.
+func (s *Server) taskWorktree(ctx context.Context, workspace, taskID string) string {
+	wt := s.getWorktrees()
+	if wt == nil || taskID == "" {
+		return ""
+	}
+	path, err := wt.Ensure(ctx, workspace, taskID)
+	if err != nil {
+		logger.WarnCtx(ctx, "vscode", "No worktree for task, applying in the workspace", map[string]interface{}{
+			"task_id": taskID,
+			"error":   err.Error(),
+		})
+		return ""
+	}
+	return path
+}
+
+// runWorktreeLifecycle ties task worktrees to claim leases: claiming a
+// task provisions its worktree, and releasing, failing or completing it,
+// or letting the lease expire, removes it. It blocks until ctx is done.
+func (s *Server) runWorktreeLifecycle(ctx context.Context, wt *codex.Worktrees) {
+	if s.messageBus == nil {
+		return
+	}
+	tap := s.messageBus.SubscribeSystem("worktrees")
+	// Claims that ended while the server was down
+	s.pruneWorktrees(ctx, wt)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case raw, ok := <-tap:
+			if !ok {
+				return
+			}
+			if evt, ok := raw.(bus.SystemEvent); ok {
+				s.handleWorktreeEvent(ctx, wt, evt)
+			}
+		}
+	}
+}
+
+func (s *Server) handleWorktreeEvent(ctx context.Context, wt *codex.Worktrees, evt bus.SystemEvent) {
+	data, _ := evt.Data.(map[string]interface{})
+	taskID, _ := data["task_id"].(string)
+	switch evt.Type {
+	case "task.claimed":
+		if taskID == "" {
+			return
+		}
+		workspace, err := s.resolveWorkspace(ctx, "", taskID)
+		if err == nil {
+			_, err = wt.Ensure(ctx, workspace, taskID)
+		}
+		if err != nil {
+			logger.WarnCF("worktrees", "Failed to create task worktree", map[string]interface{}{
+				"task_id": taskID,
+				"error":   err.Error(),
+			})
+		}
+	case "task.released", "task.failed", "task.completed":
+		if err := wt.Remove(ctx, taskID); err != nil {
+			logger.WarnCF("worktrees", "Failed to remove task worktree", map[string]interface{}{
+				"task_id": taskID,
+				"error":   err.Error(),
+			})
+		}
+	case "task.lease_expired":
+		s.pruneWorktrees(ctx, wt)
+	}
+}
+
+// pruneWorktrees removes the worktrees of tasks no longer under an active
+// claim.
+func (s *Server) pruneWorktrees(ctx context.Context, wt *codex.Worktrees) {
+	kb := s.getKanban()
+	if kb == nil {
+		return
+	}
+	claims, err := kb.ActiveClaimsCtx(ctx)
+	if err != nil {
+		logger.WarnCF("worktrees", "Failed to list claims for worktree cleanup", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	keep := make(map[string]bool, len(claims))
+	for _, c := range claims {
+		keep[c.TaskID] = true
+	}
+	if n, err := wt.Prune(ctx, keep); err != nil {
+		logger.WarnCF("worktrees", "Worktree cleanup failed", map[string]interface{}{"error": err.Error()})
+	} else if n > 0 {
+		logger.InfoCF("worktrees", "Removed worktrees of ended claims", map[string]interface{}{"count": n})
+	}
+}
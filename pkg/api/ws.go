@@ -1,33 +1,34 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			return true // Same-origin requests have no Origin header
-		}
-		// Allow localhost origins only
-		for _, prefix := range []string{"http://localhost", "http://127.0.0.1", "https://localhost", "https://127.0.0.1"} {
-			if len(origin) >= len(prefix) && origin[:len(prefix)] == prefix {
-				return true
-			}
-		}
-		logger.WarnCF("ws", "Rejected WebSocket from disallowed origin", map[string]interface{}{"origin": origin})
-		return false
-	},
+// checkOrigin admits same-origin upgrades and origins allowed by
+// gateway.allowed_origins (localhost always).
+func (h *WSHub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Same-origin requests have no Origin header
+	}
+	if h.server.isAllowedOrigin(origin) {
+		return true
+	}
+	logger.WarnCtx(r.Context(), "ws", "Rejected WebSocket from disallowed origin", map[string]interface{}{"origin": origin})
+	return false
 }
 
 // WSEvent represents an event sent to WebSocket clients.
@@ -35,6 +36,8 @@ type WSEvent struct {
 	Type      string      `json:"type"`
 	Timestamp string      `json:"timestamp"`
 	Data      interface{} `json:"data"`
+	Replay    bool        `json:"replay,omitempty"`     // re-sent from history on connect
+	RequestID string      `json:"request_id,omitempty"` // API request that caused the event
 }
 
 // WSClient represents a connected WebSocket client.
@@ -42,6 +45,26 @@ type WSClient struct {
 	conn *websocket.Conn
 	send chan []byte
 	hub  *WSHub
+
+	// Subscription filter. Until the client sends its first subscribe it
+	// receives every event; after that only types matching patterns.
+	subMu    sync.RWMutex
+	filtered bool
+	patterns []string
+
+	replay int // recent events to replay on connect
+
+	// Backpressure state, guarded by hub.mu.
+	remote    string
+	dropped   int64     // events that didn't fit in send
+	slowSince time.Time // zero while the buffer is below the high-water mark
+}
+
+// wsControl is an inbound client message, e.g.
+// {"action":"subscribe","types":["task.*","status_update"]}.
+type wsControl struct {
+	Action string   `json:"action"` // subscribe, unsubscribe
+	Types  []string `json:"types"`  // event types; path.Match globs such as "task.*"
 }
 
 // WSHub manages WebSocket connections and broadcasts events.
@@ -52,24 +75,90 @@ type WSHub struct {
 	register   chan *WSClient
 	unregister chan *WSClient
 	mu         sync.RWMutex
+
+	// Slow-client policy; see config.WebSocketConfig.
+	sendBuffer    int
+	highWaterMark int
+	slowGrace     time.Duration
+	stuckDropped  int64 // clients disconnected as stuck
+
+	statusDirty chan struct{} // a state change may have happened
+	lastStatus  []byte        // fingerprint of the last status_update; Run goroutine only
+
+	recent     []WSEvent // ring of the last wsReplaySize events; Run goroutine only
+	recentNext int
+}
+
+// wsReplaySize is how many recent events the hub keeps for replay to newly
+// connected clients. Clients choose how many to receive with ?replay=N.
+const (
+	wsReplaySize    = 100
+	wsReplayDefault = 50
+)
+
+// Status updates are pushed when something changes rather than on a fast
+// timer. Any broadcast event marks status dirty; changes within
+// statusDebounce are coalesced into one update, which is only sent if the
+// snapshot actually differs. statusKeepalive forces an update regardless.
+const (
+	statusDebounce  = 250 * time.Millisecond
+	statusKeepalive = 30 * time.Second
+)
+
+// WSClientStats is one client's backpressure state, reported in system status.
+type WSClientStats struct {
+	Remote      string `json:"remote"`
+	Buffered    int    `json:"buffered"`
+	Dropped     int64  `json:"dropped"`
+	Slow        bool   `json:"slow"`
+	SlowSeconds int    `json:"slow_seconds,omitempty"`
+}
+
+// WSHubStats summarizes connected clients and past disconnects.
+type WSHubStats struct {
+	Clients           []WSClientStats `json:"clients"`
+	HighWaterMark     int             `json:"high_water_mark"`
+	SlowGraceSeconds  int             `json:"slow_grace_seconds"`
+	StuckDisconnected int64           `json:"stuck_disconnected"`
 }
 
 // NewWSHub creates a new WebSocket hub.
 func NewWSHub(server *Server) *WSHub {
-	return &WSHub{
-		server:     server,
-		clients:    make(map[*WSClient]bool),
-		broadcast:  make(chan WSEvent, 256),
-		register:   make(chan *WSClient),
-		unregister: make(chan *WSClient),
+	h := &WSHub{
+		server:        server,
+		clients:       make(map[*WSClient]bool),
+		broadcast:     make(chan WSEvent, 256),
+		register:      make(chan *WSClient),
+		unregister:    make(chan *WSClient),
+		statusDirty:   make(chan struct{}, 1),
+		sendBuffer:    256,
+		highWaterMark: 192,
+		slowGrace:     30 * time.Second,
 	}
+	if server != nil && server.config != nil {
+		ws := server.config.Gateway.WebSocket
+		if ws.SendBuffer > 0 {
+			h.sendBuffer = ws.SendBuffer
+		}
+		if ws.HighWaterMark > 0 {
+			h.highWaterMark = ws.HighWaterMark
+		}
+		if ws.SlowGraceSeconds > 0 {
+			h.slowGrace = time.Duration(ws.SlowGraceSeconds) * time.Second
+		}
+	}
+	if h.highWaterMark > h.sendBuffer {
+		h.highWaterMark = h.sendBuffer
+	}
+	return h
 }
 
 // Run starts the hub's main loop.
 func (h *WSHub) Run(ctx context.Context) {
-	// Periodic status broadcast
-	statusTicker := time.NewTicker(5 * time.Second)
-	defer statusTicker.Stop()
+	// Slow keepalive; real changes are pushed via statusDirty.
+	keepalive := time.NewTicker(statusKeepalive)
+	defer keepalive.Stop()
+	var debounce <-chan time.Time
 
 	for {
 		select {
@@ -88,8 +177,9 @@ func (h *WSHub) Run(ctx context.Context) {
 			h.mu.Unlock()
 			logger.DebugC("ws", "Client connected")
 
-			// Send initial state
+			// Send initial state, then what happened just before
 			h.sendInitialState(client)
+			h.replayRecent(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
@@ -105,36 +195,169 @@ func (h *WSHub) Run(ctx context.Context) {
 			if err != nil {
 				continue
 			}
-			h.mu.RLock()
+			h.remember(event)
+			h.mu.Lock()
 			for client := range h.clients {
-				select {
-				case client.send <- data:
-				default:
-					// Client too slow, drop
-					close(client.send)
-					delete(h.clients, client)
+				if !client.wants(event.Type) {
+					continue
 				}
+				h.deliver(client, data, event)
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+
+		case <-h.statusDirty:
+			if debounce == nil {
+				debounce = time.After(statusDebounce)
+			}
+
+		case <-debounce:
+			debounce = nil
+			h.broadcastStatus(false)
+
+		case <-keepalive.C:
+			h.broadcastStatus(true)
+		}
+	}
+}
 
-		case <-statusTicker.C:
-			h.broadcastStatus()
+// deliver queues data for one client under the slow-client policy. A client
+// at or above the high-water mark is slow: it still gets events while
+// there is room, and events that don't fit are dropped and counted. Only a
+// client that has been slow for longer than the grace window is considered
+// stuck and disconnected, so a brief stall doesn't kill a dashboard.
+// Caller must hold h.mu.
+func (h *WSHub) deliver(client *WSClient, data []byte, event WSEvent) {
+	now := time.Now()
+	if len(client.send) < h.highWaterMark {
+		client.slowSince = time.Time{}
+	} else if client.slowSince.IsZero() {
+		client.slowSince = now
+	}
+
+	if !client.slowSince.IsZero() && now.Sub(client.slowSince) > h.slowGrace {
+		close(client.send)
+		delete(h.clients, client)
+		h.stuckDropped++
+		logger.WarnCF("ws", "Disconnected stuck client", map[string]interface{}{
+			"remote":  client.remote,
+			"dropped": client.dropped,
+		})
+		h.deadLetter("stuck client disconnected", event)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		client.dropped++
+		h.deadLetter("slow client buffer full", event)
+	}
+}
+
+// remember adds an event to the replay ring. Status updates are skipped:
+// a new client already has fresher state from sendInitialState.
+func (h *WSHub) remember(event WSEvent) {
+	if event.Type == "status_update" {
+		return
+	}
+	if len(h.recent) < wsReplaySize {
+		h.recent = append(h.recent, event)
+		return
+	}
+	h.recent[h.recentNext] = event
+	h.recentNext = (h.recentNext + 1) % wsReplaySize
+}
+
+// replayRecent sends up to client.replay of the most recent events the
+// client's subscription covers, oldest first, marked as replays.
+func (h *WSHub) replayRecent(client *WSClient) {
+	n := len(h.recent)
+	ordered := make([]WSEvent, 0, n)
+	ordered = append(ordered, h.recent[h.recentNext:]...)
+	ordered = append(ordered, h.recent[:h.recentNext]...)
+
+	var matched []WSEvent
+	for i := len(ordered) - 1; i >= 0 && len(matched) < client.replay; i-- {
+		if client.wants(ordered[i].Type) {
+			matched = append(matched, ordered[i])
+		}
+	}
+	for i := len(matched) - 1; i >= 0; i-- {
+		event := matched[i]
+		event.Replay = true
+		data, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			return
 		}
 	}
 }
 
+// Stats reports per-client buffer usage and drop counts.
+func (h *WSHub) Stats() WSHubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := WSHubStats{
+		Clients:           make([]WSClientStats, 0, len(h.clients)),
+		HighWaterMark:     h.highWaterMark,
+		SlowGraceSeconds:  int(h.slowGrace.Seconds()),
+		StuckDisconnected: h.stuckDropped,
+	}
+	for c := range h.clients {
+		cs := WSClientStats{
+			Remote:   c.remote,
+			Buffered: len(c.send),
+			Dropped:  c.dropped,
+			Slow:     !c.slowSince.IsZero(),
+		}
+		if cs.Slow {
+			cs.SlowSeconds = int(time.Since(c.slowSince).Seconds())
+		}
+		stats.Clients = append(stats.Clients, cs)
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool { return stats.Clients[i].Remote < stats.Clients[j].Remote })
+	return stats
+}
+
 // Broadcast sends an event to all connected clients.
 func (h *WSHub) Broadcast(eventType string, data interface{}) {
-	event := WSEvent{
-		Type:      eventType,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Data:      data,
-	}
+	h.publish(WSEvent{Type: eventType, Data: data})
+}
+
+// publish timestamps event and queues it for every client.
+func (h *WSHub) publish(event WSEvent) {
+	event.Timestamp = time.Now().UTC().Format(time.RFC3339)
 	select {
 	case h.broadcast <- event:
 	default:
 		// Channel full, drop event
+		h.deadLetter("broadcast queue full", event)
+	}
+	if event.Type != "status_update" {
+		h.RequestStatus()
+	}
+}
+
+// RequestStatus asks the hub to check for a status change and push an
+// update if there is one. Calls are cheap and debounced.
+func (h *WSHub) RequestStatus() {
+	select {
+	case h.statusDirty <- struct{}{}:
+	default:
+	}
+}
+
+// deadLetter reports a dropped WebSocket event to the bus dead-letter sink.
+func (h *WSHub) deadLetter(reason string, event WSEvent) {
+	if h.server == nil || h.server.messageBus == nil {
+		return
 	}
+	h.server.messageBus.DeadLetter(bus.DeadLetterWS, event.Type, reason, event)
 }
 
 // HandleWebSocket handles WebSocket upgrade requests.
@@ -143,18 +366,43 @@ func (h *WSHub) Broadcast(eventType string, data interface{}) {
 // the Authorization header, X-API-Key header, or ?token= query param.
 // No per-message auth is needed — WebSocket connections are stateful.
 func (h *WSHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		logger.ErrorCF("ws", "WebSocket upgrade failed", map[string]interface{}{
+		logger.ErrorCtx(r.Context(), "ws", "WebSocket upgrade failed", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return
 	}
 
 	client := &WSClient{
-		conn: conn,
-		send: make(chan []byte, 256),
-		hub:  h,
+		conn:   conn,
+		send:   make(chan []byte, h.sendBuffer),
+		hub:    h,
+		remote: r.RemoteAddr,
+		replay: wsReplayDefault,
+	}
+
+	// ?types=task.*,bot.* subscribes up front, so replay is filtered too;
+	// ?replay=N picks how many recent events to receive (0 for none).
+	q := r.URL.Query()
+	if types := q.Get("types"); types != "" {
+		client.filtered = true
+		for _, t := range strings.Split(types, ",") {
+			if _, err := path.Match(t, ""); err == nil && t != "" {
+				client.patterns = append(client.patterns, t)
+			}
+		}
+	}
+	if n, err := strconv.Atoi(q.Get("replay")); err == nil && n >= 0 {
+		client.replay = n
+		if n > wsReplaySize {
+			client.replay = wsReplaySize
+		}
 	}
 
 	h.register <- client
@@ -203,7 +451,9 @@ func (h *WSHub) sendInitialState(client *WSClient) {
 	}
 }
 
-func (h *WSHub) broadcastStatus() {
+// broadcastStatus sends a status_update if the snapshot differs from the
+// last one sent, or unconditionally when force is set.
+func (h *WSHub) broadcastStatus(force bool) {
 	h.mu.RLock()
 	clientCount := len(h.clients)
 	h.mu.RUnlock()
@@ -212,11 +462,7 @@ func (h *WSHub) broadcastStatus() {
 		return
 	}
 
-	status := map[string]interface{}{
-		"uptime_seconds": int(time.Since(h.server.startTime).Seconds()),
-		"timestamp":      time.Now().UTC().Format(time.RFC3339),
-	}
-
+	status := map[string]interface{}{}
 	if h.server.agentLoop != nil {
 		status["agent_running"] = h.server.agentLoop.IsRunning()
 	}
@@ -227,6 +473,18 @@ func (h *WSHub) broadcastStatus() {
 		status["cron"] = h.server.cronService.Status()
 	}
 
+	// Compare before adding the fields that change on every call.
+	fingerprint, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	if !force && bytes.Equal(fingerprint, h.lastStatus) {
+		return
+	}
+	h.lastStatus = fingerprint
+
+	status["uptime_seconds"] = int(time.Since(h.server.startTime).Seconds())
+	status["timestamp"] = time.Now().UTC().Format(time.RFC3339)
 	h.Broadcast("status_update", status)
 }
 
@@ -246,11 +504,105 @@ func (c *WSClient) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, msg, err := c.conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		c.handleControl(msg)
+	}
+}
+
+// handleControl applies a subscribe/unsubscribe message and acknowledges it
+// with the resulting pattern list. Malformed messages get an error reply.
+func (c *WSClient) handleControl(msg []byte) {
+	var ctl wsControl
+	if err := json.Unmarshal(msg, &ctl); err != nil {
+		c.reply("error", map[string]interface{}{"error": "invalid control message"})
+		return
+	}
+	for _, t := range ctl.Types {
+		if _, err := path.Match(t, ""); err != nil {
+			c.reply("error", map[string]interface{}{"error": "invalid type pattern " + t})
+			return
+		}
+	}
+
+	c.subMu.Lock()
+	switch ctl.Action {
+	case "subscribe":
+		c.filtered = true
+		for _, t := range ctl.Types {
+			if !containsString(c.patterns, t) {
+				c.patterns = append(c.patterns, t)
+			}
+		}
+	case "unsubscribe":
+		if len(ctl.Types) == 0 {
+			c.patterns = nil
+		}
+		kept := c.patterns[:0]
+		for _, p := range c.patterns {
+			if !containsString(ctl.Types, p) {
+				kept = append(kept, p)
+			}
+		}
+		c.patterns = kept
+	default:
+		c.subMu.Unlock()
+		c.reply("error", map[string]interface{}{"error": "unknown action " + ctl.Action})
+		return
+	}
+	patterns := append([]string{}, c.patterns...)
+	c.subMu.Unlock()
+
+	c.reply("subscriptions", map[string]interface{}{"types": patterns})
+}
+
+// wants reports whether the client's subscription covers eventType.
+func (c *WSClient) wants(eventType string) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	if !c.filtered {
+		return true
+	}
+	for _, p := range c.patterns {
+		if ok, _ := path.Match(p, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reply queues a message for this client only, dropping it if the buffer
+// is full. The hub lock guards against sending after the hub has closed
+// c.send.
+func (c *WSClient) reply(eventType string, data interface{}) {
+	msg, err := json.Marshal(WSEvent{
+		Type:      eventType,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		return
+	}
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if !c.hub.clients[c] {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
 	}
+	return false
 }
 
 func (c *WSClient) writePump() {
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// startTestHub runs a hub behind an httptest server and returns a client
+// connection dialled with the given query string.
+func startTestHub(t *testing.T, query string) (*WSHub, *websocket.Conn) {
+	t.Helper()
+	hub := NewWSHub(&Server{startTime: time.Now()})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	return hub, dialTestHub(t, srv, query)
+}
+
+func dialTestHub(t *testing.T, srv *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+query, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readEvent returns the next event, splitting frames that carry several
+// newline-separated messages.
+func readEvent(t *testing.T, conn *websocket.Conn, pending *[]string) WSEvent {
+	t.Helper()
+	for len(*pending) == 0 {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		*pending = strings.Split(string(msg), "\n")
+	}
+	var ev WSEvent
+	if err := json.Unmarshal([]byte((*pending)[0]), &ev); err != nil {
+		t.Fatalf("decode %q: %v", (*pending)[0], err)
+	}
+	*pending = (*pending)[1:]
+	return ev
+}
+
+func TestWSSubscriptionFilter(t *testing.T) {
+	hub, conn := startTestHub(t, "")
+	var pending []string
+
+	if ev := readEvent(t, conn, &pending); ev.Type != "initial_state" {
+		t.Fatalf("first event = %q, want initial_state", ev.Type)
+	}
+
+	conn.WriteJSON(wsControl{Action: "subscribe", Types: []string{"task.*"}})
+	if ev := readEvent(t, conn, &pending); ev.Type != "subscriptions" {
+		t.Fatalf("ack = %q, want subscriptions", ev.Type)
+	}
+
+	hub.Broadcast("message.inbound", nil)
+	hub.Broadcast("task.moved", nil)
+	if ev := readEvent(t, conn, &pending); ev.Type != "task.moved" {
+		t.Errorf("got %q, want only task.moved", ev.Type)
+	}
+}
+
+func TestWSSlowClientPolicy(t *testing.T) {
+	hub := NewWSHub(&Server{startTime: time.Now()})
+	hub.highWaterMark = 1
+	hub.slowGrace = 50 * time.Millisecond
+	client := &WSClient{send: make(chan []byte, 2), hub: hub}
+	hub.clients[client] = true
+
+	ev := WSEvent{Type: "test"}
+	hub.deliver(client, []byte("1"), ev)
+	if !client.slowSince.IsZero() {
+		t.Fatal("client below high-water mark should not be slow")
+	}
+	hub.deliver(client, []byte("2"), ev) // at the mark: slow, still queued
+	hub.deliver(client, []byte("3"), ev) // buffer full: dropped
+	if client.slowSince.IsZero() || client.dropped != 1 || len(client.send) != 2 {
+		t.Fatalf("slow client: slow=%v dropped=%d buffered=%d, want slow, 1, 2",
+			!client.slowSince.IsZero(), client.dropped, len(client.send))
+	}
+	if st := hub.Stats(); len(st.Clients) != 1 || !st.Clients[0].Slow || st.Clients[0].Dropped != 1 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	// Draining below the mark clears the slow state.
+	<-client.send
+	<-client.send
+	hub.deliver(client, []byte("4"), ev)
+	if !client.slowSince.IsZero() {
+		t.Fatal("drained client should no longer be slow")
+	}
+
+	// Staying slow past the grace window disconnects.
+	hub.deliver(client, []byte("5"), ev)
+	time.Sleep(60 * time.Millisecond)
+	hub.deliver(client, []byte("6"), ev)
+	if hub.clients[client] {
+		t.Error("stuck client should be disconnected")
+	}
+	if hub.Stats().StuckDisconnected != 1 {
+		t.Errorf("StuckDisconnected = %d, want 1", hub.Stats().StuckDisconnected)
+	}
+}
+
+func TestWSStatusPushedOnChangeOnly(t *testing.T) {
+	hub, conn := startTestHub(t, "")
+	var pending []string
+	readEvent(t, conn, &pending) // initial_state
+
+	// The first event after connect pushes a status snapshot.
+	hub.Broadcast("task.moved", nil)
+	got := []string{readEvent(t, conn, &pending).Type, readEvent(t, conn, &pending).Type}
+	if got[0] != "task.moved" || got[1] != "status_update" {
+		t.Fatalf("events = %v, want [task.moved status_update]", got)
+	}
+
+	// Nothing changed, so another event brings no status update.
+	hub.Broadcast("task.moved", nil)
+	if ev := readEvent(t, conn, &pending); ev.Type != "task.moved" {
+		t.Fatalf("event = %q, want task.moved", ev.Type)
+	}
+	conn.SetReadDeadline(time.Now().Add(3 * statusDebounce))
+	if _, msg, err := conn.ReadMessage(); err == nil {
+		t.Errorf("unexpected message %s", msg)
+	}
+}
+
+func TestWSReplayRecentEvents(t *testing.T) {
+	hub := NewWSHub(&Server{startTime: time.Now()})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	hub.Broadcast("task.created", map[string]string{"id": "T1"})
+	hub.Broadcast("message.inbound", nil)
+	hub.Broadcast("task.moved", map[string]string{"id": "T1"})
+	hub.Broadcast("task.done", map[string]string{"id": "T1"})
+	time.Sleep(50 * time.Millisecond) // let Run record them
+
+	conn := dialTestHub(t, srv, "?types=task.*&replay=2")
+	var pending []string
+	if ev := readEvent(t, conn, &pending); ev.Type != "initial_state" {
+		t.Fatalf("first event = %q, want initial_state", ev.Type)
+	}
+	for _, want := range []string{"task.moved", "task.done"} {
+		ev := readEvent(t, conn, &pending)
+		if ev.Type != want || !ev.Replay {
+			t.Errorf("replayed %q (replay=%v), want %q", ev.Type, ev.Replay, want)
+		}
+	}
+}
+
+// idleChannel is a channels.Channel that only tracks whether it's running.
+type idleChannel struct{ running atomic.Bool }
+
+func (c *idleChannel) Name() string                                            { return "idle" }
+func (c *idleChannel) Start(ctx context.Context) error                         { c.running.Store(true); return nil }
+func (c *idleChannel) Stop(ctx context.Context) error                          { c.running.Store(false); return nil }
+func (c *idleChannel) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+func (c *idleChannel) IsRunning() bool                                         { return c.running.Load() }
+func (c *idleChannel) IsAllowed(senderID string) bool                          { return true }
+
+func TestWSStatusPushedOnChannelChange(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	cfg := &config.Config{}
+	cfg.Gateway.APIKey = "test"
+	mgr, err := channels.NewManager(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.RegisterChannel("idle", &idleChannel{})
+
+	s := NewServer(cfg, nil, mgr, nil, msgBus, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.wsHub.Run(ctx)
+	srv := httptest.NewServer(http.HandlerFunc(s.wsHub.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn := dialTestHub(t, srv, "")
+	var pending []string
+	readEvent(t, conn, &pending) // initial_state
+
+	// Starting the channel is the only thing that happens; the update must
+	// not wait for the keepalive.
+	mgr.StartAll(ctx)
+	t.Cleanup(func() { mgr.StopAll(context.Background()) })
+	ev := readEvent(t, conn, &pending)
+	if ev.Type != "status_update" {
+		t.Fatalf("event = %q, want status_update", ev.Type)
+	}
+	entry, _ := ev.Data.(map[string]interface{})["channels"].(map[string]interface{})["idle"].(map[string]interface{})
+	if entry["running"] != true {
+		t.Errorf("status_update channels = %v, want idle running", ev.Data)
+	}
+}
@@ -32,6 +32,35 @@ func (s *AgentService) CreateAgent(name string, config agentdomain.ModelConfig)
 	return ag, nil
 }
 
+// EnsureRunning finds the agent called name, creating it with config if it
+// doesn't exist, sets its tool and skill bindings to exactly tools and
+// skills, and starts it. It is how a process records the agent it runs, so
+// subscribers to EventAgentStarted see its current bindings.
+func (s *AgentService) EnsureRunning(name string, config agentdomain.ModelConfig, tools, skills []string) (*agentdomain.Agent, error) {
+	ag, err := s.repo.FindByName(name)
+	if err != nil {
+		if ag, err = s.CreateAgent(name, config); err != nil {
+			return nil, err
+		}
+	}
+
+	ag.Tools = make([]agentdomain.ToolBinding, 0, len(tools))
+	for _, t := range tools {
+		ag.BindTool(agentdomain.ToolBinding{Name: t, Enabled: true})
+	}
+	ag.Skills = make([]agentdomain.SkillBinding, 0, len(skills))
+	for _, sk := range skills {
+		ag.BindSkill(agentdomain.SkillBinding{Name: sk, Enabled: true})
+	}
+	ag.Start()
+	if err := s.repo.Save(ag); err != nil {
+		return nil, err
+	}
+
+	s.publishEvents(ag)
+	return ag, nil
+}
+
 // StartAgent transitions an agent to the running state.
 func (s *AgentService) StartAgent(id domain.EntityID) error {
 	ag, err := s.repo.FindByID(id)
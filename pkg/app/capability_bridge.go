@@ -0,0 +1,90 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	agentdomain "github.com/sipeed/picoclaw/pkg/domain/agent"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/orchestration"
+)
+
+// ---------------------------------------------------------------------------
+// Capability bridge — Agent bindings → orchestrator routing
+// ---------------------------------------------------------------------------
+
+// DefaultSkillTaskCategories maps each skill category to the kanban task
+// categories an agent holding such a skill can take on.
+var DefaultSkillTaskCategories = map[skilldomain.SkillCategory][]string{
+	skilldomain.CategoryResearch:   {"research"},
+	skilldomain.CategoryKnowledge:  {"research"},
+	skilldomain.CategoryData:       {"research"},
+	skilldomain.CategoryAutomation: {"ops"},
+	skilldomain.CategoryDevOps:     {"infra", "ops"},
+	skilldomain.CategorySystem:     {"infra", "ops"},
+	skilldomain.CategoryMedia:      {"design"},
+	skilldomain.CategoryComms:      {"personal", "meeting"},
+}
+
+// DeriveCapability builds an orchestrator capability from an agent's enabled
+// tool and skill bindings. Skills are looked up in skills to find their
+// category, which mapping turns into task categories; unknown skills and
+// unmapped categories contribute nothing. The agent's name is its routing ID.
+func DeriveCapability(ag *agentdomain.Agent, skills skilldomain.Repository, mapping map[skilldomain.SkillCategory][]string) orchestration.AgentCapability {
+	cap := orchestration.AgentCapability{
+		AgentID:    ag.Name,
+		Categories: []string{},
+		Tools:      []string{},
+	}
+
+	for _, t := range ag.Tools {
+		if t.Enabled {
+			cap.Tools = append(cap.Tools, t.Name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, b := range ag.Skills {
+		if !b.Enabled || skills == nil {
+			continue
+		}
+		sk, err := skills.FindByName(b.Name)
+		if err != nil || sk == nil {
+			continue
+		}
+		for _, cat := range mapping[sk.Category] {
+			if !seen[cat] {
+				seen[cat] = true
+				cap.Categories = append(cap.Categories, cat)
+			}
+		}
+	}
+	sort.Strings(cap.Categories)
+	sort.Strings(cap.Tools)
+	return cap
+}
+
+// BridgeCapabilities keeps orch in step with agent lifecycle events: when an
+// agent starts, its capability is derived from its bindings and registered;
+// when it stops, that registration is removed. Agents registered by hand
+// with Orchestrator.RegisterAgent are left alone in both cases. A nil
+// mapping uses DefaultSkillTaskCategories.
+func (c *Container) BridgeCapabilities(orch *orchestration.Orchestrator, mapping map[skilldomain.SkillCategory][]string) {
+	if mapping == nil {
+		mapping = DefaultSkillTaskCategories
+	}
+	c.EventBus.Subscribe(domain.EventAgentStarted, func(event domain.Event) {
+		ag, err := c.Agents.FindByID(event.AggregateID())
+		if err != nil || ag == nil {
+			return
+		}
+		orch.RegisterDerivedAgent(DeriveCapability(ag, c.Skills, mapping))
+	})
+	c.EventBus.Subscribe(domain.EventAgentStopped, func(event domain.Event) {
+		ag, err := c.Agents.FindByID(event.AggregateID())
+		if err != nil || ag == nil {
+			return
+		}
+		orch.UnregisterDerivedAgent(ag.Name)
+	})
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	agentdomain "github.com/sipeed/picoclaw/pkg/domain/agent"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/eventbus"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/persistence"
+	"github.com/sipeed/picoclaw/pkg/orchestration"
+)
+
+// waitForAgents polls until orch's registered agent IDs equal want.
+func waitForAgents(t *testing.T, orch *orchestration.Orchestrator, want ...string) []orchestration.AgentCapability {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		caps := orch.GetAgents()
+		ids := []string{}
+		for _, c := range caps {
+			ids = append(ids, c.AgentID)
+		}
+		if reflect.DeepEqual(ids, want) || (len(ids) == 0 && len(want) == 0) {
+			return caps
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("registered agents = %v, want %v", ids, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBridgeCapabilitiesOnStart(t *testing.T) {
+	dir := t.TempDir()
+	events := eventbus.New()
+	defer events.Close()
+	c := &Container{
+		EventBus: events,
+		Agents:   persistence.NewAgentRepository(dir),
+		Skills:   persistence.NewSkillRepository(dir),
+	}
+	skills := NewSkillService(c.Skills, &fakeSkillRegistry{names: map[string]bool{}}, events)
+	if _, err := skills.RegisterSkill("deploy", "1.0.0", "", skilldomain.CategoryDevOps, domain.SkillSourceWorkspace, skilldomain.SkillSpec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	orch := orchestration.NewOrchestrator()
+	c.BridgeCapabilities(orch, nil)
+	agents := NewAgentService(c.Agents, events)
+
+	ag, err := agents.EnsureRunning("picoclaw", agentdomain.ModelConfig{Model: "m"}, []string{"shell", "git"}, []string{"deploy", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := waitForAgents(t, orch, "picoclaw")
+	if !reflect.DeepEqual(caps[0].Categories, []string{"infra", "ops"}) || !reflect.DeepEqual(caps[0].Tools, []string{"git", "shell"}) {
+		t.Errorf("derived capability = %+v", caps[0])
+	}
+
+	// A restart rebinds to the current set instead of accumulating
+	if again, err := agents.EnsureRunning("picoclaw", agentdomain.ModelConfig{Model: "m"}, []string{"shell"}, nil); err != nil || again.ID() != ag.ID() {
+		t.Fatalf("EnsureRunning again = %v, %v; want the same agent", again, err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for caps := orch.GetAgents(); len(caps) != 1 || len(caps[0].Tools) != 1; caps = orch.GetAgents() {
+		if time.Now().After(deadline) {
+			t.Fatalf("capability after rebinding = %+v", caps)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := agents.StopAgent(ag.ID()); err != nil {
+		t.Fatal(err)
+	}
+	waitForAgents(t, orch)
+
+	// Manual registration overrides the derived one
+	orch.RegisterAgent(orchestration.AgentCapability{AgentID: "picoclaw", Categories: []string{"code"}})
+	agents.EnsureRunning("picoclaw", agentdomain.ModelConfig{Model: "m"}, []string{"shell"}, []string{"deploy"})
+	agents.StopAgent(ag.ID())
+	time.Sleep(50 * time.Millisecond)
+	if caps := orch.GetAgents(); len(caps) != 1 || !reflect.DeepEqual(caps[0].Categories, []string{"code"}) {
+		t.Errorf("manual registration after bridge events = %+v", caps)
+	}
+}
@@ -140,35 +140,94 @@ func (s *ChannelService) DisconnectChannel(ctx context.Context, id domain.Entity
 	return nil
 }
 
-// SendMessage delivers a message through a channel.
-func (s *ChannelService) SendMessage(ctx context.Context, channelID domain.EntityID, chatID, content string) error {
+// SendMessage delivers a message through a channel and returns the provider
+// message ID, which EditMessage and DeleteMessage accept.
+func (s *ChannelService) SendMessage(ctx context.Context, channelID domain.EntityID, chatID, content string) (string, error) {
 	ch, err := s.repo.FindByID(channelID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	transport, ok := s.transports[channelID]
 	if !ok {
-		return fmt.Errorf("no transport for channel %s", ch.Name)
+		return "", fmt.Errorf("no transport for channel %s", ch.Name)
 	}
 
 	msg := channeldomain.NewOutboundMessage(channelID, chatID, content)
 
-	if err := transport.Send(ctx, msg); err != nil {
+	messageID, err := transport.Send(ctx, msg)
+	if err != nil {
 		ch.MarkError(err.Error())
 		s.repo.Save(ch)
-		return err
+		return "", err
 	}
 
 	ch.RecordMessageSent()
 	s.repo.Save(ch)
 	s.eventBus.Publish(domain.NewEvent(domain.EventMessageSent, channelID, map[string]string{
-		"channel": ch.Name,
-		"chat_id": chatID,
+		"channel":    ch.Name,
+		"chat_id":    chatID,
+		"message_id": messageID,
 	}))
+	return messageID, nil
+}
+
+// EditMessage replaces the content of a message previously sent through the
+// channel. Returns ErrUnsupported if the transport cannot edit.
+func (s *ChannelService) EditMessage(ctx context.Context, channelID domain.EntityID, messageID, content string) error {
+	ch, transport, err := s.transportFor(channelID)
+	if err != nil {
+		return err
+	}
+
+	editor, ok := transport.(channeldomain.MessageEditor)
+	if !ok {
+		return fmt.Errorf("edit message on %s: %w", ch.Name, channeldomain.ErrUnsupported)
+	}
+	return editor.Edit(ctx, messageID, content)
+}
+
+// DeleteMessage removes a message previously sent through the channel.
+// Returns ErrUnsupported if the transport cannot delete.
+func (s *ChannelService) DeleteMessage(ctx context.Context, channelID domain.EntityID, messageID string) error {
+	ch, transport, err := s.transportFor(channelID)
+	if err != nil {
+		return err
+	}
+
+	deleter, ok := transport.(channeldomain.MessageDeleter)
+	if !ok {
+		return fmt.Errorf("delete message on %s: %w", ch.Name, channeldomain.ErrUnsupported)
+	}
+	return deleter.Delete(ctx, messageID)
+}
+
+// Typing shows a typing indicator in chatID. Transports without presence
+// support make this a no-op.
+func (s *ChannelService) Typing(ctx context.Context, channelID domain.EntityID, chatID string) error {
+	_, transport, err := s.transportFor(channelID)
+	if err != nil {
+		return err
+	}
+
+	if ti, ok := transport.(channeldomain.TypingIndicator); ok {
+		return ti.Typing(ctx, chatID)
+	}
 	return nil
 }
 
+func (s *ChannelService) transportFor(channelID domain.EntityID) (*channeldomain.Channel, channeldomain.Transport, error) {
+	ch, err := s.repo.FindByID(channelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, ok := s.transports[channelID]
+	if !ok {
+		return nil, nil, fmt.Errorf("no transport for channel %s", ch.Name)
+	}
+	return ch, transport, nil
+}
+
 // GetChannel retrieves channel details.
 func (s *ChannelService) GetChannel(id domain.EntityID) (*channeldomain.Channel, error) {
 	return s.repo.FindByID(id)
@@ -4,6 +4,8 @@
 package app
 
 import (
+	"fmt"
+
 	"github.com/sipeed/picoclaw/pkg/domain"
 	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
 	agentdomain "github.com/sipeed/picoclaw/pkg/domain/agent"
@@ -11,6 +13,7 @@ import (
 	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
 	workflowdomain "github.com/sipeed/picoclaw/pkg/domain/workflow"
 	providerdomain "github.com/sipeed/picoclaw/pkg/domain/provider"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 // ---------------------------------------------------------------------------
@@ -23,6 +26,9 @@ type Container struct {
 	// Domain event bus
 	EventBus domain.EventBus
 
+	// Append-only event history (optional; nil disables recording)
+	EventStore domain.EventStore
+
 	// Repositories
 	Channels  channeldomain.Repository
 	Agents    agentdomain.Repository
@@ -72,3 +78,27 @@ func (c *Container) PublishEvents(aggregate interface {
 		c.EventBus.Publish(event)
 	}
 }
+
+// AttachEventStore records every event published on the container's bus to
+// store, so aggregate histories can be replayed later. Events that fail to
+// append are logged and skipped.
+func (c *Container) AttachEventStore(store domain.EventStore) {
+	c.EventStore = store
+	c.EventBus.SubscribeAll(func(event domain.Event) {
+		if err := store.Append(event); err != nil {
+			logger.WarnCF("events", "Failed to record event", map[string]interface{}{
+				"event_type":   string(event.EventType()),
+				"aggregate_id": string(event.AggregateID()),
+				"error":        err.Error(),
+			})
+		}
+	})
+}
+
+// ReplayEvents returns the recorded history of an aggregate, oldest first.
+func (c *Container) ReplayEvents(aggregateID domain.EntityID) ([]domain.Event, error) {
+	if c.EventStore == nil {
+		return nil, fmt.Errorf("event store not configured")
+	}
+	return c.EventStore.Replay(aggregateID)
+}
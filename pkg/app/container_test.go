@@ -0,0 +1,53 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/eventbus"
+)
+
+// flakyStore fails every other append.
+type flakyStore struct {
+	mu     sync.Mutex
+	calls  int
+	events []domain.Event
+}
+
+func (s *flakyStore) Append(event domain.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls%2 == 0 {
+		return errors.New("disk full")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *flakyStore) Replay(aggregateID domain.EntityID) ([]domain.Event, error) {
+	return s.events, nil
+}
+
+func TestAttachEventStore(t *testing.T) {
+	events := eventbus.New()
+	c := &Container{EventBus: events}
+	if _, err := c.ReplayEvents("ch"); err == nil {
+		t.Error("ReplayEvents without a store succeeded")
+	}
+
+	store := &flakyStore{}
+	c.AttachEventStore(store)
+	for _, typ := range []domain.EventType{domain.EventChannelConnected, domain.EventChannelError, domain.EventChannelDisconnected} {
+		events.Publish(domain.NewEvent(typ, "ch", nil))
+	}
+	events.Close()
+
+	// A failed append is skipped, not fatal to the ones after it
+	got, _ := c.ReplayEvents("ch")
+	if store.calls != 3 || len(got) != 2 || got[1].EventType() != domain.EventChannelDisconnected {
+		t.Errorf("recorded %v after %d appends", got, store.calls)
+	}
+}
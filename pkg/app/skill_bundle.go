@@ -0,0 +1,221 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+)
+
+// ---------------------------------------------------------------------------
+// Skill bundles — shareable sets of skills
+// ---------------------------------------------------------------------------
+
+// SkillBundleFormat identifies the bundle layout written by ExportBundle.
+const SkillBundleFormat = "picoclaw.skills/v1"
+
+// SkillBundle is a portable set of skills: their identity, spec, and
+// dependencies, without local state such as metrics or install paths.
+type SkillBundle struct {
+	Format     string         `json:"format"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Skills     []BundledSkill `json:"skills"`
+}
+
+// BundledSkill is one skill as carried in a SkillBundle.
+type BundledSkill struct {
+	Name         string                        `json:"name"`
+	Version      string                        `json:"version"`
+	Description  string                        `json:"description"`
+	Author       string                        `json:"author,omitempty"`
+	License      string                        `json:"license,omitempty"`
+	Tags         domain.Tags                   `json:"tags,omitempty"`
+	Category     skilldomain.SkillCategory     `json:"category"`
+	Spec         skilldomain.SkillSpec         `json:"spec"`
+	Dependencies []skilldomain.SkillDependency `json:"dependencies,omitempty"`
+}
+
+// CollisionStrategy decides what ImportBundle does with a skill whose name
+// is already registered.
+type CollisionStrategy string
+
+const (
+	CollisionSkip      CollisionStrategy = "skip"
+	CollisionOverwrite CollisionStrategy = "overwrite"
+	CollisionRename    CollisionStrategy = "rename"
+)
+
+// ImportReport lists what ImportBundle did with each bundled skill.
+type ImportReport struct {
+	Imported    []string          `json:"imported"`
+	Overwritten []string          `json:"overwritten,omitempty"`
+	Skipped     []string          `json:"skipped,omitempty"`
+	Renamed     map[string]string `json:"renamed,omitempty"` // bundle name → registered name
+}
+
+// ExportBundle serializes the named skills as a JSON SkillBundle. An empty
+// names list exports every skill.
+func (s *SkillService) ExportBundle(names []string) ([]byte, error) {
+	var skills []*skilldomain.Skill
+	if len(names) == 0 {
+		all, err := s.repo.FindAll()
+		if err != nil {
+			return nil, err
+		}
+		skills = all
+	} else {
+		for _, name := range names {
+			sk, err := s.repo.FindByName(name)
+			if err != nil {
+				return nil, fmt.Errorf("skill '%s': %w", name, err)
+			}
+			skills = append(skills, sk)
+		}
+	}
+
+	bundle := SkillBundle{
+		Format:     SkillBundleFormat,
+		ExportedAt: time.Now().UTC(),
+		Skills:     make([]BundledSkill, 0, len(skills)),
+	}
+	for _, sk := range skills {
+		bundle.Skills = append(bundle.Skills, BundledSkill{
+			Name:         sk.Name,
+			Version:      sk.Version,
+			Description:  sk.Description,
+			Author:       sk.Author,
+			License:      sk.License,
+			Tags:         sk.Tags,
+			Category:     sk.Category,
+			Spec:         sk.Spec,
+			Dependencies: sk.Dependencies,
+		})
+	}
+	sort.Slice(bundle.Skills, func(i, j int) bool { return bundle.Skills[i].Name < bundle.Skills[j].Name })
+	return json.MarshalIndent(bundle, "", "  ")
+}
+
+// ImportBundle registers the skills in a bundle produced by ExportBundle.
+// Name collisions are resolved by strategy (empty means skip). Renamed
+// skills get a numeric suffix, and dependencies inside the bundle follow
+// the rename.
+func (s *SkillService) ImportBundle(data []byte, strategy CollisionStrategy) (*ImportReport, error) {
+	var bundle SkillBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("decode skill bundle: %w", err)
+	}
+	if bundle.Format != SkillBundleFormat {
+		return nil, fmt.Errorf("unsupported skill bundle format %q", bundle.Format)
+	}
+	switch strategy {
+	case "":
+		strategy = CollisionSkip
+	case CollisionSkip, CollisionOverwrite, CollisionRename:
+	default:
+		return nil, fmt.Errorf("unknown collision strategy %q", strategy)
+	}
+	for i, b := range bundle.Skills {
+		if b.Name == "" {
+			return nil, fmt.Errorf("skill bundle entry %d: %w: name is empty", i, skilldomain.ErrInvalidSkillSpec)
+		}
+	}
+
+	report := &ImportReport{Imported: []string{}}
+
+	// Settle names first so dependencies can be rewritten to match.
+	names := make(map[string]string, len(bundle.Skills))
+	existing := make(map[string]*skilldomain.Skill)
+	for _, b := range bundle.Skills {
+		sk, _ := s.repo.FindByName(b.Name)
+		if sk == nil {
+			names[b.Name] = b.Name
+			continue
+		}
+		switch strategy {
+		case CollisionSkip:
+			report.Skipped = append(report.Skipped, b.Name)
+		case CollisionOverwrite:
+			names[b.Name] = b.Name
+			existing[b.Name] = sk
+		case CollisionRename:
+			renamed := s.freeSkillName(b.Name, names)
+			names[b.Name] = renamed
+			if report.Renamed == nil {
+				report.Renamed = make(map[string]string)
+			}
+			report.Renamed[b.Name] = renamed
+		}
+	}
+
+	for _, b := range bundle.Skills {
+		name, ok := names[b.Name]
+		if !ok {
+			continue
+		}
+		deps := make([]skilldomain.SkillDependency, len(b.Dependencies))
+		for i, dep := range b.Dependencies {
+			if to, ok := names[dep.SkillName]; ok {
+				dep.SkillName = to
+			}
+			deps[i] = dep
+		}
+
+		sk := existing[b.Name]
+		if sk == nil {
+			created, err := s.factory.CreateSkill(name, b.Version, b.Description, b.Category, domain.SkillSourceHub, b.Spec)
+			if err != nil {
+				return report, fmt.Errorf("skill '%s': %w", b.Name, err)
+			}
+			sk = created
+		} else {
+			s.registry.Unregister(sk.Name)
+			sk.Version = b.Version
+			sk.Description = b.Description
+			sk.Category = b.Category
+			sk.Spec = b.Spec
+			sk.UpdatedAt = domain.Now()
+		}
+		sk.Author = b.Author
+		sk.License = b.License
+		sk.Tags = b.Tags
+		sk.Dependencies = deps
+
+		if err := s.repo.Save(sk); err != nil {
+			return report, fmt.Errorf("save skill '%s': %w", name, err)
+		}
+		if err := s.registry.Register(sk); err != nil {
+			return report, fmt.Errorf("register skill '%s': %w", name, err)
+		}
+		s.publishEvents(sk)
+
+		if existing[b.Name] != nil {
+			report.Overwritten = append(report.Overwritten, name)
+		} else {
+			report.Imported = append(report.Imported, name)
+		}
+	}
+	return report, nil
+}
+
+// freeSkillName returns name with the lowest numeric suffix that is neither
+// registered nor already taken by this import.
+func (s *SkillService) freeSkillName(name string, taken map[string]string) string {
+	inUse := func(candidate string) bool {
+		for _, n := range taken {
+			if n == candidate {
+				return true
+			}
+		}
+		sk, _ := s.repo.FindByName(candidate)
+		return sk != nil
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !inUse(candidate) {
+			return candidate
+		}
+	}
+}
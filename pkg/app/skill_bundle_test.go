@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/eventbus"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/persistence"
+)
+
+type fakeSkillRegistry struct{ names map[string]bool }
+
+func (r *fakeSkillRegistry) Register(sk *skilldomain.Skill) error {
+	r.names[sk.Name] = true
+	return nil
+}
+func (r *fakeSkillRegistry) Unregister(name string) error { delete(r.names, name); return nil }
+func (r *fakeSkillRegistry) Discover(string, skilldomain.SkillCategory, domain.Tags) ([]*skilldomain.Skill, error) {
+	return nil, nil
+}
+func (r *fakeSkillRegistry) Get(string) (*skilldomain.Skill, error) { return nil, nil }
+func (r *fakeSkillRegistry) List() ([]*skilldomain.Skill, error)    { return nil, nil }
+func (r *fakeSkillRegistry) Count() int                             { return len(r.names) }
+
+func newTestSkillService(t *testing.T) *SkillService {
+	t.Helper()
+	return NewSkillService(persistence.NewSkillRepository(t.TempDir()), &fakeSkillRegistry{names: map[string]bool{}}, eventbus.New())
+}
+
+func TestSkillBundleRoundtrip(t *testing.T) {
+	src := newTestSkillService(t)
+	if _, err := src.RegisterSkill("fetch", "1.0.0", "fetch a page", skilldomain.CategoryResearch, domain.SkillSourceWorkspace,
+		skilldomain.SkillSpec{Command: "fetch {{url}}"}); err != nil {
+		t.Fatal(err)
+	}
+	summarize, err := src.RegisterSkill("summarize", "1.0.0", "summarize text", skilldomain.CategoryKnowledge, domain.SkillSourceWorkspace, skilldomain.SkillSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summarize.AddDependency("fetch", "", true)
+
+	data, err := src.ExportBundle([]string{"fetch", "summarize"})
+	if err != nil {
+		t.Fatalf("ExportBundle() error: %v", err)
+	}
+
+	dst := newTestSkillService(t)
+	if _, err := dst.RegisterSkill("fetch", "0.1.0", "local fetch", skilldomain.CategoryResearch, domain.SkillSourceWorkspace, skilldomain.SkillSpec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := dst.ImportBundle(data, CollisionSkip)
+	if err != nil {
+		t.Fatalf("ImportBundle(skip) error: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Imported) != 1 {
+		t.Errorf("skip report = %+v", report)
+	}
+	if sk, _ := dst.GetSkillByName("fetch"); sk.Description != "local fetch" {
+		t.Errorf("skip overwrote fetch: %q", sk.Description)
+	}
+
+	if _, err := dst.ImportBundle(data, CollisionOverwrite); err != nil {
+		t.Fatalf("ImportBundle(overwrite) error: %v", err)
+	}
+	if sk, _ := dst.GetSkillByName("fetch"); sk.Spec.Command != "fetch {{url}}" {
+		t.Errorf("overwrite kept old spec: %+v", sk.Spec)
+	}
+
+	report, err = dst.ImportBundle(data, CollisionRename)
+	if err != nil {
+		t.Fatalf("ImportBundle(rename) error: %v", err)
+	}
+	if report.Renamed["fetch"] != "fetch-2" || report.Renamed["summarize"] != "summarize-2" {
+		t.Fatalf("renamed = %v", report.Renamed)
+	}
+	sk, err := dst.GetSkillByName("summarize-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sk.HasDependency("fetch-2") {
+		t.Errorf("dependency not rewritten: %+v", sk.Dependencies)
+	}
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+)
+
+// ---------------------------------------------------------------------------
+// Remote skill registry client (ClawHub)
+// ---------------------------------------------------------------------------
+
+// maxSkillDownload caps any single manifest or file fetched from a registry.
+const maxSkillDownload = 10 << 20
+
+// ErrRemoteInstallDisabled is returned by InstallFromRegistry until
+// EnableRemoteInstall has been called. Registry skills carry commands that
+// run on this machine, so fetching them is strictly opt-in.
+var ErrRemoteInstallDisabled = errors.New("remote skill install is disabled")
+
+// SkillManifest is what a registry serves at
+// {registry}/skills/{name}/{version}/manifest.json ("latest" when no
+// version is asked for).
+type SkillManifest struct {
+	BundledSkill
+	Files []SkillFile `json:"files,omitempty"`
+}
+
+// SkillFile is one file of a registry skill. URL may be relative to the
+// manifest; SHA256 is required and checked before anything is installed.
+type SkillFile struct {
+	Path   string `json:"path"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// remoteInstall holds the opt-in settings for registry installs.
+type remoteInstall struct {
+	dir    string
+	client *http.Client
+}
+
+// EnableRemoteInstall allows InstallFromRegistry, placing downloaded skills
+// under skillsDir/<name>. A nil client uses a 30s-timeout default.
+func (s *SkillService) EnableRemoteInstall(skillsDir string, client *http.Client) {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	s.remote = &remoteInstall{dir: skillsDir, client: client}
+}
+
+// InstallFromRegistry downloads a skill from a remote registry, verifies
+// every file's checksum, validates its spec, and registers it. Required
+// dependencies that aren't registered yet are installed from the same
+// registry first. An empty version means the latest.
+func (s *SkillService) InstallFromRegistry(registryURL, name, version string) (*skilldomain.Skill, error) {
+	if s.remote == nil {
+		return nil, ErrRemoteInstallDisabled
+	}
+	base, err := parseRegistryURL(registryURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.installFromRegistry(base, name, version, map[string]bool{})
+}
+
+func (s *SkillService) installFromRegistry(base *url.URL, name, version string, visiting map[string]bool) (*skilldomain.Skill, error) {
+	if visiting[name] {
+		return nil, fmt.Errorf("skill '%s': %w", name, skilldomain.ErrCircularDependency)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	if existing, _ := s.repo.FindByName(name); existing != nil {
+		return nil, fmt.Errorf("skill '%s': %w", name, skilldomain.ErrSkillAlreadyExists)
+	}
+	if version == "" {
+		version = "latest"
+	}
+
+	manifestURL := base.JoinPath("skills", name, version, "manifest.json")
+	data, err := s.fetchSkillFile(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest for '%s': %w", name, err)
+	}
+	var manifest SkillManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest for '%s': %w", name, err)
+	}
+	if manifest.Name != name {
+		return nil, fmt.Errorf("registry returned skill '%s' for '%s'", manifest.Name, name)
+	}
+	if err := validateSkillSpec(manifest.Spec); err != nil {
+		return nil, fmt.Errorf("skill '%s': %w", name, err)
+	}
+
+	for _, dep := range manifest.Dependencies {
+		if !dep.Required {
+			continue
+		}
+		if sk, _ := s.repo.FindByName(dep.SkillName); sk != nil {
+			continue
+		}
+		if _, err := s.installFromRegistry(base, dep.SkillName, exactVersion(dep.VersionConstraint), visiting); err != nil {
+			return nil, fmt.Errorf("skill '%s': dependency: %w", name, err)
+		}
+	}
+
+	dir, err := s.downloadSkillFiles(manifestURL, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("skill '%s': %w", name, err)
+	}
+
+	sk, err := s.factory.CreateSkill(manifest.Name, manifest.Version, manifest.Description, manifest.Category, domain.SkillSourceHub, manifest.Spec)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	sk.Author = manifest.Author
+	sk.License = manifest.License
+	sk.Tags = manifest.Tags
+	sk.Dependencies = manifest.Dependencies
+	sk.Install(dir)
+
+	if err := s.repo.Save(sk); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("save skill: %w", err)
+	}
+	if err := s.registry.Register(sk); err != nil {
+		return nil, fmt.Errorf("register skill: %w", err)
+	}
+	s.publishEvents(sk)
+	return sk, nil
+}
+
+// downloadSkillFiles fetches and verifies every file in the manifest into a
+// staging directory, then moves it to skillsDir/<name>. Nothing lands in
+// place unless every checksum matches.
+func (s *SkillService) downloadSkillFiles(manifestURL *url.URL, manifest SkillManifest) (string, error) {
+	dest := filepath.Join(s.remote.dir, manifest.Name)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+	if err := os.MkdirAll(s.remote.dir, 0755); err != nil {
+		return "", err
+	}
+	staging, err := os.MkdirTemp(s.remote.dir, "."+manifest.Name+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	for _, f := range manifest.Files {
+		rel := path.Clean(f.Path)
+		if f.Path == "" || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return "", fmt.Errorf("file path %q escapes the skill directory", f.Path)
+		}
+		if f.SHA256 == "" {
+			return "", fmt.Errorf("file %s has no sha256", f.Path)
+		}
+		fileURL, err := manifestURL.Parse(f.URL)
+		if err != nil || f.URL == "" {
+			return "", fmt.Errorf("file %s: invalid url %q", f.Path, f.URL)
+		}
+		data, err := s.fetchSkillFile(fileURL)
+		if err != nil {
+			return "", fmt.Errorf("fetch %s: %w", f.Path, err)
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, f.SHA256) {
+			return "", fmt.Errorf("file %s: checksum mismatch (got %s, want %s)", f.Path, got, f.SHA256)
+		}
+
+		target := filepath.Join(staging, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.Rename(staging, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+func (s *SkillService) fetchSkillFile(u *url.URL) ([]byte, error) {
+	resp, err := s.remote.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, u.Redacted())
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSkillDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSkillDownload {
+		return nil, fmt.Errorf("%s is larger than %d bytes", u.Redacted(), maxSkillDownload)
+	}
+	return data, nil
+}
+
+// parseRegistryURL accepts https registries, and plain http only on
+// loopback for local testing.
+func parseRegistryURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid registry url %q", raw)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		ip := net.ParseIP(u.Hostname())
+		if u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("registry url %q must use https", raw)
+		}
+	default:
+		return nil, fmt.Errorf("invalid registry url %q", raw)
+	}
+	return u, nil
+}
+
+// exactVersion returns constraint if it pins one version, or "" (latest)
+// for ranges.
+func exactVersion(constraint string) string {
+	c := strings.TrimPrefix(strings.TrimSpace(constraint), "=")
+	if c == "" || strings.ContainsAny(c, "<>^~*| ,") {
+		return ""
+	}
+	return c
+}
+
+// validSkillParamTypes are the SkillParam types a spec may declare.
+var validSkillParamTypes = map[string]bool{
+	"string": true, "int": true, "float": true, "bool": true, "json": true, "file": true,
+}
+
+// validateSkillSpec checks a spec received from outside before it is
+// registered.
+func validateSkillSpec(spec skilldomain.SkillSpec) error {
+	if spec.TimeoutSec < 0 {
+		return fmt.Errorf("%w: negative timeout", skilldomain.ErrInvalidSkillSpec)
+	}
+	for _, params := range [][]skilldomain.SkillParam{spec.Inputs, spec.Outputs} {
+		seen := make(map[string]bool, len(params))
+		for _, p := range params {
+			if p.Name == "" {
+				return fmt.Errorf("%w: parameter without a name", skilldomain.ErrInvalidSkillSpec)
+			}
+			if seen[p.Name] {
+				return fmt.Errorf("%w: duplicate parameter %q", skilldomain.ErrInvalidSkillSpec, p.Name)
+			}
+			seen[p.Name] = true
+			if !validSkillParamTypes[p.Type] {
+				return fmt.Errorf("%w: parameter %q has unknown type %q", skilldomain.ErrInvalidSkillSpec, p.Name, p.Type)
+			}
+		}
+	}
+	return nil
+}
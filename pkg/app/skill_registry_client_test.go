@@ -0,0 +1,82 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+)
+
+func TestInstallFromRegistry(t *testing.T) {
+	script := []byte("print('hi')\n")
+	sum := sha256.Sum256(script)
+
+	manifests := map[string]SkillManifest{
+		"/skills/fetch/latest/manifest.json": {
+			BundledSkill: BundledSkill{
+				Name: "fetch", Version: "1.2.0", Category: skilldomain.CategoryResearch,
+				Spec:         skilldomain.SkillSpec{Command: "python fetch.py", Inputs: []skilldomain.SkillParam{{Name: "url", Type: "string"}}},
+				Dependencies: []skilldomain.SkillDependency{{SkillName: "http", VersionConstraint: "0.3.0", Required: true}},
+			},
+			Files: []SkillFile{{Path: "fetch.py", URL: "../files/fetch.py", SHA256: hex.EncodeToString(sum[:])}},
+		},
+		"/skills/http/0.3.0/manifest.json": {
+			BundledSkill: BundledSkill{Name: "http", Version: "0.3.0", Category: skilldomain.CategorySystem},
+		},
+		"/skills/bad/latest/manifest.json": {
+			BundledSkill: BundledSkill{Name: "bad", Version: "1.0.0"},
+			Files:        []SkillFile{{Path: "fetch.py", URL: "/skills/fetch/files/fetch.py", SHA256: "00"}},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/skills/fetch/files/fetch.py" {
+			w.Write(script)
+			return
+		}
+		m, ok := manifests[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(m)
+	}))
+	defer srv.Close()
+
+	svc := newTestSkillService(t)
+	if _, err := svc.InstallFromRegistry(srv.URL, "fetch", ""); !errors.Is(err, ErrRemoteInstallDisabled) {
+		t.Fatalf("install before opt-in error = %v, want ErrRemoteInstallDisabled", err)
+	}
+
+	dir := t.TempDir()
+	svc.EnableRemoteInstall(dir, srv.Client())
+	sk, err := svc.InstallFromRegistry(srv.URL, "fetch", "")
+	if err != nil {
+		t.Fatalf("InstallFromRegistry() error: %v", err)
+	}
+	if !sk.Installed || sk.Path != filepath.Join(dir, "fetch") {
+		t.Errorf("skill = %+v", sk)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "fetch", "fetch.py")); err != nil || string(got) != string(script) {
+		t.Errorf("fetch.py = %q, %v", got, err)
+	}
+	if _, err := svc.GetSkillByName("http"); err != nil {
+		t.Errorf("dependency not installed: %v", err)
+	}
+
+	if _, err := svc.InstallFromRegistry(srv.URL, "bad", ""); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad")); !os.IsNotExist(err) {
+		t.Errorf("bad skill left files behind: %v", err)
+	}
+	if _, err := svc.GetSkillByName("bad"); err == nil {
+		t.Error("bad skill was registered")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/domain"
 	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // ---------------------------------------------------------------------------
@@ -18,6 +19,7 @@ type SkillService struct {
 	registry skilldomain.Registry
 	eventBus domain.EventBus
 	factory  skilldomain.Factory
+	remote   *remoteInstall // nil until EnableRemoteInstall
 }
 
 // NewSkillService creates a new skill application service.
@@ -179,16 +181,26 @@ func (s *SkillService) GetRegistryStats() map[string]interface{} {
 
 // RecordExecution tracks a skill execution result.
 func (s *SkillService) RecordExecution(name string, durationMS int64, err error) {
+	s.RecordRun(name, nil, durationMS, err)
+}
+
+// RecordRun tracks a skill execution result along with the inputs it ran
+// with. Secret-looking inputs are redacted before they reach the history.
+func (s *SkillService) RecordRun(name string, inputs map[string]interface{}, durationMS int64, err error) {
 	skill, findErr := s.repo.FindByName(name)
 	if findErr != nil {
 		return
 	}
 
+	run := skilldomain.SkillExecution{
+		DurationMS: durationMS,
+		Success:    err == nil,
+		Inputs:     utils.RedactMap(inputs, utils.DefaultSecretKeys),
+	}
 	if err != nil {
-		skill.RecordError(err.Error())
-	} else {
-		skill.RecordExecution(durationMS)
+		run.Error = err.Error()
 	}
+	skill.RecordRun(run)
 
 	s.repo.Save(skill)
 
@@ -205,6 +217,34 @@ func (s *SkillService) RecordExecution(name string, durationMS int64, err error)
 	s.eventBus.Publish(domain.NewEvent(eventType, skill.ID(), eventData))
 }
 
+// ExecutionHistory returns up to limit of a skill's most recent runs,
+// newest first. limit <= 0 returns everything kept.
+func (s *SkillService) ExecutionHistory(name string, limit int) ([]skilldomain.SkillExecution, error) {
+	skill, err := s.repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	n := len(skill.History)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	runs := make([]skilldomain.SkillExecution, 0, n)
+	for i := len(skill.History) - 1; i >= 0 && len(runs) < n; i-- {
+		runs = append(runs, skill.History[i])
+	}
+	return runs, nil
+}
+
+// DryRun validates inputs for a skill and returns the command it would
+// run, without running it.
+func (s *SkillService) DryRun(name string, inputs map[string]interface{}) (*skilldomain.DryRunResult, error) {
+	skill, err := s.repo.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return skill.DryRun(inputs), nil
+}
+
 // ValidateDependencies checks that all dependencies of a skill are available.
 func (s *SkillService) ValidateDependencies(skillName string) []string {
 	skill, err := s.repo.FindByName(skillName)
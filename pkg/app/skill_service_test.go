@@ -0,0 +1,45 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	skilldomain "github.com/sipeed/picoclaw/pkg/domain/skill"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+func TestExecutionHistory(t *testing.T) {
+	svc := newTestSkillService(t)
+	if _, err := svc.RegisterSkill("fetch", "1.0.0", "", skilldomain.CategoryResearch, domain.SkillSourceWorkspace, skilldomain.SkillSpec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < skilldomain.MaxHistory+5; i++ {
+		svc.RecordRun("fetch", map[string]interface{}{"url": "https://example.com", "api_key": "sk-123"}, int64(i), nil)
+	}
+	svc.RecordRun("fetch", nil, 7, errors.New("timeout"))
+
+	runs, err := svc.ExecutionHistory("fetch", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != skilldomain.MaxHistory {
+		t.Fatalf("len(runs) = %d, want %d", len(runs), skilldomain.MaxHistory)
+	}
+	if runs[0].Success || runs[0].Error != "timeout" {
+		t.Errorf("newest run = %+v, want the failure", runs[0])
+	}
+	if runs[1].Inputs["api_key"] != utils.RedactedValue {
+		t.Errorf("api_key not redacted: %v", runs[1].Inputs)
+	}
+
+	sk, _ := svc.GetSkillByName("fetch")
+	if sk.Metrics.ExecutionCount != int64(skilldomain.MaxHistory+5) || sk.Metrics.ErrorCount != 1 {
+		t.Errorf("metrics = %+v", sk.Metrics)
+	}
+
+	if runs, _ := svc.ExecutionHistory("fetch", 3); len(runs) != 3 {
+		t.Errorf("limited len = %d, want 3", len(runs))
+	}
+}
@@ -2,9 +2,15 @@ package bus
 
 import (
 	"context"
+	"errors"
 	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
+// ErrNoSubscriber is returned by Deliver when no subscriber has the name.
+var ErrNoSubscriber = errors.New("no such subscriber")
+
 // Subscriber is a named tap on a message stream. Multiple subscribers can
 // independently consume the same published messages (fan-out).
 type Subscriber struct {
@@ -20,20 +26,56 @@ type MessageBus struct {
 	closed   bool
 	closeOnce sync.Once
 
+	// Deliver sends outside the lock; Close aborts and waits for it
+	done       chan struct{}
+	delivering sync.WaitGroup
+
 	// Fan-out subscribers — every published message is sent to all taps
 	inboundSubs  []*Subscriber
 	outboundSubs []*Subscriber
 	systemSubs   []*Subscriber // for SystemEvent fan-out
+
+	// Messages that were dropped or failed delivery
+	deadLetters *DeadLetterSink
+
+	// Provider message IDs already published, per channel
+	dedup inboundDedup
 }
 
 func NewMessageBus() *MessageBus {
 	return &MessageBus{
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
-		handlers: make(map[string]MessageHandler),
+		handlers:    make(map[string]MessageHandler),
+		deadLetters: NewDeadLetterSink(DefaultDeadLetterCapacity),
+		done:        make(chan struct{}),
 	}
 }
 
+// --- Dead letters ---
+
+// DeadLetter records a message that was dropped or could not be delivered.
+// Components outside the bus (WebSocket hub, channel dispatch) use this to
+// report their own losses into the same sink.
+func (mb *MessageBus) DeadLetter(kind, target, reason string, payload interface{}) {
+	mb.deadLetters.Add(DeadLetter{
+		Kind:    kind,
+		Target:  target,
+		Reason:  reason,
+		Payload: payload,
+	})
+}
+
+// DeadLetters returns retained dead letters, newest first (limit <= 0 for all).
+func (mb *MessageBus) DeadLetters(limit int) []DeadLetter {
+	return mb.deadLetters.List(limit)
+}
+
+// DeadLetterTotal returns the number of dead letters recorded since startup.
+func (mb *MessageBus) DeadLetterTotal() int64 {
+	return mb.deadLetters.Total()
+}
+
 // --- Fan-out subscriptions ---
 
 // SubscribeInboundTap creates a named subscriber that receives copies of all
@@ -75,8 +117,69 @@ func (mb *MessageBus) PublishSystem(event SystemEvent) {
 		select {
 		case sub.ch <- event:
 		default: // drop if slow
+			mb.DeadLetter(DeadLetterSystem, sub.Name, "subscriber buffer full", event)
+		}
+	}
+}
+
+// HasSubscriber reports whether any tap or system subscriber is called name.
+func (mb *MessageBus) HasSubscriber(name string) bool {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	for _, subs := range [][]*Subscriber{mb.inboundSubs, mb.outboundSubs, mb.systemSubs} {
+		for _, sub := range subs {
+			if sub.Name == name {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// Deliver sends msg to the subscribers called name on msg's stream only,
+// waiting for buffer space rather than dropping. It is for replays aimed
+// at one consumer; normal traffic goes through the Publish methods. The
+// wait happens outside the bus lock, so a consumer that publishes before
+// draining its channel doesn't stall the bus.
+func (mb *MessageBus) Deliver(ctx context.Context, name string, msg interface{}) error {
+	mb.mu.RLock()
+	if mb.closed {
+		mb.mu.RUnlock()
+		return errors.New("message bus closed")
+	}
+	var all []*Subscriber
+	switch msg.(type) {
+	case InboundMessage:
+		all = mb.inboundSubs
+	case OutboundMessage:
+		all = mb.outboundSubs
+	case SystemEvent:
+		all = mb.systemSubs
+	}
+	var subs []*Subscriber
+	for _, sub := range all {
+		if sub.Name == name {
+			subs = append(subs, sub)
+		}
+	}
+	// Close waits for this before closing subscriber channels
+	mb.delivering.Add(1)
+	mb.mu.RUnlock()
+	defer mb.delivering.Done()
+
+	if len(subs) == 0 {
+		return ErrNoSubscriber
+	}
+	for _, sub := range subs {
+		select {
+		case sub.ch <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-mb.done:
+			return errors.New("message bus closed")
+		}
+	}
+	return nil
 }
 
 func (mb *MessageBus) fanOutInbound(msg InboundMessage) {
@@ -84,6 +187,7 @@ func (mb *MessageBus) fanOutInbound(msg InboundMessage) {
 		select {
 		case sub.ch <- msg:
 		default: // non-blocking — drop if subscriber is slow
+			mb.DeadLetter(DeadLetterInbound, sub.Name, "subscriber buffer full", msg)
 		}
 	}
 }
@@ -93,6 +197,7 @@ func (mb *MessageBus) fanOutOutbound(msg OutboundMessage) {
 		select {
 		case sub.ch <- msg:
 		default:
+			mb.DeadLetter(DeadLetterOutbound, sub.Name, "subscriber buffer full", msg)
 		}
 	}
 }
@@ -105,6 +210,17 @@ func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 		mb.mu.RUnlock()
 		return
 	}
+	// A redelivery of a message already published (e.g. after the
+	// channel reconnected) goes nowhere.
+	if mb.dedup.duplicate(msg) {
+		mb.mu.RUnlock()
+		logger.DebugCF("bus", "Dropped duplicate inbound message", map[string]interface{}{
+			"channel":    msg.Channel,
+			"chat_id":    msg.ChatID,
+			"message_id": msg.Metadata[MetadataMessageID],
+		})
+		return
+	}
 	// Fan out to all taps
 	mb.fanOutInbound(msg)
 	mb.mu.RUnlock()
@@ -114,12 +230,14 @@ func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 	default:
 		// Channel full — drop oldest and retry
 		select {
-		case <-mb.inbound:
+		case old := <-mb.inbound:
+			mb.DeadLetter(DeadLetterInbound, "consumer", "queue full, evicted oldest", old)
 		default:
 		}
 		select {
 		case mb.inbound <- msg:
 		default:
+			mb.DeadLetter(DeadLetterInbound, "consumer", "queue full", msg)
 		}
 	}
 }
@@ -148,12 +266,14 @@ func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 	default:
 		// Channel full — drop oldest and retry
 		select {
-		case <-mb.outbound:
+		case old := <-mb.outbound:
+			mb.DeadLetter(DeadLetterOutbound, "dispatcher", "queue full, evicted oldest", old)
 		default:
 		}
 		select {
 		case mb.outbound <- msg:
 		default:
+			mb.DeadLetter(DeadLetterOutbound, "dispatcher", "queue full", msg)
 		}
 	}
 }
@@ -184,6 +304,11 @@ func (mb *MessageBus) Close() {
 	mb.closeOnce.Do(func() {
 		mb.mu.Lock()
 		mb.closed = true
+		mb.mu.Unlock()
+		close(mb.done)
+		mb.delivering.Wait()
+
+		mb.mu.Lock()
 		// Close subscriber channels
 		for _, sub := range mb.inboundSubs {
 			close(sub.ch)
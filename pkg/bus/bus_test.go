@@ -0,0 +1,67 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeliverDoesNotStallBus(t *testing.T) {
+	mb := NewMessageBus()
+	ch := mb.SubscribeSystem("replay")
+	for i := 0; i < cap(ch); i++ {
+		mb.PublishSystem(SystemEvent{Type: "fill"})
+	}
+
+	delivered := make(chan error, 1)
+	go func() { delivered <- mb.Deliver(context.Background(), "replay", SystemEvent{Type: "replayed"}) }()
+	time.Sleep(20 * time.Millisecond) // let Deliver block on the full buffer
+
+	// A writer waiting for the lock must not hold up publishers behind it
+	done := make(chan struct{})
+	go func() {
+		mb.SubscribeSystem("late")
+		mb.PublishSystem(SystemEvent{Type: "more"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe/Publish blocked behind a pending Deliver")
+	}
+
+	<-ch
+	select {
+	case err := <-delivered:
+		if err != nil {
+			t.Fatalf("Deliver() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Deliver did not finish once the buffer drained")
+	}
+	if err := mb.Deliver(context.Background(), "nobody", SystemEvent{}); err != ErrNoSubscriber {
+		t.Errorf("Deliver to unknown subscriber = %v", err)
+	}
+}
+
+func TestCloseAbortsBlockedDeliver(t *testing.T) {
+	mb := NewMessageBus()
+	ch := mb.SubscribeSystem("replay")
+	for i := 0; i < cap(ch); i++ {
+		mb.PublishSystem(SystemEvent{Type: "fill"})
+	}
+
+	delivered := make(chan error, 1)
+	go func() { delivered <- mb.Deliver(context.Background(), "replay", SystemEvent{Type: "replayed"}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	mb.Close()
+	select {
+	case err := <-delivered:
+		if err == nil {
+			t.Error("Deliver succeeded on a closed bus")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not abort the blocked Deliver")
+	}
+}
@@ -0,0 +1,90 @@
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDeadLetterCapacity bounds how many dead letters are retained.
+const DefaultDeadLetterCapacity = 500
+
+// Dead-letter kinds identify which stream a lost message came from.
+const (
+	DeadLetterInbound  = "inbound"
+	DeadLetterOutbound = "outbound"
+	DeadLetterSystem   = "system"
+	DeadLetterWS       = "ws"
+)
+
+// DeadLetter records a message that was dropped or failed delivery.
+type DeadLetter struct {
+	Time    time.Time   `json:"time"`
+	Kind    string      `json:"kind"`             // inbound, outbound, system, ws
+	Target  string      `json:"target,omitempty"` // subscriber or channel that missed it
+	Reason  string      `json:"reason"`
+	Payload interface{} `json:"payload"`
+}
+
+// DeadLetterSink is a bounded ring buffer of dead letters. Once full, the
+// oldest entries are overwritten; Total keeps counting so callers can tell
+// how many were lost overall.
+type DeadLetterSink struct {
+	entries []DeadLetter
+	next    int
+	full    bool
+	total   int64
+	mu      sync.Mutex
+}
+
+// NewDeadLetterSink creates a sink retaining at most capacity entries.
+func NewDeadLetterSink(capacity int) *DeadLetterSink {
+	if capacity <= 0 {
+		capacity = DefaultDeadLetterCapacity
+	}
+	return &DeadLetterSink{entries: make([]DeadLetter, capacity)}
+}
+
+// Add records a dead letter.
+func (s *DeadLetterSink) Add(dl DeadLetter) {
+	if dl.Time.IsZero() {
+		dl.Time = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[s.next] = dl
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+	s.total++
+}
+
+// List returns retained dead letters, newest first. If limit > 0 at most
+// limit entries are returned.
+func (s *DeadLetterSink) List(limit int) []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.next
+	if s.full {
+		n = len(s.entries)
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	out := make([]DeadLetter, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (s.next - 1 - i + len(s.entries)) % len(s.entries)
+		out = append(out, s.entries[idx])
+	}
+	return out
+}
+
+// Total returns the number of dead letters recorded since startup,
+// including ones that have since been evicted.
+func (s *DeadLetterSink) Total() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
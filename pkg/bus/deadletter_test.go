@@ -0,0 +1,44 @@
+package bus
+
+import "testing"
+
+func TestDeadLetterSinkIsBounded(t *testing.T) {
+	s := NewDeadLetterSink(3)
+	for i := 0; i < 5; i++ {
+		s.Add(DeadLetter{Kind: DeadLetterSystem, Reason: "full", Payload: i})
+	}
+
+	got := s.List(0)
+	if len(got) != 3 {
+		t.Fatalf("len(List) = %d, want 3", len(got))
+	}
+	// Newest first: 4, 3, 2
+	for i, want := range []int{4, 3, 2} {
+		if got[i].Payload != want {
+			t.Errorf("List()[%d].Payload = %v, want %d", i, got[i].Payload, want)
+		}
+	}
+	if s.Total() != 5 {
+		t.Errorf("Total() = %d, want 5", s.Total())
+	}
+	if n := len(s.List(2)); n != 2 {
+		t.Errorf("len(List(2)) = %d, want 2", n)
+	}
+}
+
+func TestPublishSystemDeadLettersSlowSubscriber(t *testing.T) {
+	mb := NewMessageBus()
+	mb.SubscribeSystem("slow") // never drained
+
+	for i := 0; i < 70; i++ {
+		mb.PublishSystem(SystemEvent{Type: "test", Source: "test"})
+	}
+
+	dls := mb.DeadLetters(0)
+	if len(dls) != 6 {
+		t.Fatalf("len(DeadLetters) = %d, want 6", len(dls))
+	}
+	if dls[0].Kind != DeadLetterSystem || dls[0].Target != "slow" {
+		t.Errorf("dead letter = %+v, want kind=system target=slow", dls[0])
+	}
+}
@@ -0,0 +1,73 @@
+package bus
+
+import "sync"
+
+// MetadataMessageID is the InboundMessage.Metadata key carrying the
+// provider's own ID for the message. Channels that set it get redelivered
+// messages (e.g. after a reconnect) dropped by the bus.
+const MetadataMessageID = "message_id"
+
+// DefaultDedupCapacity bounds how many message IDs are remembered per
+// channel.
+const DefaultDedupCapacity = 1000
+
+// seenSet is a bounded set of recently seen keys. Once full, the oldest
+// key is forgotten.
+type seenSet struct {
+	keys  []string
+	index map[string]struct{}
+	next  int
+	mu    sync.Mutex
+}
+
+func newSeenSet(capacity int) *seenSet {
+	if capacity <= 0 {
+		capacity = DefaultDedupCapacity
+	}
+	return &seenSet{keys: make([]string, capacity), index: make(map[string]struct{}, capacity)}
+}
+
+// add records key and reports whether it was already present.
+func (s *seenSet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[key]; ok {
+		return true
+	}
+	if old := s.keys[s.next]; old != "" {
+		delete(s.index, old)
+	}
+	s.keys[s.next] = key
+	s.index[key] = struct{}{}
+	s.next = (s.next + 1) % len(s.keys)
+	return false
+}
+
+// inboundDedup remembers the provider message IDs seen on each channel.
+type inboundDedup struct {
+	channels map[string]*seenSet
+	capacity int
+	mu       sync.Mutex
+}
+
+// duplicate records msg's provider message ID and reports whether it was
+// seen before. Messages without an ID are never duplicates.
+func (d *inboundDedup) duplicate(msg InboundMessage) bool {
+	id := msg.Metadata[MetadataMessageID]
+	if id == "" {
+		return false
+	}
+	d.mu.Lock()
+	set, ok := d.channels[msg.Channel]
+	if !ok {
+		if d.channels == nil {
+			d.channels = make(map[string]*seenSet)
+		}
+		set = newSeenSet(d.capacity)
+		d.channels[msg.Channel] = set
+	}
+	d.mu.Unlock()
+	// Provider IDs are only unique within a chat on some platforms
+	// (Telegram), so the chat is part of the key.
+	return set.add(msg.ChatID + "\x00" + id)
+}
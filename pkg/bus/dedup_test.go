@@ -0,0 +1,60 @@
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPublishInboundDropsRedeliveries(t *testing.T) {
+	mb := NewMessageBus()
+	defer mb.Close()
+
+	msg := func(channel, chatID, id string) InboundMessage {
+		return InboundMessage{Channel: channel, ChatID: chatID, Content: id, Metadata: map[string]string{MetadataMessageID: id}}
+	}
+	mb.PublishInbound(msg("telegram", "1", "100"))
+	mb.PublishInbound(msg("telegram", "1", "100")) // redelivered after reconnect
+	mb.PublishInbound(msg("telegram", "2", "100")) // same ID, other chat
+	mb.PublishInbound(msg("slack", "1", "100"))    // same ID, other channel
+	mb.PublishInbound(InboundMessage{Channel: "cli", ChatID: "1", Content: "no id"})
+	mb.PublishInbound(InboundMessage{Channel: "cli", ChatID: "1", Content: "no id"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var got []string
+	for len(got) < 5 {
+		m, ok := mb.ConsumeInbound(ctx)
+		if !ok {
+			break
+		}
+		got = append(got, m.Channel+"/"+m.ChatID)
+	}
+	want := []string{"telegram/1", "telegram/2", "slack/1", "cli/1", "cli/1"}
+	if len(got) != len(want) {
+		t.Fatalf("consumed %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+	if len(mb.inbound) != 0 {
+		t.Errorf("%d messages left in the queue, want 0", len(mb.inbound))
+	}
+}
+
+func TestSeenSetIsBounded(t *testing.T) {
+	s := newSeenSet(2)
+	for _, k := range []string{"a", "b", "c"} {
+		if s.add(k) {
+			t.Errorf("add(%q) reported a duplicate", k)
+		}
+	}
+	if !s.add("c") {
+		t.Error("add(c) again should report a duplicate")
+	}
+	if s.add("a") {
+		t.Error("a should have been forgotten once the set was full")
+	}
+}
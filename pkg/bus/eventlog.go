@@ -0,0 +1,252 @@
+package bus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultEventLogCapacity bounds how many events an EventLog keeps.
+const DefaultEventLogCapacity = 10000
+
+// eventLogPruneEvery is how many inserts pass between prunes.
+const eventLogPruneEvery = 100
+
+// Logged event kinds.
+const (
+	EventKindSystem  = "system"
+	EventKindInbound = "inbound"
+)
+
+// LoggedEvent is one bus message as recorded by an EventLog. For system
+// events Type and Source are the event's; for inbound messages Type is
+// "inbound" and Source the channel.
+type LoggedEvent struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	Type      string          `json:"type"`
+	Source    string          `json:"source"`
+	RequestID string          `json:"request_id,omitempty"`
+	At        time.Time       `json:"at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// EventQuery selects logged events. Zero fields don't filter; Type matches
+// exactly, or by prefix when it ends in "*" ("task.*").
+type EventQuery struct {
+	Kind  string
+	Type  string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// EventLog is a bounded SQLite record of the system events and inbound
+// messages that flowed through a MessageBus, for debugging the reactions
+// to them. Once it holds more than its capacity the oldest are deleted.
+type EventLog struct {
+	db       *sql.DB
+	capacity int
+	inserts  int
+	mu       sync.Mutex
+}
+
+// OpenEventLog opens (or creates) the event log database at path, keeping
+// at most capacity events (<= 0 uses DefaultEventLogCapacity).
+func OpenEventLog(path string, capacity int) (*EventLog, error) {
+	if capacity <= 0 {
+		capacity = DefaultEventLogCapacity
+	}
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open event log db: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS bus_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		type TEXT NOT NULL,
+		source TEXT DEFAULT '',
+		request_id TEXT DEFAULT '',
+		at TEXT NOT NULL,
+		payload TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_bus_events_type ON bus_events(type, id);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init event log schema: %w", err)
+	}
+	return &EventLog{db: db, capacity: capacity}, nil
+}
+
+// Close closes the database.
+func (l *EventLog) Close() error {
+	return l.db.Close()
+}
+
+// Attach subscribes the log to mb's system events and inbound messages and
+// records them until ctx is cancelled. Like any tap, it drops what it can't
+// keep up with into the dead-letter sink.
+func (l *EventLog) Attach(ctx context.Context, mb *MessageBus) {
+	systemTap := mb.SubscribeSystem("event-log")
+	inboundTap := mb.SubscribeInboundTap("event-log")
+
+	go func() {
+		for {
+			var raw interface{}
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok = <-systemTap:
+			case raw, ok = <-inboundTap:
+			}
+			if !ok {
+				return
+			}
+			if err := l.Record(raw, time.Now()); err != nil {
+				mb.DeadLetter(DeadLetterSystem, "event-log", err.Error(), raw)
+			}
+		}
+	}()
+}
+
+// Record appends a SystemEvent or InboundMessage seen at at. Other values
+// are ignored.
+func (l *EventLog) Record(msg interface{}, at time.Time) error {
+	var kind, typ, source, requestID string
+	switch m := msg.(type) {
+	case SystemEvent:
+		kind, typ, source, requestID = EventKindSystem, m.Type, m.Source, m.RequestID
+	case InboundMessage:
+		kind, typ, source = EventKindInbound, EventKindInbound, m.Channel
+	default:
+		return nil
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.db.Exec(`INSERT INTO bus_events (kind, type, source, request_id, at, payload)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		kind, typ, source, requestID, at.UTC().Format(time.RFC3339Nano), string(payload))
+	if err != nil {
+		return err
+	}
+	l.inserts++
+	if l.inserts%eventLogPruneEvery == 0 {
+		_, err = l.db.Exec(`DELETE FROM bus_events WHERE id <= (SELECT MAX(id) FROM bus_events) - ?`, l.capacity)
+	}
+	return err
+}
+
+// Query returns matching events, oldest first.
+func (l *EventLog) Query(ctx context.Context, q EventQuery) ([]LoggedEvent, error) {
+	var where []string
+	var args []interface{}
+	if q.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, q.Kind)
+	}
+	if prefix, ok := strings.CutSuffix(q.Type, "*"); ok {
+		where = append(where, "substr(type, 1, ?) = ?")
+		args = append(args, len(prefix), prefix)
+	} else if q.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, q.Type)
+	}
+	// RFC3339Nano drops trailing zeros, so the stored timestamps don't sort
+	// as strings; compare them as times.
+	if !q.Since.IsZero() {
+		where = append(where, "julianday(at) >= julianday(?)")
+		args = append(args, q.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "julianday(at) <= julianday(?)")
+		args = append(args, q.Until.UTC().Format(time.RFC3339Nano))
+	}
+	query := "SELECT id, kind, type, source, request_id, at, payload FROM bus_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []LoggedEvent{}
+	for rows.Next() {
+		var e LoggedEvent
+		var at, payload string
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Type, &e.Source, &e.RequestID, &at, &payload); err != nil {
+			return nil, err
+		}
+		e.At, _ = time.Parse(time.RFC3339Nano, at)
+		e.Payload = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Replay re-delivers the events matching q, oldest first, to the
+// subscriber named consumer only, skipping streams it doesn't listen to;
+// other subscribers and the primary consumers don't see them. Replayed
+// system events have Replayed set and their Data decoded from JSON, so
+// typed payloads arrive as maps. Returns how many events were delivered.
+func (l *EventLog) Replay(ctx context.Context, mb *MessageBus, consumer string, q EventQuery) (int, error) {
+	events, err := l.Query(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	if !mb.HasSubscriber(consumer) {
+		return 0, fmt.Errorf("%w: %s", ErrNoSubscriber, consumer)
+	}
+
+	delivered := 0
+	for _, e := range events {
+		var msg interface{}
+		switch e.Kind {
+		case EventKindSystem:
+			var ev SystemEvent
+			if err := json.Unmarshal(e.Payload, &ev); err != nil {
+				return delivered, fmt.Errorf("decode event %d: %w", e.ID, err)
+			}
+			ev.Replayed = true
+			msg = ev
+		case EventKindInbound:
+			var in InboundMessage
+			if err := json.Unmarshal(e.Payload, &in); err != nil {
+				return delivered, fmt.Errorf("decode event %d: %w", e.ID, err)
+			}
+			msg = in
+		default:
+			continue
+		}
+		err := mb.Deliver(ctx, consumer, msg)
+		if errors.Is(err, ErrNoSubscriber) {
+			continue // consumer doesn't listen to this stream
+		}
+		if err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
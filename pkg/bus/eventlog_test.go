@@ -0,0 +1,84 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventLogQueryAndReplay(t *testing.T) {
+	log, err := OpenEventLog(filepath.Join(t.TempDir(), "events.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	base := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	log.Record(SystemEvent{Type: "task.created", Source: "kanban", Data: map[string]interface{}{"id": "t1"}}, base)
+	log.Record(InboundMessage{Channel: "telegram", ChatID: "42", Content: "hi"}, base.Add(time.Minute))
+	log.Record(SystemEvent{Type: "task.moved", Source: "kanban"}, base.Add(2*time.Minute))
+	log.Record(SystemEvent{Type: "agent.reply", Source: "agent"}, base.Add(3*time.Minute))
+	log.Record("ignored", base)
+
+	ctx := context.Background()
+	tasks, err := log.Query(ctx, EventQuery{Type: "task.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].Type != "task.created" || tasks[1].Type != "task.moved" {
+		t.Fatalf("Query(task.*) = %+v", tasks)
+	}
+	inbound, _ := log.Query(ctx, EventQuery{Kind: EventKindInbound})
+	if len(inbound) != 1 || inbound[0].Source != "telegram" {
+		t.Errorf("Query(inbound) = %+v", inbound)
+	}
+	recent, _ := log.Query(ctx, EventQuery{Since: base.Add(90 * time.Second), Limit: 1})
+	if len(recent) != 1 || recent[0].Type != "task.moved" {
+		t.Errorf("Query(since, limit 1) = %+v", recent)
+	}
+
+	mb := NewMessageBus()
+	defer mb.Close()
+	if _, err := log.Replay(ctx, mb, "nobody", EventQuery{}); !errors.Is(err, ErrNoSubscriber) {
+		t.Fatalf("Replay to unknown consumer error = %v, want ErrNoSubscriber", err)
+	}
+	tap := mb.SubscribeSystem("debugger")
+	n, err := log.Replay(ctx, mb, "debugger", EventQuery{Since: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("Replay delivered %d, want 3 (system events only)", n)
+	}
+	first := (<-tap).(SystemEvent)
+	if !first.Replayed || first.Type != "task.created" {
+		t.Errorf("first replayed event = %+v", first)
+	}
+	if data, _ := first.Data.(map[string]interface{}); data["id"] != "t1" {
+		t.Errorf("replayed data = %#v", first.Data)
+	}
+}
+
+func TestEventLogIsBounded(t *testing.T) {
+	log, err := OpenEventLog(filepath.Join(t.TempDir(), "events.db"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	now := time.Now()
+	for i := 0; i < eventLogPruneEvery; i++ {
+		if err := log.Record(SystemEvent{Type: "tick"}, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := log.Query(context.Background(), EventQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 10 || events[9].ID != eventLogPruneEvery {
+		t.Errorf("after pruning: %d events, last id %d", len(events), events[len(events)-1].ID)
+	}
+}
@@ -4,6 +4,7 @@ type InboundMessage struct {
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
 	ChatID     string            `json:"chat_id"`
+	ThreadID   string            `json:"thread_id,omitempty"` // conversation thread within the chat (reply chain root)
 	Content    string            `json:"content"`
 	Media      []string          `json:"media,omitempty"`
 	SessionKey string            `json:"session_key"`
@@ -11,9 +12,10 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel  string `json:"channel"`
+	ChatID   string `json:"chat_id"`
+	ThreadID string `json:"thread_id,omitempty"` // thread the reply belongs to, from InboundMessage.ThreadID
+	Content  string `json:"content"`
 }
 
 // SystemEvent is a typed event flowing through the bus for observability.
@@ -22,6 +24,12 @@ type SystemEvent struct {
 	Type   string      `json:"type"`   // e.g. "task.created", "bot.started"
 	Source string      `json:"source"` // e.g. "kanban", "orchestrator"
 	Data   interface{} `json:"data"`
+	// RequestID is the API request that caused the event, when there was
+	// one, so a single action can be traced from HTTP through to the board.
+	RequestID string `json:"request_id,omitempty"`
+	// Replayed marks an event re-delivered from the event log rather than
+	// published live.
+	Replayed bool `json:"replayed,omitempty"`
 }
 
 type MessageHandler func(InboundMessage) error
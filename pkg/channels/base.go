@@ -3,9 +3,13 @@ package channels
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 type Channel interface {
@@ -17,23 +21,74 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// messageSender is implemented by channels whose sends return a message ID.
+// Those channels also implement channeldomain.MessageEditor and
+// MessageDeleter, which take the IDs SendWithID returns.
+type messageSender interface {
+	SendWithID(ctx context.Context, msg bus.OutboundMessage) (string, error)
+}
+
+// formatMessageRef builds the opaque message ID handed out by SendWithID.
+// It carries the chat so edits and deletes need only the ID.
+func formatMessageRef(chatID, messageID string) string {
+	return chatID + ":" + messageID
+}
+
+// parseMessageRef splits a ref produced by formatMessageRef. Platform chat
+// IDs never contain ':', so the first separator is the boundary.
+func parseMessageRef(ref string) (chatID, messageID string, err error) {
+	chatID, messageID, ok := strings.Cut(ref, ":")
+	if !ok || chatID == "" || messageID == "" {
+		return "", "", fmt.Errorf("invalid message ID %q", ref)
+	}
+	return chatID, messageID, nil
+}
+
 type BaseChannel struct {
-	config    interface{}
-	bus       *bus.MessageBus
-	running   atomic.Bool
-	name      string
-	allowList []string
+	config       interface{}
+	bus          *bus.MessageBus
+	running      atomic.Bool
+	name         string
+	allowList    []string
+	sessionScope string
+	threads      *threadIndex
+	maxLength    int
+	counters     channelStats
+	onFailure    atomic.Pointer[func(error)]                   // set by the Manager to supervise the channel
+	onConnection atomic.Pointer[func(domain.ConnectionStatus)] // set by the Manager to record connection changes
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
 	return &BaseChannel{
-		config:    config,
-		bus:       bus,
-		name:      name,
-		allowList: allowList,
+		config:       config,
+		bus:          bus,
+		name:         name,
+		allowList:    allowList,
+		sessionScope: SessionScopeChat,
+		threads:      newThreadIndex(),
+		maxLength:    defaultMaxMessageLength[name],
+	}
+}
+
+// MaxMessageLength is the longest message the platform accepts; longer
+// outbound content is split by SplitMessage. 0 means no limit.
+func (c *BaseChannel) MaxMessageLength() int {
+	return c.maxLength
+}
+
+// setMaxMessageLength overrides the platform default when n is positive.
+func (c *BaseChannel) setMaxMessageLength(n int) {
+	if n > 0 {
+		c.maxLength = n
 	}
 }
 
+// SetSessionScope selects how inbound messages are keyed to sessions; see
+// SessionScopeChat, SessionScopeThread and SessionScopeUser.
+func (c *BaseChannel) SetSessionScope(scope string) {
+	c.sessionScope, _ = NormalizeSessionScope(scope)
+}
+
 func (c *BaseChannel) Name() string {
 	return c.name
 }
@@ -57,26 +112,103 @@ func (c *BaseChannel) IsAllowed(senderID string) bool {
 }
 
 func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []string, metadata map[string]string) {
+	c.HandleThreadMessage(senderID, chatID, "", content, media, metadata)
+}
+
+// HandleThreadMessage is HandleMessage for a message that belongs to a
+// conversation thread within the chat (e.g. a reply to a bot message).
+func (c *BaseChannel) HandleThreadMessage(senderID, chatID, threadID, content string, media []string, metadata map[string]string) {
 	if !c.IsAllowed(senderID) {
 		return
 	}
 
-	// Build session key: channel:chatID
-	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
+	sessionKey := sessionKeyFor(c.sessionScope, c.name, chatID, threadID, senderID)
 
 	msg := bus.InboundMessage{
 		Channel:    c.name,
 		SenderID:   senderID,
 		ChatID:     chatID,
+		ThreadID:   threadID,
 		Content:    content,
 		Media:      media,
 		SessionKey: sessionKey,
 		Metadata:   metadata,
 	}
 
+	c.counters.recordReceived()
 	c.bus.PublishInbound(msg)
 }
 
+// rememberThread records that the bot's messageID in chatID was sent in
+// threadID, so replies to it stay in the same thread.
+func (c *BaseChannel) rememberThread(chatID, messageID, threadID string) {
+	if threadID == "" || messageID == "" {
+		return
+	}
+	c.threads.record(chatID, messageID, threadID)
+}
+
+// replyThread returns the thread for a reply to the bot's replyToID.
+func (c *BaseChannel) replyThread(chatID, replyToID string) string {
+	return c.threads.resolve(chatID, replyToID)
+}
+
 func (c *BaseChannel) setRunning(running bool) {
+	if running && !c.running.Load() {
+		c.counters.connectedSince.Store(time.Now().UnixNano())
+	} else if !running {
+		c.counters.connectedSince.Store(0)
+	}
 	c.running.Store(running)
 }
+
+// setFailureHandler registers fn to be called when the channel stops on its
+// own; see fail.
+func (c *BaseChannel) setFailureHandler(fn func(error)) {
+	c.onFailure.Store(&fn)
+}
+
+// fail marks a running channel stopped because of err (a receive loop that
+// ended, a transport that gave up) and reports it to the failure handler.
+// It is a no-op once the channel has been stopped, so a loop winding down
+// after Stop isn't mistaken for a crash.
+func (c *BaseChannel) fail(err error) {
+	if !c.running.Swap(false) {
+		return
+	}
+	c.counters.connectedSince.Store(0)
+	logger.ErrorCF("channels", "Channel stopped unexpectedly", map[string]interface{}{
+		"channel": c.name,
+		"error":   err.Error(),
+	})
+	if fn := c.onFailure.Load(); fn != nil {
+		(*fn)(err)
+	}
+}
+
+// setConnectionHandler registers fn to be told when the channel's transport
+// connects or drops; see connectionChanged.
+func (c *BaseChannel) setConnectionHandler(fn func(status domain.ConnectionStatus)) {
+	c.onConnection.Store(&fn)
+}
+
+// connectionChanged reports that a long-lived transport connection came up
+// (domain.StatusConnected) or dropped (domain.StatusDisconnected). Giving up
+// for good goes through fail instead.
+func (c *BaseChannel) connectionChanged(status domain.ConnectionStatus) {
+	if fn := c.onConnection.Load(); fn != nil {
+		(*fn)(status)
+	}
+}
+
+// recoverLoop turns a panic in a channel goroutine into fail. Defer it at
+// the top of receive loops.
+func (c *BaseChannel) recoverLoop() {
+	if r := recover(); r != nil {
+		c.fail(fmt.Errorf("panic: %v", r))
+	}
+}
+
+func (c *BaseChannel) stats() *channelStats {
+	return &c.counters
+}
@@ -0,0 +1,135 @@
+package channels
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Default per-platform message length limits, in characters. Telegram's hard
+// limit is 4096 but content grows when converted to HTML, so leave headroom.
+// Channels not listed here are not chunked unless configured.
+var defaultMaxMessageLength = map[string]int{
+	"telegram": 4000,
+	"discord":  2000,
+	"slack":    4000,
+	"whatsapp": 4096,
+	"feishu":   10000,
+	"dingtalk": 5000,
+	"qq":       2000,
+}
+
+const codeFence = "```"
+
+// SplitMessage breaks content into chunks of at most limit characters,
+// preferring paragraph, then line, then sentence, then word boundaries.
+// A code block cut across chunks is closed at the end of one chunk and
+// reopened (with its language tag) at the start of the next. limit <= 0
+// disables splitting.
+func SplitMessage(content string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(content) <= limit {
+		return []string{content}
+	}
+
+	var chunks []string
+	rest := content
+	inFence := false
+	fenceOpen := ""
+
+	for rest != "" {
+		prefix := ""
+		if inFence {
+			prefix = fenceOpen + "\n"
+		}
+		if utf8.RuneCountInString(prefix)+utf8.RuneCountInString(rest) <= limit {
+			chunks = append(chunks, prefix+rest)
+			break
+		}
+
+		// Reserve room for the reopened fence and a closing fence.
+		budget := limit - utf8.RuneCountInString(prefix) - len("\n"+codeFence)
+		if budget < limit/4 {
+			// Fence overhead would dominate; fall back to plain cuts.
+			prefix = ""
+			budget = limit
+			inFence = false
+		}
+
+		cut := findCut(rest, budget)
+		part := strings.TrimRight(rest[:cut], " \t\n")
+		open, openLine := fenceState(part, inFence, fenceOpen)
+
+		chunk := prefix + part
+		if open && budget < limit {
+			chunk += "\n" + codeFence
+		}
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		rest = strings.TrimLeft(rest[cut:], " \t")
+		rest = strings.TrimPrefix(rest, "\n")
+		if !open {
+			rest = strings.TrimLeft(rest, "\n")
+		}
+		inFence, fenceOpen = open, openLine
+	}
+	return chunks
+}
+
+// findCut returns the byte offset to split s at so the head holds at most
+// budget runes, preferring natural boundaries in the latter part of the head.
+func findCut(s string, budget int) int {
+	limit := len(s)
+	n := 0
+	for i := range s {
+		if n == budget {
+			limit = i
+			break
+		}
+		n++
+	}
+	window := s[:limit]
+	min := len(window) / 3
+
+	if i := strings.LastIndex(window, "\n\n"); i > min {
+		return i + 2
+	}
+	if i := strings.LastIndex(window, "\n"); i > min {
+		return i + 1
+	}
+	best := -1
+	for _, sep := range []string{". ", "! ", "? ", "; "} {
+		if i := strings.LastIndex(window, sep); i > best {
+			best = i
+		}
+	}
+	if best > min {
+		return best + 2
+	}
+	if i := strings.LastIndexAny(window, " \t"); i > min {
+		return i + 1
+	}
+	if limit == 0 {
+		// budget < 1; always make progress.
+		_, size := utf8.DecodeRuneInString(s)
+		return size
+	}
+	return limit
+}
+
+// fenceState scans part for ``` lines and reports whether a code block is
+// still open at its end, and the fence line that opened it.
+func fenceState(part string, inFence bool, openLine string) (bool, string) {
+	for _, line := range strings.Split(part, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, codeFence) {
+			continue
+		}
+		if inFence {
+			inFence, openLine = false, ""
+		} else {
+			inFence, openLine = true, trimmed
+		}
+	}
+	return inFence, openLine
+}
@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessageShortPassesThrough(t *testing.T) {
+	got := SplitMessage("hello world", 100)
+	if len(got) != 1 || got[0] != "hello world" {
+		t.Fatalf("SplitMessage() = %q", got)
+	}
+	if got := SplitMessage(strings.Repeat("x", 500), 0); len(got) != 1 {
+		t.Fatalf("limit 0 should not split, got %d chunks", len(got))
+	}
+}
+
+func TestSplitMessagePrefersParagraphs(t *testing.T) {
+	p1 := strings.Repeat("alpha ", 10)
+	p2 := strings.Repeat("beta ", 10)
+	content := strings.TrimSpace(p1) + "\n\n" + strings.TrimSpace(p2)
+
+	got := SplitMessage(content, 70)
+	if len(got) != 2 {
+		t.Fatalf("got %d chunks: %q", len(got), got)
+	}
+	if got[0] != strings.TrimSpace(p1) || got[1] != strings.TrimSpace(p2) {
+		t.Errorf("chunks = %q", got)
+	}
+}
+
+func TestSplitMessageNeverCutsWords(t *testing.T) {
+	content := strings.Repeat("word ", 200)
+	for _, chunk := range SplitMessage(content, 43) {
+		if utf8.RuneCountInString(chunk) > 43 {
+			t.Fatalf("chunk exceeds limit: %d", utf8.RuneCountInString(chunk))
+		}
+		for _, w := range strings.Fields(chunk) {
+			if w != "word" {
+				t.Fatalf("word was split: %q", w)
+			}
+		}
+	}
+}
+
+func TestSplitMessagePreservesCodeFences(t *testing.T) {
+	var code strings.Builder
+	for i := 0; i < 30; i++ {
+		code.WriteString("fmt.Println(\"line\")\n")
+	}
+	content := "Here is code:\n\n```go\n" + code.String() + "```\n\nDone."
+
+	chunks := SplitMessage(content, 200)
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if utf8.RuneCountInString(chunk) > 200 {
+			t.Errorf("chunk %d exceeds limit: %d", i, utf8.RuneCountInString(chunk))
+		}
+		if n := strings.Count(chunk, "```"); n%2 != 0 {
+			t.Errorf("chunk %d has unbalanced fences:\n%s", i, chunk)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "```go\n") {
+		t.Errorf("continuation chunk should reopen fence with language, got:\n%s", chunks[1])
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], "Done.") {
+		t.Errorf("tail lost: %q", chunks[len(chunks)-1])
+	}
+}
+
+func TestSplitMessageMultibyte(t *testing.T) {
+	content := strings.Repeat("你好", 100)
+	chunks := SplitMessage(content, 30)
+	if strings.Join(chunks, "") != content {
+		t.Fatal("multibyte content not preserved")
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c) || utf8.RuneCountInString(c) > 30 {
+			t.Fatalf("bad chunk %q", c)
+		}
+	}
+}
@@ -37,6 +37,7 @@ func NewDingTalkChannel(cfg config.DingTalkConfig, messageBus *bus.MessageBus) (
 	}
 
 	base := NewBaseChannel("dingtalk", cfg, messageBus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &DingTalkChannel{
 		BaseChannel:  base,
@@ -148,6 +149,7 @@ func (c *DingTalkChannel) onChatBotMessageReceived(ctx context.Context, data *ch
 	c.sessionWebhooks.Store(chatID, data.SessionWebhook)
 
 	metadata := map[string]string{
+		"message_id":        data.MsgId,
 		"sender_name":       senderNick,
 		"conversation_id":   data.ConversationId,
 		"conversation_type": data.ConversationType,
@@ -25,6 +25,7 @@ type DiscordChannel struct {
 	config      config.DiscordConfig
 	transcriber *voice.GroqTranscriber
 	ctx         context.Context
+	format      string
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -33,7 +34,13 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 		return nil, fmt.Errorf("failed to create discord session: %w", err)
 	}
 
+	format, err := normalizeFormat(cfg.Format, FormatPlain)
+	if err != nil {
+		return nil, fmt.Errorf("discord: %w", err)
+	}
+
 	base := NewBaseChannel("discord", cfg, bus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &DiscordChannel{
 		BaseChannel: base,
@@ -41,6 +48,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 		config:      cfg,
 		transcriber: nil,
 		ctx:         context.Background(),
+		format:      format,
 	}, nil
 }
 
@@ -91,33 +99,96 @@ func (c *DiscordChannel) Stop(ctx context.Context) error {
 }
 
 func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	_, err := c.SendWithID(ctx, msg)
+	return err
+}
+
+// SendWithID sends msg and returns a message ID usable with Edit and Delete.
+func (c *DiscordChannel) SendWithID(ctx context.Context, msg bus.OutboundMessage) (string, error) {
 	if !c.IsRunning() {
-		return fmt.Errorf("discord bot not running")
+		return "", fmt.Errorf("discord bot not running")
 	}
 
 	channelID := msg.ChatID
 	if channelID == "" {
-		return fmt.Errorf("channel ID is empty")
+		return "", fmt.Errorf("channel ID is empty")
 	}
 
+	// Discord renders common Markdown natively.
 	message := msg.Content
+	if c.format == FormatPlain {
+		message = stripMarkdown(message)
+	}
+
+	var sent *discordgo.Message
+	err := c.withSendTimeout(ctx, func() error {
+		var err error
+		sent, err = c.session.ChannelMessageSend(channelID, message)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send discord message: %w", err)
+	}
+	c.rememberThread(channelID, sent.ID, msg.ThreadID)
+	return formatMessageRef(channelID, sent.ID), nil
+}
+
+// Edit replaces the content of a message sent by SendWithID.
+func (c *DiscordChannel) Edit(ctx context.Context, messageID, content string) error {
+	channelID, msgID, err := parseMessageRef(messageID)
+	if err != nil {
+		return err
+	}
+	if c.format == FormatPlain {
+		content = stripMarkdown(content)
+	}
+	err = c.withSendTimeout(ctx, func() error {
+		_, err := c.session.ChannelMessageEdit(channelID, msgID, content)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to edit discord message: %w", err)
+	}
+	return nil
+}
 
+// Delete removes a message sent by SendWithID.
+func (c *DiscordChannel) Delete(ctx context.Context, messageID string) error {
+	channelID, msgID, err := parseMessageRef(messageID)
+	if err != nil {
+		return err
+	}
+	err = c.withSendTimeout(ctx, func() error {
+		return c.session.ChannelMessageDelete(channelID, msgID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete discord message: %w", err)
+	}
+	return nil
+}
+
+// Typing triggers Discord's typing indicator, which lasts ~10s.
+func (c *DiscordChannel) Typing(ctx context.Context, chatID string) error {
+	return c.withSendTimeout(ctx, func() error {
+		return c.session.ChannelTyping(chatID)
+	})
+}
+
+// withSendTimeout runs a blocking discordgo call, giving up after sendTimeout
+// or when ctx is cancelled.
+func (c *DiscordChannel) withSendTimeout(ctx context.Context, call func() error) error {
 	// 使用传入的 ctx 进行超时控制
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, message)
-		done <- err
+		done <- call()
 	}()
 
 	select {
 	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("failed to send discord message: %w", err)
-		}
-		return nil
+		return err
 	case <-sendCtx.Done():
 		return fmt.Errorf("send message timeout: %w", sendCtx.Err())
 	}
@@ -238,7 +309,13 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		"is_dm":        fmt.Sprintf("%t", m.GuildID == ""),
 	}
 
-	c.HandleMessage(senderID, m.ChannelID, content, mediaPaths, metadata)
+	threadID := ""
+	if ref := m.ReferencedMessage; ref != nil && ref.Author != nil && s.State != nil && s.State.User != nil && ref.Author.ID == s.State.User.ID {
+		threadID = c.replyThread(m.ChannelID, ref.ID)
+		metadata["reply_to_message_id"] = ref.ID
+	}
+
+	c.HandleThreadMessage(senderID, m.ChannelID, threadID, content, mediaPaths, metadata)
 }
 
 func (c *DiscordChannel) downloadAttachment(url, filename string) string {
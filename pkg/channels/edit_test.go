@@ -0,0 +1,100 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
+)
+
+// editingChannel is a countingChannel that hands out message IDs and
+// records edits and deletes.
+type editingChannel struct {
+	countingChannel
+	edits   map[string]string
+	deleted []string
+}
+
+func (c *editingChannel) SendWithID(ctx context.Context, msg bus.OutboundMessage) (string, error) {
+	return formatMessageRef(msg.ChatID, "7"), c.Send(ctx, msg)
+}
+
+func (c *editingChannel) Edit(ctx context.Context, messageID, content string) error {
+	c.edits[messageID] = content
+	return nil
+}
+
+func (c *editingChannel) Delete(ctx context.Context, messageID string) error {
+	c.deleted = append(c.deleted, messageID)
+	return nil
+}
+
+func TestMessageRef(t *testing.T) {
+	for _, tc := range []struct{ chat, msg string }{
+		{"123456", "42"},
+		{"-1001234567890", "9"},
+		{"C024BE91L", "1712345678.000100"}, // Slack ts
+	} {
+		ref := formatMessageRef(tc.chat, tc.msg)
+		chat, msg, err := parseMessageRef(ref)
+		if err != nil || chat != tc.chat || msg != tc.msg {
+			t.Errorf("parseMessageRef(%q) = %q, %q, %v", ref, chat, msg, err)
+		}
+	}
+	for _, ref := range []string{"", "42", ":42", "123:"} {
+		if _, _, err := parseMessageRef(ref); err == nil {
+			t.Errorf("parseMessageRef(%q) succeeded", ref)
+		}
+	}
+}
+
+func TestParseTelegramRef(t *testing.T) {
+	c := &TelegramChannel{}
+	chatID, msgID, err := c.parseTelegramRef("-1001234567890:42")
+	if err != nil || chatID != -1001234567890 || msgID != 42 {
+		t.Errorf("parseTelegramRef = %d, %d, %v", chatID, msgID, err)
+	}
+	for _, ref := range []string{"42", "chat:42", "123:abc", "123:"} {
+		if _, _, err := c.parseTelegramRef(ref); err == nil {
+			t.Errorf("parseTelegramRef(%q) succeeded", ref)
+		}
+	}
+}
+
+func TestManagerEditMessage(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	plain := &countingChannel{BaseChannel: NewBaseChannel("plain", nil, msgBus, nil)}
+	editing := &editingChannel{
+		countingChannel: countingChannel{BaseChannel: NewBaseChannel("editing", nil, msgBus, nil)},
+		edits:           map[string]string{},
+	}
+	m := &Manager{channels: map[string]Channel{"plain": plain, "editing": editing}, bus: msgBus}
+	ctx := context.Background()
+
+	id, err := m.SendToChannelWithID(ctx, "editing", "chat1", "working…")
+	if err != nil || id != "chat1:7" {
+		t.Fatalf("SendToChannelWithID = %q, %v", id, err)
+	}
+	if err := m.EditMessage(ctx, "editing", id, "done"); err != nil || editing.edits[id] != "done" {
+		t.Errorf("EditMessage: %v, edits %v", err, editing.edits)
+	}
+	if err := m.DeleteMessage(ctx, "editing", id); err != nil || len(editing.deleted) != 1 {
+		t.Errorf("DeleteMessage: %v, deleted %v", err, editing.deleted)
+	}
+
+	// A channel that can't edit sends without an ID and rejects edits
+	if id, err := m.SendToChannelWithID(ctx, "plain", "chat1", "hi"); err != nil || id != "" {
+		t.Errorf("SendToChannelWithID on plain = %q, %v", id, err)
+	}
+	if err := m.EditMessage(ctx, "plain", "chat1:1", "x"); !errors.Is(err, channeldomain.ErrUnsupported) {
+		t.Errorf("EditMessage on plain = %v, want ErrUnsupported", err)
+	}
+	if err := m.DeleteMessage(ctx, "plain", "chat1:1"); !errors.Is(err, channeldomain.ErrUnsupported) {
+		t.Errorf("DeleteMessage on plain = %v, want ErrUnsupported", err)
+	}
+	if err := m.EditMessage(ctx, "missing", "chat1:1", "x"); err == nil || errors.Is(err, channeldomain.ErrUnsupported) {
+		t.Errorf("EditMessage on unknown channel = %v", err)
+	}
+}
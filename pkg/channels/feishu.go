@@ -30,6 +30,7 @@ type FeishuChannel struct {
 
 func NewFeishuChannel(cfg config.FeishuConfig, bus *bus.MessageBus) (*FeishuChannel, error) {
 	base := NewBaseChannel("feishu", cfg, bus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &FeishuChannel{
 		BaseChannel: base,
@@ -62,10 +63,14 @@ func (c *FeishuChannel) Start(ctx context.Context) error {
 	logger.InfoC("feishu", "Feishu channel started (websocket mode)")
 
 	go func() {
+		defer c.recoverLoop()
 		if err := wsClient.Start(runCtx); err != nil {
 			logger.ErrorCF("feishu", "Feishu websocket stopped with error", map[string]interface{}{
 				"error": err.Error(),
 			})
+			if runCtx.Err() == nil {
+				c.fail(err)
+			}
 		}
 	}()
 
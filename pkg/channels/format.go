@@ -0,0 +1,219 @@
+package channels
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Outbound message formats. The agent writes common Markdown; each channel
+// translates it to its own dialect unless configured for plain text.
+const (
+	FormatMarkdown   = "markdown"   // platform dialect (Telegram HTML, Slack mrkdwn, Discord as-is)
+	FormatMarkdownV2 = "markdownv2" // Telegram MarkdownV2
+	FormatPlain      = "plain"      // Markdown stripped, no parse mode
+)
+
+// normalizeFormat maps a configured format to a known one, defaulting to
+// FormatMarkdown. allowed lists the non-default formats the channel supports.
+func normalizeFormat(format string, allowed ...string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+	if f == "" || f == FormatMarkdown {
+		return FormatMarkdown, nil
+	}
+	for _, a := range allowed {
+		if f == a {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported message format %q", format)
+}
+
+var (
+	mdCodeBlock  = regexp.MustCompile("```([\\w+-]*)\\n?([\\s\\S]*?)```")
+	mdInlineCode = regexp.MustCompile("`([^`\\n]+)`")
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdHeading    = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+	mdQuote      = regexp.MustCompile(`(?m)^>\s?`)
+	mdBullet     = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdBold       = regexp.MustCompile(`\*\*([^\n]+?)\*\*|__([^\n]+?)__`)
+	mdStrike     = regexp.MustCompile(`~~([^\n]+?)~~`)
+	mdItalicStar = regexp.MustCompile(`\*([^*\n]+?)\*`)
+	mdItalicUnd  = regexp.MustCompile(`(^|[^\w])_([^_\n]+?)_($|[^\w])`)
+	placeholder  = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// fragments holds already-formatted pieces of output behind placeholders so
+// later passes (escaping, other rules) leave them alone.
+type fragments struct {
+	parts []string
+}
+
+func (f *fragments) keep(s string) string {
+	f.parts = append(f.parts, s)
+	return "\x00" + strconv.Itoa(len(f.parts)-1) + "\x00"
+}
+
+func (f *fragments) restore(s string) string {
+	for strings.Contains(s, "\x00") {
+		next := placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			i, _ := strconv.Atoi(strings.Trim(m, "\x00"))
+			return f.parts[i]
+		})
+		if next == s {
+			break
+		}
+		s = next
+	}
+	return s
+}
+
+// mdStyle describes how a dialect renders each Markdown construct. escape
+// is applied to all plain text; the other functions receive text that is
+// already escaped.
+type mdStyle struct {
+	escape     func(string) string
+	codeBlock  func(lang, code string) string
+	inlineCode func(code string) string
+	link       func(text, url string) string
+	heading    func(text string) string
+	bold       func(text string) string
+	italic     func(text string) string
+	strike     func(text string) string
+	bullet     string
+}
+
+// renderMarkdown rewrites common Markdown using style.
+func renderMarkdown(text string, style mdStyle) string {
+	if text == "" {
+		return ""
+	}
+	var f fragments
+	text = mdCodeBlock.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdCodeBlock.FindStringSubmatch(m)
+		return f.keep(style.codeBlock(sub[1], sub[2]))
+	})
+	text = mdInlineCode.ReplaceAllStringFunc(text, func(m string) string {
+		return f.keep(style.inlineCode(mdInlineCode.FindStringSubmatch(m)[1]))
+	})
+	text = mdLink.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		return f.keep(style.link(style.escape(sub[1]), sub[2]))
+	})
+	text = mdHeading.ReplaceAllStringFunc(text, func(m string) string {
+		return f.keep(style.heading(style.escape(mdHeading.FindStringSubmatch(m)[1])))
+	})
+	text = mdQuote.ReplaceAllString(text, "")
+	text = mdBullet.ReplaceAllStringFunc(text, func(m string) string {
+		indent := mdBullet.FindStringSubmatch(m)[1]
+		return indent + f.keep(style.bullet)
+	})
+	text = mdBold.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdBold.FindStringSubmatch(m)
+		inner := sub[1]
+		if inner == "" {
+			inner = sub[2]
+		}
+		return f.keep(style.bold(style.escape(inner)))
+	})
+	text = mdStrike.ReplaceAllStringFunc(text, func(m string) string {
+		return f.keep(style.strike(style.escape(mdStrike.FindStringSubmatch(m)[1])))
+	})
+	text = mdItalicStar.ReplaceAllStringFunc(text, func(m string) string {
+		return f.keep(style.italic(style.escape(mdItalicStar.FindStringSubmatch(m)[1])))
+	})
+	text = mdItalicUnd.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdItalicUnd.FindStringSubmatch(m)
+		return sub[1] + f.keep(style.italic(style.escape(sub[2]))) + sub[3]
+	})
+	return f.restore(style.escape(text))
+}
+
+// telegramV2Reserved are the characters MarkdownV2 requires escaping in text.
+const telegramV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+func escapeTelegramV2(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r != 0 && strings.ContainsRune(telegramV2Reserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeTelegramV2Code escapes the characters reserved inside code entities.
+func escapeTelegramV2Code(code string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(code)
+}
+
+var telegramV2Style = mdStyle{
+	escape: escapeTelegramV2,
+	codeBlock: func(lang, code string) string {
+		return "```" + lang + "\n" + escapeTelegramV2Code(code) + "```"
+	},
+	inlineCode: func(code string) string { return "`" + escapeTelegramV2Code(code) + "`" },
+	link: func(text, url string) string {
+		return "[" + text + "](" + strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(url) + ")"
+	},
+	heading: func(text string) string { return "*" + text + "*" },
+	bold:    func(text string) string { return "*" + text + "*" },
+	italic:  func(text string) string { return "_" + text + "_" },
+	strike:  func(text string) string { return "~" + text + "~" },
+	bullet:  "• ",
+}
+
+// markdownToTelegramV2 converts common Markdown to Telegram MarkdownV2.
+func markdownToTelegramV2(text string) string {
+	return renderMarkdown(text, telegramV2Style)
+}
+
+// escapeSlack escapes the control characters Slack reserves in mrkdwn.
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+var slackStyle = mdStyle{
+	escape: escapeSlack,
+	codeBlock: func(_, code string) string {
+		return "```" + escapeSlack(strings.TrimRight(code, "\n")) + "```"
+	},
+	inlineCode: func(code string) string { return "`" + escapeSlack(code) + "`" },
+	link:       func(text, url string) string { return "<" + url + "|" + text + ">" },
+	heading:    func(text string) string { return "*" + text + "*" },
+	bold:       func(text string) string { return "*" + text + "*" },
+	italic:     func(text string) string { return "_" + text + "_" },
+	strike:     func(text string) string { return "~" + text + "~" },
+	bullet:     "• ",
+}
+
+// markdownToSlackMrkdwn converts common Markdown to Slack mrkdwn.
+func markdownToSlackMrkdwn(text string) string {
+	return renderMarkdown(text, slackStyle)
+}
+
+func identity(s string) string { return s }
+
+var plainStyle = mdStyle{
+	escape:     identity,
+	codeBlock:  func(_, code string) string { return strings.TrimRight(code, "\n") },
+	inlineCode: identity,
+	link: func(text, url string) string {
+		if text == url {
+			return url
+		}
+		return text + " (" + url + ")"
+	},
+	heading: identity,
+	bold:    identity,
+	italic:  identity,
+	strike:  identity,
+	bullet:  "• ",
+}
+
+// stripMarkdown removes Markdown syntax, leaving readable plain text.
+func stripMarkdown(text string) string {
+	return renderMarkdown(text, plainStyle)
+}
@@ -0,0 +1,64 @@
+package channels
+
+import "testing"
+
+func TestMarkdownToTelegramV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"escapes reserved", "Done! Cost: 1.5 (approx)", `Done\! Cost: 1\.5 \(approx\)`},
+		{"bold", "**very** good", "*very* good"},
+		{"italic", "an *important* note", "an _important_ note"},
+		{"escapes inside bold", "**v1.2**", `*v1\.2*`},
+		{"inline code", "run `a_b.sh`", "run `a_b.sh`"},
+		{"code block", "```go\nx := a*b\n```", "```go\nx := a*b\n```"},
+		{"link", "[v1.2 docs](https://x.io/a_b.html)", `[v1\.2 docs](https://x.io/a_b.html)`},
+		{"heading", "# Title", "*Title*"},
+		{"bullet", "- item", "• item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownToTelegramV2(tt.in); got != tt.want {
+				t.Errorf("markdownToTelegramV2(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToSlackMrkdwn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"**bold** and __also__", "*bold* and *also*"},
+		{"*italic*", "_italic_"},
+		{"~~gone~~", "~gone~"},
+		{"[site](https://example.com)", "<https://example.com|site>"},
+		{"a < b & c", "a &lt; b &amp; c"},
+		{"```py\nprint(1)\n```", "```print(1)```"},
+		{"snake_case_name stays", "snake_case_name stays"},
+	}
+	for _, tt := range tests {
+		if got := markdownToSlackMrkdwn(tt.in); got != tt.want {
+			t.Errorf("markdownToSlackMrkdwn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	in := "## Plan\n- **fix** the `bug`\n- see [issue](https://x.io/1)"
+	want := "Plan\n• fix the bug\n• see issue (https://x.io/1)"
+	if got := stripMarkdown(in); got != want {
+		t.Errorf("stripMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownToTelegramHTMLMultipleCodeBlocks(t *testing.T) {
+	got := markdownToTelegramHTML("```\none\n```\nand\n```\ntwo\n```")
+	want := "<pre><code>one\n</code></pre>\nand\n<pre><code>two\n</code></pre>"
+	if got != want {
+		t.Errorf("markdownToTelegramHTML() = %q, want %q", got, want)
+	}
+}
@@ -61,6 +61,7 @@ func (c *MaixCamChannel) Start(ctx context.Context) error {
 
 func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 	logger.DebugC("maixcam", "Starting connection acceptor")
+	defer c.recoverLoop()
 
 	for {
 		select {
@@ -74,6 +75,7 @@ func (c *MaixCamChannel) acceptConnections(ctx context.Context) {
 					logger.ErrorCF("maixcam", "Failed to accept connection", map[string]interface{}{
 						"error": err.Error(),
 					})
+					c.fail(err)
 				}
 				return
 			}
@@ -9,10 +9,15 @@ package channels
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -21,9 +26,21 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	stateRepo    channeldomain.Repository // desired enabled state; nil = all enabled
+	events       domain.EventBus          // receives the stored aggregates' events; nil = dropped
+	superviseCtx context.Context          // restarted channels run under this; set by StartAll
+	restarts     map[string][]time.Time   // recent automatic restarts per channel
+	failures     map[string]string        // channels given up on, with the last error
+	onStatus     atomic.Pointer[func()]   // told when GetStatus may have changed; see SetStatusHandler
 	mu           sync.RWMutex
 }
 
+// connectionReporter is implemented by channels that hold a long-lived
+// connection and can report its state (e.g. Slack Socket Mode).
+type connectionReporter interface {
+	ConnectionStatus() map[string]interface{}
+}
+
 type asyncTask struct {
 	cancel context.CancelFunc
 }
@@ -33,6 +50,8 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 		channels: make(map[string]Channel),
 		bus:      messageBus,
 		config:   cfg,
+		restarts: make(map[string][]time.Time),
+		failures: make(map[string]string),
 	}
 
 	if err := m.initChannels(); err != nil {
@@ -149,8 +168,22 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
+	scope, ok := NormalizeSessionScope(m.config.Channels.SessionScope)
+	if !ok {
+		logger.WarnCF("channels", "Unknown session scope, using chat scope", map[string]interface{}{
+			"session_scope": m.config.Channels.SessionScope,
+		})
+	}
+	for name, channel := range m.channels {
+		if sc, ok := channel.(interface{ SetSessionScope(string) }); ok {
+			sc.SetSessionScope(scope)
+		}
+		m.supervise(name, channel)
+	}
+
 	logger.InfoCF("channels", "Channel initialization completed", map[string]interface{}{
 		"enabled_channels": len(m.channels),
+		"session_scope":    scope,
 	})
 
 	return nil
@@ -169,10 +202,17 @@ func (m *Manager) StartAll(ctx context.Context) error {
 
 	dispatchCtx, cancel := context.WithCancel(ctx)
 	m.dispatchTask = &asyncTask{cancel: cancel}
+	m.superviseCtx = dispatchCtx
 
 	go m.dispatchOutbound(dispatchCtx)
 
 	for name, channel := range m.channels {
+		if !m.IsEnabled(name) {
+			logger.InfoCF("channels", "Channel disabled, not starting", map[string]interface{}{
+				"channel": name,
+			})
+			continue
+		}
 		logger.InfoCF("channels", "Starting channel", map[string]interface{}{
 			"channel": name,
 		})
@@ -184,6 +224,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 		}
 	}
 
+	m.statusChanged()
 	logger.InfoC("channels", "All channels started")
 	return nil
 }
@@ -211,6 +252,7 @@ func (m *Manager) StopAll(ctx context.Context) error {
 		}
 	}
 
+	m.statusChanged()
 	logger.InfoC("channels", "All channels stopped")
 	return nil
 }
@@ -237,19 +279,48 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				logger.WarnCF("channels", "Unknown channel for outbound message", map[string]interface{}{
 					"channel": msg.Channel,
 				})
+				m.bus.DeadLetter(bus.DeadLetterOutbound, msg.Channel, "unknown channel", msg)
 				continue
 			}
 
-			if err := channel.Send(ctx, msg); err != nil {
-				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
-					"channel": msg.Channel,
-					"error":   err.Error(),
-				})
-			}
+			m.sendChunked(ctx, channel, msg)
+		}
+	}
+}
+
+// sendChunked delivers msg in pieces no longer than the channel's message
+// limit, in order. On a failed piece the undelivered remainder is
+// dead-lettered rather than sent out of order.
+func (m *Manager) sendChunked(ctx context.Context, channel Channel, msg bus.OutboundMessage) {
+	chunks := SplitMessage(msg.Content, messageLimit(channel))
+	for i, chunk := range chunks {
+		part := msg
+		part.Content = chunk
+		err := channel.Send(ctx, part)
+		recordSend(channel, err)
+		if err != nil {
+			logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
+				"channel": msg.Channel,
+				"chunk":   i + 1,
+				"chunks":  len(chunks),
+				"error":   err.Error(),
+			})
+			rest := msg
+			rest.Content = strings.Join(chunks[i:], "\n")
+			m.bus.DeadLetter(bus.DeadLetterOutbound, msg.Channel, "send failed: "+err.Error(), rest)
+			return
 		}
 	}
 }
 
+// messageLimit returns the channel's max message length, or 0 if it has none.
+func messageLimit(channel Channel) int {
+	if ml, ok := channel.(interface{ MaxMessageLength() int }); ok {
+		return ml.MaxMessageLength()
+	}
+	return 0
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -257,16 +328,88 @@ func (m *Manager) GetChannel(name string) (Channel, bool) {
 	return channel, ok
 }
 
+// SetStateRepository attaches the store of channel aggregates recording
+// each channel's desired state. StartAll skips channels stored as disabled,
+// so a bot stopped through the API stays stopped across restarts. Call
+// before StartAll.
+func (m *Manager) SetStateRepository(repo channeldomain.Repository) {
+	m.stateRepo = repo
+}
+
+// SetEventBus sets the bus that events raised on the stored channel
+// aggregates are published to, e.g. a channel being marked in error.
+func (m *Manager) SetEventBus(events domain.EventBus) {
+	m.events = events
+}
+
+// SetStatusHandler registers fn to be called whenever what GetStatus reports
+// may have changed: a channel starting, stopping, failing, being restarted or
+// its connection coming and going. fn is called with the manager's lock held
+// and must not block or call back into the manager.
+func (m *Manager) SetStatusHandler(fn func()) {
+	m.onStatus.Store(&fn)
+}
+
+// statusChanged tells the status handler, if any, that GetStatus may differ.
+func (m *Manager) statusChanged() {
+	if fn := m.onStatus.Load(); fn != nil {
+		(*fn)()
+	}
+}
+
+// publishEvents hands ch's pending events to the event bus, dropping them
+// when there is none.
+func (m *Manager) publishEvents(ch *channeldomain.Channel) {
+	events := ch.PullEvents()
+	if m.events == nil {
+		return
+	}
+	for _, event := range events {
+		m.events.Publish(event)
+	}
+}
+
+// IsEnabled reports whether channelName should be running: true unless its
+// stored state says it was disabled.
+func (m *Manager) IsEnabled(channelName string) bool {
+	if m.stateRepo == nil {
+		return true
+	}
+	ch, err := m.stateRepo.FindByName(channelName)
+	return err != nil || ch.Enabled
+}
+
+// Metrics returns the message counts for channelName. ok is false when the
+// channel isn't registered or doesn't keep stats.
+func (m *Manager) Metrics(channelName string) (metrics channeldomain.ChannelMetrics, ok bool) {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	sr, ok := channel.(statsReporter)
+	if !exists || !ok {
+		return channeldomain.ChannelMetrics{}, false
+	}
+	return sr.stats().snapshot(), true
+}
+
 func (m *Manager) GetStatus() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	status := make(map[string]interface{})
 	for name, channel := range m.channels {
-		status[name] = map[string]interface{}{
-			"enabled": true,
+		entry := map[string]interface{}{
+			"enabled": m.IsEnabled(name),
 			"running": channel.IsRunning(),
 		}
+		if cr, ok := channel.(connectionReporter); ok {
+			entry["connection"] = cr.ConnectionStatus()
+		}
+		if reason, failed := m.failures[name]; failed && !channel.IsRunning() {
+			entry["error"] = reason
+		}
+		status[name] = entry
 	}
 	return status
 }
@@ -285,13 +428,21 @@ func (m *Manager) GetEnabledChannels() []string {
 func (m *Manager) RegisterChannel(name string, channel Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if sc, ok := channel.(interface{ SetSessionScope(string) }); ok {
+		scope, _ := NormalizeSessionScope(m.config.Channels.SessionScope)
+		sc.SetSessionScope(scope)
+	}
+	m.supervise(name, channel)
 	m.channels[name] = channel
+	delete(m.failures, name)
+	m.statusChanged()
 }
 
 func (m *Manager) UnregisterChannel(name string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.channels, name)
+	m.statusChanged()
 }
 
 func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
@@ -303,11 +454,116 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 		return fmt.Errorf("channel %s not found", channelName)
 	}
 
+	for _, chunk := range SplitMessage(content, messageLimit(channel)) {
+		msg := bus.OutboundMessage{
+			Channel: channelName,
+			ChatID:  chatID,
+			Content: chunk,
+		}
+		err := channel.Send(ctx, msg)
+		recordSend(channel, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Typing shows a typing indicator in chatID on channelName. Channels that
+// don't implement channeldomain.TypingIndicator (Slack among them), stopped
+// channels and unknown channels (cli, system) are a no-op.
+func (m *Manager) Typing(ctx context.Context, channelName, chatID string) error {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists || !channel.IsRunning() {
+		return nil
+	}
+	if ti, ok := channel.(channeldomain.TypingIndicator); ok {
+		return ti.Typing(ctx, chatID)
+	}
+	return nil
+}
+
+// SendToChannelWithID sends content and returns a message ID that EditMessage
+// and DeleteMessage accept. Channels that cannot edit return an empty ID.
+// Content is sent as-is; callers editing in place should stay within the
+// channel's MaxMessageLength.
+func (m *Manager) SendToChannelWithID(ctx context.Context, channelName, chatID, content string) (string, error) {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("channel %s not found", channelName)
+	}
+
 	msg := bus.OutboundMessage{
 		Channel: channelName,
 		ChatID:  chatID,
 		Content: content,
 	}
 
-	return channel.Send(ctx, msg)
+	if sender, ok := channel.(messageSender); ok {
+		id, err := sender.SendWithID(ctx, msg)
+		recordSend(channel, err)
+		return id, err
+	}
+	err := channel.Send(ctx, msg)
+	recordSend(channel, err)
+	return "", err
+}
+
+// EditMessage replaces the content of a message previously sent on
+// channelName. It returns channeldomain.ErrUnsupported if the channel can't
+// edit.
+func (m *Manager) EditMessage(ctx context.Context, channelName, messageID, content string) error {
+	channel, err := m.channelFor(channelName)
+	if err != nil {
+		return err
+	}
+	editor, ok := channel.(channeldomain.MessageEditor)
+	if !ok {
+		return fmt.Errorf("edit message on %s: %w", channelName, channeldomain.ErrUnsupported)
+	}
+	return editor.Edit(ctx, messageID, content)
+}
+
+// DeleteMessage removes a message previously sent on channelName. It returns
+// channeldomain.ErrUnsupported if the channel can't delete.
+func (m *Manager) DeleteMessage(ctx context.Context, channelName, messageID string) error {
+	channel, err := m.channelFor(channelName)
+	if err != nil {
+		return err
+	}
+	deleter, ok := channel.(channeldomain.MessageDeleter)
+	if !ok {
+		return fmt.Errorf("delete message on %s: %w", channelName, channeldomain.ErrUnsupported)
+	}
+	return deleter.Delete(ctx, messageID)
+}
+
+// The platform channels implement the domain's edit, delete and typing ports.
+var (
+	_ channeldomain.MessageEditor  = (*TelegramChannel)(nil)
+	_ channeldomain.MessageDeleter = (*TelegramChannel)(nil)
+	_ channeldomain.MessageEditor  = (*DiscordChannel)(nil)
+	_ channeldomain.MessageDeleter = (*DiscordChannel)(nil)
+	_ channeldomain.MessageEditor  = (*SlackChannel)(nil)
+	_ channeldomain.MessageDeleter = (*SlackChannel)(nil)
+
+	_ channeldomain.TypingIndicator = (*TelegramChannel)(nil)
+	_ channeldomain.TypingIndicator = (*DiscordChannel)(nil)
+)
+
+func (m *Manager) channelFor(channelName string) (Channel, error) {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("channel %s not found", channelName)
+	}
+	return channel, nil
 }
@@ -0,0 +1,75 @@
+package channels
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
+)
+
+// channelStats counts a channel's traffic. BaseChannel records inbound
+// messages as it publishes them; the Manager records outbound sends, since
+// all delivery goes through it.
+type channelStats struct {
+	received       atomic.Int64
+	sent           atomic.Int64
+	errors         atomic.Int64
+	lastActivity   atomic.Int64 // unix nanoseconds, 0 = never
+	connectedSince atomic.Int64 // unix nanoseconds, 0 = not running
+	reconnects     atomic.Int64 // transport reconnect attempts
+	lastReconnect  atomic.Int64 // unix nanoseconds, 0 = never
+}
+
+// statsReporter is implemented by channels built on BaseChannel.
+type statsReporter interface {
+	stats() *channelStats
+}
+
+func (s *channelStats) recordReceived() {
+	s.received.Add(1)
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// recordSend counts one outbound delivery, successful or not.
+func (s *channelStats) recordSend(err error) {
+	if err != nil {
+		s.errors.Add(1)
+		return
+	}
+	s.sent.Add(1)
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// recordReconnect counts one transport reconnect attempt and returns the
+// total so far.
+func (s *channelStats) recordReconnect() int64 {
+	s.lastReconnect.Store(time.Now().UnixNano())
+	return s.reconnects.Add(1)
+}
+
+func (s *channelStats) snapshot() channeldomain.ChannelMetrics {
+	return channeldomain.ChannelMetrics{
+		MessagesReceived:  s.received.Load(),
+		MessagesSent:      s.sent.Load(),
+		ErrorCount:        s.errors.Load(),
+		LastActivityAt:    unixNanoTimestamp(s.lastActivity.Load()),
+		ConnectedSince:    unixNanoTimestamp(s.connectedSince.Load()),
+		ReconnectAttempts: s.reconnects.Load(),
+		LastReconnectAt:   unixNanoTimestamp(s.lastReconnect.Load()),
+	}
+}
+
+func unixNanoTimestamp(n int64) domain.Timestamp {
+	if n == 0 {
+		return domain.ZeroTime()
+	}
+	return domain.TimestampFrom(time.Unix(0, n))
+}
+
+// recordSend counts an outbound delivery on channel, if it keeps stats.
+func recordSend(channel Channel, err error) {
+	if sr, ok := channel.(statsReporter); ok {
+		sr.stats().recordSend(err)
+	}
+}
@@ -0,0 +1,61 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+type countingChannel struct {
+	*BaseChannel
+	failSend bool
+}
+
+func (c *countingChannel) Start(ctx context.Context) error { c.setRunning(true); return nil }
+func (c *countingChannel) Stop(ctx context.Context) error  { c.setRunning(false); return nil }
+func (c *countingChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if c.failSend {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func TestManagerMetrics(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch := &countingChannel{BaseChannel: NewBaseChannel("test", nil, msgBus, []string{"alice"})}
+	m := &Manager{channels: map[string]Channel{"test": ch}, bus: msgBus}
+
+	if got, _ := m.Metrics("test"); !got.LastActivityAt.IsZero() || !got.ConnectedSince.IsZero() {
+		t.Fatalf("fresh channel has activity: %+v", got)
+	}
+
+	ch.Start(context.Background())
+	ch.HandleMessage("alice", "chat", "hi", nil, nil)
+	ch.HandleMessage("mallory", "chat", "hi", nil, nil) // not allowed, not counted
+	if err := m.SendToChannel(context.Background(), "test", "chat", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	ch.failSend = true
+	m.SendToChannel(context.Background(), "test", "chat", "hello")
+
+	got, ok := m.Metrics("test")
+	if !ok {
+		t.Fatal("no metrics for registered channel")
+	}
+	if got.MessagesReceived != 1 || got.MessagesSent != 1 || got.ErrorCount != 1 {
+		t.Errorf("metrics = %+v", got)
+	}
+	if got.LastActivityAt.IsZero() || got.ConnectedSince.IsZero() {
+		t.Errorf("timestamps not set: %+v", got)
+	}
+
+	ch.Stop(context.Background())
+	if got, _ := m.Metrics("test"); !got.ConnectedSince.IsZero() {
+		t.Errorf("connected_since after stop = %v", got.ConnectedSince)
+	}
+	if _, ok := m.Metrics("missing"); ok {
+		t.Error("metrics for unknown channel")
+	}
+}
@@ -32,6 +32,7 @@ type QQChannel struct {
 
 func NewQQChannel(cfg config.QQConfig, messageBus *bus.MessageBus) (*QQChannel, error) {
 	base := NewBaseChannel("qq", cfg, messageBus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &QQChannel{
 		BaseChannel:  base,
@@ -90,7 +91,7 @@ func (c *QQChannel) Start(ctx context.Context) error {
 			logger.ErrorCF("qq", "WebSocket session error", map[string]interface{}{
 				"error": err.Error(),
 			})
-			c.setRunning(false)
+			c.fail(err)
 		}
 	}()
 
@@ -0,0 +1,86 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Session scopes for mapping inbound messages to agent sessions.
+const (
+	SessionScopeChat   = "chat"   // one conversation per chat
+	SessionScopeThread = "thread" // replies to bot messages get a thread-scoped conversation
+	SessionScopeUser   = "user"   // one conversation per sender within a chat
+)
+
+// NormalizeSessionScope maps a configured scope to a known one, defaulting
+// to chat scope. ok is false when the input was not recognised.
+func NormalizeSessionScope(scope string) (string, bool) {
+	switch s := strings.ToLower(strings.TrimSpace(scope)); s {
+	case "":
+		return SessionScopeChat, true
+	case SessionScopeChat, SessionScopeThread, SessionScopeUser:
+		return s, true
+	default:
+		return SessionScopeChat, false
+	}
+}
+
+// sessionKeyFor builds the agent session key for an inbound message. Chat
+// scope, and thread scope for messages outside a thread, keep the historic
+// "channel:chatID" form so existing sessions carry over.
+func sessionKeyFor(scope, channel, chatID, threadID, senderID string) string {
+	base := fmt.Sprintf("%s:%s", channel, chatID)
+	switch scope {
+	case SessionScopeThread:
+		if threadID != "" {
+			return base + ":thread:" + threadID
+		}
+	case SessionScopeUser:
+		if senderID != "" {
+			return base + ":user:" + senderID
+		}
+	}
+	return base
+}
+
+// maxTrackedThreadMessages bounds the bot-message → thread index per channel.
+const maxTrackedThreadMessages = 2048
+
+// threadIndex remembers which thread each bot message was sent in, so a reply
+// to any bot message in a thread resolves to the thread's root rather than
+// starting a new one. Oldest entries are evicted first.
+type threadIndex struct {
+	mu      sync.Mutex
+	threads map[string]string
+	order   []string
+}
+
+func newThreadIndex() *threadIndex {
+	return &threadIndex{threads: make(map[string]string)}
+}
+
+func (t *threadIndex) record(chatID, messageID, threadID string) {
+	key := chatID + ":" + messageID
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.threads[key]; !ok {
+		t.order = append(t.order, key)
+		if len(t.order) > maxTrackedThreadMessages {
+			delete(t.threads, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+	t.threads[key] = threadID
+}
+
+// resolve returns the thread a reply to replyToID belongs to. A reply to an
+// untracked bot message starts a thread rooted at that message.
+func (t *threadIndex) resolve(chatID, replyToID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if thread, ok := t.threads[chatID+":"+replyToID]; ok {
+		return thread
+	}
+	return replyToID
+}
@@ -0,0 +1,58 @@
+package channels
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSessionKeyFor(t *testing.T) {
+	tests := []struct {
+		scope    string
+		threadID string
+		want     string
+	}{
+		{SessionScopeChat, "", "telegram:42"},
+		{SessionScopeChat, "7", "telegram:42"},
+		{SessionScopeThread, "", "telegram:42"},
+		{SessionScopeThread, "7", "telegram:42:thread:7"},
+		{SessionScopeUser, "7", "telegram:42:user:alice"},
+	}
+
+	for _, tt := range tests {
+		if got := sessionKeyFor(tt.scope, "telegram", "42", tt.threadID, "alice"); got != tt.want {
+			t.Errorf("sessionKeyFor(%q, thread=%q) = %q, want %q", tt.scope, tt.threadID, got, tt.want)
+		}
+	}
+}
+
+func TestThreadIndexFollowsReplyChain(t *testing.T) {
+	idx := newThreadIndex()
+
+	// Reply to an untracked bot message roots a new thread there.
+	thread := idx.resolve("42", "100")
+	if thread != "100" {
+		t.Fatalf("resolve untracked = %q, want 100", thread)
+	}
+
+	// The bot answers in that thread; replying to the answer stays in it.
+	idx.record("42", "105", thread)
+	if got := idx.resolve("42", "105"); got != "100" {
+		t.Errorf("resolve tracked = %q, want 100", got)
+	}
+	if got := idx.resolve("43", "105"); got != "105" {
+		t.Errorf("resolve other chat = %q, want 105", got)
+	}
+}
+
+func TestThreadIndexEvictsOldest(t *testing.T) {
+	idx := newThreadIndex()
+	for i := 0; i <= maxTrackedThreadMessages; i++ {
+		idx.record("42", fmt.Sprint(i), "root")
+	}
+	if got := idx.resolve("42", "0"); got != "0" {
+		t.Errorf("oldest entry not evicted: resolve = %q", got)
+	}
+	if got := idx.resolve("42", fmt.Sprint(maxTrackedThreadMessages)); got != "root" {
+		t.Errorf("newest entry lost: resolve = %q", got)
+	}
+}
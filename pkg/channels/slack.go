@@ -2,10 +2,12 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -14,21 +16,45 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/domain"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
+// Socket Mode reconnect policy. Delays double from the base up to the cap;
+// after slackMaxReconnectAttempts consecutive failed connections the channel
+// gives up and reports an error status.
+const (
+	slackReconnectBaseDelay   = 1 * time.Second
+	slackReconnectMaxDelay    = 2 * time.Minute
+	slackMaxReconnectAttempts = 10
+)
+
+// SlackChannel connects to Slack over Socket Mode. It has no Typing method:
+// Slack only offers bots a typing indicator over the legacy RTM API, which
+// Socket Mode apps can't use, so Manager.Typing is a no-op for Slack.
 type SlackChannel struct {
 	*BaseChannel
-	config       config.SlackConfig
-	api          *slack.Client
-	socketClient *socketmode.Client
-	botUserID    string
-	transcriber  *voice.GroqTranscriber
-	ctx          context.Context
-	cancel       context.CancelFunc
-	pendingAcks  sync.Map
+	config      config.SlackConfig
+	api         *slack.Client
+	botUserID   string
+	transcriber *voice.GroqTranscriber
+	ctx         context.Context
+	cancel      context.CancelFunc
+	pendingAcks sync.Map
+	format      string
+
+	connMu     sync.RWMutex
+	connStatus domain.ConnectionStatus
+	connError  string
+}
+
+// socketAttempt is how one Socket Mode connection ended.
+type socketAttempt struct {
+	connected   bool // the connection was established before it dropped
+	invalidAuth bool
+	err         error
 }
 
 type slackMessageRef struct {
@@ -46,15 +72,20 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 		slack.OptionAppLevelToken(cfg.AppToken),
 	)
 
-	socketClient := socketmode.New(api)
+	format, err := normalizeFormat(cfg.Format, FormatPlain)
+	if err != nil {
+		return nil, fmt.Errorf("slack: %w", err)
+	}
 
 	base := NewBaseChannel("slack", cfg, messageBus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &SlackChannel{
-		BaseChannel:  base,
-		config:       cfg,
-		api:          api,
-		socketClient: socketClient,
+		BaseChannel: base,
+		config:      cfg,
+		api:         api,
+		connStatus:  domain.StatusDisconnected,
+		format:      format,
 	}, nil
 }
 
@@ -78,17 +109,7 @@ func (c *SlackChannel) Start(ctx context.Context) error {
 		"team":        authResp.Team,
 	})
 
-	go c.eventLoop()
-
-	go func() {
-		if err := c.socketClient.RunContext(c.ctx); err != nil {
-			if c.ctx.Err() == nil {
-				logger.ErrorCF("slack", "Socket Mode connection error", map[string]interface{}{
-					"error": err.Error(),
-				})
-			}
-		}
-	}()
+	go c.runSocket(c.connectSocket, slackReconnectDelay)
 
 	c.setRunning(true)
 	logger.InfoC("slack", "Slack channel started (Socket Mode)")
@@ -103,31 +124,38 @@ func (c *SlackChannel) Stop(ctx context.Context) error {
 	}
 
 	c.setRunning(false)
+	c.setConnStatus(domain.StatusDisconnected, "")
 	logger.InfoC("slack", "Slack channel stopped")
 	return nil
 }
 
 func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	_, err := c.SendWithID(ctx, msg)
+	return err
+}
+
+// SendWithID sends msg and returns a message ID usable with Edit and Delete.
+func (c *SlackChannel) SendWithID(ctx context.Context, msg bus.OutboundMessage) (string, error) {
 	if !c.IsRunning() {
-		return fmt.Errorf("slack channel not running")
+		return "", fmt.Errorf("slack channel not running")
 	}
 
 	channelID, threadTS := parseSlackChatID(msg.ChatID)
 	if channelID == "" {
-		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
+		return "", fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
 
 	opts := []slack.MsgOption{
-		slack.MsgOptionText(msg.Content, false),
+		slack.MsgOptionText(c.formatOutbound(msg.Content), false),
 	}
 
 	if threadTS != "" {
 		opts = append(opts, slack.MsgOptionTS(threadTS))
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+	_, ts, err := c.api.PostMessageContext(ctx, channelID, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to send slack message: %w", err)
+		return "", fmt.Errorf("failed to send slack message: %w", err)
 	}
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
@@ -143,35 +171,196 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		"thread_ts":  threadTS,
 	})
 
+	return formatMessageRef(channelID, ts), nil
+}
+
+// formatOutbound renders agent Markdown as Slack mrkdwn, or plain text when
+// configured.
+func (c *SlackChannel) formatOutbound(content string) string {
+	if c.format == FormatPlain {
+		return escapeSlack(stripMarkdown(content))
+	}
+	return markdownToSlackMrkdwn(content)
+}
+
+// Edit replaces the text of a message sent by SendWithID.
+func (c *SlackChannel) Edit(ctx context.Context, messageID, content string) error {
+	channelID, ts, err := parseMessageRef(messageID)
+	if err != nil {
+		return err
+	}
+	if _, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(c.formatOutbound(content), false)); err != nil {
+		return fmt.Errorf("failed to edit slack message: %w", err)
+	}
 	return nil
 }
 
-func (c *SlackChannel) eventLoop() {
+// Delete removes a message sent by SendWithID.
+func (c *SlackChannel) Delete(ctx context.Context, messageID string) error {
+	channelID, ts, err := parseMessageRef(messageID)
+	if err != nil {
+		return err
+	}
+	if _, _, err := c.api.DeleteMessageContext(ctx, channelID, ts); err != nil {
+		return fmt.Errorf("failed to delete slack message: %w", err)
+	}
+	return nil
+}
+
+// runSocket supervises the Socket Mode connection. Each attempt gets a fresh
+// client (and so a fresh event subscription); when a connection drops it is
+// re-established after delay(n) for the nth consecutive failure. The counter
+// resets once a connection succeeds, so only consecutive failures count
+// toward giving up. Drops and reconnects are reported as connection changes
+// and counted in the channel metrics.
+func (c *SlackChannel) runSocket(connect func(context.Context) socketAttempt, delay func(int) time.Duration) {
+	defer c.recoverLoop()
+	failures := 0
 	for {
+		c.setConnStatus(domain.StatusConnecting, "")
+		attempt := connect(c.ctx)
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		reason := "connection closed"
+		if attempt.err != nil {
+			reason = attempt.err.Error()
+		}
+		c.setConnStatus(domain.StatusDisconnected, reason)
+		if attempt.connected {
+			c.connectionChanged(domain.StatusDisconnected)
+		}
+
+		if attempt.invalidAuth {
+			c.giveUp("invalid Slack app token")
+			return
+		}
+		if attempt.connected {
+			failures = 0
+		}
+		failures++
+		if failures > slackMaxReconnectAttempts {
+			c.giveUp(fmt.Sprintf("gave up after %d reconnect attempts: %s", slackMaxReconnectAttempts, reason))
+			return
+		}
+
+		wait := delay(failures)
+		total := c.counters.recordReconnect()
+		logger.WarnCF("slack", "Socket Mode disconnected, reconnecting", map[string]interface{}{
+			"error":        reason,
+			"attempt":      failures,
+			"max_attempts": slackMaxReconnectAttempts,
+			"delay":        wait.String(),
+			"reconnects":   total,
+		})
+
 		select {
 		case <-c.ctx.Done():
 			return
-		case event, ok := <-c.socketClient.Events:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// connectSocket runs one Socket Mode connection until it drops or ctx ends.
+func (c *SlackChannel) connectSocket(ctx context.Context) socketAttempt {
+	client := socketmode.New(c.api)
+	connCtx, connCancel := context.WithCancel(ctx)
+
+	var connected, invalidAuth atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.eventLoop(connCtx, client, &connected, &invalidAuth)
+	}()
+
+	err := client.RunContext(connCtx)
+	connCancel()
+	<-done
+	return socketAttempt{connected: connected.Load(), invalidAuth: invalidAuth.Load(), err: err}
+}
+
+// giveUp stops reconnecting and leaves the channel in an error state.
+func (c *SlackChannel) giveUp(reason string) {
+	logger.ErrorCF("slack", "Socket Mode connection lost", map[string]interface{}{
+		"error":      reason,
+		"reconnects": c.counters.reconnects.Load(),
+	})
+	c.setConnStatus(domain.StatusError, reason)
+	c.fail(errors.New(reason))
+}
+
+// slackReconnectDelay returns the backoff before reconnect attempt n (1-based).
+func slackReconnectDelay(attempt int) time.Duration {
+	delay := slackReconnectBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= slackReconnectMaxDelay {
+			return slackReconnectMaxDelay
+		}
+	}
+	return delay
+}
+
+func (c *SlackChannel) setConnStatus(status domain.ConnectionStatus, errMsg string) {
+	c.connMu.Lock()
+	c.connStatus = status
+	c.connError = errMsg
+	c.connMu.Unlock()
+}
+
+// ConnectionStatus reports the Socket Mode connection state for status APIs.
+func (c *SlackChannel) ConnectionStatus() map[string]interface{} {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	status := map[string]interface{}{
+		"status":     string(c.connStatus),
+		"reconnects": c.counters.reconnects.Load(),
+	}
+	if c.connError != "" {
+		status["error"] = c.connError
+	}
+	return status
+}
+
+func (c *SlackChannel) eventLoop(ctx context.Context, client *socketmode.Client, connected, invalidAuth *atomic.Bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-client.Events:
 			if !ok {
 				return
 			}
 			switch event.Type {
+			case socketmode.EventTypeConnected:
+				connected.Store(true)
+				c.setConnStatus(domain.StatusConnected, "")
+				c.connectionChanged(domain.StatusConnected)
+				logger.InfoC("slack", "Socket Mode connected")
+			case socketmode.EventTypeConnectionError:
+				logger.WarnCF("slack", "Socket Mode connection error", map[string]interface{}{
+					"error": fmt.Sprintf("%v", event.Data),
+				})
+			case socketmode.EventTypeInvalidAuth:
+				invalidAuth.Store(true)
 			case socketmode.EventTypeEventsAPI:
-				c.handleEventsAPI(event)
+				c.handleEventsAPI(client, event)
 			case socketmode.EventTypeSlashCommand:
-				c.handleSlashCommand(event)
+				c.handleSlashCommand(client, event)
 			case socketmode.EventTypeInteractive:
 				if event.Request != nil {
-					c.socketClient.Ack(*event.Request)
+					client.Ack(*event.Request)
 				}
 			}
 		}
 	}
 }
 
-func (c *SlackChannel) handleEventsAPI(event socketmode.Event) {
+func (c *SlackChannel) handleEventsAPI(client *socketmode.Client, event socketmode.Event) {
 	if event.Request != nil {
-		c.socketClient.Ack(*event.Request)
+		client.Ack(*event.Request)
 	}
 
 	eventsAPIEvent, ok := event.Data.(slackevents.EventsAPIEvent)
@@ -275,6 +464,7 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 	}
 
 	metadata := map[string]string{
+		"message_id": messageTS,
 		"message_ts": messageTS,
 		"channel_id": channelID,
 		"thread_ts":  threadTS,
@@ -325,6 +515,7 @@ func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
 	}
 
 	metadata := map[string]string{
+		"message_id": messageTS,
 		"message_ts": messageTS,
 		"channel_id": channelID,
 		"thread_ts":  threadTS,
@@ -335,14 +526,14 @@ func (c *SlackChannel) handleAppMention(ev *slackevents.AppMentionEvent) {
 	c.HandleMessage(senderID, chatID, content, nil, metadata)
 }
 
-func (c *SlackChannel) handleSlashCommand(event socketmode.Event) {
+func (c *SlackChannel) handleSlashCommand(client *socketmode.Client, event socketmode.Event) {
 	cmd, ok := event.Data.(slack.SlashCommand)
 	if !ok {
 		return
 	}
 
 	if event.Request != nil {
-		c.socketClient.Ack(*event.Request)
+		client.Ack(*event.Request)
 	}
 
 	senderID := cmd.UserID
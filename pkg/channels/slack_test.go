@@ -1,10 +1,16 @@
 package channels
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	"github.com/sipeed/picoclaw/pkg/infrastructure/persistence"
 )
 
 func TestParseSlackChatID(t *testing.T) {
@@ -172,3 +178,118 @@ func TestSlackChannelIsAllowed(t *testing.T) {
 		}
 	})
 }
+
+func TestSlackReconnectDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{7, 64 * time.Second},
+		{8, slackReconnectMaxDelay},
+		{50, slackReconnectMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := slackReconnectDelay(tt.attempt); got != tt.want {
+			t.Errorf("slackReconnectDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestSlackSocketGivesUp(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	failed := msgBus.SubscribeSystem("test")
+	ch, err := NewSlackChannel(config.SlackConfig{BotToken: "xoxb-test", AppToken: "xapp-test"}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var recorded []domain.EventType
+	events := eventBusFunc(func(e domain.Event) {
+		mu.Lock()
+		recorded = append(recorded, e.EventType())
+		mu.Unlock()
+	})
+	repo := persistence.NewChannelRepository(t.TempDir())
+	m := &Manager{
+		channels: map[string]Channel{},
+		bus:      msgBus,
+		config:   &config.Config{},
+		restarts: map[string][]time.Time{},
+		failures: map[string]string{},
+	}
+	m.SetStateRepository(repo)
+	m.SetEventBus(events)
+	m.RegisterChannel("slack", ch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch.ctx, ch.cancel = ctx, cancel
+	ch.setRunning(true)
+
+	// The first connection comes up and drops; every reconnect fails
+	attempts := 0
+	connect := func(context.Context) socketAttempt {
+		attempts++
+		if attempts == 1 {
+			ch.connectionChanged(domain.StatusConnected)
+			return socketAttempt{connected: true, err: errors.New("socket closed")}
+		}
+		return socketAttempt{err: errors.New("dial failed")}
+	}
+	ch.runSocket(connect, func(int) time.Duration { return 0 })
+
+	if want := slackMaxReconnectAttempts + 1; attempts != want {
+		t.Errorf("connection attempts = %d, want %d", attempts, want)
+	}
+	if got := ch.stats().snapshot().ReconnectAttempts; got != slackMaxReconnectAttempts {
+		t.Errorf("ReconnectAttempts = %d, want %d", got, slackMaxReconnectAttempts)
+	}
+	if ch.IsRunning() {
+		t.Error("channel still running after giving up")
+	}
+	if st := ch.ConnectionStatus(); st["status"] != string(domain.StatusError) {
+		t.Errorf("ConnectionStatus = %v", st)
+	}
+
+	// Restarts are off, so the manager gives up too and the stored channel
+	// records the whole history
+	select {
+	case raw := <-failed:
+		if evt := raw.(bus.SystemEvent); evt.Type != "bot.failed" {
+			t.Fatalf("event = %+v, want bot.failed", evt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no bot.failed event")
+	}
+	stored, err := repo.FindByName("slack")
+	if err != nil || stored.Status != domain.StatusError || !stored.Enabled {
+		t.Fatalf("stored channel = %+v, %v", stored, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []domain.EventType{domain.EventChannelConnected, domain.EventChannelDisconnected, domain.EventChannelError}
+	if len(recorded) != len(want) {
+		t.Fatalf("events = %v, want %v", recorded, want)
+	}
+	for i := range want {
+		if recorded[i] != want[i] {
+			t.Errorf("events = %v, want %v", recorded, want)
+			break
+		}
+	}
+}
+
+// eventBusFunc is a synchronous domain.EventBus that hands every published
+// event to itself.
+type eventBusFunc func(domain.Event)
+
+func (f eventBusFunc) Publish(e domain.Event)                                  { f(e) }
+func (f eventBusFunc) Subscribe(domain.EventType, domain.EventHandler)         {}
+func (f eventBusFunc) SubscribeAll(domain.EventHandler)                        {}
+func (f eventBusFunc) SubscribeFilter(domain.EventFilter, domain.EventHandler) {}
+func (f eventBusFunc) Close()                                                  {}
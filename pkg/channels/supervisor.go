@@ -0,0 +1,210 @@
+package channels
+
+import (
+	"context"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/domain"
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Backoff between automatic restarts of one channel: 1s, 2s, 4s, ... up to
+// a minute.
+const (
+	restartBaseDelay = 1 * time.Second
+	restartMaxDelay  = 1 * time.Minute
+)
+
+// failureReporter is implemented by channels built on BaseChannel, which
+// report stopping on their own through BaseChannel.fail.
+type failureReporter interface {
+	setFailureHandler(fn func(error))
+}
+
+// connectionNotifier is implemented by channels built on BaseChannel; the
+// ones holding a long-lived connection report it coming and going.
+type connectionNotifier interface {
+	setConnectionHandler(fn func(status domain.ConnectionStatus))
+}
+
+// supervise restarts channel when it fails, within the configured
+// restart policy, and mirrors its connection changes on the stored channel.
+func (m *Manager) supervise(name string, channel Channel) {
+	if fr, ok := channel.(failureReporter); ok {
+		fr.setFailureHandler(func(err error) {
+			m.statusChanged()
+			go m.restart(name, channel, err)
+		})
+	}
+	if cn, ok := channel.(connectionNotifier); ok {
+		cn.setConnectionHandler(func(status domain.ConnectionStatus) {
+			m.recordConnection(name, status)
+			m.statusChanged()
+		})
+	}
+}
+
+// recordConnection marks the stored channel connected or disconnected.
+func (m *Manager) recordConnection(name string, status domain.ConnectionStatus) {
+	m.updateState(name, func(ch *channeldomain.Channel) {
+		switch status {
+		case domain.StatusConnected:
+			ch.MarkConnected()
+		case domain.StatusDisconnected:
+			ch.MarkDisconnected()
+		}
+	})
+}
+
+// restart brings a failed channel back after a backoff. When the policy's
+// budget for the window is spent, the channel is left in error and
+// bot.failed is published; each successful restart publishes bot.restarted.
+func (m *Manager) restart(name string, channel Channel, cause error) {
+	policy := m.config.Channels.Restart
+	attempt, ok := m.recordRestart(name, policy.MaxRestarts, time.Duration(policy.WindowSec)*time.Second)
+	if !ok {
+		m.giveUp(name, cause, policy.MaxRestarts)
+		return
+	}
+
+	ctx := m.superviseContext()
+	delay := restartDelay(attempt)
+	logger.WarnCF("channels", "Restarting failed channel", map[string]interface{}{
+		"channel": name,
+		"error":   cause.Error(),
+		"attempt": attempt,
+		"delay":   delay.String(),
+	})
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+
+	// Leave it alone if it was removed, replaced, started by hand or
+	// disabled while we waited.
+	if current, exists := m.GetChannel(name); !exists || current != channel || channel.IsRunning() || !m.IsEnabled(name) {
+		return
+	}
+
+	if err := channel.Start(ctx); err != nil {
+		m.restart(name, channel, err)
+		return
+	}
+
+	m.mu.Lock()
+	delete(m.failures, name)
+	m.mu.Unlock()
+	m.statusChanged()
+
+	logger.InfoCF("channels", "Channel restarted", map[string]interface{}{
+		"channel": name,
+		"attempt": attempt,
+	})
+	m.publish("bot.restarted", map[string]interface{}{
+		"bot_id":  name,
+		"attempt": attempt,
+		"error":   cause.Error(),
+	})
+}
+
+// recordRestart counts a restart of name against the last window and
+// returns its number within the window, or false when max is reached.
+func (m *Manager) recordRestart(name string, max int, window time.Duration) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	recent := m.restarts[name][:0]
+	for _, t := range m.restarts[name] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= max {
+		m.restarts[name] = recent
+		return 0, false
+	}
+	m.restarts[name] = append(recent, now)
+	return len(recent) + 1, true
+}
+
+// giveUp leaves name in error: in GetStatus, in the stored channel state if
+// there is one, and as a bot.failed event.
+func (m *Manager) giveUp(name string, cause error, maxRestarts int) {
+	m.mu.Lock()
+	m.failures[name] = cause.Error()
+	m.mu.Unlock()
+	m.statusChanged()
+
+	logger.ErrorCF("channels", "Channel failed, not restarting", map[string]interface{}{
+		"channel":      name,
+		"error":        cause.Error(),
+		"max_restarts": maxRestarts,
+	})
+
+	m.updateState(name, func(ch *channeldomain.Channel) { ch.MarkError(cause.Error()) })
+
+	m.publish("bot.failed", map[string]interface{}{
+		"bot_id":       name,
+		"error":        cause.Error(),
+		"max_restarts": maxRestarts,
+	})
+}
+
+// updateState applies update to the stored aggregate for name, creating it
+// on first use, then saves it and publishes its events. A channel reporting
+// state is running, so a new aggregate starts out enabled. Without a state
+// repository it does nothing.
+func (m *Manager) updateState(name string, update func(*channeldomain.Channel)) {
+	if m.stateRepo == nil {
+		return
+	}
+	ch, err := m.stateRepo.UpdateByName(name, func() (*channeldomain.Channel, error) {
+		ch, err := channeldomain.Factory{}.CreateChannel(name, domain.ChannelType(name), channeldomain.NewChannelConfig(nil), nil)
+		if err == nil {
+			ch.Enable()
+		}
+		return ch, err
+	}, update)
+	if err != nil {
+		logger.WarnCF("channels", "Failed to save channel state", map[string]interface{}{
+			"channel": name,
+			"error":   err.Error(),
+		})
+		return
+	}
+	m.publishEvents(ch)
+}
+
+func (m *Manager) publish(eventType string, data map[string]interface{}) {
+	if m.bus == nil {
+		return
+	}
+	m.bus.PublishSystem(bus.SystemEvent{Type: eventType, Source: "channels", Data: data})
+}
+
+// superviseContext is the context restarted channels run under; it ends
+// with StopAll.
+func (m *Manager) superviseContext() context.Context {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.superviseCtx == nil {
+		return context.Background()
+	}
+	return m.superviseCtx
+}
+
+// restartDelay returns the backoff before restart attempt n (1-based).
+func restartDelay(attempt int) time.Duration {
+	delay := restartBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= restartMaxDelay {
+			return restartMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,111 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestManagerRestartsFailedChannel(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	events := msgBus.SubscribeSystem("test")
+	cfg := &config.Config{}
+	cfg.Channels.Restart = config.ChannelRestartConfig{MaxRestarts: 1, WindowSec: 60}
+
+	m := &Manager{
+		channels: map[string]Channel{},
+		bus:      msgBus,
+		config:   cfg,
+		restarts: map[string][]time.Time{},
+		failures: map[string]string{},
+	}
+	ch := &countingChannel{BaseChannel: NewBaseChannel("test", nil, msgBus, nil)}
+	m.RegisterChannel("test", ch)
+	ch.Start(context.Background())
+
+	next := func() bus.SystemEvent {
+		t.Helper()
+		select {
+		case raw := <-events:
+			return raw.(bus.SystemEvent)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return bus.SystemEvent{}
+		}
+	}
+
+	ch.fail(errors.New("receive loop ended"))
+	if evt := next(); evt.Type != "bot.restarted" {
+		t.Fatalf("event = %+v, want bot.restarted", evt)
+	}
+	if !ch.IsRunning() {
+		t.Fatal("channel not running after restart")
+	}
+
+	// The one restart allowed in the window is spent
+	ch.fail(errors.New("receive loop ended again"))
+	if evt := next(); evt.Type != "bot.failed" {
+		t.Fatalf("event = %+v, want bot.failed", evt)
+	}
+	entry := m.GetStatus()["test"].(map[string]interface{})
+	if entry["error"] != "receive loop ended again" || entry["running"] != false {
+		t.Errorf("status = %v", entry)
+	}
+
+	// A deliberate stop is not a failure
+	ch.Start(context.Background())
+	ch.Stop(context.Background())
+	ch.fail(errors.New("late"))
+	select {
+	case evt := <-events:
+		t.Errorf("unexpected event after Stop: %+v", evt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: time.Minute} {
+		if got := restartDelay(attempt); got != want {
+			t.Errorf("restartDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestManagerReportsStatusChanges(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Channels.Restart = config.ChannelRestartConfig{MaxRestarts: 0, WindowSec: 60}
+	m := &Manager{
+		channels: map[string]Channel{},
+		config:   cfg,
+		restarts: map[string][]time.Time{},
+		failures: map[string]string{},
+	}
+	changes := make(chan struct{}, 16)
+	m.SetStatusHandler(func() { changes <- struct{}{} })
+
+	ch := &countingChannel{BaseChannel: NewBaseChannel("test", nil, nil, nil)}
+	m.RegisterChannel("test", ch)
+	ch.Start(context.Background())
+	<-changes // registered
+
+	expect := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no status change after %s", what)
+		}
+	}
+	ch.connectionChanged(domain.StatusConnected)
+	expect("connect")
+	ch.connectionChanged(domain.StatusDisconnected)
+	expect("disconnect")
+	ch.fail(errors.New("receive loop ended"))
+	expect("failure")
+	expect("giving up")
+}
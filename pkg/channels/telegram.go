@@ -2,9 +2,11 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +29,12 @@ type TelegramChannel struct {
 	transcriber  *voice.GroqTranscriber
 	placeholders sync.Map // chatID -> messageID
 	stopThinking sync.Map // chatID -> thinkingCancel
+
+	mode          string
+	format        string
+	webhookSecret string
+	webhookMu     sync.RWMutex
+	webhook       telego.WebhookHandler
 }
 
 type thinkingCancel struct {
@@ -40,12 +48,22 @@ func (c *thinkingCancel) Cancel() {
 }
 
 func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus) (*TelegramChannel, error) {
+	mode, err := validateTelegramMode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	format, err := normalizeFormat(cfg.Format, FormatMarkdownV2, FormatPlain)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: %w", err)
+	}
+
 	bot, err := telego.NewBot(cfg.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
 	base := NewBaseChannel("telegram", cfg, bus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &TelegramChannel{
 		BaseChannel:  base,
@@ -55,6 +73,8 @@ func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus) (*Telegr
 		transcriber:  nil,
 		placeholders: sync.Map{},
 		stopThinking: sync.Map{},
+		mode:         mode,
+		format:       format,
 	}, nil
 }
 
@@ -63,28 +83,41 @@ func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
 }
 
 func (c *TelegramChannel) Start(ctx context.Context) error {
-	logger.InfoC("telegram", "Starting Telegram bot (polling mode)...")
-
-	updates, err := c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
-		Timeout: 30,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to start long polling: %w", err)
+	var (
+		updates <-chan telego.Update
+		err     error
+	)
+	if c.mode == TelegramModeWebhook {
+		logger.InfoC("telegram", "Starting Telegram bot (webhook mode)...")
+		updates, err = c.startWebhook(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start webhook: %w", err)
+		}
+	} else {
+		logger.InfoC("telegram", "Starting Telegram bot (polling mode)...")
+		updates, err = c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
+			Timeout: 30,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start long polling: %w", err)
+		}
 	}
 
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]interface{}{
 		"username": c.bot.Username(),
+		"mode":     c.mode,
 	})
 
 	go func() {
+		defer c.recoverLoop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case update, ok := <-updates:
 				if !ok {
-					logger.InfoC("telegram", "Updates channel closed, reconnecting...")
+					c.fail(errors.New("updates channel closed"))
 					return
 				}
 				if update.Message != nil {
@@ -100,17 +133,26 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 func (c *TelegramChannel) Stop(ctx context.Context) error {
 	logger.InfoC("telegram", "Stopping Telegram bot...")
 	c.setRunning(false)
+	if c.mode == TelegramModeWebhook {
+		c.stopWebhook(ctx)
+	}
 	return nil
 }
 
 func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	_, err := c.SendWithID(ctx, msg)
+	return err
+}
+
+// SendWithID sends msg and returns a message ID usable with Edit and Delete.
+func (c *TelegramChannel) SendWithID(ctx context.Context, msg bus.OutboundMessage) (string, error) {
 	if !c.IsRunning() {
-		return fmt.Errorf("telegram bot not running")
+		return "", fmt.Errorf("telegram bot not running")
 	}
 
 	chatID, err := parseChatID(msg.ChatID)
 	if err != nil {
-		return fmt.Errorf("invalid chat ID: %w", err)
+		return "", fmt.Errorf("invalid chat ID: %w", err)
 	}
 
 	// Stop thinking animation
@@ -121,35 +163,113 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		c.stopThinking.Delete(msg.ChatID)
 	}
 
-	htmlContent := markdownToTelegramHTML(msg.Content)
+	text, parseMode := c.formatOutbound(msg.Content)
 
 	// Try to edit placeholder
 	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
 		c.placeholders.Delete(msg.ChatID)
-		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), htmlContent)
-		editMsg.ParseMode = telego.ModeHTML
+		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), text)
+		editMsg.ParseMode = parseMode
 
 		if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
-			return nil
+			c.rememberThread(msg.ChatID, strconv.Itoa(pID.(int)), msg.ThreadID)
+			return formatMessageRef(msg.ChatID, strconv.Itoa(pID.(int))), nil
 		}
 		// Fallback to new message if edit fails
 	}
 
-	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
-	tgMsg.ParseMode = telego.ModeHTML
+	tgMsg := tu.Message(tu.ID(chatID), text)
+	tgMsg.ParseMode = parseMode
 
-	if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
-		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]interface{}{
-			"error": err.Error(),
+	sent, err := c.bot.SendMessage(ctx, tgMsg)
+	if err != nil && parseMode != "" {
+		logger.ErrorCF("telegram", "Formatted send failed, falling back to plain text", map[string]interface{}{
+			"error":      err.Error(),
+			"parse_mode": parseMode,
 		})
+		tgMsg.Text = stripMarkdown(msg.Content)
 		tgMsg.ParseMode = ""
-		_, err = c.bot.SendMessage(ctx, tgMsg)
+		sent, err = c.bot.SendMessage(ctx, tgMsg)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.rememberThread(msg.ChatID, strconv.Itoa(sent.MessageID), msg.ThreadID)
+	return formatMessageRef(msg.ChatID, strconv.Itoa(sent.MessageID)), nil
+}
+
+// formatOutbound renders agent Markdown in the configured format and returns
+// the matching Telegram parse mode.
+func (c *TelegramChannel) formatOutbound(content string) (string, string) {
+	switch c.format {
+	case FormatPlain:
+		return stripMarkdown(content), ""
+	case FormatMarkdownV2:
+		return markdownToTelegramV2(content), telego.ModeMarkdownV2
+	default:
+		return markdownToTelegramHTML(content), telego.ModeHTML
+	}
+}
+
+// Edit replaces the text of a message sent by SendWithID.
+func (c *TelegramChannel) Edit(ctx context.Context, messageID, content string) error {
+	chatID, msgID, err := c.parseTelegramRef(messageID)
+	if err != nil {
 		return err
 	}
 
+	text, parseMode := c.formatOutbound(content)
+	editMsg := tu.EditMessageText(tu.ID(chatID), msgID, text)
+	editMsg.ParseMode = parseMode
+	if _, err := c.bot.EditMessageText(ctx, editMsg); err != nil {
+		editMsg.Text = stripMarkdown(content)
+		editMsg.ParseMode = ""
+		if _, err := c.bot.EditMessageText(ctx, editMsg); err != nil {
+			return fmt.Errorf("failed to edit telegram message: %w", err)
+		}
+	}
 	return nil
 }
 
+// Delete removes a message sent by SendWithID.
+func (c *TelegramChannel) Delete(ctx context.Context, messageID string) error {
+	chatID, msgID, err := c.parseTelegramRef(messageID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(chatID), msgID)); err != nil {
+		return fmt.Errorf("failed to delete telegram message: %w", err)
+	}
+	return nil
+}
+
+// Typing shows the "typing…" chat action; Telegram clears it after ~5s.
+func (c *TelegramChannel) Typing(ctx context.Context, chatID string) error {
+	id, err := parseChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	return c.bot.SendChatAction(ctx, tu.ChatAction(tu.ID(id), telego.ChatActionTyping))
+}
+
+func (c *TelegramChannel) parseTelegramRef(ref string) (int64, int, error) {
+	chatStr, msgStr, err := parseMessageRef(ref)
+	if err != nil {
+		return 0, 0, err
+	}
+	chatID, err := parseChatID(chatStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid chat ID: %w", err)
+	}
+	msgID, err := strconv.Atoi(msgStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid message ID %q", ref)
+	}
+	return chatID, msgID, nil
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Update) {
 	message := update.Message
 	if message == nil {
@@ -343,7 +463,13 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
 	}
 
-	c.HandleMessage(senderID, fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
+	threadID := ""
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == c.bot.ID() {
+		threadID = c.replyThread(chatIDStr, strconv.Itoa(reply.MessageID))
+		metadata["reply_to_message_id"] = strconv.Itoa(reply.MessageID)
+	}
+
+	c.HandleThreadMessage(senderID, chatIDStr, threadID, content, mediaPaths, metadata)
 }
 
 func (c *TelegramChannel) downloadPhoto(ctx context.Context, fileID string) string {
@@ -454,8 +580,11 @@ func extractCodeBlocks(text string) codeBlockMatch {
 		codes = append(codes, match[1])
 	}
 
+	i := 0
 	text = re.ReplaceAllStringFunc(text, func(m string) string {
-		return fmt.Sprintf("\x00CB%d\x00", len(codes)-1)
+		ph := fmt.Sprintf("\x00CB%d\x00", i)
+		i++
+		return ph
 	})
 
 	return codeBlockMatch{text: text, codes: codes}
@@ -475,8 +604,11 @@ func extractInlineCodes(text string) inlineCodeMatch {
 		codes = append(codes, match[1])
 	}
 
+	i := 0
 	text = re.ReplaceAllStringFunc(text, func(m string) string {
-		return fmt.Sprintf("\x00IC%d\x00", len(codes)-1)
+		ph := fmt.Sprintf("\x00IC%d\x00", i)
+		i++
+		return ph
 	})
 
 	return inlineCodeMatch{text: text, codes: codes}
@@ -0,0 +1,182 @@
+package channels
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mymmrac/telego"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Telegram update delivery modes.
+const (
+	TelegramModePolling = "polling"
+	TelegramModeWebhook = "webhook"
+)
+
+// TelegramWebhookPath is where the API server mounts the Telegram webhook.
+// The full URL registered with Telegram is config.WebhookURL + this path.
+const TelegramWebhookPath = "/api/channels/telegram/webhook"
+
+// telegramSecretHeader carries the secret token Telegram echoes back on
+// every webhook request, proving the request came from Telegram.
+const telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxTelegramUpdateBytes bounds a single webhook request body.
+const maxTelegramUpdateBytes = 1 << 20
+
+// validateTelegramMode normalizes cfg.Mode and, for webhook mode, checks that
+// WebhookURL is an absolute HTTPS URL on a host Telegram could reach.
+func validateTelegramMode(cfg config.TelegramConfig) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	switch mode {
+	case "", TelegramModePolling:
+		return TelegramModePolling, nil
+	case TelegramModeWebhook:
+	default:
+		return "", fmt.Errorf("telegram mode must be %q or %q, got %q", TelegramModePolling, TelegramModeWebhook, cfg.Mode)
+	}
+
+	if cfg.WebhookURL == "" {
+		return "", fmt.Errorf("telegram webhook mode requires webhook_url")
+	}
+	u, err := url.Parse(cfg.WebhookURL)
+	if err != nil {
+		return "", fmt.Errorf("telegram webhook_url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("telegram webhook_url must use https, got %q", cfg.WebhookURL)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("telegram webhook_url has no host: %q", cfg.WebhookURL)
+	}
+	if !isPublicHost(host) {
+		return "", fmt.Errorf("telegram webhook_url host %q is not publicly reachable", host)
+	}
+	if port := u.Port(); port != "" && port != "443" && port != "80" && port != "88" && port != "8443" {
+		return "", fmt.Errorf("telegram webhook_url port must be 443, 80, 88 or 8443, got %s", port)
+	}
+	return TelegramModeWebhook, nil
+}
+
+// isPublicHost rejects loopback, private and link-local addresses and
+// names that only resolve locally. Other hostnames are assumed public.
+func isPublicHost(host string) bool {
+	lower := strings.ToLower(host)
+	if lower == "localhost" || strings.HasSuffix(lower, ".localhost") || strings.HasSuffix(lower, ".local") {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified())
+}
+
+// webhookEndpoint returns the full URL registered with Telegram.
+func (c *TelegramChannel) webhookEndpoint() string {
+	return strings.TrimRight(c.config.WebhookURL, "/") + TelegramWebhookPath
+}
+
+// startWebhook registers the webhook with Telegram and returns the update
+// stream fed by ServeWebhook.
+func (c *TelegramChannel) startWebhook(ctx context.Context) (<-chan telego.Update, error) {
+	c.webhookSecret = c.config.WebhookSecret
+	if c.webhookSecret == "" {
+		raw := make([]byte, 24)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("generate webhook secret: %w", err)
+		}
+		c.webhookSecret = hex.EncodeToString(raw)
+	}
+
+	updates, err := c.bot.UpdatesViaWebhook(ctx, c.registerWebhook,
+		telego.WithWebhookSet(ctx, &telego.SetWebhookParams{
+			URL:         c.webhookEndpoint(),
+			SecretToken: c.webhookSecret,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoCF("telegram", "Webhook registered", map[string]interface{}{
+		"url": c.webhookEndpoint(),
+	})
+	return updates, nil
+}
+
+func (c *TelegramChannel) registerWebhook(handler telego.WebhookHandler) error {
+	c.webhookMu.Lock()
+	c.webhook = handler
+	c.webhookMu.Unlock()
+	return nil
+}
+
+// stopWebhook detaches the handler and removes the webhook from Telegram so
+// a later polling start is not rejected.
+func (c *TelegramChannel) stopWebhook(ctx context.Context) {
+	c.webhookMu.Lock()
+	c.webhook = nil
+	c.webhookMu.Unlock()
+
+	if err := c.bot.DeleteWebhook(ctx, &telego.DeleteWebhookParams{}); err != nil {
+		logger.WarnCF("telegram", "Failed to delete webhook", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// WebhookMode reports whether the channel receives updates via webhook.
+func (c *TelegramChannel) WebhookMode() bool {
+	return c.mode == TelegramModeWebhook
+}
+
+// ServeWebhook handles an update POSTed by Telegram. The API server routes
+// TelegramWebhookPath here; requests without the registered secret token
+// are rejected.
+func (c *TelegramChannel) ServeWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.webhookMu.RLock()
+	handler := c.webhook
+	c.webhookMu.RUnlock()
+	if handler == nil {
+		http.Error(w, "telegram webhook not active", http.StatusServiceUnavailable)
+		return
+	}
+
+	token := r.Header.Get(telegramSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.webhookSecret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTelegramUpdateBytes))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+
+	// Updates are processed after the request returns, so detach from the
+	// request context.
+	if err := handler(context.WithoutCancel(r.Context()), body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,38 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestValidateTelegramMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.TelegramConfig
+		wantMode string
+		wantErr  bool
+	}{
+		{"default", config.TelegramConfig{}, TelegramModePolling, false},
+		{"polling", config.TelegramConfig{Mode: "Polling"}, TelegramModePolling, false},
+		{"unknown", config.TelegramConfig{Mode: "push"}, "", true},
+		{"webhook without url", config.TelegramConfig{Mode: "webhook"}, "", true},
+		{"webhook http", config.TelegramConfig{Mode: "webhook", WebhookURL: "http://bot.example.com"}, "", true},
+		{"webhook localhost", config.TelegramConfig{Mode: "webhook", WebhookURL: "https://localhost"}, "", true},
+		{"webhook private ip", config.TelegramConfig{Mode: "webhook", WebhookURL: "https://192.168.1.10"}, "", true},
+		{"webhook bad port", config.TelegramConfig{Mode: "webhook", WebhookURL: "https://bot.example.com:18790"}, "", true},
+		{"webhook ok", config.TelegramConfig{Mode: "webhook", WebhookURL: "https://bot.example.com:8443/"}, TelegramModeWebhook, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := validateTelegramMode(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateTelegramMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if mode != tt.wantMode {
+				t.Errorf("validateTelegramMode() = %q, want %q", mode, tt.wantMode)
+			}
+		})
+	}
+}
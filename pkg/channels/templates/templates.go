@@ -35,7 +35,7 @@ type BotTemplate struct {
 
 	// Runtime
 	Channel string   `yaml:"channel"` // telegram | discord | slack | webhook
-	Soul    string   `yaml:"soul"`    // system prompt / personality definition
+	Soul    string   `yaml:"soul"`    // system prompt / personality definition; {{param}} placeholders are expanded
 	Tools   []string `yaml:"tools"`   // tool names from the registry
 	Cron    string   `yaml:"cron,omitempty"` // cron schedule (optional)
 
@@ -184,19 +184,51 @@ func (r *Registry) Count() int {
 // Validation
 // ─────────────────────────────────────────────────────────────────────────────
 
-// Validate checks that all required params are present in the provided map.
-// Returns a list of missing required param names.
-func (t *BotTemplate) Validate(params map[string]string) []string {
-	var missing []string
+// ValidationResult lists what keeps a template from being instantiated.
+type ValidationResult struct {
+	Missing      []string `json:"missing,omitempty"`       // required params not provided
+	UnknownTools []string `json:"unknown_tools,omitempty"` // tools not in the agent's registry
+}
+
+// OK reports whether the template can be instantiated.
+func (v ValidationResult) OK() bool {
+	return len(v.Missing) == 0 && len(v.UnknownTools) == 0
+}
+
+// Validate checks that all required params are present in the provided map
+// and that every tool the template lists is in knownTools. A nil knownTools
+// skips the tool check (no registry to check against).
+func (t *BotTemplate) Validate(params map[string]string, knownTools []string) ValidationResult {
+	var v ValidationResult
 	for _, p := range t.Params {
 		if p.Required {
-			v, ok := params[p.Name]
-			if !ok || strings.TrimSpace(v) == "" {
-				missing = append(missing, p.Name)
+			val, ok := params[p.Name]
+			if !ok || strings.TrimSpace(val) == "" {
+				v.Missing = append(v.Missing, p.Name)
 			}
 		}
 	}
-	return missing
+	v.UnknownTools = t.UnknownTools(knownTools)
+	return v
+}
+
+// UnknownTools returns the template's tools that are not in knownTools, or
+// nil when knownTools is nil.
+func (t *BotTemplate) UnknownTools(knownTools []string) []string {
+	if knownTools == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(knownTools))
+	for _, name := range knownTools {
+		known[name] = true
+	}
+	var unknown []string
+	for _, name := range t.Tools {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
 }
 
 // ResolvedParams returns params merged with defaults (params take precedence).
@@ -213,6 +245,29 @@ func (t *BotTemplate) ResolvedParams(provided map[string]string) map[string]stri
 	return out
 }
 
+// ExpandSoul returns the soul with each {{name}} placeholder replaced by the
+// matching value in params. Placeholders without a value are left as-is.
+func (t *BotTemplate) ExpandSoul(params map[string]string) string {
+	if !strings.Contains(t.Soul, "{{") {
+		return t.Soul
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for k, v := range params {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(t.Soul)
+}
+
+// IsSecret reports whether the named param is marked secret.
+func (t *BotTemplate) IsSecret(name string) bool {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return p.Secret
+		}
+	}
+	return false
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Auto-load from standard directories
 // ─────────────────────────────────────────────────────────────────────────────
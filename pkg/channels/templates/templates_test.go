@@ -0,0 +1,79 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tmpl := &BotTemplate{
+		Name:  "t",
+		Tools: []string{"web_search", "filesystem", "message"},
+		Params: []TemplateParam{
+			{Name: "token", Required: true},
+			{Name: "chat", Required: true},
+			{Name: "project"},
+		},
+	}
+	known := []string{"web_search", "message", "read_file"}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		known  []string
+		want   ValidationResult
+	}{
+		{
+			name:   "all present",
+			params: map[string]string{"token": "x", "chat": "1"},
+			known:  append(known, "filesystem"),
+			want:   ValidationResult{},
+		},
+		{
+			name:   "missing and blank params",
+			params: map[string]string{"token": "  "},
+			known:  append(known, "filesystem"),
+			want:   ValidationResult{Missing: []string{"token", "chat"}},
+		},
+		{
+			name:   "unknown tool",
+			params: map[string]string{"token": "x", "chat": "1"},
+			known:  known,
+			want:   ValidationResult{UnknownTools: []string{"filesystem"}},
+		},
+		{
+			name:  "both",
+			known: known,
+			want:  ValidationResult{Missing: []string{"token", "chat"}, UnknownTools: []string{"filesystem"}},
+		},
+		{
+			name:   "nil registry skips the tool check",
+			params: map[string]string{"token": "x", "chat": "1"},
+			want:   ValidationResult{},
+		},
+		{
+			name:   "empty registry knows no tools",
+			params: map[string]string{"token": "x", "chat": "1"},
+			known:  []string{},
+			want:   ValidationResult{UnknownTools: []string{"web_search", "filesystem", "message"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tmpl.Validate(tt.params, tt.known)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+			if got.OK() != (len(tt.want.Missing) == 0 && len(tt.want.UnknownTools) == 0) {
+				t.Errorf("OK() = %v for %+v", got.OK(), got)
+			}
+		})
+	}
+}
+
+func TestUnknownToolsWithoutTools(t *testing.T) {
+	tmpl := &BotTemplate{Name: "t"}
+	if got := tmpl.UnknownTools([]string{"message"}); got != nil {
+		t.Errorf("UnknownTools() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,43 @@
+package channels
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// typingChannel is a countingChannel that records typing indicators.
+type typingChannel struct {
+	countingChannel
+	typed []string
+}
+
+func (c *typingChannel) Typing(ctx context.Context, chatID string) error {
+	c.typed = append(c.typed, chatID)
+	return nil
+}
+
+func TestManagerTyping(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	typing := &typingChannel{countingChannel: countingChannel{BaseChannel: NewBaseChannel("typing", nil, msgBus, nil)}}
+	plain := &countingChannel{BaseChannel: NewBaseChannel("plain", nil, msgBus, nil)}
+	m := &Manager{channels: map[string]Channel{"typing": typing, "plain": plain}, bus: msgBus}
+	ctx := context.Background()
+
+	// A stopped channel gets no indicator
+	if err := m.Typing(ctx, "typing", "chat1"); err != nil || len(typing.typed) != 0 {
+		t.Fatalf("Typing on stopped channel: %v, typed %v", err, typing.typed)
+	}
+
+	typing.Start(ctx)
+	plain.Start(ctx)
+	if err := m.Typing(ctx, "typing", "chat1"); err != nil || len(typing.typed) != 1 || typing.typed[0] != "chat1" {
+		t.Errorf("Typing: %v, typed %v", err, typing.typed)
+	}
+	for _, name := range []string{"plain", "cli", "system"} {
+		if err := m.Typing(ctx, name, "chat1"); err != nil {
+			t.Errorf("Typing on %s = %v, want no-op", name, err)
+		}
+	}
+}
@@ -26,6 +26,7 @@ type WhatsAppChannel struct {
 
 func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsAppChannel, error) {
 	base := NewBaseChannel("whatsapp", cfg, bus, cfg.AllowFrom)
+	base.setMaxMessageLength(cfg.MaxMessageLength)
 
 	return &WhatsAppChannel{
 		BaseChannel: base,
@@ -105,6 +106,7 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 }
 
 func (c *WhatsAppChannel) listen(ctx context.Context) {
+	defer c.recoverLoop()
 	backoff := time.Second
 	maxBackoff := 30 * time.Second
 
@@ -0,0 +1,134 @@
+package codex
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// AppliedLog records which diff IDs have been applied to which workspace,
+// together with the result, so a retried request can be answered without
+// touching the files again. Only successful applies are recorded; a diff
+// that failed or was rolled back may be sent again.
+type AppliedLog struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	pending map[string]*pendingDiff // workspace + diff ID -> its lock
+}
+
+// pendingDiff serializes requests for one diff ID while they're in flight.
+type pendingDiff struct {
+	sync.Mutex
+	waiters int
+}
+
+// NewAppliedLog opens (or creates) the applied-diff database at path.
+func NewAppliedLog(path string) (*AppliedLog, error) {
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open applied diff db: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS applied_diffs (
+		workspace TEXT NOT NULL,
+		diff_id TEXT NOT NULL,
+		result TEXT NOT NULL,
+		applied_at TEXT NOT NULL,
+		PRIMARY KEY (workspace, diff_id)
+	);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init applied diff schema: %w", err)
+	}
+	return &AppliedLog{db: db, pending: make(map[string]*pendingDiff)}, nil
+}
+
+// Lock holds diffID in workspaceRoot until the returned func is called.
+// Taken around Lookup, the apply and Record, it makes a retry that races
+// the original wait and then see it as applied, rather than applying the
+// diff a second time. Other diffs are not held up.
+func (l *AppliedLog) Lock(workspaceRoot, diffID string) (unlock func()) {
+	root, err := filepath.Abs(workspaceRoot)
+	if err != nil {
+		root = workspaceRoot
+	}
+	key := root + "\x00" + diffID
+
+	l.mu.Lock()
+	p := l.pending[key]
+	if p == nil {
+		p = &pendingDiff{}
+		l.pending[key] = p
+	}
+	p.waiters++
+	l.mu.Unlock()
+
+	p.Lock()
+	return func() {
+		p.Unlock()
+		l.mu.Lock()
+		if p.waiters--; p.waiters == 0 {
+			delete(l.pending, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Lookup returns the recorded result for diffID in workspaceRoot, marked
+// AlreadyApplied, or nil if the diff has not been applied there.
+func (l *AppliedLog) Lookup(ctx context.Context, workspaceRoot, diffID string) (*ApplyVerifyResult, error) {
+	root, err := filepath.Abs(workspaceRoot)
+	if err != nil {
+		return nil, err
+	}
+	var data string
+	err = l.db.QueryRowContext(ctx,
+		"SELECT result FROM applied_diffs WHERE workspace = ? AND diff_id = ?", root, diffID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result ApplyVerifyResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, fmt.Errorf("decode applied diff %s: %w", diffID, err)
+	}
+	result.AlreadyApplied = true
+	if result.Apply != nil {
+		result.Apply.AlreadyApplied = true
+	}
+	return &result, nil
+}
+
+// Record stores a successful result under its diff ID. Results with any
+// other status, or without an ID, are ignored.
+func (l *AppliedLog) Record(ctx context.Context, workspaceRoot string, result *ApplyVerifyResult) error {
+	if result == nil || result.Status != "success" || result.DiffID == "" {
+		return nil
+	}
+	root, err := filepath.Abs(workspaceRoot)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("encode applied diff %s: %w", result.DiffID, err)
+	}
+	_, err = l.db.ExecContext(ctx, `INSERT OR IGNORE INTO applied_diffs (workspace, diff_id, result, applied_at)
+		VALUES (?, ?, ?, ?)`, root, result.DiffID, string(data), time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// Close closes the underlying database.
+func (l *AppliedLog) Close() error {
+	return l.db.Close()
+}
@@ -0,0 +1,41 @@
+package codex
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppliedLog(t *testing.T) {
+	log, err := NewAppliedLog(filepath.Join(t.TempDir(), "applied.db"))
+	if err != nil {
+		t.Fatalf("NewAppliedLog() error: %v", err)
+	}
+	defer log.Close()
+	ctx := context.Background()
+	root := t.TempDir()
+
+	failed := &ApplyVerifyResult{DiffID: "d1", Status: "apply_failed"}
+	if err := log.Record(ctx, root, failed); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	if prior, _ := log.Lookup(ctx, root, "d1"); prior != nil {
+		t.Fatal("failed apply should not be recorded")
+	}
+
+	ok := &ApplyVerifyResult{DiffID: "d1", Status: "success", Apply: &ApplyResult{DiffID: "d1", Success: true, FilesChanged: 2}}
+	if err := log.Record(ctx, root, ok); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	prior, err := log.Lookup(ctx, root, "d1")
+	if err != nil || prior == nil {
+		t.Fatalf("Lookup() = %v, %v; want prior result", prior, err)
+	}
+	if !prior.AlreadyApplied || !prior.Apply.AlreadyApplied || prior.Apply.FilesChanged != 2 {
+		t.Errorf("Lookup() = %+v, want replayed result with already_applied", prior)
+	}
+
+	if prior, _ := log.Lookup(ctx, t.TempDir(), "d1"); prior != nil {
+		t.Error("diff ID should be scoped to its workspace")
+	}
+}
@@ -0,0 +1,100 @@
+package codex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommitOptions configures the git commit made after a diff applies.
+type CommitOptions struct {
+	// Branch, when set, is checked out before committing, created from
+	// HEAD if it doesn't exist yet.
+	Branch string
+}
+
+// TaskBranch names the branch for a task's commits: prefix followed by the
+// lowercased task ID, e.g. "picoclaw/task-001". It is "" when prefix is.
+func TaskBranch(prefix, taskID string) string {
+	if prefix == "" || taskID == "" {
+		return ""
+	}
+	return prefix + strings.ToLower(unsafeWorktreeChars.ReplaceAllString(taskID, "-"))
+}
+
+// Commit stages the files the diff touched in the git repository at
+// workspaceRoot and commits only those paths, returning the new commit's
+// SHA. Other changes in the tree, staged or not, stay out of the commit and
+// keep their index state. The message is the diff summary
+// prefixed with the task ID, with the diff and agent IDs as trailers.
+func (sd *StructuredDiff) Commit(ctx context.Context, workspaceRoot string, opts CommitOptions) (string, error) {
+	if opts.Branch != "" {
+		if err := checkoutBranch(ctx, workspaceRoot, opts.Branch); err != nil {
+			return "", err
+		}
+	}
+
+	paths := []string{"add", "-A", "--"}
+	seen := make(map[string]bool)
+	for _, change := range sd.Changes {
+		for _, p := range []string{change.Path, change.NewPath} {
+			if p != "" && !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	if _, err := runGit(ctx, workspaceRoot, paths...); err != nil {
+		return "", err
+	}
+
+	// --only keeps anything the user had already staged out of the commit
+	args := []string{"commit", "-q", "-m", sd.commitMessage()}
+	if len(paths) > 3 {
+		args = append(append(args, "--only", "--"), paths[3:]...)
+	}
+	// A bot's checkout may have no identity configured
+	if email, _ := runGit(ctx, workspaceRoot, "config", "user.email"); email == "" {
+		args = append([]string{"-c", "user.name=picoclaw", "-c", "user.email=picoclaw@localhost"}, args...)
+	}
+	if _, err := runGit(ctx, workspaceRoot, args...); err != nil {
+		return "", err
+	}
+	return runGit(ctx, workspaceRoot, "rev-parse", "HEAD")
+}
+
+func (sd *StructuredDiff) commitMessage() string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(sd.Summary), "\n")
+	if subject == "" {
+		subject = fmt.Sprintf("Apply diff %s", sd.ID)
+	}
+	if sd.TaskID != "" {
+		subject = fmt.Sprintf("[%s] %s", sd.TaskID, subject)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(subject)
+	sb.WriteString("\n\n")
+	if sd.ID != "" {
+		fmt.Fprintf(&sb, "Diff-Id: %s\n", sd.ID)
+	}
+	if sd.AgentID != "" {
+		fmt.Fprintf(&sb, "Agent-Id: %s\n", sd.AgentID)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// checkoutBranch switches to branch, creating it from HEAD if needed.
+// Uncommitted changes come along, so the applied diff lands on it.
+func checkoutBranch(ctx context.Context, repo, branch string) error {
+	current, _ := runGit(ctx, repo, "rev-parse", "--abbrev-ref", "HEAD")
+	if current == branch {
+		return nil
+	}
+	if _, err := runGit(ctx, repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		_, err = runGit(ctx, repo, "checkout", "-q", branch)
+		return err
+	}
+	_, err := runGit(ctx, repo, "checkout", "-q", "-b", branch)
+	return err
+}
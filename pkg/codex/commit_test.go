@@ -0,0 +1,56 @@
+package codex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommit(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	// Unrelated work in progress must stay out of the commit
+	os.WriteFile(filepath.Join(repo, "scratch.txt"), []byte("wip"), 0644)
+	os.WriteFile(filepath.Join(repo, "staged.txt"), []byte("staged"), 0644)
+	if _, err := runGit(ctx, repo, "add", "staged.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := &StructuredDiff{ID: "d1", TaskID: "TASK-001", AgentID: "coder", Summary: "Add greeting\n\nLonger text",
+		Changes: []FileChange{
+			{Op: OpCreate, Path: "hello.txt", NewContent: "hi\n"},
+			{Op: OpRename, Path: "main.txt", NewPath: "renamed.txt"},
+		}}
+	if _, err := diff.Apply(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	branch := TaskBranch("picoclaw/", diff.TaskID)
+	if branch != "picoclaw/task-001" {
+		t.Errorf("TaskBranch = %q", branch)
+	}
+	sha, err := diff.Commit(ctx, repo, CommitOptions{Branch: branch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := runGit(ctx, repo, "rev-parse", "HEAD"); head != sha || len(sha) != 40 {
+		t.Errorf("Commit SHA = %q, HEAD = %q", sha, head)
+	}
+	if cur, _ := runGit(ctx, repo, "rev-parse", "--abbrev-ref", "HEAD"); cur != branch {
+		t.Errorf("on branch %q, want %q", cur, branch)
+	}
+
+	msg, _ := runGit(ctx, repo, "log", "-1", "--format=%B")
+	if !strings.HasPrefix(msg, "[TASK-001] Add greeting\n") || !strings.Contains(msg, "Diff-Id: d1") {
+		t.Errorf("commit message = %q", msg)
+	}
+	files, _ := runGit(ctx, repo, "show", "--name-status", "--format=", "HEAD")
+	if !strings.Contains(files, "hello.txt") || !strings.Contains(files, "renamed.txt") || strings.Contains(files, "scratch.txt") || strings.Contains(files, "staged.txt") {
+		t.Errorf("committed files:\n%s", files)
+	}
+	if status, _ := runGit(ctx, repo, "status", "--porcelain"); status != "A  staged.txt\n?? scratch.txt" {
+		t.Errorf("status after commit = %q", status)
+	}
+}
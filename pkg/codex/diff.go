@@ -6,13 +6,19 @@
 package codex
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // DiffOperation represents the type of file change.
@@ -46,10 +52,20 @@ type FileChange struct {
 	// For insert: line number (1-based) and content to insert after that line
 	LineNumber int    `json:"line_number,omitempty"`
 
+	// For create: optional octal permission bits, e.g. "0755". Defaults to 0644.
+	Mode string `json:"mode,omitempty"`
+
+	// Encoding of NewContent. Empty means plain text; EncodingBase64 lets
+	// create write binary files.
+	Encoding string `json:"encoding,omitempty"`
+
 	// Description of what this change does
 	Description string `json:"description"`
 }
 
+// EncodingBase64 marks NewContent as base64-encoded bytes (create only).
+const EncodingBase64 = "base64"
+
 // StructuredDiff is the complete output a coding agent must produce.
 // This replaces free-text responses for code modification tasks.
 type StructuredDiff struct {
@@ -100,8 +116,47 @@ type VerifySpec struct {
 
 // --- Validation ---
 
-// Validate checks that the diff is well-formed before applying.
+// DiffLimits caps the size of a diff so runaway agent output is rejected
+// before it touches the filesystem. Zero fields keep the defaults: 200
+// changes, 8 MB of content in total and 2 MB per file. The limits apply
+// whatever the approval policy says.
+type DiffLimits struct {
+	// MaxChanges is the most file changes one diff may carry.
+	MaxChanges int
+	// MaxTotalBytes caps the old and new content of all changes together.
+	MaxTotalBytes int
+	// MaxFileBytes caps the new content of any one change, after base64
+	// decoding.
+	MaxFileBytes int
+}
+
+const (
+	defaultMaxChanges    = 200
+	defaultMaxTotalBytes = 8 << 20
+	defaultMaxFileBytes  = 2 << 20
+)
+
+func (l DiffLimits) withDefaults() DiffLimits {
+	if l.MaxChanges <= 0 {
+		l.MaxChanges = defaultMaxChanges
+	}
+	if l.MaxTotalBytes <= 0 {
+		l.MaxTotalBytes = defaultMaxTotalBytes
+	}
+	if l.MaxFileBytes <= 0 {
+		l.MaxFileBytes = defaultMaxFileBytes
+	}
+	return l
+}
+
+// Validate checks that the diff is well-formed before applying, within
+// the default DiffLimits.
 func (sd *StructuredDiff) Validate() error {
+	return sd.ValidateLimits(DiffLimits{})
+}
+
+// ValidateLimits is Validate with the size limits overridden by limits.
+func (sd *StructuredDiff) ValidateLimits(limits DiffLimits) error {
 	if sd.ID == "" {
 		return fmt.Errorf("diff ID is required")
 	}
@@ -112,14 +167,39 @@ func (sd *StructuredDiff) Validate() error {
 		return fmt.Errorf("diff has no changes")
 	}
 
+	limits = limits.withDefaults()
+	if len(sd.Changes) > limits.MaxChanges {
+		return fmt.Errorf("diff has %d changes (max %d)", len(sd.Changes), limits.MaxChanges)
+	}
+
+	total := 0
 	for i, change := range sd.Changes {
 		if err := change.Validate(); err != nil {
 			return fmt.Errorf("change[%d]: %w", i, err)
 		}
+		if size := change.contentSize(); size > limits.MaxFileBytes {
+			return fmt.Errorf("change[%d]: new content for %s is %d bytes (max %d)",
+				i, change.Path, size, limits.MaxFileBytes)
+		}
+		total += len(change.OldContent) + len(change.NewContent)
+		if total > limits.MaxTotalBytes {
+			return fmt.Errorf("diff content exceeds %d bytes at change[%d] (%s)",
+				limits.MaxTotalBytes, i, change.Path)
+		}
 	}
 	return nil
 }
 
+// contentSize is the number of bytes the change's new content writes.
+// Call it only after Validate, which checks base64 content decodes.
+func (fc *FileChange) contentSize() int {
+	if fc.Encoding == EncodingBase64 {
+		n := len(fc.NewContent)
+		return base64.StdEncoding.DecodedLen(n) - (n - len(strings.TrimRight(fc.NewContent, "=")))
+	}
+	return len(fc.NewContent)
+}
+
 // Validate checks a single file change.
 func (fc *FileChange) Validate() error {
 	if fc.Path == "" {
@@ -128,16 +208,50 @@ func (fc *FileChange) Validate() error {
 	if strings.Contains(fc.Path, "..") {
 		return fmt.Errorf("path traversal not allowed: %s", fc.Path)
 	}
+	if filepath.IsAbs(fc.Path) || filepath.IsAbs(fc.NewPath) {
+		return fmt.Errorf("absolute paths not allowed: %s", fc.Path)
+	}
+	if strings.Contains(fc.NewPath, "..") {
+		return fmt.Errorf("path traversal not allowed: %s", fc.NewPath)
+	}
+
+	if fc.Mode != "" {
+		if fc.Op != OpCreate {
+			return fmt.Errorf("mode is only supported for create")
+		}
+		if _, err := parseMode(fc.Mode); err != nil {
+			return err
+		}
+	}
+
+	switch fc.Encoding {
+	case "":
+	case EncodingBase64:
+		if fc.Op != OpCreate {
+			return fmt.Errorf("base64 encoding is only supported for create")
+		}
+		if _, err := base64.StdEncoding.DecodeString(fc.NewContent); err != nil {
+			return fmt.Errorf("new_content is not valid base64: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown encoding: %s", fc.Encoding)
+	}
 
 	switch fc.Op {
 	case OpCreate:
 		if fc.NewContent == "" {
 			return fmt.Errorf("new_content required for create")
 		}
+		if fc.Encoding == "" && isBinary([]byte(fc.NewContent)) {
+			return fmt.Errorf("new_content looks binary; send it with encoding %q", EncodingBase64)
+		}
 	case OpModify:
 		if fc.OldContent == "" || fc.NewContent == "" {
 			return fmt.Errorf("old_content and new_content required for modify")
 		}
+		if isBinary([]byte(fc.OldContent)) || isBinary([]byte(fc.NewContent)) {
+			return fmt.Errorf("modify cannot search-replace binary content")
+		}
 	case OpDelete:
 		// path only
 	case OpRename:
@@ -151,6 +265,9 @@ func (fc *FileChange) Validate() error {
 		if fc.NewContent == "" {
 			return fmt.Errorf("new_content required for insert")
 		}
+		if isBinary([]byte(fc.NewContent)) {
+			return fmt.Errorf("insert cannot add binary content")
+		}
 	default:
 		return fmt.Errorf("unknown operation: %s", fc.Op)
 	}
@@ -162,7 +279,10 @@ func (fc *FileChange) Validate() error {
 // CheckPreconditions verifies all preconditions against the filesystem.
 func (sd *StructuredDiff) CheckPreconditions(workspaceRoot string) error {
 	for _, pre := range sd.Preconditions {
-		fullPath := filepath.Join(workspaceRoot, pre.Path)
+		fullPath, err := utils.ResolveInWorkspace(workspaceRoot, pre.Path)
+		if err != nil {
+			return fmt.Errorf("precondition failed: %w", err)
+		}
 		data, err := os.ReadFile(fullPath)
 
 		if err != nil {
@@ -183,6 +303,50 @@ func (sd *StructuredDiff) CheckPreconditions(workspaceRoot string) error {
 	return nil
 }
 
+// AddPreconditionsFromWorkspace stamps a must_exist precondition with the
+// current SHA256 for every file the diff modifies, inserts into, renames or
+// deletes, so the apply fails if the file changes underneath it. Paths that
+// already have a precondition, or that an earlier change in the diff
+// creates or renames into place, are left alone. A missing file gets a
+// must_exist precondition without a hash, which CheckPreconditions then
+// reports.
+func (sd *StructuredDiff) AddPreconditionsFromWorkspace(workspaceRoot string) error {
+	seen := make(map[string]bool, len(sd.Preconditions))
+	for _, pre := range sd.Preconditions {
+		seen[filepath.Clean(pre.Path)] = true
+	}
+
+	for _, change := range sd.Changes {
+		path := filepath.Clean(change.Path)
+		if change.Op == OpCreate {
+			seen[path] = true
+			continue
+		}
+		if change.Op == OpRename {
+			seen[filepath.Clean(change.NewPath)] = true
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		fullPath, err := utils.ResolveInWorkspace(workspaceRoot, change.Path)
+		if err != nil {
+			return err
+		}
+		pre := FilePrecondition{Path: change.Path, MustExist: true}
+		data, err := os.ReadFile(fullPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("read %s: %w", change.Path, err)
+		}
+		if err == nil {
+			pre.SHA256 = fmt.Sprintf("%x", sha256.Sum256(data))
+		}
+		sd.Preconditions = append(sd.Preconditions, pre)
+	}
+	return nil
+}
+
 // --- Application ---
 
 // Apply applies the diff to the filesystem atomically.
@@ -197,17 +361,27 @@ func (sd *StructuredDiff) Apply(workspaceRoot string) (*ApplyResult, error) {
 	// Track applied changes for rollback
 	var rollbackOps []rollbackOp
 
+	result.Changes = make([]ChangeResult, len(sd.Changes))
+	for i, change := range sd.Changes {
+		result.Changes[i] = ChangeResult{Path: change.Path, Op: change.Op}
+	}
+
 	for i, change := range sd.Changes {
 		if err := applyChange(workspaceRoot, change, &rollbackOps); err != nil {
 			// Rollback everything
 			for j := len(rollbackOps) - 1; j >= 0; j-- {
 				rollbackOps[j].undo()
 			}
+			result.Changes[i].Error = err.Error()
+			for j := i + 1; j < len(sd.Changes); j++ {
+				result.Changes[j].Error = errNotAttempted
+			}
 			result.Success = false
 			result.Error = fmt.Sprintf("change[%d] (%s %s): %v", i, change.Op, change.Path, err)
 			result.CompletedAt = time.Now()
 			return result, err
 		}
+		result.Changes[i].Applied = true
 		result.FilesChanged++
 	}
 
@@ -226,14 +400,39 @@ type ApplyResult struct {
 	StartedAt    time.Time `json:"started_at"`
 	CompletedAt  time.Time `json:"completed_at"`
 	TestPassed   *bool     `json:"test_passed,omitempty"`
+
+	// Changes has one entry per change in the diff, in order. It is filled
+	// in even when Apply fails and rolls back, so callers can tell which
+	// changes were fine and regenerate only the one that failed.
+	Changes []ChangeResult `json:"changes,omitempty"`
+
+	// AlreadyApplied is set when this result was replayed from an earlier
+	// apply of the same diff ID rather than produced now.
+	AlreadyApplied bool `json:"already_applied,omitempty"`
 }
 
+// ChangeResult is the outcome of one change within Apply. Applied reports
+// whether the change itself went through; after a failed Apply those
+// changes have been rolled back along with the rest.
+type ChangeResult struct {
+	Path    string        `json:"path"`
+	Op      DiffOperation `json:"op"`
+	Applied bool          `json:"applied"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// errNotAttempted marks changes after the one that made Apply fail.
+const errNotAttempted = "not attempted: an earlier change failed"
+
 type rollbackOp struct {
 	undo func()
 }
 
 func applyChange(root string, change FileChange, rollbackOps *[]rollbackOp) error {
-	fullPath := filepath.Join(root, change.Path)
+	fullPath, err := utils.ResolveInWorkspace(root, change.Path)
+	if err != nil {
+		return err
+	}
 
 	switch change.Op {
 	case OpCreate:
@@ -242,84 +441,191 @@ func applyChange(root string, change FileChange, rollbackOps *[]rollbackOp) erro
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return err
 		}
-		if err := os.WriteFile(fullPath, []byte(change.NewContent), 0644); err != nil {
+		data, err := change.newBytes()
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if change.Mode != "" {
+			if mode, err = parseMode(change.Mode); err != nil {
+				return err
+			}
+		}
+		if err := writeFileMode(fullPath, data, mode); err != nil {
 			return err
 		}
 		*rollbackOps = append(*rollbackOps, rollbackOp{undo: func() { os.Remove(fullPath) }})
 
 	case OpModify:
 		// Read current content
-		existing, err := os.ReadFile(fullPath)
+		existing, mode, err := readFileMode(fullPath)
 		if err != nil {
 			return fmt.Errorf("file not found: %s", change.Path)
 		}
+		if isBinary(existing) {
+			return fmt.Errorf("%s is a binary file; modify only works on text", change.Path)
+		}
 
 		content := string(existing)
-		if !strings.Contains(content, change.OldContent) {
+		oldContent, replacement, ok := matchLineEnding(content, change.OldContent, change.NewContent)
+		if !ok {
 			return fmt.Errorf("old_content not found in %s", change.Path)
 		}
 
-		newContent := strings.Replace(content, change.OldContent, change.NewContent, 1)
+		newContent := strings.Replace(content, oldContent, replacement, 1)
 		backup := string(existing)
 
-		if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+		if err := writeFileMode(fullPath, []byte(newContent), mode); err != nil {
 			return err
 		}
 		*rollbackOps = append(*rollbackOps, rollbackOp{
-			undo: func() { os.WriteFile(fullPath, []byte(backup), 0644) },
+			undo: func() { writeFileMode(fullPath, []byte(backup), mode) },
 		})
 
 	case OpDelete:
-		existing, _ := os.ReadFile(fullPath)
+		existing, mode, _ := readFileMode(fullPath)
 		backup := string(existing)
 		if err := os.Remove(fullPath); err != nil {
 			return err
 		}
 		*rollbackOps = append(*rollbackOps, rollbackOp{
-			undo: func() { os.WriteFile(fullPath, []byte(backup), 0644) },
+			undo: func() { writeFileMode(fullPath, []byte(backup), mode) },
 		})
 
 	case OpRename:
-		newFullPath := filepath.Join(root, change.NewPath)
+		newFullPath, err := utils.ResolveInWorkspace(root, change.NewPath)
+		if err != nil {
+			return err
+		}
 		if err := os.MkdirAll(filepath.Dir(newFullPath), 0755); err != nil {
 			return err
 		}
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return fmt.Errorf("file not found: %s", change.Path)
+		}
 		if err := os.Rename(fullPath, newFullPath); err != nil {
 			return err
 		}
+		// Rename keeps the inode's mode; re-apply it anyway in case the
+		// target filesystem applied its own defaults.
+		os.Chmod(newFullPath, info.Mode().Perm())
 		*rollbackOps = append(*rollbackOps, rollbackOp{
 			undo: func() { os.Rename(newFullPath, fullPath) },
 		})
 
 	case OpInsert:
-		existing, err := os.ReadFile(fullPath)
+		existing, mode, err := readFileMode(fullPath)
 		if err != nil {
 			return fmt.Errorf("file not found: %s", change.Path)
 		}
+		if isBinary(existing) {
+			return fmt.Errorf("%s is a binary file; insert only works on text", change.Path)
+		}
 		backup := string(existing)
 
-		lines := strings.Split(string(existing), "\n")
+		eol := lineEnding(backup)
+		inserted := toLineEnding(change.NewContent, eol)
+		lines := strings.Split(backup, eol)
 		if change.LineNumber > len(lines) {
-			lines = append(lines, change.NewContent)
+			lines = append(lines, inserted)
 		} else {
 			newLines := make([]string, 0, len(lines)+1)
 			newLines = append(newLines, lines[:change.LineNumber]...)
-			newLines = append(newLines, change.NewContent)
+			newLines = append(newLines, inserted)
 			newLines = append(newLines, lines[change.LineNumber:]...)
 			lines = newLines
 		}
 
-		if err := os.WriteFile(fullPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		if err := writeFileMode(fullPath, []byte(strings.Join(lines, eol)), mode); err != nil {
 			return err
 		}
 		*rollbackOps = append(*rollbackOps, rollbackOp{
-			undo: func() { os.WriteFile(fullPath, []byte(backup), 0644) },
+			undo: func() { writeFileMode(fullPath, []byte(backup), mode) },
 		})
 	}
 
 	return nil
 }
 
+// lineEnding returns the file's dominant line break: "\r\n" when CRLF
+// breaks outnumber bare LF ones, otherwise "\n".
+func lineEnding(content string) string {
+	crlf := strings.Count(content, "\r\n")
+	if crlf > strings.Count(content, "\n")-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// toLineEnding rewrites every line break in s to eol.
+func toLineEnding(s, eol string) string {
+	if eol == "\n" {
+		return s
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", eol)
+}
+
+// matchLineEnding finds old in content, retrying with content's line ending
+// when the agent sent LF text for a CRLF file. It returns the old/repl pair to
+// use for the replacement, with repl converted to the file's line ending so
+// the write doesn't mix styles.
+func matchLineEnding(content, old, repl string) (string, string, bool) {
+	eol := lineEnding(content)
+	if strings.Contains(content, old) {
+		return old, toLineEnding(repl, eol), true
+	}
+	if eol != "\n" {
+		if converted := toLineEnding(old, eol); strings.Contains(content, converted) {
+			return converted, toLineEnding(repl, eol), true
+		}
+	}
+	return old, repl, false
+}
+
+// readFileMode reads a file along with its permission bits.
+func readFileMode(path string) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0644, err
+	}
+	data, err := os.ReadFile(path)
+	return data, info.Mode().Perm(), err
+}
+
+// writeFileMode writes data and then sets mode explicitly. os.WriteFile only
+// applies perm when it creates the file (and through the umask), so an
+// existing script would otherwise keep or lose +x depending on history.
+func writeFileMode(path string, data []byte, mode os.FileMode) error {
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// parseMode parses an octal permission string such as "0755".
+func parseMode(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("invalid mode %q: want octal permission bits like \"0755\"", s)
+	}
+	return os.FileMode(n), nil
+}
+
+// newBytes returns NewContent decoded according to Encoding.
+func (fc *FileChange) newBytes() ([]byte, error) {
+	if fc.Encoding == EncodingBase64 {
+		return base64.StdEncoding.DecodeString(fc.NewContent)
+	}
+	return []byte(fc.NewContent), nil
+}
+
+// isBinary reports whether data looks like a binary file: it contains a NUL
+// byte or is not valid UTF-8. Search-replace on such content would corrupt it.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
 // --- LLM Prompt ---
 
 // AgentPrompt returns the system prompt that constrains the coding agent
@@ -357,6 +663,7 @@ Rules:
 4. Include verify commands when possible.
 5. Each change must be independently understandable from its description.
 6. Path must be relative to workspace root. No "../" traversal.
+7. Never modify or insert into binary files. To write one, use "create" with "encoding": "base64".
 `
 
 // ParseDiff parses a JSON string into a StructuredDiff.
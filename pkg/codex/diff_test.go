@@ -0,0 +1,249 @@
+package codex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddPreconditionsFromWorkspace(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := &StructuredDiff{
+		ID:     "d1",
+		TaskID: "TASK-001",
+		Changes: []FileChange{
+			{Op: OpModify, Path: "a.txt", OldContent: "one", NewContent: "two"},
+			{Op: OpCreate, Path: "b.txt", NewContent: "new\n"},
+			{Op: OpModify, Path: "b.txt", OldContent: "new", NewContent: "newer"},
+		},
+	}
+	if err := diff.AddPreconditionsFromWorkspace(root); err != nil {
+		t.Fatalf("AddPreconditionsFromWorkspace() error: %v", err)
+	}
+	if len(diff.Preconditions) != 1 || diff.Preconditions[0].Path != "a.txt" {
+		t.Fatalf("Preconditions = %+v, want only a.txt", diff.Preconditions)
+	}
+	if !diff.Preconditions[0].MustExist || diff.Preconditions[0].SHA256 == "" {
+		t.Errorf("a.txt precondition = %+v, want must_exist with hash", diff.Preconditions[0])
+	}
+	if err := diff.CheckPreconditions(root); err != nil {
+		t.Fatalf("CheckPreconditions() on unchanged file: %v", err)
+	}
+
+	// A concurrent edit must now trip the precondition.
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := diff.CheckPreconditions(root); err == nil {
+		t.Error("CheckPreconditions() passed after concurrent edit")
+	}
+}
+
+func TestBinaryChanges(t *testing.T) {
+	root := t.TempDir()
+	bin := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	if err := os.WriteFile(filepath.Join(root, "img.png"), bin, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modify := FileChange{Op: OpModify, Path: "img.png", OldContent: "PNG", NewContent: "GIF"}
+	if err := modify.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	var ops []rollbackOp
+	if err := applyChange(root, modify, &ops); err == nil {
+		t.Error("modify of a binary file should fail")
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "img.png"))
+	if string(got) != string(bin) {
+		t.Error("binary file was altered")
+	}
+
+	raw := FileChange{Op: OpCreate, Path: "raw.bin", NewContent: string(bin)}
+	if err := raw.Validate(); err == nil {
+		t.Error("create with raw binary content should require base64")
+	}
+
+	encoded := FileChange{Op: OpCreate, Path: "copy.png", NewContent: "iVBORwD/", Encoding: EncodingBase64}
+	if err := encoded.Validate(); err != nil {
+		t.Fatalf("Validate(base64 create) error: %v", err)
+	}
+	if err := applyChange(root, encoded, &ops); err != nil {
+		t.Fatalf("applyChange(base64 create) error: %v", err)
+	}
+	got, _ = os.ReadFile(filepath.Join(root, "copy.png"))
+	if string(got) != string(bin) {
+		t.Errorf("copy.png = %x, want %x", got, bin)
+	}
+
+	badEnc := FileChange{Op: OpModify, Path: "a", OldContent: "x", NewContent: "eQ==", Encoding: EncodingBase64}
+	if err := badEnc.Validate(); err == nil {
+		t.Error("base64 encoding on modify should be rejected")
+	}
+}
+
+func TestApplyPreservesMode(t *testing.T) {
+	root := t.TempDir()
+	script := filepath.Join(root, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(script, 0755)
+
+	diff := &StructuredDiff{
+		ID:     "d1",
+		TaskID: "TASK-001",
+		Changes: []FileChange{
+			{Op: OpModify, Path: "run.sh", OldContent: "echo hi", NewContent: "echo hello"},
+			{Op: OpInsert, Path: "run.sh", LineNumber: 1, NewContent: "set -e"},
+			{Op: OpCreate, Path: "tool.sh", NewContent: "#!/bin/sh\n", Mode: "0750"},
+		},
+	}
+	if err := diff.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if _, err := diff.Apply(root); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+
+	for path, want := range map[string]os.FileMode{"run.sh": 0755, "tool.sh": 0750} {
+		info, err := os.Stat(filepath.Join(root, path))
+		if err != nil {
+			t.Fatalf("Stat(%s) error: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %o, want %o", path, got, want)
+		}
+	}
+
+	bad := FileChange{Op: OpCreate, Path: "x", NewContent: "x", Mode: "rwx"}
+	if err := bad.Validate(); err == nil {
+		t.Error("non-octal mode should be rejected")
+	}
+}
+
+func TestApplyPreservesCRLF(t *testing.T) {
+	root := t.TempDir()
+	fixture := "first\r\nsecond\r\nthird\r\n"
+	path := filepath.Join(root, "win.txt")
+	if err := os.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []FileChange{
+		{Op: OpModify, Path: "win.txt", OldContent: "second\nthird", NewContent: "2nd\nthird"},
+		{Op: OpInsert, Path: "win.txt", LineNumber: 1, NewContent: "inserted\nlines"},
+	}
+	var ops []rollbackOp
+	for _, c := range changes {
+		if err := applyChange(root, c, &ops); err != nil {
+			t.Fatalf("applyChange(%s) error: %v", c.Op, err)
+		}
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "first\r\ninserted\r\nlines\r\n2nd\r\nthird\r\n"
+	if string(got) != want {
+		t.Fatalf("content = %q, want %q", got, want)
+	}
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := rollbackChange(root, changes[i]); err != nil {
+			t.Fatalf("rollbackChange(%s) error: %v", changes[i].Op, err)
+		}
+	}
+	got, _ = os.ReadFile(path)
+	if string(got) != fixture {
+		t.Errorf("after rollback = %q, want %q", got, fixture)
+	}
+}
+
+func TestApplyReportsPerChangeResults(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := &StructuredDiff{ID: "d1", Changes: []FileChange{
+		{Op: OpModify, Path: "a.txt", OldContent: "one", NewContent: "1"},
+		{Op: OpCreate, Path: "b.txt", NewContent: "b\n"},
+		{Op: OpModify, Path: "a.txt", OldContent: "missing", NewContent: "x"},
+		{Op: OpDelete, Path: "a.txt"},
+	}}
+	result, err := diff.Apply(root)
+	if err == nil {
+		t.Fatal("Apply() should fail on the third change")
+	}
+	if len(result.Changes) != 4 {
+		t.Fatalf("len(Changes) = %d, want 4", len(result.Changes))
+	}
+	for i, want := range []bool{true, true, false, false} {
+		c := result.Changes[i]
+		if c.Applied != want {
+			t.Errorf("change[%d].Applied = %v, want %v", i, c.Applied, want)
+		}
+		if want != (c.Error == "") {
+			t.Errorf("change[%d].Error = %q", i, c.Error)
+		}
+	}
+	if result.Changes[3].Error != errNotAttempted {
+		t.Errorf("change[3].Error = %q, want not attempted", result.Changes[3].Error)
+	}
+
+	got, _ := os.ReadFile(path)
+	if string(got) != "one\ntwo\n" {
+		t.Errorf("a.txt = %q, want rolled back", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); !os.IsNotExist(err) {
+		t.Error("b.txt should be rolled back")
+	}
+}
+
+func TestDiffLimits(t *testing.T) {
+	change := func(path, content string) FileChange {
+		return FileChange{Op: OpCreate, Path: path, NewContent: content}
+	}
+
+	many := &StructuredDiff{ID: "d1", TaskID: "TASK-001"}
+	for i := 0; i < defaultMaxChanges+1; i++ {
+		many.Changes = append(many.Changes, change(fmt.Sprintf("f%d.txt", i), "x"))
+	}
+	if err := many.Validate(); err == nil || !strings.Contains(err.Error(), "201 changes") {
+		t.Errorf("Validate(%d changes) = %v", len(many.Changes), err)
+	}
+	if err := many.ValidateLimits(DiffLimits{MaxChanges: 500}); err != nil {
+		t.Errorf("ValidateLimits(MaxChanges 500) = %v", err)
+	}
+
+	big := &StructuredDiff{ID: "d2", TaskID: "TASK-001", Changes: []FileChange{
+		change("a.txt", strings.Repeat("a", 60)),
+		change("b.txt", strings.Repeat("b", 60)),
+	}}
+	if err := big.ValidateLimits(DiffLimits{MaxFileBytes: 50}); err == nil || !strings.Contains(err.Error(), "a.txt is 60 bytes") {
+		t.Errorf("ValidateLimits(MaxFileBytes 50) = %v", err)
+	}
+	if err := big.ValidateLimits(DiffLimits{MaxTotalBytes: 100}); err == nil || !strings.Contains(err.Error(), "change[1] (b.txt)") {
+		t.Errorf("ValidateLimits(MaxTotalBytes 100) = %v", err)
+	}
+	if err := big.ValidateLimits(DiffLimits{MaxTotalBytes: 120, MaxFileBytes: 60}); err != nil {
+		t.Errorf("ValidateLimits at the limits = %v", err)
+	}
+
+	// Binary content is measured decoded: "iVBORwD/" is 6 bytes.
+	bin := &StructuredDiff{ID: "d3", TaskID: "TASK-001", Changes: []FileChange{
+		{Op: OpCreate, Path: "img.png", NewContent: "iVBORwD/", Encoding: EncodingBase64},
+	}}
+	if err := bin.ValidateLimits(DiffLimits{MaxFileBytes: 6}); err != nil {
+		t.Errorf("ValidateLimits(6-byte binary, max 6) = %v", err)
+	}
+	if err := bin.ValidateLimits(DiffLimits{MaxFileBytes: 5}); err == nil {
+		t.Error("6-byte binary passed MaxFileBytes 5")
+	}
+}
@@ -0,0 +1,231 @@
+package codex
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the line-LCS table. Larger inputs fall back to a
+// single hunk spanning everything between the common prefix and suffix.
+const maxDiffCells = 4 << 20
+
+// FileEdit is one file's before/after content, the input to GenerateDiff.
+type FileEdit struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// GenerateDiff builds a StructuredDiff from before/after file contents.
+// Each edited file becomes one or more OpModify changes whose old_content is
+// widened with surrounding lines until it is unique at the point it is
+// applied. Empty before means create (base64-encoded if binary); empty after
+// means delete. Unchanged files are skipped; editing a binary file in place
+// is an error. Every file that must already exist gets a SHA256
+// precondition of its before content.
+func GenerateDiff(id, taskID, agentID string, edits []FileEdit) (*StructuredDiff, error) {
+	diff := &StructuredDiff{
+		ID:      id,
+		TaskID:  taskID,
+		AgentID: agentID,
+	}
+
+	files := 0
+	for _, edit := range edits {
+		if edit.Path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		if edit.Before == edit.After {
+			continue
+		}
+		files++
+
+		if edit.Before != "" {
+			diff.Preconditions = append(diff.Preconditions, FilePrecondition{
+				Path:      edit.Path,
+				SHA256:    fmt.Sprintf("%x", sha256.Sum256([]byte(edit.Before))),
+				MustExist: true,
+			})
+		}
+
+		switch {
+		case edit.Before == "":
+			change := FileChange{
+				Op:          OpCreate,
+				Path:        edit.Path,
+				NewContent:  edit.After,
+				Description: "create " + edit.Path,
+			}
+			if isBinary([]byte(edit.After)) {
+				change.Encoding = EncodingBase64
+				change.NewContent = base64.StdEncoding.EncodeToString([]byte(edit.After))
+			}
+			diff.Changes = append(diff.Changes, change)
+		case edit.After == "":
+			diff.Changes = append(diff.Changes, FileChange{
+				Op:          OpDelete,
+				Path:        edit.Path,
+				Description: "delete " + edit.Path,
+			})
+		case isBinary([]byte(edit.Before)) || isBinary([]byte(edit.After)):
+			return nil, fmt.Errorf("%s is binary; only create and delete are supported", edit.Path)
+		default:
+			diff.Changes = append(diff.Changes, GenerateChanges(edit.Path, edit.Before, edit.After)...)
+		}
+	}
+
+	if len(diff.Changes) == 0 {
+		return nil, fmt.Errorf("no differences between before and after")
+	}
+	diff.Summary = fmt.Sprintf("%d change(s) across %d file(s)", len(diff.Changes), files)
+	return diff, nil
+}
+
+// GenerateChanges returns the OpModify changes that turn before into after.
+// Applied in order with Apply, each old_content matches exactly once.
+func GenerateChanges(path, before, after string) []FileChange {
+	if before == after {
+		return nil
+	}
+	a := strings.SplitAfter(before, "\n")
+	b := strings.SplitAfter(after, "\n")
+	hunks := diffLines(a, b)
+
+	var changes []FileChange
+	for i := 0; i < len(hunks); i++ {
+		h := hunks[i]
+		// Lines above the hunk are already in their final form; lines
+		// below are still the originals.
+		working := append(append([]string{}, b[:h.b0]...), a[h.a0:]...)
+		text := strings.Join(working, "")
+		lo, hiA, hiB := h.b0, h.a1, h.b1
+
+		for {
+			old := strings.Join(b[lo:h.b0], "") + strings.Join(a[h.a0:hiA], "")
+			repl := strings.Join(b[lo:hiB], "")
+			if old != "" && repl != "" && matchesOnce(text, old) {
+				changes = append(changes, FileChange{
+					Op:          OpModify,
+					Path:        path,
+					OldContent:  old,
+					NewContent:  repl,
+					Description: fmt.Sprintf("edit %s near line %d", path, h.a0+1),
+				})
+				break
+			}
+
+			// Grow by a line on each side. Trailing context may not run
+			// into the next hunk; fold that hunk in instead.
+			grew := false
+			if lo > 0 {
+				lo--
+				grew = true
+			}
+			if hiA < len(a) {
+				if i+1 < len(hunks) && hiA >= hunks[i+1].a0 {
+					next := hunks[i+1]
+					hunks = append(hunks[:i+1], hunks[i+2:]...)
+					h.a1, h.b1 = next.a1, next.b1
+					hiA, hiB = next.a1, next.b1
+				} else {
+					hiA++
+					hiB++
+				}
+				grew = true
+			}
+			if !grew {
+				// Whole file: the entire working text is trivially unique.
+				changes = append(changes, FileChange{
+					Op:          OpModify,
+					Path:        path,
+					OldContent:  text,
+					NewContent:  after,
+					Description: "rewrite " + path,
+				})
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// matchesOnce reports whether sub occurs exactly once in s, counting
+// overlapping matches (which strings.Count does not).
+func matchesOnce(s, sub string) bool {
+	i := strings.Index(s, sub)
+	return i >= 0 && i == strings.LastIndex(s, sub)
+}
+
+// lineHunk is a replaced region: a[a0:a1] becomes b[b0:b1].
+type lineHunk struct {
+	a0, a1, b0, b1 int
+}
+
+// diffLines returns the changed regions between two line slices, in order.
+func diffLines(a, b []string) []lineHunk {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if len(ma) == 0 && len(mb) == 0 {
+		return nil
+	}
+	if len(ma)*len(mb) > maxDiffCells {
+		return []lineHunk{{pre, len(a) - suf, pre, len(b) - suf}}
+	}
+
+	// lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var hunks []lineHunk
+	open := false
+	var cur lineHunk
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		if i < len(ma) && j < len(mb) && ma[i] == mb[j] {
+			if open {
+				cur.a1, cur.b1 = pre+i, pre+j
+				hunks = append(hunks, cur)
+				open = false
+			}
+			i++
+			j++
+			continue
+		}
+		if !open {
+			cur = lineHunk{a0: pre + i, b0: pre + j}
+			open = true
+		}
+		if j >= len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]) {
+			i++
+		} else {
+			j++
+		}
+	}
+	if open {
+		cur.a1, cur.b1 = pre+i, pre+j
+		hunks = append(hunks, cur)
+	}
+	return hunks
+}
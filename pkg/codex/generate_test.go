@@ -0,0 +1,82 @@
+package codex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateChangesRoundtrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+	}{
+		{"single line", "a\nb\nc\n", "a\nB\nc\n"},
+		{"insert", "a\nb\n", "a\nx\nb\n"},
+		{"delete", "a\nb\nc\n", "a\nc\n"},
+		{"append without newline", "a\nb", "a\nb\nc"},
+		{"repeated lines", "}\n}\nfoo\n}\n}\n", "}\n}\nbar\n}\n}\n"},
+		{"two distant edits", "x\n1\n2\n3\n4\n5\n6\nx\n", "y\n1\n2\n3\n4\n5\n6\nz\n"},
+		{"identical context around edits", "a\nb\na\nb\na\n", "a\nB\na\nB\na\n"},
+		{"overlapping context", "c\nb\n}\n}\n}\n", "}\n}\n}\nx"},
+		{"replace everything", "old\n", "new\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := GenerateChanges("f.txt", tt.before, tt.after)
+			if len(changes) == 0 {
+				t.Fatal("expected changes")
+			}
+			content := tt.before
+			for i, c := range changes {
+				if err := c.Validate(); err != nil {
+					t.Fatalf("change[%d] invalid: %v", i, err)
+				}
+				if !matchesOnce(content, c.OldContent) {
+					t.Fatalf("change[%d] old_content %q is not unique in %q", i, c.OldContent, content)
+				}
+				content = strings.Replace(content, c.OldContent, c.NewContent, 1)
+			}
+			if content != tt.after {
+				t.Errorf("result = %q, want %q", content, tt.after)
+			}
+		})
+	}
+}
+
+func TestGenerateDiffApply(t *testing.T) {
+	root := t.TempDir()
+	before := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	after := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(before), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := GenerateDiff("d1", "TASK-001", "tester", []FileEdit{
+		{Path: "main.go", Before: before, After: after},
+		{Path: "new.txt", After: "fresh\n"},
+		{Path: "same.txt", Before: "x", After: "x"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateDiff() error: %v", err)
+	}
+	if err := diff.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if err := diff.CheckPreconditions(root); err != nil {
+		t.Fatalf("CheckPreconditions() error: %v", err)
+	}
+	if _, err := diff.Apply(root); err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(root, "main.go"))
+	if string(got) != after {
+		t.Errorf("main.go = %q, want %q", got, after)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.txt")); err != nil {
+		t.Errorf("new.txt not created: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package codex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownMaxLines caps the lines shown per change in Markdown, so a large
+// create doesn't turn into a huge note.
+const markdownMaxLines = 80
+
+// Markdown renders the diff for reading: the summary, then each change with
+// its content as a fenced diff block. Binary content is described, not
+// shown, and long content is cut to markdownMaxLines per change.
+func (sd *StructuredDiff) Markdown() string {
+	var sb strings.Builder
+	title := sd.Summary
+	if title == "" {
+		title = "Diff " + sd.ID
+	}
+	fmt.Fprintf(&sb, "### %s\n", title)
+	if sd.AgentID != "" {
+		fmt.Fprintf(&sb, "\nBy %s, %d change(s).\n", sd.AgentID, len(sd.Changes))
+	}
+
+	for _, c := range sd.Changes {
+		sb.WriteString("\n")
+		switch c.Op {
+		case OpRename:
+			fmt.Fprintf(&sb, "**rename** `%s` → `%s`\n", c.Path, c.NewPath)
+		case OpInsert:
+			fmt.Fprintf(&sb, "**insert** `%s` after line %d\n", c.Path, c.LineNumber)
+		default:
+			fmt.Fprintf(&sb, "**%s** `%s`\n", c.Op, c.Path)
+		}
+		if c.Description != "" {
+			fmt.Fprintf(&sb, "\n%s\n", c.Description)
+		}
+
+		var lines []string
+		switch c.Op {
+		case OpCreate:
+			if c.Encoding == EncodingBase64 {
+				fmt.Fprintf(&sb, "\n(binary content)\n")
+				continue
+			}
+			lines = prefixLines("+", c.NewContent)
+		case OpModify:
+			lines = append(prefixLines("-", c.OldContent), prefixLines("+", c.NewContent)...)
+		case OpInsert:
+			lines = prefixLines("+", c.NewContent)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if len(lines) > markdownMaxLines {
+			more := len(lines) - markdownMaxLines
+			lines = append(lines[:markdownMaxLines], fmt.Sprintf("… %d more line(s)", more))
+		}
+		body := strings.Join(lines, "\n")
+		fence := "```"
+		for strings.Contains(body, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&sb, "\n%sdiff\n%s\n%s\n", fence, body, fence)
+	}
+	return sb.String()
+}
+
+// prefixLines splits s into lines, each starting with prefix.
+func prefixLines(prefix, s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = prefix + strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
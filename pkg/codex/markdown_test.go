@@ -0,0 +1,37 @@
+package codex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffMarkdown(t *testing.T) {
+	diff := &StructuredDiff{
+		ID:      "d1",
+		AgentID: "coder",
+		Summary: "Fix greeting",
+		Changes: []FileChange{
+			{Op: OpModify, Path: "a.go", OldContent: "hello\n", NewContent: "hi\n", Description: "shorter"},
+			{Op: OpCreate, Path: "logo.png", NewContent: "AAAA", Encoding: EncodingBase64},
+			{Op: OpRename, Path: "old.go", NewPath: "new.go"},
+			{Op: OpCreate, Path: "big.txt", NewContent: strings.Repeat("x\n", markdownMaxLines+5)},
+		},
+	}
+	md := diff.Markdown()
+
+	for _, want := range []string{
+		"### Fix greeting\n",
+		"By coder, 4 change(s).",
+		"**modify** `a.go`\n\nshorter\n\n```diff\n-hello\n+hi\n```\n",
+		"**create** `logo.png`\n\n(binary content)\n",
+		"**rename** `old.go` → `new.go`\n",
+		"… 5 more line(s)\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q in:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "AAAA") {
+		t.Error("binary content rendered")
+	}
+}
@@ -16,8 +16,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // VerifyResult captures the outcome of post-apply verification.
@@ -34,6 +38,35 @@ type VerifyResult struct {
 	Error         string        `json:"error,omitempty"`
 }
 
+// FailedStage names the verification stage that failed ("syntax" or
+// "tests"), or "" if verification passed.
+func (v *VerifyResult) FailedStage() string {
+	switch {
+	case v.SyntaxPassed != nil && !*v.SyntaxPassed:
+		return "syntax"
+	case v.TestsPassed != nil && !*v.TestsPassed:
+		return "tests"
+	}
+	return ""
+}
+
+// failingTestLine matches the per-test failure lines of go test
+// ("--- FAIL: TestX") and pytest ("FAILED path::test").
+var failingTestLine = regexp.MustCompile(`(?m)^\s*(?:--- FAIL: |FAILED )(\S+)`)
+
+// FailingTests returns the names of failed tests found in the test output.
+func (v *VerifyResult) FailingTests() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range failingTestLine.FindAllStringSubmatch(v.TestOutput, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
 // ApprovalLevel describes how critical a diff is and whether it needs human review.
 type ApprovalLevel string
 
@@ -108,12 +141,8 @@ func (p *ApprovalPolicy) EvaluateApproval(diff *StructuredDiff) (ApprovalLevel,
 				// Try matching just the filename
 				matched, _ = filepath.Match(pattern, filepath.Base(change.Path))
 			}
-			if !matched && strings.Contains(pattern, "**") {
-				// Simple ** glob: check if the non-** suffix matches
-				suffix := strings.SplitN(pattern, "**", 2)
-				if len(suffix) == 2 {
-					matched = strings.HasSuffix(change.Path, strings.TrimPrefix(suffix[1], "/"))
-				}
+			if !matched {
+				matched = matchDoubleStar(pattern, change.Path)
 			}
 			if matched {
 				return ApprovalRequired, fmt.Sprintf(
@@ -140,6 +169,66 @@ func (p *ApprovalPolicy) EvaluateApproval(diff *StructuredDiff) (ApprovalLevel,
 	return ApprovalAuto, ""
 }
 
+// matchDoubleStar matches path against a pattern with one "**", which
+// stands for any number of directories: "dir/**" matches everything under
+// dir, and "**/name*" matches name* in any directory. Patterns without "**"
+// never match here.
+func matchDoubleStar(pattern, path string) bool {
+	prefix, rest, ok := strings.Cut(pattern, "**")
+	if !ok || !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" {
+		return true
+	}
+	// rest matches the same number of trailing path segments
+	segs := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	n := strings.Count(rest, "/") + 1
+	if len(segs) < n {
+		return false
+	}
+	matched, _ := filepath.Match(rest, strings.Join(segs[len(segs)-n:], "/"))
+	return matched
+}
+
+// CommandLimits bounds one verification command. Zero fields keep the
+// stage's default.
+type CommandLimits struct {
+	Timeout        time.Duration
+	MaxOutputBytes int
+}
+
+func (l CommandLimits) or(timeout time.Duration, maxOutput int) (time.Duration, int) {
+	if l.Timeout > 0 {
+		timeout = l.Timeout
+	}
+	if l.MaxOutputBytes > 0 {
+		maxOutput = l.MaxOutputBytes
+	}
+	return timeout, maxOutput
+}
+
+// VerifyLimits overrides the limits of the verification stages: syntax
+// check (default 60s, 4096 bytes of output) and tests (300s, 8192 bytes).
+type VerifyLimits struct {
+	Syntax CommandLimits
+	Test   CommandLimits
+}
+
+type verifyLimitsKey struct{}
+
+// WithVerifyLimits returns a copy of ctx under which RunVerification (and
+// so ApplyAndVerify) uses limits for its commands.
+func WithVerifyLimits(ctx context.Context, limits VerifyLimits) context.Context {
+	return context.WithValue(ctx, verifyLimitsKey{}, limits)
+}
+
+func verifyLimitsFrom(ctx context.Context) VerifyLimits {
+	l, _ := ctx.Value(verifyLimitsKey{}).(VerifyLimits)
+	return l
+}
+
 // RunVerification executes the verify spec after a diff has been applied.
 // If verification fails and RollbackOnFailure is true, the rollback function
 // is called to undo changes.
@@ -164,11 +253,14 @@ func RunVerification(
 
 	spec := diff.Verify
 
+	limits := verifyLimitsFrom(ctx)
+
 	// Stage 1: Syntax check
 	if spec.SyntaxCheck != "" {
-		passed, output, err := runCommand(ctx, workspaceRoot, spec.SyntaxCheck, 60*time.Second)
+		timeout, maxOutput := limits.Syntax.or(60*time.Second, 4096)
+		passed, output, err := runCommand(ctx, workspaceRoot, spec.SyntaxCheck, timeout)
 		result.SyntaxPassed = &passed
-		result.SyntaxOutput = truncateOutput(output, 4096)
+		result.SyntaxOutput = truncateOutput(output, maxOutput)
 		if err != nil && !passed {
 			result.Error = fmt.Sprintf("syntax check failed: %s", err)
 			if spec.RollbackOnFailure && rollbackFn != nil {
@@ -185,9 +277,10 @@ func RunVerification(
 
 	// Stage 2: Test command
 	if spec.TestCommand != "" {
-		passed, output, err := runCommand(ctx, workspaceRoot, spec.TestCommand, 300*time.Second)
+		timeout, maxOutput := limits.Test.or(300*time.Second, 8192)
+		passed, output, err := runCommand(ctx, workspaceRoot, spec.TestCommand, timeout)
 		result.TestsPassed = &passed
-		result.TestOutput = truncateOutput(output, 8192)
+		result.TestOutput = truncateOutput(output, maxOutput)
 		if err != nil && !passed {
 			result.Error = fmt.Sprintf("tests failed: %s", err)
 			if spec.RollbackOnFailure && rollbackFn != nil {
@@ -206,8 +299,34 @@ func RunVerification(
 	return result, nil
 }
 
+// workspaceLocks holds one *sync.Mutex per resolved workspace root.
+var workspaceLocks sync.Map
+
+// lockWorkspace takes the apply lock for a workspace and returns its unlock
+// function. The lock is per tree, keyed by the root's absolute, symlink-free
+// path, so two spellings of one directory share a lock while different
+// workspaces never contend.
+func lockWorkspace(root string) func() {
+	key, err := filepath.Abs(root)
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(key); err == nil {
+			key = resolved
+		}
+	} else {
+		key = root
+	}
+	mu, _ := workspaceLocks.LoadOrStore(key, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
 // ApplyAndVerify is the full pipeline: apply → verify → rollback on failure.
 // This is the recommended entry point for automated diff application.
+//
+// Concurrent calls on the same workspace are serialized: the workspace lock
+// is held from the precondition check through verification and any
+// rollback, so one diff never sees another half-applied. Calls on different
+// workspaces run in parallel. Apply on its own does not take the lock.
 func (sd *StructuredDiff) ApplyAndVerify(
 	ctx context.Context,
 	workspaceRoot string,
@@ -232,6 +351,9 @@ func (sd *StructuredDiff) ApplyAndVerify(
 		avr.ApprovalLevel = ApprovalAuto
 	}
 
+	unlock := lockWorkspace(workspaceRoot)
+	defer unlock()
+
 	// Step 2: Check preconditions
 	if err := sd.CheckPreconditions(workspaceRoot); err != nil {
 		avr.Status = "precondition_failed"
@@ -286,11 +408,17 @@ type ApplyVerifyResult struct {
 	DiffID         string         `json:"diff_id"`
 	TaskID         string         `json:"task_id"`
 	AgentID        string         `json:"agent_id"`
-	Status         string         `json:"status"` // success, pending_approval, precondition_failed, apply_failed, verify_failed, rolled_back
+	Status         string         `json:"status"` // success, pending_approval, precondition_failed, apply_failed, verify_failed, rolled_back, merge_failed
 	ApprovalLevel  ApprovalLevel  `json:"approval_level"`
 	ApprovalReason string         `json:"approval_reason,omitempty"`
+	Forced         bool           `json:"forced,omitempty"` // applied despite requiring approval
+	AlreadyApplied bool           `json:"already_applied,omitempty"` // replayed from an earlier apply of this diff ID
 	Apply          *ApplyResult   `json:"apply,omitempty"`
 	Verify         *VerifyResult  `json:"verify,omitempty"`
+	Worktree       string         `json:"worktree,omitempty"` // where it was verified, when not in the workspace itself
+	CommitSHA      string         `json:"commit_sha,omitempty"` // git commit made after success, if enabled
+	Branch         string         `json:"branch,omitempty"`     // branch the commit went on
+	CommitError    string         `json:"commit_error,omitempty"`
 	Error          string         `json:"error,omitempty"`
 }
 
@@ -305,6 +433,9 @@ func runCommand(ctx context.Context, workDir, cmdStr string, timeout time.Durati
 	cmd := exec.CommandContext(cmdCtx, "sh", "-c", cmdStr)
 	cmd.Dir = workDir
 	cmd.Env = append(os.Environ(), "CI=true") // hint to test frameworks
+	// Children of the shell keep the output pipes open after it is killed;
+	// don't let them hold Run past the timeout.
+	cmd.WaitDelay = time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -332,7 +463,10 @@ func runCommand(ctx context.Context, workDir, cmdStr string, timeout time.Durati
 // rollbackChange reverses a single file change.
 // This is used for post-apply rollback (when verification fails).
 func rollbackChange(root string, change FileChange) error {
-	fullPath := filepath.Join(root, change.Path)
+	fullPath, err := utils.ResolveInWorkspace(root, change.Path)
+	if err != nil {
+		return err
+	}
 
 	switch change.Op {
 	case OpCreate:
@@ -341,16 +475,17 @@ func rollbackChange(root string, change FileChange) error {
 
 	case OpModify:
 		// Undo modify → reverse the replacement
-		existing, err := os.ReadFile(fullPath)
+		existing, mode, err := readFileMode(fullPath)
 		if err != nil {
 			return err
 		}
 		content := string(existing)
-		if !strings.Contains(content, change.NewContent) {
+		newContent, oldContent, ok := matchLineEnding(content, change.NewContent, change.OldContent)
+		if !ok {
 			return fmt.Errorf("cannot rollback %s: new_content not found", change.Path)
 		}
-		reverted := strings.Replace(content, change.NewContent, change.OldContent, 1)
-		return os.WriteFile(fullPath, []byte(reverted), 0644)
+		reverted := strings.Replace(content, newContent, oldContent, 1)
+		return writeFileMode(fullPath, []byte(reverted), mode)
 
 	case OpDelete:
 		// Undo delete → recreate (we don't have content, best effort)
@@ -358,24 +493,29 @@ func rollbackChange(root string, change FileChange) error {
 
 	case OpRename:
 		// Undo rename → rename back
-		newPath := filepath.Join(root, change.NewPath)
+		newPath, err := utils.ResolveInWorkspace(root, change.NewPath)
+		if err != nil {
+			return err
+		}
 		return os.Rename(newPath, fullPath)
 
 	case OpInsert:
 		// Undo insert → remove the inserted lines
-		existing, err := os.ReadFile(fullPath)
+		existing, mode, err := readFileMode(fullPath)
 		if err != nil {
 			return err
 		}
 		content := string(existing)
-		if !strings.Contains(content, change.NewContent) {
+		eol := lineEnding(content)
+		inserted := toLineEnding(change.NewContent, eol)
+		if !strings.Contains(content, inserted) {
 			return fmt.Errorf("cannot rollback insert in %s: content not found", change.Path)
 		}
-		reverted := strings.Replace(content, change.NewContent+"\n", "", 1)
+		reverted := strings.Replace(content, inserted+eol, "", 1)
 		if reverted == content {
-			reverted = strings.Replace(content, "\n"+change.NewContent, "", 1)
+			reverted = strings.Replace(content, eol+inserted, "", 1)
 		}
-		return os.WriteFile(fullPath, []byte(reverted), 0644)
+		return writeFileMode(fullPath, []byte(reverted), mode)
 	}
 
 	return nil
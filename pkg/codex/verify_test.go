@@ -0,0 +1,151 @@
+package codex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApplyAndVerifySerializesPerWorkspace(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("base\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// slow is still verifying when fast is started; if fast got in, slow's
+	// check would see b.txt and roll back.
+	slow := &StructuredDiff{
+		ID:      "slow",
+		Changes: []FileChange{{Op: OpModify, Path: "a.txt", OldContent: "base", NewContent: "slow"}},
+		Verify:  &VerifySpec{TestCommand: "sleep 0.5 && test ! -e b.txt", RollbackOnFailure: true},
+	}
+	fast := &StructuredDiff{
+		ID:      "fast",
+		Changes: []FileChange{{Op: OpCreate, Path: "b.txt", NewContent: "fast\n"}},
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*ApplyVerifyResult, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		results[0], _ = slow.ApplyAndVerify(context.Background(), root, nil)
+	}()
+
+	// Wait until slow has written its change and is in verification.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(filepath.Join(root, "a.txt"))
+		if string(data) == "slow\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("slow diff was never applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	go func() {
+		defer wg.Done()
+		results[1], _ = fast.ApplyAndVerify(context.Background(), root, nil)
+	}()
+	wg.Wait()
+
+	for i, r := range results {
+		if r.Status != "success" {
+			t.Errorf("results[%d].Status = %q (%s), want success", i, r.Status, r.Error)
+		}
+	}
+}
+
+func TestApplyAndVerifyOtherWorkspaceNotBlocked(t *testing.T) {
+	busy := t.TempDir()
+	unlock := lockWorkspace(busy)
+	defer unlock()
+
+	done := make(chan *ApplyVerifyResult, 1)
+	go func() {
+		diff := &StructuredDiff{ID: "d", Changes: []FileChange{{Op: OpCreate, Path: "x.txt", NewContent: "x"}}}
+		r, _ := diff.ApplyAndVerify(context.Background(), t.TempDir(), nil)
+		done <- r
+	}()
+	select {
+	case r := <-done:
+		if r.Status != "success" {
+			t.Errorf("Status = %q, want success", r.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("apply to another workspace blocked on an unrelated lock")
+	}
+}
+
+func TestRunVerificationUsesConfiguredLimits(t *testing.T) {
+	diff := &StructuredDiff{ID: "d", Verify: &VerifySpec{
+		SyntaxCheck: "printf '%0100d' 0",
+		TestCommand: "sleep 5",
+	}}
+	ctx := WithVerifyLimits(context.Background(), VerifyLimits{
+		Syntax: CommandLimits{MaxOutputBytes: 10},
+		Test:   CommandLimits{Timeout: 100 * time.Millisecond},
+	})
+
+	start := time.Now()
+	r, err := RunVerification(ctx, diff, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("test command ran past its configured timeout")
+	}
+	if r.TestsPassed == nil || *r.TestsPassed {
+		t.Errorf("TestsPassed = %v, want false after timeout", r.TestsPassed)
+	}
+	if want := "0000000000\n... [truncated]"; r.SyntaxOutput != want {
+		t.Errorf("SyntaxOutput = %q, want %q", r.SyntaxOutput, want)
+	}
+}
+
+func TestEvaluateApprovalCriticalPaths(t *testing.T) {
+	policy := DefaultPolicy()
+	for path, critical := range map[string]bool{
+		"notes.txt":                  false,
+		"pkg/api/server.go":          false,
+		"docs/github.md":             false,
+		".github/workflows/ci.yml":   true,
+		"config/credentials.json":    true,
+		"credentials":                true,
+		"cmd/app/main.go":            true,
+		"main.go":                    true,
+		"app.env":                    true,
+		"deploy/docker-compose.yml":  true,
+		"internal/mainframe/util.go": false,
+	} {
+		diff := &StructuredDiff{Changes: []FileChange{{Op: OpCreate, Path: path, NewContent: "x\n"}}}
+		level, reason := policy.EvaluateApproval(diff)
+		if (level == ApprovalRequired) != critical {
+			t.Errorf("EvaluateApproval(%s) = %s (%s), critical = %v", path, level, reason, critical)
+		}
+	}
+}
+
+func TestMatchDoubleStar(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{".github/**", ".github/workflows/ci.yml", true},
+		{".github/**", "src/.github", false},
+		{"**/main.go", "main.go", true},
+		{"**/main.go", "a/b/main.go", true},
+		{"**/main.go", "a/b/main.go.bak", false},
+		{"src/**/test/*.go", "src/a/b/test/x.go", true},
+		{"src/**/test/*.go", "lib/test/x.go", false},
+		{"*.env", "app.env", false}, // no **; handled by filepath.Match
+	} {
+		if got := matchDoubleStar(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("matchDoubleStar(%q, %q) = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
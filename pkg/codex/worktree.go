@@ -0,0 +1,272 @@
+package codex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Worktrees keeps one git worktree per claimed task under dir, so coding
+// agents working in parallel each apply and verify their diffs in their
+// own checkout instead of on top of each other's uncommitted changes.
+type Worktrees struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewWorktrees creates a worktree manager that keeps its checkouts in dir.
+func NewWorktrees(dir string) *Worktrees {
+	return &Worktrees{dir: dir}
+}
+
+var unsafeWorktreeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Path returns where the worktree for taskID lives, whether or not it
+// exists yet.
+func (w *Worktrees) Path(taskID string) string {
+	return filepath.Join(w.dir, unsafeWorktreeChars.ReplaceAllString(taskID, "_"))
+}
+
+// Ensure returns the worktree for taskID, creating it from the HEAD of
+// the git repository at repoRoot if it doesn't exist. The checkout is
+// detached, so it never moves a branch.
+func (w *Worktrees) Ensure(ctx context.Context, repoRoot, taskID string) (string, error) {
+	if taskID == "" {
+		return "", fmt.Errorf("task ID is required")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path := w.Path(taskID)
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return path, nil
+	}
+	if _, err := runGit(ctx, repoRoot, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", fmt.Errorf("workspace %s is not a git repository: %w", repoRoot, err)
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return "", err
+	}
+	// A leftover directory from a crash would make worktree add fail
+	os.RemoveAll(path)
+	runGit(ctx, repoRoot, "worktree", "prune")
+	if _, err := runGit(ctx, repoRoot, "worktree", "add", "--detach", path, "HEAD"); err != nil {
+		return "", fmt.Errorf("create worktree for %s: %w", taskID, err)
+	}
+	return path, nil
+}
+
+// Remove deletes the worktree for taskID and unregisters it from its
+// repository. Removing a worktree that doesn't exist is not an error; a
+// directory in its place that isn't a git worktree is left alone.
+func (w *Worktrees) Remove(ctx context.Context, taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path := w.Path(taskID)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	commonDir, ok := worktreeCommonDir(path)
+	if !ok {
+		return fmt.Errorf("%s is not a git worktree, leaving it in place", path)
+	}
+	return removeWorktree(ctx, commonDir, path)
+}
+
+// Prune removes every worktree whose task is not in keep, e.g. those left
+// behind by leases that expired or by a restart. Only registered git
+// worktrees are removed; anything else under the directory is kept.
+func (w *Worktrees) Prune(ctx context.Context, keep map[string]bool) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	kept := make(map[string]bool, len(keep))
+	for taskID := range keep {
+		kept[filepath.Base(w.Path(taskID))] = true
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if !e.IsDir() || kept[e.Name()] {
+			continue
+		}
+		path := filepath.Join(w.dir, e.Name())
+		commonDir, ok := worktreeCommonDir(path)
+		if !ok {
+			continue
+		}
+		if err := removeWorktree(ctx, commonDir, path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// worktreeCommonDir returns the git directory of the repository that path
+// is a linked worktree of. ok is false unless path/.git is a gitfile
+// pointing at an existing <common>/worktrees/<name> entry.
+func worktreeCommonDir(path string) (commonDir string, ok bool) {
+	data, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return "", false
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return "", false
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+	if filepath.Base(filepath.Dir(gitDir)) != "worktrees" {
+		return "", false
+	}
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return filepath.Dir(filepath.Dir(gitDir)), true
+}
+
+func removeWorktree(ctx context.Context, commonDir, path string) error {
+	if _, err := runGit(ctx, "", "--git-dir="+commonDir, "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("remove worktree %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyInWorktree runs the apply → verify pipeline in worktree rather than
+// in workspaceRoot, and only merges the diff into workspaceRoot once it
+// has verified. Before applying, the files the diff touches are copied
+// from workspaceRoot into the worktree so both start from the same
+// content. A diff that verifies but no longer applies to workspaceRoot
+// comes back with status "merge_failed", and the worktree is reset to
+// match workspaceRoot again.
+func (sd *StructuredDiff) ApplyInWorktree(
+	ctx context.Context,
+	workspaceRoot, worktree string,
+	policy *ApprovalPolicy,
+) (*ApplyVerifyResult, error) {
+	if policy != nil {
+		if level, _ := policy.EvaluateApproval(sd); level == ApprovalRequired {
+			return sd.ApplyAndVerify(ctx, worktree, policy)
+		}
+	}
+
+	if err := sd.syncTouched(workspaceRoot, worktree); err != nil {
+		err = fmt.Errorf("sync worktree: %w", err)
+		return &ApplyVerifyResult{
+			DiffID:   sd.ID,
+			TaskID:   sd.TaskID,
+			AgentID:  sd.AgentID,
+			Status:   "apply_failed",
+			Worktree: worktree,
+			Error:    err.Error(),
+		}, err
+	}
+	avr, err := sd.ApplyAndVerify(ctx, worktree, policy)
+	avr.Worktree = worktree
+	if err != nil || avr.Status != "success" {
+		return avr, err
+	}
+
+	unlock := lockWorkspace(workspaceRoot)
+	defer unlock()
+	merge := func() error {
+		if err := sd.CheckPreconditions(workspaceRoot); err != nil {
+			return err
+		}
+		applyResult, err := sd.Apply(workspaceRoot)
+		if err == nil {
+			avr.Apply = applyResult
+		}
+		return err
+	}
+	if err := merge(); err != nil {
+		avr.Status = "merge_failed"
+		avr.Error = err.Error()
+		if syncErr := sd.syncTouched(workspaceRoot, worktree); syncErr != nil {
+			avr.Error += "; reset worktree: " + syncErr.Error()
+		}
+		return avr, err
+	}
+	return avr, nil
+}
+
+// syncTouched copies every file the diff touches from src to dst,
+// removing it from dst if src doesn't have it.
+func (sd *StructuredDiff) syncTouched(src, dst string) error {
+	seen := make(map[string]bool)
+	for _, change := range sd.Changes {
+		for _, p := range []string{change.Path, change.NewPath} {
+			if p == "" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			if err := syncFile(src, dst, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func syncFile(src, dst, rel string) error {
+	from, err := utils.ResolveInWorkspace(src, rel)
+	if err != nil {
+		return err
+	}
+	to, err := utils.ResolveInWorkspace(dst, rel)
+	if err != nil {
+		return err
+	}
+	data, mode, err := readFileMode(from)
+	if os.IsNotExist(err) {
+		if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return writeFileMode(to, data, mode)
+}
+
+// runGit runs git in dir and returns its trimmed stdout.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
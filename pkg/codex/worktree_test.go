@@ -0,0 +1,111 @@
+package codex
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a git repository with one committed file.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "main.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if _, err := runGit(ctx, repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestApplyInWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	wt := NewWorktrees(filepath.Join(t.TempDir(), "worktrees"))
+
+	path, err := wt.Ensure(ctx, repo, "TASK/001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := wt.Ensure(ctx, repo, "TASK/001"); again != path {
+		t.Errorf("second Ensure = %s, want %s", again, path)
+	}
+	if _, err := wt.Ensure(ctx, t.TempDir(), "TASK-002"); err == nil {
+		t.Error("Ensure on a non-git workspace should fail")
+	}
+
+	// Uncommitted work in the workspace is what the diff applies to
+	os.WriteFile(filepath.Join(repo, "main.txt"), []byte("one\ntwo\n"), 0644)
+	ok := &StructuredDiff{ID: "d1", TaskID: "TASK/001", Changes: []FileChange{
+		{Op: OpModify, Path: "main.txt", OldContent: "two", NewContent: "three"},
+	}, Verify: &VerifySpec{SyntaxCheck: "grep -q three main.txt"}}
+	result, err := ok.ApplyInWorktree(ctx, repo, path, nil)
+	if err != nil || result.Status != "success" || result.Worktree != path {
+		t.Fatalf("ApplyInWorktree = %+v, %v", result, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "main.txt")); string(got) != "one\nthree\n" {
+		t.Errorf("workspace main.txt = %q after a verified diff", got)
+	}
+
+	// A diff that fails verification never reaches the workspace
+	bad := &StructuredDiff{ID: "d2", TaskID: "TASK/001", Changes: []FileChange{
+		{Op: OpCreate, Path: "broken.txt", NewContent: "x"},
+	}, Verify: &VerifySpec{SyntaxCheck: "false", RollbackOnFailure: true}}
+	if result, _ := bad.ApplyInWorktree(ctx, repo, path, nil); result.Status != "rolled_back" {
+		t.Errorf("failing verify status = %s", result.Status)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "broken.txt")); !os.IsNotExist(err) {
+		t.Error("unverified file reached the workspace")
+	}
+
+	// Another agent changing the workspace while this diff verifies makes
+	// the merge fail
+	stale := &StructuredDiff{ID: "d3", TaskID: "TASK/001", Changes: []FileChange{
+		{Op: OpModify, Path: "main.txt", OldContent: "three", NewContent: "four"},
+	}, Verify: &VerifySpec{SyntaxCheck: "sed -i s/three/3/ " + filepath.Join(repo, "main.txt")}}
+	result, err = stale.ApplyInWorktree(ctx, repo, path, nil)
+	if err == nil || result.Status != "merge_failed" {
+		t.Errorf("ApplyInWorktree over a changed workspace = %+v, %v", result, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(path, "main.txt")); string(got) != "one\n3\n" {
+		t.Errorf("worktree main.txt = %q, want it reset to the workspace", got)
+	}
+
+	// Ending claims removes worktrees, but nothing else in the directory
+	other, _ := wt.Ensure(ctx, repo, "TASK-003")
+	foreign := filepath.Join(filepath.Dir(path), "notes")
+	os.MkdirAll(foreign, 0755)
+	os.WriteFile(filepath.Join(foreign, ".git"), []byte("gitdir: "+filepath.Join(repo, ".git")+"\n"), 0644)
+	if n, err := wt.Prune(ctx, map[string]bool{"TASK/001": true}); err != nil || n != 1 {
+		t.Errorf("Prune = %d, %v", n, err)
+	}
+	if _, err := os.Stat(other); !os.IsNotExist(err) {
+		t.Error("pruned worktree still exists")
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("Prune removed a directory that isn't a worktree: %v", err)
+	}
+	if err := wt.Remove(ctx, "notes"); err == nil {
+		t.Error("Remove of a directory that isn't a worktree succeeded")
+	}
+	if err := wt.Remove(ctx, "TASK/001"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := runGit(ctx, repo, "worktree", "list", "--porcelain"); strings.Contains(list, path) {
+		t.Errorf("worktree still registered:\n%s", list)
+	}
+}
@@ -60,6 +60,7 @@ type CronService struct {
 	storePath string
 	store     *CronStore
 	onJob     JobHandler
+	onStatus  func() // told when Status may have changed; see SetStatusHandler
 	mu        sync.RWMutex
 	running   bool
 	stopChan  chan struct{}
@@ -97,6 +98,7 @@ func (cs *CronService) Start() error {
 	}
 
 	cs.running = true
+	cs.statusChanged()
 	go cs.runLoop()
 
 	return nil
@@ -114,6 +116,7 @@ func (cs *CronService) Stop() {
 	cs.stopOnce.Do(func() {
 		close(cs.stopChan)
 	})
+	cs.statusChanged()
 }
 
 func (cs *CronService) runLoop() {
@@ -293,6 +296,23 @@ func (cs *CronService) SetOnJob(handler JobHandler) {
 	cs.onJob = handler
 }
 
+// SetStatusHandler registers fn to be called whenever what Status reports
+// may have changed: the service starting or stopping, or a job being added,
+// removed, run or toggled. fn is called with the service's lock held and
+// must not block or call back into the service.
+func (cs *CronService) SetStatusHandler(fn func()) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.onStatus = fn
+}
+
+// statusChanged calls the status handler, if any. Callers hold cs.mu.
+func (cs *CronService) statusChanged() {
+	if cs.onStatus != nil {
+		cs.onStatus()
+	}
+}
+
 func (cs *CronService) loadStore() error {
 	cs.store = &CronStore{
 		Version: 1,
@@ -311,6 +331,9 @@ func (cs *CronService) loadStore() error {
 }
 
 func (cs *CronService) saveStoreUnsafe() error {
+	// Every change to the jobs is saved through here, even if saving fails
+	defer cs.statusChanged()
+
 	dir := filepath.Dir(cs.storePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -361,6 +384,64 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	return &job, nil
 }
 
+// EnsureJob adds a recurring job called name, or updates the schedule and
+// payload of the existing one, keeping its ID and last run. It is for jobs
+// defined in config, which are registered again on every start.
+func (cs *CronService) EnsureJob(name string, schedule CronSchedule, payload CronPayload) (*CronJob, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if job.Name != name {
+			continue
+		}
+		job.Schedule = schedule
+		job.Payload = payload
+		job.UpdatedAtMS = now
+		if job.Enabled {
+			job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
+		}
+		if err := cs.saveStoreUnsafe(); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+
+	job := CronJob{
+		ID:       generateID(),
+		Name:     name,
+		Enabled:  true,
+		Schedule: schedule,
+		Payload:  payload,
+		State: CronJobState{
+			NextRunAtMS: cs.computeNextRun(&schedule, now),
+		},
+		CreatedAtMS: now,
+		UpdatedAtMS: now,
+	}
+	cs.store.Jobs = append(cs.store.Jobs, job)
+	if err := cs.saveStoreUnsafe(); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RemoveJobByName removes every job called name.
+func (cs *CronService) RemoveJobByName(name string) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	removed := false
+	for _, job := range cs.store.Jobs {
+		if job.Name == name && cs.removeJobUnsafe(job.ID) {
+			removed = true
+		}
+	}
+	return removed
+}
+
 func (cs *CronService) RemoveJob(jobID string) bool {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
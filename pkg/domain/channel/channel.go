@@ -121,6 +121,23 @@ func (ch *Channel) RecordMessageReceived() {
 	ch.UpdatedAt = domain.Now()
 }
 
+// Clone returns a deep copy of ch without its pending events.
+func (ch *Channel) Clone() *Channel {
+	c := *ch
+	c.AggregateRoot = domain.AggregateRoot{}
+	c.SetID(ch.ID())
+	if ch.ACL.AllowList != nil {
+		c.ACL.AllowList = append([]string{}, ch.ACL.AllowList...)
+	}
+	if ch.Config.Values != nil {
+		c.Config.Values = make(map[string]interface{}, len(ch.Config.Values))
+		for k, v := range ch.Config.Values {
+			c.Config.Values[k] = v
+		}
+	}
+	return &c
+}
+
 // IsAllowed checks if a sender is permitted by the access control list.
 func (ch *Channel) IsAllowed(senderID string) bool {
 	return ch.ACL.IsAllowed(senderID)
@@ -209,6 +226,10 @@ type ChannelMetrics struct {
 	ErrorCount       int64            `json:"error_count"`
 	LastActivityAt   domain.Timestamp `json:"last_activity_at"`
 	ConnectedSince   domain.Timestamp `json:"connected_since"`
+
+	// Reconnects of a long-lived transport connection, e.g. Slack Socket Mode
+	ReconnectAttempts int64            `json:"reconnect_attempts"`
+	LastReconnectAt   domain.Timestamp `json:"last_reconnect_at"`
 }
 
 // NewChannelMetrics creates zero-value metrics.
@@ -288,19 +309,54 @@ type Transport interface {
 	Connect(ctx context.Context) error
 	// Disconnect tears down the transport connection.
 	Disconnect(ctx context.Context) error
-	// Send delivers a message through the transport.
-	Send(ctx context.Context, msg Message) error
+	// Send delivers a message through the transport and returns the
+	// provider's ID for it (empty if the provider does not expose one).
+	Send(ctx context.Context, msg Message) (string, error)
 	// OnReceive registers a callback for incoming messages.
 	OnReceive(handler func(msg Message))
 	// IsConnected returns the current connection state.
 	IsConnected() bool
 }
 
+// MessageEditor is implemented by transports that can edit a message they
+// previously sent. messageID is the value returned by Transport.Send. The
+// Telegram, Discord and Slack channels implement it for the IDs their
+// SendWithID returns.
+type MessageEditor interface {
+	Edit(ctx context.Context, messageID, content string) error
+}
+
+// MessageDeleter is implemented by transports that can delete a message they
+// previously sent. messageID is the value returned by Transport.Send. The
+// Telegram, Discord and Slack channels implement it as well.
+type MessageDeleter interface {
+	Delete(ctx context.Context, messageID string) error
+}
+
+// TypingIndicator is implemented by transports that can show a "typing…"
+// presence indicator in a chat while a response is being prepared.
+type TypingIndicator interface {
+	Typing(ctx context.Context, chatID string) error
+}
+
+// CanEdit reports whether t supports editing sent messages.
+func CanEdit(t Transport) bool {
+	_, ok := t.(MessageEditor)
+	return ok
+}
+
+// CanDelete reports whether t supports deleting sent messages.
+func CanDelete(t Transport) bool {
+	_, ok := t.(MessageDeleter)
+	return ok
+}
+
 // ---------------------------------------------------------------------------
 // Repository interface — persistence port
 // ---------------------------------------------------------------------------
 
-// Repository defines persistence operations for Channel aggregates.
+// Repository defines persistence operations for Channel aggregates. Found
+// channels are copies; changes take effect through Save or UpdateByName.
 type Repository interface {
 	FindByID(id domain.EntityID) (*Channel, error)
 	FindByName(name string) (*Channel, error)
@@ -308,6 +364,10 @@ type Repository interface {
 	FindEnabled() ([]*Channel, error)
 	FindAll() ([]*Channel, error)
 	Save(ch *Channel) error
+	// UpdateByName applies update to the channel named name and saves it,
+	// serialized against other saves. When none exists, create builds it
+	// first. The saved channel is returned with the events update recorded.
+	UpdateByName(name string, create func() (*Channel, error), update func(*Channel)) (*Channel, error)
 	Delete(id domain.EntityID) error
 }
 
@@ -327,8 +387,15 @@ type Service interface {
 	ConnectChannel(ctx context.Context, id domain.EntityID) error
 	// DisconnectChannel stops the transport.
 	DisconnectChannel(ctx context.Context, id domain.EntityID) error
-	// SendMessage delivers a message through a channel.
-	SendMessage(ctx context.Context, channelID domain.EntityID, chatID, content string) error
+	// SendMessage delivers a message through a channel and returns the
+	// provider message ID.
+	SendMessage(ctx context.Context, channelID domain.EntityID, chatID, content string) (string, error)
+	// EditMessage replaces the content of a previously sent message.
+	EditMessage(ctx context.Context, channelID domain.EntityID, messageID, content string) error
+	// DeleteMessage removes a previously sent message.
+	DeleteMessage(ctx context.Context, channelID domain.EntityID, messageID string) error
+	// Typing shows a typing indicator in a chat; a no-op where unsupported.
+	Typing(ctx context.Context, channelID domain.EntityID, chatID string) error
 	// GetChannel retrieves channel details.
 	GetChannel(id domain.EntityID) (*Channel, error)
 	// ListChannels returns all registered channels.
@@ -401,4 +468,5 @@ const (
 	ErrNotConnected       ChannelError = "channel not connected"
 	ErrNotEnabled         ChannelError = "channel is not enabled"
 	ErrSenderNotAllowed   ChannelError = "sender not in allow list"
+	ErrUnsupported        ChannelError = "operation not supported by transport"
 )
@@ -59,6 +59,9 @@ const (
 	EventProviderResponse    EventType = "provider.response"
 	EventProviderError       EventType = "provider.error"
 
+	// Orchestration context events
+	EventTaskAssignmentArchived EventType = "orchestration.assignment.archived"
+
 	// System-level events
 	EventSystemStartup       EventType = "system.startup"
 	EventSystemShutdown      EventType = "system.shutdown"
@@ -107,6 +110,9 @@ func NewEvent(eventType EventType, aggregateID EntityID, data interface{}) BaseE
 // EventHandler processes a domain event. Handlers should be idempotent.
 type EventHandler func(Event)
 
+// EventFilter selects which events a subscriber receives.
+type EventFilter func(Event) bool
+
 // EventBus dispatches domain events to registered handlers.
 // This is the anti-corruption layer between bounded contexts.
 type EventBus interface {
@@ -116,6 +122,35 @@ type EventBus interface {
 	Subscribe(eventType EventType, handler EventHandler)
 	// SubscribeAll registers a handler that receives every event.
 	SubscribeAll(handler EventHandler)
+	// SubscribeFilter registers a handler that receives events matching filter.
+	SubscribeFilter(filter EventFilter, handler EventHandler)
 	// Close shuts down the event bus.
 	Close()
 }
+
+// MatchTypes returns a filter accepting any of the given event types.
+func MatchTypes(types ...EventType) EventFilter {
+	set := make(map[EventType]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(e Event) bool { return set[e.EventType()] }
+}
+
+// MatchAggregate returns a filter accepting events from one aggregate.
+func MatchAggregate(id EntityID) EventFilter {
+	return func(e Event) bool { return e.AggregateID() == id }
+}
+
+// ---------------------------------------------------------------------------
+// Event store — append-only history for audit and replay
+// ---------------------------------------------------------------------------
+
+// EventStore persists domain events in the order they were published so an
+// aggregate's history can be reconstructed after the fact.
+type EventStore interface {
+	// Append durably records an event. Events are never modified or removed.
+	Append(event Event) error
+	// Replay returns every stored event for an aggregate, oldest first.
+	Replay(aggregateID EntityID) ([]Event, error)
+}
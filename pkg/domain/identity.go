@@ -4,8 +4,8 @@ package domain
 
 import (
 	"crypto/rand"
-	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -14,15 +14,79 @@ import (
 // ---------------------------------------------------------------------------
 
 // EntityID is a typed identifier. All entities use string IDs for portability.
+//
+// New IDs are ULIDs: 26 Crockford base32 characters encoding a 48-bit
+// millisecond timestamp followed by 80 random bits. They sort
+// lexicographically in creation order, so file-backed stores list naturally.
+// IDs minted before the switch are 32-char random hex strings; they remain
+// valid identifiers but carry no timestamp (see CreatedTime).
 type EntityID string
 
-// NewID generates a cryptographically random 16-byte hex identifier.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLen is the encoded length of a ULID.
+const ulidLen = 26
+
+var ulidGen struct {
+	mu     sync.Mutex
+	lastMs uint64
+	last   [10]byte
+}
+
+// NewID generates a time-ordered ULID.
+// IDs generated within the same millisecond are monotonically increasing.
 func NewID() EntityID {
-	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidGen.mu.Lock()
+	var entropy [10]byte
+	if ms <= ulidGen.lastMs {
+		// Same (or earlier, if the clock stepped back) millisecond:
+		// increment the previous entropy so ordering is preserved.
+		ms = ulidGen.lastMs
+		entropy = ulidGen.last
+		for i := len(entropy) - 1; i >= 0; i-- {
+			entropy[i]++
+			if entropy[i] != 0 {
+				break
+			}
+		}
+	} else if _, err := rand.Read(entropy[:]); err != nil {
+		ulidGen.mu.Unlock()
 		panic(fmt.Sprintf("domain: failed to generate ID: %v", err))
 	}
-	return EntityID(hex.EncodeToString(b))
+	ulidGen.lastMs = ms
+	ulidGen.last = entropy
+	ulidGen.mu.Unlock()
+
+	var raw [16]byte
+	for i := 0; i < 6; i++ {
+		raw[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(raw[6:], entropy[:])
+	return EntityID(encodeULID(raw))
+}
+
+// encodeULID renders 128 bits as 26 Crockford base32 characters
+// (the leading character carries only 3 significant bits).
+func encodeULID(raw [16]byte) string {
+	out := make([]byte, ulidLen)
+	// Walk the 130-bit field (2 zero pad bits + 128 data bits) 5 bits at a time.
+	for i := 0; i < ulidLen; i++ {
+		bit := i*5 - 2
+		var v byte
+		for j := 0; j < 5; j++ {
+			b := bit + j
+			if b < 0 {
+				continue
+			}
+			if raw[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1 << (4 - j)
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out)
 }
 
 // String implements fmt.Stringer.
@@ -31,6 +95,49 @@ func (id EntityID) String() string { return string(id) }
 // IsZero returns true if the ID is empty.
 func (id EntityID) IsZero() bool { return id == "" }
 
+// IsULID reports whether the ID is a well-formed ULID (as opposed to a
+// legacy hex ID or a caller-chosen name).
+func (id EntityID) IsULID() bool {
+	_, ok := id.CreatedTime()
+	return ok
+}
+
+// CreatedTime extracts the creation timestamp embedded in a ULID.
+// It returns false for legacy hex IDs and any other non-ULID value.
+func (id EntityID) CreatedTime() (time.Time, bool) {
+	if len(id) != ulidLen {
+		return time.Time{}, false
+	}
+	// The first 10 characters hold the 48-bit timestamp; the leading
+	// character may only use its low 3 bits.
+	var ms uint64
+	for i := 0; i < 10; i++ {
+		v := crockfordValue(id[i])
+		if v < 0 || (i == 0 && v > 7) {
+			return time.Time{}, false
+		}
+		ms = ms<<5 | uint64(v)
+	}
+	for i := 10; i < ulidLen; i++ {
+		if crockfordValue(id[i]) < 0 {
+			return time.Time{}, false
+		}
+	}
+	return time.UnixMilli(int64(ms)).UTC(), true
+}
+
+func crockfordValue(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
 // ---------------------------------------------------------------------------
 // Timestamp value object
 // ---------------------------------------------------------------------------
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewIDIsTimeOrdered(t *testing.T) {
+	prev := NewID()
+	for i := 0; i < 1000; i++ {
+		id := NewID()
+		if len(id) != ulidLen {
+			t.Fatalf("len(%q) = %d, want %d", id, len(id), ulidLen)
+		}
+		if id <= prev {
+			t.Fatalf("NewID() = %q, not greater than previous %q", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestCreatedTime(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	id := NewID()
+	after := time.Now()
+
+	got, ok := id.CreatedTime()
+	if !ok {
+		t.Fatalf("CreatedTime(%q) ok = false", id)
+	}
+	if got.Before(before) || got.After(after) {
+		t.Errorf("CreatedTime() = %v, want between %v and %v", got, before, after)
+	}
+}
+
+func TestCreatedTimeLegacyIDs(t *testing.T) {
+	tests := []EntityID{
+		"",
+		"0123456789abcdef0123456789abcdef", // legacy 16-byte hex
+		"telegram",
+		"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", // timestamp overflows 48 bits
+		"01ARZ3NDEKTSV4RRFFQ69G5FA!",
+	}
+	for _, id := range tests {
+		if _, ok := id.CreatedTime(); ok {
+			t.Errorf("CreatedTime(%q) ok = true, want false", id)
+		}
+	}
+}
+
+func TestEncodeULIDKnownValue(t *testing.T) {
+	// From the ULID spec: timestamp 1469918176385 encodes as 01ARYZ6S41.
+	var raw [16]byte
+	ms := uint64(1469918176385)
+	for i := 0; i < 6; i++ {
+		raw[i] = byte(ms >> (40 - 8*i))
+	}
+	if got := encodeULID(raw)[:10]; got != "01ARYZ6S41" {
+		t.Errorf("timestamp prefix = %q, want %q", got, "01ARYZ6S41")
+	}
+}
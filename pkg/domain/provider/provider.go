@@ -89,6 +89,14 @@ func (p *Provider) RecordError(err string) {
 	p.UpdatedAt = domain.Now()
 }
 
+// RecordRetry tracks a failed request that is being retried.
+func (p *Provider) RecordRetry(err string) {
+	p.Metrics.RetryCount++
+	p.Metrics.LastError = err
+	p.Metrics.LastErrorAt = domain.Now()
+	p.UpdatedAt = domain.Now()
+}
+
 // ---------------------------------------------------------------------------
 // Value objects
 // ---------------------------------------------------------------------------
@@ -106,6 +114,7 @@ type ProviderConfig struct {
 type ProviderMetrics struct {
 	RequestCount     int64            `json:"request_count"`
 	ErrorCount       int64            `json:"error_count"`
+	RetryCount       int64            `json:"retry_count"`
 	PromptTokens     int64            `json:"prompt_tokens"`
 	CompletionTokens int64            `json:"completion_tokens"`
 	TotalDurationMS  int64            `json:"total_duration_ms"`
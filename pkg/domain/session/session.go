@@ -137,12 +137,18 @@ func (s *Session) SetSummary(summary string, upToIndex int) {
 	}))
 }
 
-// TruncateHistory keeps only the N most recent messages.
+// TruncateHistory keeps only the N most recent messages. If that would
+// start the history on tool results, it backs off to the assistant message
+// that made the calls, since providers reject results without their call.
 func (s *Session) TruncateHistory(keepLast int) {
 	if len(s.Messages) <= keepLast {
 		return
 	}
-	s.Messages = s.Messages[len(s.Messages)-keepLast:]
+	start := len(s.Messages) - keepLast
+	for start > 0 && start < len(s.Messages) && s.Messages[start].Role == domain.RoleTool {
+		start--
+	}
+	s.Messages = s.Messages[start:]
 	s.UpdatedAt = domain.Now()
 }
 
@@ -158,6 +164,12 @@ func (s *Session) GetHistory() []ConversationMessage {
 	return result
 }
 
+// RecordTokens adds the token usage of one LLM call to the metrics.
+func (s *Session) RecordTokens(n int) {
+	s.Metrics.TokensUsed += int64(n)
+	s.UpdatedAt = domain.Now()
+}
+
 // GetMetrics returns a copy of the session metrics.
 func (s *Session) GetMetrics() SessionMetrics {
 	return s.Metrics
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestTruncateHistoryKeepsToolCallsWithResults(t *testing.T) {
+	newSession := func() *Session {
+		s := NewSession("telegram:1", domain.ChannelTelegram, "1", "")
+		s.AddMessage(domain.RoleUser, "what's in a.txt and b.txt?")
+		s.AddMessage(domain.RoleAssistant, "Let me look.")
+		s.AddAssistantMessageWithTools("", []ToolCallInfo{
+			{ID: "call_1", Name: "read_file"},
+			{ID: "call_2", Name: "read_file"},
+		})
+		s.AddToolMessage("call_1", "read_file", "alpha")
+		s.AddToolMessage("call_2", "read_file", "beta")
+		return s
+	}
+
+	// Cutting inside the trailing tool sequence backs off to the call.
+	for _, keep := range []int{1, 2} {
+		s := newSession()
+		s.TruncateHistory(keep)
+		if len(s.Messages) != 3 || len(s.Messages[0].ToolCalls) != 2 {
+			t.Errorf("TruncateHistory(%d) kept %d messages starting with %+v; want the call and both results",
+				keep, len(s.Messages), s.Messages[0])
+		}
+	}
+
+	// A boundary that doesn't split anything is kept as asked.
+	s := newSession()
+	s.TruncateHistory(4)
+	if len(s.Messages) != 4 || s.Messages[0].Content != "Let me look." {
+		t.Errorf("TruncateHistory(4) kept %+v", s.Messages)
+	}
+	s.TruncateHistory(0)
+	if len(s.Messages) != 0 {
+		t.Errorf("TruncateHistory(0) kept %d messages", len(s.Messages))
+	}
+}
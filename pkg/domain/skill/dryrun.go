@@ -0,0 +1,116 @@
+package skill
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// templateVar matches a {{name}} placeholder in a command template.
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// DryRunResult describes what a skill would run with the given inputs.
+// Nothing is executed to produce it.
+type DryRunResult struct {
+	Skill   string                 `json:"skill"`
+	Valid   bool                   `json:"valid"`
+	Command string                 `json:"command,omitempty"`
+	Inputs  map[string]interface{} `json:"inputs"`
+	// Problems lists every input that failed validation.
+	Problems []string `json:"problems,omitempty"`
+	// Unresolved lists placeholders in the command with no value.
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// DryRun validates inputs against the skill's spec, fills in declared
+// defaults, and returns the command template with every placeholder
+// substituted. It never runs anything.
+func (s *Skill) DryRun(inputs map[string]interface{}) *DryRunResult {
+	res := &DryRunResult{
+		Skill:  s.Name,
+		Inputs: make(map[string]interface{}, len(inputs)),
+	}
+
+	declared := make(map[string]bool, len(s.Spec.Inputs))
+	for _, p := range s.Spec.Inputs {
+		declared[p.Name] = true
+		v, ok := inputs[p.Name]
+		if !ok || v == nil {
+			if p.Default != nil {
+				res.Inputs[p.Name] = p.Default
+			} else if p.Required {
+				res.Problems = append(res.Problems, fmt.Sprintf("missing required input %q", p.Name))
+			}
+			continue
+		}
+		if err := checkParamType(p, v); err != nil {
+			res.Problems = append(res.Problems, err.Error())
+		}
+		res.Inputs[p.Name] = v
+	}
+
+	var unknown []string
+	for name, v := range inputs {
+		if !declared[name] {
+			unknown = append(unknown, name)
+			res.Inputs[name] = v
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		res.Problems = append(res.Problems, fmt.Sprintf("unknown input %q", name))
+	}
+
+	unresolved := map[string]bool{}
+	res.Command = templateVar.ReplaceAllStringFunc(s.Spec.Command, func(m string) string {
+		name := templateVar.FindStringSubmatch(m)[1]
+		v, ok := res.Inputs[name]
+		if !ok {
+			unresolved[name] = true
+			return m
+		}
+		return fmt.Sprint(v)
+	})
+	for name := range unresolved {
+		res.Unresolved = append(res.Unresolved, name)
+	}
+	sort.Strings(res.Unresolved)
+
+	res.Valid = len(res.Problems) == 0 && len(res.Unresolved) == 0
+	return res
+}
+
+// checkParamType reports whether v fits the declared type of p. Values are
+// expected as decoded from JSON, so numbers arrive as float64.
+func checkParamType(p SkillParam, v interface{}) error {
+	ok := true
+	switch strings.ToLower(p.Type) {
+	case "", "json":
+	case "string", "file":
+		_, ok = v.(string)
+	case "bool":
+		_, ok = v.(bool)
+	case "float":
+		switch v.(type) {
+		case float64, float32, int, int64:
+		default:
+			ok = false
+		}
+	case "int":
+		switch n := v.(type) {
+		case int, int64:
+		case float64:
+			ok = n == math.Trunc(n)
+		default:
+			ok = false
+		}
+	default:
+		return fmt.Errorf("input %q has unknown type %q", p.Name, p.Type)
+	}
+	if !ok {
+		return fmt.Errorf("input %q must be %s, got %T", p.Name, p.Type, v)
+	}
+	return nil
+}
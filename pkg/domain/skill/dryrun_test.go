@@ -0,0 +1,39 @@
+package skill
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestDryRun(t *testing.T) {
+	sk := NewSkill("fetch", "1.0.0", "", CategoryResearch, domain.SkillSourceWorkspace)
+	sk.Spec = SkillSpec{
+		Command: "python fetch.py {{url}} --depth {{ depth }} --out {{out}}",
+		Inputs: []SkillParam{
+			{Name: "url", Type: "string", Required: true},
+			{Name: "depth", Type: "int", Default: 2},
+			{Name: "verbose", Type: "bool"},
+		},
+	}
+
+	res := sk.DryRun(map[string]interface{}{"url": "https://example.com"})
+	if res.Command != "python fetch.py https://example.com --depth 2 --out {{out}}" {
+		t.Errorf("Command = %q", res.Command)
+	}
+	if res.Valid || !reflect.DeepEqual(res.Unresolved, []string{"out"}) {
+		t.Errorf("Valid = %v, Unresolved = %v", res.Valid, res.Unresolved)
+	}
+
+	res = sk.DryRun(map[string]interface{}{"depth": 1.5, "verbose": "yes", "extra": 1})
+	want := []string{
+		`missing required input "url"`,
+		`input "depth" must be int, got float64`,
+		`input "verbose" must be bool, got string`,
+		`unknown input "extra"`,
+	}
+	if !reflect.DeepEqual(res.Problems, want) {
+		t.Errorf("Problems = %q, want %q", res.Problems, want)
+	}
+}
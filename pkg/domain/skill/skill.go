@@ -36,6 +36,8 @@ type Skill struct {
 
 	// Metrics
 	Metrics SkillMetrics `json:"metrics"`
+	// History holds the most recent runs, newest last, capped at MaxHistory.
+	History []SkillExecution `json:"history,omitempty"`
 
 	// Dependencies — skills this skill requires
 	Dependencies []SkillDependency `json:"dependencies,omitempty"`
@@ -120,6 +122,23 @@ func (s *Skill) RecordError(errMsg string) {
 	s.Metrics.LastErrorAt = domain.Now()
 }
 
+// RecordRun tracks one execution, successful or not, in both the metrics
+// and the bounded run history.
+func (s *Skill) RecordRun(run SkillExecution) {
+	if run.ExecutedAt.IsZero() {
+		run.ExecutedAt = domain.Now()
+	}
+	if run.Success {
+		s.RecordExecution(run.DurationMS)
+	} else {
+		s.RecordError(run.Error)
+	}
+	s.History = append(s.History, run)
+	if over := len(s.History) - MaxHistory; over > 0 {
+		s.History = append([]SkillExecution(nil), s.History[over:]...)
+	}
+}
+
 // HasDependency returns true if this skill depends on another.
 func (s *Skill) HasDependency(skillName string) bool {
 	for _, dep := range s.Dependencies {
@@ -205,6 +224,18 @@ type SkillMetrics struct {
 	LastErrorAt     domain.Timestamp `json:"last_error_at"`
 }
 
+// MaxHistory is how many recent runs a skill keeps.
+const MaxHistory = 50
+
+// SkillExecution is one recorded run of a skill.
+type SkillExecution struct {
+	ExecutedAt domain.Timestamp       `json:"executed_at"`
+	DurationMS int64                  `json:"duration_ms"`
+	Success    bool                   `json:"success"`
+	Error      string                 `json:"error,omitempty"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+}
+
 // NewSkillMetrics creates zero-value metrics.
 func NewSkillMetrics() SkillMetrics {
 	return SkillMetrics{}
@@ -84,6 +84,8 @@ const (
 	DiffApplied    = "diff.applied"
 	DiffRolledBack = "diff.rolled_back"
 	DiffVerified   = "diff.verified"
+	DiffPending    = "diff.pending_approval"
+	DiffFailed     = "diff.failed"
 
 	// Workflow / IDE monitor events (Antigravity + Copilot)
 	WorkflowAntigravityTaskCreated   = "antigravity.task.created"
@@ -3,32 +3,47 @@
 package eventbus
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/sipeed/picoclaw/pkg/domain"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
-// InProcessEventBus is a synchronous in-process event bus.
-// It dispatches events to registered handlers immediately on Publish().
-// For production, this can be swapped for an async/distributed implementation
-// (NATS, Redis Streams, etc.) behind the same domain.EventBus interface.
+// subscriberQueueSize bounds how many events can be pending for one
+// subscriber before new events are dropped for it.
+const subscriberQueueSize = 256
+
+// subscription is one registered handler with its own delivery goroutine.
+// Each subscriber receives events in publish order; a slow or panicking
+// handler only affects itself.
+type subscription struct {
+	filter  domain.EventFilter
+	handler domain.EventHandler
+	queue   chan domain.Event
+}
+
+// InProcessEventBus is an asynchronous in-process event bus.
+// Publish never blocks on handlers: matching events are queued to each
+// subscriber and delivered on that subscriber's goroutine, with panics
+// recovered and logged. For production, this can be swapped for a
+// distributed implementation (NATS, Redis Streams, etc.) behind the same
+// domain.EventBus interface.
 type InProcessEventBus struct {
-	handlers    map[domain.EventType][]domain.EventHandler
-	allHandlers []domain.EventHandler
-	mu          sync.RWMutex
-	closed      bool
+	subs    []*subscription
+	dropped atomic.Int64
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	closed  bool
 }
 
 // New creates a new in-process event bus.
 func New() *InProcessEventBus {
-	return &InProcessEventBus{
-		handlers:    make(map[domain.EventType][]domain.EventHandler),
-		allHandlers: make([]domain.EventHandler, 0),
-	}
+	return &InProcessEventBus{}
 }
 
-// Publish dispatches an event to all matching handlers.
-// Handlers for the specific event type are called first, then global handlers.
+// Publish queues an event for every subscriber whose filter matches.
 func (b *InProcessEventBus) Publish(event domain.Event) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -37,41 +52,88 @@ func (b *InProcessEventBus) Publish(event domain.Event) {
 		return
 	}
 
-	// Typed handlers
-	if handlers, ok := b.handlers[event.EventType()]; ok {
-		for _, handler := range handlers {
-			handler(event)
+	for _, sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			b.dropped.Add(1)
+			logger.WarnCF("eventbus", "Subscriber queue full, event dropped", map[string]interface{}{
+				"event_type":   string(event.EventType()),
+				"aggregate_id": string(event.AggregateID()),
+			})
 		}
-	}
-
-	// Global handlers
-	for _, handler := range b.allHandlers {
-		handler(event)
 	}
 }
 
 // Subscribe registers a handler for a specific event type.
 func (b *InProcessEventBus) Subscribe(eventType domain.EventType, handler domain.EventHandler) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	b.handlers[eventType] = append(b.handlers[eventType], handler)
+	b.SubscribeFilter(domain.MatchTypes(eventType), handler)
 }
 
 // SubscribeAll registers a handler that receives every event.
 func (b *InProcessEventBus) SubscribeAll(handler domain.EventHandler) {
+	b.SubscribeFilter(nil, handler)
+}
+
+// SubscribeFilter registers a handler that receives events matching filter.
+// A nil filter matches everything.
+func (b *InProcessEventBus) SubscribeFilter(filter domain.EventFilter, handler domain.EventHandler) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.allHandlers = append(b.allHandlers, handler)
+	if b.closed {
+		return
+	}
+
+	sub := &subscription{
+		filter:  filter,
+		handler: handler,
+		queue:   make(chan domain.Event, subscriberQueueSize),
+	}
+	b.subs = append(b.subs, sub)
+
+	b.wg.Add(1)
+	go b.deliver(sub)
 }
 
-// Close marks the bus as closed. No more events will be dispatched.
+func (b *InProcessEventBus) deliver(sub *subscription) {
+	defer b.wg.Done()
+	for event := range sub.queue {
+		b.invoke(sub, event)
+	}
+}
+
+// invoke runs a handler, isolating the bus from handler panics.
+func (b *InProcessEventBus) invoke(sub *subscription, event domain.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorCF("eventbus", "Event handler panicked", map[string]interface{}{
+				"event_type":   string(event.EventType()),
+				"aggregate_id": string(event.AggregateID()),
+				"panic":        fmt.Sprint(r),
+			})
+		}
+	}()
+	sub.handler(event)
+}
+
+// Close stops accepting events and waits for queued events to be delivered.
 func (b *InProcessEventBus) Close() {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
 	b.closed = true
+	for _, sub := range b.subs {
+		close(sub.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
 }
 
 // PublishAll dispatches multiple events (e.g., from AggregateRoot.PullEvents).
@@ -86,11 +148,13 @@ func (b *InProcessEventBus) HandlerCount() int {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	count := len(b.allHandlers)
-	for _, handlers := range b.handlers {
-		count += len(handlers)
-	}
-	return count
+	return len(b.subs)
+}
+
+// DroppedCount returns how many deliveries were dropped due to full
+// subscriber queues (for diagnostics).
+func (b *InProcessEventBus) DroppedCount() int64 {
+	return b.dropped.Load()
 }
 
 // Verify interface compliance at compile time.
@@ -0,0 +1,76 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestSubscribeReceivesOnlyMatchingType(t *testing.T) {
+	b := New()
+
+	var mu sync.Mutex
+	var got []domain.EventType
+	b.Subscribe(domain.EventSkillInstalled, func(e domain.Event) {
+		mu.Lock()
+		got = append(got, e.EventType())
+		mu.Unlock()
+	})
+
+	b.Publish(domain.NewEvent(domain.EventSkillInstalled, "a", nil))
+	b.Publish(domain.NewEvent(domain.EventSkillError, "a", nil))
+	b.Publish(domain.NewEvent(domain.EventSkillInstalled, "b", nil))
+	b.Close()
+
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2: %v", len(got), got)
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	b := New()
+
+	var got []domain.EntityID
+	b.SubscribeFilter(domain.MatchAggregate("x"), func(e domain.Event) {
+		got = append(got, e.AggregateID())
+	})
+
+	b.Publish(domain.NewEvent(domain.EventChannelError, "x", nil))
+	b.Publish(domain.NewEvent(domain.EventChannelError, "y", nil))
+	b.Publish(domain.NewEvent(domain.EventChannelConnected, "x", nil))
+	b.Close()
+
+	if len(got) != 2 || got[0] != "x" || got[1] != "x" {
+		t.Fatalf("got %v, want [x x]", got)
+	}
+}
+
+func TestPanickingHandlerIsIsolated(t *testing.T) {
+	b := New()
+
+	b.SubscribeAll(func(domain.Event) { panic("boom") })
+
+	count := 0
+	b.SubscribeAll(func(domain.Event) { count++ })
+
+	b.Publish(domain.NewEvent(domain.EventSystemStartup, "sys", nil))
+	b.Publish(domain.NewEvent(domain.EventSystemShutdown, "sys", nil))
+	b.Close()
+
+	if count != 2 {
+		t.Fatalf("healthy subscriber got %d events, want 2", count)
+	}
+}
+
+func TestPublishAfterCloseIsIgnored(t *testing.T) {
+	b := New()
+	called := false
+	b.SubscribeAll(func(domain.Event) { called = true })
+	b.Close()
+
+	b.Publish(domain.NewEvent(domain.EventSystemStartup, "sys", nil))
+	if called {
+		t.Fatal("handler called after Close")
+	}
+}
@@ -0,0 +1,166 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+// ---------------------------------------------------------------------------
+// Event log — append-only JSONL implementation of domain.EventStore
+// ---------------------------------------------------------------------------
+
+// StoredEvent is the on-disk record of a domain event. Seq is assigned on
+// append and gives a total order independent of clock skew.
+type StoredEvent struct {
+	Seq int64 `json:"seq"`
+	domain.BaseEvent
+}
+
+// FileEventStore appends every event as one JSON line to a single log file.
+// Replay scans the file; this is intended for audit/debugging volumes, not
+// as a query engine.
+type FileEventStore struct {
+	path string
+	file *os.File
+	seq  int64
+	mu   sync.Mutex
+}
+
+// NewFileEventStore opens (or creates) the event log at baseDir/events.jsonl.
+func NewFileEventStore(baseDir string) (*FileEventStore, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("create event log dir: %w", err)
+	}
+	path := filepath.Join(baseDir, "events.jsonl")
+
+	s := &FileEventStore{path: path}
+
+	// Resume the sequence from the last record already on disk.
+	err := s.scan(func(ev StoredEvent) {
+		if ev.Seq > s.seq {
+			s.seq = ev.Seq
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	if err := endLine(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.file = f
+	return s, nil
+}
+
+// endLine terminates a truncated final line, so the next record starts on
+// a line of its own instead of being glued to the torn one.
+func endLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return fmt.Errorf("read event log: %w", err)
+	}
+	if last[0] != '\n' {
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("repair event log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Append writes the event to the end of the log.
+func (s *FileEventStore) Append(event domain.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("event log closed")
+	}
+
+	rec := StoredEvent{
+		Seq: s.seq + 1,
+		BaseEvent: domain.BaseEvent{
+			Type:      event.EventType(),
+			Timestamp: event.OccurredAt(),
+			AggID:     event.AggregateID(),
+			EventData: event.Payload(),
+		},
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("append event: %w", err)
+	}
+	s.seq = rec.Seq
+	return nil
+}
+
+// Replay returns all events recorded for aggregateID in append order.
+func (s *FileEventStore) Replay(aggregateID domain.EntityID) ([]domain.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []domain.Event
+	err := s.scan(func(ev StoredEvent) {
+		if ev.AggID == aggregateID {
+			events = append(events, ev)
+		}
+	})
+	return events, err
+}
+
+// Close flushes and closes the log file.
+func (s *FileEventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// scan calls fn for every well-formed record in the log. A truncated final
+// line (from a crash mid-write) is skipped rather than failing the scan.
+func (s *FileEventStore) scan(fn func(StoredEvent)) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open event log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev StoredEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		fn(ev)
+	}
+	return scanner.Err()
+}
+
+// Compile-time verification
+var _ domain.EventStore = (*FileEventStore)(nil)
@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestFileEventStoreReplayOrder(t *testing.T) {
+	s, err := NewFileEventStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, e := range []domain.Event{
+		domain.NewEvent(domain.EventChannelConnected, "ch-1", nil),
+		domain.NewEvent(domain.EventChannelConnected, "ch-2", nil),
+		domain.NewEvent(domain.EventChannelError, "ch-1", map[string]string{"error": "boom"}),
+		domain.NewEvent(domain.EventChannelDisconnected, "ch-1", nil),
+	} {
+		if err := s.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := s.Replay("ch-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.EventType{domain.EventChannelConnected, domain.EventChannelError, domain.EventChannelDisconnected}
+	if len(events) != len(want) {
+		t.Fatalf("Replay(ch-1) = %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.EventType() != want[i] || e.AggregateID() != "ch-1" {
+			t.Errorf("event %d = %s %s, want %s ch-1", i, e.EventType(), e.AggregateID(), want[i])
+		}
+	}
+	if data, _ := events[1].Payload().(map[string]interface{}); data["error"] != "boom" {
+		t.Errorf("payload = %v", events[1].Payload())
+	}
+	if events, _ := s.Replay("nobody"); len(events) != 0 {
+		t.Errorf("Replay(nobody) = %v", events)
+	}
+}
+
+func TestFileEventStoreResumesSequence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileEventStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(domain.NewEvent(domain.EventAgentStarted, "a", nil))
+	s.Append(domain.NewEvent(domain.EventAgentStopped, "a", nil))
+	s.Close()
+	if err := s.Append(domain.NewEvent(domain.EventAgentStarted, "a", nil)); err == nil {
+		t.Error("Append after Close succeeded")
+	}
+
+	s, err = NewFileEventStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Append(domain.NewEvent(domain.EventAgentStarted, "a", nil))
+
+	events, _ := s.Replay("a")
+	if len(events) != 3 {
+		t.Fatalf("Replay after reopen = %d events, want 3", len(events))
+	}
+	for i, e := range events {
+		if seq := e.(StoredEvent).Seq; seq != int64(i+1) {
+			t.Errorf("event %d has seq %d, want %d", i, seq, i+1)
+		}
+	}
+}
+
+func TestFileEventStoreTruncatedLastLine(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileEventStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(domain.NewEvent(domain.EventChannelConnected, "ch", nil))
+	s.Close()
+
+	// A crash mid-write leaves half a record with no newline
+	f, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"type":"channel.err`)
+	f.Close()
+
+	s, err = NewFileEventStore(dir)
+	if err != nil {
+		t.Fatalf("reopen with a torn line: %v", err)
+	}
+	defer s.Close()
+	if err := s.Append(domain.NewEvent(domain.EventChannelError, "ch", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.Replay("ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].EventType() != domain.EventChannelError {
+		t.Fatalf("Replay = %v, want the first record and the one appended after the torn line", events)
+	}
+	if seq := events[1].(StoredEvent).Seq; seq != 2 {
+		t.Errorf("seq after torn line = %d, want 2", seq)
+	}
+}
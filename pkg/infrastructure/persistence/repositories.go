@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/domain"
 	agentdomain "github.com/sipeed/picoclaw/pkg/domain/agent"
@@ -23,9 +25,13 @@ import (
 
 // JSONStore provides generic JSON file-based persistence for any serializable type.
 // It keeps an in-memory cache and persists to disk on every Save/Delete.
+//
+// Items are listed in creation order. For ULID keys the order comes from the
+// ID itself; legacy hex-keyed files fall back to their modification time.
 type JSONStore[T any] struct {
 	baseDir  string
 	items    map[domain.EntityID]*T
+	created  map[domain.EntityID]time.Time
 	mu       sync.RWMutex
 }
 
@@ -35,9 +41,16 @@ func NewJSONStore[T any](baseDir string) *JSONStore[T] {
 	return &JSONStore[T]{
 		baseDir: baseDir,
 		items:   make(map[domain.EntityID]*T),
+		created: make(map[domain.EntityID]time.Time),
 	}
 }
 
+// idSetter is implemented by aggregates (via domain.AggregateRoot), whose
+// identity is not part of their JSON body.
+type idSetter interface {
+	SetID(domain.EntityID)
+}
+
 // Load reads all JSON files from the base directory into memory.
 func (s *JSONStore[T]) Load() error {
 	s.mu.Lock()
@@ -68,7 +81,18 @@ func (s *JSONStore[T]) Load() error {
 
 		// Use filename (without .json) as ID
 		id := domain.EntityID(entry.Name()[:len(entry.Name())-5])
+		if setter, ok := any(&item).(idSetter); ok {
+			setter.SetID(id)
+		}
 		s.items[id] = &item
+
+		created, ok := id.CreatedTime()
+		if !ok {
+			if info, err := entry.Info(); err == nil {
+				created = info.ModTime()
+			}
+		}
+		s.created[id] = created
 	}
 
 	return nil
@@ -89,6 +113,13 @@ func (s *JSONStore[T]) Put(id domain.EntityID, item *T) error {
 	defer s.mu.Unlock()
 
 	s.items[id] = item
+	if _, ok := s.created[id]; !ok {
+		created, ok := id.CreatedTime()
+		if !ok {
+			created = time.Now()
+		}
+		s.created[id] = created
+	}
 
 	data, err := json.MarshalIndent(item, "", "  ")
 	if err != nil {
@@ -109,18 +140,31 @@ func (s *JSONStore[T]) Remove(id domain.EntityID) bool {
 	}
 
 	delete(s.items, id)
+	delete(s.created, id)
 	os.Remove(filepath.Join(s.baseDir, string(id)+".json"))
 	return true
 }
 
-// All returns all items.
+// All returns all items, oldest first.
 func (s *JSONStore[T]) All() []*T {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	result := make([]*T, 0, len(s.items))
-	for _, item := range s.items {
-		result = append(result, item)
+	ids := make([]domain.EntityID, 0, len(s.items))
+	for id := range s.items {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := s.created[ids[i]], s.created[ids[j]]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return ids[i] < ids[j]
+	})
+
+	result := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, s.items[id])
 	}
 	return result
 }
@@ -137,8 +181,10 @@ func (s *JSONStore[T]) Count() int {
 // ---------------------------------------------------------------------------
 
 // ChannelRepository is the filesystem-backed implementation of channel.Repository.
+// It hands out and stores copies, so callers never share an aggregate.
 type ChannelRepository struct {
 	store *JSONStore[channeldomain.Channel]
+	mu    sync.Mutex // serializes Save and UpdateByName
 }
 
 // NewChannelRepository creates a new channel repository.
@@ -153,13 +199,13 @@ func (r *ChannelRepository) FindByID(id domain.EntityID) (*channeldomain.Channel
 	if !ok {
 		return nil, channeldomain.ErrNotFound
 	}
-	return ch, nil
+	return ch.Clone(), nil
 }
 
 func (r *ChannelRepository) FindByName(name string) (*channeldomain.Channel, error) {
 	for _, ch := range r.store.All() {
 		if ch.Name == name {
-			return ch, nil
+			return ch.Clone(), nil
 		}
 	}
 	return nil, channeldomain.ErrNotFound
@@ -169,7 +215,7 @@ func (r *ChannelRepository) FindByType(channelType domain.ChannelType) ([]*chann
 	var result []*channeldomain.Channel
 	for _, ch := range r.store.All() {
 		if ch.Type == channelType {
-			result = append(result, ch)
+			result = append(result, ch.Clone())
 		}
 	}
 	return result, nil
@@ -179,18 +225,43 @@ func (r *ChannelRepository) FindEnabled() ([]*channeldomain.Channel, error) {
 	var result []*channeldomain.Channel
 	for _, ch := range r.store.All() {
 		if ch.Enabled {
-			result = append(result, ch)
+			result = append(result, ch.Clone())
 		}
 	}
 	return result, nil
 }
 
 func (r *ChannelRepository) FindAll() ([]*channeldomain.Channel, error) {
-	return r.store.All(), nil
+	all := r.store.All()
+	result := make([]*channeldomain.Channel, len(all))
+	for i, ch := range all {
+		result[i] = ch.Clone()
+	}
+	return result, nil
 }
 
 func (r *ChannelRepository) Save(ch *channeldomain.Channel) error {
-	return r.store.Put(ch.ID(), ch)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.store.Put(ch.ID(), ch.Clone())
+}
+
+func (r *ChannelRepository) UpdateByName(name string, create func() (*channeldomain.Channel, error), update func(*channeldomain.Channel)) (*channeldomain.Channel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch, err := r.FindByName(name)
+	if err == channeldomain.ErrNotFound {
+		ch, err = create()
+	}
+	if err != nil {
+		return nil, err
+	}
+	update(ch)
+	if err := r.store.Put(ch.ID(), ch.Clone()); err != nil {
+		return nil, err
+	}
+	return ch, nil
 }
 
 func (r *ChannelRepository) Delete(id domain.EntityID) error {
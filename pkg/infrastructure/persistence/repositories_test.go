@@ -0,0 +1,55 @@
+package persistence
+
+import (
+	"sync"
+	"testing"
+
+	channeldomain "github.com/sipeed/picoclaw/pkg/domain/channel"
+)
+
+func TestChannelRepositoryUpdateByName(t *testing.T) {
+	r := NewChannelRepository(t.TempDir())
+	create := func() (*channeldomain.Channel, error) {
+		return channeldomain.Factory{}.CreateChannel("slack", "slack", channeldomain.NewChannelConfig(nil), nil)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.UpdateByName("slack", create, (*channeldomain.Channel).RecordMessageSent); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, _ := r.FindAll()
+	if len(all) != 1 || all[0].Metrics.MessagesSent != 20 {
+		t.Fatalf("after concurrent updates: %d channels, %+v", len(all), all)
+	}
+
+	ch, err := r.UpdateByName("slack", create, (*channeldomain.Channel).MarkConnected)
+	if err != nil || len(ch.PullEvents()) != 1 {
+		t.Errorf("UpdateByName = %v, want the connected event on the returned channel", err)
+	}
+}
+
+func TestChannelRepositoryReturnsCopies(t *testing.T) {
+	r := NewChannelRepository(t.TempDir())
+	ch, _ := channeldomain.Factory{}.CreateChannel("telegram", "telegram", channeldomain.NewChannelConfig(nil), []string{"alice"})
+	if err := r.Save(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	found, _ := r.FindByName("telegram")
+	found.Enable()
+	found.ACL.AllowList[0] = "mallory"
+	ch.Disable()
+
+	stored, _ := r.FindByID(ch.ID())
+	if stored.Enabled || stored.ACL.AllowList[0] != "alice" {
+		t.Errorf("stored channel changed without Save: %+v", stored)
+	}
+}
@@ -0,0 +1,219 @@
+package kanban
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// BackupDir returns the default directory for board backups, next to the DB.
+func (k *KanbanIntegration) BackupDir() string {
+	return filepath.Join(filepath.Dir(k.dbPath), "backups")
+}
+
+// Backup writes a consistent copy of the board database to destPath.
+//
+// It uses VACUUM INTO, which reads the whole database inside a single read
+// transaction, so tasks, transitions, notes, events and kv are captured
+// atomically while the server keeps running. The copy is written to a
+// temporary file and renamed into place so a failed backup never leaves a
+// truncated file at destPath.
+func (k *KanbanIntegration) Backup(ctx context.Context, destPath string) error {
+	if k.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if err := k.fileBacked(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("create backup dir: %w", err)
+	}
+
+	tmp := destPath + ".tmp"
+	os.Remove(tmp) // VACUUM INTO refuses to overwrite
+
+	k.mu.RLock()
+	_, err := k.db.ExecContext(ctx, "VACUUM INTO ?", tmp)
+	k.mu.RUnlock()
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("backup kanban db: %w", err)
+	}
+
+	if err := os.Rename(tmp, destPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("backup kanban db: %w", err)
+	}
+
+	logger.InfoCtx(ctx, "kanban", "Board backed up", map[string]interface{}{
+		"path": destPath,
+	})
+	if k.bus != nil {
+		k.bus.PublishSystem(bus.SystemEvent{
+			Type:      "kanban.backup",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
+			Data:      map[string]interface{}{"path": destPath},
+		})
+	}
+	return nil
+}
+
+// Restore replaces the live board database with the one at srcPath.
+//
+// The source is checked before anything is touched: it must pass SQLite's
+// integrity check, contain a tasks table, and have a schema version no newer
+// than SchemaVersion. The current database is kept alongside as
+// <db>.pre-restore-<timestamp> and is put back if the restored file fails to
+// open.
+func (k *KanbanIntegration) Restore(ctx context.Context, srcPath string) error {
+	if err := k.fileBacked(); err != nil {
+		return err
+	}
+	if err := checkRestoreSource(ctx, srcPath); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.db != nil {
+		if err := k.db.checkpoint(ctx); err != nil {
+			return fmt.Errorf("restore: checkpoint current db: %w", err)
+		}
+		if err := k.db.Close(); err != nil {
+			return fmt.Errorf("restore: close current db: %w", err)
+		}
+		k.db = nil
+	}
+
+	// Stage the copy in the DB directory so the final rename is atomic.
+	staged := k.dbPath + ".restore"
+	if err := copyFile(srcPath, staged); err != nil {
+		os.Remove(staged)
+		return k.reopenAfterFailedRestore(ctx, fmt.Errorf("restore: stage copy: %w", err))
+	}
+
+	saved := fmt.Sprintf("%s.pre-restore-%s", k.dbPath, time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.Rename(k.dbPath, saved); err != nil && !os.IsNotExist(err) {
+		os.Remove(staged)
+		return k.reopenAfterFailedRestore(ctx, fmt.Errorf("restore: move current db aside: %w", err))
+	}
+	os.Remove(k.dbPath + "-wal")
+	os.Remove(k.dbPath + "-shm")
+
+	if err := os.Rename(staged, k.dbPath); err != nil {
+		os.Rename(saved, k.dbPath)
+		return k.reopenAfterFailedRestore(ctx, fmt.Errorf("restore: swap in db: %w", err))
+	}
+
+	db, err := k.openDB(ctx)
+	if err != nil {
+		os.Remove(k.dbPath)
+		os.Rename(saved, k.dbPath)
+		return k.reopenAfterFailedRestore(ctx, fmt.Errorf("restore: open restored db: %w", err))
+	}
+	k.db = db
+
+	logger.InfoCtx(ctx, "kanban", "Board restored", map[string]interface{}{
+		"source":      srcPath,
+		"previous_db": saved,
+	})
+	if k.bus != nil {
+		k.bus.PublishSystem(bus.SystemEvent{
+			Type:      "kanban.restored",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
+			Data:      map[string]interface{}{"source": srcPath, "previous_db": saved},
+		})
+	}
+	return nil
+}
+
+// fileBacked reports an error unless the board is a SQLite file; other
+// backends are backed up with their own tools.
+func (k *KanbanIntegration) fileBacked() error {
+	if _, ok := k.store().(*sqliteBackend); !ok {
+		return fmt.Errorf("backup and restore need the sqlite backend; board is at %s", k.store())
+	}
+	return nil
+}
+
+// reopenAfterFailedRestore reopens whatever is at dbPath so the board stays
+// usable, and returns cause (joined with any reopen error).
+func (k *KanbanIntegration) reopenAfterFailedRestore(ctx context.Context, cause error) error {
+	db, err := k.openDB(ctx)
+	if err != nil {
+		return fmt.Errorf("%w (reopen failed: %v)", cause, err)
+	}
+	k.db = db
+	return cause
+}
+
+// checkRestoreSource validates a candidate backup without modifying it.
+func checkRestoreSource(ctx context.Context, srcPath string) error {
+	if _, err := os.Stat(srcPath); err != nil {
+		return fmt.Errorf("restore source: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("restore source: %w", err)
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return fmt.Errorf("restore source: not a readable sqlite db: %w", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("restore source: integrity check failed: %s", integrity)
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("restore source: read schema version: %w", err)
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("restore source schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+
+	var name string
+	err = db.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'tasks'").Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("restore source: no tasks table")
+	}
+	if err != nil {
+		return fmt.Errorf("restore source: %w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
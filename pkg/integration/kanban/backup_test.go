@@ -0,0 +1,84 @@
+package kanban
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestBoard(t *testing.T) *KanbanIntegration {
+	t.Helper()
+	k := &KanbanIntegration{dbPath: filepath.Join(t.TempDir(), "kanban.db")}
+	if err := k.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(func() { k.Stop(context.Background()) })
+	return k
+}
+
+func TestBackupRestoreRoundtrip(t *testing.T) {
+	ctx := context.Background()
+	k := newTestBoard(t)
+
+	task := &Task{Title: "keep me"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if err := k.AddNote(task.ID, "note", "tester"); err != nil {
+		t.Fatalf("AddNote() error: %v", err)
+	}
+
+	dest := filepath.Join(k.BackupDir(), "snap.db")
+	if err := k.Backup(ctx, dest); err != nil {
+		t.Fatalf("Backup() error: %v", err)
+	}
+
+	if err := k.DeleteTask(task.ID); err != nil {
+		t.Fatalf("DeleteTask() error: %v", err)
+	}
+	if _, err := k.GetTask(task.ID); err == nil {
+		t.Fatal("task still present after delete")
+	}
+
+	if err := k.Restore(ctx, dest); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	got, err := k.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask() after restore error: %v", err)
+	}
+	if got.Title != "keep me" {
+		t.Errorf("Title = %q, want %q", got.Title, "keep me")
+	}
+}
+
+func TestRestoreRejectsNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	k := newTestBoard(t)
+
+	src := filepath.Join(t.TempDir(), "future.db")
+	db, err := sql.Open("sqlite3", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE tasks (id TEXT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	err = k.Restore(ctx, src)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("Restore() error = %v, want schema version error", err)
+	}
+
+	// The live board must be untouched and usable.
+	if err := k.CreateTask(&Task{Title: "still works"}); err != nil {
+		t.Fatalf("CreateTask() after rejected restore error: %v", err)
+	}
+}
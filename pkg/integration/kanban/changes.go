@@ -0,0 +1,112 @@
+package kanban
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Change-feed page sizes: the default when the caller gives none, and the
+// most a single page returns.
+const (
+	DefaultChangesLimit = 200
+	MaxChangesLimit     = 1000
+)
+
+// ErrInvalidCursor is returned by ChangesSince for a malformed cursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TaskChanges is one page of the task change feed.
+type TaskChanges struct {
+	Tasks []*Task `json:"tasks"`
+	// NextCursor is passed back as since to continue from this page. It
+	// is the request's cursor when nothing changed.
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// ChangesSince returns tasks updated after cursor, oldest first.
+func (k *KanbanIntegration) ChangesSince(cursor string, limit int) (*TaskChanges, error) {
+	return k.ChangesSinceCtx(context.Background(), cursor, limit)
+}
+
+// ChangesSinceCtx is ChangesSince bound to ctx; cancelling ctx aborts the query.
+//
+// cursor is either an RFC3339 time (exclusive) or a NextCursor from an
+// earlier page; empty starts from the beginning. Timestamps only have
+// second precision, so pages are keyed on (updated_at, id) and the current
+// second is held back until it is over — otherwise a task updated later
+// in the same second as a poll would be skipped.
+//
+// Deleted tasks are not reported; deletes are hard deletes.
+func (k *KanbanIntegration) ChangesSinceCtx(ctx context.Context, cursor string, limit int) (*TaskChanges, error) {
+	since, afterID, err := parseChangesCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultChangesLimit
+	}
+	if limit > MaxChangesLimit {
+		limit = MaxChangesLimit
+	}
+	settled := time.Now().UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	// updated_at is a UTC RFC3339 string, so it orders lexically. A bare
+	// time has no ID part and excludes its whole second.
+	query := "SELECT * FROM tasks WHERE updated_at > ?"
+	args := []interface{}{since}
+	if afterID != "" {
+		query = "SELECT * FROM tasks WHERE (updated_at > ? OR (updated_at = ? AND id > ?))"
+		args = append(args, since, afterID)
+	}
+	query += " AND updated_at < ? ORDER BY updated_at ASC, id ASC LIMIT ?"
+	args = append(args, settled, limit+1)
+
+	rows, err := k.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := &TaskChanges{Tasks: []*Task{}, NextCursor: cursor}
+	for rows.Next() {
+		task, err := k.scanTaskFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes.Tasks = append(changes.Tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(changes.Tasks) > limit {
+		changes.Tasks = changes.Tasks[:limit]
+		changes.HasMore = true
+	}
+	if n := len(changes.Tasks); n > 0 {
+		last := changes.Tasks[n-1]
+		changes.NextCursor = last.UpdatedAt.UTC().Format(time.RFC3339) + "_" + last.ID
+	}
+	return changes, nil
+}
+
+// parseChangesCursor splits a cursor into its UTC timestamp and the last ID
+// seen at that timestamp.
+func parseChangesCursor(cursor string) (since, afterID string, err error) {
+	if cursor == "" {
+		return "", "", nil
+	}
+	ts, id, _ := strings.Cut(cursor, "_")
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return "", "", fmt.Errorf("%w %q: want an RFC3339 time or a next_cursor", ErrInvalidCursor, cursor)
+	}
+	return t.UTC().Format(time.RFC3339), id, nil
+}
@@ -0,0 +1,69 @@
+package kanban
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangesSincePaging(t *testing.T) {
+	k := newTestBoard(t)
+
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	stamps := []time.Time{base, base, base.Add(time.Minute), base.Add(2 * time.Minute)}
+	var ids []string
+	for _, ts := range stamps {
+		task := &Task{Title: "t"}
+		if err := k.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := k.db.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", ts.Format(time.RFC3339), task.ID); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+	// Updated this second: held back until the second is over.
+	if err := k.CreateTask(&Task{Title: "fresh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	cursor := ""
+	for page := 0; ; page++ {
+		changes, err := k.ChangesSince(cursor, 1)
+		if err != nil {
+			t.Fatalf("ChangesSince(%q) error: %v", cursor, err)
+		}
+		for _, task := range changes.Tasks {
+			got = append(got, task.ID)
+		}
+		cursor = changes.NextCursor
+		if !changes.HasMore {
+			break
+		}
+		if page > 10 {
+			t.Fatal("paging did not terminate")
+		}
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("got %v, want %v", got, ids)
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Fatalf("got %v, want %v", got, ids)
+		}
+	}
+
+	// A bare time is exclusive of its whole second.
+	changes, err := k.ChangesSince(base.Format(time.RFC3339), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Tasks) != 2 || changes.Tasks[0].ID != ids[2] {
+		t.Errorf("since base: got %d tasks", len(changes.Tasks))
+	}
+
+	if _, err := k.ChangesSince("yesterday", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,126 @@
+package kanban
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Board digest sections, in the order they appear in the message.
+const (
+	DigestStats    = "stats"
+	DigestOverdue  = "overdue"
+	DigestDueToday = "due_today"
+)
+
+// DigestSections returns every digest section in message order.
+func DigestSections() []string {
+	return []string{DigestStats, DigestOverdue, DigestDueToday}
+}
+
+// ValidateDigestSections reports the first name that isn't a digest
+// section.
+func ValidateDigestSections(sections []string) error {
+	for _, s := range sections {
+		switch s {
+		case DigestStats, DigestOverdue, DigestDueToday:
+		default:
+			return fmt.Errorf("unknown digest section %q", s)
+		}
+	}
+	return nil
+}
+
+// digestMaxTasks caps each task list in the digest.
+const digestMaxTasks = 10
+
+// Digest composes a short board summary for sending to a chat. sections
+// picks what is included (see DigestSections; empty means all), and now
+// decides what is overdue and, in now's location, what is due today.
+func (k *KanbanIntegration) Digest(sections []string, now time.Time) (string, error) {
+	return k.DigestCtx(context.Background(), sections, now)
+}
+
+// DigestCtx is Digest bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) DigestCtx(ctx context.Context, sections []string, now time.Time) (string, error) {
+	if len(sections) == 0 {
+		sections = DigestSections()
+	}
+	if err := ValidateDigestSections(sections); err != nil {
+		return "", err
+	}
+	want := make(map[string]bool, len(sections))
+	for _, s := range sections {
+		want[s] = true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Board digest — %s\n", now.Format("Mon 2 Jan"))
+
+	if want[DigestStats] {
+		stats, err := k.GetBoardStatsCtx(ctx)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\n%d open, %d done\n", stats["total"]-stats[string(StateDone)], stats[string(StateDone)])
+		var counts []string
+		for _, st := range AllStates() {
+			if st != StateDone && stats[string(st)] > 0 {
+				counts = append(counts, fmt.Sprintf("%s %d", st, stats[string(st)]))
+			}
+		}
+		if len(counts) > 0 {
+			sb.WriteString(strings.Join(counts, " · ") + "\n")
+		}
+	}
+
+	if want[DigestOverdue] || want[DigestDueToday] {
+		tasks, err := k.ListTasksCtx(ctx, TaskFilters{ExcludeDone: true, Limit: 10000})
+		if err != nil {
+			return "", err
+		}
+		y, m, d := now.Date()
+		endOfDay := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+		var overdue, dueToday []*Task
+		for _, t := range tasks {
+			switch {
+			case t.DueDate == nil:
+			case t.DueDate.Before(now):
+				overdue = append(overdue, t)
+			case t.DueDate.Before(endOfDay):
+				dueToday = append(dueToday, t)
+			}
+		}
+		if want[DigestOverdue] {
+			writeDigestTasks(&sb, "Overdue", overdue, now)
+		}
+		if want[DigestDueToday] {
+			writeDigestTasks(&sb, "Due today", dueToday, now)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// writeDigestTasks adds a titled list of tasks, earliest due first.
+func writeDigestTasks(sb *strings.Builder, title string, tasks []*Task, now time.Time) {
+	if len(tasks) == 0 {
+		fmt.Fprintf(sb, "\n%s: none\n", title)
+		return
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].DueDate.Before(*tasks[j].DueDate) })
+	fmt.Fprintf(sb, "\n%s (%d):\n", title, len(tasks))
+	for i, t := range tasks {
+		if i == digestMaxTasks {
+			fmt.Fprintf(sb, "… and %d more\n", len(tasks)-i)
+			break
+		}
+		due := t.DueDate.In(now.Location())
+		when := due.Format("15:04")
+		if due.Before(now) {
+			when = due.Format("2 Jan")
+		}
+		fmt.Fprintf(sb, "- %s %s (%s, due %s)\n", t.ID, t.Title, t.Priority, when)
+	}
+}
@@ -0,0 +1,57 @@
+package kanban
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	k := newTestBoard(t)
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	for _, task := range []*Task{
+		{Title: "late", DueDate: at(-26 * time.Hour)},
+		{Title: "standup notes", DueDate: at(3 * time.Hour)},
+		{Title: "next week", DueDate: at(7 * 24 * time.Hour)},
+		{Title: "someday"},
+		{Title: "shipped", State: StateDone, DueDate: at(-48 * time.Hour)},
+	} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+
+	got, err := k.Digest(nil, now)
+	if err != nil {
+		t.Fatalf("Digest() error: %v", err)
+	}
+	for _, want := range []string{
+		"Board digest — Mon 2 Mar",
+		"4 open, 1 done",
+		"Overdue (1):\n- TASK-001 late (",
+		"due 1 Mar)",
+		"Due today (1):\n- TASK-002 standup notes (",
+		"due 11:00)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Digest() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "next week") || strings.Contains(got, "shipped") {
+		t.Errorf("Digest() lists tasks not due:\n%s", got)
+	}
+
+	got, err = k.Digest([]string{DigestDueToday}, now)
+	if err != nil {
+		t.Fatalf("Digest(due_today) error: %v", err)
+	}
+	if strings.Contains(got, "open,") || strings.Contains(got, "Overdue") || !strings.Contains(got, "Due today (1)") {
+		t.Errorf("Digest(due_today) = %q", got)
+	}
+
+	if _, err := k.Digest([]string{"weather"}, now); err == nil {
+		t.Error("Digest() accepted an unknown section")
+	}
+}
@@ -0,0 +1,243 @@
+package kanban
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAmbiguousDate is wrapped when a due date could mean more than one
+// day or time. The error text says how to be specific.
+var ErrAmbiguousDate = errors.New("ambiguous due date")
+
+var (
+	// A trailing time of day: "3pm", "3:30 pm", "15:00", "noon", optionally
+	// after "at".
+	dueTimeRe = regexp.MustCompile(`^(?:(.*?)\s+)?(?:at\s+)?(noon|\d{1,2}:\d{2}(?:\s*[ap]m)?|\d{1,2}\s*[ap]m)$`)
+	// A bare hour after "at", which needs am/pm unless it's 13 or later.
+	dueHourRe  = regexp.MustCompile(`^(?:(.*?)\s+)?at\s+(\d{1,2})$`)
+	dueInRe    = regexp.MustCompile(`^in\s+(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten)\s+(minute|hour|day|week)s?$`)
+	dueDayRe   = regexp.MustCompile(`^(?:(this|next)\s+)?([a-z]+)$`)
+	dueMonthRe = regexp.MustCompile(`^([a-z]+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?$`)
+	dueDayMoRe = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?\s+([a-z]+)\.?(?:,?\s+(\d{4}))?$`)
+	dueSlashRe = regexp.MustCompile(`^\d{1,2}[/.]\d{1,2}(?:[/.]\d{2,4})?$`)
+)
+
+var dueNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// ParseDueDate resolves a due date against now, whose location is the
+// timezone used for days and times of day. It accepts RFC3339, a bare
+// YYYY-MM-DD, and phrases such as "today", "tomorrow", "day after
+// tomorrow", "friday", "this friday", "next friday", "in 3 days", "in 2
+// hours", "march 5" and "5 march 2027", each optionally followed by a time
+// ("3pm", "at 15:30", "noon"). A day without a time means the end of that
+// day. Phrases that could mean different days, such as "next week" or
+// "3/5", return an ErrAmbiguousDate asking for something specific.
+func ParseDueDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	loc := now.Location()
+	if d, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return endOfDay(d), nil
+	}
+
+	phrase := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	for _, prefix := range []string{"due ", "by ", "on "} {
+		phrase = strings.TrimPrefix(phrase, prefix)
+	}
+	if phrase == "" {
+		return time.Time{}, errors.New("due date is empty")
+	}
+
+	// Split off a time of day.
+	hour, min, hasTime := 0, 0, false
+	if m := dueTimeRe.FindStringSubmatch(phrase); m != nil {
+		var err error
+		if hour, min, err = parseClock(m[2]); err != nil {
+			return time.Time{}, err
+		}
+		phrase, hasTime = m[1], true
+	} else if m := dueHourRe.FindStringSubmatch(phrase); m != nil {
+		h, _ := strconv.Atoi(m[2])
+		if h < 13 || h > 23 {
+			return time.Time{}, fmt.Errorf("%w: is \"at %s\" morning or afternoon? Say %sam or %spm", ErrAmbiguousDate, m[2], m[2], m[2])
+		}
+		phrase, hour, hasTime = m[1], h, true
+	}
+
+	day, exact, err := parseDueDay(phrase, now, hasTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	due := exact
+	if !exact.IsZero() {
+		if hasTime {
+			return time.Time{}, fmt.Errorf("%w: %q gives both a duration and a time; use one", ErrAmbiguousDate, s)
+		}
+	} else if hasTime {
+		due = time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, loc)
+	} else {
+		due = endOfDay(day)
+	}
+	if due.Before(now) {
+		return time.Time{}, fmt.Errorf("due date %q is in the past (%s)", s, due.Format("Mon 2 Jan 15:04"))
+	}
+	return due, nil
+}
+
+// parseDueDay resolves the day part of a due date phrase. It returns
+// either a day (midnight in now's location) or, for "in N hours/minutes",
+// an exact time.
+func parseDueDay(phrase string, now time.Time, hasTime bool) (day, exact time.Time, err error) {
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+
+	switch phrase {
+	case "", "today", "tonight":
+		return today, time.Time{}, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), time.Time{}, nil
+	case "day after tomorrow", "the day after tomorrow":
+		return today.AddDate(0, 0, 2), time.Time{}, nil
+	case "next week", "this week", "end of week", "end of the week", "eow", "weekend", "this weekend",
+		"next month", "this month", "end of month", "end of the month", "eom", "soon", "later":
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q doesn't name a day; give a date or a weekday such as \"next friday\"", ErrAmbiguousDate, phrase)
+	}
+
+	if m := dueInRe.FindStringSubmatch(phrase); m != nil {
+		n, ok := dueNumbers[m[1]]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch m[2] {
+		case "minute":
+			return time.Time{}, now.Add(time.Duration(n) * time.Minute), nil
+		case "hour":
+			return time.Time{}, now.Add(time.Duration(n) * time.Hour), nil
+		case "day":
+			return today.AddDate(0, 0, n), time.Time{}, nil
+		default:
+			return today.AddDate(0, 0, 7*n), time.Time{}, nil
+		}
+	}
+
+	if m := dueDayRe.FindStringSubmatch(phrase); m != nil {
+		if wd, ok := parseWeekday(m[2]); ok {
+			ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+			switch m[1] {
+			case "next":
+				if ahead == 0 {
+					ahead = 7
+				}
+			case "this":
+				// Weeks run Monday to Sunday.
+				if daysSinceMonday(wd) < daysSinceMonday(today.Weekday()) {
+					return time.Time{}, time.Time{}, fmt.Errorf("%w: %s has already passed this week; did you mean \"next %s\"?", ErrAmbiguousDate, m[2], m[2])
+				}
+			default:
+				if ahead == 0 && !hasTime {
+					return time.Time{}, time.Time{}, fmt.Errorf("%w: today is %s; say \"today\" or \"next %s\"", ErrAmbiguousDate, m[2], m[2])
+				}
+			}
+			return today.AddDate(0, 0, ahead), time.Time{}, nil
+		}
+	}
+
+	if dueSlashRe.MatchString(phrase) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q could be day/month or month/day; use YYYY-MM-DD or a month name", ErrAmbiguousDate, phrase)
+	}
+
+	month, dayNum, year := "", "", ""
+	if m := dueMonthRe.FindStringSubmatch(phrase); m != nil {
+		month, dayNum, year = m[1], m[2], m[3]
+	} else if m := dueDayMoRe.FindStringSubmatch(phrase); m != nil {
+		month, dayNum, year = m[2], m[1], m[3]
+	}
+	if mon, ok := parseMonth(month); ok {
+		dn, _ := strconv.Atoi(dayNum)
+		yr := today.Year()
+		if year != "" {
+			yr, _ = strconv.Atoi(year)
+		}
+		date := time.Date(yr, mon, dn, 0, 0, 0, 0, now.Location())
+		if date.Day() != dn {
+			return time.Time{}, time.Time{}, fmt.Errorf("%s %d is not a date", mon, dn)
+		}
+		if year == "" && date.Before(today) {
+			date = date.AddDate(1, 0, 0)
+		}
+		return date, time.Time{}, nil
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("can't read due date %q; use YYYY-MM-DD or words like \"tomorrow\", \"next friday 3pm\" or \"in 3 days\"", phrase)
+}
+
+// parseClock reads "noon", "15:30", "3pm" or "3:30 pm".
+func parseClock(s string) (hour, min int, err error) {
+	if s == "noon" {
+		return 12, 0, nil
+	}
+	s = strings.ReplaceAll(s, " ", "")
+	suffix := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		suffix, s = s[len(s)-2:], s[:len(s)-2]
+	}
+	hh, mm, _ := strings.Cut(s, ":")
+	hour, _ = strconv.Atoi(hh)
+	if mm != "" {
+		min, _ = strconv.Atoi(mm)
+	}
+	switch {
+	case suffix != "" && (hour < 1 || hour > 12):
+		return 0, 0, fmt.Errorf("%q is not a time", s+suffix)
+	case suffix == "pm" && hour != 12:
+		hour += 12
+	case suffix == "am" && hour == 12:
+		hour = 0
+	}
+	if hour > 23 || min > 59 {
+		return 0, 0, fmt.Errorf("%q is not a time", s+suffix)
+	}
+	return hour, min, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if s == name || s == name[:3] {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+func parseMonth(s string) (time.Month, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if strings.HasPrefix(name, s) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+func daysSinceMonday(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+// endOfDay is the last second of t's day.
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 23, 59, 59, 0, t.Location())
+}
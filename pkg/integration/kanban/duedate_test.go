@@ -0,0 +1,64 @@
+package kanban
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDueDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	// Wednesday 4 March 2026, 10:30 in Berlin.
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, berlin)
+	day := func(m time.Month, d, h, min int) time.Time {
+		return time.Date(2026, m, d, h, min, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026-03-10", day(3, 10, 23, 59).Add(59 * time.Second)},
+		{"2026-03-10T09:00:00Z", time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)},
+		{"today 5pm", day(3, 4, 17, 0)},
+		{"tomorrow", day(3, 5, 23, 59).Add(59 * time.Second)},
+		{"Tomorrow at 9:15 am", day(3, 5, 9, 15)},
+		{"by tomorrow noon", day(3, 5, 12, 0)},
+		{"day after tomorrow 14:00", day(3, 6, 14, 0)},
+		{"friday 3pm", day(3, 6, 15, 0)},
+		{"this friday", day(3, 6, 23, 59).Add(59 * time.Second)},
+		{"next fri at 3pm", day(3, 6, 15, 0)},
+		{"next wednesday 9am", day(3, 11, 9, 0)},
+		{"monday at 15", day(3, 9, 15, 0)},
+		{"in 3 days", day(3, 7, 23, 59).Add(59 * time.Second)},
+		{"in 2 hours", now.Add(2 * time.Hour)},
+		{"in a week", day(3, 11, 23, 59).Add(59 * time.Second)},
+		{"March 20 10am", day(3, 20, 10, 0)},
+		{"5th april", day(4, 5, 23, 59).Add(59 * time.Second)},
+		{"due jan 2", time.Date(2027, 1, 2, 23, 59, 59, 0, berlin)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDueDate(tt.in, now)
+		if err != nil {
+			t.Errorf("ParseDueDate(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDueDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"next week", "3/5", "wednesday", "this monday", "friday at 3", "end of month"} {
+		if _, err := ParseDueDate(in, now); !errors.Is(err, ErrAmbiguousDate) {
+			t.Errorf("ParseDueDate(%q) error = %v, want ErrAmbiguousDate", in, err)
+		}
+	}
+	for _, in := range []string{"", "whenever", "today 9am", "february 30", "25pm"} {
+		if _, err := ParseDueDate(in, now); err == nil || errors.Is(err, ErrAmbiguousDate) {
+			t.Errorf("ParseDueDate(%q) error = %v, want a non-ambiguity error", in, err)
+		}
+	}
+}
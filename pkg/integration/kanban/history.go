@@ -0,0 +1,82 @@
+package kanban
+
+import (
+	"context"
+	"time"
+)
+
+// TaskSnapshot is a task as it stood at some past moment. Only State is
+// historical; Title and Category are the task's current values.
+type TaskSnapshot struct {
+	ID        string       `json:"id"`
+	Title     string       `json:"title"`
+	Category  TaskCategory `json:"category"`
+	State     TaskState    `json:"state"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// GetBoardStateAt reconstructs the board as of t by replaying the
+// transition log. Tasks created after t are left out, as are tasks deleted
+// since. A task's state at t is the target of its last transition at or
+// before t; failing that, the origin of its first transition after t;
+// failing that, its current state. State changes made by claiming,
+// releasing, or completing a task aren't logged as transitions, so they
+// are only reflected through the fallbacks.
+func (k *KanbanIntegration) GetBoardStateAt(t time.Time) ([]*TaskSnapshot, error) {
+	return k.GetBoardStateAtCtx(context.Background(), t)
+}
+
+// GetBoardStateAtCtx is GetBoardStateAt bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetBoardStateAtCtx(ctx context.Context, t time.Time) ([]*TaskSnapshot, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	at := t.UTC().Format(time.RFC3339)
+	rows, err := k.db.QueryContext(ctx, `
+		SELECT t.id, t.title, t.category, t.created_at, COALESCE(
+			(SELECT tr.to_state FROM task_transitions tr
+				WHERE tr.task_id = t.id AND tr.timestamp <= ?
+				ORDER BY tr.timestamp DESC, tr.id DESC LIMIT 1),
+			(SELECT tr.from_state FROM task_transitions tr
+				WHERE tr.task_id = t.id AND tr.timestamp > ?
+				ORDER BY tr.timestamp, tr.id LIMIT 1),
+			t.state)
+		FROM tasks t
+		WHERE t.created_at <= ?
+		ORDER BY t.id`, at, at, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snaps []*TaskSnapshot
+	for rows.Next() {
+		var s TaskSnapshot
+		var createdAt string
+		if err := rows.Scan(&s.ID, &s.Title, &s.Category, &createdAt, &s.State); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = parseDBTime(createdAt)
+		snaps = append(snaps, &s)
+	}
+	return snaps, rows.Err()
+}
+
+// GetBoardStatsAt is GetBoardStats as of t, built from GetBoardStateAt.
+func (k *KanbanIntegration) GetBoardStatsAt(t time.Time) (map[string]int, error) {
+	return k.GetBoardStatsAtCtx(context.Background(), t)
+}
+
+// GetBoardStatsAtCtx is GetBoardStatsAt bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetBoardStatsAtCtx(ctx context.Context, t time.Time) (map[string]int, error) {
+	snaps, err := k.GetBoardStateAtCtx(ctx, t)
+	stats := map[string]int{}
+	if err != nil {
+		return stats, err
+	}
+	for _, s := range snaps {
+		stats[string(s.State)]++
+	}
+	stats["total"] = len(snaps)
+	return stats, nil
+}
@@ -0,0 +1,86 @@
+package kanban
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetBoardStateAtReplaysTransitions(t *testing.T) {
+	k := newTestBoard(t)
+
+	day := func(n int) time.Time { return time.Date(2026, 3, n, 12, 0, 0, 0, time.UTC) }
+	setCreated := func(id string, at time.Time) {
+		if _, err := k.db.Exec("UPDATE tasks SET created_at = ? WHERE id = ?", at.Format(time.RFC3339), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved := &Task{Title: "moved"}
+	idle := &Task{Title: "idle"}
+	late := &Task{Title: "late"}
+	for _, task := range []*Task{moved, idle, late} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setCreated(moved.ID, day(1))
+	setCreated(idle.ID, day(1))
+	setCreated(late.ID, day(10))
+
+	if err := k.TransitionTask(moved.ID, StatePlanned, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.TransitionTask(moved.ID, StateRunning, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := k.db.Query("SELECT id FROM task_transitions WHERE task_id = ? ORDER BY id", moved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	for i, at := range []time.Time{day(3), day(6)} {
+		if _, err := k.db.Exec("UPDATE task_transitions SET timestamp = ? WHERE id = ?", at.Format(time.RFC3339), ids[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want map[string]TaskState
+	}{
+		{day(2), map[string]TaskState{moved.ID: StateInbox, idle.ID: StateInbox}},
+		{day(3), map[string]TaskState{moved.ID: StatePlanned, idle.ID: StateInbox}},
+		{day(11), map[string]TaskState{moved.ID: StateRunning, idle.ID: StateInbox, late.ID: StateInbox}},
+	} {
+		snaps, err := k.GetBoardStateAt(tc.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]TaskState{}
+		for _, s := range snaps {
+			got[s.ID] = s.State
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("at %s: got %v, want %v", tc.at.Format(time.DateOnly), got, tc.want)
+		}
+		for id, state := range tc.want {
+			if got[id] != state {
+				t.Errorf("at %s: %s = %s, want %s", tc.at.Format(time.DateOnly), id, got[id], state)
+			}
+		}
+	}
+
+	stats, err := k.GetBoardStatsAt(day(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["total"] != 2 || stats["planned"] != 1 || stats["inbox"] != 1 {
+		t.Errorf("stats at day 4 = %v", stats)
+	}
+}
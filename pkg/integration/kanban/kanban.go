@@ -7,6 +7,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -84,14 +85,26 @@ type Task struct {
 	Category    TaskCategory `json:"category"`
 	Source      TaskSource   `json:"source"`
 	Priority    string       `json:"priority"` // low, normal, high, critical
+	// EffectivePriority is Priority aged by time waiting; see
+	// EffectivePriority(). Filled in by ListTasks, not stored.
+	EffectivePriority float64 `json:"effective_priority"`
 	Tags        []string     `json:"tags"`
 	Assignee    string       `json:"assignee"`
 	Project     string       `json:"project"`
+	// Workspace is the tree this task's diffs apply to. Empty falls back
+	// to the project's workspace, then the global one.
+	Workspace string `json:"workspace,omitempty"`
+	// TokensUsed is the LLM tokens attributed to this task's commits.
+	TokensUsed int64 `json:"tokens_used,omitempty"`
 
 	// Tracking
 	Attempts         int    `json:"attempts"`
 	LastFailureReason string `json:"last_failure_reason"`
 	ExecutionLogURL  string `json:"execution_log_url"`
+	// LogLines and LastLogLine describe the task's execution log (see
+	// AppendLog). Filled in by GetTask, not stored.
+	LogLines    int    `json:"log_lines,omitempty"`
+	LastLogLine string `json:"last_log_line,omitempty"`
 
 	// Ownership — connects to orchestrator lease system
 	ClaimedBy      string     `json:"claimed_by,omitempty"`
@@ -135,11 +148,14 @@ var ValidTransitions = map[TaskState][]TaskState{
 
 // KanbanIntegration is the Go-native task board integration.
 type KanbanIntegration struct {
-	db     *sql.DB
-	dbPath string
-	cfg    *config.Config
-	bus    *bus.MessageBus
-	mu     sync.RWMutex
+	db      *boardDB
+	dbPath  string
+	backend backend // nil means SQLite at dbPath
+	cfg     *config.Config
+	bus     *bus.MessageBus
+	mu      sync.RWMutex
+
+	logSubs taskLogSubscribers // StreamLogs readers
 }
 
 func (k *KanbanIntegration) Name() string {
@@ -155,6 +171,11 @@ func (k *KanbanIntegration) Init(cfg *config.Config, msgBus *bus.MessageBus) err
 	if k.dbPath == "" {
 		k.dbPath = filepath.Join(cfg.WorkspacePath(), "kanban.db")
 	}
+	b, err := newBackend(cfg.Integrations.KanbanDSN, k.dbPath)
+	if err != nil {
+		return err
+	}
+	k.backend = b
 
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(k.dbPath), 0755); err != nil {
@@ -164,25 +185,69 @@ func (k *KanbanIntegration) Init(cfg *config.Config, msgBus *bus.MessageBus) err
 	return nil
 }
 
+// SQLite concurrency settings.
+//
+// SQLite allows a single writer at a time. With several agents hitting the
+// board concurrently, a pool of independent connections just turns into
+// "database is locked" errors, so the pool is pinned to one connection and
+// database/sql queues callers instead. busy_timeout covers the remaining
+// case of an external writer (the Python bots open the same file) by making
+// SQLite wait for the lock rather than failing immediately. WAL keeps readers
+// from blocking the writer; the WAL is auto-checkpointed by SQLite during
+// normal operation and truncated explicitly on Stop.
+const (
+	sqliteBusyTimeoutMs = 5000
+	sqliteMaxOpenConns  = 1
+)
+
 func (k *KanbanIntegration) Start(ctx context.Context) error {
-	db, err := sql.Open("sqlite3", k.dbPath+"?_journal_mode=WAL&_foreign_keys=ON")
+	db, err := k.openDB(ctx)
 	if err != nil {
-		return fmt.Errorf("open kanban db: %w", err)
+		return err
 	}
 	k.db = db
 
-	if err := k.initSchema(); err != nil {
-		return fmt.Errorf("init kanban schema: %w", err)
-	}
-
 	logger.InfoCF("kanban", "Task board started", map[string]interface{}{
-		"db_path": k.dbPath,
+		"db": k.store().String(),
 	})
 	return nil
 }
 
+// store returns the board's backend, SQLite at dbPath unless Init chose
+// another.
+func (k *KanbanIntegration) store() backend {
+	if k.backend == nil {
+		return &sqliteBackend{path: k.dbPath}
+	}
+	return k.backend
+}
+
+// openDB opens the board database with the concurrency settings above and
+// brings its schema up to date.
+func (k *KanbanIntegration) openDB(ctx context.Context) (*boardDB, error) {
+	b := k.store()
+	conn, err := b.open()
+	if err != nil {
+		return nil, fmt.Errorf("open kanban db: %w", err)
+	}
+	db := &boardDB{db: conn, b: b}
+
+	if err := initSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init kanban schema: %w", err)
+	}
+	return db, nil
+}
+
 func (k *KanbanIntegration) Stop(ctx context.Context) error {
 	if k.db != nil {
+		// Fold the WAL back into the main file so the DB is self-contained
+		// after shutdown (and the -wal file doesn't grow across restarts).
+		if err := k.db.checkpoint(ctx); err != nil {
+			logger.WarnCF("kanban", "WAL checkpoint failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
 		return k.db.Close()
 	}
 	return nil
@@ -195,7 +260,12 @@ func (k *KanbanIntegration) Health() error {
 	return k.db.Ping()
 }
 
-func (k *KanbanIntegration) initSchema() error {
+// SchemaVersion is stored in PRAGMA user_version (in system_kv on
+// Postgres). Bump it whenever the
+// schema below changes so Restore can refuse databases from newer builds.
+const SchemaVersion = 6
+
+func initSchema(ctx context.Context, db *boardDB) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS tasks (
 		id TEXT PRIMARY KEY,
@@ -222,7 +292,9 @@ func (k *KanbanIntegration) initSchema() error {
 		last_error TEXT DEFAULT '',
 		created_at TEXT NOT NULL,
 		updated_at TEXT NOT NULL,
-		due_date TEXT
+		due_date TEXT,
+		workspace TEXT DEFAULT '',
+		tokens_used INTEGER DEFAULT 0
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_tasks_claimed ON tasks(claimed_by);
@@ -263,26 +335,89 @@ func (k *KanbanIntegration) initSchema() error {
 		content TEXT NOT NULL,
 		author TEXT DEFAULT '',
 		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		content_type TEXT DEFAULT 'text/plain',
 		FOREIGN KEY (task_id) REFERENCES tasks(id)
 	);
 
+	CREATE TABLE IF NOT EXISTS note_attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		note_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		FOREIGN KEY (note_id) REFERENCES task_notes(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_note_attachments_note ON note_attachments(note_id);
+
+	CREATE TABLE IF NOT EXISTS task_watchers (
+		task_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		PRIMARY KEY (task_id, user_id),
+		FOREIGN KEY (task_id) REFERENCES tasks(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS task_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		line TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (task_id) REFERENCES tasks(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_logs_task ON task_logs(task_id, id);
+
 	CREATE TABLE IF NOT EXISTS system_kv (
 		key TEXT PRIMARY KEY,
 		value TEXT NOT NULL,
 		updated_at TEXT NOT NULL
 	);
 	`
-	_, err := k.db.Exec(schema)
+	if _, err := db.ExecContext(ctx, db.b.ddl(schema)); err != nil {
+		return err
+	}
+	// Columns added after the first release. New columns go last so the
+	// positional scans of SELECT * keep working on upgraded databases.
+	if err := ensureColumn(ctx, db, "tasks", "workspace", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, "tasks", "tokens_used", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(ctx, db, "task_notes", "content_type", "TEXT DEFAULT 'text/plain'"); err != nil {
+		return err
+	}
+	version, err := db.b.schemaVersion(ctx, db.db)
+	if err != nil {
+		return err
+	}
+	if version < SchemaVersion {
+		return db.b.setSchemaVersion(ctx, db.db, SchemaVersion)
+	}
+	return nil
+}
+
+// ensureColumn adds a column to an existing table if it is missing.
+func ensureColumn(ctx context.Context, db *boardDB, table, column, decl string) error {
+	ok, err := db.b.hasColumn(ctx, db.db, table, column)
+	if err != nil || ok {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
 	return err
 }
 
 // CreateTask creates a new task and returns it.
 func (k *KanbanIntegration) CreateTask(task *Task) error {
+	return k.CreateTaskCtx(context.Background(), task)
+}
+
+// CreateTaskCtx is CreateTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) CreateTaskCtx(ctx context.Context, task *Task) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
 	if task.ID == "" {
-		id, err := k.nextID()
+		id, err := k.nextID(ctx)
 		if err != nil {
 			return err
 		}
@@ -307,29 +442,34 @@ func (k *KanbanIntegration) CreateTask(task *Task) error {
 
 	tagsJSON, _ := json.Marshal(task.Tags)
 
-	_, err := k.db.Exec(`
+	_, err := k.db.ExecContext(ctx, `
 		INSERT INTO tasks (id, title, description, state, category, source, priority, tags,
 			assignee, project, attempts, last_failure_reason, execution_log_url,
 			telegram_message_id, vscode_task_id, external_ref,
 			llm_categorized, llm_summary, claimed_by, lease_expires_at, claim_count, last_error,
-			created_at, updated_at, due_date)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			created_at, updated_at, due_date, workspace, tokens_used)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		task.ID, task.Title, task.Description, task.State, task.Category,
 		task.Source, task.Priority, string(tagsJSON),
 		task.Assignee, task.Project, task.Attempts,
 		task.LastFailureReason, task.ExecutionLogURL,
 		task.TelegramMessageID, task.VSCodeTaskID, task.ExternalRef,
-		task.LLMCategorized, task.LLMSummary,
+		dbBool(task.LLMCategorized), task.LLMSummary,
 		task.ClaimedBy, formatOptionalTime(task.LeaseExpiresAt), task.ClaimCount, task.LastError,
 		task.CreatedAt.Format(time.RFC3339), task.UpdatedAt.Format(time.RFC3339),
-		formatOptionalTime(task.DueDate),
+		formatOptionalTime(task.DueDate), task.Workspace, task.TokensUsed,
 	)
 
+	if err == nil && task.Assignee != "" {
+		err = k.addWatcher(ctx, task.ID, task.Assignee)
+	}
+
 	// Publish task.created event to bus
 	if err == nil && k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   "task.created",
-			Source: "kanban",
+			Type:      "task.created",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
 			Data: map[string]interface{}{
 				"task_id":  task.ID,
 				"title":    task.Title,
@@ -344,23 +484,40 @@ func (k *KanbanIntegration) CreateTask(task *Task) error {
 
 // GetTask retrieves a task by ID.
 func (k *KanbanIntegration) GetTask(id string) (*Task, error) {
+	return k.GetTaskCtx(context.Background(), id)
+}
+
+// GetTaskCtx is GetTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetTaskCtx(ctx context.Context, id string) (*Task, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	row := k.db.QueryRow("SELECT * FROM tasks WHERE id = ?", id)
-	return k.scanTask(row)
+	row := k.db.QueryRowContext(ctx, "SELECT * FROM tasks WHERE id = ?", id)
+	task, err := k.scanTask(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.fillLogInfo(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
 }
 
 // GetTaskByExternalRef looks up a task by its external_ref field.
 // Returns nil, nil if no task matches (not an error).
 func (k *KanbanIntegration) GetTaskByExternalRef(ref string) (*Task, error) {
+	return k.GetTaskByExternalRefCtx(context.Background(), ref)
+}
+
+// GetTaskByExternalRefCtx is GetTaskByExternalRef bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetTaskByExternalRefCtx(ctx context.Context, ref string) (*Task, error) {
 	if ref == "" {
 		return nil, nil
 	}
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	row := k.db.QueryRow("SELECT * FROM tasks WHERE external_ref = ?", ref)
+	row := k.db.QueryRowContext(ctx, "SELECT * FROM tasks WHERE external_ref = ?", ref)
 	task, err := k.scanTask(row)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
@@ -373,12 +530,30 @@ func (k *KanbanIntegration) GetTaskByExternalRef(ref string) (*Task, error) {
 
 // ListTasks returns tasks matching the given filters.
 func (k *KanbanIntegration) ListTasks(filters TaskFilters) ([]*Task, error) {
+	return k.ListTasksCtx(context.Background(), filters)
+}
+
+// ListTasksCtx is ListTasks bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ListTasksCtx(ctx context.Context, filters TaskFilters) ([]*Task, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	query := "SELECT * FROM tasks WHERE 1=1"
-	args := []interface{}{}
+	where, args := k.filterConditions(filters)
+	query := "SELECT * FROM tasks WHERE 1=1" + where + " ORDER BY updated_at DESC"
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filters.Limit)
+	} else {
+		query += " LIMIT 500"
+	}
+	return k.queryTasks(ctx, query, args...)
+}
 
+// filterConditions returns the WHERE conditions for filters, each starting
+// with " AND", and their arguments. Limit is left to the caller.
+func (k *KanbanIntegration) filterConditions(filters TaskFilters) (string, []interface{}) {
+	query := ""
+	args := []interface{}{}
 	if filters.State != "" {
 		query += " AND state = ?"
 		args = append(args, string(filters.State))
@@ -387,6 +562,14 @@ func (k *KanbanIntegration) ListTasks(filters TaskFilters) ([]*Task, error) {
 		query += " AND category = ?"
 		args = append(args, string(filters.Category))
 	}
+	if len(filters.Categories) > 0 {
+		placeholders := make([]string, len(filters.Categories))
+		for i, c := range filters.Categories {
+			placeholders[i] = "?"
+			args = append(args, string(c))
+		}
+		query += " AND category IN (" + joinStrings(placeholders, ", ") + ")"
+	}
 	if filters.Source != "" {
 		query += " AND source = ?"
 		args = append(args, string(filters.Source))
@@ -398,27 +581,31 @@ func (k *KanbanIntegration) ListTasks(filters TaskFilters) ([]*Task, error) {
 	if filters.ExcludeDone {
 		query += " AND state != 'done'"
 	}
-
-	query += " ORDER BY updated_at DESC"
-
-	if filters.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", filters.Limit)
-	} else {
-		query += " LIMIT 500"
+	if filters.ClaimableBy != "" {
+		query += " AND (claimed_by IS NULL OR claimed_by = '' OR claimed_by = ? OR lease_expires_at IS NULL OR " + k.db.b.before("lease_expires_at") + ")"
+		args = append(args, filters.ClaimableBy, time.Now().UTC().Format(time.RFC3339))
 	}
+	return query, args
+}
 
-	rows, err := k.db.Query(query, args...)
+// queryTasks runs a SELECT * FROM tasks query and returns its tasks with
+// their effective priority filled in. The caller holds k.mu.
+func (k *KanbanIntegration) queryTasks(ctx context.Context, query string, args ...interface{}) ([]*Task, error) {
+	rows, err := k.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	now := time.Now()
+	aging := k.agingRate()
 	var tasks []*Task
 	for rows.Next() {
 		task, err := k.scanTaskFromRows(rows)
 		if err != nil {
 			continue
 		}
+		task.EffectivePriority = EffectivePriority(task.Priority, task.CreatedAt, now, aging)
 		tasks = append(tasks, task)
 	}
 	return tasks, nil
@@ -426,13 +613,20 @@ func (k *KanbanIntegration) ListTasks(filters TaskFilters) ([]*Task, error) {
 
 // TransitionTask moves a task to a new state if the transition is valid.
 func (k *KanbanIntegration) TransitionTask(id string, newState TaskState, reason, executor string) error {
+	return k.TransitionTaskCtx(context.Background(), id, newState, reason, executor)
+}
+
+// TransitionTaskCtx is TransitionTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) TransitionTaskCtx(ctx context.Context, id string, newState TaskState, reason, executor string) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	row := k.db.QueryRow("SELECT state FROM tasks WHERE id = ?", id)
+	row := k.db.QueryRowContext(ctx, "SELECT state FROM tasks WHERE id = ?", id)
 	var currentState string
-	if err := row.Scan(&currentState); err != nil {
-		return fmt.Errorf("task %s not found: %w", id, err)
+	if err := row.Scan(&currentState); err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("load task %s: %w", id, err)
 	}
 
 	// Validate transition
@@ -445,23 +639,26 @@ func (k *KanbanIntegration) TransitionTask(id string, newState TaskState, reason
 		}
 	}
 	if !valid {
-		return fmt.Errorf("invalid transition: %s → %s", currentState, newState)
+		return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, currentState, newState)
+	}
+	if err := k.checkWIPLimit(ctx, newState); err != nil {
+		return err
 	}
 
 	now := time.Now().UTC()
-	tx, err := k.db.Begin()
+	tx, err := k.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("UPDATE tasks SET state = ?, updated_at = ? WHERE id = ?",
+	_, err = tx.ExecContext(ctx, "UPDATE tasks SET state = ?, updated_at = ? WHERE id = ?",
 		string(newState), now.Format(time.RFC3339), id)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec(`INSERT INTO task_transitions (task_id, from_state, to_state, reason, executor, timestamp)
+	_, err = tx.ExecContext(ctx, `INSERT INTO task_transitions (task_id, from_state, to_state, reason, executor, timestamp)
 		VALUES (?, ?, ?, ?, ?, ?)`,
 		id, currentState, string(newState), reason, executor, now.Format(time.RFC3339))
 	if err != nil {
@@ -473,6 +670,12 @@ func (k *KanbanIntegration) TransitionTask(id string, newState TaskState, reason
 		return err
 	}
 
+	change := fmt.Sprintf("%s → %s", currentState, newState)
+	if reason != "" {
+		change += " (" + reason + ")"
+	}
+	k.notifyWatchers(ctx, id, executor, change, nil)
+
 	// Publish state transition event
 	if k.bus != nil {
 		eventType := "task.updated"
@@ -482,8 +685,9 @@ func (k *KanbanIntegration) TransitionTask(id string, newState TaskState, reason
 			eventType = "task.failed"
 		}
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   eventType,
-			Source: "kanban",
+			Type:      eventType,
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
 			Data: map[string]interface{}{
 				"task_id":    id,
 				"from_state": currentState,
@@ -498,6 +702,11 @@ func (k *KanbanIntegration) TransitionTask(id string, newState TaskState, reason
 
 // UpdateTask updates a task's mutable fields.
 func (k *KanbanIntegration) UpdateTask(id string, updates map[string]interface{}) error {
+	return k.UpdateTaskCtx(context.Background(), id, updates)
+}
+
+// UpdateTaskCtx is UpdateTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) UpdateTaskCtx(ctx context.Context, id string, updates map[string]interface{}) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
@@ -508,6 +717,7 @@ func (k *KanbanIntegration) UpdateTask(id string, updates map[string]interface{}
 		"llm_categorized": true, "external_ref": true,
 		"claimed_by": true, "lease_expires_at": true, "claim_count": true,
 		"last_error": true, "last_failure_reason": true,
+		"workspace": true,
 	}
 
 	setClauses := []string{}
@@ -522,6 +732,16 @@ func (k *KanbanIntegration) UpdateTask(id string, updates map[string]interface{}
 				val = string(j)
 			}
 		}
+		if field == "lease_expires_at" {
+			lease, err := normalizeLease(val)
+			if err != nil {
+				return err
+			}
+			val = lease
+		}
+		if b, ok := val.(bool); ok {
+			val = dbBool(b)
+		}
 		setClauses = append(setClauses, field+" = ?")
 		args = append(args, val)
 	}
@@ -535,54 +755,151 @@ func (k *KanbanIntegration) UpdateTask(id string, updates map[string]interface{}
 	args = append(args, id)
 
 	query := "UPDATE tasks SET " + joinStrings(setClauses, ", ") + " WHERE id = ?"
-	_, err := k.db.Exec(query, args...)
-	return err
+	if _, err := k.db.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	// A new assignee starts watching the task.
+	if assignee, ok := updates["assignee"].(string); ok && assignee != "" {
+		return k.addWatcher(ctx, id, assignee)
+	}
+	return nil
 }
 
-// DeleteTask removes a task and its transitions.
+// normalizeLease turns a lease_expires_at update into the stored form: a
+// UTC RFC3339 string, or NULL for an empty value.
+func normalizeLease(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339), nil
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		return v.UTC().Format(time.RFC3339), nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an RFC 3339 time", ErrInvalidLease, v)
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	return nil, fmt.Errorf("%w: unexpected %T", ErrInvalidLease, val)
+}
+
+// DeleteTask removes a task and everything that references it.
+// Child rows are deleted before the parent so the foreign key constraints
+// hold at every step; any failure rolls back the whole delete.
 func (k *KanbanIntegration) DeleteTask(id string) error {
+	return k.DeleteTaskCtx(context.Background(), id)
+}
+
+// DeleteTaskCtx is DeleteTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) DeleteTaskCtx(ctx context.Context, id string) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	tx, err := k.db.Begin()
+	tx, err := k.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	tx.Exec("DELETE FROM task_transitions WHERE task_id = ?", id)
-	tx.Exec("DELETE FROM task_notes WHERE task_id = ?", id)
-	tx.Exec("DELETE FROM task_events WHERE task_id = ?", id)
-	tx.Exec("DELETE FROM tasks WHERE id = ?", id)
+	stmts := []struct {
+		table string
+		query string
+	}{
+		{"task_transitions", "DELETE FROM task_transitions WHERE task_id = ?"},
+		{"note_attachments", "DELETE FROM note_attachments WHERE note_id IN (SELECT id FROM task_notes WHERE task_id = ?)"},
+		{"task_notes", "DELETE FROM task_notes WHERE task_id = ?"},
+		{"task_events", "DELETE FROM task_events WHERE task_id = ?"},
+		{"task_watchers", "DELETE FROM task_watchers WHERE task_id = ?"},
+		{"task_logs", "DELETE FROM task_logs WHERE task_id = ?"},
+		{"tasks", "DELETE FROM tasks WHERE id = ?"},
+	}
+	for _, st := range stmts {
+		if _, err := tx.ExecContext(ctx, st.query, id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("delete task %s: %s: %w", id, st.table, err)
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return fmt.Errorf("delete task %s: commit: %w", id, err)
 	}
 
 	if k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   "task.deleted",
-			Source: "kanban",
-			Data:   map[string]interface{}{"task_id": id},
+			Type:      "task.deleted",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
+			Data:      map[string]interface{}{"task_id": id},
 		})
 	}
 	return nil
 }
 
+// ErrTaskNotFound is wrapped by errors about a task ID that doesn't exist.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrInvalidTransition is wrapped when a state change isn't allowed by
+// ValidTransitions.
+var ErrInvalidTransition = errors.New("invalid transition")
+
+// ErrNotClaimHolder is wrapped when an agent acts on a task it hasn't claimed.
+var ErrNotClaimHolder = errors.New("task is not claimed by this agent")
+
+// ErrInvalidLease is wrapped when an update sets lease_expires_at to
+// something that isn't a time.
+var ErrInvalidLease = errors.New("invalid lease_expires_at")
+
+// ClaimConflictError is returned by ClaimTask when another agent holds an
+// active lease on the task.
+type ClaimConflictError struct {
+	TaskID    string
+	ClaimedBy string
+	ExpiresAt time.Time
+}
+
+func (e *ClaimConflictError) Error() string {
+	return fmt.Sprintf("task %s already claimed by %s (expires %s)",
+		e.TaskID, e.ClaimedBy, e.ExpiresAt.Format(time.RFC3339))
+}
+
 // ClaimTask marks a task as claimed by an agent with a lease expiry.
-// Returns error if already claimed by someone else with an active lease.
+// Returns a *ClaimConflictError if already claimed by someone else with an
+// active lease.
+//
+// The claim is a single conditional UPDATE that only matches while the
+// task is free, already held by agentID, or its lease has expired, so two
+// picoclaw instances sharing a database can't both win the same task: the
+// loser's UPDATE affects no rows and it gets the conflict error.
 func (k *KanbanIntegration) ClaimTask(taskID, agentID string, leaseDuration time.Duration) error {
+	return k.ClaimTaskCtx(context.Background(), taskID, agentID, leaseDuration)
+}
+
+// ClaimTaskCtx is ClaimTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ClaimTaskCtx(ctx context.Context, taskID, agentID string, leaseDuration time.Duration) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
 	now := time.Now().UTC()
 
 	// Check current claim
+	var state string
 	var claimedBy sql.NullString
 	var leaseExpires sql.NullString
-	err := k.db.QueryRow("SELECT claimed_by, lease_expires_at FROM tasks WHERE id = ?", taskID).
-		Scan(&claimedBy, &leaseExpires)
+	err := k.db.QueryRowContext(ctx, "SELECT state, claimed_by, lease_expires_at FROM tasks WHERE id = ?", taskID).
+		Scan(&state, &claimedBy, &leaseExpires)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
 	if err != nil {
-		return fmt.Errorf("task %s not found: %w", taskID, err)
+		return fmt.Errorf("load task %s: %w", taskID, err)
 	}
 
 	// If claimed by someone else and lease hasn't expired, reject
@@ -590,24 +907,39 @@ func (k *KanbanIntegration) ClaimTask(taskID, agentID string, leaseDuration time
 		if leaseExpires.Valid {
 			expiry, _ := time.Parse(time.RFC3339, leaseExpires.String)
 			if now.Before(expiry) {
-				return fmt.Errorf("task %s already claimed by %s (expires %s)",
-					taskID, claimedBy.String, expiry.Format(time.RFC3339))
+				return &ClaimConflictError{TaskID: taskID, ClaimedBy: claimedBy.String, ExpiresAt: expiry}
 			}
 		}
 	}
 
+	// Claiming moves the task to running, so it counts against that limit.
+	if TaskState(state) != StateRunning {
+		if err := k.checkWIPLimit(ctx, StateRunning); err != nil {
+			return err
+		}
+	}
+
 	expiresAt := now.Add(leaseDuration)
-	_, err = k.db.Exec(`UPDATE tasks SET claimed_by = ?, lease_expires_at = ?,
-		claim_count = claim_count + 1, state = 'running', updated_at = ? WHERE id = ?`,
-		agentID, expiresAt.Format(time.RFC3339), now.Format(time.RFC3339), taskID)
+	res, err := k.db.ExecContext(ctx, `UPDATE tasks SET claimed_by = ?, lease_expires_at = ?,
+		claim_count = claim_count + 1, state = 'running', updated_at = ?
+		WHERE id = ? AND (claimed_by IS NULL OR claimed_by = '' OR claimed_by = ?
+			OR lease_expires_at IS NULL OR `+k.db.b.before("lease_expires_at")+`)`,
+		agentID, expiresAt.Format(time.RFC3339), now.Format(time.RFC3339),
+		taskID, agentID, now.Format(time.RFC3339))
 	if err != nil {
 		return err
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return k.claimConflict(ctx, taskID)
+	}
 
 	if k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   "task.claimed",
-			Source: "kanban",
+			Type:      "task.claimed",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
 			Data: map[string]interface{}{
 				"task_id":    taskID,
 				"claimed_by": agentID,
@@ -618,8 +950,29 @@ func (k *KanbanIntegration) ClaimTask(taskID, agentID string, leaseDuration time
 	return nil
 }
 
+// claimConflict explains a claim that matched no rows: the task was
+// deleted, or another process claimed it after it was read.
+func (k *KanbanIntegration) claimConflict(ctx context.Context, taskID string) error {
+	var claimedBy, leaseExpires sql.NullString
+	err := k.db.QueryRowContext(ctx, "SELECT claimed_by, lease_expires_at FROM tasks WHERE id = ?", taskID).
+		Scan(&claimedBy, &leaseExpires)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if err != nil {
+		return fmt.Errorf("load task %s: %w", taskID, err)
+	}
+	expiry, _ := time.Parse(time.RFC3339, leaseExpires.String)
+	return &ClaimConflictError{TaskID: taskID, ClaimedBy: claimedBy.String, ExpiresAt: expiry}
+}
+
 // ReleaseTask clears the claim on a task, optionally setting error info.
 func (k *KanbanIntegration) ReleaseTask(taskID, agentID, reason string) error {
+	return k.ReleaseTaskCtx(context.Background(), taskID, agentID, reason)
+}
+
+// ReleaseTaskCtx is ReleaseTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ReleaseTaskCtx(ctx context.Context, taskID, agentID, reason string) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
@@ -629,7 +982,7 @@ func (k *KanbanIntegration) ReleaseTask(taskID, agentID, reason string) error {
 		newState = string(StateBlocked)
 	}
 
-	_, err := k.db.Exec(`UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
+	_, err := k.db.ExecContext(ctx, `UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
 		state = ?, last_error = ?, updated_at = ? WHERE id = ? AND claimed_by = ?`,
 		newState, reason, now.Format(time.RFC3339), taskID, agentID)
 	if err != nil {
@@ -642,8 +995,9 @@ func (k *KanbanIntegration) ReleaseTask(taskID, agentID, reason string) error {
 	}
 	if k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   eventType,
-			Source: "kanban",
+			Type:      eventType,
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
 			Data: map[string]interface{}{
 				"task_id":  taskID,
 				"agent_id": agentID,
@@ -656,11 +1010,16 @@ func (k *KanbanIntegration) ReleaseTask(taskID, agentID, reason string) error {
 
 // CompleteTask marks a task as done and clears ownership.
 func (k *KanbanIntegration) CompleteTask(taskID, agentID string) error {
+	return k.CompleteTaskCtx(context.Background(), taskID, agentID)
+}
+
+// CompleteTaskCtx is CompleteTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) CompleteTaskCtx(ctx context.Context, taskID, agentID string) error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
 	now := time.Now().UTC()
-	_, err := k.db.Exec(`UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
+	_, err := k.db.ExecContext(ctx, `UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
 		state = 'done', last_error = '', updated_at = ? WHERE id = ?`,
 		now.Format(time.RFC3339), taskID)
 	if err != nil {
@@ -669,8 +1028,9 @@ func (k *KanbanIntegration) CompleteTask(taskID, agentID string) error {
 
 	if k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   "task.completed",
-			Source: "kanban",
+			Type:      "task.completed",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
 			Data: map[string]interface{}{
 				"task_id":  taskID,
 				"agent_id": agentID,
@@ -680,16 +1040,60 @@ func (k *KanbanIntegration) CompleteTask(taskID, agentID string) error {
 	return nil
 }
 
+// Claim is an agent's unexpired lease on a task.
+type Claim struct {
+	TaskID    string    `json:"task_id"`
+	AgentID   string    `json:"agent_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ActiveClaims returns every claim whose lease hasn't expired. Tasks
+// claimed without a lease are claimable by anyone and aren't included.
+func (k *KanbanIntegration) ActiveClaims() ([]Claim, error) {
+	return k.ActiveClaimsCtx(context.Background())
+}
+
+// ActiveClaimsCtx is ActiveClaims bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ActiveClaimsCtx(ctx context.Context) ([]Claim, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rows, err := k.db.QueryContext(ctx, `SELECT id, claimed_by, lease_expires_at FROM tasks
+		WHERE claimed_by != '' AND lease_expires_at IS NOT NULL AND NOT `+k.db.b.before("lease_expires_at")+` ORDER BY id`,
+		time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []Claim
+	for rows.Next() {
+		var c Claim
+		var expires string
+		if err := rows.Scan(&c.TaskID, &c.AgentID, &expires); err != nil {
+			return nil, err
+		}
+		c.ExpiresAt, _ = time.Parse(time.RFC3339, expires)
+		claims = append(claims, c)
+	}
+	return claims, rows.Err()
+}
+
 // CleanupExpiredClaims releases tasks where the lease has expired.
 // Returns the number of tasks released.
 func (k *KanbanIntegration) CleanupExpiredClaims() (int, error) {
+	return k.CleanupExpiredClaimsCtx(context.Background())
+}
+
+// CleanupExpiredClaimsCtx is CleanupExpiredClaims bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) CleanupExpiredClaimsCtx(ctx context.Context) (int, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
 	now := time.Now().UTC().Format(time.RFC3339)
-	result, err := k.db.Exec(`UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
+	result, err := k.db.ExecContext(ctx, `UPDATE tasks SET claimed_by = '', lease_expires_at = NULL,
 		state = 'planned', last_error = 'lease expired'
-		WHERE claimed_by != '' AND lease_expires_at IS NOT NULL AND lease_expires_at < ?`, now)
+		WHERE claimed_by != '' AND lease_expires_at IS NOT NULL AND `+k.db.b.before("lease_expires_at"), now)
 	if err != nil {
 		return 0, err
 	}
@@ -697,39 +1101,314 @@ func (k *KanbanIntegration) CleanupExpiredClaims() (int, error) {
 	affected, _ := result.RowsAffected()
 	if affected > 0 && k.bus != nil {
 		k.bus.PublishSystem(bus.SystemEvent{
-			Type:   "task.lease_expired",
-			Source: "kanban",
-			Data:   map[string]interface{}{"count": affected},
+			Type:      "task.lease_expired",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
+			Data:      map[string]interface{}{"count": affected},
 		})
 	}
 	return int(affected), nil
 }
 
-// AddNote adds a note to a task.
+// Note content types. Notes are plain text unless given another.
+const (
+	NoteText     = "text/plain"
+	NoteMarkdown = "text/markdown"
+)
+
+// ErrInvalidContentType is wrapped when a note's content type isn't
+// NoteText or NoteMarkdown.
+var ErrInvalidContentType = errors.New("unsupported note content type")
+
+// NoteOptions are the optional parts of a note.
+type NoteOptions struct {
+	ContentType string   // NoteText (the default) or NoteMarkdown
+	Attachments []string // URLs or file paths, e.g. logs and screenshots
+	// Quiet stores the note without acting on its content: no mentions,
+	// references or watcher notifications. For notes holding generated
+	// content such as diffs.
+	Quiet bool
+}
+
+// AddNote adds a plain-text note to a task.
 func (k *KanbanIntegration) AddNote(taskID, content, author string) error {
-	_, err := k.db.Exec(
-		"INSERT INTO task_notes (task_id, content, author) VALUES (?, ?, ?)",
-		taskID, content, author,
-	)
-	return err
+	return k.AddNoteCtx(context.Background(), taskID, content, author)
+}
+
+// AddNoteCtx is AddNote bound to ctx; cancelling ctx aborts the query.
+// Once the note is stored, @mentions notify the mentioned users, TASK-n
+// references cross-link the two tasks, and the task's other watchers are
+// notified.
+func (k *KanbanIntegration) AddNoteCtx(ctx context.Context, taskID, content, author string) error {
+	return k.AddRichNoteCtx(ctx, taskID, content, author, NoteOptions{})
+}
+
+// AddRichNote adds a note with a content type and attachments to a task.
+func (k *KanbanIntegration) AddRichNote(taskID, content, author string, opts NoteOptions) error {
+	return k.AddRichNoteCtx(context.Background(), taskID, content, author, opts)
+}
+
+// AddRichNoteCtx is AddRichNote bound to ctx; cancelling ctx aborts the
+// query. The note also appears in the task's activity feed as a
+// note.added event whose details are the note as JSON. Unless
+// opts.Quiet is set, mentions, references and watchers are handled as in
+// AddNoteCtx.
+func (k *KanbanIntegration) AddRichNoteCtx(ctx context.Context, taskID, content, author string, opts NoteOptions) error {
+	note := TaskNote{
+		TaskID:      taskID,
+		Content:     content,
+		Author:      author,
+		ContentType: opts.ContentType,
+		Attachments: opts.Attachments,
+	}
+	switch note.ContentType {
+	case "":
+		note.ContentType = NoteText
+	case NoteText, NoteMarkdown:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidContentType, note.ContentType)
+	}
+
+	by := author
+	if by == "" {
+		by = "someone"
+	}
+	if err := k.insertNote(ctx, &note, "Note from "+by); err != nil {
+		return err
+	}
+	if opts.Quiet {
+		return nil
+	}
+
+	notified := k.processNote(ctx, taskID, content, author)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	k.notifyWatchers(ctx, taskID, author, fmt.Sprintf("note from %s:\n%s", by, content), notified)
+	return nil
+}
+
+// insertNote stores note, its attachments and its note.added event in one
+// transaction, filling in note.ID and note.CreatedAt.
+func (k *KanbanIntegration) insertNote(ctx context.Context, note *TaskNote, summary string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO task_notes (task_id, content, author, content_type) VALUES (?, ?, ?, ?) RETURNING id",
+		note.TaskID, note.Content, note.Author, note.ContentType,
+	).Scan(&note.ID)
+	if err != nil {
+		return err
+	}
+	for _, url := range note.Attachments {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO note_attachments (note_id, url) VALUES (?, ?)", note.ID, url); err != nil {
+			return fmt.Errorf("add attachment %s: %w", url, err)
+		}
+	}
+	note.CreatedAt = time.Now().UTC()
+
+	details, _ := json.Marshal(note)
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_events (task_id, source, event_type, summary, details) VALUES (?, ?, ?, ?, ?)",
+		note.TaskID, "kanban", "note.added", summary, string(details),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TaskNote is a note on a task: plain text or markdown, with optional
+// attachments.
+type TaskNote struct {
+	ID          int64     `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Content     string    `json:"content"`
+	ContentType string    `json:"content_type"`
+	Attachments []string  `json:"attachments,omitempty"`
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListNotes returns a task's notes, oldest first.
+func (k *KanbanIntegration) ListNotes(taskID string) ([]*TaskNote, error) {
+	return k.ListNotesCtx(context.Background(), taskID)
+}
+
+// ListNotesCtx is ListNotes bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ListNotesCtx(ctx context.Context, taskID string) ([]*TaskNote, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rows, err := k.db.QueryContext(ctx,
+		"SELECT id, task_id, content, content_type, author, created_at FROM task_notes WHERE task_id = ? ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*TaskNote
+	byID := make(map[int64]*TaskNote)
+	for rows.Next() {
+		var n TaskNote
+		var contentType sql.NullString
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.TaskID, &n.Content, &contentType, &n.Author, &createdAt); err != nil {
+			return nil, err
+		}
+		n.ContentType = contentType.String
+		if n.ContentType == "" {
+			n.ContentType = NoteText
+		}
+		n.CreatedAt = parseDBTime(createdAt)
+		notes = append(notes, &n)
+		byID[n.ID] = &n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	attachments, err := k.db.QueryContext(ctx, `SELECT a.note_id, a.url FROM note_attachments a
+		JOIN task_notes n ON n.id = a.note_id WHERE n.task_id = ? ORDER BY a.id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer attachments.Close()
+	for attachments.Next() {
+		var noteID int64
+		var url string
+		if err := attachments.Scan(&noteID, &url); err != nil {
+			return nil, err
+		}
+		if n, ok := byID[noteID]; ok {
+			n.Attachments = append(n.Attachments, url)
+		}
+	}
+	return notes, attachments.Err()
 }
 
 // LogEvent records a task event.
 func (k *KanbanIntegration) LogEvent(taskID, source, eventType, summary string) error {
-	_, err := k.db.Exec(
+	return k.LogEventCtx(context.Background(), taskID, source, eventType, summary)
+}
+
+// LogEventCtx is LogEvent bound to ctx; cancelling ctx aborts the query.
+// The task's watchers are notified of the event.
+func (k *KanbanIntegration) LogEventCtx(ctx context.Context, taskID, source, eventType, summary string) error {
+	if err := k.logEvent(ctx, taskID, source, eventType, summary); err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	k.notifyWatchers(ctx, taskID, source, eventType+": "+summary, nil)
+	return nil
+}
+
+// AddTokens adds tokens to the task's TokensUsed and records why as a
+// task event.
+func (k *KanbanIntegration) AddTokens(taskID string, tokens int64, source, summary string) error {
+	return k.AddTokensCtx(context.Background(), taskID, tokens, source, summary)
+}
+
+// AddTokensCtx is AddTokens bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) AddTokensCtx(ctx context.Context, taskID string, tokens int64, source, summary string) error {
+	k.mu.Lock()
+	res, err := k.db.ExecContext(ctx,
+		"UPDATE tasks SET tokens_used = tokens_used + ?, updated_at = ? WHERE id = ?",
+		tokens, time.Now().UTC().Format(time.RFC3339), taskID)
+	k.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	return k.logEvent(ctx, taskID, source, "tokens", summary)
+}
+
+// logEvent records a task event without notifying anyone.
+func (k *KanbanIntegration) logEvent(ctx context.Context, taskID, source, eventType, summary string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	_, err := k.db.ExecContext(ctx,
 		"INSERT INTO task_events (task_id, source, event_type, summary) VALUES (?, ?, ?, ?)",
 		taskID, source, eventType, summary,
 	)
 	return err
 }
 
-// GetBoardStats returns aggregate stats for the dashboard.
+// TaskEvent is one entry in a task's activity feed.
+type TaskEvent struct {
+	ID        int64     `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Source    string    `json:"source"`
+	EventType string    `json:"event_type"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListEvents returns a task's most recent events, newest first. A limit of
+// 0 or less means 50.
+func (k *KanbanIntegration) ListEvents(taskID string, limit int) ([]*TaskEvent, error) {
+	return k.ListEventsCtx(context.Background(), taskID, limit)
+}
+
+// ListEventsCtx is ListEvents bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ListEventsCtx(ctx context.Context, taskID string, limit int) ([]*TaskEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rows, err := k.db.QueryContext(ctx, `SELECT id, task_id, source, event_type, summary, details, created_at
+		FROM task_events WHERE task_id = ? ORDER BY id DESC LIMIT ?`, taskID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*TaskEvent
+	for rows.Next() {
+		var e TaskEvent
+		var details sql.NullString
+		var createdAt string
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Source, &e.EventType, &e.Summary, &details, &createdAt); err != nil {
+			return nil, err
+		}
+		e.Details = details.String
+		e.CreatedAt = parseDBTime(createdAt)
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// GetBoardStats returns aggregate stats for the dashboard: the task count
+// of each state, "total", and "<state>_wip_limit" for states with a WIP
+// limit.
 func (k *KanbanIntegration) GetBoardStats() (map[string]int, error) {
+	return k.GetBoardStatsCtx(context.Background())
+}
+
+// GetBoardStatsCtx is GetBoardStats bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetBoardStatsCtx(ctx context.Context) (map[string]int, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	stats := map[string]int{}
-	rows, err := k.db.Query("SELECT state, COUNT(*) FROM tasks GROUP BY state")
+	rows, err := k.db.QueryContext(ctx, "SELECT state, COUNT(*) FROM tasks GROUP BY state")
 	if err != nil {
 		return stats, err
 	}
@@ -744,16 +1423,26 @@ func (k *KanbanIntegration) GetBoardStats() (map[string]int, error) {
 		total += count
 	}
 	stats["total"] = total
+	for _, st := range AllStates() {
+		if limit := k.wipLimit(st); limit > 0 {
+			stats[string(st)+"_wip_limit"] = limit
+		}
+	}
 	return stats, nil
 }
 
 // GetCategoryStats returns task counts by category.
 func (k *KanbanIntegration) GetCategoryStats() (map[string]int, error) {
+	return k.GetCategoryStatsCtx(context.Background())
+}
+
+// GetCategoryStatsCtx is GetCategoryStats bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetCategoryStatsCtx(ctx context.Context) (map[string]int, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	stats := map[string]int{}
-	rows, err := k.db.Query("SELECT category, COUNT(*) FROM tasks WHERE state != 'done' GROUP BY category")
+	rows, err := k.db.QueryContext(ctx, "SELECT category, COUNT(*) FROM tasks WHERE state != 'done' GROUP BY category")
 	if err != nil {
 		return stats, err
 	}
@@ -768,21 +1457,86 @@ func (k *KanbanIntegration) GetCategoryStats() (map[string]int, error) {
 	return stats, nil
 }
 
+// DashboardSummary is everything the dashboard shows about the board,
+// read in one pass.
+type DashboardSummary struct {
+	// States counts tasks per state, plus "total", as GetBoardStats does.
+	States map[string]int `json:"states"`
+	// Categories counts tasks that aren't done, as GetCategoryStats does.
+	Categories map[string]int `json:"categories"`
+	// ActiveClaims counts tasks held under an unexpired lease.
+	ActiveClaims int `json:"active_claims"`
+	// Overdue counts unfinished tasks whose due date has passed.
+	Overdue int `json:"overdue"`
+}
+
+// GetDashboardSummary returns board stats, category stats, and the active
+// claim and overdue counts from a single query.
+func (k *KanbanIntegration) GetDashboardSummary() (*DashboardSummary, error) {
+	return k.GetDashboardSummaryCtx(context.Background())
+}
+
+// GetDashboardSummaryCtx is GetDashboardSummary bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) GetDashboardSummaryCtx(ctx context.Context) (*DashboardSummary, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := k.db.QueryContext(ctx, `
+		SELECT state, category, COUNT(*),
+			COALESCE(SUM(CASE WHEN claimed_by != '' AND lease_expires_at IS NOT NULL AND NOT `+k.db.b.before("lease_expires_at")+` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN state != 'done' AND `+k.db.b.before("due_date")+` THEN 1 ELSE 0 END), 0)
+		FROM tasks GROUP BY state, category`, now, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sum := &DashboardSummary{
+		States:     map[string]int{},
+		Categories: map[string]int{},
+	}
+	total := 0
+	for rows.Next() {
+		var state, category string
+		var count, claimed, overdue int
+		if err := rows.Scan(&state, &category, &count, &claimed, &overdue); err != nil {
+			return nil, err
+		}
+		sum.States[state] += count
+		if state != string(StateDone) {
+			sum.Categories[category] += count
+		}
+		sum.ActiveClaims += claimed
+		sum.Overdue += overdue
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sum.States["total"] = total
+	return sum, nil
+}
+
 // TaskFilters holds query parameters for listing tasks.
 type TaskFilters struct {
-	State       TaskState    `json:"state,omitempty"`
-	Category    TaskCategory `json:"category,omitempty"`
-	Source      TaskSource   `json:"source,omitempty"`
-	Project     string       `json:"project,omitempty"`
-	ExcludeDone bool         `json:"exclude_done,omitempty"`
-	Limit       int          `json:"limit,omitempty"`
+	State       TaskState      `json:"state,omitempty"`
+	Category    TaskCategory   `json:"category,omitempty"`
+	Categories  []TaskCategory `json:"categories,omitempty"` // match any of these
+	Source      TaskSource     `json:"source,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	ExcludeDone bool           `json:"exclude_done,omitempty"`
+	// ClaimableBy hides tasks held under an active lease by any agent
+	// other than this one. Unclaimed and expired claims are kept.
+	ClaimableBy string `json:"claimable_by,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
 }
 
 // Helper functions
 
-func (k *KanbanIntegration) nextID() (string, error) {
+func (k *KanbanIntegration) nextID(ctx context.Context) (string, error) {
 	var maxID sql.NullString
-	err := k.db.QueryRow("SELECT id FROM tasks ORDER BY id DESC LIMIT 1").Scan(&maxID)
+	err := k.db.QueryRowContext(ctx, "SELECT id FROM tasks ORDER BY id DESC LIMIT 1").Scan(&maxID)
 	if err == sql.ErrNoRows || !maxID.Valid {
 		return "TASK-001", nil
 	}
@@ -810,7 +1564,7 @@ func (k *KanbanIntegration) scanTask(row *sql.Row) (*Task, error) {
 		&task.TelegramMessageID, &task.VSCodeTaskID, &task.ExternalRef,
 		&llmCategorized, &task.LLMSummary,
 		&task.ClaimedBy, &leaseExpiresAt, &task.ClaimCount, &task.LastError,
-		&createdAt, &updatedAt, &dueDate,
+		&createdAt, &updatedAt, &dueDate, &task.Workspace, &task.TokensUsed,
 	)
 	if err != nil {
 		return nil, err
@@ -852,7 +1606,7 @@ func (k *KanbanIntegration) scanTaskFromRows(rows *sql.Rows) (*Task, error) {
 		&task.TelegramMessageID, &task.VSCodeTaskID, &task.ExternalRef,
 		&llmCategorized, &task.LLMSummary,
 		&task.ClaimedBy, &leaseExpiresAt, &task.ClaimCount, &task.LastError,
-		&createdAt, &updatedAt, &dueDate,
+		&createdAt, &updatedAt, &dueDate, &task.Workspace, &task.TokensUsed,
 	)
 	if err != nil {
 		return nil, err
@@ -880,6 +1634,15 @@ func (k *KanbanIntegration) scanTaskFromRows(rows *sql.Rows) (*Task, error) {
 	return task, nil
 }
 
+// dbBool stores a flag in an INTEGER column. SQLite takes Go bools there
+// but Postgres wants the number.
+func dbBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func formatOptionalTime(t *time.Time) interface{} {
 	if t == nil {
 		return nil
@@ -887,6 +1650,16 @@ func formatOptionalTime(t *time.Time) interface{} {
 	return t.Format(time.RFC3339)
 }
 
+// parseDBTime reads a timestamp written either by this package (RFC3339)
+// or by SQLite's datetime('now') default, which is UTC.
+func parseDBTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	t, _ := time.Parse("2006-01-02 15:04:05", s)
+	return t
+}
+
 func joinStrings(strs []string, sep string) string {
 	result := ""
 	for i, s := range strs {
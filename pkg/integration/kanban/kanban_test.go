@@ -0,0 +1,483 @@
+package kanban
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestListTasksClaimableBy(t *testing.T) {
+	ctx := context.Background()
+	k := newTestBoard(t)
+
+	free := &Task{Title: "free", Category: CategoryCode}
+	mine := &Task{Title: "mine", Category: CategoryBug}
+	theirs := &Task{Title: "theirs", Category: CategoryCode}
+	other := &Task{Title: "research", Category: CategoryResearch}
+	for _, task := range []*Task{free, mine, theirs, other} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+	if err := k.ClaimTask(mine.ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("ClaimTask(mine) error: %v", err)
+	}
+	if err := k.ClaimTask(theirs.ID, "agent-b", time.Hour); err != nil {
+		t.Fatalf("ClaimTask(theirs) error: %v", err)
+	}
+
+	tasks, err := k.ListTasksCtx(ctx, TaskFilters{
+		Categories:  []TaskCategory{CategoryCode, CategoryBug},
+		ClaimableBy: "agent-a",
+	})
+	if err != nil {
+		t.Fatalf("ListTasksCtx() error: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, task := range tasks {
+		got[task.ID] = true
+	}
+	if !got[free.ID] || !got[mine.ID] {
+		t.Errorf("expected free and own tasks, got %v", got)
+	}
+	if got[theirs.ID] {
+		t.Error("task claimed by another agent should be hidden")
+	}
+	if got[other.ID] {
+		t.Error("task outside the category list should be hidden")
+	}
+}
+
+func TestClaimTaskConflict(t *testing.T) {
+	k := newTestBoard(t)
+
+	task := &Task{Title: "contested"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if err := k.ClaimTask(task.ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("ClaimTask(agent-a) error: %v", err)
+	}
+
+	err := k.ClaimTask(task.ID, "agent-b", time.Hour)
+	var conflict *ClaimConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("ClaimTask(agent-b) error = %v, want *ClaimConflictError", err)
+	}
+	if conflict.ClaimedBy != "agent-a" {
+		t.Errorf("ClaimedBy = %q, want %q", conflict.ClaimedBy, "agent-a")
+	}
+
+	// Renewing your own claim is not a conflict.
+	if err := k.ClaimTask(task.ID, "agent-a", time.Hour); err != nil {
+		t.Errorf("ClaimTask(agent-a) renew error: %v", err)
+	}
+}
+
+func TestClaimTaskAcrossInstances(t *testing.T) {
+	// Two boards on one database stand in for two picoclaw processes; they
+	// share no lock, so only the conditional UPDATE keeps claims exclusive.
+	k1 := newTestBoard(t)
+	k2 := &KanbanIntegration{dbPath: k1.dbPath}
+	if err := k2.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(func() { k2.Stop(context.Background()) })
+
+	for round := 0; round < 5; round++ {
+		task := &Task{Title: "contested"}
+		if err := k1.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+		var wg sync.WaitGroup
+		var won atomic.Int32
+		for i := 0; i < 8; i++ {
+			board := k1
+			if i%2 == 1 {
+				board = k2
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := board.ClaimTask(task.ID, fmt.Sprintf("agent-%d", i), time.Hour)
+				var conflict *ClaimConflictError
+				switch {
+				case err == nil:
+					won.Add(1)
+				case !errors.As(err, &conflict):
+					t.Errorf("ClaimTask(agent-%d) error = %v, want *ClaimConflictError", i, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if n := won.Load(); n != 1 {
+			t.Fatalf("round %d: %d agents claimed the task, want 1", round, n)
+		}
+	}
+}
+
+func TestActiveClaims(t *testing.T) {
+	k := newTestBoard(t)
+
+	held := &Task{Title: "held"}
+	lapsed := &Task{Title: "lapsed"}
+	free := &Task{Title: "free"}
+	for _, task := range []*Task{held, lapsed, free} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+	if err := k.ClaimTask(held.ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("ClaimTask(held) error: %v", err)
+	}
+	if err := k.ClaimTask(lapsed.ID, "agent-b", -time.Minute); err != nil {
+		t.Fatalf("ClaimTask(lapsed) error: %v", err)
+	}
+
+	claims, err := k.ActiveClaims()
+	if err != nil {
+		t.Fatalf("ActiveClaims() error: %v", err)
+	}
+	if len(claims) != 1 || claims[0].TaskID != held.ID || claims[0].AgentID != "agent-a" {
+		t.Fatalf("ActiveClaims() = %+v, want only %s by agent-a", claims, held.ID)
+	}
+	if until := time.Until(claims[0].ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("ExpiresAt = %v, want about an hour from now", claims[0].ExpiresAt)
+	}
+}
+
+func TestLeaseExpiryAcrossOffsets(t *testing.T) {
+	k := newTestBoard(t)
+	task := &Task{Title: "lapsed"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if err := k.ClaimTask(task.ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("ClaimTask() error: %v", err)
+	}
+
+	// Half an hour ago, written at +14:00: as text it sorts after now.
+	lapsed := time.Now().Add(-30 * time.Minute).In(time.FixedZone("", 14*3600)).Format(time.RFC3339)
+	if _, err := k.db.Exec("UPDATE tasks SET lease_expires_at = ? WHERE id = ?", lapsed, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if claims, _ := k.ActiveClaims(); len(claims) != 0 {
+		t.Errorf("ActiveClaims() = %+v, want the lapsed lease left out", claims)
+	}
+	if err := k.ClaimTask(task.ID, "agent-b", time.Hour); err != nil {
+		t.Errorf("ClaimTask() over a lapsed lease error: %v", err)
+	}
+}
+
+func TestUpdateTaskNormalizesLease(t *testing.T) {
+	k := newTestBoard(t)
+	task := &Task{Title: "leased"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+
+	if err := k.UpdateTask(task.ID, map[string]interface{}{"lease_expires_at": "2030-01-01T00:00:00+02:00"}); err != nil {
+		t.Fatalf("UpdateTask() error: %v", err)
+	}
+	var stored string
+	k.db.QueryRow("SELECT lease_expires_at FROM tasks WHERE id = ?", task.ID).Scan(&stored)
+	if stored != "2029-12-31T22:00:00Z" {
+		t.Errorf("stored lease = %q, want UTC", stored)
+	}
+
+	for _, bad := range []interface{}{"tomorrow", 42.0} {
+		if err := k.UpdateTask(task.ID, map[string]interface{}{"lease_expires_at": bad}); !errors.Is(err, ErrInvalidLease) {
+			t.Errorf("UpdateTask(%v) error = %v, want ErrInvalidLease", bad, err)
+		}
+	}
+	if err := k.UpdateTask(task.ID, map[string]interface{}{"lease_expires_at": ""}); err != nil {
+		t.Fatalf("UpdateTask(\"\") error: %v", err)
+	}
+	var cleared *string
+	k.db.QueryRow("SELECT lease_expires_at FROM tasks WHERE id = ?", task.ID).Scan(&cleared)
+	if cleared != nil {
+		t.Errorf("lease after clearing = %q, want NULL", *cleared)
+	}
+}
+
+func TestClaimNextAging(t *testing.T) {
+	ctx := context.Background()
+	k := newTestBoard(t)
+	k.cfg = &config.Config{}
+
+	old := &Task{Title: "old low", Priority: "low", CreatedAt: time.Now().Add(-24 * time.Hour)}
+	fresh := &Task{Title: "fresh high", Priority: "high"}
+	for _, task := range []*Task{old, fresh} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+
+	// Without aging, priority wins.
+	got, err := k.ClaimNextCtx(ctx, "agent-a", nil, time.Hour)
+	if err != nil || got.ID != fresh.ID {
+		t.Fatalf("ClaimNext() without aging = %v, %v; want %s", got, err, fresh.ID)
+	}
+	k.ReleaseTask(fresh.ID, "agent-a", "")
+	k.UpdateTask(fresh.ID, map[string]interface{}{"state": string(StateInbox)})
+
+	// A day of aging at 0.25/h lifts the low task well past high.
+	k.cfg.Integrations.TaskAgingPerHour = 0.25
+	got, err = k.ClaimNextCtx(ctx, "agent-a", nil, time.Hour)
+	if err != nil || got.ID != old.ID {
+		t.Fatalf("ClaimNext() with aging = %v, %v; want %s", got, err, old.ID)
+	}
+	if got.EffectivePriority < 6 {
+		t.Errorf("EffectivePriority = %v, want >= 6", got.EffectivePriority)
+	}
+
+	if _, err := k.ClaimNextCtx(ctx, "agent-b", []TaskCategory{CategoryOps}, time.Hour); !errors.Is(err, ErrNoClaimableTask) {
+		t.Errorf("ClaimNext() with no match error = %v, want ErrNoClaimableTask", err)
+	}
+}
+
+func TestClaimNextBeyondListLimit(t *testing.T) {
+	k := newTestBoard(t)
+	k.cfg = &config.Config{}
+	k.cfg.Integrations.TaskAgingPerHour = 0.25
+
+	// The aged task is the least recently updated, so a capped
+	// most-recent-first listing would never see it.
+	old := &Task{Title: "old low", Priority: "low", CreatedAt: time.Now().Add(-24 * time.Hour)}
+	if err := k.CreateTask(old); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if _, err := k.db.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", old.CreatedAt.UTC().Format(time.RFC3339), old.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 510; i++ {
+		if err := k.CreateTask(&Task{Title: fmt.Sprintf("task %d", i), Priority: "high"}); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+
+	got, err := k.ClaimNextCtx(context.Background(), "agent-a", nil, time.Hour)
+	if err != nil || got.ID != old.ID {
+		t.Fatalf("ClaimNext() = %v, %v; want %s", got, err, old.ID)
+	}
+}
+
+func TestGetDashboardSummaryMatchesStats(t *testing.T) {
+	k := newTestBoard(t)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tasks := []*Task{
+		{Title: "overdue", Category: CategoryBug, DueDate: &past},
+		{Title: "due later", Category: CategoryBug, DueDate: &future},
+		{Title: "claimed", Category: CategoryCode},
+		{Title: "done late", Category: CategoryCode, State: StateDone, DueDate: &past},
+	}
+	for _, task := range tasks {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := k.ClaimTask(tasks[2].ID, "agent-a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := k.GetDashboardSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	board, _ := k.GetBoardStats()
+	cats, _ := k.GetCategoryStats()
+	if len(sum.States) != len(board) || len(sum.Categories) != len(cats) {
+		t.Fatalf("summary %+v disagrees with board %v / categories %v", sum, board, cats)
+	}
+	for state, n := range board {
+		if sum.States[state] != n {
+			t.Errorf("states[%s] = %d, want %d", state, sum.States[state], n)
+		}
+	}
+	for cat, n := range cats {
+		if sum.Categories[cat] != n {
+			t.Errorf("categories[%s] = %d, want %d", cat, sum.Categories[cat], n)
+		}
+	}
+	if sum.ActiveClaims != 1 || sum.Overdue != 1 {
+		t.Errorf("active_claims = %d, overdue = %d; want 1, 1", sum.ActiveClaims, sum.Overdue)
+	}
+}
+
+func TestBoardMetaCoversEnums(t *testing.T) {
+	meta := GetBoardMeta()
+	groups := []struct {
+		name   string
+		labels []LabelMeta
+		known  int
+	}{
+		{"states", meta.States, len(stateDisplay)},
+		{"categories", meta.Categories, len(categoryDisplay)},
+		{"priorities", meta.Priorities, len(priorityWeights)},
+		{"sources", meta.Sources, len(sourceDisplay)},
+	}
+	for _, g := range groups {
+		if len(g.labels) != g.known {
+			t.Errorf("%s: %d entries, want %d", g.name, len(g.labels), g.known)
+		}
+		for _, l := range g.labels {
+			if l.Label == "" || l.Color == "" || l.Icon == "" {
+				t.Errorf("%s: incomplete entry %+v", g.name, l)
+			}
+		}
+	}
+	for _, s := range AllStates() {
+		if _, ok := meta.Transitions[s]; !ok {
+			t.Errorf("state %s missing from transitions", s)
+		}
+	}
+}
+
+func TestAddRichNote(t *testing.T) {
+	k := newTestBoard(t)
+
+	task := &Task{Title: "flaky build"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if err := k.AddNote(task.ID, "plain", "alice"); err != nil {
+		t.Fatalf("AddNote() error: %v", err)
+	}
+	opts := NoteOptions{ContentType: NoteMarkdown, Attachments: []string{"https://ci.example/log/1", "/tmp/shot.png"}}
+	if err := k.AddRichNote(task.ID, "## Build log", "bob", opts); err != nil {
+		t.Fatalf("AddRichNote() error: %v", err)
+	}
+	if err := k.AddRichNote(task.ID, "x", "bob", NoteOptions{ContentType: "text/html"}); !errors.Is(err, ErrInvalidContentType) {
+		t.Errorf("AddRichNote(text/html) error = %v, want ErrInvalidContentType", err)
+	}
+
+	notes, err := k.ListNotes(task.ID)
+	if err != nil || len(notes) != 2 {
+		t.Fatalf("ListNotes() = %+v, %v", notes, err)
+	}
+	if notes[0].ContentType != NoteText || len(notes[0].Attachments) != 0 {
+		t.Errorf("plain note = %+v", notes[0])
+	}
+	if notes[1].ContentType != NoteMarkdown || len(notes[1].Attachments) != 2 || notes[1].Attachments[1] != "/tmp/shot.png" {
+		t.Errorf("rich note = %+v", notes[1])
+	}
+
+	events, err := k.ListEvents(task.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].EventType != "note.added" || events[0].Summary != "Note from bob" {
+		t.Fatalf("ListEvents() = %+v", events)
+	}
+	var logged TaskNote
+	if err := json.Unmarshal([]byte(events[0].Details), &logged); err != nil {
+		t.Fatalf("event details %q: %v", events[0].Details, err)
+	}
+	if logged.ID != notes[1].ID || logged.ContentType != NoteMarkdown || len(logged.Attachments) != 2 {
+		t.Errorf("event note = %+v", logged)
+	}
+
+	if err := k.DeleteTask(task.ID); err != nil {
+		t.Fatalf("DeleteTask() error: %v", err)
+	}
+	var left int
+	k.db.QueryRow("SELECT COUNT(*) FROM note_attachments").Scan(&left)
+	if left != 0 {
+		t.Errorf("%d attachments left after DeleteTask", left)
+	}
+}
+
+func TestDeleteTaskRollsBack(t *testing.T) {
+	k := newTestBoard(t)
+	task := &Task{Title: "keep me"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+	if err := k.TransitionTask(task.ID, StatePlanned, "triaged", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddNote(task.ID, "repro steps", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.LogEvent(task.ID, "test", "note", "seen"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddWatcher(task.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() map[string]int {
+		got := map[string]int{}
+		for _, table := range []string{"task_transitions", "task_notes", "task_events", "task_watchers"} {
+			var n int
+			k.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE task_id = ?", task.ID).Scan(&n)
+			got[table] = n
+		}
+		return got
+	}
+	before := counts()
+
+	// The last child delete fails once the others have run
+	if _, err := k.db.Exec("DROP TABLE task_logs"); err != nil {
+		t.Fatal(err)
+	}
+	err := k.DeleteTask(task.ID)
+	if err == nil || !strings.Contains(err.Error(), "task_logs") {
+		t.Fatalf("DeleteTask() error = %v, want the task_logs failure", err)
+	}
+
+	var kept int
+	k.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE id = ?", task.ID).Scan(&kept)
+	if kept != 1 {
+		t.Error("task gone after a failed delete")
+	}
+	if after := counts(); !reflect.DeepEqual(after, before) {
+		t.Errorf("rows after a failed delete = %v, want %v", after, before)
+	}
+	for table, n := range before {
+		if n == 0 {
+			t.Errorf("no %s rows to check", table)
+		}
+	}
+}
+
+func TestCtxMethodsHonourCancellation(t *testing.T) {
+	k := newTestBoard(t)
+	task := &Task{Title: "existing"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := k.ListTasksCtx(ctx, TaskFilters{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListTasksCtx() error = %v, want context.Canceled", err)
+	}
+	if _, err := k.GetTaskCtx(ctx, task.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetTaskCtx() error = %v, want context.Canceled", err)
+	}
+	if err := k.CreateTaskCtx(ctx, &Task{Title: "never"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateTaskCtx() error = %v, want context.Canceled", err)
+	}
+	if err := k.DeleteTaskCtx(ctx, task.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteTaskCtx() error = %v, want context.Canceled", err)
+	}
+
+	tasks, err := k.ListTasks(TaskFilters{})
+	if err != nil || len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Errorf("tasks after cancelled calls = %v, %v; want only %s", tasks, err, task.ID)
+	}
+}
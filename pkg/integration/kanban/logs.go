@@ -0,0 +1,231 @@
+package kanban
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxTaskLogLines is how many log lines a task keeps; older ones are
+// deleted as new ones arrive.
+const MaxTaskLogLines = 5000
+
+// maxTaskLogLineBytes caps one stored log line.
+const maxTaskLogLineBytes = 4096
+
+// taskLogStreamBuffer is how many live lines a StreamLogs subscriber may
+// fall behind by before lines are dropped for it.
+const taskLogStreamBuffer = 256
+
+// TaskLogLine is one line of a task's execution log. Seq increases across
+// the whole board, so a reader can resume after the last Seq it saw.
+type TaskLogLine struct {
+	Seq    int64     `json:"seq"`
+	TaskID string    `json:"task_id"`
+	Line   string    `json:"line"`
+	At     time.Time `json:"at"`
+}
+
+// taskLogSubscribers fans appended lines out to StreamLogs readers.
+type taskLogSubscribers struct {
+	mu   sync.Mutex
+	subs map[string]map[chan TaskLogLine]struct{}
+}
+
+func (s *taskLogSubscribers) add(taskID string) chan TaskLogLine {
+	ch := make(chan TaskLogLine, taskLogStreamBuffer)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[string]map[chan TaskLogLine]struct{})
+	}
+	if s.subs[taskID] == nil {
+		s.subs[taskID] = make(map[chan TaskLogLine]struct{})
+	}
+	s.subs[taskID][ch] = struct{}{}
+	return ch
+}
+
+func (s *taskLogSubscribers) remove(taskID string, ch chan TaskLogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs[taskID], ch)
+	if len(s.subs[taskID]) == 0 {
+		delete(s.subs, taskID)
+	}
+}
+
+// publish hands lines to the task's subscribers without blocking; a
+// subscriber whose buffer is full misses them.
+func (s *taskLogSubscribers) publish(lines []TaskLogLine) {
+	if len(lines) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs[lines[0].TaskID] {
+		for _, l := range lines {
+			select {
+			case ch <- l:
+			default:
+			}
+		}
+	}
+}
+
+// AppendLog adds progress output to a task's execution log. Text with
+// several lines is stored as one entry per line; each is cut to 4 KB.
+// Live StreamLogs readers receive the new lines.
+func (k *KanbanIntegration) AppendLog(taskID, line string) error {
+	return k.AppendLogCtx(context.Background(), taskID, line)
+}
+
+// AppendLogCtx is AppendLog bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) AppendLogCtx(ctx context.Context, taskID, line string) error {
+	text := strings.TrimRight(strings.ReplaceAll(line, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var exists int
+	if err := k.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE id = ?", taskID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var added []TaskLogLine
+	for _, l := range strings.Split(text, "\n") {
+		if len(l) > maxTaskLogLineBytes {
+			l = l[:maxTaskLogLineBytes]
+		}
+		var seq int64
+		err := tx.QueryRowContext(ctx, "INSERT INTO task_logs (task_id, line, created_at) VALUES (?, ?, ?) RETURNING id",
+			taskID, l, now.Format(time.RFC3339Nano)).Scan(&seq)
+		if err != nil {
+			return err
+		}
+		added = append(added, TaskLogLine{Seq: seq, TaskID: taskID, Line: l, At: now})
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM task_logs WHERE task_id = ? AND id <= (
+		SELECT id FROM task_logs WHERE task_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`,
+		taskID, taskID, MaxTaskLogLines)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	k.logSubs.publish(added)
+	return nil
+}
+
+// ListLogs returns a task's log lines after seq (0 for the start), oldest
+// first, at most limit of them (0 for all kept lines).
+func (k *KanbanIntegration) ListLogs(taskID string, after int64, limit int) ([]TaskLogLine, error) {
+	return k.ListLogsCtx(context.Background(), taskID, after, limit)
+}
+
+// ListLogsCtx is ListLogs bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ListLogsCtx(ctx context.Context, taskID string, after int64, limit int) ([]TaskLogLine, error) {
+	if limit <= 0 {
+		limit = MaxTaskLogLines
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rows, err := k.db.QueryContext(ctx, `SELECT id, line, created_at FROM task_logs
+		WHERE task_id = ? AND id > ? ORDER BY id LIMIT ?`, taskID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []TaskLogLine
+	for rows.Next() {
+		l := TaskLogLine{TaskID: taskID}
+		var createdAt string
+		if err := rows.Scan(&l.Seq, &l.Line, &createdAt); err != nil {
+			return nil, err
+		}
+		l.At = parseDBTime(createdAt)
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// StreamLogs tails a task's execution log: the channel first carries the
+// kept lines after seq, then each line as it is appended, until ctx is
+// cancelled and the channel closed. A reader that falls more than a few
+// hundred lines behind misses some; it can fill the gap with ListLogs.
+func (k *KanbanIntegration) StreamLogs(ctx context.Context, taskID string, after int64) (<-chan TaskLogLine, error) {
+	if _, err := k.GetTaskCtx(ctx, taskID); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+
+	// Subscribe before reading the backlog so no line falls between them
+	live := k.logSubs.add(taskID)
+	backlog, err := k.ListLogsCtx(ctx, taskID, after, 0)
+	if err != nil {
+		k.logSubs.remove(taskID, live)
+		return nil, err
+	}
+
+	out := make(chan TaskLogLine)
+	go func() {
+		defer close(out)
+		defer k.logSubs.remove(taskID, live)
+
+		last := after
+		for _, l := range backlog {
+			select {
+			case out <- l:
+				last = l.Seq
+			case <-ctx.Done():
+				return
+			}
+		}
+		for {
+			select {
+			case l := <-live:
+				if l.Seq <= last {
+					continue // already sent from the backlog
+				}
+				select {
+				case out <- l:
+					last = l.Seq
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// fillLogInfo sets a task's log size and last line. The caller holds k.mu.
+func (k *KanbanIntegration) fillLogInfo(ctx context.Context, task *Task) error {
+	err := k.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM task_logs WHERE task_id = ?", task.ID).Scan(&task.LogLines)
+	if err != nil || task.LogLines == 0 {
+		return err
+	}
+	return k.db.QueryRowContext(ctx, "SELECT line FROM task_logs WHERE task_id = ? ORDER BY id DESC LIMIT 1",
+		task.ID).Scan(&task.LastLogLine)
+}
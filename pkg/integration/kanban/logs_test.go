@@ -0,0 +1,83 @@
+package kanban
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskLogs(t *testing.T) {
+	k := newTestBoard(t)
+	task := &Task{Title: "build"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.AppendLog(task.ID, "cloning repo\r\nrunning tests\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AppendLog(task.ID, strings.Repeat("x", 5000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AppendLog("TASK-999", "lost"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("AppendLog(unknown task) = %v, want ErrTaskNotFound", err)
+	}
+
+	lines, err := k.ListLogs(task.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || lines[0].Line != "cloning repo" || lines[1].Line != "running tests" ||
+		len(lines[2].Line) != maxTaskLogLineBytes {
+		t.Fatalf("ListLogs = %+v", lines)
+	}
+	if rest, _ := k.ListLogs(task.ID, lines[0].Seq, 1); len(rest) != 1 || rest[0].Seq != lines[1].Seq {
+		t.Errorf("ListLogs(after %d, limit 1) = %+v", lines[0].Seq, rest)
+	}
+
+	got, err := k.GetTask(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LogLines != 3 || got.LastLogLine != lines[2].Line {
+		t.Errorf("GetTask log info = %d lines, last %.20q", got.LogLines, got.LastLogLine)
+	}
+
+	// A stream from the second line gets the rest of the backlog, then
+	// lines as they are appended.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := k.StreamLogs(ctx, task.ID, lines[0].Seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AppendLog(task.ID, "done"); err != nil {
+		t.Fatal(err)
+	}
+	var streamed []string
+	for len(streamed) < 3 {
+		select {
+		case l := <-stream:
+			streamed = append(streamed, l.Line)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("stream stalled after %d lines", len(streamed))
+		}
+	}
+	if streamed[0] != "running tests" || streamed[2] != "done" {
+		t.Errorf("streamed %.40q", streamed)
+	}
+	cancel()
+	for range stream {
+	}
+	if n := len(k.logSubs.subs); n != 0 {
+		t.Errorf("%d tasks still have subscribers after cancel", n)
+	}
+
+	if err := k.DeleteTask(task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := k.ListLogs(task.ID, 0, 0); len(left) != 0 {
+		t.Errorf("deleting the task left %d log lines", len(left))
+	}
+}
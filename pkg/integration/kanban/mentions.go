@@ -0,0 +1,142 @@
+package kanban
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// mentionPattern matches "@handle" not preceded by a word character, so
+// email addresses aren't taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_][A-Za-z0-9_.-]*[A-Za-z0-9_]|[A-Za-z0-9_])`)
+
+// taskRefPattern matches a task ID such as TASK-123.
+var taskRefPattern = regexp.MustCompile(`\bTASK-\d+\b`)
+
+// parseMentions returns the distinct @handles in content, in order.
+func parseMentions(content string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		h := strings.ToLower(m[1])
+		if !seen[h] {
+			seen[h] = true
+			handles = append(handles, h)
+		}
+	}
+	return handles
+}
+
+// parseTaskRefs returns the distinct task IDs referenced in content, in
+// order, leaving out self.
+func parseTaskRefs(content, self string) []string {
+	var refs []string
+	seen := map[string]bool{self: true}
+	for _, ref := range taskRefPattern.FindAllString(content, -1) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// processNote acts on the mentions and task references in a note that has
+// just been stored, and returns the users it notified. Nothing here is
+// allowed to fail the note: problems are logged and the rest of the note is
+// still processed.
+func (k *KanbanIntegration) processNote(ctx context.Context, taskID, content, author string) map[string]bool {
+	for _, ref := range parseTaskRefs(content, taskID) {
+		if err := k.linkTasks(ctx, taskID, ref, author); err != nil {
+			logger.WarnCtx(ctx, "kanban", "Failed to cross-link task", map[string]interface{}{
+				"task_id": taskID,
+				"ref":     ref,
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	notified := map[string]bool{}
+	handles := parseMentions(content)
+	if len(handles) == 0 {
+		return notified
+	}
+	title := taskID
+	if task, err := k.GetTaskCtx(ctx, taskID); err == nil {
+		title = fmt.Sprintf("%s %q", taskID, task.Title)
+	}
+	from := author
+	if from == "" {
+		from = "someone"
+	}
+	for _, h := range handles {
+		msg := fmt.Sprintf("%s mentioned you on %s:\n%s", from, title, content)
+		if !k.notifyUser(h, msg) {
+			continue
+		}
+		notified[h] = true
+		k.logEvent(ctx, taskID, "kanban", "note.mentioned", "@"+h)
+	}
+	return notified
+}
+
+// linkTasks records a reference from one task to another in both tasks'
+// event logs. Unknown task IDs are skipped.
+func (k *KanbanIntegration) linkTasks(ctx context.Context, from, to, author string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var exists int
+	if err := k.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE id = ?", to).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	by := ""
+	if author != "" {
+		by = " by " + author
+	}
+	_, err := k.db.ExecContext(ctx, `INSERT INTO task_events (task_id, source, event_type, summary, details)
+		VALUES (?, 'kanban', 'task.linked', ?, ?), (?, 'kanban', 'task.linked', ?, ?)`,
+		from, "References "+to+by, to,
+		to, "Referenced from "+from+by, from)
+	return err
+}
+
+// notifyUser sends text to the channel configured for handle. It reports
+// whether anything was sent; handles with no contact are ignored.
+func (k *KanbanIntegration) notifyUser(handle, text string) bool {
+	if k.bus == nil {
+		return false
+	}
+	contact, ok := k.contactFor(handle)
+	if !ok {
+		return false
+	}
+	k.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: contact.Channel,
+		ChatID:  contact.ChatID,
+		Content: text,
+	})
+	return true
+}
+
+// contactFor looks up where a user is notified. Handles match case-insensitively.
+func (k *KanbanIntegration) contactFor(handle string) (config.UserContact, bool) {
+	if k.cfg == nil {
+		return config.UserContact{}, false
+	}
+	for name, c := range k.cfg.Integrations.Users {
+		if strings.EqualFold(name, handle) && c.Channel != "" && c.ChatID != "" {
+			return c, true
+		}
+	}
+	return config.UserContact{}, false
+}
@@ -0,0 +1,73 @@
+package kanban
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestParseMentionsAndRefs(t *testing.T) {
+	content := "@Alice see TASK-002 and TASK-001, cc @bob. Mail carol@example.com; again @alice TASK-002"
+	if got, want := parseMentions(content), []string{"alice", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMentions = %v, want %v", got, want)
+	}
+	if got, want := parseTaskRefs(content, "TASK-001"), []string{"TASK-002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTaskRefs = %v, want %v", got, want)
+	}
+}
+
+func TestAddNoteMentionsAndLinks(t *testing.T) {
+	k := newTestBoard(t)
+	k.bus = bus.NewMessageBus()
+	k.cfg = config.DefaultConfig()
+	k.cfg.Integrations.Users = map[string]config.UserContact{
+		"bob": {Channel: "telegram", ChatID: "42"},
+	}
+
+	a, b := &Task{Title: "a"}, &Task{Title: "b"}
+	for _, task := range []*Task{a, b} {
+		if err := k.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unknown users and tasks are ignored rather than failing the note.
+	if err := k.AddNote(a.ID, "@bob @nobody blocked on "+b.ID+" and TASK-999", "alice"); err != nil {
+		t.Fatalf("AddNote() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := k.bus.SubscribeOutbound(ctx)
+	if !ok || msg.Channel != "telegram" || msg.ChatID != "42" {
+		t.Fatalf("notification = %+v, %v; want telegram/42", msg, ok)
+	}
+
+	for _, tc := range []struct{ task, summary string }{
+		{a.ID, "References " + b.ID + " by alice"},
+		{b.ID, "Referenced from " + a.ID + " by alice"},
+	} {
+		events, err := k.ListEvents(tc.task, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, e := range events {
+			if e.EventType == "task.linked" && e.Summary == tc.summary {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %q link event in %+v", tc.task, tc.summary, events)
+		}
+	}
+
+	notes, err := k.ListNotes(a.ID)
+	if err != nil || len(notes) != 1 || notes[0].Author != "alice" || notes[0].CreatedAt.IsZero() {
+		t.Fatalf("ListNotes = %+v, %v", notes, err)
+	}
+}
@@ -0,0 +1,115 @@
+package kanban
+
+import "strings"
+
+// LabelMeta is how the dashboard renders one enum value. Color is a CSS
+// color hint and Icon a Lucide icon name.
+type LabelMeta struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+}
+
+// BoardMeta describes every task enum and the state machine, so clients
+// can render the board without hardcoding either.
+type BoardMeta struct {
+	States      []LabelMeta               `json:"states"`
+	Categories  []LabelMeta               `json:"categories"`
+	Priorities  []LabelMeta               `json:"priorities"`
+	Sources     []LabelMeta               `json:"sources"`
+	Transitions map[TaskState][]TaskState `json:"transitions"`
+}
+
+// AllStates returns every task state in board column order.
+func AllStates() []TaskState {
+	return []TaskState{StateInbox, StatePlanned, StateRunning, StateBlocked, StateReview, StateDone}
+}
+
+// AllPriorities returns every task priority from lowest to highest.
+func AllPriorities() []string {
+	return []string{"low", "normal", "high", "critical"}
+}
+
+// AllSources returns every task source.
+func AllSources() []TaskSource {
+	return []TaskSource{SourceTelegram, SourceVSCode, SourceAPI, SourceCLI, SourceLLM, SourceManual}
+}
+
+// display is the label, color and icon of one enum value.
+type display struct{ label, color, icon string }
+
+var stateDisplay = map[TaskState]display{
+	StateInbox:   {"Inbox", "#64748b", "inbox"},
+	StatePlanned: {"Planned", "#3b82f6", "calendar"},
+	StateRunning: {"Running", "#f59e0b", "loader"},
+	StateBlocked: {"Blocked", "#ef4444", "octagon-alert"},
+	StateReview:  {"Review", "#8b5cf6", "eye"},
+	StateDone:    {"Done", "#22c55e", "circle-check"},
+}
+
+var categoryDisplay = map[TaskCategory]display{
+	CategoryCode:          {"Code", "#3b82f6", "code"},
+	CategoryDesign:        {"Design", "#ec4899", "palette"},
+	CategoryInfra:         {"Infra", "#0ea5e9", "server"},
+	CategoryBug:           {"Bug", "#ef4444", "bug"},
+	CategoryFeature:       {"Feature", "#22c55e", "sparkles"},
+	CategoryResearch:      {"Research", "#8b5cf6", "search"},
+	CategoryOps:           {"Ops", "#f97316", "wrench"},
+	CategoryPersonal:      {"Personal", "#14b8a6", "user"},
+	CategoryMeeting:       {"Meeting", "#eab308", "users"},
+	CategoryUncategorized: {"Uncategorized", "#94a3b8", "circle-help"},
+}
+
+var priorityDisplay = map[string]display{
+	"low":      {"Low", "#94a3b8", "arrow-down"},
+	"normal":   {"Normal", "#3b82f6", "minus"},
+	"high":     {"High", "#f97316", "arrow-up"},
+	"critical": {"Critical", "#ef4444", "flame"},
+}
+
+var sourceDisplay = map[TaskSource]display{
+	SourceTelegram: {"Telegram", "#229ed9", "send"},
+	SourceVSCode:   {"VS Code", "#007acc", "square-code"},
+	SourceAPI:      {"API", "#64748b", "plug"},
+	SourceCLI:      {"CLI", "#334155", "terminal"},
+	SourceLLM:      {"LLM", "#8b5cf6", "bot"},
+	SourceManual:   {"Manual", "#64748b", "pencil"},
+}
+
+// GetBoardMeta returns the display metadata for every enum value and the
+// valid transition graph.
+func GetBoardMeta() *BoardMeta {
+	meta := &BoardMeta{Transitions: make(map[TaskState][]TaskState, len(ValidTransitions))}
+	for _, s := range AllStates() {
+		meta.States = append(meta.States, labelMeta(string(s), stateDisplay[s]))
+	}
+	for _, c := range AllCategories() {
+		meta.Categories = append(meta.Categories, labelMeta(string(c), categoryDisplay[c]))
+	}
+	for _, p := range AllPriorities() {
+		meta.Priorities = append(meta.Priorities, labelMeta(p, priorityDisplay[p]))
+	}
+	for _, s := range AllSources() {
+		meta.Sources = append(meta.Sources, labelMeta(string(s), sourceDisplay[s]))
+	}
+	for from, to := range ValidTransitions {
+		meta.Transitions[from] = append([]TaskState{}, to...)
+	}
+	return meta
+}
+
+// labelMeta fills in a neutral look for values added without display
+// metadata, so they still render.
+func labelMeta(value string, d display) LabelMeta {
+	if d.label == "" {
+		d.label = strings.ToUpper(value[:1]) + value[1:]
+	}
+	if d.color == "" {
+		d.color = "#94a3b8"
+	}
+	if d.icon == "" {
+		d.icon = "circle"
+	}
+	return LabelMeta{Value: value, Label: d.label, Color: d.color, Icon: d.icon}
+}
@@ -0,0 +1,7 @@
+//go:build !nopostgres
+
+package kanban
+
+// The pgx driver backs kanban_dsn boards. Build with -tags nopostgres to
+// leave it out when the board only ever lives in SQLite.
+import _ "github.com/jackc/pgx/v5/stdlib"
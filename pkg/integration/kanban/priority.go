@@ -0,0 +1,90 @@
+package kanban
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// priorityWeights is the base score for each task priority.
+var priorityWeights = map[string]float64{
+	"low":      0,
+	"normal":   1,
+	"high":     2,
+	"critical": 3,
+}
+
+// ErrNoClaimableTask is returned by ClaimNext when nothing is waiting.
+var ErrNoClaimableTask = errors.New("no claimable task")
+
+// EffectivePriority is a task's priority weight (low=0 … critical=3) plus
+// agingPerHour for every hour since it was created. Aging keeps low-priority
+// work from starving behind a steady stream of high-priority tasks: at 0.25
+// a low task outranks a fresh critical one after 12 hours.
+func EffectivePriority(priority string, createdAt, now time.Time, agingPerHour float64) float64 {
+	base, ok := priorityWeights[priority]
+	if !ok {
+		base = priorityWeights["normal"]
+	}
+	if agingPerHour <= 0 || createdAt.IsZero() || now.Before(createdAt) {
+		return base
+	}
+	return base + now.Sub(createdAt).Hours()*agingPerHour
+}
+
+// agingRate returns the configured priority aging per hour.
+func (k *KanbanIntegration) agingRate() float64 {
+	if k.cfg == nil {
+		return 0
+	}
+	return k.cfg.Integrations.TaskAgingPerHour
+}
+
+// ClaimNext claims the waiting (inbox or planned) task with the highest
+// effective priority that agentID may take, restricted to categories when
+// given. Ties go to the older task. Returns ErrNoClaimableTask when there is
+// nothing to claim.
+func (k *KanbanIntegration) ClaimNext(agentID string, categories []TaskCategory, leaseDuration time.Duration) (*Task, error) {
+	return k.ClaimNextCtx(context.Background(), agentID, categories, leaseDuration)
+}
+
+// ClaimNextCtx is ClaimNext bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ClaimNextCtx(ctx context.Context, agentID string, categories []TaskCategory, leaseDuration time.Duration) (*Task, error) {
+	// Every waiting task is ranked, not just the recently updated ones
+	// ListTasks returns: the oldest are the ones aging is meant to lift.
+	where, args := k.filterConditions(TaskFilters{Categories: categories, ClaimableBy: agentID})
+	k.mu.RLock()
+	waiting, err := k.queryTasks(ctx, "SELECT * FROM tasks WHERE state IN ('inbox', 'planned')"+where, args...)
+	k.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(waiting, func(i, j int) bool {
+		if waiting[i].EffectivePriority != waiting[j].EffectivePriority {
+			return waiting[i].EffectivePriority > waiting[j].EffectivePriority
+		}
+		return waiting[i].CreatedAt.Before(waiting[j].CreatedAt)
+	})
+
+	// Another agent may win the race for the top task; fall through to the
+	// next one rather than failing.
+	for _, t := range waiting {
+		err := k.ClaimTaskCtx(ctx, t.ID, agentID, leaseDuration)
+		var conflict *ClaimConflictError
+		if errors.As(err, &conflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		claimed, err := k.GetTaskCtx(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		claimed.EffectivePriority = t.EffectivePriority
+		return claimed, nil
+	}
+	return nil, ErrNoClaimableTask
+}
@@ -0,0 +1,216 @@
+package kanban
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxFailureOutput caps the output kept per failed attempt. The tail is
+// kept, since that is where compilers and test runners put the summary.
+const maxFailureOutput = 4000
+
+// retryFailureLimit is how many previous failures a RetryContext carries.
+const retryFailureLimit = 3
+
+// AttemptFailure describes why one attempt at a task failed.
+type AttemptFailure struct {
+	Attempt      int       `json:"attempt"`
+	Reason       string    `json:"reason"`
+	Stage        string    `json:"stage,omitempty"` // apply, syntax, tests
+	FailingTests []string  `json:"failing_tests,omitempty"`
+	Output       string    `json:"output,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// RetryContext is what an agent needs to know before retrying a task that
+// has failed before. Prompt is ready to append to the agent's context.
+type RetryContext struct {
+	Attempt           int              `json:"attempt"` // the attempt about to start
+	LastFailureReason string           `json:"last_failure_reason"`
+	Failures          []AttemptFailure `json:"failures,omitempty"` // newest first
+	Prompt            string           `json:"prompt"`
+}
+
+// RecordFailure counts a failed attempt against a task and keeps its
+// details for the next retry. The claim is left as it is.
+func (k *KanbanIntegration) RecordFailure(taskID string, f AttemptFailure) error {
+	return k.RecordFailureCtx(context.Background(), taskID, f)
+}
+
+// RecordFailureCtx is RecordFailure bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) RecordFailureCtx(ctx context.Context, taskID string, f AttemptFailure) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.recordFailure(ctx, taskID, f)
+}
+
+// FailTask records a failed attempt and releases the task as blocked.
+func (k *KanbanIntegration) FailTask(taskID, agentID string, f AttemptFailure) error {
+	return k.FailTaskCtx(context.Background(), taskID, agentID, f)
+}
+
+// FailTaskCtx is FailTask bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) FailTaskCtx(ctx context.Context, taskID, agentID string, f AttemptFailure) error {
+	if f.Reason == "" {
+		return fmt.Errorf("failure reason is required")
+	}
+	k.mu.RLock()
+	var claimedBy string
+	err := k.db.QueryRowContext(ctx, "SELECT claimed_by FROM tasks WHERE id = ?", taskID).Scan(&claimedBy)
+	k.mu.RUnlock()
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if err != nil {
+		return err
+	}
+	if claimedBy != agentID {
+		return fmt.Errorf("%w: %s (agent %s)", ErrNotClaimHolder, taskID, agentID)
+	}
+
+	if err := k.RecordFailureCtx(ctx, taskID, f); err != nil {
+		return err
+	}
+	return k.ReleaseTaskCtx(ctx, taskID, agentID, f.Reason)
+}
+
+func (k *KanbanIntegration) recordFailure(ctx context.Context, taskID string, f AttemptFailure) error {
+	if f.At.IsZero() {
+		f.At = time.Now().UTC()
+	}
+	f.Output = tailOutput(f.Output, maxFailureOutput)
+
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE tasks SET attempts = attempts + 1,
+		last_failure_reason = ?, updated_at = ? WHERE id = ?`,
+		f.Reason, f.At.Format(time.RFC3339), taskID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT attempts FROM tasks WHERE id = ?", taskID).Scan(&f.Attempt); err != nil {
+		return err
+	}
+
+	details, _ := json.Marshal(f)
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO task_events (task_id, source, event_type, summary, details) VALUES (?, ?, ?, ?, ?)",
+		taskID, "kanban", "attempt.failed", f.Reason, string(details),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if k.bus != nil {
+		k.bus.PublishSystem(bus.SystemEvent{
+			Type:      "task.attempt_failed",
+			Source:    "kanban",
+			RequestID: logger.RequestID(ctx),
+			Data: map[string]interface{}{
+				"task_id": taskID,
+				"attempt": f.Attempt,
+				"reason":  f.Reason,
+				"stage":   f.Stage,
+			},
+		})
+	}
+	return nil
+}
+
+// RetryContextFor returns the failure history an agent should see before
+// working on task, or nil if the task has never failed.
+func (k *KanbanIntegration) RetryContextFor(task *Task) (*RetryContext, error) {
+	return k.RetryContextForCtx(context.Background(), task)
+}
+
+// RetryContextForCtx is RetryContextFor bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) RetryContextForCtx(ctx context.Context, task *Task) (*RetryContext, error) {
+	if task == nil || (task.Attempts == 0 && task.LastFailureReason == "") {
+		return nil, nil
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rows, err := k.db.QueryContext(ctx, `SELECT details FROM task_events
+		WHERE task_id = ? AND event_type = 'attempt.failed'
+		ORDER BY id DESC LIMIT ?`, task.ID, retryFailureLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rc := &RetryContext{
+		Attempt:           task.Attempts + 1,
+		LastFailureReason: task.LastFailureReason,
+	}
+	for rows.Next() {
+		var details string
+		if err := rows.Scan(&details); err != nil {
+			return nil, err
+		}
+		var f AttemptFailure
+		if json.Unmarshal([]byte(details), &f) == nil {
+			rc.Failures = append(rc.Failures, f)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rc.Prompt = rc.formatPrompt(task)
+	return rc, nil
+}
+
+// formatPrompt renders the retry context as a markdown section.
+func (rc *RetryContext) formatPrompt(task *Task) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Previous Attempts\n\n")
+	fmt.Fprintf(&sb, "This is attempt %d at task %q. Earlier attempts failed; avoid repeating the same mistake.\n\n",
+		rc.Attempt, task.Title)
+	if rc.LastFailureReason != "" {
+		fmt.Fprintf(&sb, "Last failure: %s\n", rc.LastFailureReason)
+	}
+	for _, f := range rc.Failures {
+		fmt.Fprintf(&sb, "\n### Attempt %d", f.Attempt)
+		if f.Stage != "" {
+			fmt.Fprintf(&sb, " (%s failed)", f.Stage)
+		}
+		fmt.Fprintf(&sb, "\n\n%s\n", f.Reason)
+		if len(f.FailingTests) > 0 {
+			fmt.Fprintf(&sb, "\nFailing tests: %s\n", strings.Join(f.FailingTests, ", "))
+		}
+		if f.Output != "" {
+			fmt.Fprintf(&sb, "\n```\n%s\n```\n", strings.TrimRight(f.Output, "\n"))
+		}
+	}
+	return sb.String()
+}
+
+// tailOutput keeps the last max bytes of s, starting on a line boundary
+// where possible.
+func tailOutput(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[len(s)-max:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
@@ -0,0 +1,68 @@
+package kanban
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailTaskFeedsRetryContext(t *testing.T) {
+	k := newTestBoard(t)
+
+	task := &Task{Title: "fix parser"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	if rc, err := k.RetryContextFor(task); err != nil || rc != nil {
+		t.Fatalf("fresh task retry context = %v, %v; want nil", rc, err)
+	}
+
+	if err := k.ClaimTask(task.ID, "agent-a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.FailTask(task.ID, "agent-b", AttemptFailure{Reason: "tests failed"}); err == nil {
+		t.Fatal("FailTask by a non-holder succeeded")
+	}
+	err := k.FailTask(task.ID, "agent-a", AttemptFailure{
+		Reason:       "tests failed",
+		Stage:        "tests",
+		FailingTests: []string{"TestParse"},
+		Output:       strings.Repeat("x", maxFailureOutput) + "\n--- FAIL: TestParse\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := k.GetTask(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attempts != 1 || got.LastFailureReason != "tests failed" || got.ClaimedBy != "" || got.State != StateBlocked {
+		t.Fatalf("after failure: attempts=%d reason=%q claimed_by=%q state=%s",
+			got.Attempts, got.LastFailureReason, got.ClaimedBy, got.State)
+	}
+
+	if err := k.RecordFailure(task.ID, AttemptFailure{Reason: "syntax error", Stage: "syntax"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = k.GetTask(task.ID)
+
+	rc, err := k.RetryContextFor(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc == nil || rc.Attempt != 3 || len(rc.Failures) != 2 {
+		t.Fatalf("retry context = %+v, want attempt 3 with 2 failures", rc)
+	}
+	if rc.Failures[0].Attempt != 2 || rc.Failures[1].Attempt != 1 {
+		t.Fatalf("failures not newest first: %+v", rc.Failures)
+	}
+	if out := rc.Failures[1].Output; len(out) > maxFailureOutput+len("...\n") || !strings.Contains(out, "--- FAIL: TestParse") {
+		t.Fatalf("output not trimmed to its tail: %d bytes", len(out))
+	}
+	for _, want := range []string{"attempt 3", "Last failure: syntax error", "Failing tests: TestParse"} {
+		if !strings.Contains(rc.Prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, rc.Prompt)
+		}
+	}
+}
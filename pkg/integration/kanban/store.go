@@ -0,0 +1,298 @@
+package kanban
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backend is the database engine behind the board. Queries are written
+// once, in SQLite's dialect with ? placeholders; a backend opens the
+// connection pool and supplies the few statements that cannot be shared.
+type backend interface {
+	// open connects and configures the pool.
+	open() (*sql.DB, error)
+	// rebind rewrites the ? placeholders of a query for the driver.
+	rebind(query string) string
+	// ddl adapts the SQLite schema to the backend.
+	ddl(schema string) string
+	schemaVersion(ctx context.Context, db *sql.DB) (int, error)
+	setSchemaVersion(ctx context.Context, db *sql.DB, version int) error
+	hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error)
+	// checkpoint makes the stored data self-contained before a close.
+	checkpoint(ctx context.Context, db *sql.DB) error
+	// before is a condition that the RFC 3339 timestamp in column is
+	// earlier than the one bound to the following placeholder.
+	before(column string) string
+	// insertOrder is an ORDER BY term for the order rows were inserted in.
+	insertOrder() string
+	// String describes where the board lives, without credentials.
+	String() string
+}
+
+// newBackend picks the backend for a board: Postgres when dsn is a
+// postgres:// URL, otherwise the SQLite file at path.
+func newBackend(dsn, path string) (backend, error) {
+	if dsn == "" {
+		return &sqliteBackend{path: path}, nil
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return &postgresBackend{dsn: dsn}, nil
+	}
+	return nil, fmt.Errorf("unsupported kanban dsn %q: want postgres:// or postgresql://", redactDSN(dsn))
+}
+
+// sqliteBackend stores the board in a single SQLite file.
+type sqliteBackend struct {
+	path string
+}
+
+func (s *sqliteBackend) open() (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_foreign_keys=ON&_busy_timeout=%d", s.path, sqliteBusyTimeoutMs)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+	db.SetMaxIdleConns(sqliteMaxOpenConns)
+	db.SetConnMaxLifetime(0)
+	return db, nil
+}
+
+func (s *sqliteBackend) rebind(query string) string { return query }
+
+func (s *sqliteBackend) ddl(schema string) string { return schema }
+
+func (s *sqliteBackend) schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+func (s *sqliteBackend) setSchemaVersion(ctx context.Context, db *sql.DB, version int) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
+
+func (s *sqliteBackend) hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// checkpoint folds the WAL back into the main file.
+func (s *sqliteBackend) checkpoint(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// before compares with julianday because stored timestamps may carry any
+// UTC offset, so they don't sort as text.
+func (s *sqliteBackend) before(column string) string {
+	return fmt.Sprintf("julianday(%s) < julianday(?)", column)
+}
+
+func (s *sqliteBackend) insertOrder() string { return "rowid" }
+
+func (s *sqliteBackend) String() string { return s.path }
+
+// postgresBackend stores the board in a PostgreSQL database, so several
+// picoclaw instances can share one board. The pgx driver is linked in
+// unless the build uses the nopostgres tag.
+type postgresBackend struct {
+	dsn string
+}
+
+// postgresDrivers are the database/sql driver names tried, in order.
+var postgresDrivers = []string{"pgx", "postgres"}
+
+func (p *postgresBackend) open() (*sql.DB, error) {
+	registered := make(map[string]bool)
+	for _, name := range sql.Drivers() {
+		registered[name] = true
+	}
+	for _, name := range postgresDrivers {
+		if registered[name] {
+			return sql.Open(name, p.dsn)
+		}
+	}
+	return nil, fmt.Errorf("no postgres driver compiled in (want one of %s)", strings.Join(postgresDrivers, ", "))
+}
+
+// rebind numbers placeholders $1, $2, … skipping ? inside string literals.
+func (p *postgresBackend) rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n, quoted := 0, false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// ddl swaps SQLite's autoincrement keys and datetime('now') defaults for
+// their Postgres equivalents. The defaults keep SQLite's text format so
+// parseDBTime reads rows from either backend.
+func (p *postgresBackend) ddl(schema string) string {
+	return strings.NewReplacer(
+		"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+		"DEFAULT (datetime('now'))", "DEFAULT (to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'))",
+	).Replace(schema)
+}
+
+// Postgres has no user_version, so the schema version lives in system_kv.
+const schemaVersionKey = "schema_version"
+
+func (p *postgresBackend) schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var value string
+	err := db.QueryRowContext(ctx, "SELECT value FROM system_kv WHERE key = $1", schemaVersionKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (p *postgresBackend) setSchemaVersion(ctx context.Context, db *sql.DB, version int) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO system_kv (key, value, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		schemaVersionKey, strconv.Itoa(version), time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (p *postgresBackend) hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2`,
+		table, column).Scan(&n)
+	return n > 0, err
+}
+
+func (p *postgresBackend) checkpoint(ctx context.Context, db *sql.DB) error { return nil }
+
+func (p *postgresBackend) before(column string) string {
+	return fmt.Sprintf("CAST(%s AS TIMESTAMPTZ) < CAST(? AS TIMESTAMPTZ)", column)
+}
+
+// insertOrder uses the physical row position; watcher rows are never
+// updated, so it matches insertion order.
+func (p *postgresBackend) insertOrder() string { return "ctid" }
+
+func (p *postgresBackend) String() string { return redactDSN(p.dsn) }
+
+// redactDSN hides the password in a connection URL for logs and errors.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return dsn
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
+// boardDB is the board's connection pool. It rebinds every query for its
+// backend, so callers write SQLite-style ? placeholders whatever the engine.
+// The pool is a named field rather than embedded so that only the methods
+// below, which all rebind, can be called on it.
+type boardDB struct {
+	db *sql.DB
+	b  backend
+}
+
+func (d *boardDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.db.Exec(d.b.rebind(query), args...)
+}
+
+func (d *boardDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, d.b.rebind(query), args...)
+}
+
+func (d *boardDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.Query(d.b.rebind(query), args...)
+}
+
+func (d *boardDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.b.rebind(query), args...)
+}
+
+func (d *boardDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRow(d.b.rebind(query), args...)
+}
+
+func (d *boardDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.b.rebind(query), args...)
+}
+
+func (d *boardDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*boardTx, error) {
+	tx, err := d.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &boardTx{tx: tx, b: d.b}, nil
+}
+
+// checkpoint makes the stored data self-contained, e.g. before a close.
+func (d *boardDB) checkpoint(ctx context.Context) error {
+	return d.b.checkpoint(ctx, d.db)
+}
+
+func (d *boardDB) Ping() error { return d.db.Ping() }
+
+func (d *boardDB) Close() error { return d.db.Close() }
+
+// boardTx is a transaction on a boardDB, rebinding queries the same way.
+type boardTx struct {
+	tx *sql.Tx
+	b  backend
+}
+
+func (t *boardTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, t.b.rebind(query), args...)
+}
+
+func (t *boardTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, t.b.rebind(query), args...)
+}
+
+func (t *boardTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, t.b.rebind(query), args...)
+}
+
+func (t *boardTx) Commit() error { return t.tx.Commit() }
+
+func (t *boardTx) Rollback() error { return t.tx.Rollback() }
@@ -0,0 +1,203 @@
+package kanban
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewBackend(t *testing.T) {
+	if b, err := newBackend("", "/tmp/kanban.db"); err != nil || b.String() != "/tmp/kanban.db" {
+		t.Errorf("empty dsn = %v, %v; want sqlite at the path", b, err)
+	}
+	b, err := newBackend("postgres://bot:secret@db:5432/board", "/tmp/kanban.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*postgresBackend); !ok || strings.Contains(b.String(), "secret") {
+		t.Errorf("postgres dsn = %T %s", b, b)
+	}
+	if _, err := newBackend("mysql://db/board", ""); err == nil {
+		t.Error("unsupported dsn accepted")
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	p := &postgresBackend{}
+	cases := map[string]string{
+		"SELECT 1": "SELECT 1",
+		"UPDATE tasks SET state = ? WHERE id = ?":         "UPDATE tasks SET state = $1 WHERE id = $2",
+		"SELECT * FROM tasks WHERE title = '?' OR id = ?": "SELECT * FROM tasks WHERE title = '?' OR id = $1",
+		"SELECT 'it''s ?', ?":                             "SELECT 'it''s ?', $1",
+	}
+	for in, want := range cases {
+		if got := p.rebind(in); got != want {
+			t.Errorf("rebind(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	ddl := p.ddl("id INTEGER PRIMARY KEY AUTOINCREMENT, created_at TEXT NOT NULL DEFAULT (datetime('now'))")
+	if strings.Contains(ddl, "AUTOINCREMENT") || strings.Contains(ddl, "datetime(") {
+		t.Errorf("ddl left sqlite syntax: %s", ddl)
+	}
+}
+
+func TestBackupNeedsSQLite(t *testing.T) {
+	k := newTestBoard(t)
+	k.backend = &postgresBackend{dsn: "postgres://db/board"}
+	if err := k.Backup(context.Background(), t.TempDir()+"/b.db"); err == nil {
+		t.Error("Backup on postgres should fail")
+	}
+}
+
+func TestSQLiteOpenWaitsForWriter(t *testing.T) {
+	ctx := context.Background()
+	k := newTestBoard(t)
+	if n := k.db.db.Stats().MaxOpenConnections; n != sqliteMaxOpenConns {
+		t.Errorf("MaxOpenConnections = %d, want %d", n, sqliteMaxOpenConns)
+	}
+	var timeout int
+	if err := k.db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != sqliteBusyTimeoutMs {
+		t.Errorf("busy_timeout = %d, %v; want %d", timeout, err, sqliteBusyTimeoutMs)
+	}
+
+	// Another process holds the write lock for a moment; a board write
+	// waits it out under busy_timeout instead of failing with SQLITE_BUSY.
+	other, err := (&sqliteBackend{path: k.dbPath}).open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	conn, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- k.CreateTask(&Task{Title: "waits"}) }()
+	time.Sleep(200 * time.Millisecond)
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("CreateTask() while another writer held the lock: %v", err)
+	}
+	if waited := time.Since(start); waited < 200*time.Millisecond {
+		t.Errorf("CreateTask() returned after %v, before the lock was released", waited)
+	}
+}
+
+// newPostgresBoard starts a board in a fresh schema of the database at
+// PICOCLAW_TEST_POSTGRES_DSN, dropped again when the test ends. The test is
+// skipped when the variable is unset.
+func newPostgresBoard(t *testing.T) *KanbanIntegration {
+	t.Helper()
+	dsn := os.Getenv("PICOCLAW_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PICOCLAW_TEST_POSTGRES_DSN not set")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	schema := fmt.Sprintf("picoclaw_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	k := &KanbanIntegration{backend: &postgresBackend{dsn: dsn + sep + "search_path=" + schema}}
+	if err := k.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(func() { k.Stop(context.Background()) })
+	return k
+}
+
+func TestPostgresBoard(t *testing.T) {
+	k := newPostgresBoard(t)
+	ctx := context.Background()
+
+	due := time.Now().Add(-time.Hour)
+	task := &Task{Title: "shared board", Category: CategoryCode, DueDate: &due}
+	other := &Task{Title: "second"}
+	for _, tk := range []*Task{task, other} {
+		if err := k.CreateTaskCtx(ctx, tk); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+	}
+	if err := k.UpdateTaskCtx(ctx, task.ID, map[string]interface{}{"llm_categorized": true}); err != nil {
+		t.Fatalf("UpdateTask() error: %v", err)
+	}
+
+	if err := k.ClaimTaskCtx(ctx, task.ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("ClaimTask() error: %v", err)
+	}
+	var conflict *ClaimConflictError
+	if err := k.ClaimTaskCtx(ctx, task.ID, "agent-b", time.Hour); !errors.As(err, &conflict) {
+		t.Errorf("second ClaimTask() error = %v, want *ClaimConflictError", err)
+	}
+
+	if err := k.AddRichNoteCtx(ctx, task.ID, "see log", "alice", NoteOptions{Attachments: []string{"a.log"}, Quiet: true}); err != nil {
+		t.Fatalf("AddRichNote() error: %v", err)
+	}
+	notes, err := k.ListNotesCtx(ctx, task.ID)
+	if err != nil || len(notes) != 1 || notes[0].ID == 0 || len(notes[0].Attachments) != 1 {
+		t.Errorf("ListNotes() = %+v, %v", notes, err)
+	}
+
+	for _, u := range []string{"bob", "alice", "bob"} {
+		if err := k.AddWatcherCtx(ctx, task.ID, u); err != nil {
+			t.Fatalf("AddWatcher(%s) error: %v", u, err)
+		}
+	}
+	if got, _ := k.ListWatchersCtx(ctx, task.ID); !reflect.DeepEqual(got, []string{"bob", "alice"}) {
+		t.Errorf("ListWatchers() = %v, want [bob alice]", got)
+	}
+
+	if err := k.AppendLogCtx(ctx, task.ID, "one\ntwo"); err != nil {
+		t.Fatalf("AppendLog() error: %v", err)
+	}
+	if lines, err := k.ListLogsCtx(ctx, task.ID, 0, 10); err != nil || len(lines) != 2 || lines[1].Seq <= lines[0].Seq {
+		t.Errorf("ListLogs() = %+v, %v", lines, err)
+	}
+
+	sum, err := k.GetDashboardSummaryCtx(ctx)
+	if err != nil {
+		t.Fatalf("GetDashboardSummary() error: %v", err)
+	}
+	if sum.ActiveClaims != 1 || sum.Overdue != 1 || sum.States["total"] != 2 {
+		t.Errorf("GetDashboardSummary() = %+v", sum)
+	}
+
+	if err := k.DeleteTaskCtx(ctx, task.ID); err != nil {
+		t.Fatalf("DeleteTask() error: %v", err)
+	}
+
+	// Reopening finds the schema current and leaves the data alone
+	if err := k.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Start(ctx); err != nil {
+		t.Fatalf("restart error: %v", err)
+	}
+	if got, err := k.GetTaskCtx(ctx, other.ID); err != nil || got.Title != "second" {
+		t.Errorf("GetTask() after restart = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,145 @@
+package kanban
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// AddWatcher subscribes a user to changes on a task. User IDs are the same
+// handles used in @mentions and are stored lowercased. Adding an existing
+// watcher is a no-op.
+func (k *KanbanIntegration) AddWatcher(taskID, userID string) error {
+	return k.AddWatcherCtx(context.Background(), taskID, userID)
+}
+
+// AddWatcherCtx is AddWatcher bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) AddWatcherCtx(ctx context.Context, taskID, userID string) error {
+	if strings.TrimSpace(userID) == "" {
+		return fmt.Errorf("user id is required")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var exists int
+	if err := k.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE id = ?", taskID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	return k.addWatcher(ctx, taskID, userID)
+}
+
+// RemoveWatcher unsubscribes a user from a task.
+func (k *KanbanIntegration) RemoveWatcher(taskID, userID string) error {
+	return k.RemoveWatcherCtx(context.Background(), taskID, userID)
+}
+
+// RemoveWatcherCtx is RemoveWatcher bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) RemoveWatcherCtx(ctx context.Context, taskID, userID string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	_, err := k.db.ExecContext(ctx, "DELETE FROM task_watchers WHERE task_id = ? AND user_id = ?",
+		taskID, normalizeUserID(userID))
+	return err
+}
+
+// ListWatchers returns the users watching a task, in the order they started.
+func (k *KanbanIntegration) ListWatchers(taskID string) ([]string, error) {
+	return k.ListWatchersCtx(context.Background(), taskID)
+}
+
+// ListWatchersCtx is ListWatchers bound to ctx; cancelling ctx aborts the query.
+func (k *KanbanIntegration) ListWatchersCtx(ctx context.Context, taskID string) ([]string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.listWatchers(ctx, taskID)
+}
+
+// addWatcher inserts a watcher row. The caller holds k.mu.
+func (k *KanbanIntegration) addWatcher(ctx context.Context, taskID, userID string) error {
+	_, err := k.db.ExecContext(ctx,
+		"INSERT INTO task_watchers (task_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		taskID, normalizeUserID(userID))
+	return err
+}
+
+// listWatchers reads a task's watchers. The caller holds k.mu.
+func (k *KanbanIntegration) listWatchers(ctx context.Context, taskID string) ([]string, error) {
+	rows, err := k.db.QueryContext(ctx,
+		"SELECT user_id FROM task_watchers WHERE task_id = ? ORDER BY "+k.db.b.insertOrder(), taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// notifyWatchers tells every watcher of a task about a change. The actor
+// who made the change and anyone in skip (already told about this change,
+// e.g. by a mention) are left out, and watchers sharing a chat get one
+// message between them. The caller holds k.mu.
+func (k *KanbanIntegration) notifyWatchers(ctx context.Context, taskID, actor, change string, skip map[string]bool) {
+	if k.bus == nil || k.cfg == nil {
+		return
+	}
+	users, err := k.listWatchers(ctx, taskID)
+	if err != nil {
+		logger.WarnCtx(ctx, "kanban", "Failed to load task watchers", map[string]interface{}{
+			"task_id": taskID,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if len(users) == 0 {
+		return
+	}
+
+	label := taskID
+	var title string
+	if k.db.QueryRowContext(ctx, "SELECT title FROM tasks WHERE id = ?", taskID).Scan(&title) == nil {
+		label = fmt.Sprintf("%s %q", taskID, title)
+	}
+	text := fmt.Sprintf("%s: %s", label, change)
+
+	actor = normalizeUserID(actor)
+	sent := map[string]bool{}
+	for _, u := range users {
+		if u == actor || skip[u] {
+			continue
+		}
+		contact, ok := k.contactFor(u)
+		if !ok {
+			continue
+		}
+		key := contact.Channel + "/" + contact.ChatID
+		if sent[key] {
+			continue
+		}
+		sent[key] = true
+		k.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: contact.Channel,
+			ChatID:  contact.ChatID,
+			Content: text,
+		})
+	}
+}
+
+func normalizeUserID(userID string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(userID), "@"))
+}
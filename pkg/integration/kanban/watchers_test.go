@@ -0,0 +1,91 @@
+package kanban
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// drainOutbound returns every message queued on the bus's outbound channel.
+func drainOutbound(t *testing.T, mb *bus.MessageBus) []bus.OutboundMessage {
+	t.Helper()
+	var msgs []bus.OutboundMessage
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		msg, ok := mb.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			return msgs
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestWatchersNotifiedOncePerChange(t *testing.T) {
+	k := newTestBoard(t)
+	k.bus = bus.NewMessageBus()
+	k.cfg = config.DefaultConfig()
+	k.cfg.Integrations.Users = map[string]config.UserContact{
+		"alice": {Channel: "telegram", ChatID: "1"},
+		"bob":   {Channel: "telegram", ChatID: "2"},
+		"carol": {Channel: "telegram", ChatID: "3"},
+		"team":  {Channel: "telegram", ChatID: "3"}, // shares carol's chat
+	}
+
+	task := &Task{Title: "ship it", Assignee: "Bob"}
+	if err := k.CreateTask(task); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"@Alice", "carol", "team"} {
+		if err := k.AddWatcher(task.ID, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watchers, err := k.ListWatchers(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bob", "alice", "carol", "team"}; !reflect.DeepEqual(watchers, want) {
+		t.Fatalf("watchers = %v, want %v", watchers, want)
+	}
+
+	// The actor isn't told about their own change; a shared chat gets one message.
+	if err := k.TransitionTask(task.ID, StatePlanned, "", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	chats := func(msgs []bus.OutboundMessage) []string {
+		var ids []string
+		for _, m := range msgs {
+			ids = append(ids, m.ChatID)
+		}
+		return ids
+	}
+	if got, want := chats(drainOutbound(t, k.bus)), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("transition notified chats %v, want %v", got, want)
+	}
+
+	// A mentioned watcher gets the mention, not a second watcher ping.
+	if err := k.AddNote(task.ID, "@bob can you look?", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chats(drainOutbound(t, k.bus)), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("note notified chats %v, want %v", got, want)
+	}
+
+	if err := k.RemoveWatcher(task.ID, "CAROL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.RemoveWatcher(task.ID, "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.LogEvent(task.ID, "bob", "diff.applied", "fix"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chats(drainOutbound(t, k.bus)), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("event notified chats %v, want %v", got, want)
+	}
+}
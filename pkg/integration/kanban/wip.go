@@ -0,0 +1,44 @@
+package kanban
+
+import (
+	"context"
+	"fmt"
+)
+
+// WIPLimitError is returned when a task would move into a state that
+// already holds as many tasks as its work-in-progress limit allows.
+type WIPLimitError struct {
+	State TaskState
+	Limit int
+	Count int
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("%s is at its WIP limit of %d (%d tasks); finish or move one first", e.State, e.Limit, e.Count)
+}
+
+// wipLimit is the configured work-in-progress limit for state
+// (integrations.wip_limits); 0 means unlimited.
+func (k *KanbanIntegration) wipLimit(state TaskState) int {
+	if k.cfg == nil {
+		return 0
+	}
+	return k.cfg.Integrations.WIPLimits[string(state)]
+}
+
+// checkWIPLimit returns a *WIPLimitError when one more task can't enter
+// state. Callers hold k.mu, so the count can't change before they write.
+func (k *KanbanIntegration) checkWIPLimit(ctx context.Context, state TaskState) error {
+	limit := k.wipLimit(state)
+	if limit <= 0 {
+		return nil
+	}
+	var count int
+	if err := k.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE state = ?", string(state)).Scan(&count); err != nil {
+		return fmt.Errorf("count %s tasks: %w", state, err)
+	}
+	if count >= limit {
+		return &WIPLimitError{State: state, Limit: limit, Count: count}
+	}
+	return nil
+}
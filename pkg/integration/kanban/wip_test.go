@@ -0,0 +1,61 @@
+package kanban
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestWIPLimits(t *testing.T) {
+	k := newTestBoard(t)
+	k.cfg = &config.Config{}
+	k.cfg.Integrations.WIPLimits = map[string]int{"running": 2, "review": 0}
+
+	var tasks []*Task
+	for _, title := range []string{"a", "b", "c"} {
+		task := &Task{Title: title}
+		if err := k.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask() error: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := k.TransitionTask(tasks[0].ID, StateRunning, "", "test"); err != nil {
+		t.Fatalf("first transition: %v", err)
+	}
+	if err := k.ClaimTask(tasks[1].ID, "agent-a", time.Hour); err != nil {
+		t.Fatalf("claim under the limit: %v", err)
+	}
+
+	err := k.TransitionTask(tasks[2].ID, StateRunning, "", "test")
+	var wip *WIPLimitError
+	if !errors.As(err, &wip) || wip.State != StateRunning || wip.Limit != 2 || wip.Count != 2 {
+		t.Fatalf("transition into a full state: err = %v, want a WIPLimitError for running 2/2", err)
+	}
+	if err := k.ClaimTask(tasks[2].ID, "agent-b", time.Hour); !errors.As(err, &wip) {
+		t.Errorf("claim into a full state: err = %v, want a WIPLimitError", err)
+	}
+	// Renewing a claim on a task already running doesn't need a free slot.
+	if err := k.ClaimTask(tasks[1].ID, "agent-a", time.Hour); err != nil {
+		t.Errorf("renewing a claim at the limit: %v", err)
+	}
+
+	stats, err := k.GetBoardStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["running"] != 2 || stats["running_wip_limit"] != 2 || stats["total"] != 3 {
+		t.Errorf("stats = %v", stats)
+	}
+	if _, ok := stats["review_wip_limit"]; ok {
+		t.Error("a 0 limit is unlimited and should not be reported")
+	}
+
+	if err := k.TransitionTask(tasks[0].ID, StateDone, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.TransitionTask(tasks[2].ID, StateRunning, "", "test"); err != nil {
+		t.Errorf("transition after a slot freed up: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying a request correlation ID.
+// Log calls made through the *Ctx functions with that context include it
+// as the "request_id" field.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID stored in ctx, or "" if none.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID adds ctx's request ID to fields, copying so the caller's map
+// is left alone.
+func withRequestID(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	id := RequestID(ctx)
+	if id == "" {
+		return fields
+	}
+	out := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		out[k] = v
+	}
+	out["request_id"] = id
+	return out
+}
+
+func DebugCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logMessage(DEBUG, component, message, withRequestID(ctx, fields))
+}
+
+func InfoCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logMessage(INFO, component, message, withRequestID(ctx, fields))
+}
+
+func WarnCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logMessage(WARN, component, message, withRequestID(ctx, fields))
+}
+
+func ErrorCtx(ctx context.Context, component string, message string, fields map[string]interface{}) {
+	logMessage(ERROR, component, message, withRequestID(ctx, fields))
+}
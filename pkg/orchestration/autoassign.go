@@ -0,0 +1,131 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultAutoAssignLease is how long an auto-assigned claim holds its task
+// before the agent has to renew it.
+const DefaultAutoAssignLease = 30 * time.Minute
+
+// TaskBoard is the system of record for claims, as auto-assignment sees it.
+type TaskBoard interface {
+	// Claim claims taskID for agentID, failing if another agent holds it.
+	Claim(ctx context.Context, taskID, agentID string, lease time.Duration) error
+	// Plan moves taskID to planned, where it waits for an agent to claim it.
+	Plan(ctx context.Context, taskID string) error
+	// Release gives up agentID's claim on taskID, returning it to planned.
+	Release(ctx context.Context, taskID, agentID string) error
+}
+
+// AutoAssign routes a newly created task to a free agent for its category
+// and claims it on board for that agent. When every capable agent is at
+// its MaxConcurrent limit, or none handles the category, the task is moved
+// to planned instead and "" is returned. If the orchestrator can't record
+// the claim, the board claim is released so no untracked agent holds it.
+func (o *Orchestrator) AutoAssign(ctx context.Context, board TaskBoard, taskID, category string, lease time.Duration) (string, error) {
+	agentID, err := o.RouteTask(category)
+	if err != nil {
+		return "", board.Plan(ctx, taskID)
+	}
+	if err := board.Claim(ctx, taskID, agentID, lease); err != nil {
+		return "", err
+	}
+	// Recorded straight away so the next task routed sees the agent's load
+	if _, err := o.ClaimTask(ctx, taskID, agentID, lease); err != nil {
+		if relErr := board.Release(ctx, taskID, agentID); relErr != nil {
+			return "", fmt.Errorf("%w; releasing board claim: %v", err, relErr)
+		}
+		return "", err
+	}
+	return agentID, nil
+}
+
+// RunAutoAssigner auto-assigns tasks as they are created, for the
+// categories that opted in, and publishes task.assigned to tell the chosen
+// agent. Tasks created already claimed, running or finished are left
+// alone. It blocks until ctx is done.
+func (o *Orchestrator) RunAutoAssigner(ctx context.Context, mb *bus.MessageBus, board TaskBoard, categories []string, lease time.Duration) {
+	if len(categories) == 0 {
+		return
+	}
+	optedIn := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		optedIn[c] = true
+	}
+	if lease <= 0 {
+		lease = DefaultAutoAssignLease
+	}
+
+	events := mb.SubscribeSystem("auto-assign")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case raw, ok := <-events:
+			if !ok {
+				return
+			}
+			evt, ok := raw.(bus.SystemEvent)
+			if !ok || evt.Type != "task.created" {
+				continue
+			}
+			data, _ := evt.Data.(map[string]interface{})
+			taskID, category, state := eventString(data["task_id"]), eventString(data["category"]), eventString(data["state"])
+			if taskID == "" || !optedIn[category] || (state != "inbox" && state != "planned") {
+				continue
+			}
+			o.autoAssign(ctx, mb, board, evt.RequestID, taskID, category, lease)
+		}
+	}
+}
+
+func (o *Orchestrator) autoAssign(ctx context.Context, mb *bus.MessageBus, board TaskBoard, requestID, taskID, category string, lease time.Duration) {
+	agentID, err := o.AutoAssign(ctx, board, taskID, category, lease)
+	if err != nil {
+		logger.WarnCF("orchestration", "Auto-assignment failed", map[string]interface{}{
+			"task_id":  taskID,
+			"category": category,
+			"error":    err.Error(),
+		})
+		return
+	}
+	if agentID == "" {
+		logger.InfoCF("orchestration", "No free agent, task left planned", map[string]interface{}{
+			"task_id":  taskID,
+			"category": category,
+		})
+		return
+	}
+
+	logger.InfoCF("orchestration", "Task auto-assigned", map[string]interface{}{
+		"task_id":  taskID,
+		"agent_id": agentID,
+	})
+	mb.PublishSystem(bus.SystemEvent{
+		Type:      "task.assigned",
+		Source:    "orchestration",
+		RequestID: requestID,
+		Data: map[string]interface{}{
+			"task_id":    taskID,
+			"agent_id":   agentID,
+			"category":   category,
+			"expires_at": time.Now().Add(lease).UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// eventString reads an event field as a string. Kanban publishes state
+// and category as its own string types, so they are formatted rather than
+// asserted.
+func eventString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
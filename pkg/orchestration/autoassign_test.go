@@ -0,0 +1,141 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// fakeBoard records what auto-assignment did to each task.
+type fakeBoard struct {
+	mu       sync.Mutex
+	claimed  map[string]string // taskID -> agentID
+	planned  map[string]bool
+	released map[string]string // taskID -> agentID
+}
+
+func newFakeBoard() *fakeBoard {
+	return &fakeBoard{claimed: map[string]string{}, planned: map[string]bool{}, released: map[string]string{}}
+}
+
+func (b *fakeBoard) Claim(ctx context.Context, taskID, agentID string, lease time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.claimed[taskID] = agentID
+	return nil
+}
+
+func (b *fakeBoard) Plan(ctx context.Context, taskID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.planned[taskID] = true
+	return nil
+}
+
+func (b *fakeBoard) Release(ctx context.Context, taskID, agentID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.claimed[taskID] == agentID {
+		delete(b.claimed, taskID)
+		b.planned[taskID] = true
+	}
+	b.released[taskID] = agentID
+	return nil
+}
+
+func TestAutoAssignReleasesUntrackedClaim(t *testing.T) {
+	o := NewOrchestrator()
+	o.RegisterAgent(AgentCapability{AgentID: "coder", Categories: []string{"code"}, MaxConcurrent: 2})
+	board := newFakeBoard()
+	ctx := context.Background()
+
+	// The orchestrator already tracks a claim on the task, so recording the
+	// new one fails after the board claim went through.
+	if _, err := o.ClaimTask(ctx, "TASK-001", "reviewer", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := o.AutoAssign(ctx, board, "TASK-001", "code", time.Minute)
+	if err == nil || agent != "" {
+		t.Fatalf("AutoAssign = %q, %v; want an error", agent, err)
+	}
+	if board.released["TASK-001"] != "coder" || board.claimed["TASK-001"] != "" {
+		t.Errorf("board claim not released: claimed %v, released %v", board.claimed, board.released)
+	}
+}
+
+func TestAutoAssignRespectsConcurrency(t *testing.T) {
+	o := NewOrchestrator()
+	o.RegisterAgent(AgentCapability{AgentID: "coder", Categories: []string{"code"}, MaxConcurrent: 1})
+	board := newFakeBoard()
+	ctx := context.Background()
+
+	agent, err := o.AutoAssign(ctx, board, "TASK-001", "code", time.Minute)
+	if err != nil || agent != "coder" || board.claimed["TASK-001"] != "coder" {
+		t.Fatalf("first AutoAssign = %q, %v; claims %v", agent, err, board.claimed)
+	}
+	if a, ok := o.GetAssignment("TASK-001"); !ok || a.AgentID != "coder" {
+		t.Errorf("assignment = %+v, want claimed by coder", a)
+	}
+
+	// coder is at its limit, so the next task waits in planned
+	agent, err = o.AutoAssign(ctx, board, "TASK-002", "code", time.Minute)
+	if err != nil || agent != "" || !board.planned["TASK-002"] || board.claimed["TASK-002"] != "" {
+		t.Errorf("AutoAssign over the limit = %q, %v; want the task planned", agent, err)
+	}
+	if agent, _ := o.AutoAssign(ctx, board, "TASK-003", "design", time.Minute); agent != "" || !board.planned["TASK-003"] {
+		t.Errorf("AutoAssign with no capable agent = %q, want the task planned", agent)
+	}
+}
+
+func TestRunAutoAssignerOptIn(t *testing.T) {
+	o := NewOrchestrator()
+	o.RegisterAgent(AgentCapability{AgentID: "coder", Categories: []string{"*"}})
+	board := newFakeBoard()
+	mb := bus.NewMessageBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		o.RunAutoAssigner(ctx, mb, board, []string{"code"}, time.Minute)
+		close(done)
+	}()
+	for !mb.HasSubscriber("auto-assign") {
+		time.Sleep(time.Millisecond)
+	}
+	assigned := mb.SubscribeSystem("test")
+
+	created := func(id, category string) {
+		mb.PublishSystem(bus.SystemEvent{Type: "task.created", Source: "kanban", Data: map[string]interface{}{
+			"task_id": id, "category": category, "state": "inbox",
+		}})
+	}
+	created("TASK-001", "research") // not opted in
+	created("TASK-002", "code")
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case raw := <-assigned:
+			evt := raw.(bus.SystemEvent)
+			if evt.Type != "task.assigned" {
+				continue
+			}
+			data := evt.Data.(map[string]interface{})
+			if data["task_id"] != "TASK-002" || data["agent_id"] != "coder" {
+				t.Errorf("task.assigned data = %v", data)
+			}
+			if _, ok := board.claimed["TASK-001"]; ok {
+				t.Error("task in a category that didn't opt in was claimed")
+			}
+			cancel()
+			<-done
+			return
+		case <-deadline:
+			t.Fatal("no task.assigned event")
+		}
+	}
+}
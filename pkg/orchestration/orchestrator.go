@@ -11,8 +11,11 @@ package orchestration
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
 )
 
 // TaskAssignment represents a task claimed by an agent.
@@ -24,6 +27,8 @@ type TaskAssignment struct {
 	Attempt   int       `json:"attempt"`
 	MaxRetry  int       `json:"max_retry"`
 	Status    string    `json:"status"` // claimed, executing, completed, failed, expired
+
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // set on complete/fail
 }
 
 // AgentCapability describes what an agent can do.
@@ -53,13 +58,52 @@ func DefaultRetryPolicy() RetryPolicy {
 	}
 }
 
+// ThroughputWindow is the rolling window over which throughput is measured.
+const ThroughputWindow = 5 * time.Minute
+
+// DefaultAssignmentRetention is how long finished assignments are kept
+// before PruneAssignments drops them.
+const DefaultAssignmentRetention = 24 * time.Hour
+
+// AgentStatus is one agent's share of the work, as reported by PerAgentStatus.
+type AgentStatus struct {
+	AgentID       string  `json:"agent_id"`
+	Active        int     `json:"active"`
+	Completed     int     `json:"completed"`
+	Failed        int     `json:"failed"`
+	AvgDurationMS int64   `json:"avg_duration_ms"`    // mean claim-to-completion time
+	Throughput    float64 `json:"throughput_per_min"` // completions per minute over ThroughputWindow
+}
+
+// agentStats accumulates outcomes per agent. Unlike assignments, which are
+// overwritten on retry, these count every attempt.
+type agentStats struct {
+	completed int
+	failed    int
+	busy      time.Duration // summed duration of completed tasks
+}
+
+// completion is one finished task, kept for the throughput window.
+type completion struct {
+	agentID string
+	at      time.Time
+}
+
 // Orchestrator manages task assignment, locking, and execution policies.
 type Orchestrator struct {
 	assignments  map[string]*TaskAssignment // taskID -> assignment
 	capabilities map[string]*AgentCapability // agentID -> capability
+	manual       map[string]bool            // agentIDs registered via RegisterAgent
 	policies     map[string]RetryPolicy     // category -> retry policy
+	stats        map[string]*agentStats     // agentID -> lifetime counters
+	completions  []completion               // oldest first, trimmed to ThroughputWindow
 	mu           sync.RWMutex
 	defaultPolicy RetryPolicy
+
+	retention  time.Duration      // how long terminal assignments are kept
+	eventStore domain.EventStore // archive for pruned assignments; nil = none
+
+	lastReconcile *ReconcileReport // nil until RunClaimReconciler's first pass
 }
 
 // NewOrchestrator creates a new orchestrator with default policies.
@@ -67,18 +111,53 @@ func NewOrchestrator() *Orchestrator {
 	return &Orchestrator{
 		assignments:   make(map[string]*TaskAssignment),
 		capabilities:  make(map[string]*AgentCapability),
+		manual:        make(map[string]bool),
 		policies:      make(map[string]RetryPolicy),
+		stats:         make(map[string]*agentStats),
 		defaultPolicy: DefaultRetryPolicy(),
+		retention:     DefaultAssignmentRetention,
 	}
 }
 
+// SetRetention sets how long completed, failed, expired and released
+// assignments are kept. Zero or negative disables pruning.
+func (o *Orchestrator) SetRetention(d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retention = d
+}
+
+// SetEventStore enables archiving: each assignment is appended to store as
+// an EventTaskAssignmentArchived event before it is pruned.
+func (o *Orchestrator) SetEventStore(store domain.EventStore) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.eventStore = store
+}
+
 // --- Capability Registry ---
 
-// RegisterAgent adds an agent's capabilities to the registry.
+// RegisterAgent adds an agent's capabilities to the registry. A manual
+// registration takes precedence over derived ones: RegisterDerivedAgent
+// will not replace it.
 func (o *Orchestrator) RegisterAgent(cap AgentCapability) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	o.capabilities[cap.AgentID] = &cap
+	o.manual[cap.AgentID] = true
+}
+
+// RegisterDerivedAgent registers capabilities worked out from an agent's
+// tool and skill bindings. It does nothing if the agent was registered
+// manually, and reports whether the capability was stored.
+func (o *Orchestrator) RegisterDerivedAgent(cap AgentCapability) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.manual[cap.AgentID] {
+		return false
+	}
+	o.capabilities[cap.AgentID] = &cap
+	return true
 }
 
 // UnregisterAgent removes an agent from the registry.
@@ -86,6 +165,16 @@ func (o *Orchestrator) UnregisterAgent(agentID string) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	delete(o.capabilities, agentID)
+	delete(o.manual, agentID)
+}
+
+// UnregisterDerivedAgent removes an agent unless it was registered manually.
+func (o *Orchestrator) UnregisterDerivedAgent(agentID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.manual[agentID] {
+		delete(o.capabilities, agentID)
+	}
 }
 
 // GetAgents returns all registered agent capabilities.
@@ -209,7 +298,15 @@ func (o *Orchestrator) CompleteTask(taskID, agentID string) error {
 		return fmt.Errorf("task %s is not claimed by %s", taskID, agentID)
 	}
 
+	now := time.Now()
 	assignment.Status = "completed"
+	assignment.FinishedAt = &now
+
+	st := o.statsFor(agentID)
+	st.completed++
+	st.busy += now.Sub(assignment.ClaimedAt)
+	o.completions = append(o.completions, completion{agentID: agentID, at: now})
+	o.trimCompletions(now)
 	return nil
 }
 
@@ -227,6 +324,10 @@ func (o *Orchestrator) FailTask(taskID, agentID, reason string) (bool, error) {
 		return false, fmt.Errorf("task %s is not claimed by %s", taskID, agentID)
 	}
 
+	now := time.Now()
+	assignment.FinishedAt = &now
+	o.statsFor(agentID).failed++
+
 	policy := o.getPolicy(taskID)
 	if assignment.Attempt < policy.MaxAttempts {
 		// Release the claim so another agent (or same) can retry
@@ -253,7 +354,9 @@ func (o *Orchestrator) ReleaseClaim(taskID, agentID string) error {
 		return fmt.Errorf("task %s is not claimed by %s", taskID, agentID)
 	}
 
+	now := time.Now()
 	assignment.Status = "released"
+	assignment.FinishedAt = &now
 	return nil
 }
 
@@ -306,13 +409,47 @@ func (o *Orchestrator) CleanupExpiredLeases() int {
 	for _, a := range o.assignments {
 		if (a.Status == "claimed" || a.Status == "executing") && now.After(a.ExpiresAt) {
 			a.Status = "expired"
+			a.FinishedAt = &now
 			expired++
 		}
 	}
 	return expired
 }
 
-// RunLeaseWatcher starts a background goroutine that cleans expired leases.
+// PruneAssignments removes finished assignments (completed, failed, expired,
+// released) that finished more than the retention window ago, archiving each
+// to the event store if one is set. Returns the number removed.
+// GetAssignment reports a pruned task as not found.
+func (o *Orchestrator) PruneAssignments() int {
+	o.mu.Lock()
+	if o.retention <= 0 {
+		o.mu.Unlock()
+		return 0
+	}
+	cutoff := time.Now().Add(-o.retention)
+	var pruned []*TaskAssignment
+	for id, a := range o.assignments {
+		if a.FinishedAt == nil || a.Status == "claimed" || a.Status == "executing" {
+			continue
+		}
+		if a.FinishedAt.Before(cutoff) {
+			pruned = append(pruned, a)
+			delete(o.assignments, id)
+		}
+	}
+	store := o.eventStore
+	o.mu.Unlock()
+
+	if store != nil {
+		for _, a := range pruned {
+			store.Append(domain.NewEvent(domain.EventTaskAssignmentArchived, domain.EntityID(a.TaskID), *a))
+		}
+	}
+	return len(pruned)
+}
+
+// RunLeaseWatcher starts a background goroutine that cleans expired leases
+// and prunes old assignments.
 func (o *Orchestrator) RunLeaseWatcher(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -327,6 +464,7 @@ func (o *Orchestrator) RunLeaseWatcher(ctx context.Context) {
 				// Log or broadcast
 				_ = expired
 			}
+			o.PruneAssignments()
 		}
 	}
 }
@@ -361,3 +499,74 @@ func (o *Orchestrator) Status() map[string]interface{} {
 		"total_assignments": len(o.assignments),
 	}
 }
+
+// PerAgentStatus returns, for each registered agent, its active task count,
+// lifetime completed/failed counts, average task duration and completions
+// per minute over the last ThroughputWindow. Sorted by agent ID.
+func (o *Orchestrator) PerAgentStatus() []AgentStatus {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	since := time.Now().Add(-ThroughputWindow)
+	recent := make(map[string]int)
+	for _, c := range o.completions {
+		if c.at.After(since) {
+			recent[c.agentID]++
+		}
+	}
+
+	out := make([]AgentStatus, 0, len(o.capabilities))
+	for agentID := range o.capabilities {
+		st := AgentStatus{
+			AgentID:    agentID,
+			Active:     o.countActiveAssignments(agentID),
+			Throughput: float64(recent[agentID]) / ThroughputWindow.Minutes(),
+		}
+		if s, ok := o.stats[agentID]; ok {
+			st.Completed = s.completed
+			st.Failed = s.failed
+			if s.completed > 0 {
+				st.AvgDurationMS = (s.busy / time.Duration(s.completed)).Milliseconds()
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AgentID < out[j].AgentID })
+	return out
+}
+
+// Throughput returns tasks completed per minute, across all agents, over
+// the last ThroughputWindow.
+func (o *Orchestrator) Throughput() float64 {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	since := time.Now().Add(-ThroughputWindow)
+	n := 0
+	for _, c := range o.completions {
+		if c.at.After(since) {
+			n++
+		}
+	}
+	return float64(n) / ThroughputWindow.Minutes()
+}
+
+func (o *Orchestrator) statsFor(agentID string) *agentStats {
+	st, ok := o.stats[agentID]
+	if !ok {
+		st = &agentStats{}
+		o.stats[agentID] = st
+	}
+	return st
+}
+
+// trimCompletions drops completions that have left the throughput window.
+// Caller must hold o.mu.
+func (o *Orchestrator) trimCompletions(now time.Time) {
+	since := now.Add(-ThroughputWindow)
+	i := 0
+	for i < len(o.completions) && !o.completions[i].at.After(since) {
+		i++
+	}
+	o.completions = o.completions[i:]
+}
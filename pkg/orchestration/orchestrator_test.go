@@ -0,0 +1,101 @@
+package orchestration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/domain"
+)
+
+func TestPerAgentStatus(t *testing.T) {
+	o := NewOrchestrator()
+	o.RegisterAgent(AgentCapability{AgentID: "b", Categories: []string{"*"}})
+	o.RegisterAgent(AgentCapability{AgentID: "a", Categories: []string{"*"}})
+	ctx := context.Background()
+
+	for _, id := range []string{"t1", "t2", "t3"} {
+		if _, err := o.ClaimTask(ctx, id, "a", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o.CompleteTask("t1", "a")
+	o.FailTask("t2", "a", "boom")
+
+	got := o.PerAgentStatus()
+	if len(got) != 2 || got[0].AgentID != "a" || got[1].AgentID != "b" {
+		t.Fatalf("PerAgentStatus() = %+v, want agents a, b", got)
+	}
+	a := got[0]
+	if a.Active != 1 || a.Completed != 1 || a.Failed != 1 {
+		t.Errorf("agent a = %+v, want 1 active, 1 completed, 1 failed", a)
+	}
+	if want := 1 / ThroughputWindow.Minutes(); a.Throughput != want || o.Throughput() != want {
+		t.Errorf("throughput = %v / %v, want %v", a.Throughput, o.Throughput(), want)
+	}
+	if got[1].Completed != 0 || got[1].Throughput != 0 {
+		t.Errorf("agent b = %+v, want idle", got[1])
+	}
+}
+
+type memEventStore struct{ events []domain.Event }
+
+func (m *memEventStore) Append(e domain.Event) error { m.events = append(m.events, e); return nil }
+
+func (m *memEventStore) Replay(id domain.EntityID) ([]domain.Event, error) { return m.events, nil }
+
+func TestPruneAssignments(t *testing.T) {
+	o := NewOrchestrator()
+	o.SetRetention(time.Hour)
+	store := &memEventStore{}
+	o.SetEventStore(store)
+	ctx := context.Background()
+
+	for _, id := range []string{"old", "recent", "running"} {
+		o.ClaimTask(ctx, id, "a", time.Hour)
+	}
+	o.CompleteTask("old", "a")
+	o.CompleteTask("recent", "a")
+	old, _ := o.GetAssignment("old")
+	past := time.Now().Add(-2 * time.Hour)
+	old.FinishedAt = &past
+
+	if n := o.PruneAssignments(); n != 1 {
+		t.Fatalf("PruneAssignments() = %d, want 1", n)
+	}
+	if a, ok := o.GetAssignment("old"); ok || a != nil {
+		t.Error("pruned assignment should be not found")
+	}
+	for _, id := range []string{"recent", "running"} {
+		if _, ok := o.GetAssignment(id); !ok {
+			t.Errorf("%s should be kept", id)
+		}
+	}
+	if len(store.events) != 1 || store.events[0].AggregateID() != "old" {
+		t.Errorf("archived events = %+v, want one for old", store.events)
+	}
+}
+
+func TestDerivedRegistrationDefersToManual(t *testing.T) {
+	o := NewOrchestrator()
+	o.RegisterAgent(AgentCapability{AgentID: "coder", Categories: []string{"code"}})
+
+	if o.RegisterDerivedAgent(AgentCapability{AgentID: "coder", Categories: []string{"research"}}) {
+		t.Error("derived registration should not replace a manual one")
+	}
+	o.UnregisterDerivedAgent("coder")
+	if got, err := o.RouteTask("code"); err != nil || got != "coder" {
+		t.Errorf("RouteTask(code) = %q, %v; want manual coder kept", got, err)
+	}
+
+	if !o.RegisterDerivedAgent(AgentCapability{AgentID: "researcher", Categories: []string{"research"}}) {
+		t.Fatal("derived registration of a new agent should be stored")
+	}
+	if got, _ := o.RouteTask("research"); got != "researcher" {
+		t.Errorf("RouteTask(research) = %q, want researcher", got)
+	}
+	o.UnregisterDerivedAgent("researcher")
+	if _, err := o.RouteTask("research"); err == nil {
+		t.Error("researcher should be unregistered")
+	}
+}
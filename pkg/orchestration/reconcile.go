@@ -0,0 +1,161 @@
+package orchestration
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultReconcileInterval is how often RunClaimReconciler compares
+// assignments with the claim source.
+const DefaultReconcileInterval = 30 * time.Second
+
+// Claim is a task lease held in the system of record for claims (the
+// kanban board).
+type Claim struct {
+	TaskID    string    `json:"task_id"`
+	AgentID   string    `json:"agent_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClaimSource returns the claims currently active in the system of record.
+type ClaimSource func(ctx context.Context) ([]Claim, error)
+
+// Divergence kinds reported by Reconcile.
+const (
+	DivergenceMissing  = "missing"  // claimed in the source, free here
+	DivergenceAgent    = "agent"    // claimed here by a different agent
+	DivergenceOrphaned = "orphaned" // claimed here, free in the source
+)
+
+// Divergence is one task whose assignment disagreed with the claim source,
+// as found before Reconcile corrected it.
+type Divergence struct {
+	TaskID     string `json:"task_id"`
+	Kind       string `json:"kind"`
+	ClaimAgent string `json:"claim_agent,omitempty"` // holder in the source
+	LocalAgent string `json:"local_agent,omitempty"` // holder here
+}
+
+// ReconcileReport is the outcome of one RunClaimReconciler pass.
+type ReconcileReport struct {
+	At          time.Time    `json:"at"`
+	Claims      int          `json:"claims"`
+	Divergences []Divergence `json:"divergences"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Reconcile makes active assignments match claims, which are taken as the
+// truth: a claim without a matching assignment is adopted for the claim's
+// agent, and an active assignment without a claim is released. Lease
+// expiries are synced without being reported. Returns the divergences
+// found, sorted by task ID.
+func (o *Orchestrator) Reconcile(claims []Claim) []Divergence {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	divergences := []Divergence{}
+	claimed := make(map[string]bool, len(claims))
+	for _, c := range claims {
+		claimed[c.TaskID] = true
+		a, ok := o.assignments[c.TaskID]
+		if ok && (a.Status == "claimed" || a.Status == "executing") {
+			if a.AgentID == c.AgentID {
+				a.ExpiresAt = c.ExpiresAt
+				continue
+			}
+			divergences = append(divergences, Divergence{
+				TaskID: c.TaskID, Kind: DivergenceAgent, ClaimAgent: c.AgentID, LocalAgent: a.AgentID,
+			})
+		} else {
+			divergences = append(divergences, Divergence{
+				TaskID: c.TaskID, Kind: DivergenceMissing, ClaimAgent: c.AgentID,
+			})
+		}
+
+		attempt := 1
+		if ok {
+			attempt = a.Attempt + 1
+		}
+		o.assignments[c.TaskID] = &TaskAssignment{
+			TaskID:    c.TaskID,
+			AgentID:   c.AgentID,
+			ClaimedAt: now,
+			ExpiresAt: c.ExpiresAt,
+			Attempt:   attempt,
+			MaxRetry:  o.getPolicy(c.TaskID).MaxAttempts,
+			Status:    "claimed",
+		}
+	}
+
+	for id, a := range o.assignments {
+		if claimed[id] || (a.Status != "claimed" && a.Status != "executing") {
+			continue
+		}
+		divergences = append(divergences, Divergence{
+			TaskID: id, Kind: DivergenceOrphaned, LocalAgent: a.AgentID,
+		})
+		a.Status = "released"
+		a.FinishedAt = &now
+	}
+
+	sort.Slice(divergences, func(i, j int) bool { return divergences[i].TaskID < divergences[j].TaskID })
+	return divergences
+}
+
+// LastReconcile returns the report of the most recent RunClaimReconciler
+// pass, or false if none has run.
+func (o *Orchestrator) LastReconcile() (ReconcileReport, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.lastReconcile == nil {
+		return ReconcileReport{}, false
+	}
+	return *o.lastReconcile, true
+}
+
+// RunClaimReconciler reconciles assignments with src straight away and then
+// every interval until ctx is cancelled. Each correction is logged.
+func (o *Orchestrator) RunClaimReconciler(ctx context.Context, src ClaimSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		o.reconcileFrom(ctx, src)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Orchestrator) reconcileFrom(ctx context.Context, src ClaimSource) {
+	report := ReconcileReport{At: time.Now()}
+	claims, err := src(ctx)
+	if err != nil {
+		// Without the source of truth, leave assignments as they are.
+		report.Error = err.Error()
+		logger.WarnCF("orchestration", "Failed to load claims for reconciliation", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		report.Claims = len(claims)
+		report.Divergences = o.Reconcile(claims)
+		for _, d := range report.Divergences {
+			logger.WarnCF("orchestration", "Corrected assignment to match kanban claim", map[string]interface{}{
+				"task_id":     d.TaskID,
+				"kind":        d.Kind,
+				"claim_agent": d.ClaimAgent,
+				"local_agent": d.LocalAgent,
+			})
+		}
+	}
+
+	o.mu.Lock()
+	o.lastReconcile = &report
+	o.mu.Unlock()
+}
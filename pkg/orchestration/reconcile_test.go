@@ -0,0 +1,84 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReconcileFollowsClaims(t *testing.T) {
+	o := NewOrchestrator()
+	ctx := context.Background()
+	for id, agent := range map[string]string{"same": "a", "stolen": "a", "orphan": "b", "done": "b"} {
+		if _, err := o.ClaimTask(ctx, id, agent, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o.CompleteTask("done", "b")
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	got := o.Reconcile([]Claim{
+		{TaskID: "same", AgentID: "a", ExpiresAt: expires},
+		{TaskID: "stolen", AgentID: "c", ExpiresAt: expires},
+		{TaskID: "new", AgentID: "c", ExpiresAt: expires},
+	})
+
+	want := []Divergence{
+		{TaskID: "new", Kind: DivergenceMissing, ClaimAgent: "c"},
+		{TaskID: "orphan", Kind: DivergenceOrphaned, LocalAgent: "b"},
+		{TaskID: "stolen", Kind: DivergenceAgent, ClaimAgent: "c", LocalAgent: "a"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Reconcile() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("divergence %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for id, agent := range map[string]string{"same": "a", "stolen": "c", "new": "c"} {
+		a, ok := o.GetAssignment(id)
+		if !ok || a.Status != "claimed" || a.AgentID != agent || !a.ExpiresAt.Equal(expires) {
+			t.Errorf("assignment %s = %+v, want claimed by %s until %v", id, a, agent, expires)
+		}
+	}
+	if a, _ := o.GetAssignment("orphan"); a.Status != "released" {
+		t.Errorf("orphan status = %q, want released", a.Status)
+	}
+	if a, _ := o.GetAssignment("done"); a.Status != "completed" {
+		t.Errorf("finished assignment touched: %q", a.Status)
+	}
+
+	// A second pass finds nothing to correct.
+	if got := o.Reconcile([]Claim{
+		{TaskID: "same", AgentID: "a", ExpiresAt: expires},
+		{TaskID: "stolen", AgentID: "c", ExpiresAt: expires},
+		{TaskID: "new", AgentID: "c", ExpiresAt: expires},
+	}); len(got) != 0 {
+		t.Errorf("second Reconcile() = %+v, want none", got)
+	}
+}
+
+func TestReconcileSourceError(t *testing.T) {
+	o := NewOrchestrator()
+	if _, err := o.ClaimTask(context.Background(), "t1", "a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o.LastReconcile(); ok {
+		t.Fatal("LastReconcile() before any pass")
+	}
+
+	o.reconcileFrom(context.Background(), func(context.Context) ([]Claim, error) {
+		return nil, errors.New("board down")
+	})
+
+	report, ok := o.LastReconcile()
+	if !ok || report.Error != "board down" {
+		t.Errorf("LastReconcile() = %+v, %v", report, ok)
+	}
+	if a, _ := o.GetAssignment("t1"); a.Status != "claimed" {
+		t.Errorf("assignment released without a claim source: %q", a.Status)
+	}
+}
@@ -19,6 +19,7 @@ func NewClaudeProvider(token string) *ClaudeProvider {
 	client := anthropic.NewClient(
 		option.WithAuthToken(token),
 		option.WithBaseURL("https://api.anthropic.com"),
+		option.WithMaxRetries(0), // retried by the caller; see ChatWithRetry
 	)
 	return &ClaudeProvider{client: &client}
 }
@@ -80,9 +81,7 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 				if msg.Content != "" {
 					blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
 				}
-				for _, tc := range msg.ToolCalls {
-					blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, tc.Arguments, tc.Name))
-				}
+				blocks = append(blocks, claudeToolUseBlocks(msg.ToolCalls)...)
 				anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(blocks...))
 			} else {
 				anthropicMessages = append(anthropicMessages,
@@ -128,19 +127,13 @@ func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam
 		tool := anthropic.ToolParam{
 			Name: t.Function.Name,
 			InputSchema: anthropic.ToolInputSchemaParam{
-				Properties: t.Function.Parameters["properties"],
+				Properties: toolParameters(t)["properties"],
 			},
 		}
 		if desc := t.Function.Description; desc != "" {
 			tool.Description = anthropic.String(desc)
 		}
-		if req, ok := t.Function.Parameters["required"].([]interface{}); ok {
-			required := make([]string, 0, len(req))
-			for _, r := range req {
-				if s, ok := r.(string); ok {
-					required = append(required, s)
-				}
-			}
+		if required := requiredParams(t.Function.Parameters); len(required) > 0 {
 			tool.InputSchema.Required = required
 		}
 		result = append(result, anthropic.ToolUnionParam{OfTool: &tool})
@@ -158,16 +151,7 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 			tb := block.AsText()
 			content += tb.Text
 		case "tool_use":
-			tu := block.AsToolUse()
-			var args map[string]interface{}
-			if err := json.Unmarshal(tu.Input, &args); err != nil {
-				args = map[string]interface{}{"raw": string(tu.Input)}
-			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        tu.ID,
-				Name:      tu.Name,
-				Arguments: args,
-			})
+			toolCalls = append(toolCalls, claudeToolCall(block.AsToolUse()))
 		}
 	}
 
@@ -193,6 +177,47 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 	}
 }
 
+// claudeToolAdapter is the ToolCallAdapter for the Messages API: native
+// calls are tool_use content blocks, native tools the request's tools.
+type claudeToolAdapter struct{}
+
+func (claudeToolAdapter) DecodeToolCalls(native []byte) ([]ToolCall, error) {
+	var blocks []anthropic.ContentBlockUnion
+	if err := json.Unmarshal(native, &blocks); err != nil {
+		return nil, fmt.Errorf("decode tool calls: %w", err)
+	}
+	calls := []ToolCall{}
+	for _, block := range blocks {
+		if block.Type == "tool_use" {
+			calls = append(calls, claudeToolCall(block.AsToolUse()))
+		}
+	}
+	return calls, nil
+}
+
+func (claudeToolAdapter) EncodeToolCalls(calls []ToolCall) ([]byte, error) {
+	return json.Marshal(claudeToolUseBlocks(calls))
+}
+
+func (claudeToolAdapter) EncodeTools(defs []ToolDefinition) ([]byte, error) {
+	return json.Marshal(translateToolsForClaude(defs))
+}
+
+func claudeToolCall(tu anthropic.ToolUseBlock) ToolCall {
+	return NormalizeToolCall(ToolCall{
+		ID:       tu.ID,
+		Function: &FunctionCall{Name: tu.Name, Arguments: string(tu.Input)},
+	})
+}
+
+func claudeToolUseBlocks(calls []ToolCall) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(calls))
+	for _, tc := range normalizeToolCalls(calls) {
+		blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, tc.Arguments, tc.Name))
+	}
+	return blocks
+}
+
 func createClaudeTokenSource() func() (string, error) {
 	return func() (string, error) {
 		cred, err := auth.GetCredential("anthropic")
@@ -22,6 +22,7 @@ func NewCodexProvider(token, accountID string) *CodexProvider {
 	opts := []option.RequestOption{
 		option.WithBaseURL("https://chatgpt.com/backend-api/codex"),
 		option.WithAPIKey(token),
+		option.WithMaxRetries(0), // retried by the caller; see ChatWithRetry
 	}
 	if accountID != "" {
 		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accountID))
@@ -100,16 +101,7 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 						},
 					})
 				}
-				for _, tc := range msg.ToolCalls {
-					argsJSON, _ := json.Marshal(tc.Arguments)
-					inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-						OfFunctionCall: &responses.ResponseFunctionToolCallParam{
-							CallID:    tc.ID,
-							Name:      tc.Name,
-							Arguments: string(argsJSON),
-						},
-					})
-				}
+				inputItems = append(inputItems, codexFunctionCallItems(msg.ToolCalls)...)
 			} else {
 				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
 					OfMessage: &responses.EasyInputMessageParam{
@@ -160,7 +152,7 @@ func translateToolsForCodex(tools []ToolDefinition) []responses.ToolUnionParam {
 	for _, t := range tools {
 		ft := responses.FunctionToolParam{
 			Name:       t.Function.Name,
-			Parameters: t.Function.Parameters,
+			Parameters: toolParameters(t),
 			Strict:     openai.Opt(false),
 		}
 		if t.Function.Description != "" {
@@ -184,15 +176,7 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 				}
 			}
 		case "function_call":
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(item.Arguments), &args); err != nil {
-				args = map[string]interface{}{"raw": item.Arguments}
-			}
-			toolCalls = append(toolCalls, ToolCall{
-				ID:        item.CallID,
-				Name:      item.Name,
-				Arguments: args,
-			})
+			toolCalls = append(toolCalls, codexToolCall(item))
 		}
 	}
 
@@ -221,6 +205,53 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 }
 
+// codexToolAdapter is the ToolCallAdapter for the Responses API: native
+// calls are function_call output items, native tools the request's tools.
+type codexToolAdapter struct{}
+
+func (codexToolAdapter) DecodeToolCalls(native []byte) ([]ToolCall, error) {
+	var items []responses.ResponseOutputItemUnion
+	if err := json.Unmarshal(native, &items); err != nil {
+		return nil, fmt.Errorf("decode tool calls: %w", err)
+	}
+	calls := []ToolCall{}
+	for _, item := range items {
+		if item.Type == "function_call" {
+			calls = append(calls, codexToolCall(item))
+		}
+	}
+	return calls, nil
+}
+
+func (codexToolAdapter) EncodeToolCalls(calls []ToolCall) ([]byte, error) {
+	return json.Marshal(codexFunctionCallItems(calls))
+}
+
+func (codexToolAdapter) EncodeTools(defs []ToolDefinition) ([]byte, error) {
+	return json.Marshal(translateToolsForCodex(defs))
+}
+
+func codexToolCall(item responses.ResponseOutputItemUnion) ToolCall {
+	return NormalizeToolCall(ToolCall{
+		ID:       item.CallID,
+		Function: &FunctionCall{Name: item.Name, Arguments: item.Arguments},
+	})
+}
+
+func codexFunctionCallItems(calls []ToolCall) []responses.ResponseInputItemUnionParam {
+	items := make([]responses.ResponseInputItemUnionParam, 0, len(calls))
+	for _, tc := range normalizeToolCalls(calls) {
+		items = append(items, responses.ResponseInputItemUnionParam{
+			OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+				CallID:    tc.ID,
+				Name:      tc.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+	return items
+}
+
 func createCodexTokenSource() func() (string, string, error) {
 	return func() (string, string, error) {
 		cred, err := auth.GetCredential("openai")
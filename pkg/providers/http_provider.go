@@ -43,11 +43,11 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": openAIMessages(messages),
 	}
 
 	if len(tools) > 0 {
-		requestBody["tools"] = tools
+		requestBody["tools"] = encodeOpenAITools(tools)
 		requestBody["tool_choice"] = "auto"
 	}
 
@@ -91,7 +91,11 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: %s", string(body))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Body:       string(body),
+		}
 	}
 
 	return p.parseResponse(body)
@@ -101,15 +105,8 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
-				ToolCalls []struct {
-					ID       string `json:"id"`
-					Type     string `json:"type"`
-					Function *struct {
-						Name      string `json:"name"`
-						Arguments string `json:"arguments"`
-					} `json:"function"`
-				} `json:"tool_calls"`
+				Content   string           `json:"content"`
+				ToolCalls []openAIToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -129,39 +126,9 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 
 	choice := apiResponse.Choices[0]
 
-	toolCalls := make([]ToolCall, 0, len(choice.Message.ToolCalls))
-	for _, tc := range choice.Message.ToolCalls {
-		arguments := make(map[string]interface{})
-		name := ""
-
-		// Handle OpenAI format with nested function object
-		if tc.Type == "function" && tc.Function != nil {
-			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
-		} else if tc.Function != nil {
-			// Legacy format without type field
-			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
-		}
-
-		toolCalls = append(toolCalls, ToolCall{
-			ID:        tc.ID,
-			Name:      name,
-			Arguments: arguments,
-		})
-	}
-
 	return &LLMResponse{
 		Content:      choice.Message.Content,
-		ToolCalls:    toolCalls,
+		ToolCalls:    decodeOpenAIToolCalls(choice.Message.ToolCalls),
 		FinishReason: choice.FinishReason,
 		Usage:        apiResponse.Usage,
 	}, nil
@@ -171,6 +138,97 @@ func (p *HTTPProvider) GetDefaultModel() string {
 	return ""
 }
 
+// openAIMessage is a chat message in the chat completions format.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a tool call in the chat completions format. Arguments
+// are a JSON-encoded string, though some compatible servers send an object.
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAIToolAdapter is the ToolCallAdapter for the chat completions API:
+// native calls are a message's tool_calls array, native tools the
+// request's tools array.
+type openAIToolAdapter struct{}
+
+func (openAIToolAdapter) DecodeToolCalls(native []byte) ([]ToolCall, error) {
+	var raw []openAIToolCall
+	if err := json.Unmarshal(native, &raw); err != nil {
+		return nil, fmt.Errorf("decode tool calls: %w", err)
+	}
+	return decodeOpenAIToolCalls(raw), nil
+}
+
+func (openAIToolAdapter) EncodeToolCalls(calls []ToolCall) ([]byte, error) {
+	return json.Marshal(encodeOpenAIToolCalls(calls))
+}
+
+func (openAIToolAdapter) EncodeTools(defs []ToolDefinition) ([]byte, error) {
+	return json.Marshal(encodeOpenAITools(defs))
+}
+
+func decodeOpenAIToolCalls(raw []openAIToolCall) []ToolCall {
+	calls := make([]ToolCall, 0, len(raw))
+	for _, tc := range raw {
+		var args string
+		if err := json.Unmarshal(tc.Function.Arguments, &args); err != nil {
+			args = string(tc.Function.Arguments) // sent as an object
+		}
+		calls = append(calls, NormalizeToolCall(ToolCall{
+			ID:       tc.ID,
+			Function: &FunctionCall{Name: tc.Function.Name, Arguments: args},
+		}))
+	}
+	return calls
+}
+
+func encodeOpenAIToolCalls(calls []ToolCall) []openAIToolCall {
+	out := make([]openAIToolCall, 0, len(calls))
+	for _, tc := range normalizeToolCalls(calls) {
+		var otc openAIToolCall
+		otc.ID, otc.Type = tc.ID, tc.Type
+		otc.Function.Name = tc.Function.Name
+		otc.Function.Arguments, _ = json.Marshal(tc.Function.Arguments)
+		out = append(out, otc)
+	}
+	return out
+}
+
+func encodeOpenAITools(defs []ToolDefinition) []ToolDefinition {
+	out := make([]ToolDefinition, 0, len(defs))
+	for _, d := range defs {
+		d.Type = "function"
+		d.Function.Parameters = toolParameters(d)
+		out = append(out, d)
+	}
+	return out
+}
+
+// openAIMessages converts messages to the chat completions format, with
+// tool calls in its shape whichever shape they were stored in.
+func openAIMessages(messages []Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages))
+	for _, m := range messages {
+		om := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.ToolCalls) > 0 {
+			om.ToolCalls = encodeOpenAIToolCalls(m.ToolCalls)
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
 func createClaudeAuthProvider() (LLMProvider, error) {
 	cred, err := auth.GetCredential("anthropic")
 	if err != nil {
@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// StatusError is a non-200 answer from an HTTP provider.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header; 0 when absent
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error: %s", e.Body)
+}
+
+// RetryPolicy bounds how a failed chat call is retried: up to MaxRetries
+// more attempts, waiting a jittered BaseDelay*2^n between them, never more
+// than MaxDelay. MaxRetries 0 disables retries.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is used where no policy is configured.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+
+// retryableStatus are the statuses worth another attempt: timeouts, rate
+// limits, server errors and Anthropic's 529 "overloaded".
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+	529:                            true,
+}
+
+// IsRetryable reports whether a chat error is transient, along with how
+// long the provider asked to wait (0 when it didn't say). Auth failures,
+// bad requests and cancellations are not retryable.
+func IsRetryable(err error) (bool, time.Duration) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus[statusErr.StatusCode], statusErr.RetryAfter
+	}
+	var claudeErr *anthropic.Error
+	if errors.As(err, &claudeErr) {
+		return retryableStatus[claudeErr.StatusCode], retryAfterOf(claudeErr.Response)
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return retryableStatus[openaiErr.StatusCode], retryAfterOf(openaiErr.Response)
+	}
+	// Dropped connections and network timeouts
+	var netErr net.Error
+	return errors.As(err, &netErr), 0
+}
+
+func retryAfterOf(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
+
+// parseRetryAfter reads a Retry-After value: delay seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// backoff is the wait before retry n (1-based): half of BaseDelay*2^(n-1)
+// plus a random share of the other half, capped at MaxDelay.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// ChatWithRetry calls p.Chat, retrying transient failures under policy.
+// A Retry-After from the provider replaces the computed backoff; one longer
+// than MaxDelay ends the retries so a turn isn't held up for minutes.
+// onRetry, when set, is called before each wait.
+func ChatWithRetry(ctx context.Context, p LLMProvider, policy RetryPolicy, onRetry func(attempt int, err error, wait time.Duration),
+	messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err == nil || attempt > policy.MaxRetries {
+			return resp, err
+		}
+		retryable, after := IsRetryable(err)
+		if !retryable || (after > 0 && policy.MaxDelay > 0 && after > policy.MaxDelay) {
+			return resp, err
+		}
+		wait := policy.backoff(attempt)
+		if after > 0 {
+			wait = after
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
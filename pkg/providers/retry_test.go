@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// flakyProvider fails with errs in order, then answers.
+type flakyProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *flakyProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return nil, p.errs[p.calls-1]
+	}
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (p *flakyProvider) GetDefaultModel() string { return "flaky" }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+		after     time.Duration
+	}{
+		{&StatusError{StatusCode: 429, RetryAfter: 2 * time.Second}, true, 2 * time.Second},
+		{fmt.Errorf("LLM call failed: %w", &StatusError{StatusCode: 503}), true, 0},
+		{&StatusError{StatusCode: 401}, false, 0},
+		{&StatusError{StatusCode: 400}, false, 0},
+		{&anthropic.Error{StatusCode: 529}, true, 0},
+		{&anthropic.Error{StatusCode: 403}, false, 0},
+		{&netTimeout{}, true, 0},
+		{context.Canceled, false, 0},
+		{errors.New("no API key"), false, 0},
+	}
+	for _, tt := range tests {
+		retryable, after := IsRetryable(tt.err)
+		if retryable != tt.retryable || after != tt.after {
+			t.Errorf("IsRetryable(%v) = %v, %v; want %v, %v", tt.err, retryable, after, tt.retryable, tt.after)
+		}
+	}
+}
+
+type netTimeout struct{}
+
+func (netTimeout) Error() string   { return "i/o timeout" }
+func (netTimeout) Timeout() bool   { return true }
+func (netTimeout) Temporary() bool { return true }
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	if got := parseRetryAfter("7", now); got != 7*time.Second {
+		t.Errorf("seconds: got %v", got)
+	}
+	if got := parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now); got != 90*time.Second {
+		t.Errorf("HTTP date: got %v", got)
+	}
+	if got := parseRetryAfter("soon", now); got != 0 {
+		t.Errorf("garbage: got %v", got)
+	}
+}
+
+func TestChatWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	ctx := context.Background()
+
+	p := &flakyProvider{errs: []error{&StatusError{StatusCode: 503}, &StatusError{StatusCode: 429}}}
+	var waits []time.Duration
+	resp, err := ChatWithRetry(ctx, p, policy, func(attempt int, err error, wait time.Duration) {
+		waits = append(waits, wait)
+	}, nil, nil, "m", nil)
+	if err != nil || resp.Content != "ok" || p.calls != 3 || len(waits) != 2 {
+		t.Fatalf("transient errors: resp %v, err %v, calls %d, waits %v", resp, err, p.calls, waits)
+	}
+	for i, w := range waits {
+		if max := policy.BaseDelay << i; w < max/2 || w > max {
+			t.Errorf("wait %d = %v, want within [%v, %v]", i, w, max/2, max)
+		}
+	}
+
+	p = &flakyProvider{errs: []error{&StatusError{StatusCode: 401}}}
+	if _, err := ChatWithRetry(ctx, p, policy, nil, nil, nil, "m", nil); err == nil || p.calls != 1 {
+		t.Errorf("auth error: err %v after %d calls, want a failure after 1", err, p.calls)
+	}
+
+	p = &flakyProvider{errs: []error{&StatusError{StatusCode: 503}, &StatusError{StatusCode: 503}, &StatusError{StatusCode: 503}, &StatusError{StatusCode: 503}}}
+	if _, err := ChatWithRetry(ctx, p, policy, nil, nil, nil, "m", nil); err == nil || p.calls != 4 {
+		t.Errorf("persistent 503: err %v after %d calls, want a failure after 4", err, p.calls)
+	}
+
+	// A Retry-After beyond MaxDelay gives up rather than stalling the turn.
+	p = &flakyProvider{errs: []error{&StatusError{StatusCode: 429, RetryAfter: time.Minute}}}
+	if _, err := ChatWithRetry(ctx, p, policy, nil, nil, nil, "m", nil); err == nil || p.calls != 1 {
+		t.Errorf("long Retry-After: err %v after %d calls, want a failure after 1", err, p.calls)
+	}
+	p = &flakyProvider{errs: []error{&StatusError{StatusCode: 429, RetryAfter: 5 * time.Millisecond}}}
+	waits = nil
+	if _, err := ChatWithRetry(ctx, p, policy, func(_ int, _ error, wait time.Duration) { waits = append(waits, wait) }, nil, nil, "m", nil); err != nil ||
+		len(waits) != 1 || waits[0] != 5*time.Millisecond {
+		t.Errorf("short Retry-After: err %v, waits %v, want one 5ms wait", err, waits)
+	}
+}
+
+func TestHTTPProviderStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewHTTPProvider("key", srv.URL).Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	retryable, after := IsRetryable(err)
+	if !retryable || after != 3*time.Second {
+		t.Errorf("IsRetryable(%v) = %v, %v; want true, 3s", err, retryable, after)
+	}
+}
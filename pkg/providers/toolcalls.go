@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"encoding/json"
+)
+
+// ToolCallAdapter maps one provider's native tool-calling format to and
+// from the common types. Native values are the provider's JSON: for
+// decoding, the part of a response that holds the calls; for encoding, the
+// part of a request that holds them.
+type ToolCallAdapter interface {
+	// DecodeToolCalls maps native tool calls to normalized ToolCalls.
+	// Entries that aren't tool calls (e.g. text blocks) are skipped.
+	DecodeToolCalls(native []byte) ([]ToolCall, error)
+	// EncodeToolCalls renders an assistant turn's calls natively.
+	EncodeToolCalls(calls []ToolCall) ([]byte, error)
+	// EncodeTools renders tool definitions in the provider's schema.
+	EncodeTools(defs []ToolDefinition) ([]byte, error)
+}
+
+// ToolCallAdapters holds the adapter for each provider wire format:
+// "openai" (the chat completions API, also spoken by OpenRouter, Groq,
+// Zhipu, Moonshot, vLLM and Gemini's compatibility endpoint), "anthropic"
+// (Messages API) and "codex" (Responses API).
+var ToolCallAdapters = map[string]ToolCallAdapter{
+	"openai":    openAIToolAdapter{},
+	"anthropic": claudeToolAdapter{},
+	"codex":     codexToolAdapter{},
+}
+
+// NormalizeToolCall returns tc with both shapes filled in, whichever it
+// arrived with, and Type "function". Name/Arguments win when both are set.
+func NormalizeToolCall(tc ToolCall) ToolCall {
+	if tc.Name == "" && tc.Function != nil {
+		tc.Name = tc.Function.Name
+	}
+	if tc.Arguments == nil && tc.Function != nil {
+		tc.Arguments = decodeArguments(tc.Function.Arguments)
+	}
+	if tc.Arguments == nil {
+		tc.Arguments = map[string]interface{}{}
+	}
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	tc.Function = &FunctionCall{Name: tc.Name, Arguments: string(argsJSON)}
+	tc.Type = "function"
+	return tc
+}
+
+// normalizeToolCalls applies NormalizeToolCall to each call.
+func normalizeToolCalls(calls []ToolCall) []ToolCall {
+	out := make([]ToolCall, 0, len(calls))
+	for _, tc := range calls {
+		out = append(out, NormalizeToolCall(tc))
+	}
+	return out
+}
+
+// decodeArguments parses JSON-encoded call arguments. Arguments that
+// aren't a JSON object are kept under "raw" so the tool can report them.
+func decodeArguments(s string) map[string]interface{} {
+	args := map[string]interface{}{}
+	if s == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(s), &args); err != nil || args == nil {
+		return map[string]interface{}{"raw": s}
+	}
+	return args
+}
+
+// toolParameters returns a definition's parameter schema, defaulting to
+// an object with no properties; providers reject a missing schema.
+func toolParameters(def ToolDefinition) map[string]interface{} {
+	if def.Function.Parameters == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return def.Function.Parameters
+}
+
+// requiredParams reads a schema's "required" list, which tools declare as
+// []string and decoded JSON holds as []interface{}.
+func requiredParams(schema map[string]interface{}) []string {
+	switch req := schema["required"].(type) {
+	case []string:
+		return req
+	case []interface{}:
+		required := make([]string, 0, len(req))
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				required = append(required, s)
+			}
+		}
+		return required
+	}
+	return nil
+}
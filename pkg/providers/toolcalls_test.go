@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// toolSchema pulls a tool's name and required parameters out of one entry
+// of an adapter's encoded tools.
+type toolSchema func(tool map[string]interface{}) (name string, required interface{})
+
+// testToolCallAdapter checks the behaviour every adapter must share.
+// native is the provider's encoding of one call, get_weather(city=SF) with
+// ID call_1, possibly alongside entries that aren't calls.
+func testToolCallAdapter(t *testing.T, a ToolCallAdapter, native string, schema toolSchema) {
+	t.Helper()
+
+	calls, err := a.DecodeToolCalls([]byte(native))
+	if err != nil {
+		t.Fatalf("DecodeToolCalls: %v", err)
+	}
+	want := ToolCall{
+		ID:        "call_1",
+		Type:      "function",
+		Name:      "get_weather",
+		Arguments: map[string]interface{}{"city": "SF"},
+		Function:  &FunctionCall{Name: "get_weather", Arguments: `{"city":"SF"}`},
+	}
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0], want) {
+		t.Fatalf("DecodeToolCalls = %+v, want [%+v]", calls, want)
+	}
+
+	// Calls in either stored shape survive a round trip through the
+	// native format.
+	for _, in := range []ToolCall{
+		{ID: "call_2", Name: "read_file", Arguments: map[string]interface{}{"path": "a.txt", "lines": 3.0}},
+		{ID: "call_3", Type: "function", Function: &FunctionCall{Name: "list_dir", Arguments: `{"path":"."}`}},
+		{ID: "call_4", Name: "status"},
+	} {
+		encoded, err := a.EncodeToolCalls([]ToolCall{in})
+		if err != nil {
+			t.Fatalf("EncodeToolCalls(%s): %v", in.ID, err)
+		}
+		got, err := a.DecodeToolCalls(encoded)
+		if err != nil {
+			t.Fatalf("DecodeToolCalls(EncodeToolCalls(%s)): %v (native %s)", in.ID, err, encoded)
+		}
+		if want := NormalizeToolCall(in); len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+			t.Errorf("round trip of %s = %+v, want %+v (native %s)", in.ID, got, want, encoded)
+		}
+	}
+
+	encoded, err := a.EncodeTools([]ToolDefinition{
+		{Type: "function", Function: ToolFunctionDefinition{
+			Name:        "get_weather",
+			Description: "Get weather for a city",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+				"required":   []string{"city"},
+			},
+		}},
+		{Function: ToolFunctionDefinition{Name: "status", Description: "No parameters"}},
+	})
+	if err != nil {
+		t.Fatalf("EncodeTools: %v", err)
+	}
+	var tools []map[string]interface{}
+	if err := json.Unmarshal(encoded, &tools); err != nil || len(tools) != 2 {
+		t.Fatalf("EncodeTools = %s (%v), want two tools", encoded, err)
+	}
+	name, required := schema(tools[0])
+	if name != "get_weather" || !reflect.DeepEqual(required, []interface{}{"city"}) {
+		t.Errorf("encoded tool = %s, required %v; want get_weather requiring city", name, required)
+	}
+	if name, _ := schema(tools[1]); name != "status" {
+		t.Errorf("encoded parameterless tool name = %q, want status", name)
+	}
+}
+
+func TestOpenAIToolAdapter(t *testing.T) {
+	testToolCallAdapter(t, ToolCallAdapters["openai"],
+		`[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"SF\"}"}}]`,
+		func(tool map[string]interface{}) (string, interface{}) {
+			fn, _ := tool["function"].(map[string]interface{})
+			params, _ := fn["parameters"].(map[string]interface{})
+			if tool["type"] != "function" || params == nil {
+				t.Errorf("tool %v: want type function with a parameter schema", tool)
+			}
+			name, _ := fn["name"].(string)
+			return name, params["required"]
+		})
+
+	// Some compatible servers send arguments as an object, or leave out
+	// the type.
+	calls, err := ToolCallAdapters["openai"].DecodeToolCalls([]byte(
+		`[{"id":"call_1","function":{"name":"get_weather","arguments":{"city":"SF"}}}]`))
+	if err != nil || len(calls) != 1 || calls[0].Arguments["city"] != "SF" || calls[0].Name != "get_weather" {
+		t.Errorf("object arguments decoded to %+v (%v)", calls, err)
+	}
+}
+
+func TestClaudeToolAdapter(t *testing.T) {
+	testToolCallAdapter(t, ToolCallAdapters["anthropic"],
+		`[{"type":"text","text":"Checking."},{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"SF"}}]`,
+		func(tool map[string]interface{}) (string, interface{}) {
+			schema, _ := tool["input_schema"].(map[string]interface{})
+			if schema == nil {
+				t.Errorf("tool %v: want an input_schema", tool)
+			}
+			name, _ := tool["name"].(string)
+			return name, schema["required"]
+		})
+}
+
+func TestCodexToolAdapter(t *testing.T) {
+	testToolCallAdapter(t, ToolCallAdapters["codex"],
+		`[{"id":"msg_1","type":"message","role":"assistant","content":[]},`+
+			`{"id":"fc_1","type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"SF\"}","status":"completed"}]`,
+		func(tool map[string]interface{}) (string, interface{}) {
+			params, _ := tool["parameters"].(map[string]interface{})
+			if tool["type"] != "function" || params == nil {
+				t.Errorf("tool %v: want type function with parameters", tool)
+			}
+			name, _ := tool["name"].(string)
+			return name, params["required"]
+		})
+}
+
+func TestNormalizeToolCallKeepsUnparsableArguments(t *testing.T) {
+	tc := NormalizeToolCall(ToolCall{ID: "x", Function: &FunctionCall{Name: "exec", Arguments: "ls -la"}})
+	if tc.Name != "exec" || tc.Arguments["raw"] != "ls -la" {
+		t.Errorf("NormalizeToolCall = %+v", tc)
+	}
+}
@@ -2,6 +2,10 @@ package providers
 
 import "context"
 
+// ToolCall carries a call in two shapes: Name/Arguments, which the agent
+// and the SDK providers read, and Function with JSON-encoded arguments, the
+// OpenAI wire shape assistant turns are stored in. Providers read calls
+// through NormalizeToolCall, so either shape works with any provider.
 type ToolCall struct {
 	ID        string                 `json:"id"`
 	Type      string                 `json:"type,omitempty"`
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +17,9 @@ type Session struct {
 	Summary  string              `json:"summary,omitempty"`
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
+	// TokensUsed is the provider-reported token usage (prompt plus
+	// completion) of every agent call made for this session.
+	TokensUsed int64 `json:"tokens_used"`
 }
 
 type SessionManager struct {
@@ -121,6 +125,35 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	}
 }
 
+// AddTokens adds n to the session's token usage and returns the new total.
+func (sm *SessionManager) AddTokens(key string, n int) int64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		session = &Session{
+			Key:      key,
+			Messages: []providers.Message{},
+			Created:  time.Now(),
+		}
+		sm.sessions[key] = session
+	}
+	session.TokensUsed += int64(n)
+	return session.TokensUsed
+}
+
+// TokensUsed returns the session's token usage.
+func (sm *SessionManager) TokensUsed(key string) int64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if session, ok := sm.sessions[key]; ok {
+		return session.TokensUsed
+	}
+	return 0
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -134,7 +167,13 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 		return
 	}
 
-	session.Messages = session.Messages[len(session.Messages)-keepLast:]
+	// Never start on tool results: keep the assistant message that made
+	// the calls too
+	start := len(session.Messages) - keepLast
+	for start > 0 && start < len(session.Messages) && session.Messages[start].Role == "tool" {
+		start--
+	}
+	session.Messages = session.Messages[start:]
 	session.Updated = time.Now()
 }
 
@@ -146,13 +185,15 @@ func (sm *SessionManager) ListSessions() []Session {
 	sessions := make([]Session, 0, len(sm.sessions))
 	for _, s := range sm.sessions {
 		sessions = append(sessions, Session{
-			Key:      s.Key,
-			Messages: nil, // omit full history for listing
-			Summary:  s.Summary,
-			Created:  s.Created,
-			Updated:  s.Updated,
+			Key:        s.Key,
+			Messages:   nil, // omit full history for listing
+			Summary:    s.Summary,
+			Created:    s.Created,
+			Updated:    s.Updated,
+			TokensUsed: s.TokensUsed,
 		})
 	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Key < sessions[j].Key })
 	return sessions
 }
 
@@ -16,6 +16,33 @@ type ContextualTool interface {
 	SetContext(channel, chatID string)
 }
 
+type toolChatKey struct{}
+
+// toolChat is the chat a tool call serves.
+type toolChat struct{ channel, chatID string }
+
+// WithChat returns ctx carrying the chat a tool call serves. Unlike
+// SetContext it belongs to the one call, so concurrent chats sharing a tool
+// instance never see each other's.
+func WithChat(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, toolChatKey{}, toolChat{channel, chatID})
+}
+
+// chatFrom returns the chat set with WithChat, or empty strings.
+func chatFrom(ctx context.Context) (channel, chatID string) {
+	c, _ := ctx.Value(toolChatKey{}).(toolChat)
+	return c.channel, c.chatID
+}
+
+// HealthChecker is an optional interface for tools that depend on
+// something outside the process (an API key, a daemon, an integration).
+// HealthCheck reports why the tool can't work right now, or nil. It must
+// be cheap and side-effect free.
+type HealthChecker interface {
+	Tool
+	HealthCheck(ctx context.Context) error
+}
+
 func ToolToSchema(tool Tool) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "function",
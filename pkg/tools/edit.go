@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // EditFileTool edits a file by replacing old_text with new_text.
@@ -80,13 +82,11 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 
 	// Check directory restriction
 	if t.allowedDir != "" {
-		allowedAbs, err := filepath.Abs(t.allowedDir)
+		jailed, err := utils.ResolveInWorkspace(t.allowedDir, resolvedPath)
 		if err != nil {
-			return "", fmt.Errorf("failed to resolve allowed directory: %w", err)
-		}
-		if !strings.HasPrefix(resolvedPath, allowedAbs) {
-			return "", fmt.Errorf("path %s is outside allowed directory %s", path, t.allowedDir)
+			return "", err
 		}
+		resolvedPath = jailed
 	}
 
 	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
@@ -160,7 +160,10 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return "", fmt.Errorf("content is required")
 	}
 
-	filePath := filepath.Clean(path)
+	filePath, err := checkPathAllowed(path)
+	if err != nil {
+		return "", err
+	}
 
 	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
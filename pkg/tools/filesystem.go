@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 // allowedDir restricts file operations. Set via SetAllowedDir().
@@ -16,7 +17,8 @@ func SetFSAllowedDir(dir string) {
 	fsAllowedDir = dir
 }
 
-// checkPathAllowed validates the path is within the allowed directory.
+// checkPathAllowed validates the path is within the allowed directory,
+// following symlinks so a link inside the workspace can't reach outside it.
 func checkPathAllowed(rawPath string) (string, error) {
 	absPath, err := filepath.Abs(rawPath)
 	if err != nil {
@@ -25,15 +27,7 @@ func checkPathAllowed(rawPath string) (string, error) {
 	if fsAllowedDir == "" {
 		return absPath, nil
 	}
-	allowedAbs, err := filepath.Abs(fsAllowedDir)
-	if err != nil {
-		return "", fmt.Errorf("invalid allowed dir: %w", err)
-	}
-	// Ensure path is under the allowed dir
-	if !strings.HasPrefix(absPath, allowedAbs+string(filepath.Separator)) && absPath != allowedAbs {
-		return "", fmt.Errorf("access denied: path %q is outside allowed directory %q", absPath, allowedAbs)
-	}
-	return absPath, nil
+	return utils.ResolveInWorkspace(fsAllowedDir, absPath)
 }
 
 type ReadFileTool struct{}
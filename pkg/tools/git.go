@@ -0,0 +1,396 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/codex"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// gitLogDefault and gitLogMax bound how many commits 'log' shows.
+const (
+	gitLogDefault = 20
+	gitLogMax     = 200
+)
+
+// GitTool lets the agent inspect and, with care, change git state in the
+// workspace. status, log, diff, show and branches only read. commit and
+// checkout run the files they would change through the diff approval
+// policy and need confirm=true when the policy wants a human to approve.
+// Repositories and paths are confined to the workspace: git is kept from
+// searching above it for a repository, and a repo whose top level lies
+// outside it is refused.
+type GitTool struct {
+	workspace string
+	ceiling   string // GIT_CEILING_DIRECTORIES: the workspace's parent
+	policy    *codex.ApprovalPolicy
+	timeout   time.Duration
+}
+
+// NewGitTool creates a GitTool jailed to workspace. policy decides which
+// commits need the user's approval; nil uses codex.DefaultPolicy.
+func NewGitTool(workspace string, policy *codex.ApprovalPolicy) *GitTool {
+	if policy == nil {
+		policy = codex.DefaultPolicy()
+	}
+	root, err := filepath.Abs(workspace)
+	if err == nil {
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			root = real
+		}
+	}
+	return &GitTool{workspace: workspace, ceiling: filepath.Dir(root), policy: policy, timeout: 30 * time.Second}
+}
+
+func (t *GitTool) Name() string { return "git" }
+
+func (t *GitTool) Description() string {
+	return `Inspect and update git repositories in the workspace.
+
+Available operations:
+  • status   — current branch and changed files
+  • log      — recent commits (limit, path optional)
+  • diff     — uncommitted changes, or against ref (staged, path optional)
+  • show     — one commit with its patch (ref, default HEAD)
+  • branches — local branches with their upstreams
+  • commit   — stage paths and commit just them (or commit what is staged) with message; commits touching critical files need confirm=true, so ask the user first
+  • checkout — switch to ref, creating it with create=true; switching to a ref that changes critical files needs confirm=true, so ask the user first
+
+Check status and diff before editing code so you know what you are changing.`
+}
+
+func (t *GitTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"status", "log", "diff", "show", "branches", "commit", "checkout"},
+				"description": "Operation to perform",
+			},
+			"repo": map[string]interface{}{
+				"type":        "string",
+				"description": "Repository directory relative to the workspace (default: the workspace)",
+			},
+			"ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit, branch or tag for diff, show and checkout",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Limit log or diff to this file or directory",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Commits to show for log (default %d, max %d)", gitLogDefault, gitLogMax),
+			},
+			"staged": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For diff: show staged changes instead of unstaged ones",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit message for commit",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files to stage and commit; other staged files are left out. Empty commits what is already staged",
+			},
+			"create": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For checkout: create ref as a new branch",
+			},
+			"confirm": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Must be true for commits and checkouts that need approval; only set it after the user has confirmed",
+			},
+		},
+		"required": []string{"operation"},
+	}
+}
+
+func (t *GitTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	operation, _ := args["operation"].(string)
+	repo, err := t.repoDir(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	ref, _ := args["ref"].(string)
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	path, err := t.repoPath(repo, args["path"])
+	if err != nil {
+		return "", err
+	}
+	confirm, _ := args["confirm"].(bool)
+
+	var out string
+	switch operation {
+	case "status":
+		out, err = t.git(ctx, repo, "status", "--short", "--branch")
+	case "log":
+		limit := gitLogDefault
+		if n, ok := args["limit"].(float64); ok && n > 0 {
+			limit = int(n)
+		}
+		if limit > gitLogMax {
+			limit = gitLogMax
+		}
+		gitArgs := []string{"log", "--format=%h %ad %an%n    %s", "--date=short", "-n", strconv.Itoa(limit)}
+		out, err = t.git(ctx, repo, withPath(gitArgs, path)...)
+	case "diff":
+		gitArgs := []string{"diff", "--stat", "--patch"}
+		if staged, _ := args["staged"].(bool); staged {
+			gitArgs = append(gitArgs, "--staged")
+		}
+		if ref != "" {
+			gitArgs = append(gitArgs, ref)
+		}
+		out, err = t.git(ctx, repo, withPath(gitArgs, path)...)
+	case "show":
+		if ref == "" {
+			ref = "HEAD"
+		}
+		out, err = t.git(ctx, repo, "show", "--stat", "--patch", ref)
+	case "branches":
+		out, err = t.git(ctx, repo, "branch", "--list", "-vv")
+	case "commit":
+		return t.commit(ctx, repo, args, confirm)
+	case "checkout":
+		return t.checkout(ctx, repo, ref, args, confirm)
+	default:
+		return "", fmt.Errorf("unknown git operation %q; valid: status, log, diff, show, branches, commit, checkout", operation)
+	}
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		out = "(no output)"
+	}
+	// A configured output cap is applied by the registry
+	if toolLimitsFrom(ctx).MaxOutputBytes == 0 {
+		out = TruncateOutput(out, 10000)
+	}
+	return out, nil
+}
+
+// commit stages the requested paths, checks what would be committed
+// against the approval policy and commits it. When the commit waits for
+// confirmation, the paths are unstaged again.
+func (t *GitTool) commit(ctx context.Context, repo string, args map[string]interface{}, confirm bool) (string, error) {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return "", fmt.Errorf("message is required for commit")
+	}
+	rawPaths, _ := args["paths"].([]interface{})
+	var paths []string
+	for _, p := range rawPaths {
+		rel, err := t.repoPath(repo, p)
+		if err != nil {
+			return "", err
+		}
+		if rel == "" {
+			return "", fmt.Errorf("paths must be non-empty strings")
+		}
+		paths = append(paths, rel)
+	}
+	if len(paths) > 0 {
+		if _, err := t.git(ctx, repo, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+			return "", err
+		}
+	}
+
+	// With paths, only they are judged and committed
+	pathspec := append([]string{"--"}, paths...)
+	staged, err := t.git(ctx, repo, append([]string{"diff", "--cached", "--name-status", "--no-renames"}, pathspec...)...)
+	if err != nil {
+		return "", err
+	}
+	if staged == "" {
+		return "Nothing to commit: no staged changes. Pass paths to stage files first.", nil
+	}
+
+	// Judge the commit as the diff it is
+	numstat, _ := t.git(ctx, repo, append([]string{"diff", "--cached", "--numstat", "--no-renames"}, pathspec...)...)
+	diff := stagedAsDiff(staged, numstat)
+	if level, reason := t.policy.EvaluateApproval(diff); level == codex.ApprovalRequired && !confirm {
+		if len(paths) > 0 {
+			// Don't leave what was staged for the commit in the user's index
+			if _, err := t.git(ctx, repo, append([]string{"reset", "-q"}, pathspec...)...); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("Not committed. %s — ask the user to confirm, then call commit again with confirm=true. Staged:\n%s",
+			reason, staged), nil
+	}
+
+	commitArgs := []string{"commit", "-q", "-m", message}
+	if len(paths) > 0 {
+		// Leave anything else already in the index uncommitted
+		commitArgs = append(append(commitArgs, "--only"), pathspec...)
+	}
+	// A bot's checkout may have no identity configured
+	if email, _ := t.git(ctx, repo, "config", "user.email"); email == "" {
+		commitArgs = append([]string{"-c", "user.name=picoclaw", "-c", "user.email=picoclaw@localhost"}, commitArgs...)
+	}
+	if _, err := t.git(ctx, repo, commitArgs...); err != nil {
+		return "", err
+	}
+	return t.git(ctx, repo, "log", "-1", "--stat", "--format=Committed %h%n    %s")
+}
+
+// checkout switches repo to ref, or creates it as a branch. The files the
+// switch would change are judged by the approval policy like a commit.
+func (t *GitTool) checkout(ctx context.Context, repo, ref string, args map[string]interface{}, confirm bool) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("ref is required for checkout")
+	}
+	gitArgs := []string{"checkout", "-q", "-b", ref}
+	if create, _ := args["create"].(bool); !create {
+		// A new branch starts at HEAD and changes no files
+		changed, err := t.git(ctx, repo, "diff", "--name-status", "--no-renames", "HEAD", ref, "--")
+		if err != nil {
+			return "", err
+		}
+		numstat, _ := t.git(ctx, repo, "diff", "--numstat", "--no-renames", "HEAD", ref, "--")
+		if level, reason := t.policy.EvaluateApproval(stagedAsDiff(changed, numstat)); level == codex.ApprovalRequired && !confirm {
+			return fmt.Sprintf("Not checked out. %s — ask the user to confirm, then call checkout again with confirm=true. Changes:\n%s",
+				reason, changed), nil
+		}
+		gitArgs = []string{"checkout", "-q", ref, "--"}
+	}
+	if _, err := t.git(ctx, repo, gitArgs...); err != nil {
+		return "", err
+	}
+	return t.git(ctx, repo, "status", "--short", "--branch")
+}
+
+// stagedAsDiff describes changes (git diff --name-status and --numstat
+// output) as a StructuredDiff for the approval policy. Only the paths,
+// operations and line counts are filled in.
+func stagedAsDiff(nameStatus, numstat string) *codex.StructuredDiff {
+	lines := make(map[string]int)
+	for _, l := range strings.Split(numstat, "\n") {
+		f := strings.SplitN(l, "\t", 3)
+		if len(f) == 3 {
+			added, _ := strconv.Atoi(f[0])
+			removed, _ := strconv.Atoi(f[1])
+			lines[f[2]] = added + removed
+		}
+	}
+
+	diff := &codex.StructuredDiff{}
+	for _, l := range strings.Split(nameStatus, "\n") {
+		f := strings.SplitN(l, "\t", 2)
+		if len(f) != 2 {
+			continue
+		}
+		op := codex.OpModify
+		switch f[0] {
+		case "A":
+			op = codex.OpCreate
+		case "D":
+			op = codex.OpDelete
+		}
+		// One content line per changed line, so MaxAutoLines sees the size
+		content := strings.Repeat("\n", lines[f[1]])
+		diff.Changes = append(diff.Changes, codex.FileChange{Op: op, Path: f[1], NewContent: content})
+	}
+	return diff
+}
+
+// repoDir resolves the repo argument inside the workspace and checks that
+// the repository it belongs to lies inside the workspace too.
+func (t *GitTool) repoDir(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, _ := args["repo"].(string)
+	if repo == "" {
+		repo = "."
+	}
+	dir, err := utils.ResolveInWorkspace(t.workspace, repo)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("repo %s is not a directory", repo)
+	}
+	top, err := t.git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("repo %s is not a git repository in the workspace", repo)
+	}
+	if _, err := utils.ResolveInWorkspace(t.workspace, top); err != nil {
+		return "", fmt.Errorf("repo %s belongs to a repository outside the workspace", repo)
+	}
+	return dir, nil
+}
+
+// repoPath checks that a path argument stays in repo and returns it
+// relative to repo, or "" if it wasn't given.
+func (t *GitTool) repoPath(repo string, arg interface{}) (string, error) {
+	p, _ := arg.(string)
+	if p == "" {
+		return "", nil
+	}
+	full, err := utils.ResolveInWorkspace(repo, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(repo, full)
+}
+
+func withPath(args []string, path string) []string {
+	if path == "" {
+		return args
+	}
+	return append(args, "--", path)
+}
+
+// git runs one git command in repo and returns its trimmed output. A
+// failing command's stderr becomes the error.
+func (t *GitTool) git(ctx context.Context, repo string, args ...string) (string, error) {
+	cmdCtx, cancel := context.WithTimeout(ctx, toolTimeout(ctx, t.timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, "git", append([]string{"--no-pager"}, args...)...)
+	cmd.Dir = repo
+	// The ceiling keeps git from finding a repository above the workspace
+	cmd.Env = append(gitEnv(), "GIT_TERMINAL_PROMPT=0", "GIT_CEILING_DIRECTORIES="+t.ceiling)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git %s timed out", args[0])
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// gitEnv is the process environment without the variables that point git
+// at a repository directly, which would bypass the ceiling.
+func gitEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case "GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_COMMON_DIR", "GIT_CEILING_DIRECTORIES":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
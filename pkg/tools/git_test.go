@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/codex"
+)
+
+// testPolicy requires approval for *.env files and deletions only.
+func testPolicy() *codex.ApprovalPolicy {
+	return &codex.ApprovalPolicy{
+		CriticalPaths: []string{"*.env"},
+		CriticalOps:   []codex.DiffOperation{codex.OpDelete},
+		MaxAutoFiles:  10,
+		MaxAutoLines:  500,
+	}
+}
+
+// runGitCmd runs git in dir for test setup.
+func runGitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newGitWorkspace creates a workspace that is a git repository with one
+// committed file.
+func newGitWorkspace(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "main.txt"), []byte("one\n"), 0644)
+	runGitCmd(t, ws, "init", "-q")
+	runGitCmd(t, ws, "add", ".")
+	runGitCmd(t, ws, "commit", "-q", "-m", "init")
+	return ws
+}
+
+func TestGitToolRejectsEscapes(t *testing.T) {
+	ws := newGitWorkspace(t)
+	tool := NewGitTool(ws, testPolicy())
+	ctx := context.Background()
+
+	for name, args := range map[string]map[string]interface{}{
+		"option ref":     {"operation": "show", "ref": "--output=/tmp/x"},
+		"path outside":   {"operation": "log", "path": "../outside"},
+		"repo outside":   {"operation": "status", "repo": ".."},
+		"commit outside": {"operation": "commit", "message": "x", "paths": []interface{}{"../x"}},
+	} {
+		if _, err := tool.Execute(ctx, args); err == nil {
+			t.Errorf("%s: Execute(%v) succeeded", name, args)
+		}
+	}
+}
+
+func TestGitToolStaysOutOfEnclosingRepo(t *testing.T) {
+	outer := newGitWorkspace(t)
+	ws := filepath.Join(outer, "workspace")
+	os.MkdirAll(ws, 0755)
+
+	// The workspace isn't a repository itself; git must not find outer's
+	tool := NewGitTool(ws, testPolicy())
+	if out, err := tool.Execute(context.Background(), map[string]interface{}{"operation": "log"}); err == nil {
+		t.Errorf("log in a non-repo workspace inside a repo = %q, want an error", out)
+	}
+}
+
+func TestGitToolCommitApproval(t *testing.T) {
+	ws := newGitWorkspace(t)
+	tool := NewGitTool(ws, testPolicy())
+	ctx := context.Background()
+
+	// Unrelated staged work must stay out of the bot's commit
+	os.WriteFile(filepath.Join(ws, "wip.txt"), []byte("wip\n"), 0644)
+	runGitCmd(t, ws, "add", "wip.txt")
+
+	os.WriteFile(filepath.Join(ws, "app.env"), []byte("KEY=1\n"), 0644)
+	commit := map[string]interface{}{"operation": "commit", "message": "add env", "paths": []interface{}{"app.env"}}
+	out, err := tool.Execute(ctx, commit)
+	if err != nil || !strings.HasPrefix(out, "Not committed.") {
+		t.Fatalf("commit of a critical file without confirm = %q, %v", out, err)
+	}
+	if head := runGitCmd(t, ws, "log", "--format=%s"); head != "init" {
+		t.Fatalf("log after refused commit = %q", head)
+	}
+	if staged := runGitCmd(t, ws, "diff", "--cached", "--name-only"); staged != "wip.txt" {
+		t.Errorf("staged after refused commit = %q, want only wip.txt", staged)
+	}
+
+	commit["confirm"] = true
+	if out, err := tool.Execute(ctx, commit); err != nil || !strings.Contains(out, "Committed") {
+		t.Fatalf("confirmed commit = %q, %v", out, err)
+	}
+	if files := runGitCmd(t, ws, "show", "--name-only", "--format=", "HEAD"); files != "app.env" {
+		t.Errorf("committed files = %q, want only app.env", files)
+	}
+	if staged := runGitCmd(t, ws, "diff", "--cached", "--name-only"); staged != "wip.txt" {
+		t.Errorf("staged after commit = %q, want wip.txt left staged", staged)
+	}
+
+	// Non-critical files commit without confirm
+	os.WriteFile(filepath.Join(ws, "main.txt"), []byte("two\n"), 0644)
+	out, err = tool.Execute(ctx, map[string]interface{}{"operation": "commit", "message": "edit", "paths": []interface{}{"main.txt"}})
+	if err != nil || !strings.Contains(out, "Committed") {
+		t.Errorf("ordinary commit = %q, %v", out, err)
+	}
+}
+
+func TestGitToolCheckoutApproval(t *testing.T) {
+	ws := newGitWorkspace(t)
+	tool := NewGitTool(ws, testPolicy())
+	ctx := context.Background()
+	base := runGitCmd(t, ws, "rev-parse", "--abbrev-ref", "HEAD")
+
+	// Creating a branch changes no files
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "checkout", "ref": "feature", "create": true})
+	if err != nil {
+		t.Fatalf("checkout create = %q, %v", out, err)
+	}
+	os.WriteFile(filepath.Join(ws, "app.env"), []byte("KEY=1\n"), 0644)
+	runGitCmd(t, ws, "add", "app.env")
+	runGitCmd(t, ws, "commit", "-q", "-m", "env")
+
+	// Switching back would delete app.env
+	back := map[string]interface{}{"operation": "checkout", "ref": base}
+	if out, _ := tool.Execute(ctx, back); !strings.HasPrefix(out, "Not checked out.") {
+		t.Fatalf("checkout removing a critical file without confirm = %q", out)
+	}
+	back["confirm"] = true
+	if _, err := tool.Execute(ctx, back); err != nil {
+		t.Fatal(err)
+	}
+	if cur := runGitCmd(t, ws, "rev-parse", "--abbrev-ref", "HEAD"); cur != base {
+		t.Errorf("on %q after confirmed checkout, want %q", cur, base)
+	}
+}
+
+func TestStagedAsDiff(t *testing.T) {
+	nameStatus := "A\tnew.go\nM\tpkg/a.go\nD\told.go\n"
+	numstat := "10\t0\tnew.go\n3\t2\tpkg/a.go\n0\t7\told.go\n-\t-\tlogo.png\n"
+	diff := stagedAsDiff(nameStatus, numstat)
+
+	want := []struct {
+		op    codex.DiffOperation
+		path  string
+		lines int
+	}{
+		{codex.OpCreate, "new.go", 10},
+		{codex.OpModify, "pkg/a.go", 5},
+		{codex.OpDelete, "old.go", 7},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("changes = %+v", diff.Changes)
+	}
+	for i, w := range want {
+		c := diff.Changes[i]
+		if c.Op != w.op || c.Path != w.path || strings.Count(c.NewContent, "\n") != w.lines {
+			t.Errorf("change %d = %s %s (%d lines), want %s %s (%d)",
+				i, c.Op, c.Path, strings.Count(c.NewContent, "\n"), w.op, w.path, w.lines)
+		}
+	}
+	if len(stagedAsDiff("", "").Changes) != 0 {
+		t.Error("empty output should give no changes")
+	}
+}
@@ -0,0 +1,388 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/integration"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+)
+
+// kanbanClaimLease is how long a claim made from chat holds the task, the
+// same default the REST claim endpoint uses.
+const kanbanClaimLease = 5 * time.Minute
+
+// kanbanListLimit caps how many tasks 'list' returns so a large board
+// doesn't flood the context window.
+const kanbanListLimit = 20
+
+// KanbanTool lets the agent manage the task board conversationally:
+// create, list, get, transition, claim and note. Deleting a task requires
+// confirm=true; without it the tool only describes what would be deleted so
+// the agent can ask the user first.
+type KanbanTool struct {
+	board   func() *kanban.KanbanIntegration
+	loc     *time.Location // zone for reading due dates
+	mu      sync.RWMutex
+	channel string
+	chatID  string
+}
+
+// NewKanbanTool creates a KanbanTool backed by the kanban integration in
+// the global registry. The board is looked up on each call, so the tool can
+// be registered before the integration starts. Due dates such as
+// "friday 3pm" are read in loc.
+func NewKanbanTool(loc *time.Location) *KanbanTool {
+	return &KanbanTool{board: registeredKanban, loc: loc}
+}
+
+// HealthCheck reports when the kanban integration isn't running.
+func (t *KanbanTool) HealthCheck(ctx context.Context) error {
+	if t.board() == nil {
+		return fmt.Errorf("kanban integration is not running")
+	}
+	return nil
+}
+
+func (t *KanbanTool) location() *time.Location {
+	if t.loc == nil {
+		return time.Local
+	}
+	return t.loc
+}
+
+func registeredKanban() *kanban.KanbanIntegration {
+	integ, ok := integration.GetRegistry().Get("kanban")
+	if !ok {
+		return nil
+	}
+	ki, _ := integ.(*kanban.KanbanIntegration)
+	return ki
+}
+
+func (t *KanbanTool) Name() string { return "kanban" }
+
+func (t *KanbanTool) Description() string {
+	return `Manage tasks on the kanban board.
+
+Available operations:
+  • create     — add a task (title required; description, category, priority, project, tags, due optional)
+  • list       — list tasks, optionally filtered by state, category or project
+  • get        — show one task with its notes
+  • transition — move a task to another state (inbox, planned, running, blocked, review, done)
+  • claim      — claim a task so other agents leave it alone
+  • note       — add a note to a task
+  • delete     — delete a task; requires confirm=true, so ask the user before setting it
+
+Use this when the user asks to track, update or check on work ("create a task to fix the login bug").`
+}
+
+func (t *KanbanTool) Parameters() map[string]interface{} {
+	var states, categories []string
+	for _, s := range kanban.AllStates() {
+		states = append(states, string(s))
+	}
+	for _, c := range kanban.AllCategories() {
+		categories = append(categories, string(c))
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"create", "list", "get", "transition", "claim", "note", "delete"},
+				"description": "Operation to perform",
+			},
+			"task_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Task ID (e.g. TASK-12) for get, transition, claim, note and delete",
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Task title for create",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "Task description for create",
+			},
+			"state": map[string]interface{}{
+				"type":        "string",
+				"enum":        states,
+				"description": "Target state for transition, or state filter for list",
+			},
+			"category": map[string]interface{}{
+				"type":        "string",
+				"enum":        categories,
+				"description": "Category for create, or category filter for list",
+			},
+			"priority": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"low", "normal", "high", "critical"},
+				"description": "Priority for create (default: normal)",
+			},
+			"project": map[string]interface{}{
+				"type":        "string",
+				"description": "Project for create, or project filter for list",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Tags for create",
+			},
+			"due": map[string]interface{}{
+				"type":        "string",
+				"description": "Due date for create, as YYYY-MM-DD or words like \"tomorrow 5pm\", \"next friday\" or \"in 3 days\". If it is rejected as ambiguous, ask the user which day they mean",
+			},
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Note text for note, or reason for transition",
+			},
+			"confirm": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Must be true to delete; only set it after the user has confirmed",
+			},
+		},
+		"required": []string{"operation"},
+	}
+}
+
+// SetContext records the chat the tool is serving; it becomes the author
+// of notes and the identity claims are made under.
+func (t *KanbanTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// actor names the agent acting for the current chat, e.g.
+// "agent:telegram:12345".
+func (t *KanbanTool) actor() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.channel == "" {
+		return "agent"
+	}
+	return "agent:" + t.channel + ":" + t.chatID
+}
+
+// source maps the current channel to a task source, falling back to llm.
+func (t *KanbanTool) source() kanban.TaskSource {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, s := range kanban.AllSources() {
+		if string(s) == t.channel {
+			return s
+		}
+	}
+	return kanban.SourceLLM
+}
+
+func (t *KanbanTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	kb := t.board()
+	if kb == nil {
+		return "", fmt.Errorf("kanban board not available")
+	}
+
+	operation, _ := args["operation"].(string)
+	taskID, _ := args["task_id"].(string)
+	taskID = strings.TrimSpace(taskID)
+	if operation != "create" && operation != "list" && taskID == "" {
+		return "", fmt.Errorf("task_id is required for %s", operation)
+	}
+
+	switch operation {
+	case "create":
+		return t.create(ctx, kb, args)
+	case "list":
+		return t.list(ctx, kb, args)
+	case "get":
+		return t.get(ctx, kb, taskID)
+
+	case "transition":
+		state, _ := args["state"].(string)
+		if !validKanbanState(state) {
+			return "", fmt.Errorf("unknown state %q", state)
+		}
+		reason, _ := args["text"].(string)
+		if err := kb.TransitionTaskCtx(ctx, taskID, kanban.TaskState(state), reason, t.actor()); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Moved %s to %s", taskID, state), nil
+
+	case "claim":
+		if err := kb.ClaimTaskCtx(ctx, taskID, t.actor(), kanbanClaimLease); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Claimed %s for %s", taskID, kanbanClaimLease), nil
+
+	case "note":
+		text, _ := args["text"].(string)
+		if strings.TrimSpace(text) == "" {
+			return "", fmt.Errorf("text is required for note")
+		}
+		if _, err := getKanbanTask(ctx, kb, taskID); err != nil {
+			return "", err
+		}
+		if err := kb.AddNoteCtx(ctx, taskID, text, t.actor()); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added note to %s", taskID), nil
+
+	case "delete":
+		task, err := getKanbanTask(ctx, kb, taskID)
+		if err != nil {
+			return "", err
+		}
+		if confirm, _ := args["confirm"].(bool); !confirm {
+			return fmt.Sprintf("Not deleted. %s — ask the user to confirm, then call delete again with confirm=true.",
+				formatKanbanTask(task)), nil
+		}
+		if err := kb.DeleteTaskCtx(ctx, taskID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted %s (%s)", taskID, task.Title), nil
+
+	default:
+		return "", fmt.Errorf("unknown kanban operation %q; valid: create, list, get, transition, claim, note, delete", operation)
+	}
+}
+
+func (t *KanbanTool) create(ctx context.Context, kb *kanban.KanbanIntegration, args map[string]interface{}) (string, error) {
+	title, _ := args["title"].(string)
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", fmt.Errorf("title is required for create")
+	}
+	task := &kanban.Task{Title: title, Source: t.source()}
+	task.Description, _ = args["description"].(string)
+	task.Project, _ = args["project"].(string)
+	task.Priority, _ = args["priority"].(string)
+	if c, _ := args["category"].(string); c != "" {
+		task.Category = kanban.TaskCategory(c)
+	}
+	if tags, ok := args["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok && s != "" {
+				task.Tags = append(task.Tags, s)
+			}
+		}
+	}
+
+	var due time.Time
+	if d, _ := args["due"].(string); strings.TrimSpace(d) != "" {
+		var err error
+		if due, err = kanban.ParseDueDate(d, time.Now().In(t.location())); err != nil {
+			return "", err
+		}
+		utc := due.UTC()
+		task.DueDate = &utc
+	}
+
+	if err := kb.CreateTaskCtx(ctx, task); err != nil {
+		return "", err
+	}
+	if task.DueDate != nil {
+		return fmt.Sprintf("Created %s, due %s", formatKanbanTask(task), due.Format("Mon 2 Jan 15:04 MST")), nil
+	}
+	return "Created " + formatKanbanTask(task), nil
+}
+
+func (t *KanbanTool) list(ctx context.Context, kb *kanban.KanbanIntegration, args map[string]interface{}) (string, error) {
+	var filters kanban.TaskFilters
+	if s, _ := args["state"].(string); s != "" {
+		if !validKanbanState(s) {
+			return "", fmt.Errorf("unknown state %q", s)
+		}
+		filters.State = kanban.TaskState(s)
+	} else {
+		filters.ExcludeDone = true
+	}
+	if c, _ := args["category"].(string); c != "" {
+		filters.Category = kanban.TaskCategory(c)
+	}
+	filters.Project, _ = args["project"].(string)
+
+	tasks, err := kb.ListTasksCtx(ctx, filters)
+	if err != nil {
+		return "", err
+	}
+	if len(tasks) == 0 {
+		return "No matching tasks.", nil
+	}
+
+	var sb strings.Builder
+	for i, task := range tasks {
+		if i == kanbanListLimit {
+			fmt.Fprintf(&sb, "... and %d more; narrow the filters to see them\n", len(tasks)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s\n", formatKanbanTask(task))
+	}
+	return sb.String(), nil
+}
+
+func (t *KanbanTool) get(ctx context.Context, kb *kanban.KanbanIntegration, taskID string) (string, error) {
+	task, err := getKanbanTask(ctx, kb, taskID)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(formatKanbanTask(task) + "\n")
+	if task.Description != "" {
+		sb.WriteString("\n" + task.Description + "\n")
+	}
+
+	notes, err := kb.ListNotesCtx(ctx, taskID)
+	if err != nil {
+		return "", err
+	}
+	if len(notes) > 0 {
+		sb.WriteString("\nNotes:\n")
+		for _, n := range notes {
+			fmt.Fprintf(&sb, "- %s (%s): %s\n", n.Author, n.CreatedAt.Format("2006-01-02 15:04"), n.Content)
+			for _, a := range n.Attachments {
+				fmt.Fprintf(&sb, "  attachment: %s\n", a)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// formatKanbanTask renders a one-line summary such as
+// "TASK-12 [running] Fix login bug (bug, high, claimed by agent:cli:x)".
+func formatKanbanTask(task *kanban.Task) string {
+	details := []string{string(task.Category), task.Priority}
+	if task.Project != "" {
+		details = append(details, "project "+task.Project)
+	}
+	if task.ClaimedBy != "" {
+		details = append(details, "claimed by "+task.ClaimedBy)
+	}
+	return fmt.Sprintf("%s [%s] %s (%s)", task.ID, task.State, task.Title, strings.Join(details, ", "))
+}
+
+// getKanbanTask is GetTaskCtx with a missing task reported as
+// kanban.ErrTaskNotFound rather than a bare sql.ErrNoRows.
+func getKanbanTask(ctx context.Context, kb *kanban.KanbanIntegration, id string) (*kanban.Task, error) {
+	task, err := kb.GetTaskCtx(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", kanban.ErrTaskNotFound, id)
+	}
+	return task, err
+}
+
+func validKanbanState(s string) bool {
+	for _, state := range kanban.AllStates() {
+		if string(state) == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/integration/kanban"
+)
+
+// newKanbanTool returns a KanbanTool over a fresh SQLite board in a temp
+// directory, serving the telegram chat 42.
+func newKanbanTool(t *testing.T) (*KanbanTool, *kanban.KanbanIntegration) {
+	t.Helper()
+	t.Setenv("PICOCLAW_DB", filepath.Join(t.TempDir(), "kanban.db"))
+	kb := &kanban.KanbanIntegration{}
+	if err := kb.Init(config.DefaultConfig(), nil); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	if err := kb.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	t.Cleanup(func() { kb.Stop(context.Background()) })
+
+	tool := &KanbanTool{board: func() *kanban.KanbanIntegration { return kb }, loc: time.UTC}
+	tool.SetContext("telegram", "42")
+	return tool, kb
+}
+
+// createKanbanTask creates a task through the tool and returns its ID.
+func createKanbanTask(t *testing.T, tool *KanbanTool, title string) string {
+	t.Helper()
+	out, err := tool.Execute(context.Background(), map[string]interface{}{
+		"operation": "create", "title": title, "category": "bug", "priority": "high",
+		"tags": []interface{}{"login", ""},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	id := strings.Fields(strings.TrimPrefix(out, "Created "))[0]
+	if !strings.HasPrefix(id, "TASK-") {
+		t.Fatalf("create output = %q", out)
+	}
+	return id
+}
+
+func TestKanbanToolCreate(t *testing.T) {
+	tool, kb := newKanbanTool(t)
+	ctx := context.Background()
+
+	id := createKanbanTask(t, tool, "Fix login bug")
+	task, err := kb.GetTaskCtx(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "Fix login bug" || task.Category != "bug" || task.Priority != "high" ||
+		task.Source != kanban.SourceTelegram || len(task.Tags) != 1 || task.Tags[0] != "login" {
+		t.Errorf("created task = %+v", task)
+	}
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "create", "title": "Ship it", "due": "2030-03-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, ", due Fri 1 Mar") {
+		t.Errorf("create with due = %q", out)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "create", "title": "  "}); err == nil {
+		t.Error("create without title succeeded")
+	}
+}
+
+func TestKanbanToolTransition(t *testing.T) {
+	tool, kb := newKanbanTool(t)
+	ctx := context.Background()
+	id := createKanbanTask(t, tool, "Fix login bug")
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "transition", "task_id": id, "state": "planned"})
+	if err != nil || out != "Moved "+id+" to planned" {
+		t.Fatalf("transition = %q, %v", out, err)
+	}
+	if task, _ := kb.GetTaskCtx(ctx, id); task.State != kanban.StatePlanned {
+		t.Errorf("state = %s, want planned", task.State)
+	}
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"operation": "transition", "task_id": id, "state": "review"})
+	if !errors.Is(err, kanban.ErrInvalidTransition) {
+		t.Errorf("planned → review error = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "transition", "task_id": id, "state": "sideways"}); err == nil {
+		t.Error("transition to unknown state succeeded")
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "transition", "state": "done"}); err == nil {
+		t.Error("transition without task_id succeeded")
+	}
+}
+
+func TestKanbanToolClaim(t *testing.T) {
+	tool, kb := newKanbanTool(t)
+	ctx := context.Background()
+	id := createKanbanTask(t, tool, "Fix login bug")
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "claim", "task_id": id}); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := kb.GetTaskCtx(ctx, id)
+	if task.ClaimedBy != "agent:telegram:42" || task.State != kanban.StateRunning {
+		t.Errorf("after claim: claimed_by %q, state %s", task.ClaimedBy, task.State)
+	}
+
+	// Another chat can't take it while the lease holds
+	other := &KanbanTool{board: tool.board}
+	other.SetContext("discord", "7")
+	_, err := other.Execute(ctx, map[string]interface{}{"operation": "claim", "task_id": id})
+	var conflict *kanban.ClaimConflictError
+	if !errors.As(err, &conflict) || conflict.ClaimedBy != "agent:telegram:42" {
+		t.Errorf("second claim error = %v, want ClaimConflictError", err)
+	}
+}
+
+func TestKanbanToolNote(t *testing.T) {
+	tool, _ := newKanbanTool(t)
+	ctx := context.Background()
+	id := createKanbanTask(t, tool, "Fix login bug")
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "note", "task_id": id, "text": "repro on safari"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "get", "task_id": id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Notes:\n- agent:telegram:42 (") || !strings.Contains(out, "): repro on safari") {
+		t.Errorf("get output:\n%s", out)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "note", "task_id": id, "text": " "}); err == nil {
+		t.Error("empty note succeeded")
+	}
+	_, err = tool.Execute(ctx, map[string]interface{}{"operation": "note", "task_id": "TASK-999", "text": "x"})
+	if !errors.Is(err, kanban.ErrTaskNotFound) {
+		t.Errorf("note on missing task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestKanbanToolDeleteNeedsConfirm(t *testing.T) {
+	tool, kb := newKanbanTool(t)
+	ctx := context.Background()
+	id := createKanbanTask(t, tool, "Fix login bug")
+
+	for _, args := range []map[string]interface{}{
+		{"operation": "delete", "task_id": id},
+		{"operation": "delete", "task_id": id, "confirm": false},
+		{"operation": "delete", "task_id": id, "confirm": "true"},
+	} {
+		out, err := tool.Execute(ctx, args)
+		if err != nil {
+			t.Fatalf("delete %v: %v", args, err)
+		}
+		if !strings.HasPrefix(out, "Not deleted. "+id) {
+			t.Errorf("delete %v = %q", args, out)
+		}
+		if _, err := kb.GetTaskCtx(ctx, id); err != nil {
+			t.Fatalf("task gone after unconfirmed delete %v: %v", args, err)
+		}
+	}
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "delete", "task_id": id, "confirm": true})
+	if err != nil || out != "Deleted "+id+" (Fix login bug)" {
+		t.Fatalf("confirmed delete = %q, %v", out, err)
+	}
+	if _, err := kb.GetTaskCtx(ctx, id); err == nil {
+		t.Error("task still exists after confirmed delete")
+	}
+	_, err = tool.Execute(ctx, map[string]interface{}{"operation": "delete", "task_id": id, "confirm": true})
+	if !errors.Is(err, kanban.ErrTaskNotFound) {
+		t.Errorf("delete of missing task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestKanbanToolList(t *testing.T) {
+	tool, _ := newKanbanTool(t)
+	ctx := context.Background()
+	id := createKanbanTask(t, tool, "Fix login bug")
+	done := createKanbanTask(t, tool, "Old work")
+	if _, err := tool.Execute(ctx, map[string]interface{}{"operation": "transition", "task_id": done, "state": "done"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"operation": "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, id+" [inbox] Fix login bug (bug, high)") || strings.Contains(out, done) {
+		t.Errorf("list output:\n%s", out)
+	}
+	out, _ = tool.Execute(ctx, map[string]interface{}{"operation": "list", "state": "done"})
+	if !strings.Contains(out, done) || strings.Contains(out, id) {
+		t.Errorf("list done output:\n%s", out)
+	}
+}
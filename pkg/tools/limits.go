@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ToolLimits bounds one tool's execution. Zero fields keep the tool's
+// built-in default (exec: 60s and 10000 bytes, qmd: 30s and no output cap).
+type ToolLimits struct {
+	Timeout        time.Duration
+	MaxOutputBytes int
+}
+
+type toolLimitsKey struct{}
+
+func withToolLimits(ctx context.Context, l ToolLimits) context.Context {
+	return context.WithValue(ctx, toolLimitsKey{}, l)
+}
+
+func toolLimitsFrom(ctx context.Context) ToolLimits {
+	l, _ := ctx.Value(toolLimitsKey{}).(ToolLimits)
+	return l
+}
+
+// toolTimeout is the configured timeout for the running tool, or def when
+// none is set.
+func toolTimeout(ctx context.Context, def time.Duration) time.Duration {
+	if t := toolLimitsFrom(ctx).Timeout; t > 0 {
+		return t
+	}
+	return def
+}
+
+// TruncateOutput cuts s to max bytes and says how much was dropped, so the
+// model knows the output is incomplete.
+func TruncateOutput(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + fmt.Sprintf("\n... (truncated, %d more bytes)", len(s)-max)
+}
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fact is one remembered key/value pair, scoped to a chat.
+type Fact struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FactStore persists facts the agent chooses to remember. Scopes keep one
+// user's or chat's facts from leaking into another's.
+type FactStore interface {
+	SetFact(scope, key, value string) error
+	GetFact(scope, key string) (string, bool, error)
+	DeleteFact(scope, key string) error
+	// ListFacts returns a scope's facts, most recently updated first.
+	ListFacts(scope string) ([]Fact, error)
+}
+
+// FactScope is the memory scope for a chat on a channel.
+func FactScope(channel, chatID string) string {
+	return channel + ":" + chatID
+}
+
+// factContext is what the memory tools share. The chat they serve comes
+// with each call (see WithChat), never from the shared instance, so
+// concurrent chats can't reach each other's facts.
+type factContext struct {
+	store FactStore
+}
+
+func (c *factContext) scope(ctx context.Context) (string, error) {
+	channel, chatID := chatFrom(ctx)
+	if channel == "" || chatID == "" {
+		return "", fmt.Errorf("no chat context for memory")
+	}
+	return FactScope(channel, chatID), nil
+}
+
+// NewMemoryTools returns the memory_set, memory_get and memory_list tools
+// backed by store.
+func NewMemoryTools(store FactStore) []Tool {
+	return []Tool{
+		&MemorySetTool{factContext{store: store}},
+		&MemoryGetTool{factContext{store: store}},
+		&MemoryListTool{factContext{store: store}},
+	}
+}
+
+// MemorySetTool remembers (or forgets) a fact about the current chat.
+type MemorySetTool struct{ factContext }
+
+func (t *MemorySetTool) Name() string { return "memory_set" }
+
+func (t *MemorySetTool) Description() string {
+	return "Remember a durable fact about the user or their setup (e.g. key 'timezone', value 'PST') so it is available in future conversations. Use a short, stable key. An empty value forgets the fact."
+}
+
+func (t *MemorySetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Short name for the fact, e.g. 'timezone' or 'prod_db'",
+			},
+			"value": map[string]interface{}{
+				"type":        "string",
+				"description": "The fact to remember; empty to forget it",
+			},
+		},
+		"required": []string{"key", "value"},
+	}
+}
+
+func (t *MemorySetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	scope, err := t.scope(ctx)
+	if err != nil {
+		return "", err
+	}
+	key, _ := args["key"].(string)
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	value, _ := args["value"].(string)
+
+	if strings.TrimSpace(value) == "" {
+		if err := t.store.DeleteFact(scope, key); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Forgot %s", key), nil
+	}
+	if err := t.store.SetFact(scope, key, value); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Remembered %s", key), nil
+}
+
+// MemoryGetTool recalls one fact about the current chat.
+type MemoryGetTool struct{ factContext }
+
+func (t *MemoryGetTool) Name() string { return "memory_get" }
+
+func (t *MemoryGetTool) Description() string {
+	return "Recall a fact previously saved with memory_set, by key."
+}
+
+func (t *MemoryGetTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key of the fact to recall",
+			},
+		},
+		"required": []string{"key"},
+	}
+}
+
+func (t *MemoryGetTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	scope, err := t.scope(ctx)
+	if err != nil {
+		return "", err
+	}
+	key, _ := args["key"].(string)
+	value, ok, err := t.store.GetFact(scope, strings.TrimSpace(key))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("Nothing remembered for %s", key), nil
+	}
+	return value, nil
+}
+
+// MemoryListTool lists every fact remembered for the current chat.
+type MemoryListTool struct{ factContext }
+
+func (t *MemoryListTool) Name() string { return "memory_list" }
+
+func (t *MemoryListTool) Description() string {
+	return "List every fact remembered for this conversation's user."
+}
+
+func (t *MemoryListTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *MemoryListTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	scope, err := t.scope(ctx)
+	if err != nil {
+		return "", err
+	}
+	facts, err := t.store.ListFacts(scope)
+	if err != nil {
+		return "", err
+	}
+	if len(facts) == 0 {
+		return "Nothing remembered yet.", nil
+	}
+	var sb strings.Builder
+	for _, f := range facts {
+		fmt.Fprintf(&sb, "- %s: %s\n", f.Key, f.Value)
+	}
+	return sb.String(), nil
+}
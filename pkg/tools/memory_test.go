@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// mapFactStore is an in-memory FactStore.
+type mapFactStore struct {
+	mu    sync.Mutex
+	facts map[string]string // scope + "/" + key -> value
+}
+
+func (s *mapFactStore) SetFact(scope, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.facts[scope+"/"+key] = value
+	return nil
+}
+
+func (s *mapFactStore) GetFact(scope, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.facts[scope+"/"+key]
+	return v, ok, nil
+}
+
+func (s *mapFactStore) DeleteFact(scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.facts, scope+"/"+key)
+	return nil
+}
+
+func (s *mapFactStore) ListFacts(scope string) ([]Fact, error) { return nil, nil }
+
+func TestMemoryToolsScopePerCall(t *testing.T) {
+	store := &mapFactStore{facts: map[string]string{}}
+	r := NewToolRegistry()
+	for _, tool := range NewMemoryTools(store) {
+		r.Register(tool)
+	}
+
+	// Chats running at once share the tool instances but not their facts
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chat := fmt.Sprint(i)
+			args := map[string]interface{}{"key": "name", "value": "user" + chat}
+			if _, err := r.ExecuteWithContext(context.Background(), "memory_set", args, "api", chat); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		chat := fmt.Sprint(i)
+		if v, _, _ := store.GetFact(FactScope("api", chat), "name"); v != "user"+chat {
+			t.Errorf("chat %s remembered %q", chat, v)
+		}
+	}
+}
@@ -4,14 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
+// qmdTimeout bounds one search or CLI call unless the registry configures
+// a different timeout for the tool.
+const qmdTimeout = 30 * time.Second
+
 // QMDTool gives agents access to the QMD hybrid search engine.
 //
 // Search modes:
@@ -20,6 +32,8 @@ import (
 //   - "mcp":  always use the HTTP daemon (fail if not running).
 //   - "cli":  always use the qmd CLI; never attempts the daemon.
 //
+// A single call can override the mode with the force_mode argument.
+//
 // Daemon mode keeps the 2 GB of local ML models warm across requests so
 // repeated searches are fast.  Start with:
 //
@@ -36,8 +50,30 @@ type QMDTool struct {
 	mcpEndpoint string
 	mode        string
 	httpClient  *http.Client
+
+	// Last daemon health probe, reused for qmdProbeTTL
+	probeMu      sync.Mutex
+	probedAt     time.Time
+	probeHealthy bool
+
+	// autoStart spawns the daemon when "auto" mode finds it down; the
+	// spawn is attempted at most once per tool.
+	autoStart bool
+	startOnce sync.Once
+	startErr  error // guarded by probeMu
 }
 
+// qmdStartWait bounds spawning the daemon and waiting for it to report
+// healthy.
+const qmdStartWait = 15 * time.Second
+
+// qmdProbeTTL is how long a daemon health probe result is reused, so a
+// burst of searches doesn't pay the health check on every call.
+const qmdProbeTTL = 10 * time.Second
+
+// qmdModes are the valid values for the instance mode and force_mode.
+var qmdModes = []string{"auto", "mcp", "cli"}
+
 // NewQMDTool creates a QMDTool.
 //   - mcpEndpoint: QMD HTTP MCP URL (empty → "http://localhost:8181/mcp")
 //   - mode:        "auto" | "mcp" | "cli"  (empty → "auto")
@@ -51,10 +87,17 @@ func NewQMDTool(mcpEndpoint, mode string) *QMDTool {
 	return &QMDTool{
 		mcpEndpoint: mcpEndpoint,
 		mode:        mode,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  &http.Client{}, // calls are bounded by qmdTimeout or the configured limit
 	}
 }
 
+// SetAutoStartDaemon makes "auto" mode start the daemon (qmd mcp --http
+// --daemon on the endpoint's port) instead of degrading to BM25 when it
+// isn't running.
+func (q *QMDTool) SetAutoStartDaemon(enabled bool) {
+	q.autoStart = enabled
+}
+
 func (q *QMDTool) Name() string { return "qmd" }
 
 func (q *QMDTool) Description() string {
@@ -68,7 +111,8 @@ Available operations:
   • status  — show indexed collections and document counts
 
 Always search before answering questions about past decisions, kanban tasks, or system history.
-Use 'search' for quick lookups; 'query' when you need the most accurate results.`
+Use 'search' for quick lookups; 'query' when you need the most accurate results.
+Results end with a JSON block of hits (path, docid, score, snippet) — or the document's path and title for 'get'; cite sources from it.`
 }
 
 func (q *QMDTool) Parameters() map[string]interface{} {
@@ -98,6 +142,12 @@ func (q *QMDTool) Parameters() map[string]interface{} {
 				"description": "Maximum results to return (default: 5)",
 				"default":     5,
 			},
+			"force_mode": map[string]interface{}{
+				"type": "string",
+				"enum": qmdModes,
+				"description": "Optional: override the backend for this call only — " +
+					"'cli' for a fast BM25 search, 'mcp' to require the daemon, 'auto' to pick",
+			},
 		},
 		"required": []string{"operation"},
 	}
@@ -112,7 +162,14 @@ func (q *QMDTool) Execute(ctx context.Context, args map[string]interface{}) (str
 		limit = int(l)
 	}
 
-	useMCP := q.mode == "mcp" || (q.mode == "auto" && q.isDaemonReachable())
+	mode := q.mode
+	if forced, _ := args["force_mode"].(string); forced != "" {
+		if !validQMDMode(forced) {
+			return "", fmt.Errorf("invalid force_mode %q; valid: %s", forced, strings.Join(qmdModes, ", "))
+		}
+		mode = forced
+	}
+	useMCP := mode == "mcp" || (mode == "auto" && (q.isDaemonReachable() || q.ensureDaemon()))
 
 	switch operation {
 	case "search":
@@ -130,7 +187,7 @@ func (q *QMDTool) Execute(ctx context.Context, args map[string]interface{}) (str
 		if err != nil {
 			return result, err
 		}
-		return "[Note: QMD daemon not running — showing BM25 keyword results instead of vector search]\n\n" + result, nil
+		return q.fallbackNote("showing BM25 keyword results instead of vector search") + result, nil
 
 	case "query":
 		if useMCP {
@@ -140,16 +197,19 @@ func (q *QMDTool) Execute(ctx context.Context, args map[string]interface{}) (str
 		if err != nil {
 			return result, err
 		}
-		return "[Note: QMD daemon not running — showing BM25 results. Start daemon for full hybrid search.]\n\n" + result, nil
+		return q.fallbackNote("showing BM25 results. Start daemon for full hybrid search.") + result, nil
 
 	case "get":
 		if query == "" {
 			return "", fmt.Errorf("'query' must contain the document path or #docid to retrieve")
 		}
+		if err := checkQMDPath(query); err != nil {
+			return "", err
+		}
 		if useMCP {
 			return q.mcpToolCall(ctx, "get", map[string]interface{}{"file": query})
 		}
-		return q.cliRun(ctx, []string{"get", query})
+		return q.cliGet(ctx, query)
 
 	case "status":
 		if useMCP {
@@ -162,6 +222,38 @@ func (q *QMDTool) Execute(ctx context.Context, args map[string]interface{}) (str
 	}
 }
 
+// checkQMDPath keeps 'get' inside the workspace jail. Docids and
+// collection-relative paths are resolved by qmd against its own index, but
+// absolute or home-relative filesystem paths must stay under the workspace,
+// and ".." segments are never allowed.
+func checkQMDPath(ref string) error {
+	if strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	for _, seg := range strings.FieldsFunc(ref, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return fmt.Errorf("path traversal not allowed: %s", ref)
+		}
+	}
+	if fsAllowedDir == "" {
+		return nil
+	}
+
+	path := ref
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("cannot expand %s: %w", ref, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		return nil
+	}
+	_, err := utils.ResolveInWorkspace(fsAllowedDir, path)
+	return err
+}
+
 // ---------------------------------------------------------------------------
 // MCP HTTP client
 // ---------------------------------------------------------------------------
@@ -183,8 +275,37 @@ type mcpResponse struct {
 	} `json:"error,omitempty"`
 }
 
-// isDaemonReachable probes the /health endpoint with a short timeout.
+// HealthCheck reports when no backend for the configured mode is
+// available: the daemon for "mcp", the CLI for "cli", either for "auto".
+func (q *QMDTool) HealthCheck(ctx context.Context) error {
+	daemon := q.mode != "cli" && q.isDaemonReachable()
+	_, cliErr := exec.LookPath(resolveQMDCmd())
+	switch {
+	case q.mode == "mcp" && !daemon:
+		return fmt.Errorf("QMD daemon not reachable at %s", q.mcpEndpoint)
+	case q.mode == "cli" && cliErr != nil:
+		return fmt.Errorf("qmd CLI not found: %v", cliErr)
+	case q.mode == "auto" && !daemon && cliErr != nil:
+		return fmt.Errorf("neither the QMD daemon (%s) nor the qmd CLI is available", q.mcpEndpoint)
+	}
+	return nil
+}
+
+// isDaemonReachable reports whether the daemon is up, probing at most once
+// per qmdProbeTTL.
 func (q *QMDTool) isDaemonReachable() bool {
+	q.probeMu.Lock()
+	defer q.probeMu.Unlock()
+	if time.Since(q.probedAt) < qmdProbeTTL {
+		return q.probeHealthy
+	}
+	q.probeHealthy = q.probeDaemon()
+	q.probedAt = time.Now()
+	return q.probeHealthy
+}
+
+// probeDaemon checks the /health endpoint with a short timeout.
+func (q *QMDTool) probeDaemon() bool {
 	healthURL := strings.Replace(q.mcpEndpoint, "/mcp", "/health", 1)
 	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
 	defer cancel()
@@ -200,6 +321,80 @@ func (q *QMDTool) isDaemonReachable() bool {
 	return resp.StatusCode == 200
 }
 
+// ensureDaemon starts the daemon if auto-start is enabled and no start has
+// been attempted yet, and reports whether it is now reachable. A failed
+// start is remembered and not retried; fallbackNote reports it.
+func (q *QMDTool) ensureDaemon() bool {
+	if !q.autoStart {
+		return false
+	}
+	q.startOnce.Do(func() {
+		err := q.startDaemon()
+		if err != nil {
+			logger.WarnCF("qmd", "QMD daemon auto-start failed", map[string]interface{}{"error": err.Error()})
+		}
+		q.probeMu.Lock()
+		q.startErr = err
+		q.probeMu.Unlock()
+	})
+	return q.startFailure() == nil && q.isDaemonReachable()
+}
+
+func (q *QMDTool) startFailure() error {
+	q.probeMu.Lock()
+	defer q.probeMu.Unlock()
+	return q.startErr
+}
+
+// startDaemon spawns the daemon on the endpoint's port and waits for it to
+// pass the health check.
+func (q *QMDTool) startDaemon() error {
+	port := "8181"
+	if u, err := url.Parse(q.mcpEndpoint); err == nil && u.Port() != "" {
+		port = u.Port()
+	}
+	logger.InfoCF("qmd", "Starting QMD daemon", map[string]interface{}{"port": port})
+
+	ctx, cancel := context.WithTimeout(context.Background(), qmdStartWait)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, resolveQMDCmd(), "mcp", "--http", "--daemon", "--port", port)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// The forked daemon inherits stderr; don't wait for it to close.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		errMsg := filterLlamaStderr(stderr.String())
+		if errMsg == "" {
+			errMsg = err.Error()
+		}
+		return fmt.Errorf("qmd mcp --daemon: %s", errMsg)
+	}
+
+	for {
+		if q.probeDaemon() {
+			q.probeMu.Lock()
+			q.probeHealthy, q.probedAt = true, time.Now()
+			q.probeMu.Unlock()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("daemon on port %s not healthy after %s", port, qmdStartWait)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// fallbackNote explains why results came from BM25, including a failed
+// auto-start.
+func (q *QMDTool) fallbackNote(detail string) string {
+	if err := q.startFailure(); err != nil {
+		return fmt.Sprintf("[Note: QMD daemon could not be started (%v) — %s]\n\n", err, detail)
+	}
+	return fmt.Sprintf("[Note: QMD daemon not running — %s]\n\n", detail)
+}
+
 // mcpToolCall executes a tools/call via the MCP HTTP transport.
 // It follows the MCP session protocol:
 //  1. POST initialize → receive Mcp-Session-Id header
@@ -212,6 +407,9 @@ func (q *QMDTool) mcpToolCall(ctx context.Context, toolName string, arguments ma
 		}
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout(ctx, qmdTimeout))
+	defer cancel()
+
 	sessionID, err := q.mcpInit(ctx)
 	if err != nil {
 		return "", fmt.Errorf("qmd daemon unreachable: %w", err)
@@ -230,7 +428,7 @@ func (q *QMDTool) mcpToolCall(ctx context.Context, toolName string, arguments ma
 	if err != nil {
 		return "", err
 	}
-	return extractMCPText(raw)
+	return extractMCPResult(raw).render(), nil
 }
 
 // mcpInit sends an MCP initialize request and returns the session ID.
@@ -298,44 +496,64 @@ func (q *QMDTool) mcpPost(ctx context.Context, req mcpRequest, sessionID string)
 	return mcpResp.Result, nil
 }
 
-// extractMCPText pulls human-readable text out of a tools/call result.
-func extractMCPText(raw json.RawMessage) (string, error) {
+// extractMCPResult pulls human-readable text out of a tools/call result,
+// along with search hits (from structuredContent or a JSON text block) and,
+// for 'get', the returned document's path and title.
+func extractMCPResult(raw json.RawMessage) *qmdResult {
 	var result struct {
 		Content []struct {
 			Type     string `json:"type"`
 			Text     string `json:"text"`
 			Resource *struct {
+				URI   string `json:"uri"`
 				Name  string `json:"name"`
 				Title string `json:"title"`
 				Text  string `json:"text"`
 			} `json:"resource,omitempty"`
 		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
 	}
 	if err := json.Unmarshal(raw, &result); err != nil {
-		return string(raw), nil // return raw if unparseable
+		return &qmdResult{Text: string(raw)} // return raw if unparseable
+	}
+
+	out := &qmdResult{}
+	if len(result.StructuredContent) > 0 {
+		out.Hits = parseQMDHits(result.StructuredContent)
 	}
 
 	var parts []string
 	for _, c := range result.Content {
 		switch c.Type {
 		case "text":
-			if c.Text != "" {
-				parts = append(parts, c.Text)
+			if c.Text == "" {
+				continue
 			}
+			if hits := parseQMDHits([]byte(c.Text)); hits != nil && out.Hits == nil {
+				out.Hits = hits
+				parts = append(parts, formatQMDHits(hits))
+				continue
+			}
+			parts = append(parts, c.Text)
 		case "resource":
 			if c.Resource != nil {
-				header := c.Resource.Name
-				if c.Resource.Title != "" && c.Resource.Title != c.Resource.Name {
-					header = fmt.Sprintf("%s (%s)", c.Resource.Name, c.Resource.Title)
+				path := c.Resource.Name
+				if path == "" {
+					path = c.Resource.URI
+				}
+				header := path
+				if c.Resource.Title != "" && c.Resource.Title != path {
+					header = fmt.Sprintf("%s (%s)", path, c.Resource.Title)
 				}
 				parts = append(parts, fmt.Sprintf("=== %s ===\n%s", header, c.Resource.Text))
+				if out.Document == nil {
+					out.Document = &qmdDocument{Path: path, Title: c.Resource.Title}
+				}
 			}
 		}
 	}
-	if len(parts) == 0 {
-		return "(no results)", nil
-	}
-	return strings.Join(parts, "\n\n"), nil
+	out.Text = strings.Join(parts, "\n\n")
+	return out
 }
 
 // ---------------------------------------------------------------------------
@@ -363,11 +581,32 @@ func (q *QMDTool) cliSearch(ctx context.Context, mode, query, collection string,
 	if collection != "" {
 		args = append(args, "-c", collection)
 	}
-	return q.cliRun(ctx, args)
+	out, err := q.cliRun(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	if hits := parseQMDHits([]byte(out)); hits != nil {
+		return (&qmdResult{Text: formatQMDHits(hits), Hits: hits}).render(), nil
+	}
+	return out, nil
+}
+
+// cliGet retrieves a document with the CLI. The CLI prints only the
+// content, so the path is the one asked for and the title its first heading.
+func (q *QMDTool) cliGet(ctx context.Context, ref string) (string, error) {
+	text, err := q.cliRun(ctx, []string{"get", ref})
+	if err != nil {
+		return "", err
+	}
+	doc := &qmdDocument{Title: markdownTitle(text)}
+	if !strings.HasPrefix(ref, "#") {
+		doc.Path = ref
+	}
+	return (&qmdResult{Text: text, Document: doc}).render(), nil
 }
 
 func (q *QMDTool) cliRun(ctx context.Context, args []string) (string, error) {
-	cmdCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	cmdCtx, cancel := context.WithTimeout(ctx, toolTimeout(ctx, qmdTimeout))
 	defer cancel()
 
 	cmd := exec.CommandContext(cmdCtx, resolveQMDCmd(), args...)
@@ -396,6 +635,15 @@ func (q *QMDTool) cliRun(ctx context.Context, args []string) (string, error) {
 // Helpers
 // ---------------------------------------------------------------------------
 
+func validQMDMode(mode string) bool {
+	for _, m := range qmdModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
 // filterLlamaStderr removes node-llama-cpp compilation noise from stderr.
 // On every cold startup, node-llama-cpp tries to build native binaries and
 // emits cmake/CUDA output even when it falls back successfully to CPU.  We only
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// qmdHit is one search result in a form the agent can cite from.
+type qmdHit struct {
+	Path    string  `json:"path,omitempty"`
+	DocID   string  `json:"docid,omitempty"`
+	Title   string  `json:"title,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// qmdDocument identifies the document a 'get' returned.
+type qmdDocument struct {
+	Path  string `json:"path,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// qmdResult is a QMD response: readable text plus whatever structure the
+// daemon or CLI provided.
+type qmdResult struct {
+	Text     string
+	Hits     []qmdHit
+	Document *qmdDocument
+}
+
+// render returns the readable text followed by the structured data as a
+// JSON block, so the agent can quote paths and scores exactly.
+func (r *qmdResult) render() string {
+	var structured interface{}
+	label := ""
+	switch {
+	case len(r.Hits) > 0:
+		structured, label = r.Hits, "Results"
+	case r.Document != nil && (r.Document.Path != "" || r.Document.Title != ""):
+		structured, label = r.Document, "Document"
+	}
+
+	text := r.Text
+	if text == "" {
+		text = "(no results)"
+	}
+	if structured == nil {
+		return text
+	}
+	data, err := json.Marshal(structured)
+	if err != nil {
+		return text
+	}
+	return fmt.Sprintf("%s\n\n%s (JSON):\n%s", text, label, data)
+}
+
+// parseQMDHits reads search hits from QMD JSON: an array of results, or an
+// object holding one under "results" or "hits". Field names vary between the
+// CLI and the daemon, so the common spellings are accepted. It returns nil
+// when data isn't a result list.
+func parseQMDHits(data []byte) []qmdHit {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped map[string]json.RawMessage
+		if json.Unmarshal(data, &wrapped) != nil {
+			return nil
+		}
+		list, ok := wrapped["results"]
+		if !ok {
+			list = wrapped["hits"]
+		}
+		if json.Unmarshal(list, &items) != nil {
+			return nil
+		}
+	}
+
+	hits := make([]qmdHit, 0, len(items))
+	for _, item := range items {
+		hit := qmdHit{
+			Path:    firstString(item, "path", "file", "filepath", "uri"),
+			DocID:   firstString(item, "docid", "doc_id", "id"),
+			Title:   firstString(item, "title", "name"),
+			Snippet: firstString(item, "snippet", "context", "text", "content"),
+		}
+		if hit.DocID != "" && !strings.HasPrefix(hit.DocID, "#") {
+			hit.DocID = "#" + hit.DocID
+		}
+		if score, ok := item["score"].(float64); ok {
+			hit.Score = score
+		}
+		if hit.Path != "" || hit.DocID != "" {
+			hits = append(hits, hit)
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+	return hits
+}
+
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// formatQMDHits renders hits as a numbered list for reading.
+func formatQMDHits(hits []qmdHit) string {
+	var sb strings.Builder
+	for i, h := range hits {
+		ref := h.Path
+		if ref == "" {
+			ref = h.DocID
+		}
+		fmt.Fprintf(&sb, "%d. %s", i+1, ref)
+		var meta []string
+		if h.Title != "" && h.Title != h.Path {
+			meta = append(meta, h.Title)
+		}
+		if h.DocID != "" && ref != h.DocID {
+			meta = append(meta, h.DocID)
+		}
+		if h.Score != 0 {
+			meta = append(meta, fmt.Sprintf("score %.2f", h.Score))
+		}
+		if len(meta) > 0 {
+			fmt.Fprintf(&sb, " (%s)", strings.Join(meta, ", "))
+		}
+		sb.WriteString("\n")
+		if h.Snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", strings.ReplaceAll(strings.TrimSpace(h.Snippet), "\n", "\n   "))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// markdownTitle returns the first "# " heading in text, if any.
+func markdownTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return ""
+}
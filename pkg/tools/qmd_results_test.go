@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQMDHits(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []qmdHit
+	}{
+		{
+			name: "cli array",
+			data: `[{"docid":"abc123","score":0.82,"file":"notes/go.md","title":"Go notes","snippet":"goroutines"}]`,
+			want: []qmdHit{{Path: "notes/go.md", DocID: "#abc123", Title: "Go notes", Score: 0.82, Snippet: "goroutines"}},
+		},
+		{
+			name: "mcp results object",
+			data: `{"results":[{"path":"a.md","doc_id":"#d1","name":"A","context":"first"},{"uri":"qmd://b.md","id":"d2","text":"second","score":1}]}`,
+			want: []qmdHit{
+				{Path: "a.md", DocID: "#d1", Title: "A", Snippet: "first"},
+				{Path: "qmd://b.md", DocID: "#d2", Score: 1, Snippet: "second"},
+			},
+		},
+		{
+			name: "hits object",
+			data: `{"hits":[{"filepath":"c.md","content":"body"}]}`,
+			want: []qmdHit{{Path: "c.md", Snippet: "body"}},
+		},
+		{
+			name: "missing optional fields",
+			data: `[{"path":"only-path.md"},{"docid":"only-id"}]`,
+			want: []qmdHit{{Path: "only-path.md"}, {DocID: "#only-id"}},
+		},
+		{
+			name: "non-numeric score ignored",
+			data: `[{"path":"x.md","score":"high"}]`,
+			want: []qmdHit{{Path: "x.md"}},
+		},
+		{
+			name: "entries without path or docid dropped",
+			data: `[{"title":"orphan","snippet":"no ref"}]`,
+		},
+		{name: "empty array", data: `[]`},
+		{name: "object without results", data: `{"collections":3}`},
+		{name: "plain text", data: `Collection notes: 12 documents`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseQMDHits([]byte(tt.data))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQMDHits() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMCPResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		text     string
+		hits     []qmdHit
+		document *qmdDocument
+	}{
+		{
+			name: "structured content",
+			raw:  `{"content":[{"type":"text","text":"Found 1 result"}],"structuredContent":{"results":[{"file":"a.md","docid":"d1","score":0.5}]}}`,
+			text: "Found 1 result",
+			hits: []qmdHit{{Path: "a.md", DocID: "#d1", Score: 0.5}},
+		},
+		{
+			name: "json text block",
+			raw:  `{"content":[{"type":"text","text":"[{\"file\":\"b.md\",\"snippet\":\"hello\"}]"}]}`,
+			text: "1. b.md\n   hello",
+			hits: []qmdHit{{Path: "b.md", Snippet: "hello"}},
+		},
+		{
+			name: "plain text fallback",
+			raw:  `{"content":[{"type":"text","text":"Index: 2 collections"},{"type":"text","text":""}]}`,
+			text: "Index: 2 collections",
+		},
+		{
+			name:     "get resource",
+			raw:      `{"content":[{"type":"resource","resource":{"uri":"qmd://notes/go.md","name":"notes/go.md","title":"Go notes","text":"# Go notes"}}]}`,
+			text:     "=== notes/go.md (Go notes) ===\n# Go notes",
+			document: &qmdDocument{Path: "notes/go.md", Title: "Go notes"},
+		},
+		{
+			name:     "get resource without name",
+			raw:      `{"content":[{"type":"resource","resource":{"uri":"qmd://x.md","text":"body"}}]}`,
+			text:     "=== qmd://x.md ===\nbody",
+			document: &qmdDocument{Path: "qmd://x.md"},
+		},
+		{
+			name: "unparseable",
+			raw:  `"just a string"`,
+			text: `"just a string"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractMCPResult(json.RawMessage(tt.raw))
+			if got.Text != tt.text {
+				t.Errorf("Text = %q, want %q", got.Text, tt.text)
+			}
+			if !reflect.DeepEqual(got.Hits, tt.hits) {
+				t.Errorf("Hits = %#v, want %#v", got.Hits, tt.hits)
+			}
+			if !reflect.DeepEqual(got.Document, tt.document) {
+				t.Errorf("Document = %#v, want %#v", got.Document, tt.document)
+			}
+		})
+	}
+}
+
+func TestQMDResultRender(t *testing.T) {
+	hits := []qmdHit{{Path: "a.md", DocID: "#d1", Title: "A", Score: 0.5, Snippet: "line one\nline two"}}
+	got := (&qmdResult{Text: formatQMDHits(hits), Hits: hits}).render()
+	want := "1. a.md (A, #d1, score 0.50)\n   line one\n   line two\n\nResults (JSON):\n" +
+		`[{"path":"a.md","docid":"#d1","title":"A","score":0.5,"snippet":"line one\nline two"}]`
+	if got != want {
+		t.Errorf("render hits =\n%s\nwant\n%s", got, want)
+	}
+
+	got = (&qmdResult{Text: "# Title\nbody", Document: &qmdDocument{Path: "t.md", Title: markdownTitle("# Title\nbody")}}).render()
+	if !strings.HasSuffix(got, "Document (JSON):\n"+`{"path":"t.md","title":"Title"}`) {
+		t.Errorf("render document = %q", got)
+	}
+
+	// A docid 'get' of an untitled document has nothing structured to add
+	if got := (&qmdResult{Text: "body", Document: &qmdDocument{}}).render(); got != "body" {
+		t.Errorf("render empty document = %q", got)
+	}
+	if got := (&qmdResult{}).render(); got != "(no results)" {
+		t.Errorf("render empty = %q", got)
+	}
+}
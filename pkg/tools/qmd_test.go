@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeQMDDaemon is an httptest stand-in for "qmd mcp --http": /health
+// reports healthy while up, and /mcp answers initialize and tools/call.
+type fakeQMDDaemon struct {
+	*httptest.Server
+	up         atomic.Bool
+	marker     string // when set, the daemon is also up once this file exists
+	healthHits atomic.Int32
+	toolCalls  atomic.Int32
+}
+
+func newFakeQMDDaemon(t *testing.T) *fakeQMDDaemon {
+	t.Helper()
+	d := &fakeQMDDaemon{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		d.healthHits.Add(1)
+		if !d.isUp() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		var req mcpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "initialize" {
+			w.Header().Set("Mcp-Session-Id", "s1")
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+			return
+		}
+		d.toolCalls.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"daemon status"}]}}`))
+	})
+	d.Server = httptest.NewServer(mux)
+	t.Cleanup(d.Close)
+	return d
+}
+
+func (d *fakeQMDDaemon) isUp() bool {
+	if d.up.Load() {
+		return true
+	}
+	if d.marker == "" {
+		return false
+	}
+	_, err := os.Stat(d.marker)
+	return err == nil
+}
+
+func (d *fakeQMDDaemon) endpoint() string { return d.URL + "/mcp" }
+
+// fakeQMDCLI puts a qmd script first on PATH. It logs each invocation to
+// the returned file, answers "status" with "cli status", and handles
+// "mcp --daemon" by failing when failStart is set or otherwise touching
+// marker, which brings the fake daemon up.
+func fakeQMDCLI(t *testing.T, marker string, failStart bool) (logPath string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake qmd CLI is a shell script")
+	}
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "calls.log")
+	start := ": > '" + marker + "'"
+	if failStart {
+		start = "echo 'port in use' >&2; exit 1"
+	}
+	script := "#!/bin/sh\necho \"$@\" >> '" + logPath + "'\n" +
+		"case \"$1\" in\n  mcp) " + start + " ;;\n  *) echo \"cli $1\" ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, "qmd"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	return logPath
+}
+
+// qmdCalls returns the logged CLI invocations.
+func qmdCalls(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestQMDForceMode(t *testing.T) {
+	d := newFakeQMDDaemon(t)
+	d.up.Store(true)
+	logPath := fakeQMDCLI(t, "", false)
+	ctx := context.Background()
+
+	cliTool := NewQMDTool(d.endpoint(), "cli")
+	if out, err := cliTool.Execute(ctx, map[string]interface{}{"operation": "status"}); err != nil || out != "cli status" {
+		t.Fatalf("cli mode = %q, %v", out, err)
+	}
+	out, err := cliTool.Execute(ctx, map[string]interface{}{"operation": "status", "force_mode": "mcp"})
+	if err != nil || out != "daemon status" || d.toolCalls.Load() != 1 {
+		t.Errorf("force_mode mcp = %q, %v (tool calls %d)", out, err, d.toolCalls.Load())
+	}
+
+	mcpTool := NewQMDTool(d.endpoint(), "mcp")
+	out, err = mcpTool.Execute(ctx, map[string]interface{}{"operation": "status", "force_mode": "cli"})
+	if err != nil || out != "cli status" || d.toolCalls.Load() != 1 {
+		t.Errorf("force_mode cli = %q, %v (tool calls %d)", out, err, d.toolCalls.Load())
+	}
+
+	calls := len(qmdCalls(t, logPath))
+	_, err = mcpTool.Execute(ctx, map[string]interface{}{"operation": "status", "force_mode": "bm25"})
+	if err == nil || !strings.Contains(err.Error(), `invalid force_mode "bm25"`) {
+		t.Errorf("invalid force_mode error = %v", err)
+	}
+	if d.toolCalls.Load() != 1 || len(qmdCalls(t, logPath)) != calls {
+		t.Error("invalid force_mode reached a backend")
+	}
+}
+
+func TestQMDProbeCache(t *testing.T) {
+	d := newFakeQMDDaemon(t)
+	d.up.Store(true)
+	q := NewQMDTool(d.endpoint(), "auto")
+
+	for i := 0; i < 3; i++ {
+		if !q.isDaemonReachable() {
+			t.Fatal("daemon not reachable")
+		}
+	}
+	if n := d.healthHits.Load(); n != 1 {
+		t.Errorf("health probes = %d, want 1 within the TTL", n)
+	}
+
+	// The cached answer holds until the TTL runs out
+	d.up.Store(false)
+	if !q.isDaemonReachable() {
+		t.Error("cached probe result not reused")
+	}
+	q.probeMu.Lock()
+	q.probedAt = time.Now().Add(-qmdProbeTTL)
+	q.probeMu.Unlock()
+	if q.isDaemonReachable() {
+		t.Error("expired probe result reused")
+	}
+	if n := d.healthHits.Load(); n != 2 {
+		t.Errorf("health probes = %d, want 2 after expiry", n)
+	}
+}
+
+func TestQMDAutoStartFailsOnce(t *testing.T) {
+	d := newFakeQMDDaemon(t)
+	logPath := fakeQMDCLI(t, "", true)
+	q := NewQMDTool(d.endpoint(), "auto")
+	q.SetAutoStartDaemon(true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if q.ensureDaemon() {
+				t.Error("ensureDaemon() = true after a failed start")
+			}
+		}()
+	}
+	wg.Wait()
+
+	port := mustPort(t, d.URL)
+	if calls := qmdCalls(t, logPath); len(calls) != 1 || calls[0] != "mcp --http --daemon --port "+port {
+		t.Errorf("qmd invocations = %q, want one daemon start", calls)
+	}
+	if note := q.fallbackNote("x"); !strings.Contains(note, "could not be started (qmd mcp --daemon: port in use)") {
+		t.Errorf("fallback note = %q", note)
+	}
+
+	// Searches keep falling back to the CLI without respawning
+	out, err := q.Execute(context.Background(), map[string]interface{}{"operation": "status"})
+	if err != nil || out != "cli status" {
+		t.Errorf("status after failed start = %q, %v", out, err)
+	}
+	if calls := qmdCalls(t, logPath); len(calls) != 2 {
+		t.Errorf("qmd invocations = %q, want the start and one status", calls)
+	}
+}
+
+func TestQMDAutoStartBringsDaemonUp(t *testing.T) {
+	d := newFakeQMDDaemon(t)
+	d.marker = filepath.Join(t.TempDir(), "started")
+	logPath := fakeQMDCLI(t, d.marker, false)
+	ctx := context.Background()
+
+	off := NewQMDTool(d.endpoint(), "auto")
+	if out, _ := off.Execute(ctx, map[string]interface{}{"operation": "status"}); out != "cli status" {
+		t.Errorf("without auto-start = %q", out)
+	}
+	if calls := qmdCalls(t, logPath); len(calls) != 1 || calls[0] != "status" {
+		t.Fatalf("auto-start disabled but qmd invocations = %q", calls)
+	}
+
+	q := NewQMDTool(d.endpoint(), "auto")
+	q.SetAutoStartDaemon(true)
+	for i := 0; i < 2; i++ {
+		out, err := q.Execute(ctx, map[string]interface{}{"operation": "status"})
+		if err != nil || out != "daemon status" {
+			t.Fatalf("status %d = %q, %v", i, out, err)
+		}
+	}
+	if calls := qmdCalls(t, logPath); len(calls) != 2 || !strings.HasPrefix(calls[1], "mcp --http --daemon") {
+		t.Errorf("qmd invocations = %q, want one daemon start", calls)
+	}
+}
+
+func mustPort(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Port()
+}
@@ -10,16 +10,36 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools  map[string]Tool
+	limits map[string]ToolLimits
+	mu     sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
-		tools: make(map[string]Tool),
+		tools:  make(map[string]Tool),
+		limits: make(map[string]ToolLimits),
 	}
 }
 
+// SetLimits overrides the timeout and output cap for the named tool. The
+// timeout bounds the whole call; tools with their own deadline (exec, qmd)
+// use it in place of their default. Output beyond MaxOutputBytes is
+// truncated with a marker.
+func (r *ToolRegistry) SetLimits(name string, limits ToolLimits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[name] = limits
+}
+
+// Limits returns the limits set for the named tool; zero values mean the
+// tool's defaults apply.
+func (r *ToolRegistry) Limits(name string) ToolLimits {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.limits[name]
+}
+
 func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -57,10 +77,22 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
 	}
+	if channel != "" && chatID != "" {
+		ctx = WithChat(ctx, channel, chatID)
+	}
+
+	limits := r.Limits(name)
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+	ctx = withToolLimits(ctx, limits)
 
 	start := time.Now()
 	result, err := tool.Execute(ctx, args)
 	duration := time.Since(start)
+	result = TruncateOutput(result, limits.MaxOutputBytes)
 
 	if err != nil {
 		logger.ErrorCF("tool", "Tool execution failed",
@@ -92,6 +124,44 @@ func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 	return definitions
 }
 
+// Filtered returns a new registry holding only the tools for which allow
+// returns true. The tools themselves are shared, not copied.
+func (r *ToolRegistry) Filtered(allow func(name string) bool) *ToolRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := NewToolRegistry()
+	for name, tool := range r.tools {
+		if allow(name) {
+			out.tools[name] = tool
+			if l, ok := r.limits[name]; ok {
+				out.limits[name] = l
+			}
+		}
+	}
+	return out
+}
+
+// CheckHealth probes every tool that implements HealthChecker. The result
+// maps each probed tool to its error, nil meaning healthy; tools without a
+// probe are left out.
+func (r *ToolRegistry) CheckHealth(ctx context.Context) map[string]error {
+	r.mu.RLock()
+	probes := make(map[string]HealthChecker)
+	for name, tool := range r.tools {
+		if hc, ok := tool.(HealthChecker); ok {
+			probes[name] = hc
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make(map[string]error, len(probes))
+	for name, hc := range probes {
+		results[name] = hc.HealthCheck(ctx)
+	}
+	return results
+}
+
 // List returns a list of all registered tool names.
 func (r *ToolRegistry) List() []string {
 	r.mu.RLock()
@@ -6,10 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type ExecTool struct {
@@ -74,6 +75,13 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 
 	cwd := t.workingDir
 	if wd, ok := args["working_dir"].(string); ok && wd != "" {
+		if t.restrictToWorkspace && t.workingDir != "" {
+			jailed, err := utils.ResolveInWorkspace(t.workingDir, wd)
+			if err != nil {
+				return fmt.Sprintf("Error: Command blocked by safety guard (%v)", err), nil
+			}
+			wd = jailed
+		}
 		cwd = wd
 	}
 
@@ -88,13 +96,17 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		return fmt.Sprintf("Error: %s", guardError), nil
 	}
 
-	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	timeout := toolTimeout(ctx, t.timeout)
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	// Children of the shell keep the output pipes open after it is killed;
+	// don't let them hold Run past the timeout.
+	cmd.WaitDelay = time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -108,7 +120,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 
 	if err != nil {
 		if cmdCtx.Err() == context.DeadlineExceeded {
-			return fmt.Sprintf("Error: Command timed out after %v", t.timeout), nil
+			return fmt.Sprintf("Error: Command timed out after %v", timeout), nil
 		}
 		output += fmt.Sprintf("\nExit code: %v", err)
 	}
@@ -117,9 +129,9 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (st
 		output = "(no output)"
 	}
 
-	maxLen := 10000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
+	// A configured output cap is applied by the registry
+	if toolLimitsFrom(ctx).MaxOutputBytes == 0 {
+		output = TruncateOutput(output, 10000)
 	}
 
 	return output, nil
@@ -153,26 +165,18 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 			return "Command blocked by safety guard (path traversal detected)"
 		}
 
-		cwdPath, err := filepath.Abs(cwd)
-		if err != nil {
-			return ""
+		// Paths are checked against the workspace root (falling back to
+		// the working dir), with symlinks resolved.
+		root := t.workingDir
+		if root == "" {
+			root = cwd
 		}
 
 		pathPattern := regexp.MustCompile(`[A-Za-z]:\\[^\\\"']+|/[^\s\"']+`)
 		matches := pathPattern.FindAllString(cmd, -1)
 
 		for _, raw := range matches {
-			p, err := filepath.Abs(raw)
-			if err != nil {
-				continue
-			}
-
-			rel, err := filepath.Rel(cwdPath, p)
-			if err != nil {
-				continue
-			}
-
-			if strings.HasPrefix(rel, "..") {
+			if _, err := utils.ResolveInWorkspace(root, raw); err != nil {
 				return "Command blocked by safety guard (path outside working dir)"
 			}
 		}
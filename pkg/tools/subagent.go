@@ -25,6 +25,7 @@ type SubagentManager struct {
 	tasks     map[string]*SubagentTask
 	mu        sync.RWMutex
 	provider  providers.LLMProvider
+	retry     providers.RetryPolicy
 	bus       *bus.MessageBus
 	workspace string
 	nextID    int
@@ -34,12 +35,19 @@ func NewSubagentManager(provider providers.LLMProvider, workspace string, bus *b
 	return &SubagentManager{
 		tasks:     make(map[string]*SubagentTask),
 		provider:  provider,
+		retry:     providers.DefaultRetryPolicy,
 		bus:       bus,
 		workspace: workspace,
 		nextID:    1,
 	}
 }
 
+// SetRetryPolicy sets how transient provider errors are retried; the
+// default is providers.DefaultRetryPolicy.
+func (sm *SubagentManager) SetRetryPolicy(policy providers.RetryPolicy) {
+	sm.retry = policy
+}
+
 func (sm *SubagentManager) Spawn(ctx context.Context, task, label, originChannel, originChatID string) (string, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -81,7 +89,7 @@ func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask) {
 		},
 	}
 
-	response, err := sm.provider.Chat(ctx, messages, nil, sm.provider.GetDefaultModel(), map[string]interface{}{
+	response, err := providers.ChatWithRetry(ctx, sm.provider, sm.retry, nil, messages, nil, sm.provider.GetDefaultModel(), map[string]interface{}{
 		"max_tokens": 4096,
 	})
 
@@ -58,6 +58,14 @@ func (t *WebSearchTool) Parameters() map[string]interface{} {
 	}
 }
 
+// HealthCheck reports a missing search API key.
+func (t *WebSearchTool) HealthCheck(ctx context.Context) error {
+	if t.apiKey == "" {
+		return fmt.Errorf("BRAVE_API_KEY not configured")
+	}
+	return nil
+}
+
 func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	if t.apiKey == "" {
 		return "Error: BRAVE_API_KEY not configured", nil
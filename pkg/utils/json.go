@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CanonicalJSON marshals v to compact JSON with object keys sorted at every
+// level, including inside json.RawMessage values, and without HTML escaping.
+// Numbers keep their original text. Equal values always produce identical
+// bytes, which makes the output safe to compare or hash.
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	raw, err := marshalNoEscape(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through interface{} so embedded raw JSON is re-sorted too;
+	// encoding/json sorts map keys on the way back out.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return marshalNoEscape(generic)
+}
+
+func marshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
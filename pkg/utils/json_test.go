@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	v := map[string]interface{}{
+		"b":    1,
+		"a":    json.RawMessage(`{"z": 1, "y": [ {"d": 2, "c": 1.50} ]}`),
+		"html": "<a&b>",
+	}
+	want := `{"a":{"y":[{"c":1.50,"d":2}],"z":1},"b":1,"html":"<a&b>"}`
+
+	for i := 0; i < 5; i++ {
+		got, err := CanonicalJSON(v)
+		if err != nil {
+			t.Fatalf("CanonicalJSON() error: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("CanonicalJSON() = %s, want %s", got, want)
+		}
+	}
+}
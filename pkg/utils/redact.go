@@ -0,0 +1,70 @@
+package utils
+
+import "strings"
+
+// RedactedValue replaces secret values in redacted output.
+const RedactedValue = "[REDACTED]"
+
+// DefaultSecretKeys are key-name fragments treated as secrets when no list
+// is configured.
+var DefaultSecretKeys = []string{
+	"token", "secret", "password", "passwd", "api_key", "apikey",
+	"authorization", "credential", "private_key",
+}
+
+// IsSecretKey reports whether a key name contains any of the given
+// fragments, ignoring case.
+func IsSecretKey(key string, fragments []string) bool {
+	k := strings.ToLower(key)
+	for _, f := range fragments {
+		if f != "" && strings.Contains(k, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactMap returns a copy of m with the value of every key matching one of
+// fragments replaced by RedactedValue. Nested maps and slices are walked;
+// m itself is not modified. Empty values stay empty so callers can still
+// tell "unset" from "set".
+func RedactMap(m map[string]interface{}, fragments []string) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if IsSecretKey(k, fragments) && !isEmptyValue(v) {
+			out[k] = RedactedValue
+			continue
+		}
+		out[k] = redactValue(v, fragments)
+	}
+	return out
+}
+
+func redactValue(v interface{}, fragments []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return RedactMap(val, fragments)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = redactValue(item, fragments)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	default:
+		return false
+	}
+}
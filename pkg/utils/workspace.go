@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveInWorkspace resolves path against the workspace root and returns its
+// absolute, symlink-free form. Relative paths are taken relative to root.
+// It fails if the result — after following every symlink along the way —
+// is not root itself or somewhere beneath it. Paths that don't exist yet are
+// checked through their deepest existing ancestor, so a create under a
+// symlinked directory pointing elsewhere is rejected too.
+func ResolveInWorkspace(root, path string) (string, error) {
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace root: %w", err)
+	}
+	rootReal, err := filepath.EvalSymlinks(rootAbs)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace root: %w", err)
+	}
+
+	target := path
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(rootAbs, target)
+	}
+	resolved, err := evalExisting(filepath.Clean(target))
+	if err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	if !withinDir(rootReal, resolved) {
+		return "", fmt.Errorf("access denied: path %q is outside workspace %q", path, rootAbs)
+	}
+	return resolved, nil
+}
+
+// evalExisting resolves symlinks in the longest existing prefix of path and
+// appends the remaining (not yet created) elements unchanged.
+func evalExisting(path string) (string, error) {
+	var missing []string
+	p := path
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		// A dangling symlink can't be checked, but writing through it
+		// would still land wherever it points.
+		if info, lerr := os.Lstat(p); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("dangling symlink %s", p)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return path, nil
+		}
+		missing = append(missing, filepath.Base(p))
+		p = parent
+	}
+}
+
+// withinDir reports whether path is dir or lies beneath it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
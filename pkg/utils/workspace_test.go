@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveInWorkspace(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "src"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"root itself", ".", true},
+		{"relative file", "src/main.go", true},
+		{"new nested file", "src/new/dir/file.go", true},
+		{"absolute inside", filepath.Join(root, "src"), true},
+		{"symlink staying inside", "alias/main.go", true},
+		{"dotdot escape", "../etc/passwd", false},
+		{"absolute outside", "/etc/passwd", false},
+		{"symlink pointing outside", "escape/file", false},
+		{"dangling symlink", "dangling", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveInWorkspace(root, tt.path)
+			if (err == nil) != tt.ok {
+				t.Errorf("ResolveInWorkspace(%q) error = %v, want ok=%v", tt.path, err, tt.ok)
+			}
+		})
+	}
+}